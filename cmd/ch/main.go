@@ -2,6 +2,10 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -9,18 +13,22 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/MehmetMHY/ch/internal/chat"
 	"github.com/MehmetMHY/ch/internal/config"
 	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/tokens"
 	"github.com/MehmetMHY/ch/internal/ui"
 	"github.com/MehmetMHY/ch/pkg/types"
 	"github.com/chzyer/readline"
 	"github.com/google/uuid"
-	"github.com/tiktoken-go/tokenizer"
 )
 
 func init() {
@@ -38,33 +46,65 @@ func main() {
 	state := config.InitializeAppState()
 
 	// detect if stdout is being piped
-	stdoutStat, _ := os.Stdout.Stat()
-	if (stdoutStat.Mode() & os.ModeCharDevice) == 0 {
+	if !isTerminal(os.Stdout) {
 		state.Config.IsPipedOutput = true
 	}
 
 	// initialize components
 	terminal := ui.NewTerminal(state.Config)
+
+	// conflicting command keys would send input to the wrong handler, so
+	// they are reported before anything runs
+	if err := config.CheckCommandKeys(state.Config); err != nil {
+		terminal.PrintError(err.Error())
+		os.Exit(1)
+	}
+
 	chatManager := chat.NewManager(state)
 	platformManager := platform.NewManager(state.Config)
 	chatManager.SetPlatformManager(platformManager)
+	platformManager.ConfirmSpend = terminal.Confirm
+	platformManager.ConfirmTool = terminal.Confirm
+	for _, tool := range chat.LocalTools(terminal, state.Config, chatManager.AddRecentlyCreatedFile) {
+		platformManager.RegisterTool(tool)
+	}
+	if state.Config.Memory {
+		platformManager.RegisterTool(chatManager.MemoryTool())
+	}
 
 	// parse command line arguments
 	var (
-		helpFlag       = flag.Bool("h", false, "Show help")
-		codedumpFlag   = flag.String("d", "", "Generate codedump file (optionally specify directory path)")
-		platformFlag   = flag.String("p", "", "Switch platform (leave empty for interactive selection)")
-		modelFlag      = flag.String("m", "", "Specify model to use")
-		allModelsFlag  = flag.String("o", "", "Specify platform and model (format: platform|model)")
-		exportCodeFlag = flag.Bool("e", false, "Export code blocks from the last response")
-		tokenFlag      = flag.String("t", "", "Estimate token count in file, or piped stdin if no file is given")
-		loadFileFlag   = flag.String("l", "", "Load and display file content (supports text, PDF, DOCX, XLSX, CSV)")
-		webSearchFlag  = flag.String("w", "", "Perform a web search and print the results")
-		scrapeURLFlag  = flag.String("s", "", "Scrape a URL and print the content")
-		continueFlag   = flag.Bool("c", false, "Continue from latest session")
-		clearFlag      = flag.Bool("clear", false, "Clear latest session")
-		historyFlag    = flag.Bool("a", false, "Search and load previous sessions")
-		fetchFlag      = flag.Bool("f", false, "Fetch a session into interactive mode (file name, path, or fzf pick)")
+		helpFlag         = flag.Bool("h", false, "Show help")
+		codedumpFlag     = flag.String("d", "", "Generate codedump file (optionally specify directory path)")
+		platformFlag     = flag.String("p", "", "Switch platform (leave empty for interactive selection)")
+		modelFlag        = flag.String("m", "", "Specify model to use")
+		allModelsFlag    = flag.String("o", "", "Specify platform and model (format: platform|model)")
+		exportCodeFlag   = flag.Bool("e", false, "Export code blocks from the last response")
+		tokenFlag        = flag.String("t", "", "Estimate token count in file, or piped stdin if no file is given")
+		loadFileFlag     = flag.String("l", "", "Load and display file content (supports text, PDF, DOCX, XLSX, CSV)")
+		webSearchFlag    = flag.String("w", "", "Perform a web search and print the results")
+		scrapeURLFlag    = flag.String("s", "", "Scrape a URL and print the content")
+		continueFlag     = flag.Bool("c", false, "Continue from latest session")
+		clearFlag        = flag.Bool("clear", false, "Remove stale temp files, and saved sessions with 'sessions [age]'")
+		historyFlag      = flag.Bool("a", false, "Search and load previous sessions")
+		fetchFlag        = flag.Bool("f", false, "Fetch a session into interactive mode (file name, path, or fzf pick)")
+		datasetFlag      = flag.String("dataset", "", "Export saved sessions as a training dataset (openai, sharegpt)")
+		usageFlag        = flag.Bool("usage", false, "Report token usage and estimated cost recorded in ~/.ch/usage.jsonl")
+		templateFlag     = flag.String("T", "", "Fill in a prompt template from ~/.ch/templates (name=value args, piped stdin for {{stdin}}) and send it")
+		minRatingFlag    = flag.Int("min-rating", 0, "Only export sessions rated at least this value (with --dataset)")
+		tagFlag          = flag.String("tag", "", "Only export sessions with these comma-separated tags (with --dataset)")
+		seedFlag         = flag.Int("seed", 0, "Seed for reproducible generations on providers that support it")
+		logprobsFlag     = flag.Bool("logprobs", false, "Show token probabilities and top alternatives after responses")
+		lowBandwidthFlag = flag.Bool("low-bandwidth", false, "Redraw less and print streamed replies in chunks, for slow SSH links")
+		streamJSONFlag   = flag.Bool("stream-json", false, "Emit newline-delimited JSON events instead of text while generating")
+		dryRunFlag       = flag.Bool("dry-run", false, "Print the request that would be sent, with tokens and estimated cost, instead of sending it")
+		dumpStdoutFlag   = flag.Bool("stdout", false, "Write the codedump to stdout instead of a file (with -d)")
+		dumpFormatFlag   = flag.String("dump-format", ui.CodeDumpText, "Codedump format: text or markdown (with -d)")
+		manifestFlag     = flag.Bool("manifest", false, "Also write a JSON manifest of the dumped files (with -d)")
+		sinceFlag        = flag.String("since", "", "Only dump files changed since a git ref or time (with -d)")
+		indexFlag        = flag.String("index", "", "Build or update the embeddings index of a directory, used by !rag")
+		profileFlag      = flag.String("profile", "", "Start with a named preset of platform, model, persona, search, and safe-mode settings from config")
+		applyFlag        = flag.Bool("apply", false, "Ask for edits to existing files and apply them after previewing a diff")
 	)
 	flag.StringVar(tokenFlag, "token", "", "Estimate token count in file, or piped stdin if no file is given")
 	flag.BoolVar(continueFlag, "continue", false, "Continue from latest session")
@@ -84,7 +124,7 @@ func main() {
 		// Match every spelling Go's flag package accepts for these flags
 		// (one or two leading dashes are equivalent).
 		switch arg {
-		case "-t", "--t", "-token", "--token":
+		case "-t", "--t", "-token", "--token", "-d", "--d":
 			nextIsValue := i+1 < len(cliArgs) && !strings.HasPrefix(cliArgs[i+1], "-")
 			if !nextIsValue {
 				cliArgs[i] = arg + "="
@@ -96,12 +136,50 @@ func main() {
 	}
 
 	tokenFlagProvided := false
+	codedumpFlagProvided := false
 	flag.Visit(func(f *flag.Flag) {
 		if f.Name == "t" || f.Name == "token" {
 			tokenFlagProvided = true
 		}
+		if f.Name == "d" {
+			codedumpFlagProvided = true
+		}
+		// Any seed value, including 0, is valid once the flag is passed
+		if f.Name == "seed" {
+			state.Config.Seed = seedFlag
+		}
 	})
 
+	if *logprobsFlag {
+		state.Config.ShowLogprobs = true
+	}
+
+	if *lowBandwidthFlag {
+		state.Config.LowBandwidth = true
+	}
+
+	// JSON events replace the text output, so everything else is kept off stdout
+	if *streamJSONFlag {
+		state.Config.StreamJSON = true
+		state.Config.IsPipedOutput = true
+	}
+
+	if *dryRunFlag {
+		state.Config.DryRun = true
+	}
+
+	// A profile's settings are applied before anything reads them
+	var profile types.Profile
+	if *profileFlag != "" {
+		var err error
+		if profile, err = config.ApplyProfile(state.Config, *profileFlag); err != nil {
+			terminal.PrintError(err.Error())
+			return
+		}
+		state.Messages = config.InitialMessages(state.Config)
+		state.ChatHistory[0].User = state.Config.SystemPrompt
+	}
+
 	// Link -n and --no-history flags together
 	if flag.Lookup("no-history").Value.String() == "true" {
 		*noHistoryFlag = true
@@ -125,46 +203,57 @@ func main() {
 		return
 	}
 
-	// handle clear session flag
+	// handle clear flag: `ch --clear [sessions [age]]`
 	if *clearFlag {
-		if !state.Config.EnableSessionSave {
-			terminal.PrintError("session save feature is disabled in config")
-			return
+		if err := handleClear(remainingArgs, terminal); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
+		return
+	}
 
-		// Confirm before clearing (in red, default to No)
-		fmt.Printf("\033[91mdelete all temp files? (y/N)\033[0m ")
-		var response string
-		_, err := fmt.Scanln(&response)
-
-		// Convert to lowercase and check response
-		response = strings.ToLower(strings.TrimSpace(response))
-		if response != "y" && response != "yes" {
-			fmt.Println("cancelled")
-			return
+	// handle usage report flag: `ch --usage [age]`
+	if *usageFlag {
+		if err := handleUsageReport(remainingArgs); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
+		return
+	}
 
-		// Get temp directory using centralized utility
-		tmpDir, err := config.GetTempDir()
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("failed to get temp directory: %v", err))
-			return
+	// handle dataset export flag
+	if *datasetFlag != "" {
+		if err := handleDatasetExport(*datasetFlag, *minRatingFlag, *tagFlag, state.Config, terminal); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
+		return
+	}
 
-		// Delete and recreate temp directory
-		err = os.RemoveAll(tmpDir)
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error clearing temporary files: %v", err))
-			return
+	// handle cheatsheet subcommand: `ch cheatsheet`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "cheatsheet" {
+		fmt.Print(terminal.Cheatsheet())
+		return
+	}
+
+	// handle workspace subcommand: `ch ws [list|switch|model|prompt]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "ws" {
+		if err := handleWorkspaceCommand(remainingArgs[1:], state, terminal); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
+		return
+	}
 
-		// Recreate empty directory
-		err = os.MkdirAll(tmpDir, 0700)
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error recreating temporary directory: %v", err))
-			return
+	// handle export subcommand: `ch export [session] [--since t] [--role r] [--last N] [--strip-context] [--notes] [--format f]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "export" {
+		if err := handleExportCommand(remainingArgs[1:], chatManager, state); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
+		return
+	}
 
+	// handle session database subcommand: `ch db [stats|import|export|prune|search]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "db" {
+		if err := handleSessionDB(remainingArgs[1:], state.Config); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
 		return
 	}
 
@@ -214,7 +303,7 @@ func main() {
 	}
 
 	// handle codedump flag
-	if flag.Lookup("d").Value.String() != flag.Lookup("d").DefValue {
+	if codedumpFlagProvided {
 		targetDir := *codedumpFlag
 		if targetDir == "" {
 			targetDir = "."
@@ -228,7 +317,7 @@ func main() {
 			}
 		}
 
-		codedump, err := terminal.CodeDumpFromDirForCLI(targetDir)
+		dump, err := terminal.CodeDumpFilesForCLI(targetDir, *sinceFlag)
 		if err != nil {
 			// Check if user cancelled (Ctrl-C/Ctrl-D during fzf)
 			if strings.Contains(err.Error(), "user cancelled") {
@@ -237,20 +326,9 @@ func main() {
 			terminal.PrintError(fmt.Sprintf("error generating codedump: %v", err))
 			return
 		}
-
-		currentDir, err := os.Getwd()
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error getting current directory: %v", err))
-			return
-		}
-		filename := generateUniqueCodeDumpFilename(currentDir, codedump)
-		err = os.WriteFile(filename, []byte(codedump), 0600)
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error writing codedump file: %v", err))
-			return
+		if err := writeCodeDump(dump, *dumpFormatFlag, state.Config.CurrentModel, *dumpStdoutFlag, *manifestFlag); err != nil {
+			terminal.PrintError(fmt.Sprintf("error writing codedump: %v", err))
 		}
-
-		fmt.Println(filename)
 		return
 	}
 
@@ -264,6 +342,12 @@ func main() {
 		return
 	}
 
+	// handle apply flag without a prompt, like -e
+	if *applyFlag && len(remainingArgs) == 0 && pipedInput == "" {
+		handleApplyPatches(chatManager, terminal)
+		return
+	}
+
 	// handle token counting flag
 	if tokenFlagProvided {
 		err := handleTokenCount(*tokenFlag, *modelFlag, terminal, state, pipedInput)
@@ -364,6 +448,15 @@ func main() {
 		*modelFlag = modelName
 	}
 
+	// A profile's platform and model act as -p and -m. An explicit -p drops
+	// both, while -m alone replaces only the model.
+	if *platformFlag == "" {
+		*platformFlag = profile.Platform
+		if *modelFlag == "" {
+			*modelFlag = profile.Model
+		}
+	}
+
 	// Set platform and model based on precedence: flags > env vars > config file
 	finalPlatform := state.Config.CurrentPlatform
 	finalModel := state.Config.CurrentModel
@@ -384,6 +477,32 @@ func main() {
 		finalModel = *modelFlag
 	}
 
+	// handle embed subcommand: `ch embed [files...] [--model name]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "embed" {
+		state.Config.CurrentPlatform = finalPlatform
+		if err := handleEmbed(remainingArgs[1:], pipedInput, platformManager, state); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return
+	}
+
+	// handle test subcommand: `ch test [-o platform|model] [prompt]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "test" {
+		if !handleTest(remainingArgs[1:], finalPlatform, finalModel, platformManager, terminal, state) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// handle --index: build or update a directory's embeddings index
+	if *indexFlag != "" {
+		state.Config.CurrentPlatform = finalPlatform
+		if err := handleIndex(*indexFlag, platformManager, terminal, state); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return
+	}
+
 	// Handle -f / --fetch flag: load a session by name/path or via fzf, then
 	// fall through to interactive mode (or direct query if a prompt follows).
 	sessionRestored := false
@@ -410,16 +529,16 @@ func main() {
 				if _, statErr := os.Stat(sessionArg); statErr == nil {
 					resolvedPath = sessionArg
 				} else {
-					tmpDir, dirErr := config.GetTempDir()
+					tmpDir, dirErr := config.GetSessionDir(state.Config)
 					if dirErr != nil {
-						terminal.PrintError(fmt.Sprintf("failed to get temp directory: %v", dirErr))
+						terminal.PrintError(fmt.Sprintf("failed to get session directory: %v", dirErr))
 						return
 					}
 					resolvedPath = filepath.Join(tmpDir, sessionArg)
 				}
 			}
 
-			if _, statErr := os.Stat(resolvedPath); statErr != nil {
+			if !chatManager.SessionExists(resolvedPath) {
 				terminal.PrintError(fmt.Sprintf("session file not found: %s", sessionArg))
 				return
 			}
@@ -575,6 +694,24 @@ func main() {
 		terminal.PrintError(fmt.Sprintf("failed to initialize client: %v", err))
 		return
 	}
+	defer printSessionArtifacts(chatManager, terminal)
+	defer writeUsageSummary(chatManager, terminal)
+
+	// handle summarize subcommand: `ch summarize <file|dir|url> [focus]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "summarize" {
+		if err := handleSummarize(remainingArgs[1:], pipedInput, chatManager, terminal, state); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return
+	}
+
+	// handle tail subcommand: `ch tail [-f] <file> [--ask text]`
+	if len(remainingArgs) > 0 && remainingArgs[0] == "tail" {
+		if err := handleTail(remainingArgs[1:], chatManager, terminal); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return
+	}
 
 	// handle web search flag
 	if *webSearchFlag != "" {
@@ -694,6 +831,11 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		for range sigChan {
+			if ui.ForegroundChildRunning() {
+				// fzf, the editor, or a recorded shell got the same Ctrl+C
+				// and returns to the ch prompt on its own
+				continue
+			}
 			if state.IsStreaming && state.StreamingCancel != nil {
 				fmt.Print("\r\033[K")
 				state.StreamingCancel()
@@ -704,11 +846,25 @@ func main() {
 				if state.Config.EnableSessionSave && !*noHistoryFlag {
 					_ = chatManager.SaveSessionState()
 				}
+				writeUsageSummary(chatManager, terminal)
+				printSessionArtifacts(chatManager, terminal)
 				os.Exit(0)
 			}
 		}
 	}()
 
+	// handle template flag: `ch -T name [var=value...]`
+	if *templateFlag != "" {
+		prompt, err := fillTemplate(*templateFlag, remainingArgs, pipedInput, terminal)
+		if err == nil {
+			err = processDirectQuery(prompt, chatManager, platformManager, terminal, state, *exportCodeFlag, *applyFlag, *noHistoryFlag)
+		}
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return
+	}
+
 	// handle direct query mode (with piped input support)
 	if len(remainingArgs) > 0 || pipedInput != "" {
 		var query string
@@ -726,13 +882,24 @@ func main() {
 			query = strings.Join(remainingArgs, " ")
 		}
 
-		err := processDirectQuery(query, chatManager, platformManager, terminal, state, *exportCodeFlag, *noHistoryFlag)
+		err := processDirectQuery(query, chatManager, platformManager, terminal, state, *exportCodeFlag, *applyFlag, *noHistoryFlag)
 		if err != nil {
 			terminal.PrintError(fmt.Sprintf("%v", err))
 		}
 		return
 	}
 
+	if state.Config.StreamJSON {
+		terminal.PrintError("--stream-json needs a query or piped input")
+		return
+	}
+
+	// Interactive with piped stdout (e.g. `ch | tee log.txt`): the UI moves to
+	// stderr so stdout carries only response text
+	if state.Config.IsPipedOutput && isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		state.Config.UIToStderr = true
+	}
+
 	// interactive mode
 	runInteractiveMode(chatManager, platformManager, terminal, state, *noHistoryFlag)
 
@@ -743,17 +910,30 @@ func main() {
 	}
 }
 
-func processDirectQuery(query string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, exportCode bool, noHistory bool) error {
+// requestNotSent reports send errors that need no message: an interrupted
+// request, or one --dry-run printed instead of sending
+func requestNotSent(err error) bool {
+	return err.Error() == "request was interrupted" || errors.Is(err, platform.ErrDryRun)
+}
+
+func processDirectQuery(query string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, exportCode bool, applyEdits bool, noHistory bool) error {
 	if handleSpecialCommands(query, chatManager, platformManager, terminal, state, noHistory, nil) {
 		return nil
 	}
 
-	chatManager.AddUserMessage(query)
+	query = chatManager.ExpandMentions(terminal, query)
+	query = chatManager.InterpolateShell(terminal, query)
+	prompt := query
+	if applyEdits {
+		prompt = chat.WithApplyInstructions(query)
+	}
+	chatManager.AddUserMessage(prompt)
+	chatManager.PrepareContext(terminal)
 
-	response, err := platformManager.SendChatRequest(chatManager.GetMessages(), chatManager.GetCurrentModel(), &state.StreamingCancel, &state.IsStreaming)
+	response, err := chatManager.SendWithContextRetry(platformManager, terminal)
 	if err != nil {
-		chatManager.RemovePendingUserMessage(query)
-		if err.Error() == "request was interrupted" {
+		chatManager.RemovePendingUserMessage(prompt)
+		if requestNotSent(err) {
 			return nil
 		}
 		return err
@@ -781,6 +961,11 @@ func processDirectQuery(query string, chatManager *chat.Manager, platformManager
 		}
 	}
 
+	// Apply edits from the response if --apply was used
+	if applyEdits {
+		handleApplyPatches(chatManager, terminal)
+	}
+
 	return nil
 }
 
@@ -789,6 +974,8 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 		Prompt:          "\033[94muser: \033[0m",
 		InterruptPrompt: "", // Don't show ^C when Ctrl+C is pressed
 		EOFPrompt:       "exit",
+		Stdout:          terminal.UIWriter(),
+		Stderr:          terminal.UIWriter(),
 	})
 	if err != nil {
 		panic(err)
@@ -796,22 +983,32 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 	defer rl.Close()
 
 	if noHistory && state.Config.EnableSessionSave {
-		fmt.Printf("\033[91mChat Is Temporary\033[0m\n")
+		fmt.Fprintf(terminal.UIWriter(), "\033[91mChat Is Temporary\033[0m\n")
 	}
 
 	if state.Config.EnableSessionSave && !noHistory {
 		if _, err := chatManager.PrepareSessionFilePath(); err != nil {
 			terminal.PrintError(fmt.Sprintf("error preparing session file: %v", err))
 		}
+		// A restored or empty session is only written once it changes
+		chatManager.MarkSessionSaved()
 	}
 
+	var followups []string
+	warmedUp := ""
 	for {
+		// Warm up at the start and after each platform or model switch
+		if target := state.Config.CurrentPlatform + "|" + state.Config.CurrentModel; state.Config.Warmup && target != warmedUp {
+			warmedUp = target
+			platformManager.Warmup(state.Config.CurrentModel)
+		}
+
 		line, err := rl.Readline()
 		if err != nil {
 			if err == readline.ErrInterrupt {
 				// Ctrl+C pressed - clear input and continue
 				if !state.Config.MuteNotifications {
-					fmt.Printf("\033[93mpress ctrl+d to exit\033[0m\n")
+					fmt.Fprintf(terminal.UIWriter(), "\033[93mpress ctrl+d to exit\033[0m\n")
 				}
 				continue
 			}
@@ -824,6 +1021,13 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 			continue
 		}
 
+		// A number picks one of the follow-up questions suggested after the last response
+		if question, ok := resolveFollowupShortcut(input, followups); ok {
+			input = question
+			fmt.Printf("\033[90m> %s\033[0m\n", input)
+		}
+		followups = nil
+
 		// Check if input ends with backslash for automatic multi-line continuation
 		if strings.HasSuffix(input, state.Config.MultiLine) && input != state.Config.MultiLine {
 			// Remove trailing backslash from the first line
@@ -881,11 +1085,28 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 			}
 		}
 
-		if handleSpecialCommands(input, chatManager, platformManager, terminal, state, noHistory, rl) {
+		// Keep reading until an unterminated code fence is closed
+		if openCodeFence(input) != "" {
+			var ok bool
+			if input, ok = readFenceContinuation(input, terminal); !ok {
+				continue
+			}
+		}
+
+		if handleSpecialCommands(input, chatManager, platformManager, terminal, state, noHistory, rl) || handleDuplicatePrompt(input, chatManager, terminal, state) {
+			// Commands can answer prompts or change the session without saving it
+			if state.Config.EnableSessionSave && !noHistory {
+				if err := chatManager.AutosaveSession(); err != nil {
+					terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
+				}
+			}
 			continue
 		}
 
+		input = chatManager.ExpandMentions(terminal, input)
+		input = chatManager.InterpolateShell(terminal, input)
 		chatManager.AddUserMessage(input)
+		chatManager.PrepareContext(terminal)
 
 		// Start loading animation for non-streaming models
 		var loadingDone chan bool
@@ -893,7 +1114,7 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 			loadingDone = make(chan bool)
 			go terminal.ShowLoadingAnimation("thinking", loadingDone)
 		}
-		response, err := platformManager.SendChatRequest(chatManager.GetMessages(), chatManager.GetCurrentModel(), &state.StreamingCancel, &state.IsStreaming)
+		response, err := chatManager.SendWithContextRetry(platformManager, terminal)
 
 		// Stop loading animation if it was started
 		if loadingDone != nil {
@@ -902,7 +1123,7 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 
 		if err != nil {
 			chatManager.RemovePendingUserMessage(input)
-			if err.Error() == "request was interrupted" {
+			if requestNotSent(err) {
 				continue
 			}
 			terminal.PrintError(fmt.Sprintf("%v", err))
@@ -910,16 +1131,18 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 		}
 
 		// Print response for non-streaming models
-		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
+		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) && !state.Config.StreamJSON {
 			if state.Config.IsPipedOutput {
 				fmt.Printf("%s\n", response)
 			} else {
-				fmt.Printf("\033[92m%s\033[0m\n", response)
+				fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
 			}
+			platformManager.PrintLastLogprobs()
 		}
 
 		chatManager.AddAssistantMessage(response)
 		chatManager.AddToHistory(input, response)
+		printResponseFooter(chatManager, terminal, state.Config)
 
 		// Auto-save session state if enabled (unless -nh flag is set)
 		if state.Config.EnableSessionSave && !noHistory {
@@ -927,7 +1150,101 @@ func runInteractiveMode(chatManager *chat.Manager, platformManager *platform.Man
 				terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
 			}
 		}
+
+		if state.Config.SuggestFollowups && (!state.Config.IsPipedOutput || state.Config.UIToStderr) {
+			followups = chatManager.SuggestFollowups(terminal)
+			for i, question := range followups {
+				fmt.Fprintf(terminal.UIWriter(), "\033[90m%d) %s\033[0m\n", i+1, question)
+			}
+		}
+	}
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// resolveFollowupShortcut maps a bare number to the matching suggested follow-up question
+func resolveFollowupShortcut(input string, followups []string) (string, bool) {
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 || n > len(followups) {
+		return "", false
+	}
+	return followups[n-1], true
+}
+
+// saveShortcutPattern matches the !s1, !s2, ... code block save shortcuts
+var saveShortcutPattern = regexp.MustCompile(`^!s(\d+)(?:\s+(.*))?$`)
+
+// parseSaveCodeBlock reads the block number and optional path of a save
+// command: "<save> [n] [path]" or "!s<n> [path]". The block defaults to 1.
+func parseSaveCodeBlock(input, saveKey string) (int, string, bool) {
+	if match := saveShortcutPattern.FindStringSubmatch(input); match != nil {
+		n, err := strconv.Atoi(match[1])
+		return n, strings.TrimSpace(match[2]), err == nil && n > 0
+	}
+
+	args := strings.TrimSpace(strings.TrimPrefix(input, saveKey))
+	if args == "" {
+		return 1, "", true
+	}
+	first, rest, _ := strings.Cut(args, " ")
+	n, err := strconv.Atoi(first)
+	if err != nil {
+		return 1, args, true
+	}
+	return n, strings.TrimSpace(rest), n > 0
+}
+
+// openCodeFence returns the marker of a ``` or ~~~ code fence left open at
+// the end of text, or "" when every fence is closed
+func openCodeFence(text string) string {
+	open := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		n := len(line) - len(strings.TrimLeft(line, "`"))
+		if n < 3 {
+			n = len(line) - len(strings.TrimLeft(line, "~"))
+		}
+		if n < 3 {
+			continue
+		}
+		marker := line[:n]
+		if open == "" {
+			open = marker
+		} else if marker[0] == open[0] && len(marker) >= len(open) && strings.TrimSpace(line[n:]) == "" {
+			open = ""
+		}
+	}
+	return open
+}
+
+// readFenceContinuation reads more lines after input until its open code
+// fence is closed. Returns false when the user cancels with Ctrl+C or Ctrl+D,
+// so a half-finished snippet is never sent.
+func readFenceContinuation(input string, terminal *ui.Terminal) (string, bool) {
+	fenceRl, err := readline.NewEx(&readline.Config{
+		Prompt:      "``` ",
+		HistoryFile: "/dev/null", // Disable history for code block input
+	})
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error creating multi-line input: %v", err))
+		return "", false
+	}
+	defer fenceRl.Close()
+
+	lines := []string{input}
+	for openCodeFence(strings.Join(lines, "\n")) != "" {
+		line, err := fenceRl.Readline()
+		if err != nil {
+			terminal.PrintInfo("code block not closed, prompt discarded")
+			return "", false
+		}
+		lines = append(lines, line)
 	}
+	return strings.Join(lines, "\n"), true
 }
 
 func handleSpecialCommands(input string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool, rl *readline.Instance) bool {
@@ -942,6 +1259,8 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		if state.Config.EnableSessionSave && !noHistory {
 			_ = chatManager.SaveSessionState()
 		}
+		writeUsageSummary(chatManager, terminal)
+		printSessionArtifacts(chatManager, terminal)
 		os.Exit(0)
 		return true
 
@@ -966,6 +1285,9 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		return true
 
 	case input == config.ModelSwitch:
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
 		models, err := platformManager.ListModels()
 		if err != nil {
 			terminal.PrintError(fmt.Sprintf("error fetching models: %v", err))
@@ -979,6 +1301,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 
 		if selectedModel != "" {
+			selectedModel = platform.ModelFromLabel(selectedModel)
 			chatManager.SetCurrentModel(selectedModel)
 			if !config.MuteNotifications {
 				terminal.PrintModelSwitch(selectedModel)
@@ -987,6 +1310,9 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		return true
 
 	case strings.HasPrefix(input, config.ModelSwitch+" "):
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
 		modelName := strings.TrimPrefix(input, config.ModelSwitch+" ")
 		chatManager.SetCurrentModel(modelName)
 		if !config.MuteNotifications {
@@ -995,6 +1321,9 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		return true
 
 	case input == config.PlatformSwitch:
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
 		result, err := platformManager.SelectPlatform("", "", terminal.FzfSelect)
 		if err != nil {
 			terminal.PrintError(fmt.Sprintf("%v", err))
@@ -1014,6 +1343,9 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		return true
 
 	case strings.HasPrefix(input, config.PlatformSwitch+" "):
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
 		platformName := strings.TrimPrefix(input, config.PlatformSwitch+" ")
 		result, err := platformManager.SelectPlatform(platformName, "", terminal.FzfSelect)
 		if err != nil {
@@ -1034,7 +1366,76 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		return true
 
 	case input == config.AllModels:
-		return handleAllModels(chatManager, platformManager, terminal, state)
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
+		return handleAllModels(platform.ModelFilter{}, chatManager, platformManager, terminal, state)
+
+	case input == config.AllModels+" pull" || strings.HasPrefix(input, config.AllModels+" pull "):
+		return handleOllamaPull(strings.TrimSpace(strings.TrimPrefix(input, config.AllModels+" pull")), platformManager, terminal, state)
+
+	case strings.HasPrefix(input, config.AllModels+" "):
+		if !confirmLockedSwitch(chatManager, terminal, state) {
+			return true
+		}
+		return handleAllModels(platformManager.ParseModelFilter(strings.TrimPrefix(input, config.AllModels)), chatManager, platformManager, terminal, state)
+
+	case input == config.ReplayTo || strings.HasPrefix(input, config.ReplayTo+" "):
+		return handleReplayTo(strings.TrimSpace(strings.TrimPrefix(input, config.ReplayTo)), chatManager, platformManager, terminal, state, noHistory)
+
+	case input == config.ResponseActions:
+		return handleResponseActions(chatManager, platformManager, terminal, state, noHistory, rl)
+
+	case input == config.UseTemplate || strings.HasPrefix(input, config.UseTemplate+" "):
+		return handleUseTemplate(strings.Fields(strings.TrimPrefix(input, config.UseTemplate)), chatManager, platformManager, terminal, state)
+
+	case input == config.LockModel:
+		state.ModelLocked = true
+		terminal.PrintInfo(fmt.Sprintf("locked to %s/%s, model and platform switches now ask first", chatManager.GetCurrentPlatform(), chatManager.GetCurrentModel()))
+		return true
+
+	case input == config.UnlockModel:
+		state.ModelLocked = false
+		terminal.PrintInfo("unlocked, model and platform switches no longer ask")
+		return true
+
+	case input == config.ResetTerminal:
+		if err := terminal.ResetTerminal(); err != nil {
+			terminal.PrintError(fmt.Sprintf("failed to reset terminal: %v", err))
+			return true
+		}
+		terminal.PrintSuccess("terminal reset")
+		return true
+
+	case input == config.ListBookmarks:
+		if err := chatManager.ShowBookmark(terminal); err != nil {
+			terminal.PrintError(err.Error())
+		}
+		return true
+
+	case input == config.AddBookmark || strings.HasPrefix(input, config.AddBookmark+" "):
+		label, err := chatManager.AddBookmark(strings.TrimSpace(strings.TrimPrefix(input, config.AddBookmark)))
+		if err != nil {
+			terminal.PrintError(err.Error())
+		} else {
+			terminal.PrintInfo(fmt.Sprintf("bookmarked: %s", label))
+		}
+		return true
+
+	case input == config.ShowLog || strings.HasPrefix(input, config.ShowLog+" "):
+		count := 0
+		if arg := strings.TrimSpace(strings.TrimPrefix(input, config.ShowLog)); arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				terminal.PrintError(fmt.Sprintf("usage: %s [number of exchanges]", config.ShowLog))
+				return true
+			}
+			count = n
+		}
+		if err := chatManager.ShowTranscript(terminal, count); err != nil {
+			terminal.PrintError(err.Error())
+		}
+		return true
 
 	case input == config.LoadFiles:
 		return handleFileLoad(chatManager, terminal, state, "")
@@ -1109,7 +1510,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 				return true
 			}
 
-			fmt.Printf("\033[94m> %s\033[0m\n", strings.ReplaceAll(userInput, "\n", "\n> "))
+			terminal.EchoInput(userInput)
 
 			chatManager.AddUserMessage(userInput)
 			chatManager.AddToHistoryWithContext("Text editor buffer loaded", "", userInput)
@@ -1132,9 +1533,16 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 			return true
 		}
 
-		fmt.Printf("\033[94m> %s\033[0m\n", strings.ReplaceAll(userInput, "\n", "\n> "))
+		terminal.EchoInput(userInput)
+
+		if handleDuplicatePrompt(userInput, chatManager, terminal, state) {
+			return true
+		}
 
+		userInput = chatManager.ExpandMentions(terminal, userInput)
+		userInput = chatManager.InterpolateShell(terminal, userInput)
 		chatManager.AddUserMessage(userInput)
+		chatManager.PrepareContext(terminal)
 
 		var loadingDone chan bool
 		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
@@ -1142,7 +1550,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 			go terminal.ShowLoadingAnimation("Thinking", loadingDone)
 		}
 
-		response, err := platformManager.SendChatRequest(chatManager.GetMessages(), chatManager.GetCurrentModel(), &state.StreamingCancel, &state.IsStreaming)
+		response, err := chatManager.SendWithContextRetry(platformManager, terminal)
 
 		if loadingDone != nil {
 			loadingDone <- true
@@ -1150,7 +1558,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 
 		if err != nil {
 			chatManager.RemovePendingUserMessage(userInput)
-			if err.Error() == "request was interrupted" {
+			if requestNotSent(err) {
 				return true
 			}
 			terminal.PrintError(fmt.Sprintf("%v", err))
@@ -1158,11 +1566,13 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 
 		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
-			fmt.Printf("\033[92m%s\033[0m\n", response)
+			fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
+			platformManager.PrintLastLogprobs()
 		}
 
 		chatManager.AddAssistantMessage(response)
 		chatManager.AddToHistory(userInput, response)
+		printResponseFooter(chatManager, terminal, state.Config)
 		return true
 
 	case input == config.ExportChat || strings.HasPrefix(input, config.ExportChat+" "):
@@ -1171,12 +1581,47 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		if strings.HasPrefix(input, config.ExportChat+" ") {
 			targetFile = strings.TrimSpace(strings.TrimPrefix(input, config.ExportChat+" "))
 		}
+
+		// Filter flags export the matching exchanges directly, without the pickers
+		if strings.HasPrefix(targetFile, "-") {
+			opts, err := parseExportArgs(strings.Fields(targetFile))
+			if err != nil {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+				return true
+			}
+			if opts.filtered {
+				path, err := exportFilteredChat(chatManager, opts)
+				if err != nil {
+					terminal.PrintError(fmt.Sprintf("error exporting chat: %v", err))
+				} else {
+					terminal.PrintInfo(fmt.Sprintf("exported to %s", path))
+				}
+				return true
+			}
+		}
+
 		err := handleExportChatInteractive(chatManager, terminal, state, targetFile)
 		if err != nil {
 			terminal.PrintError(fmt.Sprintf("error exporting chat: %v", err))
 		}
 		return true
 
+	case input == config.SaveCodeBlock || strings.HasPrefix(input, config.SaveCodeBlock+" ") || saveShortcutPattern.MatchString(input):
+		n, path, ok := parseSaveCodeBlock(input, config.SaveCodeBlock)
+		if !ok {
+			terminal.PrintError(fmt.Sprintf("usage: %s [block number] [path], or !s<block number> [path]", config.SaveCodeBlock))
+			return true
+		}
+		savedPath, err := chatManager.SaveCodeBlock(n, path, func(existing string) bool {
+			return terminal.Confirm(fmt.Sprintf("overwrite %s?", existing))
+		})
+		if err != nil {
+			terminal.PrintError(err.Error())
+		} else {
+			terminal.PrintSuccess(savedPath)
+		}
+		return true
+
 	case input == config.Backtrack:
 		backtrackedCount, err := chatManager.BacktrackHistory(terminal)
 		if err != nil {
@@ -1186,107 +1631,155 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 		return true
 
-	case input == config.AnswerSearch || strings.HasPrefix(input, config.AnswerSearch+" "):
-		if !config.SaveAllSessions {
-			terminal.PrintError("session search requires save_all_sessions to be enabled in config")
+	case input == config.RateSession || strings.HasPrefix(input, config.RateSession+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [1-5] - rate session for dataset exports (0 clears)\033[0m\n", config.RateSession)
 			return true
 		}
+		return handleRateSession(strings.TrimSpace(strings.TrimPrefix(input, config.RateSession)), chatManager, terminal, state, noHistory)
 
-		args := strings.Fields(strings.TrimPrefix(input, config.AnswerSearch))
-		session, err := chatManager.SearchSessions(terminal, args)
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("%v", err))
+	case input == config.TagExchange || strings.HasPrefix(input, config.TagExchange+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [name] - tag last exchange and session (favorite if no name, -name removes)\033[0m\n", config.TagExchange)
 			return true
 		}
+		return handleTagExchange(strings.Fields(strings.TrimPrefix(input, config.TagExchange)), chatManager, terminal, state, noHistory)
 
-		// Restore the session
-		chatManager.RestoreSessionState(session)
-		chatManager.ForkSessionOnNextSave()
-
-		// Populate readline history with the loaded session's user prompts
-		if rl != nil {
-			for _, entry := range session.ChatHistory {
-				if entry.User != "" && entry.User != state.Config.SystemPrompt {
-					_ = rl.SaveHistory(entry.User)
-				}
-			}
+	case input == config.AddNote || strings.HasPrefix(input, config.AddNote+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [text|clear] - add a note to the session, list notes without text\033[0m\n", config.AddNote)
+			return true
 		}
+		return handleNote(strings.TrimSpace(strings.TrimPrefix(input, config.AddNote)), chatManager, terminal, state, noHistory)
 
-		// Re-initialize platform client
-		err = platformManager.Initialize()
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error initializing client: %v", err))
+	case input == config.EditStopSequences || strings.HasPrefix(input, config.EditStopSequences+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [seq|clear] - set stop sequences for this session (\\n escapes allowed)\033[0m\n", config.EditStopSequences)
 			return true
 		}
+		return handleStopSequences(strings.TrimSpace(strings.TrimPrefix(input, config.EditStopSequences)), terminal, state)
 
-		// Print session info and conversation
-		fmt.Printf("\033[91m%s UTC (%s)\033[0m\n", time.Unix(session.Timestamp, 0).UTC().Format("2006-01-02 15:04:05"), filepath.Base(session.SourceFile))
-
-		// Print the entire conversation history
-		for _, entry := range session.ChatHistory {
-			if entry.User == state.Config.SystemPrompt {
-				continue // Skip system prompt
-			}
-			// Print user message
-			if entry.User != "" {
-				fmt.Printf("\033[94muser:\033[0m %s\n", entry.User)
-			}
-			// Print bot response
-			if entry.Bot != "" {
-				fmt.Printf("\033[92m%s\033[0m\n", entry.Bot)
-			}
+	case input == config.BigFile || strings.HasPrefix(input, config.BigFile+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [path|clear] - index a huge file and answer from relevant chunks\033[0m\n", config.BigFile)
+			return true
 		}
+		return handleBigFile(strings.TrimSpace(strings.TrimPrefix(input, config.BigFile)), chatManager, terminal)
 
-		return true
-
-	case input == config.ScrapeURL:
+	case input == config.Retrieve || strings.HasPrefix(input, config.Retrieve+" "):
 		if fromHelp {
-			fmt.Printf("\033[93m%s [url] - scrape URL(s)\033[0m\n", config.ScrapeURL)
+			fmt.Printf("\033[93m%s [question] - answer from the ch --index of this directory\033[0m\n", config.Retrieve)
 			return true
 		}
+		return handleRetrieve(strings.TrimSpace(strings.TrimPrefix(input, config.Retrieve)), chatManager, platformManager, terminal, state)
 
-		// Extract all URLs from both chat history and messages
-		historyURLs := terminal.ExtractURLsFromChatHistory(chatManager.GetChatHistory())
-		messageURLs := terminal.ExtractURLsFromMessages(chatManager.GetMessages())
+	case input == config.ApplyPatches || strings.HasPrefix(input, config.ApplyPatches+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [request] - ask for edits to existing files, or apply the last response's edits, after a diff preview\033[0m\n", config.ApplyPatches)
+			return true
+		}
+		request := strings.TrimSpace(strings.TrimPrefix(input, config.ApplyPatches))
+		if request != "" && !sendPrompt(chat.WithApplyInstructions(request), request, chatManager, platformManager, terminal, state) {
+			return true
+		}
+		handleApplyPatches(chatManager, terminal)
+		return true
 
-		// Combine and deduplicate URLs while preserving order
-		seen := make(map[string]bool)
-		var allURLs []string
-		for _, url := range historyURLs {
-			if !seen[url] {
-				allURLs = append(allURLs, url)
-				seen[url] = true
-			}
+	case input == config.EditFile || strings.HasPrefix(input, config.EditFile+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s <file> <instruction> - have the model edit a file, preview the diff, and write it back with a .bak backup\033[0m\n", config.EditFile)
+			return true
 		}
-		for _, url := range messageURLs {
-			if !seen[url] {
-				allURLs = append(allURLs, url)
-				seen[url] = true
-			}
+		return handleEdit(strings.TrimSpace(strings.TrimPrefix(input, config.EditFile)), chatManager, platformManager, terminal, state)
+
+	case input == config.Remember || strings.HasPrefix(input, config.Remember+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s <fact> - save a fact or preference recalled in future sessions (needs memory=true)\033[0m\n", config.Remember)
+			return true
 		}
+		return handleRemember(strings.TrimSpace(strings.TrimPrefix(input, config.Remember)), chatManager, terminal, state)
 
-		if len(allURLs) == 0 {
-			terminal.PrintError("no URLs found in chat history")
+	case input == config.Memories || strings.HasPrefix(input, config.Memories+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [clear] - review long-term memories and pick ones to delete\033[0m\n", config.Memories)
 			return true
 		}
+		return handleMemories(strings.TrimSpace(strings.TrimPrefix(input, config.Memories)), terminal)
 
-		// Let user select URLs using fzf with multi-select (tab key)
-		selectedURLs, err := terminal.FzfMultiSelect(allURLs, "select urls to scrape (tab=multi): ")
-		if err != nil {
-			terminal.PrintError(fmt.Sprintf("error selecting URLs: %v", err))
+	case input == config.LiveFiles || strings.HasPrefix(input, config.LiveFiles+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [path|clear] - load a file and refresh it in context whenever it changes on disk\033[0m\n", config.LiveFiles)
+			return true
+		}
+		return handleLiveFiles(strings.TrimSpace(strings.TrimPrefix(input, config.LiveFiles)), chatManager, terminal)
+
+	case input == config.RemoteLoad || strings.HasPrefix(input, config.RemoteLoad+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [user@]host:path - load a remote file or directory listing over ssh\033[0m\n", config.RemoteLoad)
+			return true
+		}
+		return handleRemoteLoad(strings.TrimSpace(strings.TrimPrefix(input, config.RemoteLoad)), chatManager, terminal)
+
+	case input == config.LoadRows || strings.HasPrefix(input, config.LoadRows+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s <n|start-end> [sheet] [file] - load rows of the last summarized CSV/XLSX file (or the given one) into context\033[0m\n", config.LoadRows)
+			return true
+		}
+		return handleLoadRows(strings.Fields(strings.TrimPrefix(input, config.LoadRows)), chatManager, terminal)
+
+	case input == config.DBQuery || strings.HasPrefix(input, config.DBQuery+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s <sqlite file> <sql> - run a read-only query and add the results to context as a markdown table\033[0m\n", config.DBQuery)
+			return true
+		}
+		return handleDBQuery(strings.TrimPrefix(input, config.DBQuery), chatManager, terminal)
+
+	case input == config.EditHeaders || strings.HasPrefix(input, config.EditHeaders+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [Name: value|Name:|clear|save] - set, remove, or save extra HTTP headers for this platform\033[0m\n", config.EditHeaders)
 			return true
 		}
+		return handleHeaders(strings.TrimSpace(strings.TrimPrefix(input, config.EditHeaders)), platformManager, terminal, state)
 
+	case input == config.EditRedactions || strings.HasPrefix(input, config.EditRedactions+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [find => replace|re:pattern => replace|clear] - redact exported content for this session\033[0m\n", config.EditRedactions)
+			return true
+		}
+		return handleRedactions(strings.TrimSpace(strings.TrimPrefix(input, config.EditRedactions)), terminal, state)
+
+	case input == config.AnswerSearch || strings.HasPrefix(input, config.AnswerSearch+" "):
+		if fromHelp {
+			fmt.Printf("\033[93m%s [filter] [--exact] - search past answers\033[0m\n", config.AnswerSearch)
+			return true
+		}
+		args := strings.Fields(strings.TrimPrefix(input, config.AnswerSearch))
+		return handleAnswerSearch(args, chatManager, platformManager, terminal, state, rl)
+
+	case input == config.ScrapeURL:
+		if fromHelp {
+			fmt.Printf("\033[93m%s [--md|--text] [url] - scrape URL(s)\033[0m\n", config.ScrapeURL)
+			return true
+		}
+
+		selectedURLs := pickURLsFromHistory(chatManager, terminal)
 		if len(selectedURLs) == 0 {
 			return true
 		}
 
 		// Scrape the selected URLs
-		return handleScrapeURLs(selectedURLs, chatManager, terminal, state)
+		return handleScrapeURLs(selectedURLs, config.ScrapeFormat, chatManager, terminal, state)
 
 	case strings.HasPrefix(input, config.ScrapeURL+" "):
-		urls := strings.Fields(strings.TrimPrefix(input, config.ScrapeURL+" "))
-		return handleScrapeURLs(urls, chatManager, terminal, state)
+		urls, format := parseScrapeArgs(strings.Fields(strings.TrimPrefix(input, config.ScrapeURL+" ")), config.ScrapeFormat)
+		if len(urls) == 0 {
+			// Only a format flag was given, so pick URLs from history
+			urls = pickURLsFromHistory(chatManager, terminal)
+			if len(urls) == 0 {
+				return true
+			}
+		}
+		return handleScrapeURLs(urls, format, chatManager, terminal, state)
 
 	case input == config.WebSearch:
 		if fromHelp {
@@ -1314,11 +1807,36 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 
 		// Use the selected sentence as the search query
-		return handleWebSearch(selectedSentence, chatManager, terminal, state)
+		return handleWebSearch(selectedSentence, chatManager, platformManager, terminal, state)
 
 	case strings.HasPrefix(input, config.WebSearch+" "):
 		query := strings.TrimPrefix(input, config.WebSearch+" ")
-		return handleWebSearch(query, chatManager, terminal, state)
+		return handleWebSearch(query, chatManager, platformManager, terminal, state)
+
+	case input == config.DeepSearch:
+		if fromHelp {
+			fmt.Printf("\033[93m%s [query] - search, read the top pages, and answer with citations\033[0m\n", config.DeepSearch)
+			return true
+		}
+
+		allSentences := terminal.ExtractSentencesFromChatHistory(chatManager.GetChatHistory(), chatManager.GetMessages())
+		if len(allSentences) == 0 {
+			terminal.PrintError("no sentences found in chat history")
+			return true
+		}
+		selectedSentence, err := terminal.FzfSelect(allSentences, "select sentence to search: ")
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("error selecting sentence: %v", err))
+			return true
+		}
+		if selectedSentence == "" {
+			return true
+		}
+		return handleDeepSearch(selectedSentence, chatManager, platformManager, terminal, state)
+
+	case strings.HasPrefix(input, config.DeepSearch+" "):
+		query := strings.TrimPrefix(input, config.DeepSearch+" ")
+		return handleDeepSearch(query, chatManager, platformManager, terminal, state)
 
 	case input == config.CopyToClipboard:
 		err := terminal.CopyResponsesInteractive(chatManager.GetChatHistory(), chatManager.GetMessages())
@@ -1336,6 +1854,23 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 		return true
 
+	case input == config.ClipboardHistory || strings.HasPrefix(input, config.ClipboardHistory+" "):
+		switch arg := strings.TrimSpace(strings.TrimPrefix(input, config.ClipboardHistory)); arg {
+		case "":
+			if err := terminal.CopyFromClipboardHistory(); err != nil {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+			}
+		case "clear":
+			if err := ui.ClearClipboardHistory(); err != nil {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+			} else {
+				terminal.PrintInfo("clipboard history cleared")
+			}
+		default:
+			terminal.PrintError(fmt.Sprintf("usage: %s [clear]", config.ClipboardHistory))
+		}
+		return true
+
 	case input == config.MultiLine:
 		var lines []string
 		terminal.PrintInfo("multi-line mode (exit with '\\')")
@@ -1370,7 +1905,14 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 			return true
 		}
 
+		if handleDuplicatePrompt(fullInput, chatManager, terminal, state) {
+			return true
+		}
+
+		fullInput = chatManager.ExpandMentions(terminal, fullInput)
+		fullInput = chatManager.InterpolateShell(terminal, fullInput)
 		chatManager.AddUserMessage(fullInput)
+		chatManager.PrepareContext(terminal)
 
 		// Start loading animation for non-streaming models
 		var loadingDone chan bool
@@ -1379,7 +1921,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 			go terminal.ShowLoadingAnimation("Thinking", loadingDone)
 		}
 
-		response, err := platformManager.SendChatRequest(chatManager.GetMessages(), chatManager.GetCurrentModel(), &state.StreamingCancel, &state.IsStreaming)
+		response, err := chatManager.SendWithContextRetry(platformManager, terminal)
 
 		// Stop loading animation if it was started
 		if loadingDone != nil {
@@ -1388,7 +1930,7 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 
 		if err != nil {
 			chatManager.RemovePendingUserMessage(fullInput)
-			if err.Error() == "request was interrupted" {
+			if requestNotSent(err) {
 				return true
 			}
 			terminal.PrintError(fmt.Sprintf("%v", err))
@@ -1396,16 +1938,18 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 		}
 
 		// Print response for non-streaming models
-		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
+		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) && !state.Config.StreamJSON {
 			if state.Config.IsPipedOutput {
 				fmt.Printf("%s\n", response)
 			} else {
-				fmt.Printf("\033[92m%s\033[0m\n", response)
+				fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
 			}
+			platformManager.PrintLastLogprobs()
 		}
 
 		chatManager.AddAssistantMessage(response)
 		chatManager.AddToHistory(fullInput, response)
+		printResponseFooter(chatManager, terminal, state.Config)
 		return true
 
 	case strings.HasPrefix(input, "!!"):
@@ -1427,6 +1971,9 @@ func handleSpecialCommandsInternal(input string, chatManager *chat.Manager, plat
 	}
 }
 
+// deepLoadOption is the !l picker entry that relists a shallow directory at full depth
+const deepLoadOption = ">deep"
+
 func handleFileLoad(chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState, dirPath string) bool {
 	var files []string
 	var err error
@@ -1440,10 +1987,6 @@ func handleFileLoad(chatManager *chat.Manager, terminal *ui.Terminal, state *typ
 			terminal.PrintError(fmt.Sprintf("error reading current directory: %v", err))
 			return true
 		}
-		if len(files) == 0 {
-			terminal.PrintError("no files found in current directory")
-			return true
-		}
 	} else {
 		// Use specified directory
 		if _, err := os.Stat(dirPath); os.IsNotExist(err) {
@@ -1456,57 +1999,129 @@ func handleFileLoad(chatManager *chat.Manager, terminal *ui.Terminal, state *typ
 			terminal.PrintError(fmt.Sprintf("error reading directory %s: %v", dirPath, err))
 			return true
 		}
-		if len(files) == 0 {
+	}
+
+	// Recently loaded paths come first, so an empty directory can still offer them
+	recent := terminal.RecentLoadOptions()
+	if len(files) == 0 && len(recent) == 0 {
+		if dirPath == "" {
+			terminal.PrintError("no files found in current directory")
+		} else {
 			terminal.PrintError(fmt.Sprintf("no files found in directory: %s", dirPath))
-			return true
 		}
+		return true
 	}
 
-	// Check if this is a shallow load directory and inform the user
-	if config.IsShallowLoadDir(state.Config, targetPath) {
-		terminal.PrintInfo("shallow loading")
+	// Check if this is a shallow load directory and inform the user. The
+	// >deep option lists every level instead.
+	options := append(recent, files...)
+	if depth := config.ShallowLoadDepth(state.Config, targetPath); depth > 0 {
+		terminal.PrintInfo(fmt.Sprintf("shallow loading (depth %d), pick %s to load deep anyway", depth, deepLoadOption))
+		options = append([]string{deepLoadOption}, options...)
 	}
 
-	selections, err := terminal.FzfMultiSelect(files, "files: ")
+	// A path typed into the picker that is not in the list is loaded directly
+	selections, query, err := terminal.FzfMultiSelectOrQuery(options, "files: ")
 	if err != nil {
 		terminal.PrintError(fmt.Sprintf("error selecting files: %v", err))
 		return true
 	}
+	if len(selections) == 0 && query != "" {
+		return handleTypedLoadPath(query, chatManager, terminal, state)
+	}
+
+	if slices.Contains(selections, deepLoadOption) {
+		files, err = terminal.GetDirFilesDeep(targetPath)
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("error reading directory %s: %v", targetPath, err))
+			return true
+		}
+		selections, err = terminal.FzfMultiSelect(files, "files (deep): ")
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("error selecting files: %v", err))
+			return true
+		}
+	}
 
 	if len(selections) == 0 {
 		return true
 	}
 
-	// Resolve full paths if using custom directory
-	var fullPaths []string
-	if dirPath != "" {
-		for _, selection := range selections {
+	// Resolve full paths: recent entries are absolute, the rest are relative
+	// to the listed directory
+	var fullPaths, names []string
+	for _, selection := range selections {
+		if path, ok := ui.RecentLoadPath(selection); ok {
+			fullPaths = append(fullPaths, path)
+			names = append(names, path)
+			continue
+		}
+		if dirPath != "" {
 			fullPaths = append(fullPaths, filepath.Join(dirPath, selection))
+		} else {
+			fullPaths = append(fullPaths, selection)
 		}
-	} else {
-		fullPaths = selections
+		names = append(names, selection)
 	}
 
-	content, err := terminal.LoadFileContent(fullPaths)
+	content, err := terminal.LoadFileContentWithPicker(fullPaths)
 	if err != nil {
 		terminal.PrintError(fmt.Sprintf("error loading content: %v", err))
 		return true
 	}
+	content = diffReloadedFiles(chatManager, terminal, content)
 
 	if content != "" {
-		chatManager.AddUserMessage(content)
+		chatManager.AddUserMessageWithImages(content, ui.ImagePaths(fullPaths))
 		if dirPath != "" {
-			historySummary := fmt.Sprintf("Loaded from %s: %s", dirPath, strings.Join(selections, ", "))
+			historySummary := fmt.Sprintf("Loaded from %s: %s", dirPath, strings.Join(names, ", "))
 			chatManager.AddToHistoryWithContext(historySummary, "", content)
 		} else {
-			historySummary := fmt.Sprintf("Loaded: %s", strings.Join(selections, ", "))
+			historySummary := fmt.Sprintf("Loaded: %s", strings.Join(names, ", "))
 			chatManager.AddToHistoryWithContext(historySummary, "", content)
 		}
+		_ = terminal.RecordRecentLoads(fullPaths)
+	}
+
+	return true
+}
+
+// handleTypedLoadPath loads a path typed into the !l picker. Directories
+// open their own picker, files are loaded directly, and ~ is expanded.
+func handleTypedLoadPath(query string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	path, ok := ui.ResolveTypedPath(query)
+	if !ok {
+		terminal.PrintError(fmt.Sprintf("no such file or directory: %s", query))
+		return true
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return handleFileLoad(chatManager, terminal, state, path)
 	}
 
+	content, err := terminal.LoadFileContentWithPicker([]string{path})
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error loading content: %v", err))
+		return true
+	}
+	content = diffReloadedFiles(chatManager, terminal, content)
+	if content != "" {
+		chatManager.AddUserMessageWithImages(content, ui.ImagePaths([]string{path}))
+		chatManager.AddToHistoryWithContext(fmt.Sprintf("Loaded: %s", path), "", content)
+		_ = terminal.RecordRecentLoads([]string{path})
+	}
 	return true
 }
 
+// diffReloadedFiles swaps files !l loaded earlier in the conversation for
+// a diff against that copy, telling the user which ones were
+func diffReloadedFiles(chatManager *chat.Manager, terminal *ui.Terminal, content string) string {
+	content, reloaded := chatManager.DiffReloadedFiles(content)
+	if len(reloaded) > 0 {
+		terminal.PrintInfo(fmt.Sprintf("already in context, sending only changes: %s", strings.Join(reloaded, ", ")))
+	}
+	return content
+}
+
 func handleCodeDump(chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
 	codedump, err := terminal.CodeDump()
 	if err != nil {
@@ -1580,10 +2195,16 @@ func handleShellCommand(command string, chatManager *chat.Manager, terminal *ui.
 		return true
 	}
 
-	// Set up signal handling for command interruption
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-	defer signal.Stop(sigChan)
+	// Ctrl+C reaches the global handler, which cancels the command through
+	// CommandCancel instead of exiting ch
+	interrupted := make(chan struct{})
+	var interruptOnce sync.Once
+	state.CommandCancel = func() { interruptOnce.Do(func() { close(interrupted) }) }
+	state.IsExecutingCommand = true
+	defer func() {
+		state.IsExecutingCommand = false
+		state.CommandCancel = nil
+	}()
 
 	// Capture output while streaming it live
 	var outputBuffer strings.Builder
@@ -1619,7 +2240,7 @@ func handleShellCommand(command string, chatManager *chat.Manager, terminal *ui.
 
 	var cmdErr error
 	select {
-	case <-sigChan:
+	case <-interrupted:
 		// Interrupt received - kill the command
 		if err := cmd.Process.Kill(); err != nil {
 			terminal.PrintError(fmt.Sprintf("failed to kill command: %v", err))
@@ -1655,7 +2276,7 @@ func handleShellCommand(command string, chatManager *chat.Manager, terminal *ui.
 
 	if saveToHistory {
 		chatManager.AddUserMessage(formattedContent)
-		chatManager.AddToHistoryWithContext(fmt.Sprintf("!x %s", command), "Command executed and output added to context", formattedContent)
+		chatManager.AddToHistoryWithContext(fmt.Sprintf("!x %s", command), chat.CommandOutputNote, formattedContent)
 	}
 
 	return true
@@ -1694,6 +2315,53 @@ func handleExportCodeBlocks(chatManager *chat.Manager, terminal *ui.Terminal) er
 	return nil
 }
 
+// handleEdit asks the model to change a file as instructed, then previews
+// the diff and writes the file back once confirmed
+func handleEdit(args string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	path, instruction, _ := strings.Cut(args, " ")
+	instruction = strings.TrimSpace(instruction)
+	if path == "" || instruction == "" {
+		terminal.PrintError(fmt.Sprintf("usage: %s <file> <instruction>", state.Config.EditFile))
+		return true
+	}
+	content, err := chat.ReadEditableFile(path)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+
+	if !sendPrompt(chat.EditPrompt(path, content, instruction), fmt.Sprintf("Edit %s: %s", path, instruction), chatManager, platformManager, terminal, state) {
+		return true
+	}
+	written, err := chatManager.ApplyEdit(terminal, path)
+	switch {
+	case err != nil:
+		terminal.PrintError(fmt.Sprintf("error applying edits: %v", err))
+	case !written:
+		terminal.PrintInfo("edits not applied")
+	default:
+		terminal.PrintInfo(fmt.Sprintf("wrote %s (original in %s.bak)", path, path))
+	}
+	return true
+}
+
+// handleApplyPatches previews the edits in the last response and writes them
+// once confirmed
+func handleApplyPatches(chatManager *chat.Manager, terminal *ui.Terminal) {
+	written, err := chatManager.ApplyPatches(terminal)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error applying edits: %v", err))
+		return
+	}
+	if len(written) == 0 {
+		terminal.PrintInfo("edits not applied")
+		return
+	}
+	for _, path := range written {
+		fmt.Println(path)
+	}
+}
+
 func handleExportChatInteractive(chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState, targetFile string) error {
 	filePath, err := chatManager.ExportChatInteractive(terminal, targetFile)
 	if err != nil {
@@ -1712,6 +2380,12 @@ func handleShowState(chatManager *chat.Manager, terminal *ui.Terminal, state *ty
 	currentDate := time.Now().Format("2006-01-02")
 	currentTime := time.Now().Format("15:04:05 MST")
 
+	// Get requests made this run, before platform is shadowed below
+	usage := ""
+	if total := chatManager.SessionUsage(); total.Requests > 0 {
+		usage = platform.FormatUsage(total)
+	}
+
 	// Get platform and model
 	platform := chatManager.GetCurrentPlatform()
 	model := chatManager.GetCurrentModel()
@@ -1737,15 +2411,12 @@ func handleShowState(chatManager *chat.Manager, terminal *ui.Terminal, state *ty
 		totalContent += message.Content + " "
 	}
 
-	encoding := tokenizer.Cl100kBase
-	enc, err := tokenizer.Get(encoding)
-	if err != nil {
-		return fmt.Errorf("error getting tokenizer: %v", err)
-	}
+	tokenCount := tokens.Count(model, totalContent)
 
-	tokenCount, err := enc.Count(totalContent)
-	if err != nil {
-		return fmt.Errorf("error counting tokens: %v", err)
+	spend := formatSpend(chatManager.SessionSpend(), state.Config)
+	capabilities := ""
+	if caps, ok := chatManager.ModelCapabilities(); ok {
+		capabilities = caps.String()
 	}
 
 	// Print the state
@@ -1754,25 +2425,59 @@ func handleShowState(chatManager *chat.Manager, terminal *ui.Terminal, state *ty
 		fmt.Printf("%s %s\n", "date:", combinedDateTime)
 		fmt.Printf("%s %s\n", "platform:", platform)
 		fmt.Printf("%s %s\n", "model:", model)
+		if capabilities != "" {
+			fmt.Printf("%s %s\n", "capabilities:", capabilities)
+		}
 		if sessionFile != "" {
 			fmt.Printf("%s %s\n", "file:", sessionFile)
 		}
 		fmt.Printf("%s %d\n", "chats:", chatCount)
 		fmt.Printf("%s %d\n", "tokens:", tokenCount)
+		if usage != "" {
+			fmt.Printf("%s %s\n", "usage:", usage)
+		}
+		if spend != "" {
+			fmt.Printf("%s %s\n", "spend:", spend)
+		}
 	} else {
 		fmt.Printf("\033[96m%s\033[0m \033[93m%s\033[0m\n", "date:", combinedDateTime)
 		fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", "platform:", platform)
 		fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", "model:", model)
+		if capabilities != "" {
+			fmt.Printf("\033[96m%s\033[0m \033[90m%s\033[0m\n", "capabilities:", capabilities)
+		}
 		if sessionFile != "" {
 			fmt.Printf("\033[96m%s\033[0m \033[93m%s\033[0m\n", "file:", sessionFile)
 		}
 		fmt.Printf("\033[96m%s\033[0m \033[92m%d\033[0m\n", "chats:", chatCount)
 		fmt.Printf("\033[96m%s\033[0m \033[91m%d\033[0m\n", "tokens:", tokenCount)
+		if usage != "" {
+			fmt.Printf("\033[96m%s\033[0m \033[92m%s\033[0m\n", "usage:", usage)
+		}
+		if spend != "" {
+			fmt.Printf("\033[96m%s\033[0m \033[93m%s\033[0m\n", "spend:", spend)
+		}
 	}
 
 	return nil
 }
 
+// formatSpend describes estimated spend against the configured limits for
+// >state, or returns "" when there are no limits and nothing was spent
+func formatSpend(sessionSpend float64, cfg *types.Config) string {
+	if cfg.MaxSessionCost <= 0 && cfg.MaxDailyCost <= 0 && sessionSpend == 0 {
+		return ""
+	}
+	parts := []string{fmt.Sprintf("$%.4f session", sessionSpend)}
+	if cfg.MaxSessionCost > 0 {
+		parts[0] += fmt.Sprintf(" of $%.2f", cfg.MaxSessionCost)
+	}
+	if cfg.MaxDailyCost > 0 {
+		parts = append(parts, fmt.Sprintf("$%.4f today of $%.2f", platform.DailySpend(), cfg.MaxDailyCost))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func handleTokenCount(filePath string, model string, terminal *ui.Terminal, state *types.AppState, pipedInput string) error {
 	var content string
 	sourceLabel := filePath
@@ -1805,41 +2510,19 @@ func handleTokenCount(filePath string, model string, terminal *ui.Terminal, stat
 		}
 	}
 
-	// Map model names to tokenizer encodings
-	var encoding tokenizer.Encoding
-	switch {
-	case strings.Contains(strings.ToLower(targetModel), "gpt-4"):
-		encoding = tokenizer.Cl100kBase
-	case strings.Contains(strings.ToLower(targetModel), "gpt-3.5"):
-		encoding = tokenizer.Cl100kBase
-	case strings.Contains(strings.ToLower(targetModel), "gpt-2"):
-		encoding = tokenizer.R50kBase
-	case strings.Contains(strings.ToLower(targetModel), "claude"):
-		encoding = tokenizer.Cl100kBase // Use cl100k_base as approximation for Claude
-	default:
-		encoding = tokenizer.Cl100kBase // Default to cl100k_base
-	}
-
-	// Get tokenizer
-	enc, err := tokenizer.Get(encoding)
-	if err != nil {
-		return fmt.Errorf("error getting tokenizer: %v", err)
-	}
-
-	// Count tokens without materializing the token slice
-	tokenCount, err := enc.Count(content)
-	if err != nil {
-		return fmt.Errorf("error counting tokens: %v", err)
-	}
+	tok := tokens.For(targetModel)
+	tokenCount := tok.Count(content)
 
 	// Print results with colors matching the project's style
 	if state.Config.IsPipedOutput {
 		fmt.Printf("%s %s\n", "file:", sourceLabel)
 		fmt.Printf("%s %s\n", "model:", targetModel)
+		fmt.Printf("%s %s\n", "tokenizer:", tok.Name())
 		fmt.Printf("%s %d\n", "tokens:", tokenCount)
 	} else {
 		fmt.Printf("\033[96m%s\033[0m %s\n", "file:", sourceLabel)
 		fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", "model:", targetModel)
+		fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", "tokenizer:", tok.Name())
 		fmt.Printf("\033[96m%s\033[0m \033[91m%d\033[0m\n", "tokens:", tokenCount)
 	}
 
@@ -1870,7 +2553,8 @@ func splitByDelimiters(input string) []string {
 // context: the loaded/scraped/searched content
 // prompt: the user's query/instruction
 func handleFlagWithPrompt(chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, context string, prompt string, noHistory bool) error {
-	// Combine context and prompt for the message
+	// Combine context and prompt for the message, distilling large context first
+	context = chatManager.CompressContext(terminal, context, prompt)
 	combinedMessage := context + "\n\n" + prompt
 
 	chatManager.AddUserMessage(combinedMessage)
@@ -1882,7 +2566,7 @@ func handleFlagWithPrompt(chatManager *chat.Manager, platformManager *platform.M
 		go terminal.ShowLoadingAnimation("thinking", loadingDone)
 	}
 
-	response, err := platformManager.SendChatRequest(chatManager.GetMessages(), chatManager.GetCurrentModel(), &state.StreamingCancel, &state.IsStreaming)
+	response, err := chatManager.SendWithContextRetry(platformManager, terminal)
 
 	// Stop loading animation if it was started
 	if loadingDone != nil {
@@ -1891,19 +2575,20 @@ func handleFlagWithPrompt(chatManager *chat.Manager, platformManager *platform.M
 
 	if err != nil {
 		chatManager.RemovePendingUserMessage(combinedMessage)
-		if err.Error() == "request was interrupted" {
+		if requestNotSent(err) {
 			return nil
 		}
 		return err
 	}
 
 	// Print response for non-streaming models
-	if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
+	if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) && !state.Config.StreamJSON {
 		if state.Config.IsPipedOutput {
 			fmt.Printf("%s\n", response)
 		} else {
-			fmt.Printf("\033[92m%s\033[0m\n", response)
+			fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
 		}
+		platformManager.PrintLastLogprobs()
 	}
 
 	chatManager.AddAssistantMessage(response)
@@ -1920,13 +2605,13 @@ func handleFlagWithPrompt(chatManager *chat.Manager, platformManager *platform.M
 }
 
 // handleScrapeURLs handles the !s command for scraping URLs
-func handleScrapeURLs(urls []string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+func handleScrapeURLs(urls []string, format string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
 	if len(urls) == 0 {
 		terminal.PrintError("no URLs provided")
 		return true
 	}
 
-	content, err := terminal.ScrapeURLs(urls)
+	content, err := terminal.ScrapeURLsWithFormat(urls, format)
 	if err != nil {
 		terminal.PrintError(fmt.Sprintf("error scraping URLs: %v", err))
 		return true
@@ -1941,12 +2626,71 @@ func handleScrapeURLs(urls []string, chatManager *chat.Manager, terminal *ui.Ter
 	return true
 }
 
-// handleWebSearch handles the !w command for web search
-func handleWebSearch(query string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+// parseScrapeArgs separates the --md/--text output format flags from the URLs
+// given to !s, falling back to the configured format
+func parseScrapeArgs(args []string, defaultFormat string) ([]string, string) {
+	format := defaultFormat
+	var urls []string
+	for _, arg := range args {
+		switch arg {
+		case "--md", "--markdown":
+			format = "markdown"
+		case "--text":
+			format = "text"
+		default:
+			urls = append(urls, arg)
+		}
+	}
+	return urls, format
+}
+
+// pickURLsFromHistory lets the user fzf multi-select URLs found in the chat history
+func pickURLsFromHistory(chatManager *chat.Manager, terminal *ui.Terminal) []string {
+	// Extract all URLs from both chat history and messages
+	historyURLs := terminal.ExtractURLsFromChatHistory(chatManager.GetChatHistory())
+	messageURLs := terminal.ExtractURLsFromMessages(chatManager.GetMessages())
+
+	// Combine and deduplicate URLs while preserving order
+	seen := make(map[string]bool)
+	var allURLs []string
+	for _, url := range historyURLs {
+		if !seen[url] {
+			allURLs = append(allURLs, url)
+			seen[url] = true
+		}
+	}
+	for _, url := range messageURLs {
+		if !seen[url] {
+			allURLs = append(allURLs, url)
+			seen[url] = true
+		}
+	}
+
+	if len(allURLs) == 0 {
+		terminal.PrintError("no URLs found in chat history")
+		return nil
+	}
+
+	// Let user select URLs using fzf with multi-select (tab key)
+	selectedURLs, err := terminal.FzfMultiSelect(allURLs, "select urls to scrape (tab=multi): ")
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error selecting URLs: %v", err))
+		return nil
+	}
+
+	return selectedURLs
+}
+
+// handleWebSearch handles the !w command for web search, running a deep
+// search instead when web_search_deep is on
+func handleWebSearch(query string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
 	if query == "" {
 		terminal.PrintError("no search query provided")
 		return true
 	}
+	if state.Config.WebSearchDeep {
+		return handleDeepSearch(query, chatManager, platformManager, terminal, state)
+	}
 
 	content, err := terminal.WebSearch(query)
 	if err != nil {
@@ -1963,68 +2707,1619 @@ func handleWebSearch(query string, chatManager *chat.Manager, terminal *ui.Termi
 	return true
 }
 
-// generateUniqueCodeDumpFilename generates a unique filename for code dump with collision detection
-func generateUniqueCodeDumpFilename(currentDir, content string) string {
-	baseHash := chat.GenerateHashFromContent(content, 8)
-	filename := fmt.Sprintf("ch_cd%s.txt", baseHash)
-	fullPath := filepath.Join(currentDir, filename)
+// handleDeepSearch handles the !w! command: it scrapes the top search results
+// and asks the model to answer the query from them with citations
+func handleDeepSearch(query string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	if query == "" {
+		terminal.PrintError("no search query provided")
+		return true
+	}
 
-	// Check if file exists, if not return it
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return filename
+	content, err := terminal.DeepSearch(query)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error searching: %v", err))
+		return true
 	}
 
-	// If file exists, try with different offsets
-	for offset := 1; offset <= 10; offset++ {
-		newHash := chat.GenerateHashFromContentWithOffset(content, 8, offset)
-		filename = fmt.Sprintf("ch_cd%s.txt", newHash)
-		fullPath = filepath.Join(currentDir, filename)
+	sendPrompt(content, fmt.Sprintf("Deep web search: %s", query), chatManager, platformManager, terminal, state)
+	return true
+}
 
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			return filename
-		}
+// handleDatasetExport prints saved sessions matching the rating and tag filters
+// as an OpenAI fine-tuning JSONL or ShareGPT dataset.
+func handleDatasetExport(format string, minRating int, tags string, cfg *types.Config, terminal *ui.Terminal) error {
+	sessions, err := chat.LoadSavedSessions(cfg)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		chat.RedactSession(session, cfg.Redactions)
 	}
 
-	// If still colliding, add a numeric suffix
-	for counter := 1; counter <= 999; counter++ {
-		filename = fmt.Sprintf("ch_cd%s_%03d.txt", baseHash, counter)
-		fullPath = filepath.Join(currentDir, filename)
-
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			return filename
-		}
+	filter := chat.DatasetFilter{MinRating: minRating, Tags: parseTagList(tags)}
+	dataset, count, err := chat.ExportDataset(sessions, strings.ToLower(format), filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("no saved sessions matched the dataset filters")
 	}
 
-	// Fallback to original UUID if everything fails
-	return fmt.Sprintf("ch_cd%s.txt", uuid.New().String())
+	fmt.Print(dataset)
+	return nil
 }
 
-// handleAllModels handles the !o command for selecting from all available models
-func handleAllModels(chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
-	// Create channels for async operation
-	type modelResult struct {
-		models []string
-		err    error
+// handleWorkspaceCommand lists workspaces, maps the current project to a named
+// workspace, or sets the current workspace's default model and system prompt
+func handleWorkspaceCommand(args []string, state *types.AppState, terminal *ui.Terminal) error {
+	if !state.Config.Workspaces {
+		return fmt.Errorf("workspaces are disabled, set \"workspaces\": true in ~/.ch/config.json")
 	}
-	resultChan := make(chan modelResult)
-
-	// Start fetching models in a goroutine
-	go func() {
-		models, err := platformManager.FetchAllModelsAsync()
-		resultChan <- modelResult{models, err}
-	}()
 
-	// Show loading animation
-	done := make(chan bool)
-	go terminal.ShowLoadingAnimation("fetching models", done)
-
-	// Wait for models to be fetched
-	result := <-resultChan
-	done <- true // Stop animation
+	store, err := config.LoadWorkspaceStore()
+	if err != nil {
+		return err
+	}
+	current, root, err := config.CurrentWorkspace(store)
+	if err != nil {
+		return err
+	}
 
-	if result.err != nil {
-		terminal.PrintError(fmt.Sprintf("%v", result.err))
-		return true
+	subcommand := ""
+	if len(args) > 0 {
+		subcommand = args[0]
+		args = args[1:]
+	}
+	value := strings.TrimSpace(strings.Join(args, " "))
+
+	switch subcommand {
+	case "", "list":
+		names, err := config.ListWorkspaces(store)
+		if err != nil {
+			return err
+		}
+		wsDir, err := config.GetWorkspacesDir()
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(names, current) {
+			names = append(names, current)
+		}
+		for _, name := range names {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			line := fmt.Sprintf("%s %s (%d sessions)", marker, name, chat.CountSavedSessions(state.Config, filepath.Join(wsDir, name)))
+			if settings := store.Workspaces[name]; settings.Platform != "" || settings.Model != "" {
+				line += fmt.Sprintf(" %s|%s", settings.Platform, settings.Model)
+			}
+			fmt.Println(line)
+		}
+		return nil
+
+	case "switch":
+		name := value
+		if name == "" {
+			names, err := config.ListWorkspaces(store)
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("no workspaces yet, use: ch ws switch <name>")
+			}
+			name, err = terminal.FzfSelect(names, "workspace: ")
+			if err != nil || name == "" {
+				return nil
+			}
+		}
+		if name == "auto" {
+			delete(store.Projects, root)
+		} else {
+			if name = config.NormalizeWorkspaceName(name); name == "" {
+				return fmt.Errorf("invalid workspace name")
+			}
+			store.Projects[root] = name
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		current, _, _ = config.CurrentWorkspace(store)
+		fmt.Printf("%s -> %s\n", root, current)
+		return nil
+
+	case "model":
+		settings := store.Workspaces[current]
+		if value == "" || value == "clear" {
+			settings.Platform, settings.Model = "", ""
+		} else {
+			platformName, modelName, ok := strings.Cut(value, "|")
+			if !ok || platformName == "" || modelName == "" {
+				return fmt.Errorf("usage: ch ws model platform|model")
+			}
+			if _, exists := state.Config.Platforms[platformName]; !exists && platformName != "openai" {
+				return fmt.Errorf("platform '%s' not found", platformName)
+			}
+			settings.Platform, settings.Model = platformName, modelName
+		}
+		setWorkspaceSettings(store, current, settings)
+		return store.Save()
+
+	case "prompt":
+		settings := store.Workspaces[current]
+		if value == "clear" {
+			value = ""
+		}
+		settings.SystemPrompt = value
+		setWorkspaceSettings(store, current, settings)
+		return store.Save()
+	}
+
+	return fmt.Errorf("unknown workspace command %q (use list, switch, model, or prompt)", subcommand)
+}
+
+// setWorkspaceSettings stores settings for a workspace, dropping the entry once it is empty
+func setWorkspaceSettings(store *config.WorkspaceStore, name string, settings types.WorkspaceSettings) {
+	if settings == (types.WorkspaceSettings{}) {
+		delete(store.Workspaces, name)
+		return
+	}
+	store.Workspaces[name] = settings
+}
+
+// handleClear removes stale temp files and, with "sessions", saved session
+// files, optionally only those older than an age such as 30d or a date. It
+// shows what would be removed and asks before deleting anything.
+// writeUsageSummary appends this run's token usage and cost to
+// ~/.ch/usage.jsonl, warning instead of failing when it cannot
+func writeUsageSummary(chatManager *chat.Manager, terminal *ui.Terminal) {
+	if err := chatManager.WriteUsageSummary(); err != nil {
+		terminal.PrintError(fmt.Sprintf("warning: failed to record usage: %v", err))
+	}
+}
+
+// printSessionArtifacts lists the files ch and its tools wrote this
+// session, so generated files are easy to find after exit
+func printSessionArtifacts(chatManager *chat.Manager, terminal *ui.Terminal) {
+	terminal.PrintArtifacts(chatManager.SessionArtifacts())
+}
+
+// handleUsageReport prints usage recorded in ~/.ch/usage.jsonl by model and
+// by day, only for runs newer than age when one is given
+func handleUsageReport(args []string) error {
+	var since time.Time
+	if len(args) > 1 {
+		return fmt.Errorf("usage: ch --usage [age]")
+	}
+	if len(args) == 1 {
+		parsed, ok := ui.ParseSinceTime(args[0], time.Now())
+		if !ok {
+			return fmt.Errorf("invalid age %q (try 30d, 2w, or 2024-06-01)", args[0])
+		}
+		since = parsed
+	}
+
+	summaries, err := platform.LoadUsageSummaries()
+	if err != nil {
+		return err
+	}
+	fmt.Print(strings.TrimSuffix(platform.UsageReport(summaries, since), "\n") + "\n")
+	return nil
+}
+
+func handleClear(args []string, terminal *ui.Terminal) error {
+	var opts chat.CleanOptions
+	if len(args) > 0 {
+		if args[0] != "sessions" || len(args) > 2 {
+			return fmt.Errorf("usage: ch --clear [sessions [age]]")
+		}
+		opts.Sessions = true
+		if len(args) == 2 {
+			before, ok := ui.ParseSinceTime(args[1], time.Now())
+			if !ok {
+				return fmt.Errorf("invalid age %q (try 30d, 2w, or 2024-06-01)", args[1])
+			}
+			opts.SessionsBefore = before
+		}
+	}
+
+	tmpDir, err := config.GetTempDir()
+	if err != nil {
+		return fmt.Errorf("failed to get temp directory: %v", err)
+	}
+	opts.DryRun = true
+	pending, err := chat.CleanTempDir(tmpDir, opts, time.Now())
+	if err != nil {
+		return fmt.Errorf("error scanning %s: %v", tmpDir, err)
+	}
+	if pending.TempFiles == 0 && pending.Sessions == 0 {
+		terminal.PrintInfo("nothing to clear")
+		return nil
+	}
+
+	// Confirm before clearing (in red, default to No)
+	fmt.Printf("\033[91mdelete %s from %s? (y/N)\033[0m ", pending, tmpDir)
+	var response string
+	_, _ = fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("cancelled")
+		return nil
+	}
+
+	opts.DryRun = false
+	removed, err := chat.CleanTempDir(tmpDir, opts, time.Now())
+	if err != nil {
+		return fmt.Errorf("error clearing temporary files: %v", err)
+	}
+	terminal.PrintInfo(fmt.Sprintf("removed %s", removed))
+	return nil
+}
+
+// handleSessionDB runs maintenance commands on the SQLite session database
+func handleSessionDB(args []string, cfg *types.Config) error {
+	sessionDir, err := config.GetSessionDir(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := chat.OpenSessionDB(sessionDir)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	subcommand := ""
+	if len(args) > 0 {
+		subcommand = args[0]
+		args = args[1:]
+	}
+
+	switch subcommand {
+	case "", "stats":
+		stats, err := db.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("database: %s (%d KB, storage_backend=%s)\n", filepath.Join(sessionDir, chat.SessionDBName), stats.DatabaseSize/1024, cfg.StorageBackend)
+		fmt.Printf("sessions: %d, messages: %d\n", stats.Sessions, stats.Messages)
+		if stats.Sessions > 0 {
+			fmt.Printf("range: %s to %s\n", time.Unix(stats.Oldest, 0).UTC().Format("2006-01-02"), time.Unix(stats.Newest, 0).UTC().Format("2006-01-02"))
+		}
+		for _, usage := range stats.ModelUsage {
+			fmt.Printf("  %s: %d exchanges, ~%d input + ~%d output tokens\n", usage.Model, usage.Exchanges, usage.InputTokens, usage.OutputTokens)
+		}
+		return nil
+
+	case "import":
+		overwrite := slices.Contains(args, "--overwrite")
+		args = slices.DeleteFunc(args, func(arg string) bool { return arg == "--overwrite" })
+		source := sessionDir
+		if len(args) > 0 {
+			source = args[0]
+		}
+		count, err := db.ImportJSON(source, overwrite)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d sessions from %s\n", count, source)
+		if cfg.StorageBackend != chat.StorageSQLite {
+			fmt.Println("set \"storage_backend\": \"sqlite\" in ~/.ch/config.json to use the database")
+		}
+		return nil
+
+	case "export":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: ch db export <dir>")
+		}
+		count, err := db.ExportJSON(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("exported %d sessions to %s\n", count, args[0])
+		return nil
+
+	case "prune":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: ch db prune <age> (e.g. 30d, 8w, 6m, 1y)")
+		}
+		age, err := parseAge(args[0])
+		if err != nil {
+			return err
+		}
+		count, err := db.Prune(time.Now().Add(-age).Unix())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pruned %d sessions older than %s\n", count, args[0])
+		return nil
+
+	case "search":
+		text := strings.TrimSpace(strings.Join(args, " "))
+		if text == "" {
+			return fmt.Errorf("usage: ch db search <text>")
+		}
+		matches, err := db.Search(text, 50)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no messages match %q", text)
+		}
+		for _, match := range matches {
+			preview := strings.ReplaceAll(match.Content, "\n", " ")
+			if len(preview) > 100 {
+				preview = preview[:100] + "..."
+			}
+			fmt.Printf("%s %s %s: %s\n", time.Unix(match.Time, 0).UTC().Format("2006-01-02 15:04"), match.Session, match.Role, preview)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown db command %q (use stats, import, export, prune, or search)", subcommand)
+	}
+}
+
+// parseAge parses ages like 30d, 8w, 6m, and 1y
+func parseAge(value string) (time.Duration, error) {
+	matches := regexp.MustCompile(`^(\d+)([dwmy])$`).FindStringSubmatch(value)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid age %q, use a number followed by d, w, m, or y", value)
+	}
+	n, _ := strconv.Atoi(matches[1])
+	days := map[string]int{"d": 1, "w": 7, "m": 30, "y": 365}[matches[2]]
+	return time.Duration(n*days) * 24 * time.Hour, nil
+}
+
+// summarizeArgs holds the parsed arguments of the summarize subcommand
+type summarizeArgs struct {
+	target string
+	opts   chat.SummarizeOptions
+}
+
+// parseSummarizeArgs parses summarize subcommand arguments. The first
+// positional argument is the target and the rest form an optional focus.
+func parseSummarizeArgs(args []string, cfg *types.Config) (summarizeArgs, error) {
+	parsed := summarizeArgs{}
+	fs := flag.NewFlagSet("summarize", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&parsed.opts.ChunkTokens, "chunk-size", cfg.SummarizeChunkTokens, "Tokens per chunk")
+	fs.IntVar(&parsed.opts.OverlapTokens, "overlap", cfg.SummarizeOverlapTokens, "Tokens shared between chunks")
+	fs.IntVar(&parsed.opts.Parallel, "parallel", cfg.SummarizeParallel, "Chunks summarized at once")
+
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return parsed, fmt.Errorf("summarize: %v", err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	if parsed.opts.ChunkTokens <= 0 {
+		return parsed, fmt.Errorf("--chunk-size must be positive")
+	}
+	if len(positional) > 0 {
+		parsed.target = positional[0]
+		parsed.opts.Instruction = strings.Join(positional[1:], " ")
+	}
+	return parsed, nil
+}
+
+// handleSummarize loads a file, directory, URL, or piped stdin and prints a
+// map-reduce summary, showing chunk progress on stderr
+func handleSummarize(args []string, pipedInput string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) error {
+	parsed, err := parseSummarizeArgs(args, state.Config)
+	if err != nil {
+		return err
+	}
+
+	content := pipedInput
+	if parsed.target != "" {
+		if !terminal.IsURL(parsed.target) {
+			if _, err := os.Stat(parsed.target); err != nil {
+				return fmt.Errorf("file does not exist: %s", parsed.target)
+			}
+		}
+		if content, err = terminal.LoadFileContent([]string{parsed.target}); err != nil {
+			return fmt.Errorf("error loading '%s': %v", parsed.target, err)
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("nothing to summarize: pass a file, directory, or URL, or pipe text to stdin")
+	}
+
+	redraw := terminal.NewRedrawLimiter()
+	summary, err := chatManager.Summarize(content, parsed.opts, func(level, done, total int) {
+		if !redraw.Allow() && done < total {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "\r\033[Ksummarizing: level %d, %d/%d chunks", level, done, total)
+	})
+	fmt.Fprint(os.Stderr, "\r\033[K")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(summary)
+	return nil
+}
+
+// parseTailArgs parses tail subcommand arguments: the file to follow, --ask,
+// --interval, and --max-lines. -f is accepted for familiarity with tail(1);
+// ch tail always follows.
+func parseTailArgs(args []string) (chat.TailOptions, error) {
+	opts := chat.TailOptions{}
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Bool("f", true, "Follow the file")
+	fs.StringVar(&opts.Ask, "ask", "", "What to look for in new lines")
+	fs.DurationVar(&opts.Interval, "interval", 30*time.Second, "How long new lines are batched before asking")
+	fs.IntVar(&opts.MaxLines, "max-lines", 500, "Newest lines sent per batch")
+
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return opts, fmt.Errorf("tail: %v", err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	if len(positional) != 1 {
+		return opts, fmt.Errorf("usage: ch tail [-f] <file> [--ask text] [--interval 30s] [--max-lines 500]")
+	}
+	if opts.Interval <= 0 || opts.MaxLines <= 0 {
+		return opts, fmt.Errorf("--interval and --max-lines must be positive")
+	}
+	opts.Path = positional[0]
+	return opts, nil
+}
+
+// handleTail follows a log file and prints the model's findings about new
+// lines as they come in, until Ctrl+C
+func handleTail(args []string, chatManager *chat.Manager, terminal *ui.Terminal) error {
+	opts, err := parseTailArgs(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	terminal.PrintInfo(fmt.Sprintf("following %s, checking new lines every %s (Ctrl+C to stop)", opts.Path, opts.Interval))
+	err = chatManager.Tail(ctx, opts, func(finding chat.TailFinding) {
+		terminal.PrintInfo(fmt.Sprintf("[%s] finding in the last %d new lines", finding.Time.Format("15:04:05"), finding.Lines))
+		fmt.Println(finding.Text)
+	})
+	if err != nil {
+		return err
+	}
+	terminal.PrintInfo("stopped following " + opts.Path)
+	return nil
+}
+
+// exportOptions holds the parsed arguments of `ch export` and `!e` filters
+type exportOptions struct {
+	filter   chat.ExportFilter
+	format   string
+	filtered bool // any filter or format flag was given
+	target   string
+}
+
+// parseExportArgs parses export filter flags (--since, --role, --last,
+// --strip-context, --notes, --format) given before or after one target argument
+func parseExportArgs(args []string) (exportOptions, error) {
+	opts := exportOptions{}
+	var since string
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&since, "since", "", "Only exchanges since a time (2h, 3d, 1w, a date, or an epoch)")
+	fs.StringVar(&opts.filter.Role, "role", "", "Only the user or assistant side")
+	fs.IntVar(&opts.filter.Last, "last", 0, "Only the last N exchanges")
+	fs.BoolVar(&opts.filter.StripContext, "strip-context", false, "Leave out file loads, scrapes, and command output")
+	fs.BoolVar(&opts.filter.Notes, "notes", false, "Append the session's !note notes")
+	fs.StringVar(&opts.format, "format", chat.ExportText, "Output format (text, markdown, json)")
+
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return opts, fmt.Errorf("export: %v", err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			if opts.target != "" {
+				return opts, fmt.Errorf("export: unexpected argument %q", args[0])
+			}
+			opts.target = args[0]
+			args = args[1:]
+		}
+	}
+	fs.Visit(func(*flag.Flag) { opts.filtered = true })
+
+	if since != "" {
+		cutoff, ok := ui.ParseSinceTime(since, time.Now())
+		if !ok {
+			return opts, fmt.Errorf("export: invalid --since %q (try 2h, 3d, 1w, or 2024-06-01)", since)
+		}
+		opts.filter.Since = cutoff
+	}
+	if opts.filter.Last < 0 {
+		return opts, fmt.Errorf("export: --last must be positive")
+	}
+	opts.format = strings.ToLower(opts.format)
+	return opts, nil
+}
+
+// handleExportCommand prints a saved session, the latest by default, as a
+// filtered transcript: `ch export [session] [--since ...] [--role ...] ...`
+func handleExportCommand(args []string, chatManager *chat.Manager, state *types.AppState) error {
+	opts, err := parseExportArgs(args)
+	if err != nil {
+		return err
+	}
+	if !state.Config.EnableSessionSave {
+		return fmt.Errorf("session save feature is disabled in config")
+	}
+
+	var session *types.SessionFile
+	if opts.target != "" {
+		session, err = chatManager.LoadCustomHistoryFile(opts.target)
+	} else {
+		session, err = chatManager.LoadLatestSessionState()
+	}
+	if err != nil {
+		return err
+	}
+	chatManager.RestoreSessionState(session)
+
+	content, err := chatManager.ExportFiltered(opts.filter, opts.format)
+	if err != nil {
+		return err
+	}
+	fmt.Print(content)
+	return nil
+}
+
+// exportFilteredChat writes the current session's exchanges that pass the !e
+// filters to target, or to a new ch_export_<time> file in the current directory
+func exportFilteredChat(chatManager *chat.Manager, opts exportOptions) (string, error) {
+	content, err := chatManager.ExportFiltered(opts.filter, opts.format)
+	if err != nil {
+		return "", err
+	}
+
+	path := opts.target
+	if path == "" {
+		ext := map[string]string{chat.ExportMarkdown: ".md", chat.ExportJSON: ".json"}[opts.format]
+		if ext == "" {
+			ext = ".txt"
+		}
+		path = fmt.Sprintf("ch_export_%d%s", time.Now().Unix(), ext)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		chatManager.AddRecentlyCreatedFile(absPath)
+	}
+	return path, nil
+}
+
+// embedOptions holds the parsed arguments of the embed subcommand
+type embedOptions struct {
+	model     string
+	format    string
+	lines     bool
+	batchSize int
+	rpm       int
+	files     []string
+}
+
+// parseEmbedArgs parses embed subcommand arguments. Flags may appear before or
+// after file names, matching `ch embed file.txt --model name`.
+func parseEmbedArgs(args []string, cfg *types.Config) (embedOptions, error) {
+	opts := embedOptions{}
+	fs := flag.NewFlagSet("embed", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.model, "model", cfg.EmbeddingModel, "Embedding model")
+	fs.StringVar(&opts.model, "m", cfg.EmbeddingModel, "Embedding model")
+	fs.StringVar(&opts.format, "format", "json", "Output format (json, binary)")
+	fs.BoolVar(&opts.lines, "lines", false, "Embed each non-empty line separately")
+	fs.IntVar(&opts.batchSize, "batch", cfg.EmbeddingBatchSize, "Inputs per request")
+	fs.IntVar(&opts.rpm, "rpm", cfg.EmbeddingRPM, "Maximum requests per minute")
+
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return opts, fmt.Errorf("embed: %v", err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			opts.files = append(opts.files, args[0])
+			args = args[1:]
+		}
+	}
+
+	opts.format = strings.ToLower(opts.format)
+	if opts.format != "json" && opts.format != "binary" {
+		return opts, fmt.Errorf("unsupported embed format %q (use json or binary)", opts.format)
+	}
+	return opts, nil
+}
+
+// collectEmbedInputs gathers the texts to embed from files, or from piped stdin
+// when no files are given, along with a source label for each text
+func collectEmbedInputs(opts embedOptions, pipedInput string) ([]string, []string, error) {
+	type source struct{ name, content string }
+	var sources []source
+	if len(opts.files) == 0 {
+		if pipedInput == "" {
+			return nil, nil, fmt.Errorf("nothing to embed: pass files or pipe text to stdin")
+		}
+		sources = append(sources, source{"stdin", pipedInput})
+	}
+	for _, file := range opts.files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %v", file, err)
+		}
+		sources = append(sources, source{file, string(data)})
+	}
+
+	var inputs, labels []string
+	for _, src := range sources {
+		if !opts.lines {
+			if strings.TrimSpace(src.content) != "" {
+				inputs = append(inputs, src.content)
+				labels = append(labels, src.name)
+			}
+			continue
+		}
+		for i, line := range strings.Split(src.content, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				inputs = append(inputs, line)
+				labels = append(labels, fmt.Sprintf("%s:%d", src.name, i+1))
+			}
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, nil, fmt.Errorf("nothing to embed: input is empty")
+	}
+	return inputs, labels, nil
+}
+
+// writeEmbeddings writes one JSON object per line, or raw little-endian
+// float32 vectors back to back for the binary format
+func writeEmbeddings(w io.Writer, format string, labels []string, vectors [][]float32) error {
+	if format == "binary" {
+		for _, vec := range vectors {
+			if err := binary.Write(w, binary.LittleEndian, vec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encoder := json.NewEncoder(w)
+	for i, vec := range vectors {
+		record := struct {
+			Index     int       `json:"index"`
+			Source    string    `json:"source"`
+			Embedding []float32 `json:"embedding"`
+		}{i, labels[i], vec}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleEmbed runs the embed subcommand against the current platform
+func handleEmbed(args []string, pipedInput string, platformManager *platform.Manager, state *types.AppState) error {
+	opts, err := parseEmbedArgs(args, state.Config)
+	if err != nil {
+		return err
+	}
+	inputs, labels, err := collectEmbedInputs(opts, pipedInput)
+	if err != nil {
+		return err
+	}
+	if err := platformManager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize client: %v", err)
+	}
+
+	vectors, err := platformManager.CreateEmbeddings(inputs, platform.EmbeddingOptions{
+		Model:             opts.model,
+		BatchSize:         opts.batchSize,
+		RequestsPerMinute: opts.rpm,
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.format == "binary" && len(vectors) > 0 {
+		// Binary output has no framing, so report the shape on stderr
+		fmt.Fprintf(os.Stderr, "%d vectors x %d float32 (little-endian)\n", len(vectors), len(vectors[0]))
+	}
+	return writeEmbeddings(os.Stdout, opts.format, labels, vectors)
+}
+
+// parseTagList splits a comma-separated tag list, dropping empty entries
+func parseTagList(raw string) []string {
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// handleRateSession shows or sets the session rating used to filter dataset exports
+func handleRateSession(arg string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool) bool {
+	if arg == "" {
+		if rating := chatManager.GetSessionRating(); rating > 0 {
+			terminal.PrintInfo(fmt.Sprintf("session rating: %d/5", rating))
+		} else {
+			terminal.PrintInfo("session is not rated")
+		}
+		return true
+	}
+
+	rating, err := strconv.Atoi(arg)
+	if err != nil || rating < 0 || rating > 5 {
+		terminal.PrintError("rating must be a number from 1 to 5 (0 clears it)")
+		return true
+	}
+
+	chatManager.SetSessionRating(rating)
+	if rating == 0 {
+		terminal.PrintInfo("session rating cleared")
+	} else {
+		terminal.PrintInfo(fmt.Sprintf("session rated %d/5", rating))
+	}
+
+	if state.Config.EnableSessionSave && !noHistory {
+		if err := chatManager.SaveSessionState(); err != nil {
+			terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
+		}
+	}
+	return true
+}
+
+// handleNote adds a note to the session, lists the notes when text is
+// empty, or removes them all with "clear"
+func handleNote(text string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool) bool {
+	switch text {
+	case "":
+		notes := chatManager.GetNotes()
+		if len(notes) == 0 {
+			terminal.PrintInfo("session has no notes")
+		}
+		for _, note := range notes {
+			terminal.PrintInfo(fmt.Sprintf("%s %s", time.Unix(note.Time, 0).Format("2006-01-02 15:04"), note.Text))
+		}
+		return true
+	case "clear":
+		chatManager.ClearNotes()
+		terminal.PrintInfo("session notes cleared")
+	default:
+		if err := chatManager.AddNote(text); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+		terminal.PrintInfo(fmt.Sprintf("note added (%d in this session)", len(chatManager.GetNotes())))
+	}
+
+	if state.Config.EnableSessionSave && !noHistory {
+		if err := chatManager.SaveSessionState(); err != nil {
+			terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
+		}
+	}
+	return true
+}
+
+// handleRemember saves text as a long-term memory
+func handleRemember(text string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	if !state.Config.Memory {
+		terminal.PrintError("memory is disabled, set \"memory\": true in ~/.ch/config.json")
+		return true
+	}
+	if text == "" {
+		terminal.PrintError(fmt.Sprintf("usage: %s <fact>", state.Config.Remember))
+		return true
+	}
+	count, err := chatManager.Remember(text)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("remembered (%d memories)", count))
+	return true
+}
+
+// handleMemories lists long-term memories and deletes the ones picked in
+// fzf, or all of them with "clear"
+func handleMemories(arg string, terminal *ui.Terminal) bool {
+	memories, err := chat.LoadMemories()
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error loading memories: %v", err))
+		return true
+	}
+	if len(memories) == 0 {
+		terminal.PrintInfo("no memories saved")
+		return true
+	}
+
+	labels := make([]string, len(memories))
+	for i, memory := range memories {
+		labels[i] = chat.FormatMemory(i, memory)
+	}
+
+	var remove []int
+	switch {
+	case arg == "clear":
+		if !terminal.Confirm(fmt.Sprintf("delete all %d memories?", len(memories))) {
+			return true
+		}
+		for i := range memories {
+			remove = append(remove, i)
+		}
+	case arg != "":
+		terminal.PrintError(fmt.Sprintf("unknown argument %q, use clear or no argument", arg))
+		return true
+	case !isTerminal(os.Stdin):
+		for _, label := range labels {
+			fmt.Println(label)
+		}
+		return true
+	default:
+		selected, err := terminal.FzfMultiSelect(labels, "delete memories (tab=multi, esc=keep all): ")
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("error selecting memories: %v", err))
+			return true
+		}
+		if len(selected) == 0 {
+			return true
+		}
+		remove = chat.MemoryIndexes(selected)
+	}
+
+	if err := chat.ForgetMemories(remove); err != nil {
+		terminal.PrintError(fmt.Sprintf("error deleting memories: %v", err))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("deleted %d of %d memories", len(remove), len(memories)))
+	return true
+}
+
+// handleTagExchange tags the last exchange and session, or removes "-name" tags
+func handleTagExchange(args []string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool) bool {
+	var add, remove []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			remove = append(remove, strings.TrimPrefix(arg, "-"))
+		} else {
+			add = append(add, arg)
+		}
+	}
+
+	if len(remove) > 0 {
+		chatManager.UntagSession(remove)
+	}
+	if len(add) > 0 || len(remove) == 0 {
+		if err := chatManager.TagLastExchange(add); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+	}
+
+	if tags := chatManager.GetSessionTags(); len(tags) > 0 {
+		terminal.PrintInfo(fmt.Sprintf("session tags: #%s", strings.Join(tags, " #")))
+	} else {
+		terminal.PrintInfo("session has no tags")
+	}
+
+	if state.Config.EnableSessionSave && !noHistory {
+		if err := chatManager.SaveSessionState(); err != nil {
+			terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
+		}
+	}
+	return true
+}
+
+// handleStopSequences shows, adds, or clears the stop sequences used for this session
+func handleStopSequences(arg string, terminal *ui.Terminal, state *types.AppState) bool {
+	switch arg {
+	case "":
+	case "clear":
+		state.Config.StopSequences = nil
+	default:
+		stop := parseStopSequence(arg)
+		found := false
+		for _, existing := range state.Config.StopSequences {
+			if existing == stop {
+				found = true
+				break
+			}
+		}
+		if !found {
+			state.Config.StopSequences = append(state.Config.StopSequences, stop)
+		}
+	}
+
+	if len(state.Config.StopSequences) == 0 {
+		terminal.PrintInfo("no stop sequences set")
+		return true
+	}
+	quoted := make([]string, len(state.Config.StopSequences))
+	for i, stop := range state.Config.StopSequences {
+		quoted[i] = strconv.Quote(stop)
+	}
+	terminal.PrintInfo(fmt.Sprintf("stop sequences: %s", strings.Join(quoted, ", ")))
+	return true
+}
+
+// handleBigFile indexes a file for per-question chunk retrieval, shows the
+// indexed file, or clears it
+func handleBigFile(arg string, chatManager *chat.Manager, terminal *ui.Terminal) bool {
+	switch arg {
+	case "":
+		if status := chatManager.BigFileStatus(); status != "" {
+			terminal.PrintInfo(fmt.Sprintf("big file: %s", status))
+		} else {
+			terminal.PrintInfo("no big file loaded")
+		}
+		return true
+	case "clear":
+		chatManager.ClearBigFile()
+		terminal.PrintInfo("big file cleared")
+		return true
+	}
+
+	path := arg
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if !terminal.IsURL(path) {
+		if _, err := os.Stat(path); err != nil {
+			terminal.PrintError(fmt.Sprintf("file does not exist: %s", arg))
+			return true
+		}
+	}
+
+	if err := chatManager.IndexBigFile(terminal, path); err != nil {
+		terminal.PrintError(fmt.Sprintf("error indexing %s: %v", arg, err))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("big file: %s, questions now include the most relevant chunks", chatManager.BigFileStatus()))
+	return true
+}
+
+// testOptions are the parsed arguments of the test subcommand
+type testOptions struct {
+	platform string
+	model    string
+	timeout  time.Duration
+	json     bool
+	prompt   string
+}
+
+// parseTestArgs parses test subcommand arguments. Flags may appear before or
+// after the prompt, and -o platform|model overrides -p and -m.
+func parseTestArgs(args []string, platformName, model string) (testOptions, error) {
+	opts := testOptions{}
+	var target string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&target, "o", "", "Platform and model (platform|model)")
+	fs.StringVar(&opts.platform, "p", platformName, "Platform")
+	fs.StringVar(&opts.model, "m", model, "Model")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "Request timeout")
+	fs.BoolVar(&opts.json, "json", false, "Print the result as JSON")
+
+	var words []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return opts, fmt.Errorf("test: %v", err)
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			words = append(words, args[0])
+			args = args[1:]
+		}
+	}
+
+	if target != "" {
+		platformPart, modelPart, ok := strings.Cut(target, "|")
+		platformPart, modelPart = strings.TrimSpace(platformPart), strings.TrimSpace(modelPart)
+		if !ok || platformPart == "" || modelPart == "" {
+			return opts, fmt.Errorf("invalid -o format: use platform|model (e.g., openai|gpt-4)")
+		}
+		opts.platform, opts.model = platformPart, modelPart
+	}
+	if opts.timeout <= 0 {
+		return opts, fmt.Errorf("test: --timeout must be positive")
+	}
+	opts.prompt = strings.Join(words, " ")
+	if opts.prompt == "" {
+		opts.prompt = "ping"
+	}
+	return opts, nil
+}
+
+// formatTestResult renders a test result as one status line
+func formatTestResult(result platform.ProbeResult) string {
+	target := result.Platform + "|" + result.Model
+	if !result.OK {
+		return fmt.Sprintf("fail %s %dms: %s", target, result.LatencyMS, result.Error)
+	}
+	return fmt.Sprintf("ok %s %dms %d in/%d out tokens: %s", target, result.LatencyMS, result.PromptTokens, result.CompletionTokens, result.Output)
+}
+
+// handleTest runs the test subcommand: one short completion against the
+// target, printed as a status line or JSON. It returns false when the
+// target failed, so monitoring jobs can alert on the exit code.
+func handleTest(args []string, platformName, model string, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	opts, err := parseTestArgs(args, platformName, model)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return false
+	}
+
+	state.Config.CurrentPlatform = opts.platform
+	state.Config.CurrentModel = opts.model
+	var result platform.ProbeResult
+	if err := platformManager.Initialize(); err != nil {
+		result = platform.ProbeResult{Platform: opts.platform, Model: opts.model, Error: fmt.Sprintf("failed to initialize client: %v", err)}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+		result = platformManager.Probe(ctx, opts.model, opts.prompt)
+		cancel()
+	}
+
+	if opts.json {
+		data, _ := json.Marshal(result)
+		fmt.Println(string(data))
+	} else {
+		fmt.Println(formatTestResult(result))
+	}
+	return result.OK
+}
+
+// handleIndex builds or updates the embeddings index of dir for !rag
+func handleIndex(dir string, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) error {
+	if err := platformManager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize client: %v", err)
+	}
+	index, stats, err := chat.BuildIndex(terminal, platformManager, state.Config, dir)
+	if err != nil {
+		return fmt.Errorf("error indexing %s: %v", dir, err)
+	}
+	terminal.PrintInfo(fmt.Sprintf("indexed %s: %d files, %d chunks (%d embedded, %d unchanged)", index.Root, stats.Files, stats.Chunks, stats.Embedded, stats.Chunks-stats.Embedded))
+	return nil
+}
+
+// handleRetrieve answers question from the most relevant chunks of the index
+// covering the current directory, or shows that index with no question
+func handleRetrieve(question string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	index, err := chat.FindIndex(".")
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	if question == "" {
+		terminal.PrintInfo(fmt.Sprintf("index: %s", index.Summary()))
+		return true
+	}
+
+	excerpts, err := chatManager.RetrieveFromIndex(index, question)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error searching index: %v", err))
+		return true
+	}
+	chatManager.AddUserMessage(excerpts)
+	chatManager.AddToHistoryWithContext(fmt.Sprintf("Index search: %s", question), "", excerpts)
+	sendPrompt(question, question, chatManager, platformManager, terminal, state)
+	return true
+}
+
+// handleAnswerSearch finds a past assistant answer and copies it, adds it to
+// the current chat, or restores the session it came from
+func handleAnswerSearch(args []string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, rl *readline.Instance) bool {
+	match, err := chatManager.SearchAnswers(terminal, args)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+
+	entry := match.Entry()
+	fmt.Printf("\033[91m%s UTC (%s)\033[0m\n", time.Unix(entry.Time, 0).UTC().Format("2006-01-02 15:04:05"), filepath.Base(match.Session.SourceFile))
+	for _, turn := range match.Context() {
+		switch {
+		case turn.Role == "user" && turn.Content == entry.User:
+			fmt.Printf("\033[94muser:\033[0m %s\n", turn.Content)
+		case turn.Role == "assistant" && turn.Content == entry.Bot:
+			fmt.Printf("\033[92m%s\033[0m\n", turn.Content)
+		case turn.Role == "user":
+			fmt.Printf("\033[90muser: %s\033[0m\n", turn.Content)
+		default:
+			fmt.Printf("\033[90m%s\033[0m\n", turn.Content)
+		}
+	}
+
+	actions := []string{"inject into chat", "copy answer", "restore session"}
+	action, err := terminal.FzfSelect(actions, "answer: ")
+	if err != nil || action == "" {
+		return true
+	}
+
+	switch action {
+	case "inject into chat":
+		content := match.InjectionContent()
+		chatManager.AddUserMessage(content)
+		chatManager.AddToHistoryWithContext("Past answer loaded", "", content)
+		terminal.PrintInfo("answer added to the chat")
+	case "copy answer":
+		if err := terminal.CopyToClipboard(entry.Bot); err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+		terminal.PrintInfo("copied to clipboard")
+	case "restore session":
+		restoreSearchedSession(match.Session, chatManager, platformManager, terminal, state, rl)
+	}
+	return true
+}
+
+// restoreSearchedSession loads a session found by search and prints it
+func restoreSearchedSession(session *types.SessionFile, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, rl *readline.Instance) {
+	// Restore the session
+	chatManager.RestoreSessionState(session)
+	chatManager.ForkSessionOnNextSave()
+
+	// Populate readline history with the loaded session's user prompts
+	if rl != nil {
+		for _, entry := range session.ChatHistory {
+			if entry.User != "" && entry.User != state.Config.SystemPrompt {
+				_ = rl.SaveHistory(entry.User)
+			}
+		}
+	}
+
+	// Re-initialize platform client
+	if err := platformManager.Initialize(); err != nil {
+		terminal.PrintError(fmt.Sprintf("error initializing client: %v", err))
+		return
+	}
+
+	// Print session info and conversation
+	fmt.Printf("\033[91m%s UTC (%s)\033[0m\n", time.Unix(session.Timestamp, 0).UTC().Format("2006-01-02 15:04:05"), filepath.Base(session.SourceFile))
+
+	// Print the entire conversation history
+	for _, entry := range session.ChatHistory {
+		if entry.User == state.Config.SystemPrompt {
+			continue // Skip system prompt
+		}
+		// Print user message
+		if entry.User != "" {
+			fmt.Printf("\033[94muser:\033[0m %s\n", entry.User)
+		}
+		// Print bot response
+		if entry.Bot != "" {
+			fmt.Printf("\033[92m%s\033[0m\n", entry.Bot)
+		}
+	}
+}
+
+// printResponseFooter prints the enabled dim lines after an interactive
+// response: the model annotation and the response stats
+func printResponseFooter(chatManager *chat.Manager, terminal *ui.Terminal, cfg *types.Config) {
+	if cfg.ShowModelAnnotation {
+		terminal.PrintAnnotation(chatManager.LastExchangeAnnotation())
+	}
+	if cfg.ShowResponseStats {
+		terminal.PrintAnnotation(chatManager.LastResponseStats())
+	}
+}
+
+// confirmLockedSwitch asks before a model or platform switch while !lock is
+// active. It returns false when the switch should not happen.
+func confirmLockedSwitch(chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	if !state.ModelLocked {
+		return true
+	}
+	current := fmt.Sprintf("%s/%s", chatManager.GetCurrentPlatform(), chatManager.GetCurrentModel())
+	if terminal.Confirm(fmt.Sprintf("conversation is locked to %s, switch anyway?", current)) {
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("kept %s, use %s to allow switching", current, state.Config.UnlockModel))
+	return false
+}
+
+// handleDuplicatePrompt offers the earlier answer when prompt was already
+// answered in this session. It returns true when the prompt should not be sent.
+func handleDuplicatePrompt(prompt string, chatManager *chat.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	if !state.Config.DuplicatePromptCheck {
+		return false
+	}
+	previous, ok := chatManager.FindPreviousAnswer(prompt)
+	if !ok {
+		return false
+	}
+
+	choice, err := terminal.FzfSelect([]string{"show previous answer", "resend"}, "already asked: ")
+	if err != nil || choice == "" {
+		terminal.PrintInfo("not sent")
+		return true
+	}
+	if choice == "resend" {
+		return false
+	}
+
+	terminal.PrintInfo(fmt.Sprintf("answered %s UTC by %s", time.Unix(previous.Time, 0).UTC().Format("2006-01-02 15:04:05"), previous.Model))
+	fmt.Println(previous.Bot)
+	return true
+}
+
+// handleLiveFiles marks a file live, lists the live files, or stops refreshing them
+func handleLiveFiles(arg string, chatManager *chat.Manager, terminal *ui.Terminal) bool {
+	switch arg {
+	case "":
+		if paths := chatManager.LiveFiles(); len(paths) > 0 {
+			terminal.PrintInfo(fmt.Sprintf("live files: %s", strings.Join(paths, ", ")))
+		} else {
+			terminal.PrintInfo("no live files")
+		}
+		return true
+	case "clear":
+		chatManager.ClearLiveFiles()
+		terminal.PrintInfo("live files cleared, their last content stays in context")
+		return true
+	}
+
+	path := arg
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if err := chatManager.AddLiveFile(terminal, path); err != nil {
+		terminal.PrintError(fmt.Sprintf("error loading %s: %v", arg, err))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("%s is live, it is re-read before each message when it changes", arg))
+	return true
+}
+
+// handleRemoteLoad loads a file or directory listing from a remote host into context
+func handleRemoteLoad(arg string, chatManager *chat.Manager, terminal *ui.Terminal) bool {
+	target, err := ui.ParseRemoteTarget(arg)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+
+	content, err := terminal.LoadRemote(target)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error loading %s: %v", target, err))
+		return true
+	}
+	chatManager.AddUserMessage(content)
+	chatManager.AddToHistoryWithContext(fmt.Sprintf("Loaded: %s", target), "", content)
+	terminal.PrintInfo(fmt.Sprintf("loaded %s", target))
+	return true
+}
+
+// handleLoadRows loads a range of rows from a CSV or XLSX file into context.
+// Arguments after the range are an optional sheet name and an optional file,
+// which defaults to the last table loaded as a schema summary.
+func handleLoadRows(args []string, chatManager *chat.Manager, terminal *ui.Terminal) bool {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	start, end, err := ui.ParseRowRange(args[0])
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+
+	path, rest := terminal.LastTable(), args[1:]
+	if n := len(rest); n > 0 {
+		if ext := strings.ToLower(filepath.Ext(rest[n-1])); ext == ".csv" || ext == ".xlsx" {
+			path, rest = rest[n-1], rest[:n-1]
+		}
+	}
+	sheet := strings.Join(rest, " ")
+	if path == "" {
+		terminal.PrintError("no table loaded, load a CSV or XLSX file with tabular_summary on, or name the file")
+		return true
+	}
+
+	content, err := terminal.LoadTableRows(path, sheet, start, end)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error loading rows: %v", err))
+		return true
+	}
+	chatManager.AddUserMessage(content)
+	chatManager.AddToHistoryWithContext(fmt.Sprintf("Loaded rows %s of %s", args[0], path), "", content)
+	terminal.PrintInfo(strings.SplitN(content, "\n", 2)[0])
+	return true
+}
+
+// handleDBQuery runs a read-only SQL query and adds the result table to context
+func handleDBQuery(arg string, chatManager *chat.Manager, terminal *ui.Terminal) bool {
+	database, query, err := ui.ParseDBQuery(arg)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	content, err := terminal.QueryDatabase(database, query)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("query failed: %v", err))
+		return true
+	}
+	fmt.Print(content)
+	chatManager.AddUserMessage(content)
+	chatManager.AddToHistoryWithContext(fmt.Sprintf("Queried %s: %s", database, query), "", content)
+	return true
+}
+
+// handleRedactions shows, adds, or clears the find-and-replace rules applied to exports
+func handleRedactions(arg string, terminal *ui.Terminal, state *types.AppState) bool {
+	switch arg {
+	case "":
+	case "clear":
+		state.Config.Redactions = nil
+	default:
+		rule, err := chat.ParseRedactionRule(arg)
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+		found := false
+		for i, existing := range state.Config.Redactions {
+			if existing.Find == rule.Find && existing.Regex == rule.Regex {
+				state.Config.Redactions[i] = rule
+				found = true
+				break
+			}
+		}
+		if !found {
+			state.Config.Redactions = append(state.Config.Redactions, rule)
+		}
+	}
+
+	if len(state.Config.Redactions) == 0 {
+		terminal.PrintInfo("no export redactions set")
+		return true
+	}
+	rules := make([]string, len(state.Config.Redactions))
+	for i, rule := range state.Config.Redactions {
+		rules[i] = chat.FormatRedactionRule(rule)
+	}
+	terminal.PrintInfo(fmt.Sprintf("export redactions: %s", strings.Join(rules, "; ")))
+	return true
+}
+
+// handleHeaders shows or edits the extra HTTP headers sent to the current
+// platform: "Name: value" sets one, "Name:" removes it, "clear" removes all,
+// and "save" writes extra_headers to ~/.ch/config.json
+func handleHeaders(arg string, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	var err error
+	switch arg {
+	case "":
+	case "clear":
+		err = platformManager.ClearExtraHeaders()
+	case "save":
+		var value any
+		if len(state.Config.ExtraHeaders) > 0 {
+			value = state.Config.ExtraHeaders
+		}
+		if err := config.SaveUserConfigField("extra_headers", value); err != nil {
+			terminal.PrintError(fmt.Sprintf("failed to save headers: %v", err))
+			return true
+		}
+		terminal.PrintInfo("saved extra_headers to ~/.ch/config.json")
+		return true
+	default:
+		name, value, parseErr := platform.ParseHeader(arg)
+		if parseErr != nil {
+			terminal.PrintError(fmt.Sprintf("%v", parseErr))
+			return true
+		}
+		err = platformManager.SetExtraHeader(name, value)
+	}
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("failed to apply headers: %v", err))
+		return true
+	}
+
+	headers := platformManager.ExtraHeaders()
+	if len(headers) == 0 {
+		terminal.PrintInfo(fmt.Sprintf("no extra headers for %s", state.Config.CurrentPlatform))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("extra headers for %s:\n%s", state.Config.CurrentPlatform, platform.FormatHeaders(headers)))
+	return true
+}
+
+// parseStopSequence interprets Go-style escapes such as \n and \t in a stop sequence,
+// falling back to the raw text when it is not a valid escaped string
+func parseStopSequence(arg string) string {
+	if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`); err == nil {
+		return unquoted
+	}
+	return arg
+}
+
+// codeDumpManifest describes the files of a codedump for tooling
+type codeDumpManifest struct {
+	Directory string                 `json:"directory"`
+	Format    string                 `json:"format"`
+	Since     string                 `json:"since,omitempty"`
+	Dump      string                 `json:"dump,omitempty"`
+	Files     []codeDumpManifestFile `json:"files"`
+}
+
+// codeDumpManifestFile is one file in a codedump manifest
+type codeDumpManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Tokens int    `json:"tokens"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// buildCodeDumpManifest lists each dumped file with its size, estimated
+// tokens for model, and checksum. dumpFile is the dump's file name, empty
+// for stdout.
+func buildCodeDumpManifest(dump *ui.CodeDump, format, dumpFile, model string) codeDumpManifest {
+	manifest := codeDumpManifest{Directory: dump.Dir, Format: format, Since: dump.Since, Dump: dumpFile, Files: []codeDumpManifestFile{}}
+	tok := tokens.For(model)
+	for _, file := range dump.Files {
+		manifest.Files = append(manifest.Files, codeDumpManifestFile{
+			Path:   file.Path,
+			Size:   file.Size,
+			Tokens: tok.Count(file.Content),
+			SHA256: file.SHA256,
+			Error:  file.Error,
+		})
+	}
+	return manifest
+}
+
+// writeCodeDump renders dump in format and writes it to stdout or to a new
+// file in the current directory, whose name is printed. With manifest, a JSON
+// manifest named after the dump, with token counts for model, is written
+// next to it.
+func writeCodeDump(dump *ui.CodeDump, format, model string, toStdout, manifest bool) error {
+	content, err := dump.Render(format)
+	if err != nil {
+		return err
+	}
+	if format == "md" {
+		format = ui.CodeDumpMarkdown
+	}
+	if format == "" {
+		format = ui.CodeDumpText
+	}
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting current directory: %v", err)
+	}
+	ext := ".txt"
+	if format == ui.CodeDumpMarkdown {
+		ext = ".md"
+	}
+	filename := generateUniqueCodeDumpFilename(currentDir, content, ext)
+
+	if toStdout {
+		fmt.Print(content)
+		if !strings.HasSuffix(content, "\n") {
+			fmt.Println()
+		}
+	} else {
+		if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+			return err
+		}
+		fmt.Println(filename)
+	}
+
+	if !manifest {
+		return nil
+	}
+	dumpFile := filename
+	if toStdout {
+		dumpFile = ""
+	}
+	data, err := json.MarshalIndent(buildCodeDumpManifest(dump, format, dumpFile, model), "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestFile := strings.TrimSuffix(filename, ext) + ".manifest.json"
+	if err := os.WriteFile(manifestFile, data, 0600); err != nil {
+		return err
+	}
+	if toStdout {
+		// Keep stdout for the dump itself so it can be piped
+		fmt.Fprintln(os.Stderr, manifestFile)
+	} else {
+		fmt.Println(manifestFile)
+	}
+	return nil
+}
+
+// generateUniqueCodeDumpFilename generates a unique filename for code dump with collision detection
+func generateUniqueCodeDumpFilename(currentDir, content, ext string) string {
+	baseHash := chat.GenerateHashFromContent(content, 8)
+	filename := fmt.Sprintf("ch_cd%s%s", baseHash, ext)
+	fullPath := filepath.Join(currentDir, filename)
+
+	// Check if file exists, if not return it
+	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		return filename
+	}
+
+	// If file exists, try with different offsets
+	for offset := 1; offset <= 10; offset++ {
+		newHash := chat.GenerateHashFromContentWithOffset(content, 8, offset)
+		filename = fmt.Sprintf("ch_cd%s%s", newHash, ext)
+		fullPath = filepath.Join(currentDir, filename)
+
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			return filename
+		}
+	}
+
+	// If still colliding, add a numeric suffix
+	for counter := 1; counter <= 999; counter++ {
+		filename = fmt.Sprintf("ch_cd%s_%03d%s", baseHash, counter, ext)
+		fullPath = filepath.Join(currentDir, filename)
+
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			return filename
+		}
+	}
+
+	// Fallback to original UUID if everything fails
+	return fmt.Sprintf("ch_cd%s%s", uuid.New().String(), ext)
+}
+
+// handleAllModels handles the !o command for selecting from all available
+// models, showing only those that pass filter
+func handleAllModels(filter platform.ModelFilter, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	// Create channels for async operation
+	type modelResult struct {
+		models []string
+		err    error
+	}
+	resultChan := make(chan modelResult)
+
+	// Start fetching models in a goroutine
+	go func() {
+		models, err := platformManager.FetchAllModelsAsync(filter)
+		resultChan <- modelResult{models, err}
+	}()
+
+	// Show loading animation
+	done := make(chan bool)
+	go terminal.ShowLoadingAnimation("fetching models", done)
+
+	// Wait for models to be fetched
+	result := <-resultChan
+	done <- true // Stop animation
+
+	if result.err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", result.err))
+		return true
 	}
 
 	if len(result.models) == 0 {
@@ -2042,11 +4337,8 @@ func handleAllModels(chatManager *chat.Manager, platformManager *platform.Manage
 	modelMap := make(map[string]modelInfo)
 
 	for _, m := range models {
-		parts := strings.SplitN(m, "|", 2)
-		if len(parts) == 2 {
-			platform := parts[0]
-			modelName := parts[1]
-			modelMap[m] = modelInfo{platform, modelName}
+		if platformName, modelName, ok := platform.ParseAllModelsLabel(m); ok {
+			modelMap[m] = modelInfo{platformName, modelName}
 		}
 	}
 
@@ -2100,3 +4392,361 @@ func handleAllModels(chatManager *chat.Manager, platformManager *platform.Manage
 
 	return true
 }
+
+// handleReplayTo replays the picked part of the conversation on another
+// platform or model: target names a platform (its model is picked), or a
+// model on the current platform, and the platform picker opens without
+// one. The replay continues as a new session, so with save_all_sessions
+// the original conversation keeps its own file.
+func handleReplayTo(target string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool) bool {
+	if !confirmLockedSwitch(chatManager, terminal, state) {
+		return true
+	}
+	entries, err := chatManager.SelectReplayEntries(terminal)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	if len(entries) == 0 {
+		return true
+	}
+
+	_, isPlatform := state.Config.Platforms[target]
+	if target == "" || target == "openai" || isPlatform {
+		result, err := platformManager.SelectPlatform(target, "", terminal.FzfSelect)
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+		chatManager.SetCurrentPlatform(result["platform_name"].(string))
+		chatManager.SetCurrentModel(result["picked_model"].(string))
+		state.Config.CurrentBaseURL = result["base_url"].(string)
+		if err := platformManager.Initialize(); err != nil {
+			terminal.PrintError(fmt.Sprintf("error initializing client: %v", err))
+			return true
+		}
+	} else {
+		chatManager.SetCurrentModel(target)
+	}
+	if !state.Config.MuteNotifications {
+		terminal.PrintPlatformSwitch(chatManager.GetCurrentPlatform(), chatManager.GetCurrentModel())
+	}
+
+	chatManager.ForkSessionOnNextSave()
+	chatManager.ClearHistory()
+	answered := 0
+	for _, entry := range entries {
+		if chat.IsLoadedContext(entry) {
+			chatManager.AddUserMessage(entry.Context)
+			chatManager.AddToHistoryWithContext(entry.User, entry.Bot, entry.Context)
+			continue
+		}
+
+		prompt := chat.EffectiveUserContent(entry)
+		fmt.Fprintf(terminal.UIWriter(), "\033[94muser: \033[0m%s\n", entry.User)
+		chatManager.AddUserMessage(prompt)
+
+		var loadingDone chan bool
+		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
+			loadingDone = make(chan bool)
+			go terminal.ShowLoadingAnimation("thinking", loadingDone)
+		}
+		response, err := chatManager.SendWithContextRetry(platformManager, terminal)
+		if loadingDone != nil {
+			loadingDone <- true
+		}
+		if err != nil {
+			chatManager.RemovePendingUserMessage(prompt)
+			if !requestNotSent(err) {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+			}
+			break
+		}
+
+		if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) && !state.Config.StreamJSON {
+			fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
+		}
+		chatManager.AddAssistantMessage(response)
+		if entry.Context != "" {
+			chatManager.AddToHistoryWithContext(entry.User, response, entry.Context)
+		} else {
+			chatManager.AddToHistory(entry.User, response)
+		}
+		answered++
+	}
+	terminal.PrintInfo(fmt.Sprintf("replayed %d prompts on %s/%s", answered, chatManager.GetCurrentPlatform(), chatManager.GetCurrentModel()))
+
+	if state.Config.EnableSessionSave && !noHistory {
+		if err := chatManager.SaveSessionState(); err != nil {
+			terminal.PrintError(fmt.Sprintf("warning: failed to save session: %v", err))
+		}
+	}
+	return true
+}
+
+// fillTemplate loads the template called name and fills its placeholders
+// from name=value args and stdin, asking for the rest when the terminal can
+// answer. Args before the first name=value, and piped input the template
+// has no {{stdin}} for, are added after the template.
+func fillTemplate(name string, args []string, stdin string, terminal *ui.Terminal) (string, error) {
+	text, err := chat.LoadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	values, extra := chat.ParseTemplateArgs(args)
+	variables := chat.TemplateVariables(text)
+	if stdin = strings.TrimSpace(stdin); stdin != "" {
+		if slices.Contains(variables, chat.TemplateStdin) {
+			values[chat.TemplateStdin] = stdin
+		} else {
+			extra = strings.TrimSpace(extra + "\n\n" + stdin)
+		}
+	}
+
+	var missing []string
+	for _, variable := range variables {
+		if _, ok := values[variable]; !ok {
+			missing = append(missing, variable)
+		}
+	}
+	if len(missing) > 0 {
+		if !terminal.IsTerminal() {
+			return "", fmt.Errorf("template %s needs %s (pass them as name=value)", name, strings.Join(missing, ", "))
+		}
+		variableRl, err := readline.NewEx(&readline.Config{
+			HistoryFile: "/dev/null", // Disable history for template variables
+		})
+		if err != nil {
+			return "", fmt.Errorf("error creating template input: %v", err)
+		}
+		defer variableRl.Close()
+		for _, variable := range missing {
+			variableRl.SetPrompt(fmt.Sprintf("\033[93m%s: \033[0m", variable))
+			value, err := variableRl.Readline()
+			if err != nil {
+				return "", fmt.Errorf("template cancelled")
+			}
+			values[variable] = value
+		}
+	}
+
+	prompt := chat.FillTemplate(text, values)
+	if extra != "" {
+		prompt += "\n\n" + extra
+	}
+	return prompt, nil
+}
+
+// handleUseTemplate fills in a template, picked with fzf when args do not
+// name one, and sends it like a typed prompt
+func handleUseTemplate(args []string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	var name string
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	} else {
+		names, err := chat.ListTemplates()
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+			return true
+		}
+		if len(names) == 0 {
+			dir, _ := config.GetTemplateDir()
+			terminal.PrintInfo(fmt.Sprintf("no templates yet, add files such as review-pr.md with {{variables}} to %s", dir))
+			return true
+		}
+		name, err = terminal.FzfSelect(names, "template: ")
+		if err != nil || name == "" {
+			return true
+		}
+	}
+
+	prompt, err := fillTemplate(name, args, "", terminal)
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	terminal.EchoInput(prompt)
+
+	prompt = chatManager.ExpandMentions(terminal, prompt)
+	prompt = chatManager.InterpolateShell(terminal, prompt)
+	sendPrompt(prompt, prompt, chatManager, platformManager, terminal, state)
+	return true
+}
+
+// sendPrompt sends prompt as the next user message and shows and records
+// the reply like a typed prompt's, with historyPrompt as the prompt saved in
+// history. It reports whether a reply was recorded.
+func sendPrompt(prompt, historyPrompt string, chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	chatManager.AddUserMessage(prompt)
+	chatManager.PrepareContext(terminal)
+
+	var loadingDone chan bool
+	if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) {
+		loadingDone = make(chan bool)
+		go terminal.ShowLoadingAnimation("thinking", loadingDone)
+	}
+	response, err := chatManager.SendWithContextRetry(platformManager, terminal)
+	if loadingDone != nil {
+		loadingDone <- true
+	}
+	if err != nil {
+		chatManager.RemovePendingUserMessage(prompt)
+		if !requestNotSent(err) {
+			terminal.PrintError(fmt.Sprintf("%v", err))
+		}
+		return false
+	}
+
+	if platformManager.IsReasoningModel(chatManager.GetCurrentModel()) && !state.Config.StreamJSON {
+		fmt.Printf("\033[92m%s\033[0m\n", platformManager.FormatResponse(response))
+		platformManager.PrintLastLogprobs()
+	}
+	chatManager.AddAssistantMessage(response)
+	chatManager.AddToHistory(historyPrompt, response)
+	printResponseFooter(chatManager, terminal, state.Config)
+	return true
+}
+
+// handleOllamaPull downloads a model into the local Ollama server with
+// progress; ctrl+c cancels the download
+func handleOllamaPull(model string, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	if model == "" {
+		terminal.PrintError(fmt.Sprintf("usage: %s pull <model>, e.g. %s pull llama3.2", state.Config.AllModels, state.Config.AllModels))
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.CommandCancel = cancel
+	state.IsExecutingCommand = true
+	defer func() {
+		cancel()
+		state.IsExecutingCommand = false
+		state.CommandCancel = nil
+	}()
+
+	redraw := terminal.NewRedrawLimiter()
+	err := platformManager.PullOllamaModel(ctx, model, func(progress platform.OllamaPullProgress) {
+		if !redraw.Allow() {
+			return
+		}
+		fmt.Printf("\r\033[K\033[93m%s\033[0m", progress)
+	})
+	fmt.Print("\r\033[K")
+	if ctx.Err() != nil {
+		terminal.PrintInfo("pull cancelled")
+		return true
+	}
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("%v", err))
+		return true
+	}
+	terminal.PrintInfo(fmt.Sprintf("pulled %s, switch to it with %s or %s ollama", model, state.Config.AllModels, state.Config.PlatformSwitch))
+	return true
+}
+
+// handleResponseActions offers the follow-up actions for the latest
+// response in one picker, running the matching command for those that have
+// one
+func handleResponseActions(chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState, noHistory bool, rl *readline.Instance) bool {
+	history := chatManager.GetChatHistory()
+	if len(history) < 2 || history[len(history)-1].Bot == "" {
+		terminal.PrintError("no response yet")
+		return true
+	}
+	response := history[len(history)-1].Bot
+	config := state.Config
+	runCommand := func(command string) bool {
+		return handleSpecialCommandsInternal(command, chatManager, platformManager, terminal, state, false, noHistory, rl)
+	}
+
+	type responseAction struct {
+		label string
+		run   func() bool
+	}
+	actions := []responseAction{
+		{"copy response (" + config.QuickCopyLatest + ")", func() bool { return runCommand(config.QuickCopyLatest) }},
+		{"copy part of a response (" + config.CopyToClipboard + ")", func() bool { return runCommand(config.CopyToClipboard) }},
+		{"save code block (" + config.SaveCodeBlock + ")", func() bool { return runCommand(config.SaveCodeBlock) }},
+	}
+	actions = append(actions,
+		responseAction{"export (" + config.ExportChat + ")", func() bool { return runCommand(config.ExportChat) }},
+		responseAction{"regenerate", func() bool { return regenerateResponse(chatManager, platformManager, terminal, state) }},
+		responseAction{"translate", func() bool { return translateResponse(chatManager, platformManager, terminal, state) }},
+	)
+	if ui.SpeechCommand() != nil {
+		actions = append(actions, responseAction{"speak", func() bool {
+			if err := terminal.Speak(response); err != nil {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+			}
+			return true
+		}})
+	}
+	if urls := terminal.ExtractURLsFromText(response); len(urls) > 0 {
+		actions = append(actions, responseAction{fmt.Sprintf("open URLs (%d)", len(urls)), func() bool {
+			selected, err := terminal.FzfMultiSelect(urls, "open: ")
+			if err != nil {
+				terminal.PrintError(fmt.Sprintf("%v", err))
+				return true
+			}
+			for _, url := range selected {
+				if err := ui.OpenURL(url); err != nil {
+					terminal.PrintError(fmt.Sprintf("%v", err))
+				}
+			}
+			return true
+		}})
+	}
+
+	labels := make([]string, len(actions))
+	for i, action := range actions {
+		labels[i] = action.label
+	}
+	selected, err := terminal.FzfSelect(labels, "action: ")
+	if err != nil || selected == "" {
+		return true
+	}
+	for _, action := range actions {
+		if action.label == selected {
+			return action.run()
+		}
+	}
+	return true
+}
+
+// regenerateResponse asks the latest prompt again in place of its answer,
+// putting the old answer back if the new request fails
+func regenerateResponse(chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	entry, prompt, ok := chatManager.DropLastExchange()
+	if !ok {
+		terminal.PrintError("the latest response cannot be regenerated")
+		return true
+	}
+	terminal.EchoInput(entry.User)
+	if !sendPrompt(prompt, entry.User, chatManager, platformManager, terminal, state) {
+		chatManager.RestoreExchange(entry, prompt)
+	}
+	return true
+}
+
+// translateResponse asks the model to translate its latest response into a
+// language read from the prompt
+func translateResponse(chatManager *chat.Manager, platformManager *platform.Manager, terminal *ui.Terminal, state *types.AppState) bool {
+	languageRl, err := readline.NewEx(&readline.Config{
+		Prompt:      "\033[93mtranslate into: \033[0m",
+		HistoryFile: "/dev/null", // Disable history for the language prompt
+	})
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("error creating language input: %v", err))
+		return true
+	}
+	language, err := languageRl.Readline()
+	languageRl.Close()
+	if language = strings.TrimSpace(language); err != nil || language == "" {
+		return true
+	}
+
+	prompt := fmt.Sprintf("Translate your previous response into %s. Keep code, commands, names, and formatting unchanged.", language)
+	terminal.EchoInput(prompt)
+	sendPrompt(prompt, prompt, chatManager, platformManager, terminal, state)
+	return true
+}
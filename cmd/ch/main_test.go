@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/MehmetMHY/ch/internal/chat"
 	chconfig "github.com/MehmetMHY/ch/internal/config"
@@ -122,6 +123,60 @@ func captureStdout(t *testing.T, fn func()) string {
 	return string(out)
 }
 
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	original := os.Stderr
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error: %v", err)
+	}
+	os.Stderr = writer
+
+	fn()
+
+	writer.Close()
+	os.Stderr = original
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	return string(out)
+}
+
+func TestUIToStderrKeepsStdoutForResponses(t *testing.T) {
+	printAll := func(terminal *ui.Terminal) {
+		terminal.PrintInfo("info")
+		terminal.PrintSuccess("saved")
+		terminal.PrintError("failed")
+		terminal.PrintModelSwitch("gpt-4.1")
+	}
+
+	// Piped direct query: stdout gets plain success text, info is suppressed
+	piped := ui.NewTerminal(&types.Config{IsPipedOutput: true, CurrentPlatform: "openai"})
+	var stdout string
+	stderr := captureStderr(t, func() {
+		stdout = captureStdout(t, func() { printAll(piped) })
+	})
+	if stdout != "saved\n" || stderr != "failed\n" {
+		t.Errorf("piped output: stdout %q, stderr %q", stdout, stderr)
+	}
+
+	// Interactive with piped stdout: all UI text goes to stderr, in color
+	passthrough := ui.NewTerminal(&types.Config{IsPipedOutput: true, UIToStderr: true, CurrentPlatform: "openai"})
+	stderr = captureStderr(t, func() {
+		stdout = captureStdout(t, func() { printAll(passthrough) })
+	})
+	if stdout != "" {
+		t.Errorf("UI text leaked to stdout: %q", stdout)
+	}
+	for _, want := range []string{"\033[93minfo", "\033[92msaved", "\033[91mfailed", "gpt-4.1"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr missing %q: %q", want, stderr)
+		}
+	}
+}
+
 func TestHandleShowStateSessionFileRow(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
@@ -210,14 +265,14 @@ func TestProcessDirectQueryRemovesPendingMessageOnProviderError(t *testing.T) {
 	}
 	terminal := ui.NewTerminal(cfg)
 
-	if err := processDirectQuery("first prompt", chatManager, platformManager, terminal, state, false, false); err == nil {
+	if err := processDirectQuery("first prompt", chatManager, platformManager, terminal, state, false, false, false); err == nil {
 		t.Fatalf("expected first provider error")
 	}
 	if len(state.Messages) != 1 {
 		t.Fatalf("expected failed prompt to be removed, got %v", state.Messages)
 	}
 
-	if err := processDirectQuery("second prompt", chatManager, platformManager, terminal, state, false, false); err == nil {
+	if err := processDirectQuery("second prompt", chatManager, platformManager, terminal, state, false, false, false); err == nil {
 		t.Fatalf("expected second provider error")
 	}
 	if len(state.Messages) != 1 {
@@ -608,3 +663,443 @@ func TestFetchFlagFollowUpPrompt(t *testing.T) {
 		t.Fatalf("-f with prompt should fall through to direct query / platform init, got:\n%s", out)
 	}
 }
+
+func TestParseScrapeArgs(t *testing.T) {
+	urls, format := parseScrapeArgs([]string{"--md", "https://a.example", "https://b.example"}, "text")
+	if format != "markdown" {
+		t.Errorf("format = %q, want markdown", format)
+	}
+	if len(urls) != 2 || urls[0] != "https://a.example" || urls[1] != "https://b.example" {
+		t.Errorf("urls = %v", urls)
+	}
+
+	urls, format = parseScrapeArgs([]string{"https://a.example", "--text"}, "markdown")
+	if format != "text" || len(urls) != 1 {
+		t.Errorf("got urls=%v format=%q, want one URL in text format", urls, format)
+	}
+
+	urls, format = parseScrapeArgs([]string{"--md"}, "text")
+	if format != "markdown" || len(urls) != 0 {
+		t.Errorf("got urls=%v format=%q, want no URLs in markdown format", urls, format)
+	}
+}
+
+func TestDatasetFlagFiltersByRating(t *testing.T) {
+	binPath := testBinPath
+	home := t.TempDir()
+
+	writeChConfig(t, home, map[string]interface{}{
+		"enable_session_save": true,
+	})
+	writeSessionFile(t, home, "ch_session_1783572299.json", types.SessionFile{
+		Timestamp: 1783572299,
+		Rating:    5,
+		ChatHistory: []types.ChatHistory{
+			{User: "sys"},
+			{User: "keep this question", Bot: "keep this answer"},
+		},
+	})
+	writeSessionFile(t, home, "ch_session_1783572300.json", types.SessionFile{
+		Timestamp: 1783572300,
+		Rating:    1,
+		ChatHistory: []types.ChatHistory{
+			{User: "sys"},
+			{User: "drop this question", Bot: "drop this answer"},
+		},
+	})
+
+	out := runWithPreparedHome(t, binPath, home, "--dataset", "openai", "--min-rating", "4")
+
+	if !strings.Contains(out, `"content":"keep this answer"`) {
+		t.Fatalf("--dataset should export the highly rated session, got:\n%s", out)
+	}
+	if strings.Contains(out, "drop this question") {
+		t.Fatalf("--dataset --min-rating should skip low rated sessions, got:\n%s", out)
+	}
+}
+
+func TestParseTagList(t *testing.T) {
+	tags := parseTagList(" go, ,favorite,")
+	if len(tags) != 2 || tags[0] != "go" || tags[1] != "favorite" {
+		t.Errorf("parseTagList() = %v, want [go favorite]", tags)
+	}
+	if tags := parseTagList(""); tags != nil {
+		t.Errorf("parseTagList(\"\") = %v, want nil", tags)
+	}
+}
+
+func TestResolveFollowupShortcut(t *testing.T) {
+	followups := []string{"first question?", "second question?"}
+
+	if got, ok := resolveFollowupShortcut("2", followups); !ok || got != "second question?" {
+		t.Errorf("resolveFollowupShortcut(\"2\") = %q, %v", got, ok)
+	}
+	for _, input := range []string{"0", "3", "hello", "1 more"} {
+		if _, ok := resolveFollowupShortcut(input, followups); ok {
+			t.Errorf("resolveFollowupShortcut(%q) should not match", input)
+		}
+	}
+	if _, ok := resolveFollowupShortcut("1", nil); ok {
+		t.Error("resolveFollowupShortcut should not match without suggestions")
+	}
+}
+
+func TestParseStopSequence(t *testing.T) {
+	tests := map[string]string{
+		`\n\n`:     "\n\n",
+		`END`:      "END",
+		`say "hi"`: `say "hi"`,
+		`\q`:       `\q`,
+	}
+	for in, want := range tests {
+		if got := parseStopSequence(in); got != want {
+			t.Errorf("parseStopSequence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseExportArgs(t *testing.T) {
+	opts, err := parseExportArgs([]string{"--last", "3", "notes.md", "--role", "assistant", "--strip-context", "--format", "Markdown", "--since", "2h"})
+	if err != nil {
+		t.Fatalf("parseExportArgs: %v", err)
+	}
+	if !opts.filtered || opts.target != "notes.md" || opts.format != "markdown" {
+		t.Errorf("got %+v", opts)
+	}
+	if opts.filter.Last != 3 || opts.filter.Role != "assistant" || !opts.filter.StripContext {
+		t.Errorf("filter = %+v", opts.filter)
+	}
+	if since := time.Since(opts.filter.Since); since < 2*time.Hour || since > 2*time.Hour+time.Minute {
+		t.Errorf("--since 2h gave %v", opts.filter.Since)
+	}
+
+	opts, err = parseExportArgs([]string{"session.json"})
+	if err != nil || opts.filtered || opts.target != "session.json" {
+		t.Errorf("plain target: %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{{"--since", "yesterday-ish"}, {"--last", "-1"}, {"a", "b"}, {"--bogus"}} {
+		if _, err := parseExportArgs(args); err == nil {
+			t.Errorf("parseExportArgs(%q) should fail", args)
+		}
+	}
+}
+
+func TestParseTailArgs(t *testing.T) {
+	opts, err := parseTailArgs([]string{"-f", "app.log", "--ask", "alert me on errors", "--interval", "5s"})
+	if err != nil {
+		t.Fatalf("parseTailArgs: %v", err)
+	}
+	if opts.Path != "app.log" || opts.Ask != "alert me on errors" || opts.Interval != 5*time.Second || opts.MaxLines != 500 {
+		t.Errorf("got %+v", opts)
+	}
+
+	for _, args := range [][]string{{}, {"a.log", "b.log"}, {"a.log", "--interval", "0s"}, {"a.log", "--max-lines", "0"}, {"a.log", "--bogus"}} {
+		if _, err := parseTailArgs(args); err == nil {
+			t.Errorf("parseTailArgs(%q) should fail", args)
+		}
+	}
+}
+
+func TestParseEmbedArgs(t *testing.T) {
+	cfg := &types.Config{EmbeddingModel: "text-embedding-3-small", EmbeddingBatchSize: 100}
+
+	opts, err := parseEmbedArgs([]string{"a.txt", "--model", "text-embedding-3-large", "b.txt", "--format", "BINARY", "--lines"}, cfg)
+	if err != nil {
+		t.Fatalf("parseEmbedArgs: %v", err)
+	}
+	if opts.model != "text-embedding-3-large" || opts.format != "binary" || !opts.lines || opts.batchSize != 100 {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+	if strings.Join(opts.files, ",") != "a.txt,b.txt" {
+		t.Errorf("files = %v", opts.files)
+	}
+
+	opts, err = parseEmbedArgs(nil, cfg)
+	if err != nil || opts.model != "text-embedding-3-small" || opts.format != "json" {
+		t.Errorf("defaults not applied: %+v, %v", opts, err)
+	}
+
+	if _, err := parseEmbedArgs([]string{"--format", "csv"}, cfg); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestParseTestArgs(t *testing.T) {
+	opts, err := parseTestArgs([]string{"-o", "groq|llama-3.1-8b", "say", "--json", "pong"}, "openai", "gpt-4o")
+	if err != nil {
+		t.Fatalf("parseTestArgs: %v", err)
+	}
+	if opts.platform != "groq" || opts.model != "llama-3.1-8b" || !opts.json || opts.prompt != "say pong" || opts.timeout != 30*time.Second {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	opts, err = parseTestArgs([]string{"--timeout", "5s"}, "openai", "gpt-4o")
+	if err != nil || opts.platform != "openai" || opts.model != "gpt-4o" || opts.prompt != "ping" || opts.timeout != 5*time.Second {
+		t.Errorf("defaults not applied: %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{{"-o", "groq"}, {"-o", "|model"}, {"--timeout", "0s"}} {
+		if _, err := parseTestArgs(args, "openai", "gpt-4o"); err == nil {
+			t.Errorf("parseTestArgs(%q) expected an error", args)
+		}
+	}
+}
+
+func TestFormatTestResult(t *testing.T) {
+	ok := platform.ProbeResult{Platform: "openai", Model: "gpt-4o", OK: true, LatencyMS: 812, PromptTokens: 8, CompletionTokens: 2, Output: "pong"}
+	if got := formatTestResult(ok); got != "ok openai|gpt-4o 812ms 8 in/2 out tokens: pong" {
+		t.Errorf("formatTestResult(ok) = %q", got)
+	}
+	failed := platform.ProbeResult{Platform: "groq", Model: "llama", LatencyMS: 30000, Error: "context deadline exceeded"}
+	if got := formatTestResult(failed); got != "fail groq|llama 30000ms: context deadline exceeded" {
+		t.Errorf("formatTestResult(failed) = %q", got)
+	}
+}
+
+func TestCollectEmbedInputs(t *testing.T) {
+	inputs, labels, err := collectEmbedInputs(embedOptions{lines: true}, "first\n\n second \n")
+	if err != nil {
+		t.Fatalf("collectEmbedInputs: %v", err)
+	}
+	if strings.Join(inputs, "|") != "first|second" || strings.Join(labels, "|") != "stdin:1|stdin:3" {
+		t.Errorf("inputs = %q, labels = %q", inputs, labels)
+	}
+
+	if _, _, err := collectEmbedInputs(embedOptions{}, ""); err == nil {
+		t.Error("expected an error with no files and no stdin")
+	}
+}
+
+func TestWriteEmbeddings(t *testing.T) {
+	vectors := [][]float32{{1, 2}, {3, 4}}
+
+	var jsonOut strings.Builder
+	if err := writeEmbeddings(&jsonOut, "json", []string{"a", "b"}, vectors); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonOut.String()), "\n")
+	if len(lines) != 2 || lines[1] != `{"index":1,"source":"b","embedding":[3,4]}` {
+		t.Errorf("json output = %q", jsonOut.String())
+	}
+
+	var binOut strings.Builder
+	if err := writeEmbeddings(&binOut, "binary", nil, vectors); err != nil {
+		t.Fatalf("binary: %v", err)
+	}
+	if binOut.Len() != 16 {
+		t.Errorf("binary output is %d bytes, want 16", binOut.Len())
+	}
+}
+
+func TestWorkspaceSwitchScopesSessions(t *testing.T) {
+	home := t.TempDir()
+	project := t.TempDir()
+	writeChConfig(t, home, map[string]interface{}{"workspaces": true})
+
+	out := runWithPreparedHomeDir(t, testBinPath, home, project, "ws", "switch", "Client Work")
+	if !strings.Contains(out, "-> client-work") {
+		t.Fatalf("unexpected switch output: %q", out)
+	}
+
+	sessionDir := filepath.Join(home, ".ch", "tmp", "ws", "client-work")
+	if err := os.MkdirAll(sessionDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sessionDir, "ch_session_1.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out = runWithPreparedHomeDir(t, testBinPath, home, project, "ws", "list")
+	if !strings.Contains(out, "* client-work (1 sessions)") {
+		t.Errorf("current workspace not listed: %q", out)
+	}
+
+	out = runWithPreparedHomeDir(t, testBinPath, home, t.TempDir(), "ws", "list")
+	if strings.Contains(out, "* client-work") {
+		t.Errorf("other directories should not use the switched workspace: %q", out)
+	}
+}
+
+func TestWorkspaceCommandRequiresOptIn(t *testing.T) {
+	home := t.TempDir()
+	out := runWithPreparedHome(t, testBinPath, home, "ws", "list")
+	if !strings.Contains(out, "workspaces are disabled") {
+		t.Errorf("expected disabled message, got %q", out)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"1d":  24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"6m":  180 * 24 * time.Hour,
+		"1y":  365 * 24 * time.Hour,
+		"90d": 90 * 24 * time.Hour,
+	}
+	for input, want := range cases {
+		got, err := parseAge(input)
+		if err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "d", "10", "3h", "-1d"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("parseAge(%q) should fail", bad)
+		}
+	}
+}
+
+func TestParseSummarizeArgs(t *testing.T) {
+	cfg := &types.Config{SummarizeChunkTokens: 6000, SummarizeOverlapTokens: 200, SummarizeParallel: 4}
+
+	parsed, err := parseSummarizeArgs([]string{"report.pdf", "--chunk-size", "2000", "focus", "on", "risks", "--parallel", "2"}, cfg)
+	if err != nil {
+		t.Fatalf("parseSummarizeArgs: %v", err)
+	}
+	if parsed.target != "report.pdf" || parsed.opts.Instruction != "focus on risks" {
+		t.Errorf("target/instruction = %q/%q", parsed.target, parsed.opts.Instruction)
+	}
+	if parsed.opts.ChunkTokens != 2000 || parsed.opts.OverlapTokens != 200 || parsed.opts.Parallel != 2 {
+		t.Errorf("unexpected options: %+v", parsed.opts)
+	}
+
+	if _, err := parseSummarizeArgs([]string{"--chunk-size", "0"}, cfg); err == nil {
+		t.Error("expected an error for a zero chunk size")
+	}
+}
+
+func TestFormatSpend(t *testing.T) {
+	if got := formatSpend(0, &types.Config{}); got != "" {
+		t.Errorf("no limits and no spend should hide the line, got %q", got)
+	}
+	if got := formatSpend(0.5, &types.Config{MaxSessionCost: 2}); got != "$0.5000 session of $2.00" {
+		t.Errorf("formatSpend = %q", got)
+	}
+}
+
+func TestModelLockBlocksUnconfirmedSwitch(t *testing.T) {
+	cfg := chconfig.DefaultConfig()
+	cfg.IsPipedOutput = true
+	cfg.CurrentModel = "primed-model"
+	state := &types.AppState{Config: cfg}
+	chatManager := chat.NewManager(state)
+	platformManager := platform.NewManager(cfg)
+	terminal := ui.NewTerminal(cfg)
+
+	// Nobody can confirm when stdin is not a terminal
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	previousStdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = previousStdin }()
+
+	handleSpecialCommands(cfg.LockModel, chatManager, platformManager, terminal, state, true, nil)
+	if !state.ModelLocked {
+		t.Fatal("!lock should lock the conversation")
+	}
+	handleSpecialCommands(cfg.ModelSwitch+" other-model", chatManager, platformManager, terminal, state, true, nil)
+	if got := chatManager.GetCurrentModel(); got != "primed-model" {
+		t.Errorf("locked switch went through without confirmation, model is %q", got)
+	}
+
+	handleSpecialCommands(cfg.UnlockModel, chatManager, platformManager, terminal, state, true, nil)
+	handleSpecialCommands(cfg.ModelSwitch+" other-model", chatManager, platformManager, terminal, state, true, nil)
+	if got := chatManager.GetCurrentModel(); got != "other-model" {
+		t.Errorf("unlocked switch should apply, model is %q", got)
+	}
+}
+
+func TestOpenCodeFence(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain question", ""},
+		{"fix this:\n```go\nfunc main() {", "```"},
+		{"```go\nfmt.Println(1)\n```", ""},
+		{"````md\n```go\n```\n", "````"},
+		{"~~~\ncode", "~~~"},
+		{"~~~\n```\n~~~", ""},
+		{"```python\nx = 1\n``` trailing text", "```"},
+		{"inline ``` mention", ""},
+	}
+	for _, tt := range tests {
+		if got := openCodeFence(tt.text); got != tt.want {
+			t.Errorf("openCodeFence(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseSaveCodeBlock(t *testing.T) {
+	tests := []struct {
+		input  string
+		n      int
+		path   string
+		wantOK bool
+	}{
+		{"!save", 1, "", true},
+		{"!save 2 main.go", 2, "main.go", true},
+		{"!save notes.md", 1, "notes.md", true},
+		{"!save 0", 0, "", false},
+		{"!s3", 3, "", true},
+		{"!s12 out/handler.go", 12, "out/handler.go", true},
+	}
+	for _, tt := range tests {
+		n, path, ok := parseSaveCodeBlock(tt.input, "!save")
+		if n != tt.n || path != tt.path || ok != tt.wantOK {
+			t.Errorf("parseSaveCodeBlock(%q) = %d, %q, %v, want %d, %q, %v", tt.input, n, path, ok, tt.n, tt.path, tt.wantOK)
+		}
+	}
+	if saveShortcutPattern.MatchString("!s") || saveShortcutPattern.MatchString("!s https://example.com") {
+		t.Error("the save shortcut should not match scrape commands")
+	}
+}
+
+func TestWriteCodeDumpWithManifest(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	dump := &ui.CodeDump{Dir: "/src", Total: 1, Files: []ui.CodeDumpFile{
+		{Path: "main.go", Content: "package main\n", Size: 13, SHA256: "abc"},
+	}}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = writeCodeDump(dump, "markdown", "", false, true)
+	})
+	if err != nil {
+		t.Fatalf("writeCodeDump() error: %v", err)
+	}
+	names := strings.Fields(output)
+	if len(names) != 2 || !strings.HasSuffix(names[0], ".md") || names[1] != strings.TrimSuffix(names[0], ".md")+".manifest.json" {
+		t.Fatalf("unexpected output files: %q", output)
+	}
+
+	data, err := os.ReadFile(names[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest codeDumpManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Dump != names[0] || manifest.Format != "markdown" || len(manifest.Files) != 1 || manifest.Files[0].Tokens == 0 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	// With --stdout the dump itself goes to stdout and no dump file is created
+	output = captureStdout(t, func() {
+		err = writeCodeDump(dump, "", "", true, false)
+	})
+	if err != nil || !strings.HasPrefix(output, "=== Code Dump ===") {
+		t.Errorf("stdout dump = %q, %v", output, err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.txt")); len(files) != 0 {
+		t.Errorf("--stdout should not write a dump file, found %v", files)
+	}
+}
@@ -0,0 +1,143 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// AnswerMatch is an assistant answer found by SearchAnswers
+type AnswerMatch struct {
+	Session *types.SessionFile
+	Index   int // position of the answer in Session.ChatHistory
+}
+
+// Entry returns the history entry holding the answer
+func (a *AnswerMatch) Entry() types.ChatHistory {
+	return a.Session.ChatHistory[a.Index]
+}
+
+// Context formats the answer with the question before it and the question
+// that followed it, for display before choosing what to do with it
+func (a *AnswerMatch) Context() []types.ChatMessage {
+	var turns []types.ChatMessage
+	if a.Index > 1 {
+		if previous := a.Session.ChatHistory[a.Index-1]; previous.Bot != "" {
+			turns = append(turns, types.ChatMessage{Role: "assistant", Content: previewLine(previous.Bot, 200)})
+		}
+	}
+	entry := a.Entry()
+	if entry.User != "" {
+		turns = append(turns, types.ChatMessage{Role: "user", Content: entry.User})
+	}
+	turns = append(turns, types.ChatMessage{Role: "assistant", Content: entry.Bot})
+	if a.Index+1 < len(a.Session.ChatHistory) {
+		if next := a.Session.ChatHistory[a.Index+1]; next.User != "" {
+			turns = append(turns, types.ChatMessage{Role: "user", Content: previewLine(next.User, 200)})
+		}
+	}
+	return turns
+}
+
+// InjectionContent formats the answer for adding to the current chat
+func (a *AnswerMatch) InjectionContent() string {
+	entry := a.Entry()
+	stamp := time.Unix(entry.Time, 0).UTC().Format("2006-01-02 15:04 UTC")
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Earlier answer from %s (%s):\n", filepath.Base(a.Session.SourceFile), stamp))
+	if entry.User != "" {
+		sb.WriteString(fmt.Sprintf("\nQuestion:\n%s\n", entry.User))
+	}
+	sb.WriteString(fmt.Sprintf("\nAnswer:\n%s", entry.Bot))
+	return sb.String()
+}
+
+// SearchAnswers searches only assistant answers across saved sessions with
+// fzf. It accepts the same filters as SearchSessions; exact or --exact turns
+// on exact matching.
+func (m *Manager) SearchAnswers(terminal *ui.Terminal, args []string) (*AnswerMatch, error) {
+	if !m.state.Config.SaveAllSessions {
+		return nil, fmt.Errorf("answer search requires save_all_sessions to be enabled in config")
+	}
+
+	filter := parseSessionSearchArgs(args)
+	sessions, err := m.loadFilteredSessions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, matches := answerSearchLines(sessions)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no answers found matching criteria")
+	}
+
+	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=search answers: "}
+	if filter.exact {
+		fzfArgs = append(fzfArgs, "--exact")
+	}
+	fzfCmd := exec.Command("fzf", fzfArgs...)
+	fzfCmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	fzfCmd.Stderr = os.Stderr
+
+	output, err := ui.OutputForeground(fzfCmd)
+	if err != nil {
+		return nil, fmt.Errorf("selection cancelled")
+	}
+	selected := strings.TrimSpace(string(output))
+	if match, ok := matches[selected]; ok {
+		return match, nil
+	}
+	return nil, fmt.Errorf("no selection made")
+}
+
+// answerSearchLines builds one fzf line per assistant answer, newest first.
+// Each line carries the answer with its question so matches show their turn.
+func answerSearchLines(sessions []*types.SessionFile) ([]string, map[string]*AnswerMatch) {
+	type answer struct {
+		line  string
+		time  int64
+		match *AnswerMatch
+	}
+
+	var answers []answer
+	for _, session := range sessions {
+		for i, entry := range session.ChatHistory {
+			if i == 0 || entry.Bot == "" {
+				continue
+			}
+			line := fmt.Sprintf("%s %s => %s",
+				time.Unix(entry.Time, 0).UTC().Format("2006-01-02 15:04"),
+				previewLine(entry.User, 40),
+				previewLine(entry.Bot, 120))
+			answers = append(answers, answer{line: line, time: entry.Time, match: &AnswerMatch{Session: session, Index: i}})
+		}
+	}
+	sort.SliceStable(answers, func(i, j int) bool { return answers[i].time > answers[j].time })
+
+	lines := make([]string, 0, len(answers))
+	matches := make(map[string]*AnswerMatch, len(answers))
+	for _, a := range answers {
+		if _, seen := matches[a.line]; seen {
+			continue
+		}
+		lines = append(lines, a.line)
+		matches[a.line] = a.match
+	}
+	return lines, matches
+}
+
+// previewLine collapses whitespace and truncates text for one-line display
+func previewLine(text string, limit int) string {
+	preview := strings.Join(strings.Fields(text), " ")
+	if len(preview) > limit {
+		preview = preview[:limit] + "..."
+	}
+	return preview
+}
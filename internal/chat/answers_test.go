@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestParseSessionSearchArgs(t *testing.T) {
+	filter := parseSessionSearchArgs([]string{"--exact", "#work", "tag:favorite", "100-200"})
+	if !filter.exact {
+		t.Error("--exact should enable exact matching")
+	}
+	if !reflect.DeepEqual(filter.tags, []string{"#work", "favorite"}) {
+		t.Errorf("tags = %v", filter.tags)
+	}
+	if filter.minTime != 100 || filter.maxTime != 200 {
+		t.Errorf("range = %d-%d, want 100-200", filter.minTime, filter.maxTime)
+	}
+
+	if filter := parseSessionSearchArgs([]string{"exact", "ch_session_1.json"}); !filter.exact || filter.targetFile != "ch_session_1.json" {
+		t.Errorf("unexpected filter: %+v", filter)
+	}
+}
+
+func TestAnswerSearchLines(t *testing.T) {
+	older := &types.SessionFile{SourceFile: "/tmp/ch_session_1.json", ChatHistory: []types.ChatHistory{
+		{User: "Sys"},
+		{User: "What is Go?", Bot: "A  programming\nlanguage.", Time: 100},
+		{User: "Loaded file", Context: "file body", Time: 110},
+	}}
+	newer := &types.SessionFile{SourceFile: "/tmp/ch_session_2.json", ChatHistory: []types.ChatHistory{
+		{User: "Sys"},
+		{User: "Hi", Bot: "Hello!", Time: 200},
+	}}
+
+	lines, matches := answerSearchLines([]*types.SessionFile{older, newer})
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per answer, got %q", lines)
+	}
+	if !strings.HasSuffix(lines[0], "Hi => Hello!") {
+		t.Errorf("newest answer should come first, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "What is Go? => A programming language.") {
+		t.Errorf("answer whitespace should be collapsed, got %q", lines[1])
+	}
+	if match := matches[lines[1]]; match.Session != older || match.Index != 1 {
+		t.Errorf("line maps to the wrong answer: %+v", match)
+	}
+}
+
+func TestAnswerMatchContextAndInjection(t *testing.T) {
+	session := &types.SessionFile{SourceFile: "/tmp/ch_session_1.json", ChatHistory: []types.ChatHistory{
+		{User: "Sys"},
+		{User: "First?", Bot: "One.", Time: 100},
+		{User: "Second?", Bot: "Two.", Time: 110},
+		{User: "Third?", Bot: "Three.", Time: 120},
+	}}
+	match := &AnswerMatch{Session: session, Index: 2}
+
+	want := []types.ChatMessage{
+		{Role: "assistant", Content: "One."},
+		{Role: "user", Content: "Second?"},
+		{Role: "assistant", Content: "Two."},
+		{Role: "user", Content: "Third?"},
+	}
+	if got := match.Context(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Context() = %+v, want %+v", got, want)
+	}
+
+	content := match.InjectionContent()
+	for _, part := range []string{"ch_session_1.json", "Question:\nSecond?", "Answer:\nTwo."} {
+		if !strings.Contains(content, part) {
+			t.Errorf("injected content missing %q:\n%s", part, content)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// applyInstructions ask the model for edits ApplyPatches can parse
+const applyInstructions = `Reply with your changes as search/replace blocks. Put the file path alone on the line before each block:
+
+path/to/file.go
+<<<<<<< SEARCH
+lines exactly as they are in the file now
+=======
+the lines that replace them
+>>>>>>> REPLACE
+
+The SEARCH part must match the file exactly, including indentation, and be just long enough to be unique. Use several blocks for several changes, and an empty SEARCH part to create a new file. Paths are relative to the current directory.`
+
+// Markers of a search/replace block
+const (
+	searchMarker  = "<<<<<<< SEARCH"
+	dividerMarker = "======="
+	replaceMarker = ">>>>>>> REPLACE"
+)
+
+// Patch replaces the first occurrence of Search in the file at Path with
+// Replace. An empty Search creates the file.
+type Patch struct {
+	Path    string
+	Search  string
+	Replace string
+}
+
+// FileChange is the result of applying every patch for one file
+type FileChange struct {
+	Path    string
+	Old     string
+	New     string
+	Created bool
+}
+
+// WithApplyInstructions appends the edit format ApplyPatches understands to
+// request
+func WithApplyInstructions(request string) string {
+	return request + "\n\n" + applyInstructions
+}
+
+// ParsePatches reads search/replace blocks from text, or unified diffs when
+// there are none
+func ParsePatches(text string) ([]Patch, error) {
+	return parsePatches(text, "")
+}
+
+// parsePatches is ParsePatches with the path used for edits that do not
+// name a file
+func parsePatches(text, defaultPath string) ([]Patch, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.Contains(text, searchMarker) {
+		return parseSearchReplace(text, defaultPath)
+	}
+	return parseUnifiedDiffs(text, defaultPath)
+}
+
+// parseSearchReplace reads search/replace blocks, taking each block's path
+// from the last line of text before it
+func parseSearchReplace(text, defaultPath string) ([]Patch, error) {
+	var patches []Patch
+	lines := strings.Split(text, "\n")
+	path := defaultPath
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line != searchMarker {
+			if line != "" && !strings.HasPrefix(line, "```") {
+				path = patchPath(line)
+			}
+			continue
+		}
+		if path == "" {
+			return nil, fmt.Errorf("search/replace block without a file path")
+		}
+
+		var search, replace []string
+		section := &search
+		closed := false
+		for i++; i < len(lines); i++ {
+			switch strings.TrimSpace(lines[i]) {
+			case dividerMarker:
+				section = &replace
+				continue
+			case replaceMarker:
+				closed = true
+			}
+			if closed {
+				break
+			}
+			*section = append(*section, lines[i])
+		}
+		if !closed {
+			return nil, fmt.Errorf("unterminated search/replace block for %s", path)
+		}
+		patches = append(patches, Patch{Path: path, Search: strings.Join(search, "\n"), Replace: strings.Join(replace, "\n")})
+	}
+	return patches, nil
+}
+
+// patchPath cleans a file path line, which models often wrap in backticks
+// or bold, or label with "File:"
+func patchPath(line string) string {
+	line = strings.TrimPrefix(line, "File:")
+	line = strings.Trim(line, " `*:")
+	line = strings.TrimPrefix(line, "#")
+	return strings.TrimSpace(line)
+}
+
+// parseUnifiedDiffs turns each hunk of the unified diffs in text into a
+// patch of its context and removed lines. Line numbers are ignored, so
+// hunks still apply when the model miscounts.
+func parseUnifiedDiffs(text, defaultPath string) ([]Patch, error) {
+	var patches []Patch
+	lines := strings.Split(text, "\n")
+	path, created := defaultPath, false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			oldPath, newPath := diffPath(line[4:]), diffPath(lines[i+1][4:])
+			if newPath == "/dev/null" {
+				return nil, fmt.Errorf("deleting files is not supported: %s", oldPath)
+			}
+			path, created = newPath, oldPath == "/dev/null"
+			i++
+			continue
+		}
+		if !strings.HasPrefix(line, "@@") || path == "" {
+			continue
+		}
+
+		var search, replace []string
+		for i+1 < len(lines) {
+			next := lines[i+1]
+			if next == "" {
+				// Blank context lines often lose their leading space
+				search, replace = append(search, ""), append(replace, "")
+			} else if kind := next[0]; kind == ' ' || kind == '-' || kind == '+' {
+				if strings.HasPrefix(next, "--- ") && i+2 < len(lines) && strings.HasPrefix(lines[i+2], "+++ ") {
+					break
+				}
+				if kind != '+' {
+					search = append(search, next[1:])
+				}
+				if kind != '-' {
+					replace = append(replace, next[1:])
+				}
+			} else if kind != '\\' {
+				break
+			}
+			i++
+		}
+		search, replace = trimTrailingBlank(search, replace)
+		if created {
+			patches = append(patches, Patch{Path: path, Replace: strings.Join(replace, "\n")})
+			continue
+		}
+		patches = append(patches, Patch{Path: path, Search: strings.Join(search, "\n"), Replace: strings.Join(replace, "\n")})
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no search/replace blocks or unified diffs found")
+	}
+	return patches, nil
+}
+
+// diffPath reads the path of a ---/+++ line, dropping the a/ or b/ prefix
+// and any timestamp
+func diffPath(field string) string {
+	field, _, _ = strings.Cut(field, "\t")
+	field = strings.TrimSpace(field)
+	if field == "/dev/null" {
+		return field
+	}
+	if strings.HasPrefix(field, "a/") || strings.HasPrefix(field, "b/") {
+		field = field[2:]
+	}
+	return field
+}
+
+// trimTrailingBlank drops blank lines both sides of a hunk end with, such
+// as the empty line before a closing code fence
+func trimTrailingBlank(search, replace []string) ([]string, []string) {
+	for len(search) > 0 && len(replace) > 0 && search[len(search)-1] == "" && replace[len(replace)-1] == "" {
+		search, replace = search[:len(search)-1], replace[:len(replace)-1]
+	}
+	return search, replace
+}
+
+// PlanPatches applies patches to the files under root in memory, in order,
+// returning one change per file. Paths must stay inside root.
+func PlanPatches(patches []Patch, root string) ([]FileChange, error) {
+	var changes []FileChange
+	byPath := make(map[string]int)
+	for _, patch := range patches {
+		path, err := patchTarget(root, patch.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		k, ok := byPath[path]
+		if !ok {
+			change := FileChange{Path: path}
+			data, err := os.ReadFile(filepath.Join(root, path)) // #nosec G304 -- patchTarget keeps paths inside the working directory.
+			switch {
+			case err == nil:
+				change.Old = string(data)
+			case os.IsNotExist(err) && patch.Search == "":
+				change.Created = true
+			default:
+				return nil, fmt.Errorf("cannot read %s: %v", path, err)
+			}
+			change.New = change.Old
+			changes = append(changes, change)
+			k = len(changes) - 1
+			byPath[path] = k
+		}
+
+		updated, err := applyPatch(changes[k].New, patch, changes[k].Created)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		changes[k].New = updated
+	}
+	return changes, nil
+}
+
+// patchTarget resolves path against root, rejecting paths that leave it
+func patchTarget(root, path string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return "", fmt.Errorf("invalid file path %q: use a path relative to the current directory", path)
+	}
+	clean := filepath.Clean(path)
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid file path %q: it is outside the current directory", path)
+	}
+	return clean, nil
+}
+
+// applyPatch replaces the first run of lines in content matching
+// patch.Search, comparing without trailing whitespace when there is no
+// exact match. An empty Search writes a new file's content.
+func applyPatch(content string, patch Patch, created bool) (string, error) {
+	if patch.Search == "" {
+		if !created {
+			return "", fmt.Errorf("empty SEARCH part, but the file already exists")
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += patch.Replace
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	search := strings.Split(patch.Search, "\n")
+	for _, same := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	} {
+		for i := 0; i+len(search) <= len(lines); i++ {
+			match := true
+			for j, line := range search {
+				if !same(lines[i+j], line) {
+					match = false
+					break
+				}
+			}
+			if match {
+				updated := append([]string{}, lines[:i]...)
+				updated = append(updated, strings.Split(patch.Replace, "\n")...)
+				updated = append(updated, lines[i+len(search):]...)
+				return strings.Join(updated, "\n"), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("SEARCH part not found:\n%s", patch.Search)
+}
+
+// ApplyPatches reads edits from the last response, shows a colored diff of
+// each file they change, and writes the files once confirmed. It returns
+// the paths written.
+func (m *Manager) ApplyPatches(terminal *ui.Terminal) ([]string, error) {
+	if len(m.state.ChatHistory) <= 1 || m.state.ChatHistory[len(m.state.ChatHistory)-1].Bot == "" {
+		return nil, fmt.Errorf("no response to apply edits from")
+	}
+	patches, err := ParsePatches(m.state.ChatHistory[len(m.state.ChatHistory)-1].Bot)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %v", err)
+	}
+	changes, err := PlanPatches(patches, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []FileChange
+	for _, change := range changes {
+		if change.New == change.Old && !change.Created {
+			continue
+		}
+		pending = append(pending, change)
+		fmt.Fprint(terminal.UIWriter(), colorDiff(change))
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("the edits change nothing")
+	}
+	if !terminal.Confirm(fmt.Sprintf("apply changes to %d file(s)?", len(pending))) {
+		return nil, nil
+	}
+
+	var written []string
+	for _, change := range pending {
+		fullPath := filepath.Join(root, change.Path)
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(fullPath); err == nil {
+			mode = info.Mode().Perm()
+		} else if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return written, fmt.Errorf("failed to create directory for %s: %v", change.Path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(change.New), mode); err != nil {
+			return written, fmt.Errorf("failed to write %s: %v", change.Path, err)
+		}
+		m.AddRecentlyCreatedFile(fullPath)
+		written = append(written, change.Path)
+	}
+	return written, nil
+}
+
+// colorDiff renders a file change as a unified diff with removed lines in
+// red, added lines in green, and hunk headers in cyan
+func colorDiff(change FileChange) string {
+	label := change.Path
+	if change.Created {
+		label += " (new file)"
+	}
+	diff, ok := unifiedDiff(change.Old, change.New, change.Path)
+	if !ok {
+		return fmt.Sprintf("\033[93m%s: %d lines -> %d lines (too large to show)\033[0m\n", label, strings.Count(change.Old, "\n"), strings.Count(change.New, "\n"))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\033[93m%s\033[0m\n", label)
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			continue
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprintf(&out, "\033[96m%s\033[0m\n", line)
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintf(&out, "\033[91m%s\033[0m\n", line)
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprintf(&out, "\033[92m%s\033[0m\n", line)
+		default:
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestParsePatches(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Patch
+	}{
+		{
+			name: "search/replace blocks",
+			text: "Here is the fix:\n\n`main.go`\n```go\n<<<<<<< SEARCH\n\treturn nil\n=======\n\treturn err\n>>>>>>> REPLACE\n```\n\n**docs/new.md**\n<<<<<<< SEARCH\n=======\n# New\n>>>>>>> REPLACE\n",
+			want: []Patch{
+				{Path: "main.go", Search: "\treturn nil", Replace: "\treturn err"},
+				{Path: "docs/new.md", Replace: "# New"},
+			},
+		},
+		{
+			name: "unified diff",
+			text: "```diff\n--- a/main.go\n+++ b/main.go\n@@ -10,3 +10,3 @@\n func run() error {\n-\treturn nil\n+\treturn err\n }\n```\n",
+			want: []Patch{{Path: "main.go", Search: "func run() error {\n\treturn nil\n}", Replace: "func run() error {\n\treturn err\n}"}},
+		},
+		{
+			name: "unified diff creating a file",
+			text: "--- /dev/null\n+++ b/notes.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n",
+			want: []Patch{{Path: "notes.txt", Replace: "one\ntwo"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePatches(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePatches() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParsePatches("no edits here"); err == nil {
+		t.Error("ParsePatches() without edits succeeded")
+	}
+	if _, err := ParsePatches("main.go\n<<<<<<< SEARCH\nx\n=======\ny\n"); err == nil || !strings.Contains(err.Error(), "unterminated") {
+		t.Errorf("ParsePatches() unterminated block error = %v", err)
+	}
+}
+
+func TestPlanPatches(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc run() error {   \n\treturn nil\n}\n"), 0600)
+
+	changes, err := PlanPatches([]Patch{
+		{Path: "main.go", Search: "func run() error {\n\treturn nil", Replace: "func run() error {\n\treturn errNope"},
+		{Path: "./main.go", Search: "package main", Replace: "package app"},
+		{Path: "sub/new.txt", Replace: "hello"},
+	}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FileChange{
+		{Path: "main.go", Old: "package main\n\nfunc run() error {   \n\treturn nil\n}\n", New: "package app\n\nfunc run() error {\n\treturn errNope\n}\n"},
+		{Path: filepath.Join("sub", "new.txt"), New: "hello\n", Created: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("PlanPatches() = %#v, want %#v", changes, want)
+	}
+
+	for _, tt := range []struct {
+		patch Patch
+		want  string
+	}{
+		{Patch{Path: "main.go", Search: "missing", Replace: "x"}, "SEARCH part not found"},
+		{Patch{Path: "main.go", Replace: "x"}, "already exists"},
+		{Patch{Path: "../outside.go", Search: "a", Replace: "b"}, "outside the current directory"},
+		{Patch{Path: "/etc/hosts", Search: "a", Replace: "b"}, "relative to the current directory"},
+		{Patch{Path: "gone.go", Search: "a", Replace: "b"}, "cannot read"},
+	} {
+		if _, err := PlanPatches([]Patch{tt.patch}, root); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("PlanPatches(%+v) error = %v, want %q", tt.patch, err, tt.want)
+		}
+	}
+}
+
+func TestManager_ApplyPatchesNeedsConfirmation(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.WriteFile("main.go", []byte("package main\n"), 0600)
+
+	cfg := &types.Config{IsPipedOutput: true}
+	m := NewManager(&types.AppState{Config: cfg, ChatHistory: []types.ChatHistory{{}}})
+	m.AddToHistory("rename the package", "main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage app\n>>>>>>> REPLACE\n")
+
+	// Confirm declines when stdin is not a terminal, so nothing is written
+	written, err := m.ApplyPatches(ui.NewTerminal(cfg))
+	if err != nil || len(written) != 0 {
+		t.Fatalf("ApplyPatches() = %v, %v; want nothing written", written, err)
+	}
+	if data, _ := os.ReadFile("main.go"); string(data) != "package main\n" {
+		t.Errorf("main.go = %q, want it unchanged", data)
+	}
+
+	m.AddToHistory("again", "main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage main\n>>>>>>> REPLACE\n")
+	if _, err := m.ApplyPatches(ui.NewTerminal(cfg)); err == nil || !strings.Contains(err.Error(), "change nothing") {
+		t.Errorf("ApplyPatches() with a no-op edit error = %v", err)
+	}
+}
+
+func TestColorDiff(t *testing.T) {
+	got := colorDiff(FileChange{Path: "a.txt", Old: "one\ntwo\n", New: "one\n2\n"})
+	for _, want := range []string{"\033[93ma.txt\033[0m", "\033[91m-two\033[0m", "\033[92m+2\033[0m", "\033[96m@@"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("colorDiff() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "+++") {
+		t.Errorf("colorDiff() = %q, want file headers dropped", got)
+	}
+}
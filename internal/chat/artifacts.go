@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// artifactWordTrim is stripped from both ends of a word in tool output
+// or command before it is checked as a file path, e.g. quotes or a trailing period
+const artifactWordTrim = "\"'`()[]{}<>,;:."
+
+// findArtifacts returns the files written since a tool call started that
+// its command or output names, e.g. "> out.csv" or "saved to
+// /tmp/chart.png", as absolute paths. Only named paths are checked, so a
+// call in a large tree does not walk it.
+func findArtifacts(command, output string, since time.Time) []string {
+	// Some filesystems keep whole-second modification times
+	since = since.Truncate(time.Second)
+
+	var artifacts []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(command + "\n" + output) {
+		word = strings.Trim(word, artifactWordTrim)
+		if !strings.Contains(word, "/") && filepath.Ext(word) == "" {
+			continue
+		}
+		abs, err := filepath.Abs(word)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		if info, err := os.Stat(abs); err == nil && info.Mode().IsRegular() && !info.ModTime().Before(since) {
+			artifacts = append(artifacts, abs)
+		}
+	}
+	return artifacts
+}
+
+// SessionArtifacts returns the files written this session by ch and by its
+// tools, oldest first, leaving out any that were deleted since
+func (m *Manager) SessionArtifacts() []string {
+	var artifacts []string
+	for _, path := range m.state.SessionArtifacts {
+		if _, err := os.Stat(path); err == nil {
+			artifacts = append(artifacts, path)
+		}
+	}
+	return artifacts
+}
+
+// addSessionArtifact records path for the list printed on exit
+func (m *Manager) addSessionArtifact(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	for _, existing := range m.state.SessionArtifacts {
+		if existing == abs {
+			return
+		}
+	}
+	m.state.SessionArtifacts = append(m.state.SessionArtifacts, abs)
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFindArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	outside := t.TempDir()
+
+	old := filepath.Join(dir, "old.txt")
+	if err := os.WriteFile(old, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for _, path := range []string{filepath.Join(dir, "out", "chart.png"), filepath.Join(outside, "report.pdf"), filepath.Join(dir, "log.txt"), filepath.Join(dir, "unnamed.txt")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := "wrote out/chart.png\nsaved to '" + filepath.Join(outside, "report.pdf") + "'. see old.txt and missing.png"
+	// Files neither the command nor its output names are not looked for
+	got := findArtifacts("python plot.py >log.txt", output, start)
+	want := []string{filepath.Join(dir, "log.txt"), filepath.Join(dir, "out", "chart.png"), filepath.Join(outside, "report.pdf")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findArtifacts() = %q, want %q", got, want)
+	}
+}
+
+func TestSessionArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	m := NewManager(&types.AppState{Config: &types.Config{}})
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.AddRecentlyCreatedFile(filepath.Join(dir, "a.txt"))
+	m.AddRecentlyCreatedFile("b.txt")
+	m.AddRecentlyCreatedFile(filepath.Join(dir, "a.txt"))
+	m.AddRecentlyCreatedFile(filepath.Join(dir, "deleted.txt"))
+
+	want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	if got := m.SessionArtifacts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SessionArtifacts() = %q, want %q", got, want)
+	}
+}
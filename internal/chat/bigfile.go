@@ -0,0 +1,225 @@
+package chat
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// bigFileExcerptHeader starts the message holding retrieved excerpts, so the
+// previous question's excerpts can be found and replaced
+const bigFileExcerptHeader = "[bigfile excerpts]"
+
+// bigFileIndex is an in-memory chunk index of one large file for this session
+type bigFileIndex struct {
+	path    string
+	chunks  []string
+	vectors [][]float32
+}
+
+// IndexBigFile loads a file too large for the context window, splits it into
+// chunks, and embeds them with embedding_model. When the platform has no
+// embeddings endpoint, retrieval falls back to keyword matching.
+func (m *Manager) IndexBigFile(terminal *ui.Terminal, path string) error {
+	content, err := terminal.LoadFileContent([]string{path})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("no content loaded from %s", path)
+	}
+
+	cfg := m.state.Config
+	chunks, err := ChunkText(content, cfg.BigFileChunkTokens, cfg.BigFileChunkTokens/8)
+	if err != nil {
+		return err
+	}
+
+	index := &bigFileIndex{path: path, chunks: chunks}
+	if m.platformManager != nil {
+		done := make(chan bool, 1)
+		go terminal.ShowLoadingAnimation(fmt.Sprintf("Embedding %d chunks...", len(chunks)), done)
+		vectors, err := m.platformManager.CreateEmbeddings(chunks, platform.EmbeddingOptions{
+			Model:             cfg.EmbeddingModel,
+			BatchSize:         cfg.EmbeddingBatchSize,
+			RequestsPerMinute: cfg.EmbeddingRPM,
+		})
+		done <- true
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("embeddings unavailable, using keyword retrieval: %v", err))
+		} else {
+			index.vectors = vectors
+		}
+	}
+
+	m.bigFile = index
+	return nil
+}
+
+// BigFileStatus describes the indexed file, or returns "" when none is loaded
+func (m *Manager) BigFileStatus() string {
+	if m.bigFile == nil {
+		return ""
+	}
+	mode := "embeddings"
+	if m.bigFile.vectors == nil {
+		mode = "keywords"
+	}
+	return fmt.Sprintf("%s (%d chunks, %s)", filepath.Base(m.bigFile.path), len(m.bigFile.chunks), mode)
+}
+
+// ClearBigFile drops the index and any excerpts it added to the conversation
+func (m *Manager) ClearBigFile() {
+	m.bigFile = nil
+	m.removeBigFileExcerpts()
+}
+
+// AddBigFileContext retrieves the chunks most relevant to the latest user
+// message and inserts them just before it. Excerpts from earlier questions
+// are removed so the context does not grow with every question.
+func (m *Manager) AddBigFileContext() {
+	if m.bigFile == nil || len(m.state.Messages) == 0 {
+		return
+	}
+	m.removeBigFileExcerpts()
+
+	last := len(m.state.Messages) - 1
+	question := m.state.Messages[last]
+	if question.Role != "user" {
+		return
+	}
+
+	selected := m.retrieveBigFileChunks(question.Content, m.state.Config.BigFileTopK)
+	if len(selected) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s Relevant excerpts from %s:\n", bigFileExcerptHeader, filepath.Base(m.bigFile.path)))
+	for _, i := range selected {
+		sb.WriteString(fmt.Sprintf("\n--- chunk %d of %d ---\n%s\n", i+1, len(m.bigFile.chunks), m.bigFile.chunks[i]))
+	}
+
+	excerpt := types.ChatMessage{Role: "user", Content: sb.String()}
+	m.state.Messages = append(m.state.Messages[:last], excerpt, question)
+}
+
+// removeBigFileExcerpts deletes excerpt messages added for earlier questions
+func (m *Manager) removeBigFileExcerpts() {
+	kept := m.state.Messages[:0]
+	for _, msg := range m.state.Messages {
+		if msg.Role == "user" && strings.HasPrefix(msg.Content, bigFileExcerptHeader) {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	m.state.Messages = kept
+}
+
+// retrieveBigFileChunks returns the indexes of the topK most relevant chunks in file order
+func (m *Manager) retrieveBigFileChunks(question string, topK int) []int {
+	if topK <= 0 {
+		topK = 1
+	}
+
+	var scores []float64
+	if m.bigFile.vectors != nil && m.platformManager != nil {
+		cfg := m.state.Config
+		vectors, err := m.platformManager.CreateEmbeddings([]string{question}, platform.EmbeddingOptions{Model: cfg.EmbeddingModel})
+		if err == nil && len(vectors) == 1 {
+			scores = make([]float64, len(m.bigFile.vectors))
+			for i, vec := range m.bigFile.vectors {
+				scores[i] = cosineSimilarity(vectors[0], vec)
+			}
+		}
+	}
+	if scores == nil {
+		scores = keywordScores(question, m.bigFile.chunks)
+	}
+
+	return topScoring(scores, topK)
+}
+
+// topScoring returns the indexes of the k highest positive scores, sorted by index
+func topScoring(scores []float64, k int) []int {
+	order := make([]int, 0, len(scores))
+	for i, score := range scores {
+		if score > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) > k {
+		order = order[:k]
+	}
+	sort.Ints(order)
+	return order
+}
+
+// cosineSimilarity compares two embedding vectors
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// keywordScores scores chunks by question term frequency weighted by how rare
+// each term is across chunks
+func keywordScores(question string, chunks []string) []float64 {
+	terms := keywordTerms(question)
+	scores := make([]float64, len(chunks))
+	if len(terms) == 0 {
+		return scores
+	}
+
+	counts := make([]map[string]int, len(chunks))
+	docFreq := map[string]int{}
+	for i, chunk := range chunks {
+		counts[i] = keywordTerms(chunk)
+		for term := range terms {
+			if counts[i][term] > 0 {
+				docFreq[term]++
+			}
+		}
+	}
+
+	for i := range chunks {
+		for term := range terms {
+			if tf := counts[i][term]; tf > 0 {
+				idf := math.Log(1 + float64(len(chunks))/float64(docFreq[term]))
+				scores[i] += (1 + math.Log(float64(tf))) * idf
+			}
+		}
+	}
+	return scores
+}
+
+// keywordTerms lowercases text and counts its words of three or more characters
+func keywordTerms(text string) map[string]int {
+	terms := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(word) >= 3 {
+			terms[word]++
+		}
+	}
+	return terms
+}
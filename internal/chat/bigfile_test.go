@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestKeywordScoresPreferRareTerms(t *testing.T) {
+	chunks := []string{
+		"the config loader reads the file",
+		"the retry policy uses exponential backoff",
+		"the file watcher reloads the config",
+	}
+	scores := keywordScores("How does the backoff retry work?", chunks)
+	if got := topScoring(scores, 1); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("top chunk = %v, want [1] (scores %v)", got, scores)
+	}
+	if got := topScoring(keywordScores("unrelated words", chunks), 2); len(got) != 0 {
+		t.Errorf("no matching terms should select nothing, got %v", got)
+	}
+}
+
+func TestTopScoringKeepsFileOrder(t *testing.T) {
+	got := topScoring([]float64{0.1, 0.9, 0, 0.5, 0.7}, 3)
+	if !reflect.DeepEqual(got, []int{1, 3, 4}) {
+		t.Errorf("topScoring = %v", got)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := cosineSimilarity([]float32{1, 0}, []float32{1, 0}); math.Abs(got-1) > 1e-9 {
+		t.Errorf("identical vectors = %v", got)
+	}
+	if got := cosineSimilarity([]float32{1, 0}, []float32{0, 1}); got != 0 {
+		t.Errorf("orthogonal vectors = %v", got)
+	}
+	if got := cosineSimilarity([]float32{1}, []float32{1, 2}); got != 0 {
+		t.Errorf("mismatched lengths = %v", got)
+	}
+}
+
+func TestAddBigFileContextReplacesEarlierExcerpts(t *testing.T) {
+	state := &types.AppState{Config: &types.Config{BigFileTopK: 1}}
+	m := NewManager(state)
+	m.bigFile = &bigFileIndex{path: "/data/server.log", chunks: []string{
+		"database connection pool exhausted",
+		"tls certificate expired for api host",
+	}}
+
+	state.Messages = []types.ChatMessage{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "why did the database fail?"},
+	}
+	m.AddBigFileContext()
+	if len(state.Messages) != 3 || !strings.Contains(state.Messages[1].Content, "connection pool") {
+		t.Fatalf("excerpt not inserted before the question: %+v", state.Messages)
+	}
+	if !strings.HasPrefix(state.Messages[1].Content, bigFileExcerptHeader) || state.Messages[2].Content != "why did the database fail?" {
+		t.Errorf("unexpected message layout: %+v", state.Messages)
+	}
+
+	state.Messages = append(state.Messages,
+		types.ChatMessage{Role: "assistant", Content: "pool exhausted"},
+		types.ChatMessage{Role: "user", Content: "what about the certificate?"},
+	)
+	m.AddBigFileContext()
+
+	excerpts := 0
+	for _, msg := range state.Messages {
+		if strings.HasPrefix(msg.Content, bigFileExcerptHeader) {
+			excerpts++
+			if !strings.Contains(msg.Content, "certificate expired") {
+				t.Errorf("excerpt should match the new question: %q", msg.Content)
+			}
+		}
+	}
+	if excerpts != 1 || len(state.Messages) != 5 {
+		t.Errorf("expected exactly one current excerpt, got %d in %+v", excerpts, state.Messages)
+	}
+
+	m.ClearBigFile()
+	if m.BigFileStatus() != "" || len(state.Messages) != 4 {
+		t.Errorf("clear should drop the index and its excerpts: %+v", state.Messages)
+	}
+}
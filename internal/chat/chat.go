@@ -3,18 +3,18 @@ package chat
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/fswalk"
 	"github.com/MehmetMHY/ch/internal/platform"
 	"github.com/MehmetMHY/ch/internal/ui"
 	"github.com/MehmetMHY/ch/pkg/types"
@@ -27,6 +27,16 @@ type Manager struct {
 	platformManager     *platform.Manager
 	forkSessionOnSave   bool
 	forkSessionBaseline string
+	savedFingerprint    string    // the session as last saved, for autosave
+	savedAt             time.Time // when it was saved
+	bigFile             *bigFileIndex
+	liveFiles           []*liveFile
+	marks               []bookmark
+
+	// Context packing (context_budget): cached unit embeddings by content
+	// hash, and whether embeddings failed so keywords are used instead
+	packVectors      map[[32]byte][]float32
+	packKeywordsOnly bool
 }
 
 // NewManager creates a new chat manager
@@ -42,11 +52,52 @@ func (m *Manager) SetPlatformManager(pm *platform.Manager) {
 	m.platformManager = pm
 }
 
+// SessionSpend reports the platform manager's spend, or 0 before one is set
+func (m *Manager) SessionSpend() float64 {
+	if m.platformManager == nil {
+		return 0
+	}
+	return m.platformManager.SessionSpend()
+}
+
+// SessionUsage totals the platform manager's per-model usage for the state view
+func (m *Manager) SessionUsage() types.ModelUsage {
+	if m.platformManager == nil {
+		return types.ModelUsage{}
+	}
+	return platform.UsageTotal(m.platformManager.SessionUsage())
+}
+
+// WriteUsageSummary appends this run's usage, labelled with the current
+// session file, to ~/.ch/usage.jsonl
+func (m *Manager) WriteUsageSummary() error {
+	if m.platformManager == nil {
+		return nil
+	}
+	return m.platformManager.WriteUsageSummary(m.CurrentSessionFileName())
+}
+
+// ModelCapabilities returns what the current model supports, or false when
+// there is no platform manager to ask
+func (m *Manager) ModelCapabilities() (platform.Capabilities, bool) {
+	if m.platformManager == nil {
+		return platform.Capabilities{}, false
+	}
+	return m.platformManager.Capabilities(m.state.Config.CurrentModel), true
+}
+
 // AddUserMessage adds a user message to the chat
 func (m *Manager) AddUserMessage(content string) {
+	m.AddUserMessageWithImages(content, nil)
+}
+
+// AddUserMessageWithImages adds a user message along with the image files it
+// describes, which platforms that take image parts also receive
+func (m *Manager) AddUserMessageWithImages(content string, images []string) {
 	m.state.Messages = append(m.state.Messages, types.ChatMessage{
 		Role:    "user",
 		Content: content,
+		Images:  images,
 	})
 }
 
@@ -65,7 +116,9 @@ func (m *Manager) AddToHistory(user, bot string) {
 		User:     user,
 		Bot:      bot,
 		Platform: m.state.Config.CurrentPlatform,
-		Model:    m.state.Config.CurrentModel,
+		Model:    m.historyModel(),
+		Seed:     currentSeed(m.state.Config),
+		Elapsed:  m.historyElapsed(),
 	})
 }
 
@@ -77,11 +130,69 @@ func (m *Manager) AddToHistoryWithContext(user, bot, context string) {
 		User:     user,
 		Bot:      bot,
 		Platform: m.state.Config.CurrentPlatform,
-		Model:    m.state.Config.CurrentModel,
+		Model:    m.historyModel(),
 		Context:  context,
+		Seed:     currentSeed(m.state.Config),
 	})
 }
 
+// historyModel returns the model to record for a new exchange, using the
+// routed model when the auto alias is active
+func (m *Manager) historyModel() string {
+	if m.state.Config.CurrentModel == platform.AutoModel && m.platformManager != nil {
+		if model := m.platformManager.LastModel(); model != "" {
+			return model
+		}
+	}
+	return m.state.Config.CurrentModel
+}
+
+// historyElapsed returns the response time in seconds of the most recent chat
+// request, rounded to milliseconds
+func (m *Manager) historyElapsed() float64 {
+	if m.platformManager == nil {
+		return 0
+	}
+	return math.Round(m.platformManager.LastElapsed().Seconds()*1000) / 1000
+}
+
+// ExchangeAnnotation returns the [platform/model · time] label of a history
+// entry, leaving out parts that were not recorded
+func ExchangeAnnotation(entry types.ChatHistory) string {
+	var parts []string
+	switch {
+	case entry.Platform != "" && entry.Model != "":
+		parts = append(parts, entry.Platform+"/"+entry.Model)
+	case entry.Model != "":
+		parts = append(parts, entry.Model)
+	}
+	if entry.Elapsed > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", entry.Elapsed))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(parts, " · ") + "]"
+}
+
+// LastExchangeAnnotation returns the annotation of the most recent exchange
+func (m *Manager) LastExchangeAnnotation() string {
+	if len(m.state.ChatHistory) == 0 {
+		return ""
+	}
+	return ExchangeAnnotation(m.state.ChatHistory[len(m.state.ChatHistory)-1])
+}
+
+// currentSeed returns a copy of the configured seed so history entries are not
+// affected by later seed changes
+func currentSeed(cfg *types.Config) *int {
+	if cfg.Seed == nil {
+		return nil
+	}
+	seed := *cfg.Seed
+	return &seed
+}
+
 // EffectiveUserContent returns Context if set, otherwise User
 func EffectiveUserContent(entry types.ChatHistory) string {
 	if entry.Context != "" {
@@ -114,14 +225,60 @@ func (m *Manager) RemovePendingUserMessage(content string) bool {
 	return true
 }
 
+// DropLastExchange removes the latest answered exchange from history and
+// from the messages sent to the model, returning it and the user message
+// content that was sent for it, so it can be asked again
+func (m *Manager) DropLastExchange() (types.ChatHistory, string, bool) {
+	last := len(m.state.ChatHistory) - 1
+	if last < 1 || m.state.ChatHistory[last].Bot == "" {
+		return types.ChatHistory{}, "", false
+	}
+	entry := m.state.ChatHistory[last]
+
+	messages := m.state.Messages
+	n := len(messages)
+	if n < 2 || messages[n-1].Role != "assistant" || messages[n-1].Content != entry.Bot || messages[n-2].Role != "user" {
+		return types.ChatHistory{}, "", false
+	}
+	prompt := messages[n-2].Content
+	m.state.Messages = messages[:n-2]
+	m.state.ChatHistory = m.state.ChatHistory[:last]
+	m.trimBookmarks()
+	return entry, prompt, true
+}
+
+// RestoreExchange puts back an exchange removed by DropLastExchange
+func (m *Manager) RestoreExchange(entry types.ChatHistory, prompt string) {
+	m.state.Messages = append(m.state.Messages,
+		types.ChatMessage{Role: "user", Content: prompt},
+		types.ChatMessage{Role: "assistant", Content: entry.Bot},
+	)
+	m.state.ChatHistory = append(m.state.ChatHistory, entry)
+}
+
+// FindPreviousAnswer returns the latest answered exchange in this session
+// whose prompt matches prompt, ignoring surrounding whitespace
+func (m *Manager) FindPreviousAnswer(prompt string) (types.ChatHistory, bool) {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return types.ChatHistory{}, false
+	}
+	for i := len(m.state.ChatHistory) - 1; i > 0; i-- {
+		entry := m.state.ChatHistory[i]
+		if entry.Bot != "" && strings.TrimSpace(entry.User) == prompt {
+			return entry, true
+		}
+	}
+	return types.ChatHistory{}, false
+}
+
 // ClearHistory clears the chat history
 func (m *Manager) ClearHistory() {
-	m.state.Messages = []types.ChatMessage{
-		{Role: "system", Content: m.state.Config.SystemPrompt},
-	}
+	m.state.Messages = config.InitialMessages(m.state.Config)
 	m.state.ChatHistory = []types.ChatHistory{
 		{Time: time.Now().Unix(), User: m.state.Config.SystemPrompt, Bot: "", Platform: m.state.Config.CurrentPlatform, Model: m.state.Config.CurrentModel},
 	}
+	m.marks = nil
 }
 
 // ExportFullHistory exports the entire chat history to a JSON file.
@@ -146,9 +303,11 @@ func (m *Manager) ExportFullHistory() (string, error) {
 			entries = append(entries, types.ExportEntry{
 				Platform:    entry.Platform,
 				ModelName:   entry.Model,
-				UserPrompt:  EffectiveUserContent(entry),
-				BotResponse: entry.Bot,
+				UserPrompt:  m.redact(EffectiveUserContent(entry)),
+				BotResponse: m.redact(entry.Bot),
 				Timestamp:   entry.Time,
+				Seed:        entry.Seed,
+				Elapsed:     entry.Elapsed,
 			})
 		}
 	}
@@ -189,7 +348,7 @@ func (m *Manager) ExportLastResponse() (string, error) {
 
 	fullPath := filepath.Join(currentDir, filename)
 
-	err = os.WriteFile(fullPath, []byte(lastEntry.Bot), 0600)
+	err = os.WriteFile(fullPath, []byte(m.redact(lastEntry.Bot)), 0600)
 	if err != nil {
 		return "", err
 	}
@@ -225,28 +384,43 @@ func (m *Manager) SaveSessionState() error {
 		Model:       m.state.Config.CurrentModel,
 		BaseURL:     m.state.Config.CurrentBaseURL,
 		ChatHistory: m.state.ChatHistory,
+		Rating:      m.state.SessionRating,
+		Tags:        m.state.SessionTags,
+		Notes:       m.state.SessionNotes,
 	}
 
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(session, "", "  ")
+	store, err := openSessionStore(m.state.Config)
 	if err != nil {
-		return fmt.Errorf("failed to marshal session: %v", err)
+		return err
 	}
+	defer store.Close()
 
-	tempFullPath := fullPath + ".tmp"
-
-	err = os.WriteFile(tempFullPath, jsonData, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to write session file: %v", err)
+	if err := store.Save(fullPath, &session); err != nil {
+		return err
 	}
+	m.MarkSessionSaved()
+	return nil
+}
 
-	err = os.Rename(tempFullPath, fullPath)
-	if err != nil {
-		_ = os.Remove(tempFullPath)
-		return fmt.Errorf("failed to rename session file: %v", err)
-	}
+// MarkSessionSaved records the current session as saved, so AutosaveSession
+// only writes it once it changes
+func (m *Manager) MarkSessionSaved() {
+	m.savedFingerprint = m.sessionSaveFingerprint()
+	m.savedAt = time.Now()
+}
 
-	return nil
+// AutosaveSession saves the session if it changed since the last save and
+// at least autosave_interval seconds have passed since then. It runs after
+// commands, not on a timer, so the interval is only a minimum gap.
+func (m *Manager) AutosaveSession() error {
+	interval := time.Duration(m.state.Config.AutosaveInterval) * time.Second
+	if interval > 0 && time.Since(m.savedAt) < interval {
+		return nil
+	}
+	if m.sessionSaveFingerprint() == m.savedFingerprint {
+		return nil
+	}
+	return m.SaveSessionState()
 }
 
 // PrepareSessionFilePath chooses the file used for saving the current session.
@@ -255,9 +429,9 @@ func (m *Manager) PrepareSessionFilePath() (string, error) {
 		return m.state.SessionFilePath, nil
 	}
 
-	tmpDir, err := config.GetTempDir()
+	tmpDir, err := config.GetSessionDir(m.state.Config)
 	if err != nil {
-		return "", fmt.Errorf("failed to get temp directory: %v", err)
+		return "", fmt.Errorf("failed to get session directory: %v", err)
 	}
 
 	filename := "ch_session_latest.json"
@@ -266,14 +440,20 @@ func (m *Manager) PrepareSessionFilePath() (string, error) {
 			m.state.SessionStartTime = time.Now().Unix()
 		}
 
+		store, err := openSessionStoreAt(m.state.Config.StorageBackend, tmpDir)
+		if err != nil {
+			return "", err
+		}
+		defer store.Close()
+
 		for {
 			filename = fmt.Sprintf("ch_session_%d.json", m.state.SessionStartTime)
 			fullPath := filepath.Join(tmpDir, filename)
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			if exists, err := store.Exists(fullPath); err != nil {
+				return "", fmt.Errorf("failed to check session file: %v", err)
+			} else if !exists {
 				m.state.SessionFilePath = fullPath
 				return m.state.SessionFilePath, nil
-			} else if err != nil {
-				return "", fmt.Errorf("failed to check session file: %v", err)
 			}
 			m.state.SessionStartTime++
 		}
@@ -299,6 +479,9 @@ func (m *Manager) sessionSaveFingerprint() string {
 		Model:       m.state.Config.CurrentModel,
 		BaseURL:     m.state.Config.CurrentBaseURL,
 		ChatHistory: m.state.ChatHistory,
+		Rating:      m.state.SessionRating,
+		Tags:        m.state.SessionTags,
+		Notes:       m.state.SessionNotes,
 	}
 
 	data, err := json.Marshal(session)
@@ -318,92 +501,47 @@ func (m *Manager) CurrentSessionFileName() string {
 
 // LoadLatestSessionState loads the session state from disk
 func (m *Manager) LoadLatestSessionState() (*types.SessionFile, error) {
-	tmpDir, err := config.GetTempDir()
+	tmpDir, err := config.GetSessionDir(m.state.Config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get temp directory: %v", err)
+		return nil, fmt.Errorf("failed to get session directory: %v", err)
 	}
 
+	store, err := openSessionStoreAt(m.state.Config.StorageBackend, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
 	if m.state.Config.SaveAllSessions {
-		// When saving all sessions, find the most recent session file
-		files, err := os.ReadDir(tmpDir)
+		// When saving all sessions, find the most recent session
+		sessions, err := store.List()
 		if err != nil {
-			return nil, fmt.Errorf("failed to read temp directory: %v", err)
+			return nil, err
 		}
 
-		var latestFile string
-		var latestTime int64 = 0
-
-		// Find all session files and get the most recent one
-		for _, file := range files {
-			if file.IsDir() {
-				continue
+		var latest *types.SessionFile
+		for _, session := range sessions {
+			if latest == nil || session.Timestamp > latest.Timestamp {
+				latest = session
 			}
-			filename := file.Name()
-			// Match both old format and new format
-			if strings.HasPrefix(filename, "ch_session_") && strings.HasSuffix(filename, ".json") {
-				fullPath := filepath.Join(tmpDir, filename)
-
-				// Read the file to check timestamp
-				data, err := os.ReadFile(fullPath) // #nosec G304 -- Session discovery reads files from Ch's own temp session directory.
-				if err != nil {
-					continue
-				}
-
-				var session types.SessionFile
-				err = json.Unmarshal(data, &session)
-				if err != nil {
-					continue
-				}
-
-				// Check if this is the most recent session
-				if session.Timestamp > latestTime {
-					latestTime = session.Timestamp
-					latestFile = fullPath
-				}
-			}
-		}
-
-		if latestFile == "" {
-			return nil, fmt.Errorf("no session file found")
-		}
-
-		// Load the most recent session
-		data, err := os.ReadFile(latestFile) // #nosec G304 -- Latest session path is selected from Ch's own temp session directory.
-		if err != nil {
-			return nil, fmt.Errorf("failed to read session file: %v", err)
-		}
-
-		var session types.SessionFile
-		err = json.Unmarshal(data, &session)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse session file (corrupt): %v", err)
 		}
-
-		session.SourceFile = latestFile
-		return &session, nil
-	} else {
-		// Load the single session file
-		fullPath := filepath.Join(tmpDir, "ch_session_latest.json")
-
-		// Check if file exists
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+		if latest == nil {
 			return nil, fmt.Errorf("no session file found")
 		}
+		return latest, nil
+	}
 
-		data, err := os.ReadFile(fullPath) // #nosec G304 -- Latest session path is under Ch's own temp session directory.
-		if err != nil {
-			return nil, fmt.Errorf("failed to read session file: %v", err)
-		}
-
-		var session types.SessionFile
-		err = json.Unmarshal(data, &session)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse session file (corrupt): %v", err)
-		}
+	// Load the single session file
+	fullPath := filepath.Join(tmpDir, "ch_session_latest.json")
+	if exists, err := store.Exists(fullPath); err != nil || !exists {
+		return nil, fmt.Errorf("no session file found")
+	}
 
-		session.SourceFile = fullPath
-		return &session, nil
+	session, err := store.Load(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session file (corrupt): %v", err)
 	}
+	return session, nil
 }
 
 // RestoreSessionState restores the application state from a SessionFile
@@ -413,11 +551,13 @@ func (m *Manager) RestoreSessionState(session *types.SessionFile) {
 	m.state.Config.CurrentBaseURL = session.BaseURL
 	m.state.ChatHistory = session.ChatHistory
 	m.state.SessionFilePath = session.SourceFile
+	m.state.SessionRating = session.Rating
+	m.state.SessionTags = session.Tags
+	m.state.SessionNotes = session.Notes
+	m.marks = nil
 
 	// Rebuild Messages from ChatHistory
-	m.state.Messages = []types.ChatMessage{
-		{Role: "system", Content: m.state.Config.SystemPrompt},
-	}
+	m.state.Messages = config.InitialMessages(m.state.Config)
 	for i, entry := range m.state.ChatHistory {
 		if i == 0 {
 			continue // Skip system prompt entry
@@ -431,27 +571,29 @@ func (m *Manager) RestoreSessionState(session *types.SessionFile) {
 	}
 }
 
-// LoadCustomHistoryFile loads a session from a custom history file path
+// LoadCustomHistoryFile loads a session from a custom history file path.
+// Paths in the session directory are read from the configured backend.
 func (m *Manager) LoadCustomHistoryFile(filePath string) (*types.SessionFile, error) {
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("history file does not exist: %s", filePath)
-	}
-
-	// Read the file
-	data, err := os.ReadFile(filePath) // #nosec G304 -- Loading an explicitly provided session history file is intentional CLI behavior.
+	store, err := openSessionStore(m.state.Config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read history file: %v", err)
+		return readSessionFile(filePath)
 	}
+	defer store.Close()
 
-	// Unmarshal the JSON
-	var session types.SessionFile
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to parse history file: %v", err)
+	return store.Load(filePath)
+}
+
+// SessionExists reports whether a session file exists at path, checking the
+// configured backend for paths in the session directory
+func (m *Manager) SessionExists(path string) bool {
+	store, err := openSessionStore(m.state.Config)
+	if err != nil {
+		return false
 	}
+	defer store.Close()
 
-	session.SourceFile = filePath
-	return &session, nil
+	exists, err := store.Exists(path)
+	return err == nil && exists
 }
 
 // SearchSessions searches through all saved sessions using fzf
@@ -460,29 +602,229 @@ func (m *Manager) SearchSessions(terminal *ui.Terminal, args []string) (*types.S
 		return nil, fmt.Errorf("session search requires save_all_sessions to be enabled in config")
 	}
 
-	var exact bool
-	var minTime, maxTime int64
-	var targetFile string
+	filter := parseSessionSearchArgs(args)
+
+	// Handle direct file load if specified
+	if filter.targetFile != "" {
+		return m.loadTargetSession(filter.targetFile)
+	}
+
+	sessions, err := m.loadFilteredSessions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, matches := sessionSearchLines(sessions)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no session entries found matching criteria")
+	}
+
+	// The summary after the tab is hidden from the list and shown in the
+	// preview pane for the highlighted line
+	fzfArgs := []string{
+		"--reverse",
+		"--height=60%",
+		"--border",
+		"--prompt=select session: ",
+		"--delimiter=\t",
+		"--with-nth=1",
+		"--preview=printf '%b' {2}",
+		"--preview-window=right:45%:wrap",
+	}
+
+	if filter.exact {
+		fzfArgs = append(fzfArgs, "--exact")
+	}
+
+	// Run fzf
+	fzfCmd := exec.Command("fzf", fzfArgs...)
+	fzfCmd.Stdin = strings.NewReader(strings.Join(lines, "\n") + "\n")
+	fzfCmd.Stderr = os.Stderr
+
+	fzfOutput, err := ui.OutputForeground(fzfCmd)
+	if err != nil {
+		return nil, fmt.Errorf("selection cancelled")
+	}
+
+	selectedLine := strings.TrimRight(string(fzfOutput), "\n")
+	if selectedLine == "" {
+		return nil, fmt.Errorf("no selection made")
+	}
+	if session, ok := matches[selectedLine]; ok {
+		return session, nil
+	}
+	return nil, fmt.Errorf("failed to find selected session")
+}
+
+// sessionSearchLines builds the fzf lines for session search, newest first:
+// one line per session with its model and first prompt, then one per message
+// so any content can be searched. Each line ends with a tab and the escaped
+// session summary for the preview pane.
+func sessionSearchLines(sessions []*types.SessionFile) ([]string, map[string]*types.SessionFile) {
+	type sessionLine struct {
+		line    string
+		time    int64
+		session *types.SessionFile
+	}
+
+	var entries []sessionLine
+	for _, session := range sessions {
+		summary := "\t" + escapePreview(sessionSummary(session))
+		add := func(preview string, timestamp int64) {
+			// A tab in the visible part would cut it short at --with-nth
+			preview = strings.NewReplacer("\t", " ", "\r", "").Replace(preview)
+			entries = append(entries, sessionLine{line: preview + summary, time: timestamp, session: session})
+		}
+
+		if header := sessionHeaderLine(session); header != "" {
+			add(header, session.Timestamp)
+		}
+		for j, entry := range session.ChatHistory {
+			if j == 0 {
+				continue // skip system prompt
+			}
+
+			if entry.User != "" {
+				user := entry.User
+				if len(entry.Tags) > 0 {
+					user = "[#" + strings.Join(entry.Tags, " #") + "] " + user
+				}
+				add(formatSessionSearchPreview(session.SourceFile, entry.Time, "user", user), entry.Time)
+			}
+			if entry.Bot != "" {
+				add(formatSessionSearchPreview(session.SourceFile, entry.Time, "bot", entry.Bot), entry.Time)
+			}
+		}
+	}
+
+	// Sort entries by timestamp (latest to oldest)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time > entries[j].time
+	})
+
+	lines := make([]string, 0, len(entries))
+	matches := make(map[string]*types.SessionFile, len(entries))
+	for _, entry := range entries {
+		if _, seen := matches[entry.line]; seen {
+			continue
+		}
+		lines = append(lines, entry.line)
+		matches[entry.line] = entry.session
+	}
+	return lines, matches
+}
+
+// sessionHeaderLine returns the list line for a whole session: its start
+// time, platform/model, exchange count, and first prompt, or "" when the
+// session has no exchanges
+func sessionHeaderLine(session *types.SessionFile) string {
+	exchanges, first := 0, ""
+	for j, entry := range session.ChatHistory {
+		if j == 0 || entry.User == "" {
+			continue
+		}
+		if first == "" {
+			first = entry.User
+		}
+		exchanges++
+	}
+	if exchanges == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s session [%s] %s: %s",
+		time.Unix(session.Timestamp, 0).UTC().Format("2006-01-02 15:04:05 UTC"),
+		sessionModelName(session),
+		ui.Plural(exchanges, "exchange"),
+		previewLine(first, 80))
+}
+
+// sessionModelName returns platform/model, or just the model for old session
+// files without a platform
+func sessionModelName(session *types.SessionFile) string {
+	if session.Platform == "" {
+		return session.Model
+	}
+	return session.Platform + "/" + session.Model
+}
+
+// sessionSummary is the preview pane text for a session: its file, model,
+// tags, and the opening exchanges
+func sessionSummary(session *types.SessionFile) string {
+	var sb strings.Builder
+	sb.WriteString(filepath.Base(session.SourceFile) + "\n")
+	sb.WriteString(sessionModelName(session))
+	if len(session.Tags) > 0 {
+		sb.WriteString(" #" + strings.Join(session.Tags, " #"))
+	}
+	sb.WriteString("\n")
+	for _, note := range session.Notes {
+		sb.WriteString("note: " + previewLine(note.Text, 300) + "\n")
+	}
+
+	shown := 0
+	for j, entry := range session.ChatHistory {
+		if j == 0 || entry.User == "" {
+			continue
+		}
+		if shown == 3 {
+			sb.WriteString("\n...")
+			break
+		}
+		sb.WriteString("\n> " + previewLine(entry.User, 300) + "\n")
+		if entry.Bot != "" {
+			sb.WriteString(previewLine(entry.Bot, 300) + "\n")
+		}
+		shown++
+	}
+	return sb.String()
+}
+
+// escapePreview encodes text for one fzf field that printf %b turns back
+// into lines
+func escapePreview(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\t", " ", "\r", "").Replace(text)
+}
+
+// sessionSearchFilter holds the filters accepted by session and answer search
+type sessionSearchFilter struct {
+	exact      bool
+	minTime    int64
+	maxTime    int64
+	targetFile string
+	tags       []string
+}
+
+// parseSessionSearchArgs parses search arguments: exact or --exact, #tag or
+// tag:name, a session file name, a relative age (1d, 1w, 1m, 1y), an epoch
+// range (start-end), or a single start epoch
+func parseSessionSearchArgs(args []string) sessionSearchFilter {
+	var filter sessionSearchFilter
+	reRel := regexp.MustCompile(`^(\d+)([dwmy])$`)
+	reRange := regexp.MustCompile(`^(\d+)-(\d+)$`)
+	reEpoch := regexp.MustCompile(`^(\d+)$`)
 
-	// Parse arguments
 	for _, arg := range args {
-		if arg == "exact" {
-			exact = true
+		if arg == "exact" || arg == "--exact" {
+			filter.exact = true
+			continue
+		}
+
+		// Tag filter (e.g., #favorite or tag:favorite)
+		if strings.HasPrefix(arg, "#") || strings.HasPrefix(arg, "tag:") {
+			filter.tags = append(filter.tags, strings.TrimPrefix(arg, "tag:"))
 			continue
 		}
 
 		if strings.HasSuffix(arg, ".json") {
-			targetFile = arg
+			filter.targetFile = arg
 			continue
 		}
 
 		// Relative time (e.g., 1d, 1w, 1m, 1y)
-		reRel := regexp.MustCompile(`^(\d+)([dwmy])$`)
 		if matches := reRel.FindStringSubmatch(arg); matches != nil {
 			val, _ := strconv.ParseInt(matches[1], 10, 64)
-			unit := matches[2]
 			var duration int64
-			switch unit {
+			switch matches[2] {
 			case "d":
 				duration = val * 24 * 3600
 			case "w":
@@ -492,187 +834,79 @@ func (m *Manager) SearchSessions(terminal *ui.Terminal, args []string) (*types.S
 			case "y":
 				duration = val * 365 * 24 * 3600
 			}
-			minTime = time.Now().Unix() - duration
+			filter.minTime = time.Now().Unix() - duration
 			continue
 		}
 
 		// Range (e.g., 1776500000-1776542796)
-		reRange := regexp.MustCompile(`^(\d+)-(\d+)$`)
 		if matches := reRange.FindStringSubmatch(arg); matches != nil {
-			minTime, _ = strconv.ParseInt(matches[1], 10, 64)
-			maxTime, _ = strconv.ParseInt(matches[2], 10, 64)
+			filter.minTime, _ = strconv.ParseInt(matches[1], 10, 64)
+			filter.maxTime, _ = strconv.ParseInt(matches[2], 10, 64)
 			continue
 		}
 
 		// Single epoch (start point)
-		reEpoch := regexp.MustCompile(`^(\d+)$`)
 		if matches := reEpoch.FindStringSubmatch(arg); matches != nil {
-			minTime, _ = strconv.ParseInt(matches[1], 10, 64)
+			filter.minTime, _ = strconv.ParseInt(matches[1], 10, 64)
 			continue
 		}
 	}
+	return filter
+}
 
-	tmpDir, err := config.GetTempDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get temp directory: %v", err)
-	}
-
-	// Handle direct file load if specified
-	if targetFile != "" {
-		// Check if it's a full path or just a filename
-		fullPath := targetFile
-		if !filepath.IsAbs(targetFile) && !strings.Contains(targetFile, string(filepath.Separator)) {
-			fullPath = filepath.Join(tmpDir, targetFile)
-		}
-		return m.LoadCustomHistoryFile(fullPath)
-	}
-
-	// Find all session files
-	pattern := filepath.Join(tmpDir, "ch_session_*.json")
-	matches, err := filepath.Glob(pattern)
-	if err != nil || len(matches) == 0 {
-		return nil, fmt.Errorf("no sessions found")
-	}
-
-	// Build list of all entries from all sessions (parallel reads)
-	type SessionEntry struct {
-		FilePath  string
-		Preview   string
-		Timestamp int64
-	}
-
-	type sessionResult struct {
-		entries []SessionEntry
-	}
-
-	results := make([]sessionResult, len(matches))
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, max(1, runtime.NumCPU()/2))
-
-	for idx, sessionPath := range matches {
-		wg.Add(1)
-		go func(i int, path string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
-
-			data, err := os.ReadFile(path) // #nosec G304 -- History search reads session files discovered under Ch's own temp directory.
-			if err != nil {
-				return
-			}
-
-			var session types.SessionFile
-			if err := json.Unmarshal(data, &session); err != nil {
-				return
-			}
-
-			if minTime > 0 && session.Timestamp < minTime {
-				return
-			}
-			if maxTime > 0 && session.Timestamp > maxTime {
-				return
-			}
-
-			var local []SessionEntry
-			for j, entry := range session.ChatHistory {
-				if j == 0 {
-					continue // skip system prompt
-				}
-
-				if entry.User != "" {
-					local = append(local, SessionEntry{
-						FilePath:  path,
-						Preview:   formatSessionSearchPreview(path, entry.Time, "user", entry.User),
-						Timestamp: entry.Time,
-					})
-				}
-
-				if entry.Bot != "" {
-					local = append(local, SessionEntry{
-						FilePath:  path,
-						Preview:   formatSessionSearchPreview(path, entry.Time, "bot", entry.Bot),
-						Timestamp: entry.Time,
-					})
-				}
-			}
-			results[i] = sessionResult{entries: local}
-		}(idx, sessionPath)
-	}
-	wg.Wait()
-
-	var entries []SessionEntry
-	for _, r := range results {
-		entries = append(entries, r.entries...)
-	}
-
-	if len(entries) == 0 {
-		return nil, fmt.Errorf("no session entries found matching criteria")
+// matches reports whether a session passes the time and tag filters
+func (f sessionSearchFilter) matches(session *types.SessionFile) bool {
+	if f.minTime > 0 && session.Timestamp < f.minTime {
+		return false
 	}
-
-	// Sort entries by timestamp (latest to oldest)
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Timestamp > entries[j].Timestamp
-	})
-
-	// Build fzf input
-	var fzfInput strings.Builder
-	for _, entry := range entries {
-		fzfInput.WriteString(entry.Preview + "\n")
+	if f.maxTime > 0 && session.Timestamp > f.maxTime {
+		return false
 	}
+	return DatasetFilter{Tags: f.tags}.Matches(session)
+}
 
-	// Prepare fzf arguments
-	fzfArgs := []string{
-		"--reverse",
-		"--height=40%",
-		"--border",
-		"--prompt=select session: ",
+// loadTargetSession loads a session by file name in the session directory, or by path
+func (m *Manager) loadTargetSession(targetFile string) (*types.SessionFile, error) {
+	fullPath := targetFile
+	if !filepath.IsAbs(targetFile) && !strings.Contains(targetFile, string(filepath.Separator)) {
+		tmpDir, err := config.GetSessionDir(m.state.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session directory: %v", err)
+		}
+		fullPath = filepath.Join(tmpDir, targetFile)
 	}
+	return m.LoadCustomHistoryFile(fullPath)
+}
 
-	if exact {
-		fzfArgs = append(fzfArgs, "--exact")
+// loadFilteredSessions returns the named session when the filter has one,
+// otherwise every saved session that passes the filter
+func (m *Manager) loadFilteredSessions(filter sessionSearchFilter) ([]*types.SessionFile, error) {
+	if filter.targetFile != "" {
+		session, err := m.loadTargetSession(filter.targetFile)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.SessionFile{session}, nil
 	}
 
-	// Run fzf
-	fzfCmd := exec.Command("fzf", fzfArgs...)
-	fzfCmd.Stdin = strings.NewReader(fzfInput.String())
-	fzfCmd.Stderr = os.Stderr
-
-	fzfOutput, err := fzfCmd.Output()
+	store, err := openSessionStore(m.state.Config)
 	if err != nil {
-		return nil, fmt.Errorf("selection cancelled")
+		return nil, err
 	}
+	defer store.Close()
 
-	selectedLine := strings.TrimSpace(string(fzfOutput))
-	if selectedLine == "" {
-		return nil, fmt.Errorf("no selection made")
+	sessions, err := store.List()
+	if err != nil || len(sessions) == 0 {
+		return nil, fmt.Errorf("no sessions found")
 	}
 
-	// Find the session file for the selected entry
-	var selectedFilePath string
-	for _, entry := range entries {
-		if entry.Preview == selectedLine {
-			selectedFilePath = entry.FilePath
-			break
+	var filtered []*types.SessionFile
+	for _, session := range sessions {
+		if filter.matches(session) {
+			filtered = append(filtered, session)
 		}
 	}
-
-	if selectedFilePath == "" {
-		return nil, fmt.Errorf("failed to find selected session")
-	}
-
-	// Load the selected session
-	data, err := os.ReadFile(selectedFilePath) // #nosec G304 -- User-selected session file is loaded intentionally.
-	if err != nil {
-		return nil, fmt.Errorf("failed to read session file: %v", err)
-	}
-
-	var session types.SessionFile
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to parse session file: %v", err)
-	}
-
-	session.SourceFile = selectedFilePath
-	return &session, nil
+	return filtered, nil
 }
 
 func formatSessionSearchPreview(filePath string, timestamp int64, role string, content string) string {
@@ -735,10 +969,9 @@ func (m *Manager) BacktrackHistory(terminal *ui.Terminal) (int, error) {
 	originalHistoryCount := len(m.state.ChatHistory)
 	m.state.ChatHistory = m.state.ChatHistory[:index+1]
 	backtrackedCount := originalHistoryCount - len(m.state.ChatHistory)
+	m.trimBookmarks()
 
-	m.state.Messages = []types.ChatMessage{
-		{Role: "system", Content: m.state.Config.SystemPrompt},
-	}
+	m.state.Messages = config.InitialMessages(m.state.Config)
 	for _, entry := range m.state.ChatHistory[1:] {
 		if entry.User != "" || entry.Context != "" {
 			m.state.Messages = append(m.state.Messages, types.ChatMessage{Role: "user", Content: EffectiveUserContent(entry)})
@@ -798,8 +1031,13 @@ func (m *Manager) GetChatHistory() []types.ChatHistory {
 	return m.state.ChatHistory
 }
 
-// GetCurrentModel returns the current model
+// GetCurrentModel returns the current model. With the auto alias this is the
+// model routed for the pending messages, so callers that check model traits
+// such as reasoning see the model that will actually be used.
 func (m *Manager) GetCurrentModel() string {
+	if m.state.Config.CurrentModel == platform.AutoModel && m.platformManager != nil {
+		return m.platformManager.ResolveModel(m.state.Messages, platform.AutoModel)
+	}
 	return m.state.Config.CurrentModel
 }
 
@@ -818,6 +1056,16 @@ func (m *Manager) SetCurrentPlatform(platform string) {
 	m.state.Config.CurrentPlatform = platform
 }
 
+// GetSessionRating returns the current session rating (0 when unrated)
+func (m *Manager) GetSessionRating() int {
+	return m.state.SessionRating
+}
+
+// SetSessionRating sets the current session rating persisted with the session file
+func (m *Manager) SetSessionRating(rating int) {
+	m.state.SessionRating = rating
+}
+
 // ExportCodeBlocks extracts and saves all code blocks from the last bot response
 func (m *Manager) ExportCodeBlocks(terminal *ui.Terminal) ([]string, error) {
 	if len(m.state.ChatHistory) <= 1 {
@@ -829,11 +1077,8 @@ func (m *Manager) ExportCodeBlocks(terminal *ui.Terminal) ([]string, error) {
 		return nil, fmt.Errorf("no bot response to export from")
 	}
 
-	// Regex to find markdown code blocks with optional language specification
-	codeBlockRegex := regexp.MustCompile("(?s)```([a-zA-Z0-9+#\\-\\.]*)\\s*?\\n(.*?)\\n?```")
-	matches := codeBlockRegex.FindAllStringSubmatch(lastEntry.Bot, -1)
-
-	if len(matches) == 0 {
+	blocks := codeBlocks(lastEntry.Bot)
+	if len(blocks) == 0 {
 		return nil, fmt.Errorf("no code blocks found in the last response")
 	}
 
@@ -843,15 +1088,15 @@ func (m *Manager) ExportCodeBlocks(terminal *ui.Terminal) ([]string, error) {
 		return nil, fmt.Errorf("failed to get current directory: %v", err)
 	}
 
-	for i, match := range matches {
-		code := match[2]
+	for i, block := range blocks {
+		code := block.Code
 
 		// Generate filename options and let user select. AI-suggested names
 		// (if any) sit at the top, followed by the deterministic hash list.
 		aiNames := m.generateAIFilenameOptions(code, terminal)
 		filenameOptions := append(aiNames, m.generateFilenameOptions(code)...)
 
-		prompt := fmt.Sprintf("file %d/%d: ", i+1, len(matches))
+		prompt := fmt.Sprintf("file %d/%d: ", i+1, len(blocks))
 		selectedFilename, err := terminal.FzfSelect(filenameOptions, prompt)
 		if err != nil {
 			return filePaths, fmt.Errorf("filename selection failed: %v", err)
@@ -867,7 +1112,7 @@ func (m *Manager) ExportCodeBlocks(terminal *ui.Terminal) ([]string, error) {
 		fullPath := filepath.Join(currentDir, filename)
 
 		// Write code to file
-		err = os.WriteFile(fullPath, []byte(code), 0600)
+		err = os.WriteFile(fullPath, []byte(m.redact(code)), 0600)
 		if err != nil {
 			return filePaths, fmt.Errorf("failed to write file %s: %v", filename, err)
 		}
@@ -881,6 +1126,45 @@ func (m *Manager) ExportCodeBlocks(terminal *ui.Terminal) ([]string, error) {
 	return filePaths, nil
 }
 
+// SaveCodeBlock writes code block n (1-based, as labelled in the displayed
+// response) of the last bot response to path. Without a path, a new file
+// named after the block's content and language is created in the current
+// directory. Existing files are only replaced when overwrite allows it.
+func (m *Manager) SaveCodeBlock(n int, path string, overwrite func(path string) bool) (string, error) {
+	if len(m.state.ChatHistory) <= 1 {
+		return "", fmt.Errorf("no chat history available")
+	}
+	lastEntry := m.state.ChatHistory[len(m.state.ChatHistory)-1]
+	blocks := codeBlocks(lastEntry.Bot)
+	if len(blocks) == 0 {
+		return "", fmt.Errorf("no code blocks found in the last response")
+	}
+	if n < 1 || n > len(blocks) {
+		return "", fmt.Errorf("the last response has %d code block(s), no block %d", len(blocks), n)
+	}
+	block := blocks[n-1]
+
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %v", err)
+	}
+	if path == "" {
+		path = m.generateUniqueFilename(currentDir, GenerateHashFromContent(block.Code, 5), m.getLanguageExtension(block.Language), block.Code)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(currentDir, path)
+	}
+	if _, err := os.Stat(path); err == nil && (overwrite == nil || !overwrite(path)) {
+		return "", fmt.Errorf("%s already exists, not overwritten", path)
+	}
+
+	if err := os.WriteFile(path, []byte(m.redact(block.Code)), 0600); err != nil {
+		return "", fmt.Errorf("failed to write file %s: %v", path, err)
+	}
+	m.AddRecentlyCreatedFile(path)
+	return path, nil
+}
+
 // ExportChatInteractive allows user to select chat entries via fzf, edit in text editor, and save
 func (m *Manager) ExportChatInteractive(terminal *ui.Terminal, targetFile string) (string, error) {
 	if len(m.state.ChatHistory) <= 1 {
@@ -1058,7 +1342,7 @@ func (m *Manager) ExportChatInteractive(terminal *ui.Terminal, targetFile string
 	}
 
 	fullPath := filepath.Join(currentDir, filename)
-	err = os.WriteFile(fullPath, []byte(editedContent), 0600)
+	err = os.WriteFile(fullPath, []byte(m.redact(editedContent)), 0600)
 	if err != nil {
 		return "", fmt.Errorf("failed to write file: %v", err)
 	}
@@ -1115,7 +1399,6 @@ func (m *Manager) ExportChatBlock(terminal *ui.Terminal, targetFile string) (str
 		Language string
 	}
 	var selectedSnippets []ExtractedSnippet
-	codeBlockRegex := regexp.MustCompile("(?s)```([a-zA-Z0-9+#\\-\\.]*)\\s*?\\n(.*?)\\n?```")
 
 	allSelected := ui.ContainsAllOption(selectedItems)
 
@@ -1184,7 +1467,7 @@ func (m *Manager) ExportChatBlock(terminal *ui.Terminal, targetFile string) (str
 			combined.WriteString(snippet.Content)
 		}
 		fullPath := filepath.Join(currentDir, targetFile)
-		if err := os.WriteFile(fullPath, []byte(combined.String()), 0600); err != nil {
+		if err := os.WriteFile(fullPath, []byte(m.redact(combined.String())), 0600); err != nil {
 			return "", fmt.Errorf("failed to write file %s: %v", targetFile, err)
 		}
 		m.AddRecentlyCreatedFile(fullPath)
@@ -1236,7 +1519,7 @@ func (m *Manager) ExportChatBlock(terminal *ui.Terminal, targetFile string) (str
 		}
 
 		fullPath := filepath.Join(currentDir, filename)
-		err = os.WriteFile(fullPath, []byte(snippet.Content), 0600)
+		err = os.WriteFile(fullPath, []byte(m.redact(snippet.Content)), 0600)
 		if err != nil {
 			return "", fmt.Errorf("failed to write file %s: %v", filename, err)
 		}
@@ -1255,6 +1538,7 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 		content string
 		isUser  bool
 		index   int
+		label   string
 	}
 	var entries []turnEntry
 
@@ -1269,7 +1553,11 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 				preview = preview[:70] + "..."
 			}
 			items = append(items, fmt.Sprintf("BOT: %s", preview))
-			entries = append(entries, turnEntry{content: entry.Bot, isUser: false, index: i})
+			label := "BOT:\n"
+			if annotation := ExchangeAnnotation(entry); annotation != "" && m.state.Config.ShowModelAnnotation {
+				label = "BOT " + annotation + ":\n"
+			}
+			entries = append(entries, turnEntry{content: entry.Bot, isUser: false, index: i, label: label})
 		}
 
 		// Add user prompt (use full context if available)
@@ -1283,7 +1571,7 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 				preview = preview[:70] + "..."
 			}
 			items = append(items, fmt.Sprintf("USER: %s", preview))
-			entries = append(entries, turnEntry{content: EffectiveUserContent(entry), isUser: true, index: i})
+			entries = append(entries, turnEntry{content: EffectiveUserContent(entry), isUser: true, index: i, label: "USER:\n"})
 		}
 	}
 
@@ -1314,11 +1602,7 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 			if i < len(entries)-1 {
 				combinedContent.WriteString("\n\n")
 			}
-			if entries[i].isUser {
-				combinedContent.WriteString("USER:\n")
-			} else {
-				combinedContent.WriteString("BOT:\n")
-			}
+			combinedContent.WriteString(entries[i].label)
 			combinedContent.WriteString(entries[i].content)
 		}
 	} else {
@@ -1344,11 +1628,7 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 			if i > 0 {
 				combinedContent.WriteString("\n\n")
 			}
-			if entry.isUser {
-				combinedContent.WriteString("USER:\n")
-			} else {
-				combinedContent.WriteString("BOT:\n")
-			}
+			combinedContent.WriteString(entry.label)
 			combinedContent.WriteString(entry.content)
 		}
 	}
@@ -1405,7 +1685,7 @@ func (m *Manager) ExportChatTurn(terminal *ui.Terminal, targetFile string) (stri
 	}
 
 	fullPath := filepath.Join(currentDir, filename)
-	if err := os.WriteFile(fullPath, []byte(editedContent), 0600); err != nil {
+	if err := os.WriteFile(fullPath, []byte(m.redact(editedContent)), 0600); err != nil {
 		return "", fmt.Errorf("failed to write file: %v", err)
 	}
 
@@ -1637,7 +1917,7 @@ func (m *Manager) generateAIFilenameOptions(content string, terminal *ui.Termina
 	// Count chars from non-system messages only.
 	historyChars := 0
 	for _, msg := range m.state.Messages {
-		if msg.Role == "system" {
+		if msg.Role == "system" || msg.Role == "developer" {
 			continue
 		}
 		historyChars += len(msg.Content)
@@ -5223,59 +5503,7 @@ func (m *Manager) getAllFilesInCurrentDir() ([]string, error) {
 		return nil, fmt.Errorf("failed to get current directory: %v", err)
 	}
 
-	// Get absolute path for shallow check
-	absCurrentDir, err := filepath.Abs(currentDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %v", err)
-	}
-
-	// Check if this directory should be loaded shallowly
-	isShallow := config.IsShallowLoadDir(m.state.Config, absCurrentDir)
-
-	var files []string
-
-	err = filepath.WalkDir(currentDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-
-		// Skip the root directory itself
-		if path == currentDir {
-			return nil
-		}
-
-		// Get relative path from current directory
-		relPath, err := filepath.Rel(currentDir, path)
-		if err != nil {
-			return nil // Skip if we can't get relative path
-		}
-
-		// For shallow directories, only include direct children (depth 1)
-		if isShallow {
-			depth := strings.Count(relPath, string(filepath.Separator))
-			if d.IsDir() && depth > 0 {
-				// Skip subdirectories beyond depth 1
-				return filepath.SkipDir
-			}
-			if !d.IsDir() && depth > 0 {
-				// Skip files beyond depth 1
-				return nil
-			}
-		}
-
-		// Skip version control directories but allow other hidden files/directories for export
-		if d.IsDir() && (filepath.Base(relPath) == ".git" || filepath.Base(relPath) == ".svn" || filepath.Base(relPath) == ".hg") {
-			return filepath.SkipDir
-		}
-
-		// Only include files, not directories
-		if !d.IsDir() {
-			files = append(files, relPath)
-		}
-
-		return nil
-	})
-
+	files, err := fswalk.List(currentDir, fswalk.OptionsFromConfig(m.state.Config, currentDir))
 	if err != nil {
 		return nil, fmt.Errorf("failed to walk directory: %v", err)
 	}
@@ -5284,8 +5512,11 @@ func (m *Manager) getAllFilesInCurrentDir() ([]string, error) {
 }
 
 // AddRecentlyCreatedFile adds a file to the recently created files list
-// Keeps the list limited to the last 10 files for performance
+// Keeps the list limited to the last 10 files for performance; every file
+// is also kept for the artifacts list printed on exit
 func (m *Manager) AddRecentlyCreatedFile(filePath string) {
+	m.addSessionArtifact(filePath)
+
 	// Convert to relative path if in current directory
 	if currentDir, err := os.Getwd(); err == nil {
 		if rel, err := filepath.Rel(currentDir, filePath); err == nil && !strings.HasPrefix(rel, "..") {
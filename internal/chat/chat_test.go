@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/MehmetMHY/ch/internal/platform"
 	"github.com/MehmetMHY/ch/pkg/types"
 )
 
@@ -159,6 +160,62 @@ func TestManager_MessageAndHistoryOperations(t *testing.T) {
 	}
 }
 
+func TestClearHistoryRestoresDeveloperPrompt(t *testing.T) {
+	cfg := &types.Config{SystemPrompt: "You are helpful.", DeveloperPrompt: "Follow the project style guide."}
+	state := &types.AppState{Config: cfg}
+	m := NewManager(state)
+
+	m.AddUserMessage("hello")
+	m.ClearHistory()
+
+	want := []types.ChatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "developer", Content: "Follow the project style guide."},
+	}
+	if !reflect.DeepEqual(state.Messages, want) {
+		t.Errorf("messages after clear = %v, want %v", state.Messages, want)
+	}
+}
+
+func TestDropAndRestoreLastExchange(t *testing.T) {
+	cfg := &types.Config{SystemPrompt: "System Prompt"}
+	state := &types.AppState{
+		Config:      cfg,
+		Messages:    []types.ChatMessage{{Role: "system", Content: cfg.SystemPrompt}},
+		ChatHistory: []types.ChatHistory{{User: cfg.SystemPrompt}},
+	}
+	m := NewManager(state)
+
+	if _, _, ok := m.DropLastExchange(); ok {
+		t.Fatal("DropLastExchange() succeeded with no exchange")
+	}
+
+	m.AddUserMessage("expanded prompt")
+	m.AddAssistantMessage("answer")
+	m.AddToHistory("prompt", "answer")
+	messages := append([]types.ChatMessage{}, state.Messages...)
+	history := append([]types.ChatHistory{}, state.ChatHistory...)
+
+	entry, prompt, ok := m.DropLastExchange()
+	if !ok || entry.User != "prompt" || entry.Bot != "answer" || prompt != "expanded prompt" {
+		t.Fatalf("DropLastExchange() = %+v, %q, %v", entry, prompt, ok)
+	}
+	if len(state.Messages) != 1 || len(state.ChatHistory) != 1 {
+		t.Errorf("after drop: %d messages, %d history entries, want 1 and 1", len(state.Messages), len(state.ChatHistory))
+	}
+
+	m.RestoreExchange(entry, prompt)
+	if !reflect.DeepEqual(state.Messages, messages) || !reflect.DeepEqual(state.ChatHistory, history) {
+		t.Errorf("RestoreExchange() did not put the exchange back: %v, %v", state.Messages, state.ChatHistory)
+	}
+
+	// A reply the model never saw cannot be dropped as an exchange
+	m.AddToHistory("shell output", "local result")
+	if _, _, ok := m.DropLastExchange(); ok {
+		t.Error("DropLastExchange() dropped a history entry without matching messages")
+	}
+}
+
 // ---- GetMessages / GetChatHistory / GetCurrentModel / SetCurrentModel / GetCurrentPlatform / SetCurrentPlatform ----
 
 func TestManager_Accessors(t *testing.T) {
@@ -302,6 +359,79 @@ func TestManager_SaveAndLoadSession_Latest(t *testing.T) {
 	}
 }
 
+func TestManager_SaveSessionStateLeavesNoTempFiles(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	state := &types.AppState{
+		Config:      &types.Config{CurrentPlatform: "openai", CurrentModel: "gpt-4o", EnableSessionSave: true},
+		ChatHistory: []types.ChatHistory{{User: "Hello?", Bot: "Hi!", Time: 1000}},
+	}
+	m := NewManager(state)
+	for i := 0; i < 2; i++ {
+		if err := m.SaveSessionState(); err != nil {
+			t.Fatalf("SaveSessionState() error: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(state.SessionFilePath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temp file %s left behind", entry.Name())
+		}
+	}
+	if info, err := os.Stat(state.SessionFilePath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("session file = %v, %v, want mode 0600", info, err)
+	}
+}
+
+func TestManager_AutosaveSession(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	state := &types.AppState{
+		Config:      &types.Config{CurrentPlatform: "openai", CurrentModel: "gpt-4o", EnableSessionSave: true},
+		ChatHistory: []types.ChatHistory{{User: "Hello?", Bot: "Hi!", Time: 1000}},
+	}
+	m := NewManager(state)
+	path, err := m.PrepareSessionFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unchanged session is not written
+	m.MarkSessionSaved()
+	if err := m.AutosaveSession(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged session was saved: %v", err)
+	}
+
+	state.ChatHistory = append(state.ChatHistory, types.ChatHistory{User: "More?", Bot: "Sure.", Time: 1001})
+	if err := m.AutosaveSession(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := readSessionFile(path); err != nil || len(loaded.ChatHistory) != 2 {
+		t.Fatalf("autosaved session = %+v, %v", loaded, err)
+	}
+
+	// Within autosave_interval of the last save, changes wait
+	state.Config.AutosaveInterval = 3600
+	state.ChatHistory = append(state.ChatHistory, types.ChatHistory{User: "Last?", Bot: "Yes.", Time: 1002})
+	if err := m.AutosaveSession(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := readSessionFile(path); len(loaded.ChatHistory) != 2 {
+		t.Errorf("session saved within autosave_interval: %d entries", len(loaded.ChatHistory))
+	}
+}
+
 func TestManager_PrepareSessionFilePath_AllSessions(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
@@ -540,6 +670,66 @@ func TestFormatSessionSearchPreview(t *testing.T) {
 	}
 }
 
+func TestSessionSearchLines(t *testing.T) {
+	older := &types.SessionFile{
+		Timestamp:  1783572000,
+		Platform:   "openai",
+		Model:      "gpt-4o",
+		SourceFile: "/tmp/ch_session_1783572000.json",
+		ChatHistory: []types.ChatHistory{
+			{User: "system prompt"},
+			{User: "how do\tI list\nfiles?", Bot: "Use `ls`.\nOr C:\\dir", Time: 1783572010},
+		},
+	}
+	newer := &types.SessionFile{
+		Timestamp:  1783573000,
+		Model:      "llama3",
+		SourceFile: "/tmp/ch_session_1783573000.json",
+		ChatHistory: []types.ChatHistory{
+			{User: "system prompt"},
+			{User: "hi", Time: 1783573001},
+			{User: "again", Time: 1783573002},
+		},
+	}
+	empty := &types.SessionFile{Timestamp: 1783574000, ChatHistory: []types.ChatHistory{{User: "system prompt"}}}
+
+	lines, matches := sessionSearchLines([]*types.SessionFile{older, newer, empty})
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, want 6: %q", len(lines), lines)
+	}
+
+	var visible []string
+	for _, line := range lines {
+		shown, summary, ok := strings.Cut(line, "\t")
+		if !ok || strings.Contains(summary, "\t") || strings.Contains(summary, "\n") {
+			t.Fatalf("line %q is not one visible field and one escaped summary", line)
+		}
+		visible = append(visible, shown)
+	}
+	want := []string{
+		"2026-07-09 04:56:42 UTC user: again",
+		"2026-07-09 04:56:41 UTC user: hi",
+		"2026-07-09 04:56:40 UTC session [llama3] 2 exchanges: hi",
+		"2026-07-09 04:40:10 UTC user: how do I list files?",
+		"2026-07-09 04:40:10 UTC bot: Use `ls`. Or C:\\dir",
+		"2026-07-09 04:40:00 UTC session [openai/gpt-4o] 1 exchange: how do I list files?",
+	}
+	for i := range want {
+		if visible[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, visible[i], want[i])
+		}
+	}
+	if matches[lines[0]] != newer || matches[lines[5]] != older {
+		t.Error("lines do not map back to their sessions")
+	}
+
+	_, summary, _ := strings.Cut(lines[5], "\t")
+	wantSummary := "ch_session_1783572000.json\\nopenai/gpt-4o\\n\\n> how do I list files?\\nUse `ls`. Or C:\\\\dir\\n"
+	if summary != wantSummary {
+		t.Errorf("summary = %q, want %q", summary, wantSummary)
+	}
+}
+
 func TestManager_LoadLatestSessionState_Missing(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
@@ -928,3 +1118,144 @@ func TestManager_CreateUnifiedFileOptions(t *testing.T) {
 		t.Errorf("createUnifiedFileOptions() = %v, want %v", opts, want)
 	}
 }
+
+func TestManager_AddToHistoryRecordsSeed(t *testing.T) {
+	seed := 7
+	cfg := &types.Config{CurrentPlatform: "openai", CurrentModel: "gpt-4o", Seed: &seed}
+	state := &types.AppState{Config: cfg}
+	m := NewManager(state)
+
+	m.AddToHistory("q", "a")
+	seed = 8 // later seed changes must not rewrite recorded history
+	m.AddToHistoryWithContext("q2", "a2", "ctx")
+	cfg.Seed = nil
+	m.AddToHistory("q3", "a3")
+
+	if got := state.ChatHistory[0].Seed; got == nil || *got != 7 {
+		t.Errorf("first entry seed = %v, want 7", got)
+	}
+	if got := state.ChatHistory[1].Seed; got == nil || *got != 8 {
+		t.Errorf("second entry seed = %v, want 8", got)
+	}
+	if state.ChatHistory[2].Seed != nil {
+		t.Errorf("entry without a configured seed should not record one")
+	}
+}
+
+func TestManager_GetCurrentModelRoutesAuto(t *testing.T) {
+	cfg := &types.Config{
+		CurrentModel:    platform.AutoModel,
+		AutoModelRoutes: []types.AutoModelRoute{{MaxTokens: 50, Model: "small"}, {Model: "large"}},
+	}
+	state := &types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "user", Content: "hello"}}}
+	m := NewManager(state)
+	m.SetPlatformManager(platform.NewManager(cfg))
+
+	if got := m.GetCurrentModel(); got != "small" {
+		t.Errorf("GetCurrentModel() = %q, want small", got)
+	}
+	state.Messages = append(state.Messages, types.ChatMessage{Role: "user", Content: strings.Repeat("word ", 200)})
+	if got := m.GetCurrentModel(); got != "large" {
+		t.Errorf("GetCurrentModel() = %q, want large", got)
+	}
+
+	// Without a request the routed model is unknown, so history keeps the alias
+	m.AddToHistory("q", "a")
+	if got := state.ChatHistory[0].Model; got != platform.AutoModel {
+		t.Errorf("history model = %q", got)
+	}
+}
+
+func TestFindPreviousAnswer(t *testing.T) {
+	m := NewManager(&types.AppState{
+		Config: &types.Config{},
+		ChatHistory: []types.ChatHistory{
+			{User: "What is Go?"},
+			{User: "What is Go?", Bot: "A language.", Time: 100},
+			{User: "Loaded: main.go", Context: "package main"},
+			{User: "What is Go?", Bot: "A programming language.", Time: 200},
+			{User: "pending"},
+		},
+	})
+
+	entry, ok := m.FindPreviousAnswer("  What is Go?\n")
+	if !ok || entry.Time != 200 {
+		t.Errorf("expected the latest matching answer, got %+v, %v", entry, ok)
+	}
+	if _, ok := m.FindPreviousAnswer("pending"); ok {
+		t.Error("unanswered prompts should not count as duplicates")
+	}
+	if _, ok := m.FindPreviousAnswer("What is Rust?"); ok {
+		t.Error("unexpected match for a new prompt")
+	}
+}
+
+func TestExchangeAnnotation(t *testing.T) {
+	tests := []struct {
+		entry types.ChatHistory
+		want  string
+	}{
+		{types.ChatHistory{Platform: "groq", Model: "llama-3.3-70b", Elapsed: 2.14}, "[groq/llama-3.3-70b · 2.1s]"},
+		{types.ChatHistory{Model: "gpt-4o"}, "[gpt-4o]"},
+		{types.ChatHistory{Elapsed: 0.5}, "[0.5s]"},
+		{types.ChatHistory{User: "Loaded: main.go"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ExchangeAnnotation(tt.entry); got != tt.want {
+			t.Errorf("ExchangeAnnotation(%+v) = %q, want %q", tt.entry, got, tt.want)
+		}
+	}
+
+	m := NewManager(&types.AppState{Config: &types.Config{CurrentPlatform: "openai", CurrentModel: "gpt-4o"}})
+	if got := m.LastExchangeAnnotation(); got != "" {
+		t.Errorf("empty history should have no annotation, got %q", got)
+	}
+	m.AddToHistory("q", "a")
+	if got := m.LastExchangeAnnotation(); got != "[openai/gpt-4o]" {
+		t.Errorf("LastExchangeAnnotation() = %q", got)
+	}
+}
+
+func TestSaveCodeBlock(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	state := &types.AppState{
+		Config: &types.Config{},
+		ChatHistory: []types.ChatHistory{
+			{User: "sys"},
+			{User: "two files", Bot: "```go\npackage main\n```\nand\n```python\nprint(1)\n```"},
+		},
+	}
+	m := NewManager(state)
+
+	if _, err := m.SaveCodeBlock(3, "", nil); err == nil {
+		t.Error("saving a block past the last one should fail")
+	}
+
+	path, err := m.SaveCodeBlock(2, "", nil)
+	if err != nil {
+		t.Fatalf("SaveCodeBlock() error: %v", err)
+	}
+	if filepath.Ext(path) != ".py" {
+		t.Errorf("generated name %q should use the block's language", path)
+	}
+
+	target := filepath.Join(dir, "main.go")
+	if _, err := m.SaveCodeBlock(1, "main.go", nil); err != nil {
+		t.Fatalf("SaveCodeBlock() error: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "package main" {
+		t.Errorf("saved block = %q", data)
+	}
+
+	if _, err := m.SaveCodeBlock(2, "main.go", func(string) bool { return false }); err == nil {
+		t.Error("declining the overwrite should fail")
+	}
+	if _, err := m.SaveCodeBlock(2, "main.go", func(string) bool { return true }); err != nil {
+		t.Fatalf("SaveCodeBlock() overwrite error: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "print(1)" {
+		t.Errorf("overwritten block = %q", data)
+	}
+}
@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// staleTempAge is how old a temp file must be before ch --clear removes it,
+// so an editor buffer or shell recording open in another ch process is kept
+const staleTempAge = time.Hour
+
+// CleanOptions selects what CleanTempDir removes. Temp files are always
+// candidates; saved sessions only when Sessions is set.
+type CleanOptions struct {
+	Sessions       bool
+	SessionsBefore time.Time // only sessions started before this, zero for all
+	DryRun         bool      // count what would be removed without removing it
+}
+
+// CleanReport counts what CleanTempDir removed, or would remove
+type CleanReport struct {
+	TempFiles int
+	Sessions  int
+	Bytes     int64
+}
+
+// String summarizes the report for the --clear prompt and result
+func (r CleanReport) String() string {
+	parts := []string{ui.Plural(r.TempFiles, "temp file")}
+	if r.Sessions > 0 {
+		parts = append(parts, ui.Plural(r.Sessions, "session"))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, " and "), ui.FormatBytes(r.Bytes))
+}
+
+// CleanTempDir removes leftover files from the temp directory: shell
+// recordings, editor buffers, subtitle downloads, and other files ch writes
+// there, once they are older than an hour. Saved session files, including
+// those under ws/, are removed only when opts.Sessions is set, and the
+// sqlite session database is never touched here.
+func CleanTempDir(dir string, opts CleanOptions, now time.Time) (CleanReport, error) {
+	var report CleanReport
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), SessionDBName) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		session := isSessionFileName(entry.Name())
+		switch {
+		case session && (!opts.Sessions || !sessionStartedBefore(entry.Name(), info.ModTime(), opts.SessionsBefore)):
+			return nil
+		case !session && (filepath.Dir(path) != dir || now.Sub(info.ModTime()) < staleTempAge):
+			// Only loose files at the top level are temp files
+			return nil
+		}
+
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		if session {
+			report.Sessions++
+		} else {
+			report.TempFiles++
+		}
+		report.Bytes += info.Size()
+		return nil
+	})
+	return report, err
+}
+
+// sessionStartedBefore reports whether a session file is older than cutoff,
+// using the epoch in its name and falling back to its modification time for
+// ch_session_latest.json. A zero cutoff matches every session.
+func sessionStartedBefore(name string, modTime, cutoff time.Time) bool {
+	if cutoff.IsZero() {
+		return true
+	}
+	started := modTime
+	epoch := strings.TrimSuffix(strings.TrimPrefix(name, "ch_session_"), ".json")
+	if seconds, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+		started = time.Unix(seconds, 0)
+	}
+	return started.Before(cutoff)
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanTempDir(t *testing.T) {
+	now := time.Unix(1790000000, 0)
+	old := now.Add(-2 * time.Hour)
+
+	files := map[string]time.Time{
+		"ch_shell_session_1.log":             old,
+		"ch-export-2.txt":                    old,
+		"ch-3.txt":                           now.Add(-time.Minute), // still being edited
+		SessionDBName:                        old,
+		"ch_session_1780000000.json":         old,
+		"ch_session_1789999000.json":         old,
+		"ch_session_latest.json":             now,
+		"ws/proj/ch_session_1780000000.json": old,
+		"ws/proj/notes.txt":                  old,
+	}
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		for name, modTime := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("12345"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name    string
+		opts    CleanOptions
+		want    CleanReport
+		removed []string
+	}{
+		{
+			name:    "temp files only",
+			want:    CleanReport{TempFiles: 2, Bytes: 10},
+			removed: []string{"ch_shell_session_1.log", "ch-export-2.txt"},
+		},
+		{
+			name:    "all sessions",
+			opts:    CleanOptions{Sessions: true},
+			want:    CleanReport{TempFiles: 2, Sessions: 4, Bytes: 30},
+			removed: []string{"ch_shell_session_1.log", "ch-export-2.txt", "ch_session_1780000000.json", "ch_session_1789999000.json", "ch_session_latest.json", "ws/proj/ch_session_1780000000.json"},
+		},
+		{
+			name:    "sessions before cutoff",
+			opts:    CleanOptions{Sessions: true, SessionsBefore: time.Unix(1785000000, 0)},
+			want:    CleanReport{TempFiles: 2, Sessions: 2, Bytes: 20},
+			removed: []string{"ch_shell_session_1.log", "ch-export-2.txt", "ch_session_1780000000.json", "ws/proj/ch_session_1780000000.json"},
+		},
+		{
+			name: "dry run",
+			opts: CleanOptions{Sessions: true, DryRun: true},
+			want: CleanReport{TempFiles: 2, Sessions: 4, Bytes: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setup(t)
+			got, err := CleanTempDir(dir, tt.opts, now)
+			if err != nil {
+				t.Fatalf("CleanTempDir() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CleanTempDir() = %+v, want %+v", got, tt.want)
+			}
+
+			removed := map[string]bool{}
+			for _, name := range tt.removed {
+				removed[name] = true
+			}
+			for name := range files {
+				_, err := os.Stat(filepath.Join(dir, name))
+				if exists := err == nil; exists == removed[name] {
+					t.Errorf("%s exists = %v, want %v", name, exists, !removed[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCleanReportString(t *testing.T) {
+	tests := []struct {
+		report CleanReport
+		want   string
+	}{
+		{CleanReport{}, "0 temp files (0 B)"},
+		{CleanReport{TempFiles: 1, Bytes: 1}, "1 temp file (1 B)"},
+		{CleanReport{TempFiles: 3, Sessions: 2, Bytes: 1536}, "3 temp files and 2 sessions (1.5 KB)"},
+		{CleanReport{TempFiles: 2, Bytes: 5 << 20}, "2 temp files (5.0 MB)"},
+	}
+	for _, tt := range tests {
+		if got := tt.report.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.report, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// compressTimeout bounds how long a single context compression request may take
+const compressTimeout = 90 * time.Second
+
+// compressPrompt asks the cheap model to keep only what the question needs
+const compressPrompt = "You are preparing context for another model. Below is loaded content followed by the user's question. " +
+	"Extract everything from the content that is relevant to the question, keeping exact code, numbers, names, and quotes where they matter, " +
+	"and summarize the rest in a few sentences. Do not answer the question. Output only the distilled content.\n\n" +
+	"Question:\n%s\n\nContent:\n%s"
+
+// CompressContext distills content larger than compress_threshold tokens with
+// compress_model, guided by the user's question. The original content is
+// returned when compression is disabled, not needed, or fails.
+func (m *Manager) CompressContext(terminal *ui.Terminal, content, question string) string {
+	cfg := m.state.Config
+	if cfg.CompressModel == "" || m.platformManager == nil || strings.TrimSpace(question) == "" {
+		return content
+	}
+	before := tokens.Count(cfg.CurrentModel, content)
+	if before < cfg.CompressThreshold {
+		return content
+	}
+
+	done := make(chan bool, 1)
+	go terminal.ShowLoadingAnimation("Compressing context...", done)
+	defer func() {
+		select {
+		case done <- true:
+		default:
+		}
+		m.state.IsStreaming = false
+		m.state.StreamingCancel = nil
+	}()
+
+	timeoutStop := make(chan struct{})
+	go func() {
+		select {
+		case <-time.After(compressTimeout):
+			if cancel := m.state.StreamingCancel; cancel != nil {
+				cancel()
+			}
+		case <-timeoutStop:
+		}
+	}()
+
+	response, err := m.platformManager.SendSilentChatRequest(
+		[]types.ChatMessage{{Role: "user", Content: fmt.Sprintf(compressPrompt, question, content)}},
+		cfg.CompressModel,
+		&m.state.StreamingCancel,
+		&m.state.IsStreaming,
+	)
+	close(timeoutStop)
+
+	m.state.IsStreaming = true
+	m.state.StreamingCancel = func() {}
+
+	if err == nil && strings.TrimSpace(response) == "" {
+		err = fmt.Errorf("empty response")
+	}
+	if err != nil {
+		terminal.PrintError(fmt.Sprintf("context compression failed, sending full context: %v", err))
+		return content
+	}
+
+	after := tokens.Count(cfg.CurrentModel, response)
+	if after >= before {
+		return content
+	}
+	terminal.PrintInfo(fmt.Sprintf("compressed context with %s: %d -> %d tokens", cfg.CompressModel, before, after))
+	return response
+}
+
+// PrepareContext runs the context stages for the latest user message before
+// it is sent: live file refresh, memory recall, big file retrieval, then
+// compression of large loaded context
+func (m *Manager) PrepareContext(terminal *ui.Terminal) {
+	m.RefreshLiveFiles(terminal)
+	m.AddMemoryContext()
+	m.AddBigFileContext()
+	m.CompressPendingContext(terminal)
+}
+
+// CompressPendingContext compresses the loaded context messages that precede
+// the latest user message, which is the question guiding the compression.
+// The distilled text replaces the context for this and later requests, while
+// history keeps the original for exports.
+func (m *Manager) CompressPendingContext(terminal *ui.Terminal) {
+	if m.state.Config.CompressModel == "" || len(m.state.Messages) < 2 {
+		return
+	}
+	last := len(m.state.Messages) - 1
+	if m.state.Messages[last].Role != "user" {
+		return
+	}
+	question := m.state.Messages[last].Content
+
+	for i := last - 1; i > 0 && m.state.Messages[i].Role == "user"; i-- {
+		if isLiveFileMessage(m.state.Messages[i]) {
+			continue // live files are re-read as-is, so keep them exact
+		}
+		m.state.Messages[i].Content = m.CompressContext(terminal, m.state.Messages[i].Content, question)
+	}
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// newCompressTestManager wires a chat manager to a fake chat completions
+// endpoint that answers every request with reply and records the model used
+func newCompressTestManager(t *testing.T, reply string) (*Manager, *ui.Terminal, *[]string) {
+	t.Helper()
+	var models []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("FAKE_API_KEY", "test")
+
+	cfg := &types.Config{
+		CurrentPlatform:   "fake",
+		CurrentModel:      "expensive",
+		CompressModel:     "cheap",
+		CompressThreshold: 50,
+		IsPipedOutput:     true,
+		Platforms: map[string]types.Platform{
+			"fake": {Name: "fake", BaseURL: types.BaseURLValue{Single: server.URL}, EnvName: "FAKE_API_KEY"},
+		},
+	}
+	pm := platform.NewManager(cfg)
+	if err := pm.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	m := NewManager(&types.AppState{Config: cfg})
+	m.SetPlatformManager(pm)
+	return m, ui.NewTerminal(cfg), &models
+}
+
+func TestCompressPendingContext(t *testing.T) {
+	m, terminal, models := newCompressTestManager(t, "the relevant part")
+	large := strings.Repeat("filler text about many things ", 100)
+	m.state.Messages = []types.ChatMessage{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "old question"},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "small context"},
+		{Role: "user", Content: large},
+		{Role: "user", Content: "what is relevant?"},
+	}
+
+	m.CompressPendingContext(terminal)
+
+	if got := m.state.Messages[4].Content; got != "the relevant part" {
+		t.Errorf("large context not compressed: %q", got)
+	}
+	if m.state.Messages[3].Content != "small context" || m.state.Messages[1].Content != "old question" {
+		t.Error("small or earlier messages should be left alone")
+	}
+	if m.state.Messages[5].Content != "what is relevant?" {
+		t.Error("the question itself must not change")
+	}
+	if len(*models) != 1 || (*models)[0] != "cheap" {
+		t.Errorf("expected one request to the cheap model, got %v", *models)
+	}
+}
+
+func TestCompressContextKeepsOriginalWhenDisabledOrLarger(t *testing.T) {
+	large := strings.Repeat("word ", 200)
+
+	m, terminal, models := newCompressTestManager(t, strings.Repeat("longer ", 400))
+	if got := m.CompressContext(terminal, large, "q"); got != large {
+		t.Error("a longer distillation should be discarded")
+	}
+
+	m.state.Config.CompressModel = ""
+	if got := m.CompressContext(terminal, large, "q"); got != large || len(*models) != 1 {
+		t.Errorf("disabled compression should not send requests, got %d", len(*models))
+	}
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// maxMessageSplitRetries caps how often a request is resent with smaller
+// message parts after the provider rejects a message as too long
+const maxMessageSplitRetries = 3
+
+// SendWithContextRetry sends the conversation to the current model. When the
+// provider rejects it as too long for the model's context window, the oldest
+// exchange is dropped and the request retried, telling the user what was
+// trimmed, until it fits or only pinned messages are left. A single message
+// rejected as too long is resent split into smaller parts.
+func (m *Manager) SendWithContextRetry(platformManager *platform.Manager, terminal *ui.Terminal) (string, error) {
+	// A stream cut off mid-response can leave colors set or the cursor hidden
+	state := ui.SaveTerminalState()
+	defer state.Restore()
+
+	splitRetries := 0
+	for {
+		response, err := platformManager.SendChatRequest(m.requestMessages(terminal), m.GetCurrentModel(), &m.state.StreamingCancel, &m.state.IsStreaming)
+		if platform.IsMessageTooLongError(err) && splitRetries < maxMessageSplitRetries && platformManager.LowerMessageLimit(err) {
+			splitRetries++
+			terminal.PrintInfo(fmt.Sprintf("message too long for the provider, resending it in parts of up to %d characters", platformManager.MessageLimit()))
+			continue
+		}
+		if !platform.IsContextLengthError(err) {
+			return response, err
+		}
+
+		dropped, tokens := m.dropOldestExchange()
+		if dropped == 0 {
+			return "", err
+		}
+		terminal.PrintInfo(fmt.Sprintf("context too long, dropped the %d oldest messages (~%d tokens) and retrying", dropped, tokens))
+	}
+}
+
+// dropOldestExchange removes the oldest user turn and its reply from the
+// context, returning how many messages and tokens were removed. The system
+// and developer prompts, live files, and the pending question with any
+// context loaded for it are pinned and never dropped.
+func (m *Manager) dropOldestExchange() (int, int) {
+	messages := m.state.Messages
+	start, end := pendingBounds(messages)
+
+	drop := make(map[int]bool)
+	tok := tokens.For(m.state.Config.CurrentModel)
+	dropped := 0
+	for i := start; i < end; i++ {
+		if isLiveFileMessage(messages[i]) {
+			continue
+		}
+		drop[i] = true
+		dropped += tok.Count(messages[i].Content)
+		if messages[i].Role == "assistant" {
+			break
+		}
+	}
+	if len(drop) == 0 {
+		return 0, 0
+	}
+
+	kept := make([]types.ChatMessage, 0, len(messages)-len(drop))
+	for i, msg := range messages {
+		if !drop[i] {
+			kept = append(kept, msg)
+		}
+	}
+	m.state.Messages = kept
+	return len(drop), dropped
+}
+
+// pendingBounds returns the range of messages between the leading system
+// and developer prompts and the pending question with any context loaded
+// for it, which are the messages that may be left out of a request
+func pendingBounds(messages []types.ChatMessage) (int, int) {
+	start := 0
+	for start < len(messages) && (messages[start].Role == "system" || messages[start].Role == "developer") {
+		start++
+	}
+	end := len(messages)
+	for end > start && messages[end-1].Role == "user" {
+		end--
+	}
+	return start, end
+}
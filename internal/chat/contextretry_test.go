@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestSendWithContextRetryDropsOldestExchangeOnContextLengthError(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []types.ChatMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		// Consecutive user messages are merged before sending, so the live file
+		// and the question after it arrive as one message
+		sizes = append(sizes, len(req.Messages))
+		if len(req.Messages) > 2 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"This model's maximum context length is 8192 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	t.Setenv("FAKE_API_KEY", "test")
+
+	cfg := &types.Config{
+		CurrentPlatform: "fake",
+		CurrentModel:    "small-context",
+		IsPipedOutput:   true,
+		Platforms: map[string]types.Platform{
+			"fake": {Name: "fake", BaseURL: types.BaseURLValue{Single: server.URL}, EnvName: "FAKE_API_KEY"},
+		},
+	}
+	pm := platform.NewManager(cfg)
+	if err := pm.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	m := NewManager(&types.AppState{Config: cfg})
+	m.SetPlatformManager(pm)
+
+	live := types.ChatMessage{Role: "user", Content: liveFileMarker("/tmp/notes.md") + "notes"}
+	m.state.Messages = []types.ChatMessage{
+		{Role: "system", Content: "sys"},
+		live,
+		{Role: "user", Content: "first question"},
+		{Role: "assistant", Content: "first answer"},
+		{Role: "user", Content: "second question"},
+		{Role: "assistant", Content: "second answer"},
+		{Role: "user", Content: "pending question"},
+	}
+
+	response, err := m.SendWithContextRetry(pm, ui.NewTerminal(cfg))
+	if err != nil {
+		t.Fatalf("SendWithContextRetry: %v", err)
+	}
+	if response != "ok" {
+		t.Errorf("response = %q, want ok", response)
+	}
+	if !reflect.DeepEqual(sizes, []int{6, 4, 2}) {
+		t.Errorf("request sizes = %v, want [6 4 2]", sizes)
+	}
+
+	want := []types.ChatMessage{
+		{Role: "system", Content: "sys"},
+		live,
+		{Role: "user", Content: "pending question"},
+	}
+	if !reflect.DeepEqual(m.state.Messages, want) {
+		t.Errorf("messages after retry = %v, want %v", m.state.Messages, want)
+	}
+}
+
+func TestDropOldestExchangeKeepsPendingQuestion(t *testing.T) {
+	m := NewManager(&types.AppState{Config: &types.Config{}})
+	m.state.Messages = []types.ChatMessage{
+		{Role: "system", Content: "sys"},
+		{Role: "developer", Content: "rules"},
+		{Role: "user", Content: "loaded file"},
+		{Role: "user", Content: "pending question"},
+	}
+
+	if dropped, _ := m.dropOldestExchange(); dropped != 0 {
+		t.Errorf("dropped %d messages, want none when only pinned messages are left", dropped)
+	}
+	if len(m.state.Messages) != 4 {
+		t.Errorf("messages changed: %v", m.state.Messages)
+	}
+}
+
+func TestSendWithContextRetrySplitsMessageRejectedAsTooLong(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []types.ChatMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		sizes = append(sizes, len(req.Messages))
+		for _, msg := range req.Messages {
+			if len(msg.Content) > 20000 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error":{"message":"Invalid 'messages[1].content': string too long. Expected a string with maximum length 20000, but got a string with length %d instead.","type":"invalid_request_error","code":"string_above_max_length"}}`, len(msg.Content))
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	t.Setenv("FAKE_API_KEY", "test")
+
+	cfg := &types.Config{
+		CurrentPlatform: "fake",
+		CurrentModel:    "strict",
+		IsPipedOutput:   true,
+		MaxMessageChars: 1000000,
+		Platforms: map[string]types.Platform{
+			"fake": {Name: "fake", BaseURL: types.BaseURLValue{Single: server.URL}, EnvName: "FAKE_API_KEY"},
+		},
+	}
+	pm := platform.NewManager(cfg)
+	if err := pm.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	m := NewManager(&types.AppState{Config: cfg})
+	m.SetPlatformManager(pm)
+
+	paste := types.ChatMessage{Role: "user", Content: strings.Repeat("a long pasted log line\n", 2000)}
+	m.state.Messages = []types.ChatMessage{{Role: "system", Content: "sys"}, paste}
+
+	response, err := m.SendWithContextRetry(pm, ui.NewTerminal(cfg))
+	if err != nil || response != "ok" {
+		t.Fatalf("SendWithContextRetry() = %q, %v", response, err)
+	}
+	// 46000 characters in parts under 20000: three acknowledged parts and
+	// the final turn after the system prompt
+	if !reflect.DeepEqual(sizes, []int{2, 8}) {
+		t.Errorf("request sizes = %v, want [2 8]", sizes)
+	}
+	if pm.MessageLimit() != 20000 {
+		t.Errorf("MessageLimit() = %d, want the provider's 20000", pm.MessageLimit())
+	}
+	if len(m.state.Messages) != 2 || m.state.Messages[1].Content != paste.Content {
+		t.Error("splitting changed the stored conversation")
+	}
+}
+
+func TestSendWithContextRetryStopsWhenSplittingDoesNotHelp(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		paste    int
+		requests int
+	}{
+		// The named maximum is already the limit after the first retry
+		{"same maximum again", `string too long. Expected a string with maximum length 20000, but got a string with length 30000 instead.`, 46000, 2},
+		// Halving would go on to 6250 without the retry cap
+		{"no maximum named", `string too long`, 200000, 1 + maxMessageSplitRetries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error":{"message":%q,"type":"invalid_request_error","code":"string_above_max_length"}}`, tt.message)
+			}))
+			defer server.Close()
+			t.Setenv("FAKE_API_KEY", "test")
+
+			cfg := &types.Config{
+				CurrentPlatform: "fake",
+				CurrentModel:    "strict",
+				IsPipedOutput:   true,
+				MaxMessageChars: 1000000,
+				Platforms: map[string]types.Platform{
+					"fake": {Name: "fake", BaseURL: types.BaseURLValue{Single: server.URL}, EnvName: "FAKE_API_KEY"},
+				},
+			}
+			pm := platform.NewManager(cfg)
+			if err := pm.Initialize(); err != nil {
+				t.Fatalf("Initialize: %v", err)
+			}
+			m := NewManager(&types.AppState{Config: cfg})
+			m.SetPlatformManager(pm)
+			m.state.Messages = []types.ChatMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: strings.Repeat("x", tt.paste)}}
+
+			if _, err := m.SendWithContextRetry(pm, ui.NewTerminal(cfg)); !platform.IsMessageTooLongError(err) {
+				t.Errorf("SendWithContextRetry() error = %v, want the provider's error", err)
+			}
+			if requests != tt.requests {
+				t.Errorf("sent %d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// DatasetFormats lists the supported training dataset export formats
+var DatasetFormats = []string{"openai", "sharegpt"}
+
+// DatasetFilter selects which saved sessions are included in a dataset export
+type DatasetFilter struct {
+	MinRating int
+	Tags      []string
+}
+
+// Matches reports whether a session passes the rating and tag filters.
+// A session must carry every requested tag.
+func (f DatasetFilter) Matches(session *types.SessionFile) bool {
+	if f.MinRating > 0 && session.Rating < f.MinRating {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !HasTag(session.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadSavedSessions reads every saved session from the session directory, oldest first
+func LoadSavedSessions(cfg *types.Config) ([]*types.SessionFile, error) {
+	store, err := openSessionStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	return store.List()
+}
+
+// datasetTurns returns the session's system prompt (the first history entry)
+// and its completed user/assistant exchanges, skipping turns without a response.
+func datasetTurns(session *types.SessionFile) (string, []types.ChatMessage) {
+	systemPrompt := ""
+	var messages []types.ChatMessage
+	for i, entry := range session.ChatHistory {
+		if i == 0 && entry.Bot == "" {
+			systemPrompt = entry.User
+			continue
+		}
+		user := EffectiveUserContent(entry)
+		if strings.TrimSpace(user) == "" || strings.TrimSpace(entry.Bot) == "" {
+			continue
+		}
+		messages = append(messages,
+			types.ChatMessage{Role: "user", Content: user},
+			types.ChatMessage{Role: "assistant", Content: entry.Bot},
+		)
+	}
+	return systemPrompt, messages
+}
+
+// shareGPTMessage is a single turn in a ShareGPT conversation
+type shareGPTMessage struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+// ExportDataset converts sessions matching filter into a training dataset.
+// The "openai" format is fine-tuning JSONL with one conversation per line, and
+// "sharegpt" is a JSON array of {"conversations": [...]} records. It returns
+// the encoded dataset and the number of conversations it contains.
+func ExportDataset(sessions []*types.SessionFile, format string, filter DatasetFilter) (string, int, error) {
+	if format != "openai" && format != "sharegpt" {
+		return "", 0, fmt.Errorf("unsupported dataset format: %s (supported: %s)", format, strings.Join(DatasetFormats, ", "))
+	}
+
+	var sb strings.Builder
+	var shareGPT []map[string][]shareGPTMessage
+	count := 0
+
+	for _, session := range sessions {
+		if !filter.Matches(session) {
+			continue
+		}
+		systemPrompt, turns := datasetTurns(session)
+		if len(turns) == 0 {
+			continue
+		}
+		count++
+
+		switch format {
+		case "openai":
+			messages := turns
+			if systemPrompt != "" {
+				messages = append([]types.ChatMessage{{Role: "system", Content: systemPrompt}}, turns...)
+			}
+			line, err := json.Marshal(map[string][]types.ChatMessage{"messages": messages})
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to encode dataset record: %v", err)
+			}
+			sb.Write(line)
+			sb.WriteString("\n")
+		case "sharegpt":
+			var conversation []shareGPTMessage
+			if systemPrompt != "" {
+				conversation = append(conversation, shareGPTMessage{From: "system", Value: systemPrompt})
+			}
+			for _, turn := range turns {
+				from := "human"
+				if turn.Role == "assistant" {
+					from = "gpt"
+				}
+				conversation = append(conversation, shareGPTMessage{From: from, Value: turn.Content})
+			}
+			shareGPT = append(shareGPT, map[string][]shareGPTMessage{"conversations": conversation})
+		}
+	}
+
+	if format == "sharegpt" {
+		if shareGPT == nil {
+			shareGPT = []map[string][]shareGPTMessage{}
+		}
+		data, err := json.MarshalIndent(shareGPT, "", "  ")
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to encode dataset: %v", err)
+		}
+		return string(data) + "\n", count, nil
+	}
+
+	return sb.String(), count, nil
+}
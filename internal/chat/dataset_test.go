@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func datasetTestSessions() []*types.SessionFile {
+	return []*types.SessionFile{
+		{
+			Timestamp: 1,
+			Rating:    5,
+			Tags:      []string{"go", "Favorite"},
+			ChatHistory: []types.ChatHistory{
+				{User: "Sys"},
+				{User: "!l main.go", Context: "File: main.go\npackage main", Bot: "It is a Go file."},
+				{User: "pending question", Bot: ""},
+			},
+		},
+		{
+			Timestamp: 2,
+			Rating:    2,
+			ChatHistory: []types.ChatHistory{
+				{User: "Sys"},
+				{User: "hi", Bot: "hello"},
+			},
+		},
+		{
+			Timestamp:   3,
+			Rating:      5,
+			ChatHistory: []types.ChatHistory{{User: "Sys"}},
+		},
+	}
+}
+
+func TestDatasetFilterMatches(t *testing.T) {
+	sessions := datasetTestSessions()
+	tests := []struct {
+		name   string
+		filter DatasetFilter
+		want   []bool
+	}{
+		{name: "no filter", filter: DatasetFilter{}, want: []bool{true, true, true}},
+		{name: "min rating", filter: DatasetFilter{MinRating: 4}, want: []bool{true, false, true}},
+		{name: "tag is case-insensitive", filter: DatasetFilter{Tags: []string{"favorite"}}, want: []bool{true, false, false}},
+		{name: "all tags required", filter: DatasetFilter{Tags: []string{"go", "rust"}}, want: []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, session := range sessions {
+				if got := tt.filter.Matches(session); got != tt.want[i] {
+					t.Errorf("session %d: Matches() = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExportDataset_OpenAI(t *testing.T) {
+	out, count, err := ExportDataset(datasetTestSessions(), "openai", DatasetFilter{})
+	if err != nil {
+		t.Fatalf("ExportDataset() error: %v", err)
+	}
+	// The third session has no completed exchanges and is skipped
+	if count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSONL lines, got %d:\n%s", len(lines), out)
+	}
+
+	var record struct {
+		Messages []types.ChatMessage `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	want := []types.ChatMessage{
+		{Role: "system", Content: "Sys"},
+		{Role: "user", Content: "File: main.go\npackage main"},
+		{Role: "assistant", Content: "It is a Go file."},
+	}
+	if len(record.Messages) != len(want) {
+		t.Fatalf("messages = %+v, want %+v", record.Messages, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(record.Messages[i], want[i]) {
+			t.Errorf("message %d = %+v, want %+v", i, record.Messages[i], want[i])
+		}
+	}
+}
+
+func TestExportDataset_ShareGPT(t *testing.T) {
+	out, count, err := ExportDataset(datasetTestSessions(), "sharegpt", DatasetFilter{MinRating: 3})
+	if err != nil {
+		t.Fatalf("ExportDataset() error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	var records []struct {
+		Conversations []shareGPTMessage `json:"conversations"`
+	}
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatalf("output is not a JSON array: %v", err)
+	}
+	if len(records) != 1 || len(records[0].Conversations) != 3 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	froms := []string{records[0].Conversations[0].From, records[0].Conversations[1].From, records[0].Conversations[2].From}
+	if strings.Join(froms, ",") != "system,human,gpt" {
+		t.Errorf("roles = %v, want system,human,gpt", froms)
+	}
+}
+
+func TestExportDataset_UnknownFormat(t *testing.T) {
+	if _, _, err := ExportDataset(datasetTestSessions(), "alpaca", DatasetFilter{}); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestManager_SessionRatingPersists(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	state := &types.AppState{
+		Config:      &types.Config{SystemPrompt: "Sys", EnableSessionSave: true},
+		ChatHistory: []types.ChatHistory{{User: "Sys"}, {User: "q", Bot: "a"}},
+	}
+	m := NewManager(state)
+	m.SetSessionRating(4)
+	state.SessionTags = []string{"keep"}
+	if err := m.SaveSessionState(); err != nil {
+		t.Fatalf("SaveSessionState() error: %v", err)
+	}
+
+	sessions, err := LoadSavedSessions(&types.Config{})
+	if err != nil {
+		t.Fatalf("LoadSavedSessions() error: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Rating != 4 || len(sessions[0].Tags) != 1 {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	restored := NewManager(&types.AppState{Config: &types.Config{SystemPrompt: "Sys"}})
+	restored.RestoreSessionState(sessions[0])
+	if restored.GetSessionRating() != 4 {
+		t.Errorf("restored rating = %d, want 4", restored.GetSessionRating())
+	}
+}
@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// ReadEditableFile returns the content of a text file for !edit
+func ReadEditableFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- The user names the file to edit.
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", fmt.Errorf("%s is not a text file", path)
+	}
+	return string(data), nil
+}
+
+// EditPrompt asks the model to change the file at path, whose current
+// content is given, as instruction says, replying with edits ApplyEdit can
+// parse
+func EditPrompt(path, content, instruction string) string {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("File: %s\n%s\n%s\n%s\n\nEdit this file: %s\n\n%s", path, fence, strings.TrimSuffix(content, "\n"), fence, instruction, applyInstructions)
+}
+
+// ApplyEdit applies the edits in the last response to the file at path,
+// whatever path the edits name, shows the diff, and once confirmed writes
+// the file after copying the original to path.bak. It reports whether the
+// file was written.
+func (m *Manager) ApplyEdit(terminal *ui.Terminal, path string) (bool, error) {
+	if len(m.state.ChatHistory) <= 1 || m.state.ChatHistory[len(m.state.ChatHistory)-1].Bot == "" {
+		return false, fmt.Errorf("no response to apply edits from")
+	}
+	patches, err := parsePatches(m.state.ChatHistory[len(m.state.ChatHistory)-1].Bot, path)
+	if err != nil {
+		return false, err
+	}
+	if len(patches) == 0 {
+		return false, fmt.Errorf("the response has no edits")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	old, err := ReadEditableFile(path)
+	if err != nil {
+		return false, err
+	}
+	updated := old
+	for _, patch := range patches {
+		if patch.Search == "" {
+			return false, fmt.Errorf("edit with an empty SEARCH part, but %s already exists", path)
+		}
+		if updated, err = applyPatch(updated, patch, false); err != nil {
+			return false, err
+		}
+	}
+	if updated == old {
+		return false, fmt.Errorf("the edits change nothing")
+	}
+
+	fmt.Fprint(terminal.UIWriter(), colorDiff(FileChange{Path: path, Old: old, New: updated}))
+	if !terminal.Confirm(fmt.Sprintf("write %s (original saved as %s.bak)?", path, path)) {
+		return false, nil
+	}
+
+	mode := info.Mode().Perm()
+	if err := os.WriteFile(path+".bak", []byte(old), mode); err != nil {
+		return false, fmt.Errorf("failed to write backup: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(updated), mode); err != nil {
+		return false, fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		m.AddRecentlyCreatedFile(absPath)
+	}
+	return true, nil
+}
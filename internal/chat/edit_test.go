@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestEditPrompt(t *testing.T) {
+	got := EditPrompt("README.md", "# Title\n```sh\nmake\n```\n", "add a license section")
+	if !strings.HasPrefix(got, "File: README.md\n````\n# Title\n```sh\nmake\n```\n````\n\nEdit this file: add a license section\n\n") {
+		t.Errorf("EditPrompt() = %q, want the content fenced with more backticks than it contains", got)
+	}
+	if !strings.HasSuffix(got, applyInstructions) {
+		t.Error("EditPrompt() is missing the edit format instructions")
+	}
+}
+
+func TestReadEditableFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "bin"), []byte("ELF\x00\x01"), 0600)
+	for _, path := range []string{dir, filepath.Join(dir, "bin"), filepath.Join(dir, "missing")} {
+		if _, err := ReadEditableFile(path); err == nil {
+			t.Errorf("ReadEditableFile(%s) succeeded", path)
+		}
+	}
+}
+
+func TestManager_ApplyEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	original := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	os.WriteFile(path, []byte(original), 0640)
+
+	cfg := &types.Config{IsPipedOutput: true}
+	terminal := ui.NewTerminal(cfg)
+	m := NewManager(&types.AppState{Config: cfg, ChatHistory: []types.ChatHistory{{}}})
+
+	tests := []struct {
+		name    string
+		reply   string
+		wantErr string
+	}{
+		{"edits without a path line", "<<<<<<< SEARCH\n\tprintln(\"hi\")\n=======\n\tprintln(\"hello\")\n>>>>>>> REPLACE", ""},
+		{"unified diff naming another file", "--- a/other.go\n+++ b/other.go\n@@ -3,3 +3,3 @@\n func main() {\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")\n }\n", ""},
+		{"search not found", "main.go\n<<<<<<< SEARCH\nmissing\n=======\nx\n>>>>>>> REPLACE", "SEARCH part not found"},
+		{"no change", "main.go\n<<<<<<< SEARCH\npackage main\n=======\npackage main\n>>>>>>> REPLACE", "change nothing"},
+		{"no edits", "Looks fine as is.", "no search/replace blocks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.AddToHistory("edit", tt.reply)
+			// Confirm declines when stdin is not a terminal, so valid edits
+			// are previewed but never written
+			written, err := m.ApplyEdit(terminal, path)
+			if tt.wantErr == "" && (err != nil || written) {
+				t.Errorf("ApplyEdit() = %v, %v; want a declined preview", written, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ApplyEdit() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("file = %q, want it unchanged", data)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup written without confirmation: %v", err)
+	}
+}
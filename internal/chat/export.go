@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// CommandOutputNote is the reply recorded for !x, whose output goes to the
+// context instead of getting an answer
+const CommandOutputNote = "Command executed and output added to context"
+
+// Export formats accepted by FormatExport
+const (
+	ExportText     = "text"
+	ExportMarkdown = "markdown"
+	ExportJSON     = "json"
+)
+
+// ExportFilter narrows the exchanges an export includes. Zero values leave
+// a filter off.
+type ExportFilter struct {
+	Since        time.Time // only exchanges at or after this time
+	Role         string    // "user" or "assistant" keeps only that side
+	Last         int       // only the last N exchanges left by the other filters
+	StripContext bool      // drop file loads, scrapes, and command output, keeping typed prompts
+	Notes        bool      // append the session's !note notes
+}
+
+// FilterHistory returns the exchanges of history that pass filter, leaving
+// out the system prompt entry at position 0
+func FilterHistory(history []types.ChatHistory, filter ExportFilter) ([]types.ChatHistory, error) {
+	switch filter.Role {
+	case "", "user", "assistant":
+	default:
+		return nil, fmt.Errorf("unknown role %q (use user or assistant)", filter.Role)
+	}
+
+	var entries []types.ChatHistory
+	for i := 1; i < len(history); i++ {
+		entry := history[i]
+		if !filter.Since.IsZero() && entry.Time < filter.Since.Unix() {
+			continue
+		}
+		if filter.StripContext {
+			if IsLoadedContext(entry) {
+				continue
+			}
+			entry.Context = ""
+		}
+		switch filter.Role {
+		case "user":
+			entry.Bot = ""
+		case "assistant":
+			entry.User, entry.Context = "", ""
+		}
+		if entry.User == "" && entry.Bot == "" && entry.Context == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if filter.Last > 0 && len(entries) > filter.Last {
+		entries = entries[len(entries)-filter.Last:]
+	}
+	return entries, nil
+}
+
+// FormatExport renders entries as a plain text transcript, Markdown, or the
+// JSON export entries
+func FormatExport(entries []types.ChatHistory, format string) (string, error) {
+	switch format {
+	case "", ExportText:
+		var b strings.Builder
+		for i, entry := range entries {
+			if i > 0 {
+				b.WriteString("\n\n" + strings.Repeat("=", 50) + "\n\n")
+			}
+			timestamp := time.Unix(entry.Time, 0).Format("2006-01-02 15:04:05")
+			fmt.Fprintf(&b, "Entry %d - %s - %s/%s\n\n", i+1, timestamp, entry.Platform, entry.Model)
+			if content := EffectiveUserContent(entry); content != "" {
+				b.WriteString("USER:\n" + content + "\n\n")
+			}
+			if entry.Bot != "" {
+				b.WriteString("ASSISTANT:\n" + entry.Bot + "\n")
+			}
+		}
+		return b.String(), nil
+
+	case ExportMarkdown:
+		var b strings.Builder
+		for _, entry := range entries {
+			timestamp := time.Unix(entry.Time, 0).Format("2006-01-02 15:04:05")
+			fmt.Fprintf(&b, "## %s · %s/%s\n\n", timestamp, entry.Platform, entry.Model)
+			if content := EffectiveUserContent(entry); content != "" {
+				b.WriteString("**User:**\n\n" + content + "\n\n")
+			}
+			if entry.Bot != "" {
+				b.WriteString("**Assistant:**\n\n" + entry.Bot + "\n\n")
+			}
+		}
+		return b.String(), nil
+
+	case ExportJSON:
+		exported := make([]types.ExportEntry, 0, len(entries))
+		for _, entry := range entries {
+			exported = append(exported, types.ExportEntry{
+				Platform:    entry.Platform,
+				ModelName:   entry.Model,
+				UserPrompt:  EffectiveUserContent(entry),
+				BotResponse: entry.Bot,
+				Timestamp:   entry.Time,
+				Seed:        entry.Seed,
+				Elapsed:     entry.Elapsed,
+			})
+		}
+		data, err := json.MarshalIndent(exported, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+		return string(data) + "\n", nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (use text, markdown, or json)", format)
+}
+
+// ExportFiltered renders the session's exchanges that pass filter in format,
+// followed by the session notes when filter asks for them, with redactions
+// applied
+func (m *Manager) ExportFiltered(filter ExportFilter, format string) (string, error) {
+	entries, err := FilterHistory(m.state.ChatHistory, filter)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("no chat entries match the export filters")
+	}
+	content, err := FormatExport(entries, format)
+	if err != nil {
+		return "", err
+	}
+	if filter.Notes {
+		if content, err = appendNotes(content, m.state.SessionNotes, format); err != nil {
+			return "", err
+		}
+	}
+	return m.redact(content), nil
+}
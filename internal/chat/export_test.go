@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFilterHistory(t *testing.T) {
+	history := []types.ChatHistory{
+		{User: "system prompt", Time: 100},
+		{User: "Loaded: main.go", Context: "package main", Time: 200},
+		{User: "explain main.go", Bot: "It prints hello.", Time: 300},
+		{User: "!x ls", Bot: CommandOutputNote, Context: "$ ls\nmain.go", Time: 400},
+		{User: "summarize", Bot: "A summary.", Context: "scraped page\n\nsummarize", Time: 500},
+		{User: "thanks", Bot: "You're welcome.", Time: 600},
+	}
+
+	users := func(entries []types.ChatHistory) string {
+		var parts []string
+		for _, entry := range entries {
+			parts = append(parts, EffectiveUserContent(entry)+"|"+entry.Bot)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	tests := []struct {
+		name   string
+		filter ExportFilter
+		want   string
+	}{
+		{"no filter", ExportFilter{}, "package main|, explain main.go|It prints hello., $ ls\nmain.go|" + CommandOutputNote + ", scraped page\n\nsummarize|A summary., thanks|You're welcome."},
+		{"strip context", ExportFilter{StripContext: true}, "explain main.go|It prints hello., summarize|A summary., thanks|You're welcome."},
+		{"since", ExportFilter{Since: time.Unix(500, 0), StripContext: true}, "summarize|A summary., thanks|You're welcome."},
+		{"last", ExportFilter{Last: 2, StripContext: true}, "summarize|A summary., thanks|You're welcome."},
+		{"assistant only", ExportFilter{Role: "assistant", StripContext: true}, "|It prints hello., |A summary., |You're welcome."},
+		{"user only", ExportFilter{Role: "user", StripContext: true, Last: 1}, "thanks|"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := FilterHistory(history, tt.filter)
+			if err != nil {
+				t.Fatalf("FilterHistory() error: %v", err)
+			}
+			if got := users(entries); got != tt.want {
+				t.Errorf("FilterHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FilterHistory(history, ExportFilter{Role: "system"}); err == nil {
+		t.Error("an unknown role should be an error")
+	}
+}
+
+func TestFormatExport(t *testing.T) {
+	entries := []types.ChatHistory{{User: "hi", Bot: "hello", Platform: "openai", Model: "gpt-4.1", Time: 1}}
+
+	text, err := FormatExport(entries, ExportText)
+	if err != nil || !strings.Contains(text, "USER:\nhi\n\nASSISTANT:\nhello\n") || !strings.Contains(text, "openai/gpt-4.1") {
+		t.Errorf("text export = %q, %v", text, err)
+	}
+
+	markdown, err := FormatExport(entries, ExportMarkdown)
+	if err != nil || !strings.Contains(markdown, "**User:**\n\nhi") || !strings.HasPrefix(markdown, "## ") {
+		t.Errorf("markdown export = %q, %v", markdown, err)
+	}
+
+	data, err := FormatExport(entries, ExportJSON)
+	var exported []types.ExportEntry
+	if err != nil || json.Unmarshal([]byte(data), &exported) != nil || len(exported) != 1 || exported[0].BotResponse != "hello" {
+		t.Errorf("json export = %q, %v", data, err)
+	}
+
+	if _, err := FormatExport(entries, "csv"); err == nil {
+		t.Error("an unknown format should be an error")
+	}
+}
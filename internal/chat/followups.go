@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// followupTimeout bounds how long the follow-up suggestion request may take
+const followupTimeout = 15 * time.Second
+
+// followupPrefixRegex matches list markers such as "1.", "2)", "-", or "*" before a question
+var followupPrefixRegex = regexp.MustCompile(`^\s*(?:\d+[.):]|[-*•])\s*`)
+
+// SuggestFollowups asks the current model for short follow-up questions to the
+// latest exchange. Returns nil on any failure so the chat continues normally.
+func (m *Manager) SuggestFollowups(terminal *ui.Terminal) []string {
+	if m.platformManager == nil || m.state == nil || m.state.Config == nil {
+		return nil
+	}
+	count := m.state.Config.FollowupCount
+	if count <= 0 {
+		return nil
+	}
+
+	prompt := fmt.Sprintf("Suggest %d short follow-up questions the user might ask next about the conversation above. "+
+		"Write each question from the user's point of view, one per line, inside one fenced code block tagged \"text\". "+
+		"Do not number them and do not add any other text.", count)
+
+	requestMessages := append([]types.ChatMessage{}, m.state.Messages...)
+	requestMessages = append(requestMessages, types.ChatMessage{Role: "user", Content: prompt})
+
+	// Same spinner and cancellation handling as AI filename suggestions, so
+	// Ctrl-C cancels the request instead of exiting the program.
+	done := make(chan bool, 1)
+	go terminal.ShowLoadingAnimation("Suggesting follow-ups...", done)
+	defer func() {
+		select {
+		case done <- true:
+		default:
+		}
+		m.state.IsStreaming = false
+		m.state.StreamingCancel = nil
+	}()
+
+	timeoutStop := make(chan struct{})
+	go func() {
+		select {
+		case <-time.After(followupTimeout):
+			if cancel := m.state.StreamingCancel; cancel != nil {
+				cancel()
+			}
+		case <-timeoutStop:
+		}
+	}()
+
+	response, err := m.platformManager.SendSilentChatRequest(
+		requestMessages,
+		m.state.Config.CurrentModel,
+		&m.state.StreamingCancel,
+		&m.state.IsStreaming,
+	)
+	close(timeoutStop)
+
+	m.state.IsStreaming = true
+	m.state.StreamingCancel = func() {}
+
+	if err != nil || strings.TrimSpace(response) == "" {
+		return nil
+	}
+
+	return parseFollowupOutput(response, count)
+}
+
+// parseFollowupOutput extracts up to maxCount questions from the model response,
+// preferring a fenced code block and stripping list markers and quotes.
+func parseFollowupOutput(response string, maxCount int) []string {
+	body := strings.TrimSpace(response)
+
+	if start := strings.Index(body, "```"); start >= 0 {
+		afterOpen := body[start+3:]
+		if nl := strings.Index(afterOpen, "\n"); nl >= 0 {
+			afterOpen = afterOpen[nl+1:]
+		}
+		if end := strings.Index(afterOpen, "```"); end >= 0 {
+			body = afterOpen[:end]
+		}
+	}
+
+	seen := make(map[string]bool)
+	var out []string
+	for _, line := range strings.Split(body, "\n") {
+		question := followupPrefixRegex.ReplaceAllString(line, "")
+		question = strings.Trim(strings.TrimSpace(question), "\"'`")
+		key := strings.ToLower(question)
+		if question == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, question)
+		if len(out) >= maxCount {
+			break
+		}
+	}
+
+	return out
+}
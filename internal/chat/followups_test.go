@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFollowupOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		max      int
+		want     []string
+	}{
+		{
+			name:     "fenced block",
+			response: "Sure!\n```text\nHow does it scale?\nWhat are the trade-offs?\n```\nHope that helps.",
+			max:      3,
+			want:     []string{"How does it scale?", "What are the trade-offs?"},
+		},
+		{
+			name:     "numbered list without fence",
+			response: "1. Why is that?\n2) \"Can you show an example?\"\n- why is that?\n* What else?",
+			max:      3,
+			want:     []string{"Why is that?", "Can you show an example?", "What else?"},
+		},
+		{
+			name:     "respects max",
+			response: "a?\nb?\nc?",
+			max:      2,
+			want:     []string{"a?", "b?"},
+		},
+		{
+			name:     "empty",
+			response: "   ",
+			max:      3,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFollowupOutput(tt.response, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFollowupOutput() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
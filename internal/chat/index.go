@@ -0,0 +1,234 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// indexExcerptHeader starts the context message holding chunks retrieved
+// from a directory index
+const indexExcerptHeader = "[index excerpts]"
+
+// DirIndex is an embeddings index of the text files of a directory, built
+// with ch --index and stored in ~/.ch/index
+type DirIndex struct {
+	Root     string       `json:"root"`
+	Platform string       `json:"platform"`
+	Model    string       `json:"model"`
+	Updated  time.Time    `json:"updated"`
+	Chunks   []IndexChunk `json:"chunks"`
+}
+
+// IndexChunk is one chunk of an indexed file and its embedding. SHA256 is
+// the checksum of the whole file, so unchanged files keep their vectors
+// when the index is rebuilt.
+type IndexChunk struct {
+	Path   string    `json:"path"`
+	SHA256 string    `json:"sha256"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// IndexStats reports the files and chunks of a built index, and how many
+// chunks had to be embedded
+type IndexStats struct {
+	Files    int
+	Chunks   int
+	Embedded int
+}
+
+// indexFile returns where the index of root is stored: its base name plus a
+// hash of the full path, so same-named directories do not collide
+func indexFile(root string) (string, error) {
+	dir, err := config.GetIndexDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", filepath.Base(root), hex.EncodeToString(sum[:6]))), nil
+}
+
+// LoadIndex reads the stored index of root, an absolute directory path
+func LoadIndex(root string) (*DirIndex, error) {
+	path, err := indexFile(root)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- Index files are named by ch inside ~/.ch/index.
+	if err != nil {
+		return nil, err
+	}
+	var index DirIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %v", path, err)
+	}
+	return &index, nil
+}
+
+// FindIndex returns the index of dir or of the closest parent directory
+// that has one
+func FindIndex(dir string) (*DirIndex, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for current := absDir; ; {
+		index, err := LoadIndex(current)
+		if err == nil {
+			return index, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, fmt.Errorf("no index covers %s: build one with ch --index <dir>", absDir)
+		}
+		current = parent
+	}
+}
+
+// save writes the index to ~/.ch/index
+func (ix *DirIndex) save() error {
+	path, err := indexFile(ix.Root)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	return nil
+}
+
+// Summary describes the index for status lines
+func (ix *DirIndex) Summary() string {
+	files := make(map[string]bool)
+	for _, chunk := range ix.Chunks {
+		files[chunk.Path] = true
+	}
+	return fmt.Sprintf("%s (%d files, %d chunks, %s on %s, updated %s)", ix.Root, len(files), len(ix.Chunks), ix.Model, ix.Platform, ix.Updated.Format("2006-01-02 15:04"))
+}
+
+// BuildIndex chunks the text files under dir, embeds them with
+// embedding_model on the current platform, and stores the index. Files
+// unchanged since the last build with the same platform and model keep
+// their vectors, so rebuilding only embeds what changed.
+func BuildIndex(terminal *ui.Terminal, platformManager *platform.Manager, cfg *types.Config, dir string) (*DirIndex, IndexStats, error) {
+	var stats IndexStats
+	dump, err := terminal.ReadTextFiles(dir)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	previous := make(map[string][]IndexChunk)
+	if old, err := LoadIndex(dump.Dir); err == nil && old.Platform == cfg.CurrentPlatform && old.Model == cfg.EmbeddingModel {
+		for _, chunk := range old.Chunks {
+			key := chunk.Path + "\x00" + chunk.SHA256
+			previous[key] = append(previous[key], chunk)
+		}
+	}
+
+	index := &DirIndex{Root: dump.Dir, Platform: cfg.CurrentPlatform, Model: cfg.EmbeddingModel, Updated: time.Now()}
+	var pending []int
+	var inputs []string
+	for _, file := range dump.Files {
+		if file.Error != "" || strings.TrimSpace(file.Content) == "" {
+			continue
+		}
+		stats.Files++
+		if kept, ok := previous[file.Path+"\x00"+file.SHA256]; ok {
+			index.Chunks = append(index.Chunks, kept...)
+			continue
+		}
+		chunks, err := ChunkText(file.Content, cfg.IndexChunkTokens, cfg.IndexChunkTokens/8)
+		if err != nil {
+			return nil, stats, fmt.Errorf("error chunking %s: %v", file.Path, err)
+		}
+		for _, text := range chunks {
+			pending = append(pending, len(index.Chunks))
+			// The path helps match questions that name a file or package
+			inputs = append(inputs, truncateForEmbedding(file.Path+"\n"+text))
+			index.Chunks = append(index.Chunks, IndexChunk{Path: file.Path, SHA256: file.SHA256, Text: text})
+		}
+	}
+	if len(index.Chunks) == 0 {
+		return nil, stats, fmt.Errorf("no text content found in %s", dump.Dir)
+	}
+
+	if len(inputs) > 0 {
+		done := make(chan bool, 1)
+		go terminal.ShowLoadingAnimation(fmt.Sprintf("Embedding %d chunks...", len(inputs)), done)
+		vectors, err := platformManager.CreateEmbeddings(inputs, platform.EmbeddingOptions{
+			Model:             cfg.EmbeddingModel,
+			BatchSize:         cfg.EmbeddingBatchSize,
+			RequestsPerMinute: cfg.EmbeddingRPM,
+		})
+		done <- true
+		if err != nil {
+			return nil, stats, err
+		}
+		for k, i := range pending {
+			index.Chunks[i].Vector = vectors[k]
+		}
+	}
+
+	stats.Chunks = len(index.Chunks)
+	stats.Embedded = len(inputs)
+	if err := index.save(); err != nil {
+		return nil, stats, err
+	}
+	return index, stats, nil
+}
+
+// RetrieveFromIndex embeds question and returns the index_top_k chunks of
+// index closest to it as a context message. The question must be embedded
+// with the platform and model the index was built with.
+func (m *Manager) RetrieveFromIndex(index *DirIndex, question string) (string, error) {
+	cfg := m.state.Config
+	if index.Platform != cfg.CurrentPlatform || index.Model != cfg.EmbeddingModel {
+		return "", fmt.Errorf("%s was indexed with %s on %s, but questions would be embedded with %s on %s: switch platform or rebuild with ch --index", index.Root, index.Model, index.Platform, cfg.EmbeddingModel, cfg.CurrentPlatform)
+	}
+	if m.platformManager == nil {
+		return "", fmt.Errorf("platform client is not initialized")
+	}
+
+	vectors, err := m.platformManager.CreateEmbeddings([]string{truncateForEmbedding(question)}, platform.EmbeddingOptions{Model: cfg.EmbeddingModel})
+	if err != nil {
+		return "", err
+	}
+	scores := make([]float64, len(index.Chunks))
+	for i, chunk := range index.Chunks {
+		scores[i] = cosineSimilarity(vectors[0], chunk.Vector)
+	}
+	topK := cfg.IndexTopK
+	if topK <= 0 {
+		topK = 1
+	}
+	selected := topScoring(scores, topK)
+	if len(selected) == 0 {
+		return "", fmt.Errorf("no indexed chunks match the question")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s Relevant excerpts from %s:\n", indexExcerptHeader, index.Root))
+	for _, i := range selected {
+		sb.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", index.Chunks[i].Path, index.Chunks[i].Text))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// newIndexTestManager returns a manager whose platform embeds text as
+// [mentions retry, mentions database, 0.1], counting the inputs embedded
+func newIndexTestManager(t *testing.T) (*Manager, *ui.Terminal, *int) {
+	t.Helper()
+	embedded := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var data []map[string]interface{}
+		for i, input := range req.Input {
+			vector := []float32{0, 0, 0.1}
+			if strings.Contains(input, "retry") {
+				vector[0] = 1
+			}
+			if strings.Contains(input, "database") {
+				vector[1] = 1
+			}
+			data = append(data, map[string]interface{}{"object": "embedding", "index": i, "embedding": vector})
+		}
+		embedded += len(req.Input)
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("FAKE_API_KEY", "test")
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := &types.Config{
+		CurrentPlatform:  "fake",
+		EmbeddingModel:   "embed-small",
+		IndexChunkTokens: 400,
+		IndexTopK:        1,
+		IsPipedOutput:    true,
+		Platforms: map[string]types.Platform{
+			"fake": {Name: "fake", BaseURL: types.BaseURLValue{Single: server.URL}, EnvName: "FAKE_API_KEY"},
+		},
+	}
+	pm := platform.NewManager(cfg)
+	if err := pm.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	m := NewManager(&types.AppState{Config: cfg})
+	m.SetPlatformManager(pm)
+	return m, ui.NewTerminal(cfg), &embedded
+}
+
+func TestBuildIndexAndRetrieve(t *testing.T) {
+	m, terminal, embedded := newIndexTestManager(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "client.go"), []byte("package client\n\n// retry failed requests three times\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "store.go"), []byte("package store\n\n// open the database connection\n"), 0600)
+	os.WriteFile(filepath.Join(dir, "empty.txt"), []byte("  \n"), 0600)
+
+	index, stats, err := BuildIndex(terminal, m.platformManager, m.state.Config, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 || stats.Chunks != 2 || stats.Embedded != 2 {
+		t.Errorf("stats = %+v, want 2 files, 2 chunks, 2 embedded", stats)
+	}
+
+	found, err := FindIndex(filepath.Join(dir, "sub", "dir"))
+	if err != nil || found.Root != index.Root || len(found.Chunks) != 2 {
+		t.Fatalf("FindIndex() = %+v, %v", found, err)
+	}
+	excerpts, err := m.RetrieveFromIndex(found, "how does the database open?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(excerpts, indexExcerptHeader) || !strings.Contains(excerpts, "--- store.go ---") || strings.Contains(excerpts, "client.go") {
+		t.Errorf("excerpts = %q", excerpts)
+	}
+
+	// Only the changed file is embedded again
+	os.WriteFile(filepath.Join(dir, "client.go"), []byte("package client\n\n// retry failed requests five times\n"), 0600)
+	*embedded = 0
+	if _, stats, err = BuildIndex(terminal, m.platformManager, m.state.Config, dir); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Embedded != 1 || *embedded != 1 || stats.Chunks != 2 {
+		t.Errorf("rebuild stats = %+v with %d inputs embedded, want 1 of 2 chunks", stats, *embedded)
+	}
+
+	m.state.Config.EmbeddingModel = "embed-large"
+	if _, err := m.RetrieveFromIndex(found, "database"); err == nil || !strings.Contains(err.Error(), "rebuild with ch --index") {
+		t.Errorf("RetrieveFromIndex() with another model error = %v", err)
+	}
+}
+
+func TestFindIndexMissing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := FindIndex(t.TempDir()); err == nil || !strings.Contains(err.Error(), "ch --index") {
+		t.Errorf("FindIndex() error = %v, want a hint to build an index", err)
+	}
+}
@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// shellInterpolationTimeout bounds each $(command) so a hung command cannot
+// block the prompt forever
+const shellInterpolationTimeout = 30 * time.Second
+
+// shellSubstitution is one $(command) span in a prompt
+type shellSubstitution struct {
+	start, end int // byte offsets of the whole $(...) span
+	command    string
+}
+
+// findShellSubstitutions returns the top-level $(command) spans in prompt.
+// Parentheses inside the command are balanced, so $(echo $(date)) is one
+// span, and an unterminated $( is left as plain text.
+func findShellSubstitutions(prompt string) []shellSubstitution {
+	var spans []shellSubstitution
+	for i := 0; i < len(prompt)-1; i++ {
+		if prompt[i] != '$' || prompt[i+1] != '(' {
+			continue
+		}
+		depth := 0
+		for j := i + 1; j < len(prompt); j++ {
+			switch prompt[j] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 {
+				if command := strings.TrimSpace(prompt[i+2 : j]); command != "" {
+					spans = append(spans, shellSubstitution{start: i, end: j + 1, command: command})
+				}
+				i = j
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// InterpolateShell runs each $(command) in a prompt and substitutes its
+// combined output, capped at shell_interpolation_max_bytes. It only runs
+// when shell_interpolation is enabled; otherwise the prompt is unchanged.
+func (m *Manager) InterpolateShell(terminal *ui.Terminal, prompt string) string {
+	cfg := m.state.Config
+	if !cfg.ShellInterpolation {
+		return prompt
+	}
+	spans := findShellSubstitutions(prompt)
+	if len(spans) == 0 {
+		return prompt
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		sb.WriteString(prompt[last:span.start])
+		terminal.PrintInfo(fmt.Sprintf("running: %s", span.command))
+		sb.WriteString(runInterpolatedCommand(span.command, cfg.ShellInterpolationMaxBytes))
+		last = span.end
+	}
+	sb.WriteString(prompt[last:])
+	return sb.String()
+}
+
+// runInterpolatedCommand runs command with sh and returns its stdout and
+// stderr, trimmed of trailing newlines and truncated to maxBytes when
+// maxBytes is positive. Failures are noted inline so the model sees them.
+func runInterpolatedCommand(command string, maxBytes int) string {
+	ctx, cancel := context.WithTimeout(context.Background(), shellInterpolationTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) // #nosec G204 -- Prompt interpolation runs commands the user typed, and is opt-in via config.
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+
+	result := strings.TrimRight(output.String(), "\n")
+	if maxBytes > 0 && len(result) > maxBytes {
+		result = result[:maxBytes] + fmt.Sprintf("\n[output truncated at %d bytes]", maxBytes)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		result += fmt.Sprintf("\n[command timed out after %s]", shellInterpolationTimeout)
+	} else if err != nil {
+		result += fmt.Sprintf("\n[command failed: %v]", err)
+	}
+	return strings.TrimPrefix(result, "\n")
+}
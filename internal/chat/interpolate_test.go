@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFindShellSubstitutions(t *testing.T) {
+	spans := findShellSubstitutions("a $(echo $(date)) b $() c $(ls) d $(unterminated")
+	if len(spans) != 2 {
+		t.Fatalf("expected two spans, got %+v", spans)
+	}
+	if spans[0].command != "echo $(date)" || spans[1].command != "ls" {
+		t.Errorf("unexpected commands: %+v", spans)
+	}
+}
+
+func TestInterpolateShellIsOptIn(t *testing.T) {
+	cfg := &types.Config{ShellInterpolation: false}
+	m := NewManager(&types.AppState{Config: cfg})
+	if got := m.InterpolateShell(ui.NewTerminal(cfg), "why $(echo hi)?"); got != "why $(echo hi)?" {
+		t.Errorf("disabled interpolation changed the prompt: %q", got)
+	}
+}
+
+func TestInterpolateShellSubstitutesCappedOutput(t *testing.T) {
+	cfg := &types.Config{ShellInterpolation: true, ShellInterpolationMaxBytes: 5, IsPipedOutput: true}
+	m := NewManager(&types.AppState{Config: cfg})
+	terminal := ui.NewTerminal(cfg)
+
+	if got := m.InterpolateShell(terminal, "x $(printf abc) y"); got != "x abc y" {
+		t.Errorf("InterpolateShell = %q", got)
+	}
+
+	got := m.InterpolateShell(terminal, "$(echo 1234567890)")
+	if !strings.HasPrefix(got, "12345\n[output truncated at 5 bytes]") {
+		t.Errorf("output not capped: %q", got)
+	}
+
+	got = m.InterpolateShell(terminal, "$(echo oops >&2; exit 3)")
+	if !strings.Contains(got, "oops") || !strings.Contains(got, "[command failed: exit status 3]") {
+		t.Errorf("stderr and failure should be kept: %q", got)
+	}
+}
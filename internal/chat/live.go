@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// liveFileHeader starts the message holding a live file's content, so the
+// message can be found and replaced when the file changes
+const liveFileHeader = "[live file]"
+
+// liveFile is a loaded file that is re-read before each send when it changes
+type liveFile struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// liveFileMarker is the first line of the context message for path
+func liveFileMarker(path string) string {
+	return fmt.Sprintf("%s %s\n", liveFileHeader, path)
+}
+
+// AddLiveFile loads a file into the chat and marks it live, so later sends
+// use its current content instead of the copy loaded now
+func (m *Manager) AddLiveFile(terminal *ui.Terminal, path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	for _, live := range m.liveFiles {
+		if live.path == absPath {
+			return fmt.Errorf("%s is already live", path)
+		}
+	}
+
+	content, err := terminal.LoadFileContent([]string{absPath})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("no content loaded from %s", path)
+	}
+
+	message := liveFileMarker(absPath) + content
+	m.AddUserMessage(message)
+	m.AddToHistoryWithContext(fmt.Sprintf("Live file loaded: %s", absPath), "", message)
+	m.liveFiles = append(m.liveFiles, &liveFile{path: absPath, modTime: info.ModTime(), size: info.Size()})
+	return nil
+}
+
+// LiveFiles returns the paths of the live files
+func (m *Manager) LiveFiles() []string {
+	paths := make([]string, len(m.liveFiles))
+	for i, live := range m.liveFiles {
+		paths[i] = live.path
+	}
+	return paths
+}
+
+// ClearLiveFiles stops refreshing every live file. Their last loaded content
+// stays in the conversation.
+func (m *Manager) ClearLiveFiles() {
+	m.liveFiles = nil
+}
+
+// RefreshLiveFiles re-reads live files that changed on disk since they were
+// last read and replaces their stale content in the conversation. Files that
+// were removed, or whose message is no longer in context, stop being live.
+func (m *Manager) RefreshLiveFiles(terminal *ui.Terminal) {
+	kept := m.liveFiles[:0]
+	for _, live := range m.liveFiles {
+		index := m.liveFileMessage(live.path)
+		if index < 0 {
+			continue
+		}
+
+		info, err := os.Stat(live.path)
+		if err != nil {
+			terminal.PrintError(fmt.Sprintf("live file %s is gone, keeping its last content", filepath.Base(live.path)))
+			continue
+		}
+		if info.ModTime().Equal(live.modTime) && info.Size() == live.size {
+			kept = append(kept, live)
+			continue
+		}
+
+		content, err := terminal.LoadFileContent([]string{live.path})
+		if err != nil || strings.TrimSpace(content) == "" {
+			terminal.PrintError(fmt.Sprintf("failed to re-read live file %s, keeping its last content", filepath.Base(live.path)))
+			kept = append(kept, live)
+			continue
+		}
+
+		m.state.Messages[index].Content = liveFileMarker(live.path) + content
+		live.modTime = info.ModTime()
+		live.size = info.Size()
+		kept = append(kept, live)
+		terminal.PrintInfo(fmt.Sprintf("refreshed live file %s", filepath.Base(live.path)))
+	}
+	m.liveFiles = kept
+}
+
+// liveFileMessage returns the index of the message holding path's content, or -1
+func (m *Manager) liveFileMessage(path string) int {
+	marker := liveFileMarker(path)
+	for i, msg := range m.state.Messages {
+		if msg.Role == "user" && strings.HasPrefix(msg.Content, marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isLiveFileMessage reports whether msg holds a live file's content
+func isLiveFileMessage(msg types.ChatMessage) bool {
+	return msg.Role == "user" && strings.HasPrefix(msg.Content, liveFileHeader+" ")
+}
@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRefreshLiveFilesReplacesChangedContent(t *testing.T) {
+	cfg := &types.Config{}
+	state := &types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "system", Content: "sys"}}}
+	m := NewManager(state)
+	terminal := ui.NewTerminal(cfg)
+
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddLiveFile(terminal, path); err != nil {
+		t.Fatalf("AddLiveFile() error: %v", err)
+	}
+	if err := m.AddLiveFile(terminal, path); err == nil {
+		t.Error("adding the same live file twice should fail")
+	}
+	state.Messages = append(state.Messages, types.ChatMessage{Role: "user", Content: "review it"})
+
+	// Unchanged files are left alone
+	m.RefreshLiveFiles(terminal)
+	if !strings.Contains(state.Messages[1].Content, "package old") {
+		t.Fatalf("unchanged live file was modified: %q", state.Messages[1].Content)
+	}
+
+	if err := os.WriteFile(path, []byte("package updated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	m.RefreshLiveFiles(terminal)
+	if len(state.Messages) != 3 || !strings.Contains(state.Messages[1].Content, "package updated") {
+		t.Fatalf("live file not replaced in place: %+v", state.Messages)
+	}
+
+	// A deleted file keeps its last content but stops being live
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	m.RefreshLiveFiles(terminal)
+	if len(m.LiveFiles()) != 0 || !strings.Contains(state.Messages[1].Content, "package updated") {
+		t.Errorf("deleted file should drop off with its last content kept: %v", m.LiveFiles())
+	}
+}
+
+func TestLiveFileDropsWhenMessageLeavesContext(t *testing.T) {
+	cfg := &types.Config{}
+	state := &types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "system", Content: "sys"}}}
+	m := NewManager(state)
+	terminal := ui.NewTerminal(cfg)
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("draft"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddLiveFile(terminal, path); err != nil {
+		t.Fatalf("AddLiveFile() error: %v", err)
+	}
+
+	state.Messages = state.Messages[:1]
+	m.RefreshLiveFiles(terminal)
+	if len(m.LiveFiles()) != 0 || len(state.Messages) != 1 {
+		t.Errorf("live file should drop off without re-adding content: %v %+v", m.LiveFiles(), state.Messages)
+	}
+}
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// bookmark is a !mark point in the conversation: the history position of
+// the latest exchange when it was made
+type bookmark struct {
+	position int
+	label    string
+	time     int64
+}
+
+// AddBookmark bookmarks the latest exchange under label, or under the
+// exchange's first prompt line when label is empty. It returns the label.
+func (m *Manager) AddBookmark(label string) (string, error) {
+	position := len(m.state.ChatHistory) - 1
+	if position < 1 {
+		return "", fmt.Errorf("nothing to bookmark yet")
+	}
+
+	if label == "" {
+		label = strings.Split(m.state.ChatHistory[position].User, "\n")[0]
+		if len(label) > 80 {
+			label = label[:80] + "..."
+		}
+	}
+	m.marks = append(m.marks, bookmark{position: position, label: label, time: time.Now().Unix()})
+	return label, nil
+}
+
+// trimBookmarks drops bookmarks past the end of the history, after the
+// exchanges they pointed at were backtracked or cleared
+func (m *Manager) trimBookmarks() {
+	kept := m.marks[:0]
+	for _, mark := range m.marks {
+		if mark.position < len(m.state.ChatHistory) {
+			kept = append(kept, mark)
+		}
+	}
+	m.marks = kept
+}
+
+// ShowBookmark lets the user pick a bookmark and pages the conversation
+// from that point on. The history itself is left untouched.
+func (m *Manager) ShowBookmark(terminal *ui.Terminal) error {
+	m.trimBookmarks()
+	if len(m.marks) == 0 {
+		return fmt.Errorf("no bookmarks, use %s to add one", m.state.Config.AddBookmark)
+	}
+
+	// Most recent first, like backtracking
+	items := make([]string, 0, len(m.marks))
+	for i := len(m.marks) - 1; i >= 0; i-- {
+		mark := m.marks[i]
+		items = append(items, fmt.Sprintf("%d: %s - %s", mark.position, time.Unix(mark.time, 0).Format("2006-01-02 15:04:05"), mark.label))
+	}
+
+	selected, err := terminal.FzfSelect(items, "jump to: ")
+	if err != nil {
+		return fmt.Errorf("fzf selection failed: %v", err)
+	}
+	if selected == "" {
+		return nil
+	}
+
+	position := 0
+	if _, err := fmt.Sscanf(selected, "%d:", &position); err != nil {
+		return fmt.Errorf("could not parse bookmark: %v", err)
+	}
+	return terminal.Page(FormatTranscript(m.state.ChatHistory, position))
+}
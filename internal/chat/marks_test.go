@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestBookmarksFollowHistory(t *testing.T) {
+	state := &types.AppState{Config: &types.Config{SystemPrompt: "sys"}, ChatHistory: []types.ChatHistory{{User: "sys"}}}
+	m := NewManager(state)
+
+	if _, err := m.AddBookmark(""); err == nil {
+		t.Error("bookmarking an empty conversation should fail")
+	}
+
+	state.ChatHistory = append(state.ChatHistory,
+		types.ChatHistory{User: "first question\nmore detail", Bot: "first answer"},
+		types.ChatHistory{User: "second question", Bot: "second answer"},
+	)
+	label, err := m.AddBookmark("")
+	if err != nil {
+		t.Fatalf("AddBookmark() error: %v", err)
+	}
+	if label != "second question" {
+		t.Errorf("default label = %q, want the first prompt line", label)
+	}
+	if _, err := m.AddBookmark("named"); err != nil {
+		t.Fatalf("AddBookmark() error: %v", err)
+	}
+
+	state.ChatHistory = state.ChatHistory[:2]
+	m.trimBookmarks()
+	if len(m.marks) != 0 {
+		t.Errorf("bookmarks past the history should be dropped, got %v", m.marks)
+	}
+
+	_, _ = m.AddBookmark("")
+	m.ClearHistory()
+	if len(m.marks) != 0 {
+		t.Errorf("clearing the history should drop bookmarks, got %v", m.marks)
+	}
+}
+
+func TestFormatTranscriptStartsAtPosition(t *testing.T) {
+	history := []types.ChatHistory{
+		{User: "system prompt"},
+		{User: "first question", Bot: "first answer", Platform: "openai", Model: "gpt-4.1"},
+		{User: "second question", Bot: "second answer", Model: "local"},
+	}
+
+	got := FormatTranscript(history, 2)
+	if strings.Contains(got, "first") {
+		t.Errorf("transcript from 2 should skip earlier exchanges:\n%s", got)
+	}
+	if !strings.Contains(got, "[2] ") || !strings.Contains(got, " local") || !strings.Contains(got, "second answer") {
+		t.Errorf("transcript missing the exchange header or reply:\n%s", got)
+	}
+
+	all := FormatTranscript(history, 0)
+	if strings.Contains(all, "system prompt") {
+		t.Errorf("transcript should never include the system prompt:\n%s", all)
+	}
+	if !strings.Contains(all, "openai/gpt-4.1") {
+		t.Errorf("transcript missing platform/model header:\n%s", all)
+	}
+}
@@ -0,0 +1,267 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/platform"
+)
+
+// memoryHeader starts the block of recalled memories appended to the system
+// prompt, so the previous question's block can be found and replaced
+const memoryHeader = "[memories]"
+
+// Memory is a durable fact or preference saved with !remember or the
+// remember tool. Vector is its embedding with EmbeddingModel on Platform,
+// filled in when first needed.
+type Memory struct {
+	Time           int64     `json:"time"`
+	Text           string    `json:"text"`
+	Platform       string    `json:"platform,omitempty"`
+	EmbeddingModel string    `json:"embedding_model,omitempty"`
+	Vector         []float32 `json:"vector,omitempty"`
+}
+
+// memoryFile returns where memories are stored
+func memoryFile() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "memory.json"), nil
+}
+
+// LoadMemories reads the saved memories, oldest first
+func LoadMemories() ([]Memory, error) {
+	path, err := memoryFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- The memory file is named by ch inside ~/.ch.
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var memories []Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return memories, nil
+}
+
+// saveMemories writes memories to ~/.ch/memory.json
+func saveMemories(memories []Memory) error {
+	path, err := memoryFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(memories)
+	if err != nil {
+		return fmt.Errorf("failed to encode memories: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write memories: %v", err)
+	}
+	return nil
+}
+
+// Remember saves text as a memory and returns how many are stored. The
+// text is embedded right away when the platform supports it.
+func (m *Manager) Remember(text string) (int, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return 0, fmt.Errorf("nothing to remember")
+	}
+	memories, err := LoadMemories()
+	if err != nil {
+		return 0, err
+	}
+	for _, memory := range memories {
+		if strings.EqualFold(memory.Text, text) {
+			return len(memories), fmt.Errorf("already remembered: %s", memory.Text)
+		}
+	}
+
+	memory := Memory{Time: time.Now().Unix(), Text: text}
+	if vectors, err := m.embedMemoryTexts([]string{text}); err == nil {
+		m.setMemoryVector(&memory, vectors[0])
+	}
+	memories = append(memories, memory)
+	return len(memories), saveMemories(memories)
+}
+
+// ForgetMemories deletes the memories at the given indexes of LoadMemories
+func ForgetMemories(indexes []int) error {
+	memories, err := LoadMemories()
+	if err != nil {
+		return err
+	}
+	remove := make(map[int]bool)
+	for _, i := range indexes {
+		if i < 0 || i >= len(memories) {
+			return fmt.Errorf("no memory #%d", i+1)
+		}
+		remove[i] = true
+	}
+	kept := make([]Memory, 0, len(memories))
+	for i, memory := range memories {
+		if !remove[i] {
+			kept = append(kept, memory)
+		}
+	}
+	return saveMemories(kept)
+}
+
+// MemoryTool lets the model save a fact with Remember. Like every tool call,
+// the user confirms it first.
+func (m *Manager) MemoryTool() platform.Tool {
+	return platform.Tool{
+		Name:        "remember",
+		Description: "Save a durable fact or preference about the user to long-term memory, recalled in future sessions. Only use it for things worth knowing later.",
+		Parameters:  stringParameters("fact", "The fact to remember, as one short sentence"),
+		Run: func(args map[string]any) (string, error) {
+			fact, err := stringArg(args, "fact")
+			if err != nil {
+				return "", err
+			}
+			if _, err := m.Remember(fact); err != nil {
+				return "", err
+			}
+			return "saved to memory", nil
+		},
+	}
+}
+
+// AddMemoryContext appends the memories most relevant to the latest user
+// message to the system prompt, replacing those recalled for the previous
+// question. It does nothing unless the memory option is on.
+func (m *Manager) AddMemoryContext() {
+	if len(m.state.Messages) == 0 || m.state.Messages[0].Role != "system" {
+		return
+	}
+	system := &m.state.Messages[0]
+	if i := strings.Index(system.Content, memoryHeader); i >= 0 {
+		system.Content = strings.TrimRight(system.Content[:i], "\n")
+	}
+
+	last := len(m.state.Messages) - 1
+	if !m.state.Config.Memory || m.state.Messages[last].Role != "user" {
+		return
+	}
+	memories, err := LoadMemories()
+	if err != nil || len(memories) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(memoryHeader + " Facts and preferences the user asked you to remember across sessions:\n")
+	for _, i := range m.recallMemories(memories, m.state.Messages[last].Content, m.state.Config.MemoryTopK) {
+		sb.WriteString("- " + memories[i].Text + "\n")
+	}
+	if system.Content != "" {
+		system.Content += "\n\n"
+	}
+	system.Content += strings.TrimRight(sb.String(), "\n")
+}
+
+// recallMemories returns the indexes of the topK memories most relevant to
+// question, oldest first. All memories are used when there are no more than
+// topK; otherwise they are ranked by embedding similarity, or by keywords
+// when the platform cannot embed. Memories without a vector for the current
+// embedding model are embedded along with the question and saved.
+func (m *Manager) recallMemories(memories []Memory, question string, topK int) []int {
+	if topK <= 0 {
+		topK = 1
+	}
+	if len(memories) <= topK {
+		all := make([]int, len(memories))
+		for i := range memories {
+			all[i] = i
+		}
+		return all
+	}
+
+	var missing []int
+	var texts []string
+	for i, memory := range memories {
+		if !m.memoryVectorCurrent(memory) {
+			missing = append(missing, i)
+			texts = append(texts, memory.Text)
+		}
+	}
+	vectors, err := m.embedMemoryTexts(append(texts, truncateForEmbedding(question)))
+	if err != nil {
+		texts = make([]string, len(memories))
+		for i, memory := range memories {
+			texts[i] = memory.Text
+		}
+		return topScoring(keywordScores(question, texts), topK)
+	}
+	if len(missing) > 0 {
+		for k, i := range missing {
+			m.setMemoryVector(&memories[i], vectors[k])
+		}
+		_ = saveMemories(memories)
+	}
+
+	questionVector := vectors[len(vectors)-1]
+	scores := make([]float64, len(memories))
+	for i, memory := range memories {
+		scores[i] = cosineSimilarity(questionVector, memory.Vector)
+	}
+	return topScoring(scores, topK)
+}
+
+// embedMemoryTexts embeds texts with embedding_model on the current platform
+func (m *Manager) embedMemoryTexts(texts []string) ([][]float32, error) {
+	if m.platformManager == nil {
+		return nil, fmt.Errorf("no platform")
+	}
+	vectors, err := m.platformManager.CreateEmbeddings(texts, platform.EmbeddingOptions{Model: m.state.Config.EmbeddingModel})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// memoryVectorCurrent reports whether memory was embedded with the current
+// platform and embedding model
+func (m *Manager) memoryVectorCurrent(memory Memory) bool {
+	cfg := m.state.Config
+	return memory.Vector != nil && memory.Platform == cfg.CurrentPlatform && memory.EmbeddingModel == cfg.EmbeddingModel
+}
+
+// setMemoryVector records vector as memory's embedding on the current platform
+func (m *Manager) setMemoryVector(memory *Memory, vector []float32) {
+	memory.Platform = m.state.Config.CurrentPlatform
+	memory.EmbeddingModel = m.state.Config.EmbeddingModel
+	memory.Vector = vector
+}
+
+// FormatMemory labels a memory for lists and pickers, numbered from 1
+func FormatMemory(i int, memory Memory) string {
+	return fmt.Sprintf("%d. %s %s", i+1, time.Unix(memory.Time, 0).Format("2006-01-02"), memory.Text)
+}
+
+// MemoryIndexes turns FormatMemory labels picked in fzf back into indexes
+func MemoryIndexes(labels []string) []int {
+	var indexes []int
+	for _, label := range labels {
+		var n int
+		if _, err := fmt.Sscanf(label, "%d.", &n); err == nil && n > 0 {
+			indexes = append(indexes, n-1)
+		}
+	}
+	return indexes
+}
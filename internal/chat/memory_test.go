@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRememberAndForget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager(&types.AppState{Config: &types.Config{}})
+
+	for _, text := range []string{"I use Go 1.22 and  zsh", "prefer tabs", "deploys go through staging"} {
+		if _, err := m.Remember(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Remember("PREFER TABS"); err == nil || !strings.Contains(err.Error(), "already remembered") {
+		t.Errorf("Remember() duplicate error = %v", err)
+	}
+	if _, err := m.Remember("  "); err == nil {
+		t.Error("Remember() empty text succeeded")
+	}
+
+	memories, err := LoadMemories()
+	if err != nil || len(memories) != 3 || memories[0].Text != "I use Go 1.22 and zsh" || memories[0].Vector != nil {
+		t.Fatalf("LoadMemories() = %+v, %v", memories, err)
+	}
+
+	if got := MemoryIndexes([]string{FormatMemory(2, memories[2]), FormatMemory(0, memories[0]), "junk"}); !reflect.DeepEqual(got, []int{2, 0}) {
+		t.Errorf("MemoryIndexes() = %v", got)
+	}
+	if err := ForgetMemories([]int{0, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if memories, _ = LoadMemories(); len(memories) != 1 || memories[0].Text != "prefer tabs" {
+		t.Errorf("after ForgetMemories() = %+v", memories)
+	}
+	if err := ForgetMemories([]int{5}); err == nil {
+		t.Error("ForgetMemories() out of range succeeded")
+	}
+}
+
+func TestAddMemoryContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := &types.Config{MemoryTopK: 1}
+	m := NewManager(&types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "system", Content: "be brief"}}})
+	m.Remember("the staging database is postgres 16")
+	m.Remember("I use zsh")
+	m.AddUserMessage("which database do we run?")
+
+	m.AddMemoryContext()
+	if m.state.Messages[0].Content != "be brief" {
+		t.Fatalf("memory off changed the system prompt: %q", m.state.Messages[0].Content)
+	}
+
+	// Without embeddings the closest memory is picked by keywords
+	cfg.Memory = true
+	m.AddMemoryContext()
+	if got := m.state.Messages[0].Content; !strings.HasPrefix(got, "be brief\n\n"+memoryHeader) || !strings.Contains(got, "- the staging database is postgres 16") || strings.Contains(got, "zsh") {
+		t.Errorf("system prompt = %q", got)
+	}
+
+	// The previous question's memories are replaced, not added to
+	m.AddAssistantMessage("postgres")
+	m.AddUserMessage("what shell is configured with zsh?")
+	m.AddMemoryContext()
+	if got := m.state.Messages[0].Content; strings.Count(got, memoryHeader) != 1 || !strings.Contains(got, "- I use zsh") || strings.Contains(got, "postgres") {
+		t.Errorf("system prompt after second question = %q", got)
+	}
+}
+
+func TestRecallMemoriesWithEmbeddings(t *testing.T) {
+	m, _, embedded := newIndexTestManager(t)
+	cfg := m.state.Config
+	cfg.Memory, cfg.MemoryTopK = true, 1
+	m.state.Messages = []types.ChatMessage{{Role: "system", Content: ""}}
+
+	if _, err := m.Remember("retry webhooks with backoff"); err != nil {
+		t.Fatal(err)
+	}
+	memories, _ := LoadMemories()
+	if len(memories[0].Vector) != 3 || memories[0].Platform != "fake" || memories[0].EmbeddingModel != "embed-small" {
+		t.Fatalf("Remember() did not embed: %+v", memories[0])
+	}
+
+	// A memory embedded with another model is embedded again with the question
+	memories = append(memories, Memory{Text: "the database runs nightly vacuum", Platform: "fake", EmbeddingModel: "old", Vector: []float32{1, 0, 0}})
+	saveMemories(memories)
+	*embedded = 0
+	m.AddUserMessage("tune the database")
+	m.AddMemoryContext()
+	if got := m.state.Messages[0].Content; !strings.Contains(got, "nightly vacuum") || strings.Contains(got, "webhooks") {
+		t.Errorf("system prompt = %q", got)
+	}
+	if *embedded != 2 {
+		t.Errorf("embedded %d inputs, want the stale memory and the question", *embedded)
+	}
+	if memories, _ = LoadMemories(); memories[1].EmbeddingModel != "embed-small" || memories[1].Vector[1] != 1 {
+		t.Errorf("stale memory was not re-embedded: %+v", memories[1])
+	}
+}
@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// mentionPattern matches @path tokens at the start of the prompt or after whitespace
+var mentionPattern = regexp.MustCompile(`(^|\s)@(\S+)`)
+
+// confirmLargeMention asks before loading a large @dir or @glob expansion;
+// tests replace it
+var confirmLargeMention = func(terminal *ui.Terminal, question string) bool {
+	return terminal.Confirm(question)
+}
+
+// mention is one @path token in a prompt that names a file, a directory, or a glob
+type mention struct {
+	token string // the text to replace, including the @
+	path  string // the path as written, without the @
+	file  string // the resolved path on disk (with ~/ expanded)
+	multi bool   // a directory or glob that expands to several files
+}
+
+// findMentions returns the @path tokens in text that name existing files or
+// directories, or are globs, in order and without duplicates. Trailing
+// punctuation is dropped when the path only exists without it, so
+// "see @main.go." still matches. Tokens that name nothing on disk
+// (handles, decorators) are left alone.
+func findMentions(text string) []mention {
+	var mentions []mention
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		written := match[2]
+		for written != "" {
+			if resolved, multi, ok := resolveMention(written); ok {
+				if !seen[written] {
+					seen[written] = true
+					mentions = append(mentions, mention{token: "@" + written, path: written, file: resolved, multi: multi})
+				}
+				break
+			}
+			trimmed := strings.TrimRight(written, ".,;:!?)]}'\"")
+			if trimmed == written {
+				break
+			}
+			written = trimmed
+		}
+	}
+	return mentions
+}
+
+// resolveMention expands ~/ and reports whether the path is an existing file,
+// an existing directory, or a glob whose base directory exists
+func resolveMention(path string) (string, bool, bool) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false, false
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	if ui.IsGlobMention(path) {
+		if strings.ContainsAny(path[len(path)-1:], ".,;:!?)}'\"") {
+			return "", false, false // let the caller trim trailing punctuation first
+		}
+		return path, true, true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false, false
+	}
+	if info.IsDir() {
+		return path, true, true
+	}
+	if !info.Mode().IsRegular() {
+		return "", false, false
+	}
+	return path, false, true
+}
+
+// ExpandMentions loads the files named by @path tokens in a prompt with the
+// regular file loaders, adds their content to the conversation as context,
+// and returns the prompt with each mention replaced by a plain reference.
+// Directory and glob mentions skip .gitignore matches and ask for
+// confirmation when they expand past mention_confirm_files or
+// mention_confirm_bytes. Prompts without mentions are returned unchanged.
+func (m *Manager) ExpandMentions(terminal *ui.Terminal, prompt string) string {
+	mentions := findMentions(prompt)
+	if len(mentions) == 0 {
+		return prompt
+	}
+
+	var loaded []string
+	var content strings.Builder
+	included := make(map[string]bool)
+	for _, mention := range mentions {
+		files := []string{mention.file}
+		if mention.multi {
+			var err error
+			files, err = terminal.MentionFiles(mention.file)
+			if err != nil || len(files) == 0 {
+				terminal.PrintInfo(fmt.Sprintf("no files match %s", mention.token))
+				continue
+			}
+			if !m.confirmMentionSize(terminal, mention.token, files) {
+				terminal.PrintInfo(fmt.Sprintf("skipped %s", mention.token))
+				continue
+			}
+		}
+
+		var fresh []string
+		for _, file := range files {
+			if !included[file] {
+				included[file] = true
+				fresh = append(fresh, file)
+			}
+		}
+
+		fileContent, err := terminal.LoadFileContent(fresh)
+		if err != nil || (len(fresh) > 0 && strings.TrimSpace(fileContent) == "") {
+			terminal.PrintError(fmt.Sprintf("failed to load %s", mention.path))
+			continue
+		}
+		content.WriteString(fileContent)
+		loaded = append(loaded, mention.path)
+		prompt = replaceMention(prompt, mention.token, fmt.Sprintf("`%s`", mention.path))
+	}
+
+	if content.Len() > 0 {
+		m.AddUserMessage(content.String())
+		m.AddToHistoryWithContext(fmt.Sprintf("Mentioned: %s", strings.Join(loaded, ", ")), "", content.String())
+	}
+	return prompt
+}
+
+// confirmMentionSize asks before loading a directory or glob expansion that
+// is over the configured file count or size
+func (m *Manager) confirmMentionSize(terminal *ui.Terminal, token string, files []string) bool {
+	var size int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size += info.Size()
+		}
+	}
+
+	cfg := m.state.Config
+	tooMany := cfg.MentionConfirmFiles > 0 && len(files) > cfg.MentionConfirmFiles
+	tooBig := cfg.MentionConfirmBytes > 0 && size > int64(cfg.MentionConfirmBytes)
+	if !tooMany && !tooBig {
+		return true
+	}
+	return confirmLargeMention(terminal, fmt.Sprintf("%s matches %d files (%d KB), load them all?", token, len(files), (size+1023)/1024))
+}
+
+// replaceMention replaces token where it stands as a whole mention, so @a.go
+// does not also rewrite part of @a.go.bak
+func replaceMention(prompt, token, reference string) string {
+	pattern := regexp.MustCompile(`(^|\s)` + regexp.QuoteMeta(token) + `([.,;:!?)\]}'"]*(\s|$))`)
+	return pattern.ReplaceAllString(prompt, "${1}"+strings.ReplaceAll(reference, "$", "$$")+"${2}")
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFindMentionsOnlyMatchesExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("src", 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	mentions := findMentions("explain @main.go, ping @alice, see user@main.go and @src and @main.go again, ok?")
+	if len(mentions) != 2 {
+		t.Fatalf("expected the file and directory mentions, got %+v", mentions)
+	}
+	if mentions[0].token != "@main.go" || mentions[0].file != "main.go" || mentions[0].multi {
+		t.Errorf("unexpected file mention: %+v", mentions[0])
+	}
+	if mentions[1].token != "@src" || !mentions[1].multi {
+		t.Errorf("unexpected directory mention: %+v", mentions[1])
+	}
+
+	if globs := findMentions("check @src/**/*.go. and @everyone?"); len(globs) != 1 || globs[0].path != "src/**/*.go" || !globs[0].multi {
+		t.Errorf("unexpected glob mentions: %+v", globs)
+	}
+}
+
+func TestExpandMentionsAddsContextAndReference(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, []byte("remember the milk"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &types.Config{}
+	state := &types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "system", Content: "sys"}}}
+	m := NewManager(state)
+
+	prompt := m.ExpandMentions(ui.NewTerminal(cfg), "summarize @"+path+".")
+	if prompt != "summarize `"+path+"`." {
+		t.Errorf("mention not replaced with a reference: %q", prompt)
+	}
+	if len(state.Messages) != 2 || !strings.Contains(state.Messages[1].Content, "remember the milk") {
+		t.Fatalf("file content not added as context: %+v", state.Messages)
+	}
+	if last := state.ChatHistory[len(state.ChatHistory)-1]; last.User != "Mentioned: "+path || last.Context == "" {
+		t.Errorf("unexpected history entry: %+v", last)
+	}
+
+	if got := m.ExpandMentions(ui.NewTerminal(cfg), "no mentions @here"); got != "no mentions @here" || len(state.Messages) != 2 {
+		t.Errorf("prompt without file mentions should be unchanged, got %q", got)
+	}
+}
+
+func TestReplaceMentionMatchesWholeTokens(t *testing.T) {
+	got := replaceMention("diff @a.go and @a.go.bak (@a.go)", "@a.go", "`a.go`")
+	if got != "diff `a.go` and @a.go.bak (@a.go)" {
+		t.Errorf("replaceMention = %q", got)
+	}
+}
+
+func TestExpandMentionsConfirmsLargeExpansions(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		if err := os.WriteFile(name, []byte("package x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var asked []string
+	answer := false
+	previous := confirmLargeMention
+	confirmLargeMention = func(_ *ui.Terminal, question string) bool {
+		asked = append(asked, question)
+		return answer
+	}
+	t.Cleanup(func() { confirmLargeMention = previous })
+
+	cfg := &types.Config{MentionConfirmFiles: 2}
+	state := &types.AppState{Config: cfg, Messages: []types.ChatMessage{{Role: "system", Content: "sys"}}}
+	m := NewManager(state)
+
+	if got := m.ExpandMentions(ui.NewTerminal(cfg), "review @*.go"); got != "review @*.go" || len(state.Messages) != 1 {
+		t.Errorf("declined expansion should leave the prompt alone, got %q", got)
+	}
+	if len(asked) != 1 || !strings.Contains(asked[0], "3 files") {
+		t.Fatalf("expected one confirmation for 3 files, got %q", asked)
+	}
+
+	answer = true
+	if got := m.ExpandMentions(ui.NewTerminal(cfg), "review @*.go"); got != "review `*.go`" {
+		t.Errorf("confirmed expansion not referenced: %q", got)
+	}
+	if len(state.Messages) != 2 || strings.Count(state.Messages[1].Content, "File: ") != 3 {
+		t.Errorf("expected all three files as context: %+v", state.Messages)
+	}
+
+	cfg.MentionConfirmFiles = -1
+	asked = nil
+	m.ExpandMentions(ui.NewTerminal(cfg), "again @*.go")
+	if len(asked) != 0 {
+		t.Errorf("negative threshold should never ask, asked %q", asked)
+	}
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// AddNote attaches a note to the session. Notes are saved with the session
+// and shown when browsing sessions, but never sent to the model.
+func (m *Manager) AddNote(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("note is empty")
+	}
+	m.state.SessionNotes = append(m.state.SessionNotes, types.SessionNote{Time: time.Now().Unix(), Text: text})
+	return nil
+}
+
+// ClearNotes removes every note from the session
+func (m *Manager) ClearNotes() {
+	m.state.SessionNotes = nil
+}
+
+// GetNotes returns the notes of the current session
+func (m *Manager) GetNotes() []types.SessionNote {
+	return m.state.SessionNotes
+}
+
+// appendNotes adds notes to an export in format: a NOTES section for text
+// and Markdown, and for JSON an object holding the entries and the notes
+func appendNotes(content string, notes []types.SessionNote, format string) (string, error) {
+	if len(notes) == 0 {
+		return content, nil
+	}
+
+	var b strings.Builder
+	switch format {
+	case ExportJSON:
+		data, err := json.MarshalIndent(struct {
+			Entries json.RawMessage     `json:"entries"`
+			Notes   []types.SessionNote `json:"notes"`
+		}{json.RawMessage(content), notes}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+		return string(data) + "\n", nil
+	case ExportMarkdown:
+		b.WriteString(content + "## Notes\n\n")
+	default:
+		b.WriteString(content + "\n\n" + strings.Repeat("=", 50) + "\n\nNOTES:\n")
+	}
+	for _, note := range notes {
+		fmt.Fprintf(&b, "- %s %s\n", time.Unix(note.Time, 0).Format("2006-01-02 15:04"), note.Text)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestExportWithNotes(t *testing.T) {
+	state := &types.AppState{
+		Config:      &types.Config{},
+		ChatHistory: []types.ChatHistory{{User: "sys"}, {User: "hi", Bot: "hello", Time: 1}},
+	}
+	m := NewManager(state)
+	if err := m.AddNote("  "); err == nil {
+		t.Error("an empty note should be an error")
+	}
+	if err := m.AddNote("TODO: retry on 429"); err != nil {
+		t.Fatalf("AddNote() error: %v", err)
+	}
+
+	plain, err := m.ExportFiltered(ExportFilter{}, ExportText)
+	if err != nil || strings.Contains(plain, "TODO") {
+		t.Errorf("export without --notes = %q, %v; want no notes", plain, err)
+	}
+	text, err := m.ExportFiltered(ExportFilter{Notes: true}, ExportText)
+	if err != nil || !strings.Contains(text, "NOTES:\n- ") || !strings.HasSuffix(text, " TODO: retry on 429\n") {
+		t.Errorf("text export with notes = %q, %v", text, err)
+	}
+	markdown, err := m.ExportFiltered(ExportFilter{Notes: true}, ExportMarkdown)
+	if err != nil || !strings.Contains(markdown, "## Notes\n\n- ") {
+		t.Errorf("markdown export with notes = %q, %v", markdown, err)
+	}
+
+	data, err := m.ExportFiltered(ExportFilter{Notes: true}, ExportJSON)
+	var exported struct {
+		Entries []types.ExportEntry `json:"entries"`
+		Notes   []types.SessionNote `json:"notes"`
+	}
+	if err != nil || json.Unmarshal([]byte(data), &exported) != nil || len(exported.Entries) != 1 || exported.Notes[0].Text != "TODO: retry on 429" {
+		t.Errorf("json export with notes = %q, %v", data, err)
+	}
+
+	m.ClearNotes()
+	if len(m.GetNotes()) != 0 {
+		t.Error("ClearNotes() left notes behind")
+	}
+}
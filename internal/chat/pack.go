@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// packEmbedChars caps the text of a context unit sent for embedding
+const packEmbedChars = 16000
+
+// packRelevanceWeight is how much relevance to the question counts against
+// recency when ranking earlier messages for a packed request
+const packRelevanceWeight = 0.6
+
+// packUnit is a group of earlier messages kept or left out together: a
+// prompt with its answer, or a loaded context message
+type packUnit struct {
+	indexes []int
+	text    string
+	tokens  int
+}
+
+// requestMessages returns the messages to send for the pending question.
+// Without context_budget that is the whole conversation. With it, the
+// system prompts, live files, and pending question are always sent, and
+// earlier messages are added by recency and relevance to the question
+// until the budget is used.
+func (m *Manager) requestMessages(terminal *ui.Terminal) []types.ChatMessage {
+	messages := m.state.Messages
+	budget := m.state.Config.ContextBudget
+	if budget <= 0 {
+		return messages
+	}
+
+	tok := tokens.For(m.state.Config.CurrentModel)
+	start, end := pendingBounds(messages)
+	units := packUnits(messages, start, end, tok)
+	pinned, candidates := 0, 0
+	for _, msg := range messages {
+		pinned += tok.Count(msg.Content)
+	}
+	for _, unit := range units {
+		pinned -= unit.tokens
+		candidates += unit.tokens
+	}
+	if pinned+candidates <= budget {
+		return messages
+	}
+
+	var questionParts []string
+	for _, msg := range messages[end:] {
+		questionParts = append(questionParts, msg.Content)
+	}
+	scores := m.packScores(terminal, units, strings.Join(questionParts, "\n"))
+	order := make([]int, len(units))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	kept := make(map[int]bool)
+	used, keptUnits := pinned, 0
+	for _, u := range order {
+		if used+units[u].tokens > budget {
+			continue
+		}
+		used += units[u].tokens
+		keptUnits++
+		for _, i := range units[u].indexes {
+			kept[i] = true
+		}
+	}
+
+	packed := make([]types.ChatMessage, 0, len(messages))
+	for i, msg := range messages {
+		if i < start || i >= end || isLiveFileMessage(msg) || kept[i] {
+			packed = append(packed, msg)
+		}
+	}
+	terminal.PrintInfo(fmt.Sprintf("context budget: sending %d of %d earlier exchanges and loads (~%d of %d tokens)", keptUnits, len(units), used, budget))
+	return packed
+}
+
+// packUnits groups the messages in [start, end) into pack units, leaving
+// out live files, which are always sent
+func packUnits(messages []types.ChatMessage, start, end int, tok tokens.Tokenizer) []packUnit {
+	var units []packUnit
+	for i := start; i < end; i++ {
+		if isLiveFileMessage(messages[i]) {
+			continue
+		}
+		unit := packUnit{indexes: []int{i}, text: messages[i].Content}
+		if messages[i].Role == "user" && i+1 < end && messages[i+1].Role == "assistant" {
+			i++
+			unit.indexes = append(unit.indexes, i)
+			unit.text += "\n\n" + messages[i].Content
+		}
+		for _, index := range unit.indexes {
+			unit.tokens += tok.Count(messages[index].Content)
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// packScores ranks units, oldest first, by relevance to question and by
+// recency, both scaled to 0..1
+func (m *Manager) packScores(terminal *ui.Terminal, units []packUnit, question string) []float64 {
+	relevance := m.packRelevance(terminal, units, question)
+	highest := 0.0
+	for _, score := range relevance {
+		highest = max(highest, score)
+	}
+
+	scores := make([]float64, len(units))
+	for k := range units {
+		if highest > 0 {
+			scores[k] = packRelevanceWeight * relevance[k] / highest
+		}
+		scores[k] += (1 - packRelevanceWeight) * float64(k+1) / float64(len(units))
+	}
+	return scores
+}
+
+// packRelevance scores units by embedding similarity to question, caching
+// unit vectors for later requests, or by keywords when embeddings are not
+// available
+func (m *Manager) packRelevance(terminal *ui.Terminal, units []packUnit, question string) []float64 {
+	texts := make([]string, len(units))
+	for k, unit := range units {
+		texts[k] = unit.text
+	}
+	cfg := m.state.Config
+	if m.packKeywordsOnly || m.platformManager == nil || cfg.EmbeddingModel == "" {
+		return keywordScores(question, texts)
+	}
+
+	if m.packVectors == nil {
+		m.packVectors = make(map[[32]byte][]float32)
+	}
+	inputs := []string{truncateForEmbedding(question)}
+	var missing [][32]byte
+	for _, text := range texts {
+		key := sha256.Sum256([]byte(text))
+		if _, ok := m.packVectors[key]; !ok && !slices.Contains(missing, key) {
+			missing = append(missing, key)
+			inputs = append(inputs, truncateForEmbedding(text))
+		}
+	}
+
+	vectors, err := m.platformManager.CreateEmbeddings(inputs, platform.EmbeddingOptions{
+		Model:             cfg.EmbeddingModel,
+		BatchSize:         cfg.EmbeddingBatchSize,
+		RequestsPerMinute: cfg.EmbeddingRPM,
+	})
+	if err != nil || len(vectors) != len(inputs) {
+		if err != nil && !errors.Is(err, platform.ErrDryRun) {
+			m.packKeywordsOnly = true
+			terminal.PrintInfo(fmt.Sprintf("embeddings unavailable, packing context by keywords: %v", err))
+		}
+		return keywordScores(question, texts)
+	}
+	for k, key := range missing {
+		m.packVectors[key] = vectors[k+1]
+	}
+
+	scores := make([]float64, len(texts))
+	for k, text := range texts {
+		scores[k] = max(0, cosineSimilarity(vectors[0], m.packVectors[sha256.Sum256([]byte(text))]))
+	}
+	return scores
+}
+
+// truncateForEmbedding keeps text within packEmbedChars, cut at a rune boundary
+func truncateForEmbedding(text string) string {
+	if len(text) <= packEmbedChars {
+		return text
+	}
+	return strings.ToValidUTF8(text[:packEmbedChars], "")
+}
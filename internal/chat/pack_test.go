@@ -0,0 +1,48 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRequestMessagesPacksByRelevanceAndRecency(t *testing.T) {
+	messages := []types.ChatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "How do I configure the postgres connection pool?"},
+		{Role: "assistant", Content: "Set max_connections in the postgres pool settings."},
+		{Role: "user", Content: "What is a good name for a cat?"},
+		{Role: "assistant", Content: "Try Miso or Pepper for a cat."},
+		{Role: "user", Content: "Which bread goes with soup?"},
+		{Role: "assistant", Content: "Sourdough bread goes well with most soup."},
+		{Role: "user", Content: "And the postgres pool timeout?"},
+	}
+	cfg := &types.Config{CurrentModel: "gpt-4o", IsPipedOutput: true}
+	m := NewManager(&types.AppState{Config: cfg, Messages: messages})
+	terminal := ui.NewTerminal(cfg)
+
+	if got := m.requestMessages(terminal); !reflect.DeepEqual(got, messages) {
+		t.Errorf("without context_budget, requestMessages() = %+v, want every message", got)
+	}
+
+	// Room for the pinned messages and two of the three earlier exchanges
+	tok := tokens.For(cfg.CurrentModel)
+	for _, i := range []int{0, 1, 2, 5, 6, 7} {
+		cfg.ContextBudget += tok.Count(messages[i].Content)
+	}
+	want := []types.ChatMessage{messages[0], messages[1], messages[2], messages[5], messages[6], messages[7]}
+	if got := m.requestMessages(terminal); !reflect.DeepEqual(got, want) {
+		t.Errorf("requestMessages() = %+v, want the relevant first exchange and the latest one", got)
+	}
+	if len(m.state.Messages) != len(messages) {
+		t.Error("requestMessages() changed the conversation")
+	}
+
+	cfg.ContextBudget = 1
+	if got := m.requestMessages(terminal); !reflect.DeepEqual(got, []types.ChatMessage{messages[0], messages[7]}) {
+		t.Errorf("with a tiny budget, requestMessages() = %+v, want only the pinned messages", got)
+	}
+}
@@ -0,0 +1,86 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// DefaultRedactionReplacement is used when a redaction rule has no replacement
+const DefaultRedactionReplacement = "[REDACTED]"
+
+// redactionRegexPrefix marks a redaction rule pattern as a regular expression
+const redactionRegexPrefix = "re:"
+
+// ParseRedactionRule parses "find => replace" into a redaction rule. A "re:"
+// prefix makes find a regular expression, and a missing replacement becomes
+// DefaultRedactionReplacement.
+func ParseRedactionRule(arg string) (types.Redaction, error) {
+	find, replace, hasReplace := strings.Cut(arg, "=>")
+	find = strings.TrimSpace(find)
+	replace = strings.TrimSpace(replace)
+	if !hasReplace || replace == "" {
+		replace = DefaultRedactionReplacement
+	}
+
+	rule := types.Redaction{Find: find, Replace: replace}
+	if strings.HasPrefix(find, redactionRegexPrefix) {
+		rule.Find = strings.TrimPrefix(find, redactionRegexPrefix)
+		rule.Regex = true
+		if _, err := regexp.Compile(rule.Find); err != nil {
+			return rule, fmt.Errorf("invalid redaction pattern: %v", err)
+		}
+	}
+	if rule.Find == "" {
+		return rule, fmt.Errorf("redaction rule needs text to find")
+	}
+	return rule, nil
+}
+
+// FormatRedactionRule renders a rule in the form accepted by ParseRedactionRule
+func FormatRedactionRule(rule types.Redaction) string {
+	find := rule.Find
+	if rule.Regex {
+		find = redactionRegexPrefix + find
+	}
+	return fmt.Sprintf("%s => %s", find, rule.Replace)
+}
+
+// ApplyRedactions applies each rule in order. Rules with invalid patterns are skipped.
+func ApplyRedactions(text string, rules []types.Redaction) string {
+	for _, rule := range rules {
+		if rule.Find == "" {
+			continue
+		}
+		if !rule.Regex {
+			text = strings.ReplaceAll(text, rule.Find, rule.Replace)
+			continue
+		}
+		re, err := regexp.Compile(rule.Find)
+		if err != nil {
+			continue
+		}
+		text = re.ReplaceAllString(text, rule.Replace)
+	}
+	return text
+}
+
+// RedactSession applies rules to every prompt, response, and loaded context in a session
+func RedactSession(session *types.SessionFile, rules []types.Redaction) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range session.ChatHistory {
+		entry := &session.ChatHistory[i]
+		entry.User = ApplyRedactions(entry.User, rules)
+		entry.Bot = ApplyRedactions(entry.Bot, rules)
+		entry.Context = ApplyRedactions(entry.Context, rules)
+	}
+}
+
+// redact applies the configured export redactions to content about to be written
+func (m *Manager) redact(text string) string {
+	return ApplyRedactions(text, m.state.Config.Redactions)
+}
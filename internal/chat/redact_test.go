@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestParseRedactionRule(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want types.Redaction
+	}{
+		{"db01.corp.local => db-host", types.Redaction{Find: "db01.corp.local", Replace: "db-host"}},
+		{"secret-token", types.Redaction{Find: "secret-token", Replace: DefaultRedactionReplacement}},
+		{`re:10\.\d+\.\d+\.\d+ => <ip>`, types.Redaction{Find: `10\.\d+\.\d+\.\d+`, Replace: "<ip>", Regex: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRedactionRule(tt.arg)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRedactionRule(%q) = %+v, %v; want %+v", tt.arg, got, err, tt.want)
+		}
+		if round, err := ParseRedactionRule(FormatRedactionRule(got)); err != nil || round != got {
+			t.Errorf("round trip of %q gave %+v, %v", tt.arg, round, err)
+		}
+	}
+
+	for _, bad := range []string{"", " => x", "re:( => x"} {
+		if _, err := ParseRedactionRule(bad); err == nil {
+			t.Errorf("ParseRedactionRule(%q) should fail", bad)
+		}
+	}
+}
+
+func TestApplyRedactions(t *testing.T) {
+	rules := []types.Redaction{
+		{Find: "db01.corp.local", Replace: "db-host"},
+		{Find: `10\.\d+\.\d+\.\d+`, Replace: "<ip>", Regex: true},
+		{Find: "(", Replace: "skipped", Regex: true},
+	}
+	got := ApplyRedactions("ssh db01.corp.local (10.1.2.3)", rules)
+	if got != "ssh db-host (<ip>)" {
+		t.Errorf("ApplyRedactions = %q", got)
+	}
+}
+
+func TestRedactSession(t *testing.T) {
+	session := &types.SessionFile{ChatHistory: []types.ChatHistory{
+		{User: "system"},
+		{User: "why is acme.internal down?", Bot: "acme.internal is unreachable", Context: "log: acme.internal timeout"},
+	}}
+	RedactSession(session, []types.Redaction{{Find: "acme.internal", Replace: "example.com"}})
+
+	entry := session.ChatHistory[1]
+	if entry.User != "why is example.com down?" || entry.Bot != "example.com is unreachable" || entry.Context != "log: example.com timeout" {
+		t.Errorf("session not fully redacted: %+v", entry)
+	}
+}
@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reloadNotePrefix starts the line under the File: header of a reloaded
+// file sent as a diff or an unchanged note, so later reloads skip it and
+// diff against a full copy
+const reloadNotePrefix = "(reloaded"
+
+// reloadDiffMaxCells bounds the line comparison table of a reload diff;
+// files with more changed lines than that are sent in full
+const reloadDiffMaxCells = 1_000_000
+
+// diffContext is the number of unchanged lines around each diff hunk
+const diffContext = 3
+
+// fileBlock is one "File: path" section of loaded content. The text before
+// the first header is a block with an empty header.
+type fileBlock struct {
+	header string
+	lines  []string
+}
+
+// splitFileBlocks splits loaded content at "File: " lines that start the
+// content or follow a blank line, the way !l separates files
+func splitFileBlocks(content string) []fileBlock {
+	lines := strings.Split(content, "\n")
+	blocks := []fileBlock{{}}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "File: ") && (i == 0 || lines[i-1] == "") {
+			blocks = append(blocks, fileBlock{header: line})
+			continue
+		}
+		last := &blocks[len(blocks)-1]
+		last.lines = append(last.lines, line)
+	}
+	if len(blocks[0].lines) == 0 {
+		blocks = blocks[1:]
+	}
+	return blocks
+}
+
+// joinFileBlocks is the inverse of splitFileBlocks
+func joinFileBlocks(blocks []fileBlock) string {
+	var lines []string
+	for _, block := range blocks {
+		if block.header != "" {
+			lines = append(lines, block.header)
+		}
+		lines = append(lines, block.lines...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// body returns the block's contents without trailing blank lines
+func (b fileBlock) body() string {
+	return strings.TrimRight(strings.Join(b.lines, "\n"), "\n")
+}
+
+// previousFileBody returns the most recent full copy of the file with
+// header still in the conversation
+func (m *Manager) previousFileBody(header string) (string, bool) {
+	for i := len(m.state.Messages) - 1; i >= 0; i-- {
+		message := m.state.Messages[i]
+		if message.Role != "user" || !strings.Contains(message.Content, header) {
+			continue
+		}
+		blocks := splitFileBlocks(message.Content)
+		for j := len(blocks) - 1; j >= 0; j-- {
+			if blocks[j].header == header && !strings.HasPrefix(blocks[j].body(), reloadNotePrefix) {
+				return blocks[j].body(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// DiffReloadedFiles replaces files in loaded content that are already in
+// the conversation with a unified diff against that copy, or a note when
+// they are unchanged. Files whose diff is not shorter than their contents
+// are kept in full. It returns the content and the reloaded headers' paths.
+func (m *Manager) DiffReloadedFiles(content string) (string, []string) {
+	if !m.state.Config.ReloadDiff {
+		return content, nil
+	}
+
+	var reloaded []string
+	blocks := splitFileBlocks(content)
+	for i, block := range blocks {
+		if block.header == "" {
+			continue
+		}
+		previous, ok := m.previousFileBody(block.header)
+		if !ok {
+			continue
+		}
+
+		current := block.body()
+		var replacement string
+		if previous == current {
+			replacement = "(reloaded, unchanged since it was loaded earlier in this conversation)"
+		} else {
+			name := strings.TrimPrefix(block.header, "File: ")
+			diff, ok := unifiedDiff(previous, current, name)
+			if !ok || len(diff) >= len(current) {
+				continue
+			}
+			replacement = "(reloaded, unified diff against the version loaded earlier in this conversation)\n" + strings.TrimSuffix(diff, "\n")
+		}
+
+		// Keep the blank lines that separate the block from the next one
+		trailing := strings.TrimPrefix(strings.Join(block.lines, "\n"), current)
+		blocks[i].lines = strings.Split(replacement+trailing, "\n")
+		reloaded = append(reloaded, strings.TrimPrefix(block.header, "File: "))
+	}
+	if len(reloaded) == 0 {
+		return content, nil
+	}
+	return joinFileBlocks(blocks), reloaded
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff from oldText to newText labelled name,
+// or false when the changed region is too large to compare
+func unifiedDiff(oldText, newText, name string) (string, bool) {
+	a, b := strings.Split(oldText, "\n"), strings.Split(newText, "\n")
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(middleA)*len(middleB) > reloadDiffMaxCells {
+		return "", false
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, lineEdits(middleA, middleB)...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return formatHunks(ops, name), true
+}
+
+// lineEdits returns a shortest edit script from a to b using their longest
+// common subsequence
+func lineEdits(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// formatHunks renders an edit script as unified diff hunks with
+// diffContext lines of context, merging hunks whose context would overlap
+func formatHunks(ops []diffOp, name string) string {
+	// oldLine[k] and newLine[k] count the old and new lines before ops[k]
+	oldLine, newLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		oldLine[k+1], newLine[k+1] = oldLine[k], newLine[k]
+		if op.kind != '+' {
+			oldLine[k+1]++
+		}
+		if op.kind != '-' {
+			newLine[k+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for start := 0; start < len(ops); {
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for k := first + 1; k < len(ops) && k <= last+2*diffContext; k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			}
+		}
+
+		from, to := max(first-diffContext, 0), min(last+diffContext+1, len(ops))
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[from], oldLine[to]-oldLine[from]), hunkRange(newLine[from], newLine[to]-newLine[from]))
+		for _, op := range ops[from:to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,count of a hunk header; an empty range names
+// the line before it
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
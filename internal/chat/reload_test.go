@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "changed line",
+			old:  "a\nb\nc\nd\ne",
+			new:  "a\nb\nC\nd\ne",
+			want: "--- f\n+++ f\n@@ -1,5 +1,5 @@\n a\n b\n-c\n+C\n d\n e\n",
+		},
+		{
+			name: "insertion at start",
+			old:  "x\ny",
+			new:  "w\nx\ny",
+			want: "--- f\n+++ f\n@@ -1,2 +1,3 @@\n+w\n x\n y\n",
+		},
+		{
+			name: "distant changes make two hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve",
+			want: "--- f\n+++ f\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := unifiedDiff(tt.old, tt.new, "f")
+			if !ok || got != tt.want {
+				t.Errorf("unifiedDiff() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffReloadedFiles(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	original := strings.Join(lines, "\n")
+	lines[19] = "line twenty"
+	edited := strings.Join(lines, "\n")
+
+	state := &types.AppState{Config: &types.Config{ReloadDiff: true}}
+	m := NewManager(state)
+	m.AddUserMessage("File: main.go\n" + original + "\n\nFile: other.txt\nsame\n\n")
+
+	content, reloaded := m.DiffReloadedFiles("File: main.go\n" + edited + "\n\nFile: other.txt\nsame\n\nFile: new.txt\nfresh\n\n")
+	if strings.Join(reloaded, ",") != "main.go,other.txt" {
+		t.Errorf("reloaded = %v, want main.go and other.txt", reloaded)
+	}
+	for _, want := range []string{
+		"File: main.go\n(reloaded, unified diff",
+		"@@ -17,7 +17,7 @@\n line 17\n line 18\n line 19\n-line 20\n+line twenty\n",
+		"File: other.txt\n(reloaded, unchanged",
+		"File: new.txt\nfresh\n\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "line 1\n") {
+		t.Errorf("content repeats unchanged lines outside the diff:\n%s", content)
+	}
+
+	// A later reload diffs against the full copy, not the diff message
+	m.AddUserMessage(content)
+	content, _ = m.DiffReloadedFiles("File: main.go\n" + original + "\n\n")
+	if !strings.HasPrefix(content, "File: main.go\n(reloaded, unchanged") {
+		t.Errorf("reloading the original = %q, want an unchanged note", content)
+	}
+
+	state.Config.ReloadDiff = false
+	if content, reloaded := m.DiffReloadedFiles("File: main.go\n" + edited + "\n\n"); reloaded != nil || !strings.Contains(content, "line 1\n") {
+		t.Errorf("with reload_diff off, DiffReloadedFiles() = %q, %v", content, reloaded)
+	}
+}
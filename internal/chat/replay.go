@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// replayAllOption is the picker entry that replays the whole conversation
+const replayAllOption = "[all exchanges]"
+
+// IsLoadedContext reports whether a history entry is loaded content (files,
+// scrapes, command output) rather than a prompt the model answered
+func IsLoadedContext(entry types.ChatHistory) bool {
+	return entry.Context != "" && (entry.Bot == "" || entry.Bot == CommandOutputNote)
+}
+
+// SelectReplayEntries lets the user pick the exchanges of the conversation
+// to replay, in their original order. Loaded context is listed too, so a
+// file the prompts refer to can be kept or left out.
+func (m *Manager) SelectReplayEntries(terminal *ui.Terminal) ([]types.ChatHistory, error) {
+	if len(m.state.ChatHistory) <= 1 {
+		return nil, fmt.Errorf("no conversation to replay")
+	}
+	selections, err := terminal.FzfMultiSelect(replayOptions(m.state.ChatHistory), "replay: ")
+	if err != nil {
+		return nil, err
+	}
+	return replayEntries(m.state.ChatHistory, selections), nil
+}
+
+// replayOptions lists the history after the system prompt as "n. first
+// line" picker entries, after replayAllOption
+func replayOptions(history []types.ChatHistory) []string {
+	options := []string{replayAllOption}
+	for i := 1; i < len(history); i++ {
+		label := strings.Split(history[i].User, "\n")[0]
+		if len(label) > 80 {
+			label = label[:80] + "..."
+		}
+		if IsLoadedContext(history[i]) {
+			label += " (context)"
+		}
+		options = append(options, fmt.Sprintf("%d. %s", i, label))
+	}
+	return options
+}
+
+// replayEntries returns the history entries picked from replayOptions
+func replayEntries(history []types.ChatHistory, selections []string) []types.ChatHistory {
+	picked := make(map[int]bool)
+	for _, selection := range selections {
+		if selection == replayAllOption {
+			return append([]types.ChatHistory(nil), history[1:]...)
+		}
+		position, _, _ := strings.Cut(selection, ". ")
+		if i, err := strconv.Atoi(position); err == nil && i > 0 && i < len(history) {
+			picked[i] = true
+		}
+	}
+
+	var entries []types.ChatHistory
+	for i := 1; i < len(history); i++ {
+		if picked[i] {
+			entries = append(entries, history[i])
+		}
+	}
+	return entries
+}
@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestReplayPicker(t *testing.T) {
+	history := []types.ChatHistory{
+		{User: "system prompt"},
+		{User: "Loaded: main.go", Context: "File: main.go\npackage main"},
+		{User: "explain main.go\nin detail", Bot: "It is empty."},
+		{User: "!x ls", Bot: CommandOutputNote, Context: "$ ls\nmain.go"},
+		{User: "add a test", Bot: "Done."},
+	}
+
+	want := []string{replayAllOption, "1. Loaded: main.go (context)", "2. explain main.go", "3. !x ls (context)", "4. add a test"}
+	if got := replayOptions(history); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayOptions() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name       string
+		selections []string
+		want       []types.ChatHistory
+	}{
+		{"all", []string{"2. explain main.go", replayAllOption}, history[1:]},
+		{"picked in history order", []string{"4. add a test", "1. Loaded: main.go (context)"}, []types.ChatHistory{history[1], history[4]}},
+		{"unknown entries are ignored", []string{"9. gone", "nonsense"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayEntries(history, tt.selections); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("replayEntries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !IsLoadedContext(history[1]) || !IsLoadedContext(history[3]) || IsLoadedContext(history[2]) {
+		t.Error("IsLoadedContext() should match loads and command output only")
+	}
+}
@@ -0,0 +1,512 @@
+package chat
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/pkg/types"
+	_ "modernc.org/sqlite" // pure-Go driver, keeps the build free of CGO for storage
+)
+
+// SessionDBName is the database file kept in the session directory
+const SessionDBName = "ch_sessions.db"
+
+// sessionSchema creates the tables on first open. Session-level tags use
+// position -1, exchange tags use the message position.
+const sessionSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id        INTEGER PRIMARY KEY,
+	name      TEXT NOT NULL UNIQUE,
+	timestamp INTEGER NOT NULL,
+	platform  TEXT NOT NULL DEFAULT '',
+	model     TEXT NOT NULL DEFAULT '',
+	base_url  TEXT NOT NULL DEFAULT '',
+	rating    INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS messages (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	time       INTEGER NOT NULL DEFAULT 0,
+	user       TEXT NOT NULL DEFAULT '',
+	bot        TEXT NOT NULL DEFAULT '',
+	context    TEXT NOT NULL DEFAULT '',
+	platform   TEXT NOT NULL DEFAULT '',
+	model      TEXT NOT NULL DEFAULT '',
+	seed       INTEGER,
+	elapsed    REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (session_id, position)
+);
+CREATE TABLE IF NOT EXISTS tags (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	tag        TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS notes (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	time       INTEGER NOT NULL DEFAULT 0,
+	text       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS usage (
+	session_id    INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	position      INTEGER NOT NULL,
+	model         TEXT NOT NULL DEFAULT '',
+	input_tokens  INTEGER NOT NULL DEFAULT 0,
+	output_tokens INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (session_id, position)
+);
+CREATE TABLE IF NOT EXISTS audit (
+	time   INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	detail TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS sessions_timestamp ON sessions(timestamp);
+CREATE INDEX IF NOT EXISTS tags_tag ON tags(tag);
+`
+
+// SessionDB is the SQLite session backend. It stores sessions, messages,
+// tags, notes, estimated token usage, and an audit log of maintenance actions.
+type SessionDB struct {
+	db  *sql.DB
+	dir string
+}
+
+// SessionStats summarizes the contents of the session database
+type SessionStats struct {
+	Sessions     int
+	Messages     int
+	Oldest       int64
+	Newest       int64
+	ModelUsage   []ModelUsage
+	DatabaseSize int64
+}
+
+// ModelUsage holds estimated token totals for one model
+type ModelUsage struct {
+	Model        string
+	Exchanges    int
+	InputTokens  int
+	OutputTokens int
+}
+
+// MessageMatch is one search hit in the session database
+type MessageMatch struct {
+	Session string
+	Time    int64
+	Role    string
+	Content string
+}
+
+// OpenSessionDB opens or creates the session database in dir
+func OpenSessionDB(dir string) (*SessionDB, error) {
+	path := filepath.Join(dir, SessionDBName)
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: path}).EscapedPath()+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %v", err)
+	}
+	if _, err := db.Exec(sessionSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize session database: %v", err)
+	}
+	if err := addMessageColumn(db, "elapsed", "REAL NOT NULL DEFAULT 0"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to upgrade session database: %v", err)
+	}
+	_ = os.Chmod(path, 0600)
+	return &SessionDB{db: db, dir: dir}, nil
+}
+
+// addMessageColumn adds a column to messages tables created before it existed
+func addMessageColumn(db *sql.DB, name, definition string) error {
+	rows, err := db.Query(`PRAGMA table_info(messages)`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var column, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &column, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if column == name {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(`ALTER TABLE messages ADD COLUMN ` + name + ` ` + definition)
+	return err
+}
+
+// Close releases the database handle
+func (s *SessionDB) Close() error {
+	return s.db.Close()
+}
+
+// owns reports whether path names a session kept in this database. Paths
+// outside the session directory are explicit files and stay JSON.
+func (s *SessionDB) owns(path string) bool {
+	return filepath.Dir(path) == s.dir
+}
+
+// Save replaces the stored copy of a session in one transaction
+func (s *SessionDB) Save(path string, session *types.SessionFile) error {
+	if !s.owns(path) {
+		return writeSessionFile(path, session)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := saveSessionTx(tx, filepath.Base(path), session); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save session: %v", err)
+	}
+	return nil
+}
+
+// saveSessionTx upserts the session row and rewrites its messages, tags,
+// notes, and usage
+func saveSessionTx(tx *sql.Tx, name string, session *types.SessionFile) error {
+	var id int64
+	err := tx.QueryRow(`INSERT INTO sessions (name, timestamp, platform, model, base_url, rating) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET timestamp = excluded.timestamp, platform = excluded.platform,
+		model = excluded.model, base_url = excluded.base_url, rating = excluded.rating
+		RETURNING id`,
+		name, session.Timestamp, session.Platform, session.Model, session.BaseURL, session.Rating).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	// Token estimates are kept for positions that already have them, so a
+	// save only counts the exchanges added since the previous one
+	counted := map[int]string{}
+	rows, err := tx.Query(`SELECT m.position, m.user || m.context || m.bot FROM messages m JOIN usage u
+		ON u.session_id = m.session_id AND u.position = m.position WHERE m.session_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var position int
+		var content string
+		if err := rows.Scan(&position, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		counted[position] = content
+	}
+	rows.Close()
+
+	for _, table := range []string{"messages", "tags", "notes"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE session_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM usage WHERE session_id = ? AND position >= ?`, id, len(session.ChatHistory)); err != nil {
+		return err
+	}
+
+	for _, tag := range session.Tags {
+		if _, err := tx.Exec(`INSERT INTO tags (session_id, position, tag) VALUES (?, -1, ?)`, id, tag); err != nil {
+			return err
+		}
+	}
+	for _, note := range session.Notes {
+		if _, err := tx.Exec(`INSERT INTO notes (session_id, time, text) VALUES (?, ?, ?)`, id, note.Time, note.Text); err != nil {
+			return err
+		}
+	}
+
+	for i, entry := range session.ChatHistory {
+		var seed any
+		if entry.Seed != nil {
+			seed = *entry.Seed
+		}
+		if _, err := tx.Exec(`INSERT INTO messages (session_id, position, time, user, bot, context, platform, model, seed, elapsed)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, i, entry.Time, entry.User, entry.Bot, entry.Context, entry.Platform, entry.Model, seed, entry.Elapsed); err != nil {
+			return err
+		}
+		for _, tag := range entry.Tags {
+			if _, err := tx.Exec(`INSERT INTO tags (session_id, position, tag) VALUES (?, ?, ?)`, id, i, tag); err != nil {
+				return err
+			}
+		}
+
+		if i == 0 || entry.Bot == "" {
+			if _, ok := counted[i]; ok {
+				if _, err := tx.Exec(`DELETE FROM usage WHERE session_id = ? AND position = ?`, id, i); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if content, ok := counted[i]; ok && content == entry.User+entry.Context+entry.Bot {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO usage (session_id, position, model, input_tokens, output_tokens) VALUES (?, ?, ?, ?, ?)`,
+			id, i, entry.Model, tokens.Count(entry.Model, EffectiveUserContent(entry)), tokens.Count(entry.Model, entry.Bot)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads one session by path
+func (s *SessionDB) Load(path string) (*types.SessionFile, error) {
+	if !s.owns(path) {
+		return readSessionFile(path)
+	}
+	sessions, err := s.query(`WHERE name = ?`, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("history file does not exist: %s", path)
+	}
+	return sessions[0], nil
+}
+
+// Exists reports whether a session is stored under path
+func (s *SessionDB) Exists(path string) (bool, error) {
+	if !s.owns(path) {
+		return jsonSessionStore{}.Exists(path)
+	}
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE name = ?`, filepath.Base(path)).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// List returns every stored session, oldest first
+func (s *SessionDB) List() ([]*types.SessionFile, error) {
+	return s.query("")
+}
+
+// query loads the sessions matching where, with their messages and tags
+func (s *SessionDB) query(where string, args ...any) ([]*types.SessionFile, error) {
+	rows, err := s.db.Query(`SELECT id, name, timestamp, platform, model, base_url, rating FROM sessions `+where+` ORDER BY timestamp, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %v", err)
+	}
+
+	var sessions []*types.SessionFile
+	byID := map[int64]*types.SessionFile{}
+	for rows.Next() {
+		var id int64
+		var name string
+		session := &types.SessionFile{}
+		if err := rows.Scan(&id, &name, &session.Timestamp, &session.Platform, &session.Model, &session.BaseURL, &session.Rating); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read sessions: %v", err)
+		}
+		session.SourceFile = filepath.Join(s.dir, name)
+		sessions = append(sessions, session)
+		byID[id] = session
+	}
+	rows.Close()
+
+	for id, session := range byID {
+		if err := s.loadMessages(id, session); err != nil {
+			return nil, fmt.Errorf("failed to read session messages: %v", err)
+		}
+	}
+	return sessions, nil
+}
+
+// loadMessages fills in the chat history, tags, and notes of one session
+func (s *SessionDB) loadMessages(id int64, session *types.SessionFile) error {
+	rows, err := s.db.Query(`SELECT time, user, bot, context, platform, model, seed, elapsed FROM messages WHERE session_id = ? ORDER BY position`, id)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var entry types.ChatHistory
+		var seed sql.NullInt64
+		if err := rows.Scan(&entry.Time, &entry.User, &entry.Bot, &entry.Context, &entry.Platform, &entry.Model, &seed, &entry.Elapsed); err != nil {
+			rows.Close()
+			return err
+		}
+		if seed.Valid {
+			value := int(seed.Int64)
+			entry.Seed = &value
+		}
+		session.ChatHistory = append(session.ChatHistory, entry)
+	}
+	rows.Close()
+
+	noteRows, err := s.db.Query(`SELECT time, text FROM notes WHERE session_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return err
+	}
+	for noteRows.Next() {
+		var note types.SessionNote
+		if err := noteRows.Scan(&note.Time, &note.Text); err != nil {
+			noteRows.Close()
+			return err
+		}
+		session.Notes = append(session.Notes, note)
+	}
+	noteRows.Close()
+
+	tagRows, err := s.db.Query(`SELECT position, tag FROM tags WHERE session_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var position int
+		var tag string
+		if err := tagRows.Scan(&position, &tag); err != nil {
+			return err
+		}
+		if position < 0 {
+			session.Tags = append(session.Tags, tag)
+		} else if position < len(session.ChatHistory) {
+			session.ChatHistory[position].Tags = append(session.ChatHistory[position].Tags, tag)
+		}
+	}
+	return tagRows.Err()
+}
+
+// ImportJSON copies session files from dir into the database. Sessions that
+// are already stored under the same name are skipped unless overwrite is set.
+func (s *SessionDB) ImportJSON(dir string, overwrite bool) (int, error) {
+	sessions, err := jsonSessionStore{dir: dir}.List()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, session := range sessions {
+		target := filepath.Join(s.dir, filepath.Base(session.SourceFile))
+		if !overwrite {
+			if exists, err := s.Exists(target); err != nil {
+				return imported, err
+			} else if exists {
+				continue
+			}
+		}
+		if err := s.Save(target, session); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+
+	s.audit("import", fmt.Sprintf("%d sessions from %s", imported, dir))
+	return imported, nil
+}
+
+// ExportJSON writes every stored session to dir as session JSON files
+func (s *SessionDB) ExportJSON(dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, fmt.Errorf("failed to create export directory: %v", err)
+	}
+	sessions, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		if err := writeSessionFile(filepath.Join(dir, filepath.Base(session.SourceFile)), session); err != nil {
+			return 0, err
+		}
+	}
+
+	s.audit("export", fmt.Sprintf("%d sessions to %s", len(sessions), dir))
+	return len(sessions), nil
+}
+
+// Prune deletes sessions last saved before the given epoch
+func (s *SessionDB) Prune(before int64) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM sessions WHERE timestamp < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sessions: %v", err)
+	}
+	removed, _ := result.RowsAffected()
+	if removed > 0 {
+		_, _ = s.db.Exec(`VACUUM`)
+	}
+
+	s.audit("prune", fmt.Sprintf("%d sessions before %d", removed, before))
+	return int(removed), nil
+}
+
+// Search finds messages containing text, newest first
+func (s *SessionDB) Search(text string, limit int) ([]MessageMatch, error) {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+	rows, err := s.db.Query(`SELECT s.name, m.time, m.user, m.bot FROM messages m JOIN sessions s ON s.id = m.session_id
+		WHERE m.position > 0 AND (m.user LIKE ? ESCAPE '\' OR m.bot LIKE ? ESCAPE '\')
+		ORDER BY m.time DESC LIMIT ?`, pattern, pattern, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %v", err)
+	}
+	defer rows.Close()
+
+	needle := strings.ToLower(text)
+	var matches []MessageMatch
+	for rows.Next() {
+		var name, user, bot string
+		var at int64
+		if err := rows.Scan(&name, &at, &user, &bot); err != nil {
+			return nil, fmt.Errorf("failed to search sessions: %v", err)
+		}
+		if strings.Contains(strings.ToLower(user), needle) {
+			matches = append(matches, MessageMatch{Session: name, Time: at, Role: "user", Content: user})
+		}
+		if strings.Contains(strings.ToLower(bot), needle) {
+			matches = append(matches, MessageMatch{Session: name, Time: at, Role: "bot", Content: bot})
+		}
+	}
+	return matches, rows.Err()
+}
+
+// Stats reports session counts and estimated token usage per model
+func (s *SessionDB) Stats() (*SessionStats, error) {
+	stats := &SessionStats{}
+	var oldest, newest sql.NullInt64
+	if err := s.db.QueryRow(`SELECT COUNT(*), MIN(timestamp), MAX(timestamp) FROM sessions`).Scan(&stats.Sessions, &oldest, &newest); err != nil {
+		return nil, fmt.Errorf("failed to read stats: %v", err)
+	}
+	stats.Oldest, stats.Newest = oldest.Int64, newest.Int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE position > 0`).Scan(&stats.Messages); err != nil {
+		return nil, fmt.Errorf("failed to read stats: %v", err)
+	}
+
+	rows, err := s.db.Query(`SELECT model, COUNT(*), SUM(input_tokens), SUM(output_tokens) FROM usage
+		GROUP BY model ORDER BY SUM(input_tokens) + SUM(output_tokens) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stats: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var usage ModelUsage
+		if err := rows.Scan(&usage.Model, &usage.Exchanges, &usage.InputTokens, &usage.OutputTokens); err != nil {
+			return nil, fmt.Errorf("failed to read stats: %v", err)
+		}
+		stats.ModelUsage = append(stats.ModelUsage, usage)
+	}
+
+	if info, err := os.Stat(filepath.Join(s.dir, SessionDBName)); err == nil {
+		stats.DatabaseSize = info.Size()
+	}
+	return stats, rows.Err()
+}
+
+// audit records a maintenance action; failures are not fatal
+func (s *SessionDB) audit(action, detail string) {
+	_, _ = s.db.Exec(`INSERT INTO audit (time, action, detail) VALUES (?, ?, ?)`, time.Now().Unix(), action, detail)
+}
@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func newSQLiteTestManager(t *testing.T) (*Manager, *types.AppState) {
+	t.Helper()
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	seed := 7
+	state := &types.AppState{
+		Config: &types.Config{
+			CurrentPlatform:   "openai",
+			CurrentModel:      "gpt-4o",
+			SystemPrompt:      "Sys",
+			EnableSessionSave: true,
+			SaveAllSessions:   true,
+			StorageBackend:    StorageSQLite,
+		},
+		ChatHistory: []types.ChatHistory{
+			{User: "Sys"},
+			{User: "Hello?", Bot: "Hi!", Time: 1000, Model: "gpt-4o", Tags: []string{"favorite"}, Seed: &seed, Elapsed: 2.125},
+			{User: "Explain", Context: "loaded file", Bot: "Sure", Time: 1010, Model: "gpt-4o"},
+		},
+		SessionRating: 4,
+		SessionTags:   []string{"favorite", "work"},
+		SessionNotes:  []types.SessionNote{{Time: 1020, Text: "use the pooled client"}},
+	}
+	return NewManager(state), state
+}
+
+func TestSQLiteBackend_SaveAndRestore(t *testing.T) {
+	m, state := newSQLiteTestManager(t)
+
+	if err := m.SaveSessionState(); err != nil {
+		t.Fatalf("SaveSessionState() error: %v", err)
+	}
+	sessionDir, _ := config.GetSessionDir(state.Config)
+	if _, err := os.Stat(state.SessionFilePath); !os.IsNotExist(err) {
+		t.Errorf("sqlite backend should not write a session JSON file")
+	}
+	if _, err := os.Stat(filepath.Join(sessionDir, SessionDBName)); err != nil {
+		t.Fatalf("session database not created: %v", err)
+	}
+	if !m.SessionExists(state.SessionFilePath) {
+		t.Errorf("SessionExists should find the stored session")
+	}
+
+	loaded, err := m.LoadLatestSessionState()
+	if err != nil {
+		t.Fatalf("LoadLatestSessionState() error: %v", err)
+	}
+	if loaded.SourceFile != state.SessionFilePath || loaded.Rating != 4 {
+		t.Errorf("unexpected session metadata: %+v", loaded)
+	}
+	if !reflect.DeepEqual(loaded.Tags, state.SessionTags) {
+		t.Errorf("session tags = %v, want %v", loaded.Tags, state.SessionTags)
+	}
+	if !reflect.DeepEqual(loaded.Notes, state.SessionNotes) {
+		t.Errorf("session notes = %v, want %v", loaded.Notes, state.SessionNotes)
+	}
+	if !reflect.DeepEqual(loaded.ChatHistory, state.ChatHistory) {
+		t.Errorf("history round trip mismatch:\n got %+v\nwant %+v", loaded.ChatHistory, state.ChatHistory)
+	}
+
+	// Saving again updates the same session instead of adding one
+	state.ChatHistory = state.ChatHistory[:2]
+	if err := m.SaveSessionState(); err != nil {
+		t.Fatalf("second SaveSessionState() error: %v", err)
+	}
+	sessions, err := LoadSavedSessions(state.Config)
+	if err != nil || len(sessions) != 1 || len(sessions[0].ChatHistory) != 2 {
+		t.Fatalf("expected one truncated session, got %d (%v)", len(sessions), err)
+	}
+}
+
+func TestOpenSessionDB_EscapesPath(t *testing.T) {
+	// ? and # would otherwise end the path and start the DSN's query
+	dir := filepath.Join(t.TempDir(), "a?b#c %d")
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenSessionDB(dir)
+	if err != nil {
+		t.Fatalf("OpenSessionDB() error: %v", err)
+	}
+	defer db.Close()
+	if _, err := os.Stat(filepath.Join(dir, SessionDBName)); err != nil {
+		t.Errorf("session database not created inside %q: %v", dir, err)
+	}
+}
+
+func TestSessionDB_ImportExportStatsPrune(t *testing.T) {
+	m, state := newSQLiteTestManager(t)
+	state.Config.StorageBackend = StorageJSON
+	if err := m.SaveSessionState(); err != nil {
+		t.Fatalf("SaveSessionState() error: %v", err)
+	}
+
+	sessionDir, _ := config.GetSessionDir(state.Config)
+	db, err := OpenSessionDB(sessionDir)
+	if err != nil {
+		t.Fatalf("OpenSessionDB() error: %v", err)
+	}
+	defer db.Close()
+
+	if n, err := db.ImportJSON(sessionDir, false); err != nil || n != 1 {
+		t.Fatalf("ImportJSON() = %d, %v", n, err)
+	}
+	if n, _ := db.ImportJSON(sessionDir, false); n != 0 {
+		t.Errorf("re-import without overwrite should skip existing sessions, imported %d", n)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+	if stats.Sessions != 1 || stats.Messages != 2 || len(stats.ModelUsage) != 1 || stats.ModelUsage[0].Exchanges != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	matches, err := db.Search("explain", 10)
+	if err != nil || len(matches) != 1 || matches[0].Role != "user" {
+		t.Errorf("Search() = %+v, %v", matches, err)
+	}
+
+	exportDir := filepath.Join(t.TempDir(), "out")
+	if n, err := db.ExportJSON(exportDir); err != nil || n != 1 {
+		t.Fatalf("ExportJSON() = %d, %v", n, err)
+	}
+	exported, err := readSessionFile(filepath.Join(exportDir, filepath.Base(state.SessionFilePath)))
+	if err != nil {
+		t.Fatalf("exported file unreadable: %v", err)
+	}
+	if !reflect.DeepEqual(exported.ChatHistory, state.ChatHistory) {
+		t.Errorf("exported history mismatch: %+v", exported.ChatHistory)
+	}
+
+	if n, err := db.Prune(exported.Timestamp + 1); err != nil || n != 1 {
+		t.Fatalf("Prune() = %d, %v", n, err)
+	}
+	if stats, _ := db.Stats(); stats.Sessions != 0 || stats.Messages != 0 || len(stats.ModelUsage) != 0 {
+		t.Errorf("prune should cascade to messages and usage: %+v", stats)
+	}
+}
@@ -0,0 +1,47 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// ResponseStats returns the [words · tokens · lines of code · time] footer
+// of a history entry's reply, or "" when it has none. Lines of code count
+// the non-blank lines inside code blocks and are left out when there are none.
+func ResponseStats(entry types.ChatHistory) string {
+	if entry.Bot == "" {
+		return ""
+	}
+
+	parts := []string{
+		ui.Plural(len(strings.Fields(entry.Bot)), "word"),
+		ui.Plural(tokens.Count(entry.Model, entry.Bot), "token"),
+	}
+	code := 0
+	for _, block := range codeBlocks(entry.Bot) {
+		for _, line := range strings.Split(block.Code, "\n") {
+			if strings.TrimSpace(line) != "" {
+				code++
+			}
+		}
+	}
+	if code > 0 {
+		parts = append(parts, ui.Plural(code, "line")+" of code")
+	}
+	if entry.Elapsed > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", entry.Elapsed))
+	}
+	return "[" + strings.Join(parts, " · ") + "]"
+}
+
+// LastResponseStats returns the stats footer of the most recent exchange
+func (m *Manager) LastResponseStats() string {
+	if len(m.state.ChatHistory) == 0 {
+		return ""
+	}
+	return ResponseStats(m.state.ChatHistory[len(m.state.ChatHistory)-1])
+}
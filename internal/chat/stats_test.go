@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestResponseStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry types.ChatHistory
+		want  string
+	}{
+		{"no reply", types.ChatHistory{User: "hi"}, ""},
+		{"prose", types.ChatHistory{Bot: "hello there", Model: "gpt-4o", Elapsed: 1.25}, "[2 words · 2 tokens · 1.2s]"},
+		{"single word", types.ChatHistory{Bot: "hello", Model: "gpt-4o"}, "[1 word · 1 token]"},
+		{
+			"code",
+			types.ChatHistory{Bot: "Try:\n```go\nx := 1\n\nfmt.Println(x)\n```", Model: "gpt-4o"},
+			"[7 words · 15 tokens · 2 lines of code]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResponseStats(tt.entry); got != tt.want {
+				t.Errorf("ResponseStats() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
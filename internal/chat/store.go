@@ -0,0 +1,212 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// Storage backends selectable with storage_backend
+const (
+	StorageJSON   = "json"
+	StorageSQLite = "sqlite"
+)
+
+// sessionStore persists saved sessions. Sessions are addressed by the path of
+// their JSON file in the session directory, so names like
+// ch_session_<epoch>.json stay stable whichever backend holds the data.
+type sessionStore interface {
+	Save(path string, session *types.SessionFile) error
+	Load(path string) (*types.SessionFile, error)
+	Exists(path string) (bool, error)
+	List() ([]*types.SessionFile, error)
+	Close() error
+}
+
+// openSessionStore opens the configured backend for the current session directory
+func openSessionStore(cfg *types.Config) (sessionStore, error) {
+	dir, err := config.GetSessionDir(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session directory: %v", err)
+	}
+	return openSessionStoreAt(cfg.StorageBackend, dir)
+}
+
+// openSessionStoreAt opens a backend rooted at dir
+func openSessionStoreAt(backend, dir string) (sessionStore, error) {
+	switch backend {
+	case "", StorageJSON:
+		return jsonSessionStore{dir: dir}, nil
+	case StorageSQLite:
+		return OpenSessionDB(dir)
+	default:
+		return nil, fmt.Errorf("unknown storage_backend %q (use %s or %s)", backend, StorageJSON, StorageSQLite)
+	}
+}
+
+// CountSavedSessions counts the sessions saved in dir with the configured backend
+func CountSavedSessions(cfg *types.Config, dir string) int {
+	if cfg.StorageBackend == StorageSQLite {
+		if _, err := os.Stat(filepath.Join(dir, SessionDBName)); err != nil {
+			return 0
+		}
+	}
+	store, err := openSessionStoreAt(cfg.StorageBackend, dir)
+	if err != nil {
+		return 0
+	}
+	defer store.Close()
+
+	sessions, err := store.List()
+	if err != nil {
+		return 0
+	}
+	return len(sessions)
+}
+
+// isSessionFileName reports whether name looks like a saved session file
+func isSessionFileName(name string) bool {
+	return strings.HasPrefix(name, "ch_session_") && strings.HasSuffix(name, ".json")
+}
+
+// jsonSessionStore keeps one JSON file per session in the session directory
+type jsonSessionStore struct {
+	dir string
+}
+
+func (s jsonSessionStore) Save(path string, session *types.SessionFile) error {
+	return writeSessionFile(path, session)
+}
+
+func (s jsonSessionStore) Load(path string) (*types.SessionFile, error) {
+	return readSessionFile(path)
+}
+
+func (s jsonSessionStore) Exists(path string) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return true, nil
+	} else if os.IsNotExist(err) {
+		return false, nil
+	} else {
+		return false, err
+	}
+}
+
+// List reads every session file in the directory in parallel, oldest first.
+// Unreadable or corrupt files are skipped.
+func (s jsonSessionStore) List() ([]*types.SessionFile, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read temp directory: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		if !file.IsDir() && isSessionFileName(file.Name()) {
+			paths = append(paths, filepath.Join(s.dir, file.Name()))
+		}
+	}
+
+	results := make([]*types.SessionFile, len(paths))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(1, runtime.NumCPU()/2))
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if session, err := readSessionFile(path); err == nil {
+				results[i] = session
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	var sessions []*types.SessionFile
+	for _, session := range results {
+		if session != nil {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Timestamp < sessions[j].Timestamp
+	})
+	return sessions, nil
+}
+
+func (s jsonSessionStore) Close() error {
+	return nil
+}
+
+// writeSessionFile writes a session as indented JSON through a temp file and rename
+func writeSessionFile(path string, session *types.SessionFile) error {
+	jsonData, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %v", err)
+	}
+
+	// Write a synced temp file and rename it over the session, so a crash or
+	// power loss leaves either the old file or the new one, never a partial one
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write session file: %v", err)
+	}
+	tempPath := temp.Name()
+	_, err = temp.Write(jsonData)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to write session file: %v", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to rename session file: %v", err)
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes directory changes such as a rename to disk. Not every
+// platform can sync a directory, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil { // #nosec G304 -- dir is the session directory being written.
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
+
+// readSessionFile loads a session JSON file and records where it came from
+func readSessionFile(path string) (*types.SessionFile, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("history file does not exist: %s", path)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Session files are read from Ch's session directory or an explicitly provided path.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+
+	var session types.SessionFile
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse history file: %v", err)
+	}
+
+	session.SourceFile = path
+	return &session, nil
+}
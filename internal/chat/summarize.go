@@ -0,0 +1,183 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// maxSummarizeLevels bounds how many times chunk summaries are re-combined
+const maxSummarizeLevels = 6
+
+// summarizeChunkPrompt is sent for each chunk in the map stage
+const summarizeChunkPrompt = "Summarize part %d of %d of a larger document. Keep key facts, names, numbers, decisions, and conclusions. " +
+	"Write only the summary.%s\n\n%s"
+
+// summarizeDocumentPrompt is used when the whole document fits in one chunk
+const summarizeDocumentPrompt = "Summarize the following document. Keep key facts, names, numbers, decisions, and conclusions.%s\n\n%s"
+
+// summarizeFinalPrompt combines chunk summaries in the reduce stage
+const summarizeFinalPrompt = "Below are summaries of consecutive parts of one document. Combine them into a single coherent summary " +
+	"of the whole document, removing repetition.%s\n\n%s"
+
+// SummarizeOptions controls chunking and parallelism for map-reduce summarization
+type SummarizeOptions struct {
+	ChunkTokens   int
+	OverlapTokens int
+	Parallel      int
+	Instruction   string
+}
+
+// SummarizeProgress reports how many chunks of the current level are done
+type SummarizeProgress func(level, done, total int)
+
+// ChunkText splits text into chunks of at most chunkTokens tokens, where each
+// chunk repeats the last overlapTokens tokens of the previous one
+func ChunkText(text string, chunkTokens, overlapTokens int) ([]string, error) {
+	if chunkTokens <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive")
+	}
+	if overlapTokens < 0 || overlapTokens >= chunkTokens {
+		overlapTokens = 0
+	}
+
+	enc, err := tokenizer.Get(tokenizer.Cl100kBase)
+	if err != nil {
+		return nil, fmt.Errorf("error getting tokenizer: %v", err)
+	}
+	ids, _, err := enc.Encode(text)
+	if err != nil {
+		return nil, fmt.Errorf("error tokenizing content: %v", err)
+	}
+	if len(ids) <= chunkTokens {
+		return []string{text}, nil
+	}
+
+	var chunks []string
+	step := chunkTokens - overlapTokens
+	for start := 0; start < len(ids); start += step {
+		end := min(start+chunkTokens, len(ids))
+		chunk, err := enc.Decode(ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("error decoding chunk: %v", err)
+		}
+		chunks = append(chunks, strings.ToValidUTF8(chunk, ""))
+		if end == len(ids) {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// Summarize condenses content of any size with the current model. Content that
+// does not fit in one chunk is split, the chunks are summarized in parallel,
+// and the summaries are combined, repeating until they fit in one final request.
+func (m *Manager) Summarize(content string, opts SummarizeOptions, progress SummarizeProgress) (string, error) {
+	if m.platformManager == nil {
+		return "", fmt.Errorf("platform is not initialized")
+	}
+	if strings.TrimSpace(content) == "" {
+		return "", fmt.Errorf("nothing to summarize")
+	}
+
+	focus := ""
+	if opts.Instruction != "" {
+		focus = " Focus on: " + opts.Instruction
+	}
+
+	for level := 1; level <= maxSummarizeLevels; level++ {
+		chunks, err := ChunkText(content, opts.ChunkTokens, opts.OverlapTokens)
+		if err != nil {
+			return "", err
+		}
+		if len(chunks) == 1 {
+			if progress != nil {
+				progress(level, 0, 1)
+			}
+			prompt := summarizeFinalPrompt
+			if level == 1 {
+				prompt = summarizeDocumentPrompt
+			}
+			summary, err := m.summarizeRequest(fmt.Sprintf(prompt, focus, content))
+			if progress != nil && err == nil {
+				progress(level, 1, 1)
+			}
+			return summary, err
+		}
+
+		summaries, err := m.summarizeChunks(chunks, focus, opts.Parallel, func(done int) {
+			if progress != nil {
+				progress(level, done, len(chunks))
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+		combined := strings.Join(summaries, "\n\n")
+		if len(combined) >= len(content) {
+			return "", fmt.Errorf("summaries are not getting shorter, try a larger chunk size")
+		}
+		content = combined
+	}
+
+	return "", fmt.Errorf("document still too large after %d summarization levels", maxSummarizeLevels)
+}
+
+// summarizeChunks runs the map stage with at most parallel requests in flight,
+// returning summaries in chunk order
+func (m *Manager) summarizeChunks(chunks []string, focus string, parallel int, onDone func(done int)) ([]string, error) {
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			summaries[i], errs[i] = m.summarizeRequest(fmt.Sprintf(summarizeChunkPrompt, i+1, len(chunks), focus, chunk))
+
+			mu.Lock()
+			done++
+			onDone(done)
+			mu.Unlock()
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %v", i+1, err)
+		}
+	}
+	return summaries, nil
+}
+
+// summarizeRequest sends one summarization prompt. Each request gets its own
+// cancel state so parallel requests do not share the session's streaming fields.
+func (m *Manager) summarizeRequest(prompt string) (string, error) {
+	var cancel func()
+	var streaming bool
+	response, err := m.platformManager.SendSilentChatRequest(
+		[]types.ChatMessage{{Role: "user", Content: prompt}},
+		m.state.Config.CurrentModel,
+		&cancel,
+		&streaming,
+	)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChunkText(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 600; i++ {
+		sb.WriteString(fmt.Sprintf("w%d ", i))
+	}
+	text := sb.String()
+
+	chunks, err := ChunkText(text, 500, 50)
+	if err != nil {
+		t.Fatalf("ChunkText: %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	if !strings.HasPrefix(text, chunks[0]) || !strings.HasSuffix(text, chunks[len(chunks)-1]) {
+		t.Error("chunks should start and end with the text")
+	}
+
+	// The overlap repeats the tail of one chunk at the head of the next
+	tail := chunks[0][len(chunks[0])-20:]
+	if !strings.Contains(chunks[1], tail) {
+		t.Errorf("chunk 2 does not overlap chunk 1: %q", tail)
+	}
+
+	single, _ := ChunkText("short text", 500, 50)
+	if len(single) != 1 || single[0] != "short text" {
+		t.Errorf("short text should be one chunk, got %q", single)
+	}
+	if _, err := ChunkText("x", 0, 0); err == nil {
+		t.Error("expected an error for a zero chunk size")
+	}
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+	m, _, models := newCompressTestManager(t, "summary")
+	content := strings.Repeat("a sentence about the document. ", 400)
+
+	var levels []int
+	summary, err := m.Summarize(content, SummarizeOptions{ChunkTokens: 1000, OverlapTokens: 100, Parallel: 3}, func(level, done, total int) {
+		if len(levels) == 0 || levels[len(levels)-1] != level {
+			levels = append(levels, level)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "summary" {
+		t.Errorf("summary = %q", summary)
+	}
+
+	chunks, _ := ChunkText(content, 1000, 100)
+	if len(*models) != len(chunks)+1 {
+		t.Errorf("expected %d chunk requests plus one final request, got %d", len(chunks), len(*models))
+	}
+	if len(levels) != 2 || levels[0] != 1 || levels[1] != 2 {
+		t.Errorf("expected a map level then a reduce level, got %v", levels)
+	}
+	for _, model := range *models {
+		if model != "expensive" {
+			t.Errorf("summaries should use the current model, got %q", model)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FavoriteTag is the tag applied by the tag command when no name is given
+const FavoriteTag = "favorite"
+
+// NormalizeTag lowercases a tag, strips a leading "#", and joins words with dashes
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+}
+
+// HasTag reports whether tags contains tag, ignoring case and a leading "#"
+func HasTag(tags []string, tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, existing := range tags {
+		if NormalizeTag(existing) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// addTags appends normalized tags that are not already present
+func addTags(tags []string, add ...string) []string {
+	for _, tag := range add {
+		if tag = NormalizeTag(tag); tag != "" && !HasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// removeTags returns tags without any of the given tags
+func removeTags(tags []string, remove ...string) []string {
+	var kept []string
+	for _, tag := range tags {
+		if !HasTag(remove, tag) {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// TagLastExchange labels the latest answered exchange and the session with tags.
+// With no tags, the exchange is marked as a favorite.
+func (m *Manager) TagLastExchange(tags []string) error {
+	if len(tags) == 0 {
+		tags = []string{FavoriteTag}
+	}
+
+	for i := len(m.state.ChatHistory) - 1; i > 0; i-- {
+		if m.state.ChatHistory[i].Bot == "" {
+			continue
+		}
+		m.state.ChatHistory[i].Tags = addTags(m.state.ChatHistory[i].Tags, tags...)
+		m.state.SessionTags = addTags(m.state.SessionTags, tags...)
+		return nil
+	}
+	return fmt.Errorf("no exchange to tag")
+}
+
+// UntagSession removes tags from the session and every exchange in it
+func (m *Manager) UntagSession(tags []string) {
+	m.state.SessionTags = removeTags(m.state.SessionTags, tags...)
+	for i := range m.state.ChatHistory {
+		m.state.ChatHistory[i].Tags = removeTags(m.state.ChatHistory[i].Tags, tags...)
+	}
+}
+
+// GetSessionTags returns the tags of the current session
+func (m *Manager) GetSessionTags() []string {
+	return m.state.SessionTags
+}
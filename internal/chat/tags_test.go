@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := map[string]string{
+		"Favorite":             "favorite",
+		"#go":                  "go",
+		"  machine  learning ": "machine-learning",
+		"":                     "",
+	}
+	for in, want := range tests {
+		if got := NormalizeTag(in); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestManager_TagLastExchange(t *testing.T) {
+	state := &types.AppState{
+		Config: &types.Config{SystemPrompt: "Sys"},
+		ChatHistory: []types.ChatHistory{
+			{User: "Sys"},
+			{User: "first", Bot: "one"},
+			{User: "second", Bot: "two"},
+			{User: "!x ls"},
+		},
+	}
+	m := NewManager(state)
+
+	if err := m.TagLastExchange(nil); err != nil {
+		t.Fatalf("TagLastExchange() error: %v", err)
+	}
+	if err := m.TagLastExchange([]string{"#Go", "go", "notes"}); err != nil {
+		t.Fatalf("TagLastExchange() error: %v", err)
+	}
+
+	if want := []string{"favorite", "go", "notes"}; !reflect.DeepEqual(state.ChatHistory[2].Tags, want) {
+		t.Errorf("exchange tags = %v, want %v", state.ChatHistory[2].Tags, want)
+	}
+	if len(state.ChatHistory[1].Tags) != 0 || len(state.ChatHistory[3].Tags) != 0 {
+		t.Errorf("only the latest answered exchange should be tagged: %+v", state.ChatHistory)
+	}
+	if want := []string{"favorite", "go", "notes"}; !reflect.DeepEqual(m.GetSessionTags(), want) {
+		t.Errorf("session tags = %v, want %v", m.GetSessionTags(), want)
+	}
+
+	m.UntagSession([]string{"GO"})
+	if want := []string{"favorite", "notes"}; !reflect.DeepEqual(m.GetSessionTags(), want) {
+		t.Errorf("session tags after untag = %v, want %v", m.GetSessionTags(), want)
+	}
+	if HasTag(state.ChatHistory[2].Tags, "go") {
+		t.Errorf("untag should remove the tag from exchanges: %v", state.ChatHistory[2].Tags)
+	}
+}
+
+func TestManager_TagLastExchange_NoExchange(t *testing.T) {
+	m := NewManager(&types.AppState{
+		Config:      &types.Config{SystemPrompt: "Sys"},
+		ChatHistory: []types.ChatHistory{{User: "Sys"}},
+	})
+	if err := m.TagLastExchange([]string{"x"}); err == nil {
+		t.Error("expected an error when there is no exchange to tag")
+	}
+}
@@ -0,0 +1,210 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// tailQuietReply is what the model answers when a batch holds nothing worth
+// reporting, so quiet batches print nothing
+const tailQuietReply = "NOTHING"
+
+// tailPrompt asks the model about one batch of new log lines
+const tailPrompt = "You are watching the log file %s as it is written. Instruction: %s\n\n" +
+	"New lines since the last check%s:\n```\n%s\n```\n\n" +
+	"If nothing in these lines matches the instruction, reply with exactly " + tailQuietReply + ". " +
+	"Otherwise reply with a short finding that quotes the relevant lines and explains them."
+
+// defaultTailInstruction is used when ch tail is given no --ask
+const defaultTailInstruction = "alert me about errors, warnings, and anything unusual, and explain the likely cause"
+
+// tailPollInterval is how often the followed file is checked for new data
+const tailPollInterval = 500 * time.Millisecond
+
+// TailOptions controls ch tail
+type TailOptions struct {
+	Path     string
+	Ask      string
+	Interval time.Duration // how long new lines are batched before asking
+	MaxLines int           // newest lines kept per batch
+}
+
+// TailFinding is a model reply about one batch that was not quiet
+type TailFinding struct {
+	Time  time.Time
+	Lines int
+	Text  string
+}
+
+// tailReader returns the complete lines appended to a file since the last
+// read. A file that shrinks was truncated or rotated and is read again from
+// the start.
+type tailReader struct {
+	path    string
+	offset  int64
+	partial []byte // trailing text without a newline yet
+}
+
+// newTailReader starts following path from its current end
+func newTailReader(path string) (*tailReader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	return &tailReader{path: path, offset: info.Size()}, nil
+}
+
+// readLines returns the complete lines written since the last call
+func (r *tailReader) readLines() ([]string, error) {
+	file, err := os.Open(r.path) // #nosec G304 -- The followed log file is chosen by the user.
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < r.offset {
+		r.offset, r.partial = 0, nil
+	}
+	if info.Size() == r.offset {
+		return nil, nil
+	}
+
+	if _, err := file.Seek(r.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(len(data))
+
+	data = append(r.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		r.partial = data
+		return nil, nil
+	}
+	r.partial = append([]byte(nil), data[end+1:]...)
+
+	var lines []string
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// tailBatchPrompt builds the prompt for a batch of lines, noting how many
+// older lines were dropped to keep the batch under --max-lines
+func tailBatchPrompt(path, ask string, lines []string, dropped int) string {
+	if ask == "" {
+		ask = defaultTailInstruction
+	}
+	note := ""
+	if dropped > 0 {
+		note = fmt.Sprintf(" (%s before these were skipped)", ui.Plural(dropped, "older line"))
+	}
+	return fmt.Sprintf(tailPrompt, path, ask, note, strings.Join(lines, "\n"))
+}
+
+// isQuietTailReply reports whether the model found nothing in a batch
+func isQuietTailReply(reply string) bool {
+	return strings.EqualFold(strings.Trim(strings.TrimSpace(reply), ".*`\"'"), tailQuietReply)
+}
+
+// Tail follows opts.Path until ctx is done. New lines are collected for
+// opts.Interval, then sent to the current model with the instruction, and
+// every reply that is not quiet is passed to report. Batches are sent one at
+// a time, so lines written during a slow reply go into the next batch.
+func (m *Manager) Tail(ctx context.Context, opts TailOptions, report func(TailFinding)) error {
+	if m.platformManager == nil {
+		return fmt.Errorf("platform is not initialized")
+	}
+	reader, err := newTailReader(opts.Path)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	var batch []string
+	dropped := 0
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		lines, err := reader.readLines()
+		if err != nil {
+			return err
+		}
+		batch = append(batch, lines...)
+		if opts.MaxLines > 0 && len(batch) > opts.MaxLines {
+			dropped += len(batch) - opts.MaxLines
+			batch = append([]string(nil), batch[len(batch)-opts.MaxLines:]...)
+		}
+		if len(batch) == 0 || time.Since(last) < opts.Interval {
+			continue
+		}
+
+		reply, err := m.tailRequest(ctx, tailBatchPrompt(opts.Path, opts.Ask, batch, dropped))
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !isQuietTailReply(reply) {
+			report(TailFinding{Time: time.Now(), Lines: len(batch) + dropped, Text: reply})
+		}
+		batch, dropped, last = nil, 0, time.Now()
+	}
+}
+
+// tailRequest sends one batch prompt, cancelling the request when ctx is done
+func (m *Manager) tailRequest(ctx context.Context, prompt string) (string, error) {
+	var cancel func()
+	var streaming bool
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if cancel != nil {
+				cancel()
+			}
+		case <-done:
+		}
+	}()
+
+	response, err := m.platformManager.SendSilentChatRequest(
+		[]types.ChatMessage{{Role: "user", Content: prompt}},
+		m.state.Config.CurrentModel,
+		&cancel,
+		&streaming,
+	)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
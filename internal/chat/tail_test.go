@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailReaderReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old line\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := newTailReader(path)
+	if err != nil {
+		t.Fatalf("newTailReader() error: %v", err)
+	}
+
+	appendLog := func(text string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		write    func()
+		expected []string
+	}{
+		{write: func() {}, expected: nil},
+		{write: func() { appendLog("first\r\n\nsecond\npart") }, expected: []string{"first", "second"}},
+		{write: func() { appendLog("ial\n") }, expected: []string{"partial"}},
+		{write: func() { _ = os.WriteFile(path, []byte("rotated\n"), 0600) }, expected: []string{"rotated"}},
+	}
+	for i, step := range steps {
+		step.write()
+		lines, err := reader.readLines()
+		if err != nil {
+			t.Fatalf("step %d: readLines() error: %v", i, err)
+		}
+		if !reflect.DeepEqual(lines, step.expected) {
+			t.Errorf("step %d: readLines() = %q, want %q", i, lines, step.expected)
+		}
+	}
+}
+
+func TestTailBatchPrompt(t *testing.T) {
+	prompt := tailBatchPrompt("app.log", "", []string{"a", "b"}, 3)
+	for _, want := range []string{"app.log", defaultTailInstruction, "(3 older lines before these were skipped)", "```\na\nb\n```", tailQuietReply} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(tailBatchPrompt("app.log", "errors", []string{"a"}, 0), "skipped") {
+		t.Error("prompt mentions skipped lines when none were dropped")
+	}
+}
+
+func TestIsQuietTailReply(t *testing.T) {
+	tests := map[string]bool{
+		"NOTHING":                 true,
+		" nothing.\n":             true,
+		"`NOTHING`":               true,
+		"**Nothing**":             true,
+		"Nothing unusual, but...": false,
+		"ERROR at line 3":         false,
+	}
+	for reply, want := range tests {
+		if got := isQuietTailReply(reply); got != want {
+			t.Errorf("isQuietTailReply(%q) = %v, want %v", reply, got, want)
+		}
+	}
+}
+
+func TestTailReportsFindings(t *testing.T) {
+	for _, tt := range []struct {
+		reply    string
+		findings int
+	}{
+		{reply: "connection refused at 12:00", findings: 1},
+		{reply: "NOTHING", findings: 0},
+	} {
+		m, _, _ := newCompressTestManager(t, tt.reply)
+		path := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var findings []TailFinding
+		done := make(chan error, 1)
+		go func() {
+			done <- m.Tail(ctx, TailOptions{Path: path, MaxLines: 1}, func(f TailFinding) {
+				findings = append(findings, f)
+				cancel()
+			})
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		if err := os.WriteFile(path, []byte("ok\nERROR connection refused\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if tt.findings == 0 {
+			time.AfterFunc(3*tailPollInterval, cancel)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Tail() error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			cancel()
+			t.Fatal("Tail() did not stop")
+		}
+
+		if len(findings) != tt.findings {
+			t.Fatalf("reply %q: got %d findings, want %d", tt.reply, len(findings), tt.findings)
+		}
+		if tt.findings > 0 && (findings[0].Text != tt.reply || findings[0].Lines != 2) {
+			t.Errorf("finding = %+v, want the reply about 2 lines", findings[0])
+		}
+	}
+}
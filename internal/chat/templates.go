@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/config"
+)
+
+// TemplateStdin is the placeholder filled with piped input
+const TemplateStdin = "stdin"
+
+// templateVariablePattern matches {{name}} placeholders, allowing spaces
+// inside the braces
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// templateNamePattern matches a placeholder name on its own
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// ListTemplates returns the names of the templates in ~/.ch/templates: file
+// names without their extension, sorted
+func ListTemplates() ([]string, error) {
+	dir, err := config.GetTemplateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadTemplate reads the template called name, matching a file name in
+// ~/.ch/templates with or without its extension
+func LoadTemplate(name string) (string, error) {
+	dir, err := config.GetTemplateDir()
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		matches, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no template %q in %s", name, dir)
+		}
+		path = matches[0]
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- Template path is resolved under the current user's ~/.ch/templates directory.
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// TemplateVariables returns the placeholder names in text, in order of
+// first use
+func TemplateVariables(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range templateVariablePattern.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// FillTemplate replaces the placeholders in text that have a value; the
+// rest are left as they are
+func FillTemplate(text string, values map[string]string) string {
+	return templateVariablePattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := templateVariablePattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// ParseTemplateArgs reads name=value arguments. Arguments without = continue
+// the previous value, so focus=error handling typed at the ch prompt is one
+// value; any before the first name=value are returned as extra text.
+func ParseTemplateArgs(args []string) (map[string]string, string) {
+	values := make(map[string]string)
+	var extra []string
+	last := ""
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && templateNamePattern.MatchString(name) {
+			values[name] = value
+			last = name
+			continue
+		}
+		if last == "" {
+			extra = append(extra, arg)
+			continue
+		}
+		values[last] = strings.TrimSpace(values[last] + " " + arg)
+	}
+	return values, strings.Join(extra, " ")
+}
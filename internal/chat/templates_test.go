@@ -0,0 +1,97 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	got := TemplateVariables("Review {{ focus }} in:\n{{stdin}}\nMention {{focus}} twice, skip {{not valid}}")
+	if want := []string{"focus", "stdin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateVariables() = %v, want %v", got, want)
+	}
+}
+
+func TestFillTemplate(t *testing.T) {
+	got := FillTemplate("{{ lang }} code, focus on {{focus}}, keep {{other}}", map[string]string{"lang": "Go", "focus": "errors"})
+	if want := "Go code, focus on errors, keep {{other}}"; got != want {
+		t.Errorf("FillTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestParseTemplateArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantValues map[string]string
+		wantExtra  string
+	}{
+		{
+			name:       "name=value pairs",
+			args:       []string{"lang=go", "focus=naming"},
+			wantValues: map[string]string{"lang": "go", "focus": "naming"},
+		},
+		{
+			name:       "words continue the previous value",
+			args:       []string{"focus=error", "handling", "lang=go"},
+			wantValues: map[string]string{"focus": "error handling", "lang": "go"},
+		},
+		{
+			name:       "leading words are extra text",
+			args:       []string{"also", "check", "tests", "lang=go"},
+			wantValues: map[string]string{"lang": "go"},
+			wantExtra:  "also check tests",
+		},
+		{
+			name:       "values may contain =",
+			args:       []string{"query=a=b", "x=1"},
+			wantValues: map[string]string{"query": "a=b", "x": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, extra := ParseTemplateArgs(tt.args)
+			if !reflect.DeepEqual(values, tt.wantValues) || extra != tt.wantExtra {
+				t.Errorf("ParseTemplateArgs() = %v, %q; want %v, %q", values, extra, tt.wantValues, tt.wantExtra)
+			}
+		})
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	dir := filepath.Join(tempHome, ".ch", "templates")
+	if err := os.MkdirAll(filepath.Join(dir, "drafts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"review-pr.md": "Review this diff:\n{{stdin}}\n\n",
+		"commit-msg":   "Write a commit message",
+		".hidden":      "skipped",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := ListTemplates()
+	if want := []string{"commit-msg", "review-pr"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTemplates() = %v, %v; want %v", names, err, want)
+	}
+
+	for _, name := range []string{"review-pr", "review-pr.md"} {
+		if got, err := LoadTemplate(name); err != nil || got != "Review this diff:\n{{stdin}}" {
+			t.Errorf("LoadTemplate(%q) = %q, %v", name, got, err)
+		}
+	}
+	for _, name := range []string{"missing", "../config.json", ""} {
+		if _, err := LoadTemplate(name); err == nil {
+			t.Errorf("LoadTemplate(%q) succeeded, want an error", name)
+		}
+	}
+}
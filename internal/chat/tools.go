@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// toolOutputBytes caps the output of one run_shell call
+const toolOutputBytes = 20000
+
+// stringParameters is the JSON schema of an arguments object with one
+// required string field
+func stringParameters(name, description string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			name: map[string]any{"type": "string", "description": description},
+		},
+		"required": []string{name},
+	}
+}
+
+// stringArg returns a required string argument
+func stringArg(args map[string]any, name string) (string, error) {
+	value, _ := args[name].(string)
+	if strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("missing %q argument", name)
+	}
+	return value, nil
+}
+
+// LocalTools returns the tools the model may call when the tools option is
+// on, built on the same code as !x, !l, !w, and URL loading. Files a
+// run_shell call writes are passed to record, when set.
+func LocalTools(terminal *ui.Terminal, cfg *types.Config, record func(path string)) []platform.Tool {
+	return []platform.Tool{
+		{
+			Name:        "run_shell",
+			Description: "Run a shell command with sh on the user's machine and return its combined stdout and stderr.",
+			Parameters:  stringParameters("command", "The command to run"),
+			Run: func(args map[string]any) (string, error) {
+				command, err := stringArg(args, "command")
+				if err != nil {
+					return "", err
+				}
+				start := time.Now()
+				output := runInterpolatedCommand(command, toolOutputBytes)
+				artifacts := findArtifacts(command, output, start)
+				if len(artifacts) == 0 {
+					return output, nil
+				}
+				for _, path := range artifacts {
+					if record != nil {
+						record(path)
+					}
+				}
+				return output + fmt.Sprintf("\n[files written: %s]", strings.Join(artifacts, ", ")), nil
+			},
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a local file or directory (text, PDF, DOCX, XLSX, CSV, and images via OCR) and return its contents.",
+			Parameters:  stringParameters("path", "Path of the file or directory"),
+			Run: func(args map[string]any) (string, error) {
+				path, err := stringArg(args, "path")
+				if err != nil {
+					return "", err
+				}
+				if _, err := os.Stat(path); err != nil {
+					return "", err
+				}
+				return terminal.LoadFileContent([]string{path})
+			},
+		},
+		{
+			Name:        "web_search",
+			Description: "Search the web and return the top results with titles, URLs, and snippets.",
+			Parameters:  stringParameters("query", "The search query"),
+			Run: func(args map[string]any) (string, error) {
+				query, err := stringArg(args, "query")
+				if err != nil {
+					return "", err
+				}
+				return terminal.WebSearch(query)
+			},
+		},
+		{
+			Name:        "scrape_url",
+			Description: "Fetch a web page or YouTube video and return its text content.",
+			Parameters:  stringParameters("url", "The http or https URL to fetch"),
+			Run: func(args map[string]any) (string, error) {
+				url, err := stringArg(args, "url")
+				if err != nil {
+					return "", err
+				}
+				if !terminal.IsURL(url) {
+					return "", fmt.Errorf("%q is not an http or https URL", url)
+				}
+				return terminal.ScrapeURLsWithFormat([]string{url}, cfg.ScrapeFormat)
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/platform"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestLocalTools(t *testing.T) {
+	cfg := &types.Config{IsPipedOutput: true}
+	tools := map[string]platform.Tool{}
+	for _, tool := range LocalTools(ui.NewTerminal(cfg), cfg, nil) {
+		tools[tool.Name] = tool
+	}
+	for _, name := range []string{"run_shell", "read_file", "web_search", "scrape_url"} {
+		if _, ok := tools[name]; !ok {
+			t.Fatalf("LocalTools() is missing %s", name)
+		}
+	}
+
+	out, err := tools["run_shell"].Run(map[string]any{"command": "printf hi; exit 3"})
+	if err != nil || !strings.HasPrefix(out, "hi\n[command failed:") {
+		t.Errorf("run_shell = %q, %v; want the output and the failure", out, err)
+	}
+
+	var recorded []string
+	runShell := LocalTools(ui.NewTerminal(cfg), cfg, func(path string) { recorded = append(recorded, path) })[0]
+	dir := t.TempDir()
+	t.Chdir(dir)
+	out, err = runShell.Run(map[string]any{"command": "echo done > result.txt"})
+	if want := filepath.Join(dir, "result.txt"); err != nil || len(recorded) != 1 || recorded[0] != want || !strings.Contains(out, "[files written: "+want+"]") {
+		t.Errorf("run_shell writing a file = %q, %v, recorded %q", out, err, recorded)
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("remember the milk"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := tools["read_file"].Run(map[string]any{"path": path}); err != nil || !strings.Contains(out, "remember the milk") {
+		t.Errorf("read_file = %q, %v", out, err)
+	}
+
+	for _, tt := range []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"read_file", map[string]any{}, `missing "path" argument`},
+		{"read_file", map[string]any{"path": filepath.Join(t.TempDir(), "gone.txt")}, "no such file"},
+		{"scrape_url", map[string]any{"url": "file:///etc/passwd"}, "is not an http or https URL"},
+		{"run_shell", map[string]any{"command": 5}, `missing "command" argument`},
+	} {
+		if _, err := tools[tt.tool].Run(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s(%v) error = %v, want %q", tt.tool, tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// FormatTranscript renders the exchanges of history from position from
+// onwards for re-reading: a dim header with the position, time, and model,
+// then the user message and the reply in the usual role colors. The system
+// prompt entry at position 0 is never included.
+func FormatTranscript(history []types.ChatHistory, from int) string {
+	var b strings.Builder
+	for i := max(from, 1); i < len(history); i++ {
+		entry := history[i]
+		if entry.User == "" && entry.Bot == "" {
+			continue
+		}
+
+		header := fmt.Sprintf("[%d] %s", i, time.Unix(entry.Time, 0).Format("2006-01-02 15:04:05"))
+		if entry.Model != "" {
+			header += " " + strings.TrimPrefix(entry.Platform+"/"+entry.Model, "/")
+		}
+		fmt.Fprintf(&b, "\033[90m%s\033[0m\n", header)
+		if entry.User != "" {
+			fmt.Fprintf(&b, "\033[94muser:\033[0m %s\n", entry.User)
+		}
+		if entry.Bot != "" {
+			fmt.Fprintf(&b, "\033[92m%s\033[0m\n", entry.Bot)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// lastExchangesStart returns the history position where the last n
+// exchanges begin, or 1 for the whole session when n is not positive
+func lastExchangesStart(history []types.ChatHistory, n int) int {
+	if n <= 0 {
+		return 1
+	}
+	for i := len(history) - 1; i >= 1; i-- {
+		if history[i].User == "" && history[i].Bot == "" {
+			continue
+		}
+		if n--; n == 0 {
+			return i
+		}
+	}
+	return 1
+}
+
+// ShowTranscript re-prints the last n exchanges, or the whole session when n
+// is 0, through the pager. Scrollback is often gone after fzf or an editor
+// cleared the screen.
+func (m *Manager) ShowTranscript(terminal *ui.Terminal, n int) error {
+	if len(m.state.ChatHistory) <= 1 {
+		return fmt.Errorf("no chat history available")
+	}
+	return terminal.Page(FormatTranscript(m.state.ChatHistory, lastExchangesStart(m.state.ChatHistory, n)))
+}
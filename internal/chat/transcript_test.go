@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestLastExchangesStart(t *testing.T) {
+	history := []types.ChatHistory{
+		{User: "system prompt"},
+		{User: "one", Bot: "1"},
+		{User: "two", Bot: "2"},
+		{}, // empty entries are not exchanges
+		{User: "three", Bot: "3"},
+	}
+
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 1},
+		{1, 4},
+		{2, 2},
+		{3, 1},
+		{10, 1},
+	}
+	for _, tt := range tests {
+		if got := lastExchangesStart(history, tt.n); got != tt.want {
+			t.Errorf("lastExchangesStart(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
@@ -3,8 +3,28 @@ package chat
 import (
 	"crypto/rand"
 	"math/big"
+	"regexp"
 )
 
+// codeBlockRegex matches a markdown code block; group 1 is the optional
+// language and group 2 the code
+var codeBlockRegex = regexp.MustCompile("(?s)```([a-zA-Z0-9+#\\-\\.]*)\\s*?\\n(.*?)\\n?```")
+
+// codeBlock is a markdown code block and its fence language
+type codeBlock struct {
+	Language string
+	Code     string
+}
+
+// codeBlocks returns every markdown code block in text, in order
+func codeBlocks(text string) []codeBlock {
+	var blocks []codeBlock
+	for _, match := range codeBlockRegex.FindAllStringSubmatch(text, -1) {
+		blocks = append(blocks, codeBlock{Language: match[1], Code: match[2]})
+	}
+	return blocks
+}
+
 // GenerateHashFromContent creates a random hash using characters from the content
 func GenerateHashFromContent(content string, length int) string {
 	return GenerateHashFromContentWithOffset(content, length, 0)
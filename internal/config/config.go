@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -51,6 +52,31 @@ func loadConfigFromFile() (*types.Config, error) {
 		"save_all_sessions",
 		"show_thinking",
 		"ai_name_enable",
+		"injection_check",
+		"injection_neutralize",
+		"suggest_followups",
+		"show_logprobs",
+		"workspaces",
+		"shell_interpolation",
+		"duplicate_prompt_check",
+		"provider_storage_opt_out",
+		"show_model_annotation",
+		"show_response_stats",
+		"number_code_blocks",
+		"render_tables",
+		"follow_symlinks",
+		"include_submodules",
+		"respect_gitignore",
+		"time_context",
+		"tabular_summary",
+		"tools",
+		"file_metadata",
+		"reload_diff",
+		"usage_log",
+		"low_bandwidth",
+		"warmup",
+		"web_search_deep",
+		"memory",
 	} {
 		if _, ok := raw[key]; ok {
 			config.ExplicitBoolFields[key] = true
@@ -66,6 +92,8 @@ func boolFieldSet(cfg *types.Config, key string) bool {
 
 // mergeConfigs merges user config with default config, user config takes precedence
 func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
+	defaultKeys := commandKeys(defaultConfig)
+
 	if userConfig.DefaultModel != "" {
 		defaultConfig.DefaultModel = userConfig.DefaultModel
 		// If current_model isn't explicitly set in user config, use the default_model
@@ -79,6 +107,15 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 	if userConfig.SystemPrompt != "" {
 		defaultConfig.SystemPrompt = userConfig.SystemPrompt
 	}
+	if userConfig.DeveloperPrompt != "" {
+		defaultConfig.DeveloperPrompt = userConfig.DeveloperPrompt
+	}
+	if boolFieldSet(userConfig, "time_context") || userConfig.TimeContext {
+		defaultConfig.TimeContext = userConfig.TimeContext
+	}
+	if userConfig.DeveloperRolePlatforms != nil {
+		defaultConfig.DeveloperRolePlatforms = userConfig.DeveloperRolePlatforms
+	}
 	if userConfig.ExitKey != "" {
 		defaultConfig.ExitKey = userConfig.ExitKey
 	}
@@ -103,6 +140,9 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 	if userConfig.WebSearch != "" {
 		defaultConfig.WebSearch = userConfig.WebSearch
 	}
+	if userConfig.DeepSearch != "" {
+		defaultConfig.DeepSearch = userConfig.DeepSearch
+	}
 	if userConfig.NumSearchResults != 0 {
 		defaultConfig.NumSearchResults = userConfig.NumSearchResults
 	}
@@ -115,6 +155,9 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 	if userConfig.ScrapeURL != "" {
 		defaultConfig.ScrapeURL = userConfig.ScrapeURL
 	}
+	if userConfig.ScrapeFormat != "" {
+		defaultConfig.ScrapeFormat = userConfig.ScrapeFormat
+	}
 	if userConfig.CopyToClipboard != "" {
 		defaultConfig.CopyToClipboard = userConfig.CopyToClipboard
 	}
@@ -133,6 +176,87 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 	if userConfig.AllModels != "" {
 		defaultConfig.AllModels = userConfig.AllModels
 	}
+	if userConfig.RateSession != "" {
+		defaultConfig.RateSession = userConfig.RateSession
+	}
+	if userConfig.TagExchange != "" {
+		defaultConfig.TagExchange = userConfig.TagExchange
+	}
+	if userConfig.AddNote != "" {
+		defaultConfig.AddNote = userConfig.AddNote
+	}
+	if userConfig.EditStopSequences != "" {
+		defaultConfig.EditStopSequences = userConfig.EditStopSequences
+	}
+	if userConfig.EditRedactions != "" {
+		defaultConfig.EditRedactions = userConfig.EditRedactions
+	}
+	if userConfig.BigFile != "" {
+		defaultConfig.BigFile = userConfig.BigFile
+	}
+	if userConfig.Retrieve != "" {
+		defaultConfig.Retrieve = userConfig.Retrieve
+	}
+	if userConfig.ApplyPatches != "" {
+		defaultConfig.ApplyPatches = userConfig.ApplyPatches
+	}
+	if userConfig.EditFile != "" {
+		defaultConfig.EditFile = userConfig.EditFile
+	}
+	if userConfig.Remember != "" {
+		defaultConfig.Remember = userConfig.Remember
+	}
+	if userConfig.Memories != "" {
+		defaultConfig.Memories = userConfig.Memories
+	}
+	if userConfig.ClipboardHistory != "" {
+		defaultConfig.ClipboardHistory = userConfig.ClipboardHistory
+	}
+	if userConfig.LiveFiles != "" {
+		defaultConfig.LiveFiles = userConfig.LiveFiles
+	}
+	if userConfig.LockModel != "" {
+		defaultConfig.LockModel = userConfig.LockModel
+	}
+	if userConfig.UnlockModel != "" {
+		defaultConfig.UnlockModel = userConfig.UnlockModel
+	}
+	if userConfig.ResetTerminal != "" {
+		defaultConfig.ResetTerminal = userConfig.ResetTerminal
+	}
+	if userConfig.AddBookmark != "" {
+		defaultConfig.AddBookmark = userConfig.AddBookmark
+	}
+	if userConfig.ListBookmarks != "" {
+		defaultConfig.ListBookmarks = userConfig.ListBookmarks
+	}
+	if userConfig.ShowLog != "" {
+		defaultConfig.ShowLog = userConfig.ShowLog
+	}
+	if userConfig.ReplayTo != "" {
+		defaultConfig.ReplayTo = userConfig.ReplayTo
+	}
+	if userConfig.UseTemplate != "" {
+		defaultConfig.UseTemplate = userConfig.UseTemplate
+	}
+	if userConfig.ResponseActions != "" {
+		defaultConfig.ResponseActions = userConfig.ResponseActions
+	}
+	if userConfig.SaveCodeBlock != "" {
+		defaultConfig.SaveCodeBlock = userConfig.SaveCodeBlock
+	}
+	if userConfig.EditHeaders != "" {
+		defaultConfig.EditHeaders = userConfig.EditHeaders
+	}
+	if userConfig.RemoteLoad != "" {
+		defaultConfig.RemoteLoad = userConfig.RemoteLoad
+	}
+	if userConfig.LoadRows != "" {
+		defaultConfig.LoadRows = userConfig.LoadRows
+	}
+	if userConfig.DBQuery != "" {
+		defaultConfig.DBQuery = userConfig.DBQuery
+	}
 	if userConfig.CodeDump != "" {
 		defaultConfig.CodeDump = userConfig.CodeDump
 	}
@@ -175,11 +299,17 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 	if boolFieldSet(userConfig, "save_all_sessions") || userConfig.SaveAllSessions {
 		defaultConfig.SaveAllSessions = userConfig.SaveAllSessions
 	}
+	if userConfig.AutosaveInterval != 0 {
+		defaultConfig.AutosaveInterval = userConfig.AutosaveInterval
+	}
 
 	// Merge ShallowLoadDirs if provided
 	if userConfig.ShallowLoadDirs != nil {
 		defaultConfig.ShallowLoadDirs = userConfig.ShallowLoadDirs
 	}
+	if userConfig.ShallowLoadDepths != nil {
+		defaultConfig.ShallowLoadDepths = userConfig.ShallowLoadDepths
+	}
 
 	// Merge SlowModelPatterns if provided
 	if userConfig.SlowModelPatterns != nil {
@@ -202,6 +332,247 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 		defaultConfig.AINamePrompt = userConfig.AINamePrompt
 	}
 
+	if boolFieldSet(userConfig, "injection_check") {
+		defaultConfig.InjectionCheck = userConfig.InjectionCheck
+	}
+	if boolFieldSet(userConfig, "injection_neutralize") || userConfig.InjectionNeutralize {
+		defaultConfig.InjectionNeutralize = userConfig.InjectionNeutralize
+	}
+	if userConfig.ScrapeCookieFile != "" {
+		defaultConfig.ScrapeCookieFile = userConfig.ScrapeCookieFile
+	}
+	if userConfig.ScrapeCookieBrowser != "" {
+		defaultConfig.ScrapeCookieBrowser = userConfig.ScrapeCookieBrowser
+	}
+	if userConfig.ScrapeParallel != 0 {
+		defaultConfig.ScrapeParallel = userConfig.ScrapeParallel
+	}
+	if userConfig.BraveMonthlyQuota != 0 {
+		defaultConfig.BraveMonthlyQuota = userConfig.BraveMonthlyQuota
+	}
+	if userConfig.BraveQuotaWarnPercent != 0 {
+		defaultConfig.BraveQuotaWarnPercent = userConfig.BraveQuotaWarnPercent
+	}
+	if userConfig.SearchFallback != "" {
+		defaultConfig.SearchFallback = userConfig.SearchFallback
+	}
+	if userConfig.SearchBackend != "" {
+		defaultConfig.SearchBackend = userConfig.SearchBackend
+	}
+	if userConfig.SearXNGURL != "" {
+		defaultConfig.SearXNGURL = userConfig.SearXNGURL
+	}
+	if boolFieldSet(userConfig, "web_search_deep") || userConfig.WebSearchDeep {
+		defaultConfig.WebSearchDeep = userConfig.WebSearchDeep
+	}
+	if userConfig.DeepSearchResults != 0 {
+		defaultConfig.DeepSearchResults = userConfig.DeepSearchResults
+	}
+	if userConfig.DeepSearchTokens != 0 {
+		defaultConfig.DeepSearchTokens = userConfig.DeepSearchTokens
+	}
+	if boolFieldSet(userConfig, "suggest_followups") || userConfig.SuggestFollowups {
+		defaultConfig.SuggestFollowups = userConfig.SuggestFollowups
+	}
+	if userConfig.FollowupCount != 0 {
+		defaultConfig.FollowupCount = userConfig.FollowupCount
+	}
+	if userConfig.StopSequences != nil {
+		defaultConfig.StopSequences = userConfig.StopSequences
+	}
+	if userConfig.Seed != nil {
+		defaultConfig.Seed = userConfig.Seed
+	}
+	if boolFieldSet(userConfig, "show_logprobs") || userConfig.ShowLogprobs {
+		defaultConfig.ShowLogprobs = userConfig.ShowLogprobs
+	}
+	if userConfig.TopLogprobs != 0 {
+		defaultConfig.TopLogprobs = userConfig.TopLogprobs
+	}
+	if userConfig.EmbeddingModel != "" {
+		defaultConfig.EmbeddingModel = userConfig.EmbeddingModel
+	}
+	if userConfig.EmbeddingBatchSize != 0 {
+		defaultConfig.EmbeddingBatchSize = userConfig.EmbeddingBatchSize
+	}
+	if userConfig.EmbeddingRPM != 0 {
+		defaultConfig.EmbeddingRPM = userConfig.EmbeddingRPM
+	}
+	if userConfig.BigFileChunkTokens != 0 {
+		defaultConfig.BigFileChunkTokens = userConfig.BigFileChunkTokens
+	}
+	if userConfig.BigFileTopK != 0 {
+		defaultConfig.BigFileTopK = userConfig.BigFileTopK
+	}
+	if userConfig.IndexChunkTokens != 0 {
+		defaultConfig.IndexChunkTokens = userConfig.IndexChunkTokens
+	}
+	if userConfig.IndexTopK != 0 {
+		defaultConfig.IndexTopK = userConfig.IndexTopK
+	}
+	if boolFieldSet(userConfig, "memory") || userConfig.Memory {
+		defaultConfig.Memory = userConfig.Memory
+	}
+	if userConfig.MemoryTopK != 0 {
+		defaultConfig.MemoryTopK = userConfig.MemoryTopK
+	}
+	if userConfig.SummarizeChunkTokens != 0 {
+		defaultConfig.SummarizeChunkTokens = userConfig.SummarizeChunkTokens
+	}
+	if userConfig.SummarizeOverlapTokens != 0 {
+		defaultConfig.SummarizeOverlapTokens = userConfig.SummarizeOverlapTokens
+	}
+	if userConfig.SummarizeParallel != 0 {
+		defaultConfig.SummarizeParallel = userConfig.SummarizeParallel
+	}
+	if userConfig.Moderation != "" {
+		defaultConfig.Moderation = userConfig.Moderation
+	}
+	if userConfig.ModerationModel != "" {
+		defaultConfig.ModerationModel = userConfig.ModerationModel
+	}
+	if userConfig.ModerationURL != "" {
+		defaultConfig.ModerationURL = userConfig.ModerationURL
+	}
+	if userConfig.Redactions != nil {
+		defaultConfig.Redactions = userConfig.Redactions
+	}
+	if userConfig.CompressModel != "" {
+		defaultConfig.CompressModel = userConfig.CompressModel
+	}
+	if userConfig.CompressThreshold != 0 {
+		defaultConfig.CompressThreshold = userConfig.CompressThreshold
+	}
+	if userConfig.ContextBudget != 0 {
+		defaultConfig.ContextBudget = userConfig.ContextBudget
+	}
+	if userConfig.MaxMessageChars != 0 {
+		defaultConfig.MaxMessageChars = userConfig.MaxMessageChars
+	}
+	if userConfig.AutoModelRoutes != nil {
+		defaultConfig.AutoModelRoutes = userConfig.AutoModelRoutes
+	}
+	if boolFieldSet(userConfig, "workspaces") || userConfig.Workspaces {
+		defaultConfig.Workspaces = userConfig.Workspaces
+	}
+	if userConfig.Profiles != nil {
+		defaultConfig.Profiles = userConfig.Profiles
+	}
+	if userConfig.ClipboardHistorySize != 0 {
+		defaultConfig.ClipboardHistorySize = userConfig.ClipboardHistorySize
+	}
+	if userConfig.RecentLoadsSize != 0 {
+		defaultConfig.RecentLoadsSize = userConfig.RecentLoadsSize
+	}
+	if userConfig.StorageBackend != "" {
+		defaultConfig.StorageBackend = userConfig.StorageBackend
+	}
+	if userConfig.MentionConfirmFiles != 0 {
+		defaultConfig.MentionConfirmFiles = userConfig.MentionConfirmFiles
+	}
+	if userConfig.MentionConfirmBytes != 0 {
+		defaultConfig.MentionConfirmBytes = userConfig.MentionConfirmBytes
+	}
+	if boolFieldSet(userConfig, "shell_interpolation") || userConfig.ShellInterpolation {
+		defaultConfig.ShellInterpolation = userConfig.ShellInterpolation
+	}
+	if userConfig.ShellInterpolationMaxBytes != 0 {
+		defaultConfig.ShellInterpolationMaxBytes = userConfig.ShellInterpolationMaxBytes
+	}
+	if boolFieldSet(userConfig, "duplicate_prompt_check") || userConfig.DuplicatePromptCheck {
+		defaultConfig.DuplicatePromptCheck = userConfig.DuplicatePromptCheck
+	}
+	if userConfig.ModelPrices != nil {
+		defaultConfig.ModelPrices = userConfig.ModelPrices
+	}
+	if userConfig.MaxSessionCost != 0 {
+		defaultConfig.MaxSessionCost = userConfig.MaxSessionCost
+	}
+	if userConfig.MaxDailyCost != 0 {
+		defaultConfig.MaxDailyCost = userConfig.MaxDailyCost
+	}
+	if userConfig.CostLimitAction != "" {
+		defaultConfig.CostLimitAction = userConfig.CostLimitAction
+	}
+	if boolFieldSet(userConfig, "usage_log") || userConfig.UsageLog {
+		defaultConfig.UsageLog = userConfig.UsageLog
+	}
+	if boolFieldSet(userConfig, "provider_storage_opt_out") || userConfig.ProviderStorageOptOut {
+		defaultConfig.ProviderStorageOptOut = userConfig.ProviderStorageOptOut
+	}
+	if userConfig.StorageOptOutHeaders != nil {
+		defaultConfig.StorageOptOutHeaders = userConfig.StorageOptOutHeaders
+	}
+	if userConfig.StorageOptOutParams != nil {
+		defaultConfig.StorageOptOutParams = userConfig.StorageOptOutParams
+	}
+	if userConfig.ExtraHeaders != nil {
+		defaultConfig.ExtraHeaders = userConfig.ExtraHeaders
+	}
+	if userConfig.ExtraBody != nil {
+		defaultConfig.ExtraBody = userConfig.ExtraBody
+	}
+	if userConfig.RateLimits != nil {
+		defaultConfig.RateLimits = userConfig.RateLimits
+	}
+	if userConfig.MockFixtures != "" {
+		defaultConfig.MockFixtures = userConfig.MockFixtures
+	}
+	if userConfig.ModelCapabilities != nil {
+		defaultConfig.ModelCapabilities = userConfig.ModelCapabilities
+	}
+	if boolFieldSet(userConfig, "show_model_annotation") || userConfig.ShowModelAnnotation {
+		defaultConfig.ShowModelAnnotation = userConfig.ShowModelAnnotation
+	}
+	if boolFieldSet(userConfig, "show_response_stats") || userConfig.ShowResponseStats {
+		defaultConfig.ShowResponseStats = userConfig.ShowResponseStats
+	}
+	if boolFieldSet(userConfig, "number_code_blocks") || userConfig.NumberCodeBlocks {
+		defaultConfig.NumberCodeBlocks = userConfig.NumberCodeBlocks
+	}
+	if boolFieldSet(userConfig, "render_tables") || userConfig.RenderTables {
+		defaultConfig.RenderTables = userConfig.RenderTables
+	}
+	if boolFieldSet(userConfig, "follow_symlinks") || userConfig.FollowSymlinks {
+		defaultConfig.FollowSymlinks = userConfig.FollowSymlinks
+	}
+	if boolFieldSet(userConfig, "include_submodules") || userConfig.IncludeSubmodules {
+		defaultConfig.IncludeSubmodules = userConfig.IncludeSubmodules
+	}
+	if userConfig.MaxWalkDepth != 0 {
+		defaultConfig.MaxWalkDepth = userConfig.MaxWalkDepth
+	}
+	if boolFieldSet(userConfig, "respect_gitignore") || userConfig.RespectGitignore {
+		defaultConfig.RespectGitignore = userConfig.RespectGitignore
+	}
+	if userConfig.MaxWalkFileBytes != 0 {
+		defaultConfig.MaxWalkFileBytes = userConfig.MaxWalkFileBytes
+	}
+	if boolFieldSet(userConfig, "file_metadata") || userConfig.FileMetadata {
+		defaultConfig.FileMetadata = userConfig.FileMetadata
+	}
+	if boolFieldSet(userConfig, "reload_diff") || userConfig.ReloadDiff {
+		defaultConfig.ReloadDiff = userConfig.ReloadDiff
+	}
+	if boolFieldSet(userConfig, "tools") || userConfig.Tools {
+		defaultConfig.Tools = userConfig.Tools
+	}
+	if userConfig.StructuredSelectBytes != 0 {
+		defaultConfig.StructuredSelectBytes = userConfig.StructuredSelectBytes
+	}
+	if boolFieldSet(userConfig, "tabular_summary") || userConfig.TabularSummary {
+		defaultConfig.TabularSummary = userConfig.TabularSummary
+	}
+	if boolFieldSet(userConfig, "low_bandwidth") || userConfig.LowBandwidth {
+		defaultConfig.LowBandwidth = userConfig.LowBandwidth
+	}
+	if userConfig.LowBandwidthInterval != 0 {
+		defaultConfig.LowBandwidthInterval = userConfig.LowBandwidthInterval
+	}
+	if boolFieldSet(userConfig, "warmup") || userConfig.Warmup {
+		defaultConfig.Warmup = userConfig.Warmup
+	}
+
 	// Merge platforms if provided
 	if userConfig.Platforms != nil {
 		for name, platform := range userConfig.Platforms {
@@ -209,11 +580,77 @@ func mergeConfigs(defaultConfig, userConfig *types.Config) *types.Config {
 		}
 	}
 
+	defaultConfig.KeyConflicts = commandKeyConflicts(commandKeys(defaultConfig), defaultKeys)
 	return defaultConfig
 }
 
+// SaveUserConfigField sets one top-level key of ~/.ch/config.json to value,
+// keeping the values of the other keys. A nil value removes the key.
+func SaveUserConfigField(key string, value any) error {
+	chDir, err := GetChDir()
+	if err != nil {
+		return err
+	}
+	configPath := filepath.Join(chDir, "config.json")
+
+	raw := map[string]json.RawMessage{}
+	data, err := os.ReadFile(configPath) // #nosec G304 -- Config path is resolved under the current user's home directory.
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", configPath, err)
+		}
+	}
+
+	if value == nil {
+		delete(raw, key)
+	} else {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw[key] = encoded
+	}
+
+	data, err = json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, append(data, '\n'), 0600)
+}
+
 // DefaultConfig returns the default configuration merged with user config from config.json
 func DefaultConfig() *types.Config {
+	defaultConfig := BuiltinDefaults()
+
+	// Load user config from config.json and merge with defaults
+	userConfig, err := loadConfigFromFile()
+	if err == nil {
+		defaultConfig = mergeConfigs(defaultConfig, userConfig)
+	}
+
+	// Workspace defaults sit between the config file and environment variables
+	if defaultConfig.Workspaces {
+		applyWorkspaceSettings(defaultConfig)
+	}
+
+	// Override with environment variables, giving them higher precedence
+	if platformEnv := os.Getenv("CH_DEFAULT_PLATFORM"); platformEnv != "" {
+		defaultConfig.CurrentPlatform = platformEnv
+	}
+	if modelEnv := os.Getenv("CH_DEFAULT_MODEL"); modelEnv != "" {
+		defaultConfig.CurrentModel = modelEnv
+		defaultConfig.DefaultModel = modelEnv
+	}
+
+	return defaultConfig
+}
+
+// BuiltinDefaults returns the hardcoded default configuration, without
+// config.json, workspace settings, or environment overrides
+func BuiltinDefaults() *types.Config {
 	// Get home directory for default shallow load dirs
 	homeDir, _ := os.UserHomeDir()
 	// Include common parent directories that are typically large and high up in the filesystem
@@ -235,8 +672,7 @@ func DefaultConfig() *types.Config {
 		shallowDirs = append(shallowDirs, homeDir)
 	}
 
-	// Start with hardcoded defaults
-	defaultConfig := &types.Config{
+	return &types.Config{
 		OpenAIAPIKey:      "", // API keys are fetched per-platform in Initialize()
 		DefaultModel:      "gpt-5.4-mini",
 		CurrentModel:      "gpt-5.4-mini",
@@ -249,17 +685,46 @@ func DefaultConfig() *types.Config {
 		ExportChat:        "!e",
 		Backtrack:         "!b",
 		WebSearch:         "!w",
+		DeepSearch:        "!w!",
 		ShowSearchResults: true,
 		NumSearchResults:  5,
 		SearchCountry:     "us",
 		SearchLang:        "en",
 		ScrapeURL:         "!s",
+		ScrapeFormat:      "text",
 		CopyToClipboard:   "!y",
 		QuickCopyLatest:   "cc",
 		LoadFiles:         "!l",
 		AnswerSearch:      "!a",
 		PlatformSwitch:    "!p",
 		AllModels:         "!o",
+		RateSession:       "!r",
+		TagExchange:       "!tag",
+		AddNote:           "!note",
+		EditStopSequences: "!stopseq",
+		EditRedactions:    "!redact",
+		BigFile:           "!bigfile",
+		Retrieve:          "!rag",
+		ApplyPatches:      "!apply",
+		EditFile:          "!edit",
+		Remember:          "!remember",
+		Memories:          "!memories",
+		ClipboardHistory:  "!yh",
+		LiveFiles:         "!live",
+		LockModel:         "!lock",
+		UnlockModel:       "!unlock",
+		ResetTerminal:     "!reset",
+		AddBookmark:       "!mark",
+		ListBookmarks:     "!marks",
+		ShowLog:           "!log",
+		ReplayTo:          "!replay-to",
+		UseTemplate:       "!tm",
+		ResponseActions:   ".",
+		SaveCodeBlock:     "!save",
+		EditHeaders:       "!headers",
+		RemoteLoad:        "!remote",
+		LoadRows:          "!rows",
+		DBQuery:           "!db",
 		CodeDump:          "!d",
 		ShellRecord:       "!x",
 		ShellOption:       "!",
@@ -270,6 +735,7 @@ func DefaultConfig() *types.Config {
 		MuteNotifications: false,
 		ShowThinking:      true,
 		EnableSessionSave: false,
+		AutosaveInterval:  0,
 		ShallowLoadDirs:   shallowDirs,
 
 		AINameEnable:         false,
@@ -287,6 +753,87 @@ func DefaultConfig() *types.Config {
 			"```text\nhello_world\napi_request_handler\nparse_json\n```\n\n" +
 			"Do not include any text before or after the code block.",
 
+		InjectionCheck:      true,
+		InjectionNeutralize: false,
+
+		ScrapeParallel: 4,
+
+		DeveloperRolePlatforms: []string{"openai"},
+
+		BraveQuotaWarnPercent: 80,
+		SearchBackend:         "brave",
+
+		WebSearchDeep:     false,
+		DeepSearchResults: 3,
+		DeepSearchTokens:  6000,
+
+		SuggestFollowups: false,
+		FollowupCount:    3,
+
+		ShowLogprobs: false,
+		TopLogprobs:  5,
+
+		EmbeddingModel:     "text-embedding-3-small",
+		EmbeddingBatchSize: 100,
+		EmbeddingRPM:       0,
+
+		BigFileChunkTokens: 800,
+		BigFileTopK:        4,
+
+		IndexChunkTokens: 400,
+		IndexTopK:        6,
+
+		Memory:     false,
+		MemoryTopK: 5,
+
+		SummarizeChunkTokens:   6000,
+		SummarizeOverlapTokens: 200,
+		SummarizeParallel:      4,
+
+		CompressThreshold: 8000,
+		MaxMessageChars:   1000000,
+
+		ClipboardHistorySize: 20,
+
+		RecentLoadsSize: 10,
+
+		StorageBackend: "json",
+
+		MentionConfirmFiles: 20,
+		MentionConfirmBytes: 200000,
+
+		ShellInterpolation:         false,
+		ShellInterpolationMaxBytes: 20000,
+
+		DuplicatePromptCheck: true,
+
+		CostLimitAction: "confirm",
+		UsageLog:        true,
+
+		ShowModelAnnotation: true,
+		ShowResponseStats:   false,
+		NumberCodeBlocks:    true,
+		RenderTables:        true,
+
+		FollowSymlinks:    false,
+		IncludeSubmodules: true,
+		MaxWalkDepth:      32,
+		RespectGitignore:  true,
+
+		StructuredSelectBytes: 32768,
+
+		FileMetadata: true,
+		ReloadDiff:   true,
+
+		LowBandwidth:         false,
+		LowBandwidthInterval: 500,
+
+		Warmup: false,
+
+		Moderation:      "off",
+		ModerationModel: "omni-moderation-latest",
+		ModerationURL:   "https://api.openai.com/v1",
+
 		Platforms: map[string]types.Platform{
 			"groq": {
 				Name:    "groq",
@@ -342,6 +889,16 @@ func DefaultConfig() *types.Config {
 					JSONPath: "models.name",
 				},
 			},
+			"azure": {
+				Name:       "azure",
+				EnvName:    "AZURE_OPENAI_API_KEY",
+				Driver:     "azure",
+				APIVersion: "2024-10-21",
+			},
+			"mock": {
+				Name:    "mock",
+				BaseURL: types.BaseURLValue{Single: "http://mock.invalid/v1"},
+			},
 			"together": {
 				Name:    "together",
 				BaseURL: types.BaseURLValue{Single: "https://api.together.ai/v1"},
@@ -353,8 +910,9 @@ func DefaultConfig() *types.Config {
 			},
 			"google": {
 				Name:    "google",
-				BaseURL: types.BaseURLValue{Single: "https://generativelanguage.googleapis.com/v1beta/openai/"},
+				BaseURL: types.BaseURLValue{Single: "https://generativelanguage.googleapis.com/v1beta"},
 				EnvName: "GEMINI_API_KEY",
+				Driver:  "gemini",
 				Models: types.PlatformModels{
 					URL:      "https://generativelanguage.googleapis.com/v1beta/models",
 					JSONPath: "models.name",
@@ -405,23 +963,16 @@ func DefaultConfig() *types.Config {
 			},
 		},
 	}
+}
 
-	// Load user config from config.json and merge with defaults
-	userConfig, err := loadConfigFromFile()
-	if err == nil {
-		defaultConfig = mergeConfigs(defaultConfig, userConfig)
-	}
-
-	// Override with environment variables, giving them higher precedence
-	if platformEnv := os.Getenv("CH_DEFAULT_PLATFORM"); platformEnv != "" {
-		defaultConfig.CurrentPlatform = platformEnv
-	}
-	if modelEnv := os.Getenv("CH_DEFAULT_MODEL"); modelEnv != "" {
-		defaultConfig.CurrentModel = modelEnv
-		defaultConfig.DefaultModel = modelEnv
-	}
-
-	return defaultConfig
+// InitialMessages returns the messages every conversation starts with: the
+// system prompt, followed by developer_prompt as a "developer" message when set
+func InitialMessages(cfg *types.Config) []types.ChatMessage {
+	messages := []types.ChatMessage{{Role: "system", Content: cfg.SystemPrompt}}
+	if cfg.DeveloperPrompt != "" {
+		messages = append(messages, types.ChatMessage{Role: "developer", Content: cfg.DeveloperPrompt})
+	}
+	return messages
 }
 
 // InitializeAppState creates and returns initial application state
@@ -429,10 +980,8 @@ func InitializeAppState() *types.AppState {
 	config := DefaultConfig()
 
 	return &types.AppState{
-		Config: config,
-		Messages: []types.ChatMessage{
-			{Role: "system", Content: config.SystemPrompt},
-		},
+		Config:   config,
+		Messages: InitialMessages(config),
 		ChatHistory: []types.ChatHistory{
 			{Time: time.Now().Unix(), User: config.SystemPrompt, Bot: ""},
 		},
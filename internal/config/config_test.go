@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/MehmetMHY/ch/pkg/types"
@@ -122,6 +123,103 @@ func TestMergeConfigs_AINameFields(t *testing.T) {
 	}
 }
 
+func TestMergeConfigs_InjectionFields(t *testing.T) {
+	def := &types.Config{
+		InjectionCheck:      true,
+		InjectionNeutralize: false,
+		Platforms:           map[string]types.Platform{},
+	}
+
+	// Unset keys keep the defaults
+	merged := mergeConfigs(def, &types.Config{CurrentPlatform: "openai"})
+	if !merged.InjectionCheck {
+		t.Error("InjectionCheck should stay true when not set in user config")
+	}
+
+	user := &types.Config{
+		InjectionCheck:      false,
+		InjectionNeutralize: true,
+		ExplicitBoolFields:  map[string]bool{"injection_check": true, "injection_neutralize": true},
+	}
+	merged = mergeConfigs(def, user)
+	if merged.InjectionCheck {
+		t.Error("InjectionCheck should be false when explicitly disabled")
+	}
+	if !merged.InjectionNeutralize {
+		t.Error("InjectionNeutralize should be true when explicitly enabled")
+	}
+}
+
+func TestMergeConfigs_SearchQuotaFields(t *testing.T) {
+	def := &types.Config{
+		BraveQuotaWarnPercent: 80,
+		Platforms:             map[string]types.Platform{},
+	}
+
+	merged := mergeConfigs(def, &types.Config{})
+	if merged.BraveMonthlyQuota != 0 || merged.BraveQuotaWarnPercent != 80 || merged.SearchFallback != "" {
+		t.Errorf("unset search quota fields should keep defaults, got %+v", merged)
+	}
+
+	user := &types.Config{
+		BraveMonthlyQuota:     2000,
+		BraveQuotaWarnPercent: 90,
+		SearchFallback:        "duckduckgo",
+	}
+	merged = mergeConfigs(def, user)
+	if merged.BraveMonthlyQuota != 2000 {
+		t.Errorf("BraveMonthlyQuota = %d, want 2000", merged.BraveMonthlyQuota)
+	}
+	if merged.BraveQuotaWarnPercent != 90 {
+		t.Errorf("BraveQuotaWarnPercent = %d, want 90", merged.BraveQuotaWarnPercent)
+	}
+	if merged.SearchFallback != "duckduckgo" {
+		t.Errorf("SearchFallback = %q, want duckduckgo", merged.SearchFallback)
+	}
+}
+
+func TestMergeConfigs_FollowupFields(t *testing.T) {
+	def := &types.Config{
+		SuggestFollowups: false,
+		FollowupCount:    3,
+		Platforms:        map[string]types.Platform{},
+	}
+
+	merged := mergeConfigs(def, &types.Config{})
+	if merged.SuggestFollowups || merged.FollowupCount != 3 {
+		t.Errorf("unset follow-up fields should keep defaults, got %v/%d", merged.SuggestFollowups, merged.FollowupCount)
+	}
+
+	user := &types.Config{
+		SuggestFollowups:   true,
+		FollowupCount:      2,
+		ExplicitBoolFields: map[string]bool{"suggest_followups": true},
+	}
+	merged = mergeConfigs(def, user)
+	if !merged.SuggestFollowups || merged.FollowupCount != 2 {
+		t.Errorf("follow-up fields should be overridden, got %v/%d", merged.SuggestFollowups, merged.FollowupCount)
+	}
+}
+
+func TestMergeConfigs_EmbeddingFields(t *testing.T) {
+	def := &types.Config{
+		EmbeddingModel:     "text-embedding-3-small",
+		EmbeddingBatchSize: 100,
+		Platforms:          map[string]types.Platform{},
+	}
+
+	merged := mergeConfigs(def, &types.Config{})
+	if merged.EmbeddingModel != "text-embedding-3-small" || merged.EmbeddingBatchSize != 100 || merged.EmbeddingRPM != 0 {
+		t.Errorf("unset embedding fields should keep defaults, got %+v", merged)
+	}
+
+	user := &types.Config{EmbeddingModel: "nomic-embed-text", EmbeddingBatchSize: 16, EmbeddingRPM: 60}
+	merged = mergeConfigs(def, user)
+	if merged.EmbeddingModel != "nomic-embed-text" || merged.EmbeddingBatchSize != 16 || merged.EmbeddingRPM != 60 {
+		t.Errorf("embedding fields should be overridden, got %q/%d/%d", merged.EmbeddingModel, merged.EmbeddingBatchSize, merged.EmbeddingRPM)
+	}
+}
+
 func TestMergeConfigs_ShowSearchResultsAndMuteNotifications(t *testing.T) {
 	def := &types.Config{
 		ShowSearchResults: true,
@@ -388,7 +486,7 @@ func TestDefaultConfig_NoConfigFile(t *testing.T) {
 		t.Errorf("NumSearchResults should be positive, got %d", cfg.NumSearchResults)
 	}
 	// All builtin platforms must be present
-	for _, p := range []string{"groq", "openrouter", "deepseek", "anthropic", "xai", "ollama", "together", "google", "mistral", "amazon"} {
+	for _, p := range []string{"groq", "openrouter", "deepseek", "anthropic", "xai", "ollama", "together", "google", "mistral", "amazon", "mock"} {
 		if _, ok := cfg.Platforms[p]; !ok {
 			t.Errorf("platform %q missing from default config", p)
 		}
@@ -442,3 +540,41 @@ func TestInitializeAppState(t *testing.T) {
 		t.Error("IsExecutingCommand should default to false")
 	}
 }
+
+func TestSaveUserConfigFieldKeepsOtherKeys(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+	chDir := filepath.Join(tempHome, ".ch")
+	if err := os.MkdirAll(chDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(chDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"current_platform": "groq", "custom_note": [1, 2]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]map[string]string{"groq": {"X-Gateway": "a"}}
+	if err := SaveUserConfigField("extra_headers", headers); err != nil {
+		t.Fatalf("SaveUserConfigField() error: %v", err)
+	}
+	var saved map[string]any
+	data, _ := os.ReadFile(configPath)
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved config is not JSON: %v", err)
+	}
+	if saved["current_platform"] != "groq" || saved["custom_note"] == nil || saved["extra_headers"] == nil {
+		t.Errorf("saved config = %v", saved)
+	}
+	if cfg := DefaultConfig(); cfg.ExtraHeaders["groq"]["X-Gateway"] != "a" {
+		t.Errorf("extra_headers not loaded back: %v", cfg.ExtraHeaders)
+	}
+
+	if err := SaveUserConfigField("extra_headers", nil); err != nil {
+		t.Fatalf("SaveUserConfigField(nil) error: %v", err)
+	}
+	data, _ = os.ReadFile(configPath)
+	if strings.Contains(string(data), "extra_headers") {
+		t.Errorf("nil should remove the key: %s", data)
+	}
+}
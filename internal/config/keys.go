@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// commandKey is a command key and the config.json setting it comes from
+type commandKey struct {
+	setting string
+	key     string
+}
+
+// reservedInputs are inputs main handles before or besides the configured
+// keys, so no key may use them
+var reservedInputs = map[string]string{
+	"help": "the help_key alias",
+	"!!":   "the unrecorded shell shortcut",
+}
+
+// saveShortcutPattern matches the !s1, !s2, ... save shortcuts main accepts
+// alongside save_code_block
+var saveShortcutPattern = regexp.MustCompile(`^!s\d+$`)
+
+// commandKeys returns the configured command keys
+func commandKeys(cfg *types.Config) []commandKey {
+	return []commandKey{
+		{"exit_key", cfg.ExitKey},
+		{"help_key", cfg.HelpKey},
+		{"clear_history", cfg.ClearHistory},
+		{"backtrack", cfg.Backtrack},
+		{"all_models", cfg.AllModels},
+		{"model_switch", cfg.ModelSwitch},
+		{"platform_switch", cfg.PlatformSwitch},
+		{"shell_record", cfg.ShellRecord},
+		{"shell_record_silent", cfg.ShellRecordSilent},
+		{"shell_option", cfg.ShellOption},
+		{"code_dump", cfg.CodeDump},
+		{"copy_to_clipboard", cfg.CopyToClipboard},
+		{"quick_copy_latest", cfg.QuickCopyLatest},
+		{"clipboard_history", cfg.ClipboardHistory},
+		{"multi_line", cfg.MultiLine},
+		{"export_chat", cfg.ExportChat},
+		{"editor_input", cfg.EditorInput},
+		{"load_files", cfg.LoadFiles},
+		{"scrape_url", cfg.ScrapeURL},
+		{"web_search", cfg.WebSearch},
+		{"deep_search", cfg.DeepSearch},
+		{"answer_search", cfg.AnswerSearch},
+		{"rate_session", cfg.RateSession},
+		{"tag_exchange", cfg.TagExchange},
+		{"add_note", cfg.AddNote},
+		{"edit_stop_sequences", cfg.EditStopSequences},
+		{"edit_redactions", cfg.EditRedactions},
+		{"edit_headers", cfg.EditHeaders},
+		{"big_file", cfg.BigFile},
+		{"retrieve", cfg.Retrieve},
+		{"apply_patches", cfg.ApplyPatches},
+		{"edit_file", cfg.EditFile},
+		{"remember", cfg.Remember},
+		{"memories", cfg.Memories},
+		{"live_files", cfg.LiveFiles},
+		{"remote_load", cfg.RemoteLoad},
+		{"load_rows", cfg.LoadRows},
+		{"db_query", cfg.DBQuery},
+		{"lock_model", cfg.LockModel},
+		{"unlock_model", cfg.UnlockModel},
+		{"reset_terminal", cfg.ResetTerminal},
+		{"add_bookmark", cfg.AddBookmark},
+		{"list_bookmarks", cfg.ListBookmarks},
+		{"show_log", cfg.ShowLog},
+		{"replay_to", cfg.ReplayTo},
+		{"use_template", cfg.UseTemplate},
+		{"response_actions", cfg.ResponseActions},
+		{"save_code_block", cfg.SaveCodeBlock},
+	}
+}
+
+// commandKeyConflicts describes keys that could send input to the wrong
+// handler: keys shared by several settings, keys with whitespace, reserved
+// inputs, and keys that start another key. Prefix pairs the defaults already
+// have, such as !m and !mark, are allowed, and shell_option, the catch-all
+// for shell commands, is left out of prefix checks.
+func commandKeyConflicts(keys, defaults []commandKey) []string {
+	defaultKeys := make(map[string]string)
+	for _, k := range defaults {
+		defaultKeys[k.setting] = k.key
+	}
+
+	var conflicts []string
+	settingsByKey := make(map[string][]string)
+	for _, k := range keys {
+		if k.key == "" {
+			continue
+		}
+		settingsByKey[k.key] = append(settingsByKey[k.key], k.setting)
+		if strings.ContainsAny(k.key, " \t\n") {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q contains whitespace", k.setting, k.key))
+		}
+		if use, ok := reservedInputs[k.key]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q is reserved for %s", k.setting, k.key, use))
+		}
+		if saveShortcutPattern.MatchString(k.key) {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q looks like a !s<n> save shortcut", k.setting, k.key))
+		}
+	}
+
+	var shared []string
+	for key, settings := range settingsByKey {
+		if len(settings) > 1 {
+			shared = append(shared, fmt.Sprintf("%q is used by %s", key, strings.Join(settings, ", ")))
+		}
+	}
+	sort.Strings(shared)
+	conflicts = append(conflicts, shared...)
+
+	for _, a := range keys {
+		for _, b := range keys {
+			if a.setting == "shell_option" || b.setting == "shell_option" || a.key == "" || len(a.key) >= len(b.key) || !strings.HasPrefix(b.key, a.key) {
+				continue
+			}
+			if a.key == defaultKeys[a.setting] && b.key == defaultKeys[b.setting] {
+				continue
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s %q is a prefix of %s %q", a.setting, a.key, b.setting, b.key))
+		}
+	}
+	return conflicts
+}
+
+// CheckCommandKeys reports the command key conflicts found when config.json
+// was merged, one per line, or nil when there are none
+func CheckCommandKeys(cfg *types.Config) error {
+	if len(cfg.KeyConflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("conflicting command keys in ~/.ch/config.json:\n  %s", strings.Join(cfg.KeyConflicts, "\n  "))
+}
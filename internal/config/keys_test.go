@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandKeyConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		user string
+		want []string // substrings of the report, none when empty
+	}{
+		{"defaults", `{}`, nil},
+		{"default prefix pairs stay allowed", `{"model_switch": "!m", "add_bookmark": "!mark"}`, nil},
+		{"duplicate key", `{"scrape_url": "!w"}`, []string{`"!w" is used by scrape_url, web_search`}},
+		{"new prefix pair", `{"model_switch": "!sm"}`, []string{`scrape_url "!s" is a prefix of model_switch "!sm"`}},
+		{"whitespace", `{"show_log": "!log all"}`, []string{`show_log "!log all" contains whitespace`}},
+		{"reserved input", `{"clear_history": "help"}`, []string{`clear_history "help" is reserved`}},
+		{"save shortcut", `{"tag_exchange": "!s2"}`, []string{`tag_exchange "!s2" looks like a !s<n> save shortcut`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempHome := t.TempDir()
+			t.Setenv("HOME", tempHome)
+			t.Setenv("USERPROFILE", tempHome)
+			chDir := filepath.Join(tempHome, ".ch")
+			if err := os.MkdirAll(chDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if !json.Valid([]byte(tt.user)) {
+				t.Fatalf("invalid test config %s", tt.user)
+			}
+			if err := os.WriteFile(filepath.Join(chDir, "config.json"), []byte(tt.user), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := CheckCommandKeys(DefaultConfig())
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("CheckCommandKeys() = %v, want no conflicts", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("CheckCommandKeys() = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("report is missing %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
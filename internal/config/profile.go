@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// ProfileNames lists the profiles defined in config.json
+func ProfileNames(cfg *types.Config) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile applies the persona, search, and safe-mode settings of the
+// named profile to cfg and returns the profile. Its platform and model are
+// left to the caller, which ranks them with -p and -m.
+func ApplyProfile(cfg *types.Config, name string) (types.Profile, error) {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		if len(cfg.Profiles) == 0 {
+			return types.Profile{}, fmt.Errorf("profile %q not found: no profiles in ~/.ch/config.json", name)
+		}
+		return types.Profile{}, fmt.Errorf("profile %q not found (profiles: %s)", name, strings.Join(ProfileNames(cfg), ", "))
+	}
+	if profile.Platform != "" && profile.Platform != "openai" {
+		if _, exists := cfg.Platforms[profile.Platform]; !exists {
+			return types.Profile{}, fmt.Errorf("profile %q: platform '%s' not found", name, profile.Platform)
+		}
+	}
+
+	if profile.SystemPrompt != "" {
+		cfg.SystemPrompt = profile.SystemPrompt
+	}
+	if profile.SearchBackend != "" {
+		cfg.SearchBackend = profile.SearchBackend
+	}
+	if profile.SafeMode {
+		cfg.Tools = false
+		cfg.ShellInterpolation = false
+		cfg.InjectionCheck = true
+		cfg.ProviderStorageOptOut = true
+	}
+	return profile, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestApplyProfile(t *testing.T) {
+	newConfig := func() *types.Config {
+		return &types.Config{
+			SystemPrompt:       "default",
+			SearchBackend:      "brave",
+			Tools:              true,
+			ShellInterpolation: true,
+			Platforms:          map[string]types.Platform{"ollama": {Name: "ollama"}},
+			Profiles: map[string]types.Profile{
+				"local":    {Platform: "ollama", Model: "llama3.2", SystemPrompt: "be terse", SearchBackend: "searxng", SafeMode: true},
+				"personal": {Model: "gpt-4.1-mini"},
+				"broken":   {Platform: "nope"},
+			},
+		}
+	}
+
+	cfg := newConfig()
+	profile, err := ApplyProfile(cfg, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Platform != "ollama" || profile.Model != "llama3.2" {
+		t.Errorf("ApplyProfile() = %+v", profile)
+	}
+	if cfg.SystemPrompt != "be terse" || cfg.SearchBackend != "searxng" {
+		t.Errorf("persona and search not applied: %+v", cfg)
+	}
+	if cfg.Tools || cfg.ShellInterpolation || !cfg.InjectionCheck || !cfg.ProviderStorageOptOut {
+		t.Errorf("safe mode not applied: %+v", cfg)
+	}
+
+	cfg = newConfig()
+	if _, err := ApplyProfile(cfg, "personal"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SystemPrompt != "default" || cfg.SearchBackend != "brave" || !cfg.Tools {
+		t.Errorf("empty profile fields changed the config: %+v", cfg)
+	}
+
+	for name, want := range map[string]string{"missing": "profiles: broken, local, personal", "broken": "platform 'nope' not found"} {
+		if _, err := ApplyProfile(newConfig(), name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ApplyProfile(%q) error = %v, want %q", name, err, want)
+		}
+	}
+	if _, err := ApplyProfile(&types.Config{}, "work"); err == nil || !strings.Contains(err.Error(), "no profiles") {
+		t.Errorf("ApplyProfile() without profiles error = %v", err)
+	}
+}
@@ -9,6 +9,21 @@ import (
 	"github.com/MehmetMHY/ch/pkg/types"
 )
 
+// GetChDir returns the application's data directory (~/.ch), creating it if it doesn't exist
+func GetChDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	chDir := filepath.Join(homeDir, ".ch")
+	if err := os.MkdirAll(chDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create ch directory: %w", err)
+	}
+
+	return chDir, nil
+}
+
 // GetTempDir returns the application's temporary directory, creating it if it doesn't exist
 func GetTempDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -26,42 +41,119 @@ func GetTempDir() (string, error) {
 	return tempDir, nil
 }
 
+// GetTemplateDir returns the directory holding prompt templates, creating it
+// if it doesn't exist
+func GetTemplateDir() (string, error) {
+	chDir, err := GetChDir()
+	if err != nil {
+		return "", err
+	}
+
+	templateDir := filepath.Join(chDir, "templates")
+	if err := os.MkdirAll(templateDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create templates directory: %w", err)
+	}
+
+	return templateDir, nil
+}
+
+// GetIndexDir returns the directory holding embeddings indexes built with
+// ch --index, creating it if it doesn't exist
+func GetIndexDir() (string, error) {
+	chDir, err := GetChDir()
+	if err != nil {
+		return "", err
+	}
+
+	indexDir := filepath.Join(chDir, "index")
+	if err := os.MkdirAll(indexDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	return indexDir, nil
+}
+
 // IsShallowLoadDir checks if a directory should be loaded shallowly (only 1 level deep)
 func IsShallowLoadDir(cfg *types.Config, dirPath string) bool {
+	return ShallowLoadDepth(cfg, dirPath) > 0
+}
+
+// ShallowLoadDepth returns how many levels below dirPath file listings go, or
+// 0 when there is no shallow limit. shallow_load_depths entries win over
+// shallow_load_dirs (depth 1), the longest matching entry wins among them,
+// and a depth of 0 there lifts the limit. Entries may be exact paths, globs
+// such as /mnt/*, or a directory and everything below it such as ~/src/**.
+func ShallowLoadDepth(cfg *types.Config, dirPath string) int {
 	// Normalize the directory path
 	absPath, err := filepath.Abs(dirPath)
 	if err != nil {
-		return false
+		return 0
 	}
 	absPath = filepath.Clean(absPath)
 
+	depth, bestLen := 0, -1
+	for rule, ruleDepth := range cfg.ShallowLoadDepths {
+		if len(rule) > bestLen && matchShallowRule(rule, absPath) {
+			depth, bestLen = max(ruleDepth, 0), len(rule)
+		}
+	}
+	if bestLen >= 0 {
+		return depth
+	}
+
 	// Check against each shallow load directory
 	for _, shallowDir := range cfg.ShallowLoadDirs {
-		if shallowDir == "" {
-			continue
+		if matchShallowRule(shallowDir, absPath) {
+			return 1
 		}
+	}
 
-		// Expand ~ to home directory
-		if strings.HasPrefix(shallowDir, "~") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				continue
-			}
-			shallowDir = filepath.Join(homeDir, shallowDir[1:])
-		}
+	return 0
+}
 
-		// Normalize shallow directory path
-		absShallowDir, err := filepath.Abs(shallowDir)
+// matchShallowRule reports whether a shallow loading rule matches the clean
+// absolute path absPath
+func matchShallowRule(rule, absPath string) bool {
+	if rule == "" {
+		return false
+	}
+
+	// Expand ~ to home directory
+	if strings.HasPrefix(rule, "~") {
+		homeDir, err := os.UserHomeDir()
 		if err != nil {
-			continue
+			return false
 		}
-		absShallowDir = filepath.Clean(absShallowDir)
+		rule = filepath.Join(homeDir, rule[1:])
+	}
+
+	recursive := strings.HasSuffix(filepath.ToSlash(rule), "/**")
+	if recursive {
+		rule = rule[:len(rule)-3]
+	}
 
-		// Check for exact match
-		if absPath == absShallowDir {
+	// Normalize the rule path
+	absRule, err := filepath.Abs(rule)
+	if err != nil {
+		return false
+	}
+	absRule = filepath.Clean(absRule)
+
+	for current := absPath; ; current = filepath.Dir(current) {
+		if matchShallowPath(absRule, current) {
 			return true
 		}
+		if !recursive || filepath.Dir(current) == current {
+			return false
+		}
 	}
+}
 
-	return false
+// matchShallowPath matches one path against an exact or glob rule
+func matchShallowPath(rule, path string) bool {
+	if strings.ContainsAny(rule, "*?[") {
+		matched, _ := filepath.Match(rule, path)
+		return matched
+	}
+	return rule == path
 }
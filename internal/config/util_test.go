@@ -34,6 +34,23 @@ func TestGetTempDir(t *testing.T) {
 	}
 }
 
+func TestGetChDir(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	got, err := GetChDir()
+	if err != nil {
+		t.Fatalf("GetChDir() returned error: %v", err)
+	}
+	if want := filepath.Join(tempHome, ".ch"); got != want {
+		t.Errorf("GetChDir() = %v, want %v", got, want)
+	}
+	if fi, err := os.Stat(got); err != nil || !fi.IsDir() {
+		t.Errorf("expected %v to exist as a directory, err: %v", got, err)
+	}
+}
+
 func TestIsShallowLoadDir(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
@@ -93,3 +110,35 @@ func TestIsShallowLoadDir(t *testing.T) {
 		})
 	}
 }
+
+func TestShallowLoadDepth(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	cfg := &types.Config{
+		ShallowLoadDirs: []string{"/mnt/*", "/srv/**", "~"},
+		ShallowLoadDepths: map[string]int{
+			"~/Downloads":      2,
+			"~/Downloads/big*": 3,
+			"/srv/keep":        0,
+		},
+	}
+
+	tests := map[string]int{
+		"/mnt/usb":                           1, // glob rule
+		"/mnt":                               0,
+		"/mnt/usb/photos":                    0,
+		"/srv/a/b":                           1, // dir/** covers descendants
+		"/srv/keep":                          0, // depth 0 lifts the limit
+		tempHome:                             1,
+		filepath.Join(tempHome, "Downloads"): 2,
+		filepath.Join(tempHome, "Downloads/big1"): 3, // longest matching rule wins
+		filepath.Join(tempHome, "src"):            0,
+	}
+	for dir, want := range tests {
+		if got := ShallowLoadDepth(cfg, dir); got != want {
+			t.Errorf("ShallowLoadDepth(%q) = %d, want %d", dir, got, want)
+		}
+	}
+}
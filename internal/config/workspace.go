@@ -0,0 +1,214 @@
+package config
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// workspaceStoreFile holds project-to-workspace mappings and per-workspace settings
+const workspaceStoreFile = "workspaces.json"
+
+// workspaceNameRegex matches characters that are not allowed in workspace names
+var workspaceNameRegex = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// WorkspaceStore is the persisted workspace state in ~/.ch/workspaces.json
+type WorkspaceStore struct {
+	Projects   map[string]string                  `json:"projects,omitempty"`
+	Workspaces map[string]types.WorkspaceSettings `json:"workspaces,omitempty"`
+}
+
+// LoadWorkspaceStore reads the workspace store, returning an empty store if none exists
+func LoadWorkspaceStore() (*WorkspaceStore, error) {
+	store := &WorkspaceStore{}
+	chDir, err := GetChDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(chDir, workspaceStoreFile)) // #nosec G304 -- Workspace store lives in Ch's own data directory.
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read workspace store: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, store); err != nil {
+			return nil, fmt.Errorf("failed to parse workspace store: %w", err)
+		}
+	}
+
+	if store.Projects == nil {
+		store.Projects = map[string]string{}
+	}
+	if store.Workspaces == nil {
+		store.Workspaces = map[string]types.WorkspaceSettings{}
+	}
+	return store, nil
+}
+
+// Save writes the workspace store to ~/.ch/workspaces.json
+func (s *WorkspaceStore) Save() error {
+	chDir, err := GetChDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(chDir, workspaceStoreFile), data, 0600)
+}
+
+// ProjectRoot returns the enclosing git repository root of dir, or dir itself
+// when it is not inside a repository
+func ProjectRoot(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+
+	for current := absDir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return absDir
+		}
+		current = parent
+	}
+}
+
+// NormalizeWorkspaceName lowercases a name and replaces unsupported characters with dashes
+func NormalizeWorkspaceName(name string) string {
+	name = workspaceNameRegex.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	return strings.Trim(name, "-.")
+}
+
+// DefaultWorkspaceName derives a workspace name from a project root. A short
+// hash of the full path keeps same-named projects in different places apart.
+func DefaultWorkspaceName(root string) string {
+	sum := sha1.Sum([]byte(root)) // #nosec G401 -- Used only to disambiguate directory names.
+	base := NormalizeWorkspaceName(filepath.Base(root))
+	if base == "" {
+		base = "root"
+	}
+	return base + "-" + hex.EncodeToString(sum[:])[:6]
+}
+
+// CurrentWorkspace returns the workspace name and project root for the
+// current directory, honoring names assigned with `ch ws switch`
+func CurrentWorkspace(store *WorkspaceStore) (string, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	root := ProjectRoot(cwd)
+	if name, ok := store.Projects[root]; ok && name != "" {
+		return name, root, nil
+	}
+	return DefaultWorkspaceName(root), root, nil
+}
+
+// GetWorkspacesDir returns the directory that holds per-workspace session directories
+func GetWorkspacesDir() (string, error) {
+	tmpDir, err := GetTempDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(tmpDir, "ws"), nil
+}
+
+// GetSessionDir returns the directory sessions are saved to. With workspaces
+// enabled this is the current workspace's directory under the temp directory,
+// so `--clear` removes workspace sessions too.
+func GetSessionDir(cfg *types.Config) (string, error) {
+	if cfg == nil || !cfg.Workspaces {
+		return GetTempDir()
+	}
+
+	store, err := LoadWorkspaceStore()
+	if err != nil {
+		return "", err
+	}
+	name, _, err := CurrentWorkspace(store)
+	if err != nil {
+		return "", err
+	}
+	wsDir, err := GetWorkspacesDir()
+	if err != nil {
+		return "", err
+	}
+
+	sessionDir := filepath.Join(wsDir, name)
+	if err := os.MkdirAll(sessionDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	return sessionDir, nil
+}
+
+// ListWorkspaces returns the names of workspaces that have saved sessions or settings, sorted
+func ListWorkspaces(store *WorkspaceStore) ([]string, error) {
+	seen := map[string]bool{}
+	for name := range store.Workspaces {
+		seen[name] = true
+	}
+	for _, name := range store.Projects {
+		seen[name] = true
+	}
+
+	wsDir, err := GetWorkspacesDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(wsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read workspaces directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			seen[entry.Name()] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// applyWorkspaceSettings overrides the platform, model, and system prompt with
+// the current workspace's defaults. Environment variables and flags still win.
+func applyWorkspaceSettings(cfg *types.Config) {
+	store, err := LoadWorkspaceStore()
+	if err != nil {
+		return
+	}
+	name, _, err := CurrentWorkspace(store)
+	if err != nil {
+		return
+	}
+	settings, ok := store.Workspaces[name]
+	if !ok {
+		return
+	}
+
+	if settings.Platform != "" {
+		cfg.CurrentPlatform = settings.Platform
+	}
+	if settings.Model != "" {
+		cfg.CurrentModel = settings.Model
+	}
+	if settings.SystemPrompt != "" {
+		cfg.SystemPrompt = settings.SystemPrompt
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// setupWorkspaceHome points HOME at a temp dir and changes into a fresh project directory
+func setupWorkspaceHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	project := filepath.Join(t.TempDir(), "My Project")
+	if err := os.MkdirAll(filepath.Join(project, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(project, "src", "pkg"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(project, "src", "pkg"))
+	return project
+}
+
+func TestProjectRoot(t *testing.T) {
+	project := setupWorkspaceHome(t)
+	realProject, _ := filepath.EvalSymlinks(project)
+
+	got, _ := filepath.EvalSymlinks(ProjectRoot(filepath.Join(project, "src", "pkg")))
+	if got != realProject {
+		t.Errorf("ProjectRoot() = %q, want %q", got, realProject)
+	}
+
+	plain := t.TempDir()
+	if got := ProjectRoot(plain); got != plain {
+		t.Errorf("ProjectRoot() outside a repo = %q, want %q", got, plain)
+	}
+}
+
+func TestDefaultWorkspaceName(t *testing.T) {
+	a := DefaultWorkspaceName("/home/me/work/My Project")
+	b := DefaultWorkspaceName("/home/me/personal/My Project")
+	if !strings.HasPrefix(a, "my-project-") || a == b {
+		t.Errorf("names should share a readable prefix but differ: %q, %q", a, b)
+	}
+	if NormalizeWorkspaceName("  Client/ACME!! ") != "client-acme" {
+		t.Errorf("NormalizeWorkspaceName() = %q", NormalizeWorkspaceName("  Client/ACME!! "))
+	}
+}
+
+func TestGetSessionDir(t *testing.T) {
+	setupWorkspaceHome(t)
+	tmpDir, _ := GetTempDir()
+
+	got, err := GetSessionDir(&types.Config{})
+	if err != nil || got != tmpDir {
+		t.Errorf("disabled workspaces should use the temp dir, got %q, %v", got, err)
+	}
+
+	store, _ := LoadWorkspaceStore()
+	_, root, _ := CurrentWorkspace(store)
+	store.Projects[root] = "work"
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = GetSessionDir(&types.Config{Workspaces: true})
+	if err != nil || got != filepath.Join(tmpDir, "ws", "work") {
+		t.Errorf("GetSessionDir() = %q, %v", got, err)
+	}
+	if info, err := os.Stat(got); err != nil || !info.IsDir() {
+		t.Errorf("workspace session dir was not created: %v", err)
+	}
+
+	names, err := ListWorkspaces(store)
+	if err != nil || strings.Join(names, ",") != "work" {
+		t.Errorf("ListWorkspaces() = %v, %v", names, err)
+	}
+}
+
+func TestApplyWorkspaceSettings(t *testing.T) {
+	setupWorkspaceHome(t)
+
+	store, _ := LoadWorkspaceStore()
+	name, _, _ := CurrentWorkspace(store)
+	store.Workspaces[name] = types.WorkspaceSettings{Platform: "groq", Model: "llama", SystemPrompt: "be terse"}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &types.Config{CurrentPlatform: "openai", CurrentModel: "gpt", SystemPrompt: "default"}
+	applyWorkspaceSettings(cfg)
+	if cfg.CurrentPlatform != "groq" || cfg.CurrentModel != "llama" || cfg.SystemPrompt != "be terse" {
+		t.Errorf("workspace settings not applied: %+v", cfg)
+	}
+}
@@ -0,0 +1,112 @@
+package fswalk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is ch's own ignore file. It uses .gitignore syntax, is read from
+// the same directory as .gitignore, and applies even when .gitignore is not
+// respected, so paths can be hidden from ch without touching git.
+const IgnoreFile = ".chignore"
+
+// vcsDirs are never listed
+var vcsDirs = map[string]bool{".git": true, ".svn": true, ".hg": true}
+
+// RepoRoot returns the git repository root above dir, or dir itself outside
+// a repository. Ignore files are read from here.
+func RepoRoot(dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for current := absDir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return absDir
+		}
+		current = parent
+	}
+}
+
+// LoadIgnorePatterns reads the patterns of .chignore in dir and, when
+// gitignore is set, .gitignore as well. The .git directory is always ignored.
+func LoadIgnorePatterns(dir string, gitignore bool) []string {
+	patterns := []string{".git/"}
+	files := []string{IgnoreFile}
+	if gitignore {
+		files = append([]string{".gitignore"}, files...)
+	}
+	for _, name := range files {
+		patterns = append(patterns, readIgnoreFile(filepath.Join(dir, name))...)
+	}
+	return patterns
+}
+
+// readIgnoreFile returns the non-comment lines of an ignore file
+func readIgnoreFile(path string) []string {
+	file, err := os.Open(path) // #nosec G304 -- Ignore files are read from the directory being listed.
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// Ignored reports whether a slash-separated path relative to the ignore root
+// matches any of patterns
+func Ignored(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern checks if a path matches a gitignore pattern (simplified)
+func matchesPattern(path, pattern string) bool {
+	// Remove leading slash if present
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	// Handle directory patterns (ending with /)
+	if strings.HasSuffix(pattern, "/") {
+		dirPattern := strings.TrimSuffix(pattern, "/")
+		// Check if the path starts with the directory pattern
+		return strings.HasPrefix(path, dirPattern+"/") || path == dirPattern
+	}
+
+	// Handle wildcard patterns
+	if strings.Contains(pattern, "*") {
+		matched, _ := filepath.Match(pattern, path)
+		if matched {
+			return true
+		}
+		// Also check if any parent directory matches
+		parts := strings.Split(path, "/")
+		for i := range parts {
+			partialPath := strings.Join(parts[:i+1], "/")
+			if matched, _ := filepath.Match(pattern, partialPath); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Exact match or prefix match for directories
+	return path == pattern || strings.HasPrefix(path, pattern+"/")
+}
@@ -0,0 +1,62 @@
+package fswalk
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// List returns the paths below root, relative to it, in walk order. Version
+// control directories and paths matched by the ignore files of the enclosing
+// repository are skipped; the rest of opts narrows the listing further.
+func List(root string, opts Options) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	ignoreRoot := RepoRoot(absRoot)
+	patterns := LoadIgnorePatterns(ignoreRoot, opts.Gitignore)
+
+	if opts.ShallowDepth > 0 && (opts.MaxDepth == 0 || opts.MaxDepth > opts.ShallowDepth) {
+		opts.MaxDepth = opts.ShallowDepth
+	}
+
+	var items []string
+	err = Walk(absRoot, opts, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == absRoot {
+			return nil // Skip files we can't access and the root itself
+		}
+		if d.IsDir() && vcsDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+
+		if rel, err := filepath.Rel(ignoreRoot, path); err == nil && Ignored(filepath.ToSlash(rel), patterns) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(absRoot, path)
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if opts.Dirs {
+				items = append(items, relPath+"/")
+			}
+			return nil
+		}
+
+		if opts.MaxFileSize > 0 {
+			if info, err := d.Info(); err != nil || info.Size() > opts.MaxFileSize {
+				return nil
+			}
+		}
+		if opts.Filter != nil && !opts.Filter(path) {
+			return nil
+		}
+		items = append(items, relPath)
+		return nil
+	})
+	return items, err
+}
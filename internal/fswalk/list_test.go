@@ -0,0 +1,82 @@
+package fswalk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListIgnoreFilesAndLimits(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":        "build/\n",
+		".chignore":         "secrets.txt\n",
+		"main.go":           "package main",
+		"secrets.txt":       "token",
+		"big.log":           strings.Repeat("x", 100),
+		"build/out.txt":     "out",
+		"src/lib/lib.go":    "package lib",
+		".git/HEAD":         "ref: refs/heads/main",
+		".svn/entries":      "x",
+		"src/lib/notes.bin": "bin",
+	})
+
+	list := func(opts Options) string {
+		t.Helper()
+		items, err := List(root, opts)
+		if err != nil {
+			t.Fatalf("List() error: %v", err)
+		}
+		return strings.Join(items, ",")
+	}
+
+	if got, want := list(Options{Gitignore: true}), ".chignore,.gitignore,big.log,main.go,src/lib/lib.go,src/lib/notes.bin"; got != want {
+		t.Errorf("gitignore listing:\n got %s\nwant %s", got, want)
+	}
+	if got := list(Options{}); !strings.Contains(got, "build/out.txt") || strings.Contains(got, "secrets.txt") {
+		t.Errorf(".chignore should apply without gitignore, got %s", got)
+	}
+
+	opts := Options{Gitignore: true, MaxFileSize: 50, Dirs: true, Filter: func(path string) bool { return filepath.Ext(path) != ".bin" }}
+	if got, want := list(opts), ".chignore,.gitignore,main.go,src/,src/lib/,src/lib/lib.go"; got != want {
+		t.Errorf("filtered listing:\n got %s\nwant %s", got, want)
+	}
+
+	if got, want := list(Options{Gitignore: true, ShallowDepth: 1, Dirs: true}), ".chignore,.gitignore,big.log,main.go,src/"; got != want {
+		t.Errorf("shallow listing:\n got %s\nwant %s", got, want)
+	}
+	if got := list(Options{ShallowDepth: 2}); strings.Contains(got, "lib.go") || !strings.Contains(got, "main.go") {
+		t.Errorf("depth 2 should stop above src/lib/ files, got %s", got)
+	}
+}
+
+func TestListReadsIgnoreFilesFromRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":       "pkg/gen/\n",
+		"pkg/api.go":       "package pkg",
+		"pkg/gen/types.go": "package gen",
+	})
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := List(filepath.Join(root, "pkg"), Options{Gitignore: true})
+	if err != nil || strings.Join(items, ",") != "api.go" {
+		t.Errorf("listing a subdirectory should use the repository .gitignore, got %v (%v)", items, err)
+	}
+}
@@ -0,0 +1,127 @@
+package fswalk
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// Options configure Walk and List. OptionsFromConfig fills them from the
+// user's config so every file listing in ch behaves the same way.
+type Options struct {
+	FollowSymlinks    bool  // descend into symlinked directories
+	IncludeSubmodules bool  // descend into submodules and nested git repositories
+	MaxDepth          int   // deepest level listed below the root, 0 for no limit
+	ShallowDepth      int   // List only: levels listed below the root for shallow dirs, 0 for no limit
+	Gitignore         bool  // List only: skip paths matched by .gitignore
+	MaxFileSize       int64 // List only: skip larger files, 0 for no limit
+	Dirs              bool  // List only: include directories, with a trailing slash
+
+	// Filter, when set, decides which files List keeps, e.g. text files only
+	Filter func(path string) bool
+}
+
+// OptionsFromConfig returns the listing options for root set in cfg,
+// including its shallow loading depth
+func OptionsFromConfig(cfg *types.Config, root string) Options {
+	return Options{
+		FollowSymlinks:    cfg.FollowSymlinks,
+		IncludeSubmodules: cfg.IncludeSubmodules,
+		MaxDepth:          cfg.MaxWalkDepth,
+		ShallowDepth:      config.ShallowLoadDepth(cfg, root),
+		Gitignore:         cfg.RespectGitignore,
+		MaxFileSize:       cfg.MaxWalkFileBytes,
+	}
+}
+
+// Walk walks root like filepath.WalkDir, including SkipDir and SkipAll,
+// with these differences:
+//   - symlinks to files are reported as the files they point to
+//   - symlinks to directories are skipped, or walked when FollowSymlinks is
+//     set; each real directory is walked once, so link cycles end
+//   - directories holding a .git entry below the root are skipped unless
+//     IncludeSubmodules is set, and submodule .git pointer files are never
+//     reported
+//   - nothing deeper than MaxDepth levels below root is reported
+func Walk(root string, opts Options, fn fs.WalkDirFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	err = walkTree(root, fs.FileInfoToDirEntry(info), 0, opts, map[string]bool{}, fn)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkTree reports path and, for directories, walks its entries
+func walkTree(path string, d fs.DirEntry, depth int, opts Options, visited map[string]bool, fn fs.WalkDirFunc) error {
+	if !d.IsDir() {
+		return fn(path, d, nil)
+	}
+
+	if opts.FollowSymlinks {
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			if visited[real] {
+				return nil // Already walked, e.g. through a link back to a parent
+			}
+			visited[real] = true
+		}
+	}
+
+	if err := fn(path, d, nil); err != nil {
+		return err
+	}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		return nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err := fn(path, d, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+			return err
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		if entry.Name() == ".git" && !entry.IsDir() && entry.Type()&fs.ModeSymlink == 0 {
+			continue // Submodule gitdir pointer, not content
+		}
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(child)
+			if err != nil || (info.IsDir() && !opts.FollowSymlinks) {
+				continue // Dangling link, or a directory link that is not followed
+			}
+			entry = fs.FileInfoToDirEntry(info)
+		}
+		if entry.IsDir() && !opts.IncludeSubmodules && isNestedRepository(child) {
+			continue
+		}
+
+		if err := walkTree(child, entry, depth+1, opts, visited, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				if entry.IsDir() {
+					continue
+				}
+				return nil // SkipDir on a file skips the rest of its directory
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// isNestedRepository reports whether dir is a submodule or nested git
+// repository, which have a .git file or directory of their own
+func isNestedRepository(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
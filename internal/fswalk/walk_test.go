@@ -0,0 +1,104 @@
+package fswalk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// walkedPaths returns the slash-separated paths Walk reports below root
+func walkedPaths(t *testing.T, root string, opts Options) []string {
+	t.Helper()
+	var paths []string
+	err := Walk(root, opts, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if d.IsDir() {
+			rel += "/"
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestWalkSymlinksSubmodulesAndDepth(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for path, content := range map[string]string{
+		"a.txt":             "a",
+		"sub/module/.git":   "gitdir: ../../.git/modules/module",
+		"sub/module/lib.go": "package lib",
+		"deep/one/two/x.go": "package x",
+	} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "linked.txt"), []byte("l"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"outside": outside,                      // directory link
+		"loop":    root,                         // cycle back to the root
+		"b.txt":   filepath.Join(root, "a.txt"), // file link
+		"broken":  filepath.Join(root, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	got := strings.Join(walkedPaths(t, root, Options{IncludeSubmodules: true}), ",")
+	want := "a.txt,b.txt,deep/,deep/one/,deep/one/two/,deep/one/two/x.go,sub/,sub/module/,sub/module/lib.go"
+	if got != want {
+		t.Errorf("default walk:\n got %s\nwant %s", got, want)
+	}
+
+	got = strings.Join(walkedPaths(t, root, Options{FollowSymlinks: true, MaxDepth: 2}), ",")
+	want = "a.txt,b.txt,deep/,deep/one/,outside/,outside/linked.txt,sub/"
+	if got != want {
+		t.Errorf("following links without submodules, depth 2:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"skip/a.txt", "keep/b.txt"} {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var files []string
+	err := Walk(root, Options{}, func(path string, d fs.DirEntry, err error) error {
+		if d.IsDir() && d.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			files = append(files, d.Name())
+		}
+		return nil
+	})
+	if err != nil || strings.Join(files, ",") != "b.txt" {
+		t.Errorf("files = %v, err = %v", files, err)
+	}
+}
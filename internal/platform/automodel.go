@@ -0,0 +1,60 @@
+package platform
+
+import (
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// AutoModel is the model alias that picks a model from auto_model_routes by prompt size
+const AutoModel = "auto"
+
+// routeAutoModel returns the model of the first route whose max_tokens fits
+// the prompt. A route with max_tokens 0 has no limit, and prompts larger than
+// every route use the last one. Returns "" when there are no routes.
+func routeAutoModel(tokens int, routes []types.AutoModelRoute) string {
+	for _, route := range routes {
+		if route.MaxTokens <= 0 || tokens <= route.MaxTokens {
+			return route.Model
+		}
+	}
+	if len(routes) > 0 {
+		return routes[len(routes)-1].Model
+	}
+	return ""
+}
+
+// countMessageTokens estimates the prompt size of messages for model
+func countMessageTokens(model string, messages []types.ChatMessage) int {
+	tok := tokens.For(model)
+	total := 0
+	for _, msg := range messages {
+		total += tok.Count(msg.Content)
+	}
+	return total
+}
+
+// ResolveModel maps the auto alias to a concrete model for messages, falling
+// back to default_model when no routes are configured. Other models are
+// returned unchanged.
+func (m *Manager) ResolveModel(messages []types.ChatMessage, model string) string {
+	if model != AutoModel {
+		return model
+	}
+	if resolved := routeAutoModel(countMessageTokens(m.config.DefaultModel, messages), m.config.AutoModelRoutes); resolved != "" {
+		return resolved
+	}
+	return m.config.DefaultModel
+}
+
+// LastModel returns the model used by the most recent chat request, which is
+// the routed model when the auto alias is active
+func (m *Manager) LastModel() string {
+	return m.lastModel
+}
+
+// LastElapsed returns how long the most recent chat request took
+func (m *Manager) LastElapsed() time.Duration {
+	return m.lastElapsed
+}
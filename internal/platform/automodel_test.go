@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRouteAutoModel(t *testing.T) {
+	routes := []types.AutoModelRoute{
+		{MaxTokens: 1000, Model: "small"},
+		{MaxTokens: 50000, Model: "medium"},
+		{MaxTokens: 0, Model: "long-context"},
+	}
+	tests := []struct {
+		tokens int
+		want   string
+	}{
+		{10, "small"},
+		{1000, "small"},
+		{1001, "medium"},
+		{900000, "long-context"},
+	}
+	for _, tt := range tests {
+		if got := routeAutoModel(tt.tokens, routes); got != tt.want {
+			t.Errorf("routeAutoModel(%d) = %q, want %q", tt.tokens, got, tt.want)
+		}
+	}
+
+	bounded := routes[:2]
+	if got := routeAutoModel(900000, bounded); got != "medium" {
+		t.Errorf("oversized prompts should use the last route, got %q", got)
+	}
+	if got := routeAutoModel(10, nil); got != "" {
+		t.Errorf("no routes should return empty, got %q", got)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	m := NewManager(&types.Config{
+		DefaultModel:    "fallback",
+		AutoModelRoutes: []types.AutoModelRoute{{MaxTokens: 100, Model: "small"}, {Model: "large"}},
+	})
+
+	short := []types.ChatMessage{{Role: "user", Content: "hi"}}
+	long := []types.ChatMessage{{Role: "user", Content: strings.Repeat("token ", 500)}}
+
+	if got := m.ResolveModel(short, "gpt-4.1"); got != "gpt-4.1" {
+		t.Errorf("explicit models must not be routed, got %q", got)
+	}
+	if got := m.ResolveModel(short, AutoModel); got != "small" {
+		t.Errorf("short prompt routed to %q", got)
+	}
+	if got := m.ResolveModel(long, AutoModel); got != "large" {
+		t.Errorf("long prompt routed to %q", got)
+	}
+
+	m.config.AutoModelRoutes = nil
+	if got := m.ResolveModel(short, AutoModel); got != "fallback" {
+		t.Errorf("without routes auto should use default_model, got %q", got)
+	}
+}
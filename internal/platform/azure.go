@@ -0,0 +1,74 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DriverAzure is the platform driver for Azure OpenAI: requests go to
+// {base_url}/openai/deployments/{deployment}/... with an api-version query
+// and the key in an api-key header
+const DriverAzure = "azure"
+
+// defaultAzureAPIVersion is used when the platform sets no api_version
+const defaultAzureAPIVersion = "2024-10-21"
+
+// azureEndpointEnv holds the resource endpoint, such as
+// https://my-resource.openai.azure.com, when the platform has no base_url
+const azureEndpointEnv = "AZURE_OPENAI_ENDPOINT"
+
+// azureBaseURL returns the platform's resource endpoint
+func azureBaseURL(platform types.Platform, baseURL string) (string, error) {
+	if baseURL == "" {
+		baseURL = os.Getenv(azureEndpointEnv)
+	}
+	if baseURL == "" {
+		return "", fmt.Errorf("%s needs base_url in ~/.ch/config.json or %s, e.g. https://my-resource.openai.azure.com", platform.Name, azureEndpointEnv)
+	}
+	return baseURL, nil
+}
+
+// azureClientConfig returns a client config that sends requests for a
+// model to the deployment deployments maps it to, or to a deployment of
+// the same name when it is not mapped
+func azureClientConfig(platform types.Platform, apiKey, baseURL string) openai.ClientConfig {
+	clientConfig := openai.DefaultAzureConfig(apiKey, baseURL)
+	clientConfig.APIVersion = platform.APIVersion
+	if clientConfig.APIVersion == "" {
+		clientConfig.APIVersion = defaultAzureAPIVersion
+	}
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := platform.Deployments[model]; ok {
+			return deployment
+		}
+		return model
+	}
+	return clientConfig
+}
+
+// azureDeploymentModels lists the friendly model names of deployments,
+// showing the deployment after names that differ from it
+func azureDeploymentModels(platform types.Platform) ([]modelWithTime, error) {
+	if len(platform.Deployments) == 0 {
+		return nil, fmt.Errorf("no deployments for %s: map model names to deployment names under \"deployments\" in ~/.ch/config.json", platform.Name)
+	}
+	names := make([]string, 0, len(platform.Deployments))
+	for name := range platform.Deployments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	models := make([]modelWithTime, 0, len(names))
+	for _, name := range names {
+		model := modelWithTime{name: name}
+		if deployment := platform.Deployments[name]; deployment != name {
+			model.detail = "deployment " + deployment
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
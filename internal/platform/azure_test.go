@@ -0,0 +1,96 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestAzureChatRequestUsesDeployment(t *testing.T) {
+	var gotURL, gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotKey, gotAuth = r.URL.String(), r.Header.Get("api-key"), r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_AZURE_KEY", "secret")
+	m := NewManager(&types.Config{
+		CurrentPlatform: "azure",
+		IsPipedOutput:   true,
+		Platforms: map[string]types.Platform{"azure": {
+			Name:        "azure",
+			BaseURL:     types.BaseURLValue{Single: server.URL},
+			EnvName:     "TEST_AZURE_KEY",
+			Driver:      DriverAzure,
+			Deployments: map[string]string{"gpt-4o": "prod-4o"},
+		}},
+	})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var cancel func()
+	var streaming bool
+	for _, tt := range []struct{ model, path string }{
+		{"gpt-4o", "/openai/deployments/prod-4o/chat/completions"},
+		{"my-deployment", "/openai/deployments/my-deployment/chat/completions"},
+	} {
+		response, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, tt.model, &cancel, &streaming)
+		if err != nil || response != "ok" {
+			t.Fatalf("SendChatRequest(%s) = %q, %v", tt.model, response, err)
+		}
+		if want := tt.path + "?api-version=" + defaultAzureAPIVersion; gotURL != want {
+			t.Errorf("request URL = %q, want %q", gotURL, want)
+		}
+		if gotKey != "secret" || gotAuth != "" {
+			t.Errorf("api-key = %q, Authorization = %q; want the key in api-key only", gotKey, gotAuth)
+		}
+	}
+}
+
+func TestAzureEndpointFromEnv(t *testing.T) {
+	t.Setenv("TEST_AZURE_KEY", "secret")
+	platform := types.Platform{Name: "azure", EnvName: "TEST_AZURE_KEY", Driver: DriverAzure}
+	m := NewManager(&types.Config{CurrentPlatform: "azure", Platforms: map[string]types.Platform{"azure": platform}})
+
+	t.Setenv(azureEndpointEnv, "")
+	if err := m.Initialize(); err == nil || !strings.Contains(err.Error(), azureEndpointEnv) {
+		t.Errorf("Initialize() without an endpoint error = %v", err)
+	}
+
+	t.Setenv(azureEndpointEnv, "https://my-resource.openai.azure.com")
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	if m.config.CurrentBaseURL != "https://my-resource.openai.azure.com" {
+		t.Errorf("CurrentBaseURL = %q", m.config.CurrentBaseURL)
+	}
+}
+
+func TestAzureDeploymentModels(t *testing.T) {
+	platform := types.Platform{Name: "azure", Driver: DriverAzure, Deployments: map[string]string{
+		"gpt-4o":      "prod-4o",
+		"gpt-4o-mini": "gpt-4o-mini",
+	}}
+	models, err := NewManager(&types.Config{}).fetchPlatformModelsWithTime(platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"gpt-4o - deployment prod-4o", "gpt-4o-mini"}
+	if got := sortModelsByTime(models); !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+
+	platform.Deployments = nil
+	if _, err := azureDeploymentModels(platform); err == nil {
+		t.Error("azureDeploymentModels() with no deployments succeeded")
+	}
+}
@@ -0,0 +1,238 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Capabilities are the request features a platform/model accepts. Features
+// check them before adding request parameters, and degrade with a message
+// instead of sending parameters the provider would reject.
+type Capabilities struct {
+	Streaming bool
+	Tools     bool
+	Vision    bool
+	JSONMode  bool
+	Logprobs  bool
+	MaxOutput int // output token limit, 0 when unknown
+}
+
+// Capability names used in messages and runtime discovery
+const (
+	CapabilityStreaming = "streaming"
+	CapabilityTools     = "tools"
+	CapabilityVision    = "vision"
+	CapabilityJSONMode  = "json_mode"
+	CapabilityLogprobs  = "logprobs"
+)
+
+// defaultCapabilities applies to platforms without an entry in
+// platformCapabilities. Unknown features default to allowed so runtime
+// discovery can turn them off.
+var defaultCapabilities = Capabilities{Streaming: true, Tools: true, JSONMode: true, Logprobs: true}
+
+// platformCapabilities is the built-in capability data per platform
+var platformCapabilities = map[string]Capabilities{
+	"openai":     {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"azure":      {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"anthropic":  {Streaming: true, Tools: true, Vision: true},
+	"google":     {Streaming: true, Tools: true, Vision: true, JSONMode: true},
+	"groq":       {Streaming: true, Tools: true, JSONMode: true},
+	"mistral":    {Streaming: true, Tools: true, JSONMode: true},
+	"deepseek":   {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"xai":        {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"together":   {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"openrouter": {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"ollama":     {Streaming: true, Tools: true, JSONMode: true, Logprobs: true},
+	"amazon":     {Streaming: true, Tools: true},
+	"mock":       {Streaming: true},
+}
+
+// modelCapabilityRules adjust the platform data for model families, in order
+var modelCapabilityRules = []struct {
+	pattern *regexp.Regexp
+	apply   func(*Capabilities)
+}{
+	{regexp.MustCompile(`gpt-4o|gpt-4\.1|gpt-5|^o[134]|claude|gemini|grok-4|grok.*vision|llava|pixtral|llama-4|vision|-vl\b`), func(c *Capabilities) { c.Vision = true }},
+	{regexp.MustCompile(`^o[134]|gpt-5`), func(c *Capabilities) { c.Logprobs = false }},
+	{regexp.MustCompile(`deepseek-reasoner`), func(c *Capabilities) { c.Tools, c.JSONMode, c.Logprobs = false, false, false }},
+	{regexp.MustCompile(`gpt-4o`), func(c *Capabilities) { c.MaxOutput = 16384 }},
+	{regexp.MustCompile(`gpt-4\.1`), func(c *Capabilities) { c.MaxOutput = 32768 }},
+	{regexp.MustCompile(`gpt-5`), func(c *Capabilities) { c.MaxOutput = 128000 }},
+	{regexp.MustCompile(`claude-3-5|claude-3\.5`), func(c *Capabilities) { c.MaxOutput = 8192 }},
+	{regexp.MustCompile(`claude-(sonnet|3-7)`), func(c *Capabilities) { c.MaxOutput = 64000 }},
+	{regexp.MustCompile(`claude-opus`), func(c *Capabilities) { c.MaxOutput = 32000 }},
+	{regexp.MustCompile(`gemini-2\.5`), func(c *Capabilities) { c.MaxOutput = 65536 }},
+	{regexp.MustCompile(`deepseek-chat`), func(c *Capabilities) { c.MaxOutput = 8192 }},
+}
+
+// Capabilities returns what model on the current platform supports: the
+// built-in platform and model data, then model_capabilities overrides, then
+// anything discovered from rejected requests this session
+func (m *Manager) Capabilities(model string) Capabilities {
+	platform := m.config.CurrentPlatform
+	caps, ok := platformCapabilities[platform]
+	if !ok {
+		caps = defaultCapabilities
+	}
+
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, rule := range modelCapabilityRules {
+		if rule.pattern.MatchString(name) {
+			rule.apply(&caps)
+		}
+	}
+
+	if override, ok := m.config.ModelCapabilities[platform]; ok {
+		applyCapabilityOverride(&caps, override)
+	}
+	if override, ok := m.config.ModelCapabilities[platform+"/"+model]; ok {
+		applyCapabilityOverride(&caps, override)
+	}
+
+	for feature := range m.unsupported[platform+"/"+model] {
+		switch feature {
+		case CapabilityStreaming:
+			caps.Streaming = false
+		case CapabilityLogprobs:
+			caps.Logprobs = false
+		case CapabilityTools:
+			caps.Tools = false
+		case CapabilityVision:
+			caps.Vision = false
+		}
+	}
+	return caps
+}
+
+// applyCapabilityOverride sets the fields a model_capabilities entry defines
+func applyCapabilityOverride(caps *Capabilities, override types.CapabilityOverride) {
+	setIf := func(field *bool, value *bool) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	setIf(&caps.Streaming, override.Streaming)
+	setIf(&caps.Tools, override.Tools)
+	setIf(&caps.Vision, override.Vision)
+	setIf(&caps.JSONMode, override.JSONMode)
+	setIf(&caps.Logprobs, override.Logprobs)
+	if override.MaxOutput > 0 {
+		caps.MaxOutput = override.MaxOutput
+	}
+}
+
+// String lists the supported features and the output limit for >state
+func (c Capabilities) String() string {
+	var features []string
+	for _, f := range []struct {
+		name string
+		ok   bool
+	}{
+		{CapabilityStreaming, c.Streaming},
+		{CapabilityTools, c.Tools},
+		{CapabilityVision, c.Vision},
+		{CapabilityJSONMode, c.JSONMode},
+		{CapabilityLogprobs, c.Logprobs},
+	} {
+		if f.ok {
+			features = append(features, f.name)
+		}
+	}
+	out := strings.Join(features, ", ")
+	if out == "" {
+		out = "none"
+	}
+	if c.MaxOutput > 0 {
+		out += fmt.Sprintf("; max output %d tokens", c.MaxOutput)
+	}
+	return out
+}
+
+// unsupportedFeature reports which optional feature of req a provider error
+// rejected, or "" when the error is about something else
+func unsupportedFeature(err error, req openai.ChatCompletionRequest) string {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.HTTPStatusCode != 0 && apiErr.HTTPStatusCode != 400 && apiErr.HTTPStatusCode != 422 {
+			return ""
+		}
+	case errors.As(err, &reqErr):
+		if reqErr.HTTPStatusCode != 400 && reqErr.HTTPStatusCode != 422 {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	message := strings.ToLower(err.Error())
+	rejected := false
+	for _, phrase := range []string{"not supported", "unsupported", "not available", "not allowed", "does not support", "unrecognized", "unknown parameter", "invalid parameter"} {
+		if strings.Contains(message, phrase) {
+			rejected = true
+			break
+		}
+	}
+	switch {
+	case !rejected:
+		return ""
+	case req.LogProbs && strings.Contains(message, "logprob"):
+		return CapabilityLogprobs
+	case req.Stream && strings.Contains(message, "stream"):
+		return CapabilityStreaming
+	case len(req.Tools) > 0 && strings.Contains(message, "tool"):
+		return CapabilityTools
+	case hasImageParts(req) && (strings.Contains(message, "image") || strings.Contains(message, "vision")):
+		return CapabilityVision
+	}
+	return ""
+}
+
+// hasImageParts reports whether any message of req carries an image part
+func hasImageParts(req openai.ChatCompletionRequest) bool {
+	for _, msg := range req.Messages {
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeImageURL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markUnsupported records that model rejected feature, so later requests
+// this session leave it out
+func (m *Manager) markUnsupported(model, feature string) {
+	key := m.config.CurrentPlatform + "/" + model
+	if m.unsupported == nil {
+		m.unsupported = make(map[string]map[string]bool)
+	}
+	if m.unsupported[key] == nil {
+		m.unsupported[key] = make(map[string]bool)
+	}
+	m.unsupported[key][feature] = true
+}
+
+// noticeOnce tells the user once per session and model that a configured
+// feature is left out of its requests
+func (m *Manager) noticeOnce(model, feature, message string) {
+	key := m.config.CurrentPlatform + "/" + model + "/" + feature
+	if m.noticed[key] {
+		return
+	}
+	if m.noticed == nil {
+		m.noticed = make(map[string]bool)
+	}
+	m.noticed[key] = true
+	m.printWarning(fmt.Sprintf("%s by %s/%s, sending without them", message, m.config.CurrentPlatform, model))
+}
@@ -0,0 +1,131 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCapabilities(t *testing.T) {
+	off := false
+	tests := []struct {
+		name     string
+		platform string
+		model    string
+		override map[string]types.CapabilityOverride
+		rejected string
+		want     Capabilities
+	}{
+		{"platform defaults", "groq", "llama-3.3-70b", nil, "", Capabilities{Streaming: true, Tools: true, JSONMode: true}},
+		{"model rules", "openai", "gpt-4o", nil, "", Capabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true, Logprobs: true, MaxOutput: 16384}},
+		{"reasoning model drops logprobs", "openai", "o3-mini", nil, "", Capabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true}},
+		{"unknown platform", "local", "my-model", nil, "", defaultCapabilities},
+		{"platform override", "local", "my-model", map[string]types.CapabilityOverride{"local": {Tools: &off}}, "", Capabilities{Streaming: true, JSONMode: true, Logprobs: true}},
+		{"model override wins", "local", "my-model", map[string]types.CapabilityOverride{
+			"local":          {Tools: &off},
+			"local/my-model": {Streaming: &off, MaxOutput: 4096},
+		}, "", Capabilities{JSONMode: true, Logprobs: true, MaxOutput: 4096}},
+		{"discovered at runtime", "together", "some-model", nil, CapabilityLogprobs, Capabilities{Streaming: true, Tools: true, JSONMode: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&types.Config{CurrentPlatform: tt.platform, ModelCapabilities: tt.override})
+			if tt.rejected != "" {
+				m.markUnsupported(tt.model, tt.rejected)
+			}
+			if got := m.Capabilities(tt.model); got != tt.want {
+				t.Errorf("Capabilities(%q) = %+v, want %+v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedFeature(t *testing.T) {
+	logprobs := openai.ChatCompletionRequest{LogProbs: true, Stream: true}
+	tests := []struct {
+		name string
+		err  error
+		req  openai.ChatCompletionRequest
+		want string
+	}{
+		{"logprobs rejected", &openai.APIError{HTTPStatusCode: 400, Message: "logprobs is not supported with this model"}, logprobs, CapabilityLogprobs},
+		{"streaming rejected", &openai.APIError{HTTPStatusCode: 400, Message: "Unsupported value: 'stream' does not support true"}, logprobs, CapabilityStreaming},
+		{"feature not requested", &openai.APIError{HTTPStatusCode: 400, Message: "logprobs is not supported"}, openai.ChatCompletionRequest{}, ""},
+		{"other status", &openai.APIError{HTTPStatusCode: 500, Message: "logprobs is not supported"}, logprobs, ""},
+		{"other message", &openai.APIError{HTTPStatusCode: 400, Message: "context length exceeded"}, logprobs, ""},
+		{"not an api error", fmt.Errorf("logprobs not supported"), logprobs, ""},
+		{"nil", nil, logprobs, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unsupportedFeature(tt.err, tt.req); got != tt.want {
+				t.Errorf("unsupportedFeature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendChatRequestRetriesWithoutRejectedLogprobs(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		requests = append(requests, body)
+		if body["logprobs"] == true {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"logprobs is not supported for this model","type":"invalid_request_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_LOCAL_KEY", "test")
+	cfg := &types.Config{
+		CurrentPlatform: "local",
+		IsPipedOutput:   true,
+		ShowLogprobs:    true,
+		Platforms: map[string]types.Platform{"local": {
+			Name:    "local",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_LOCAL_KEY",
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var cancel func()
+	var streaming bool
+	response, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, "some-model", &cancel, &streaming)
+	if err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+	if response != "ok" || len(requests) != 2 {
+		t.Fatalf("got %q after %d requests, want a retried answer", response, len(requests))
+	}
+	if m.Capabilities("some-model").Logprobs {
+		t.Error("rejected logprobs should be remembered for the session")
+	}
+
+	requests = nil
+	if _, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "again"}}, "some-model", &cancel, &streaming); err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+	if len(requests) != 1 || requests[0]["logprobs"] != nil {
+		t.Errorf("later requests should leave logprobs out: %v", requests)
+	}
+}
@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// codeBlockMarker is the dim [n] label put at the end of a code block's
+// opening fence line. It is written inside green response text, so it
+// switches back to green afterwards.
+func codeBlockMarker(n int) string {
+	return fmt.Sprintf(" \033[90m[%d]\033[92m", n)
+}
+
+// codeBlockNumberer labels the code blocks of a displayed response [1], [2],
+// and so on, so !save and !sN can refer to them. Chunks are labelled as they
+// stream: a line is checked for a ``` fence when its newline arrives.
+type codeBlockNumberer struct {
+	line    strings.Builder
+	inBlock bool
+	count   int
+}
+
+// Mark returns chunk with the marker of every code block opened in it
+// inserted before the newline that ends the opening fence line
+func (n *codeBlockNumberer) Mark(chunk string) string {
+	var out strings.Builder
+	for {
+		i := strings.IndexByte(chunk, '\n')
+		if i < 0 {
+			n.line.WriteString(chunk)
+			out.WriteString(chunk)
+			return out.String()
+		}
+
+		n.line.WriteString(chunk[:i])
+		out.WriteString(chunk[:i])
+		if strings.HasPrefix(strings.TrimSpace(n.line.String()), "```") {
+			n.inBlock = !n.inBlock
+			if n.inBlock {
+				n.count++
+				out.WriteString(codeBlockMarker(n.count))
+			}
+		}
+		n.line.Reset()
+		out.WriteByte('\n')
+		chunk = chunk[i+1:]
+	}
+}
+
+// newCodeBlockNumberer returns a numberer for streamed output, or nil when
+// number_code_blocks is off or the response is not shown in color
+func (m *Manager) newCodeBlockNumberer() *codeBlockNumberer {
+	if !m.config.NumberCodeBlocks || m.config.IsPipedOutput || m.config.StreamJSON {
+		return nil
+	}
+	return &codeBlockNumberer{}
+}
+
+// NumberCodeBlocks labels the code blocks of a complete response for display
+// the same way streamed responses are labelled
+func (m *Manager) NumberCodeBlocks(text string) string {
+	numberer := m.newCodeBlockNumberer()
+	if numberer == nil {
+		return text
+	}
+	return numberer.Mark(text)
+}
@@ -0,0 +1,40 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestCodeBlockNumbererMarksStreamedFences(t *testing.T) {
+	response := "Two blocks:\n```go\nfmt.Println(1)\n```\ntext\n```\nplain\n```\n"
+	want := "Two blocks:\n```go" + codeBlockMarker(1) + "\nfmt.Println(1)\n```\ntext\n```" + codeBlockMarker(2) + "\nplain\n```\n"
+
+	// Split at every byte so fences arrive across chunks
+	numberer := &codeBlockNumberer{}
+	var got strings.Builder
+	for i := 0; i < len(response); i++ {
+		got.WriteString(numberer.Mark(response[i : i+1]))
+	}
+	if got.String() != want {
+		t.Errorf("streamed marks:\ngot:  %q\nwant: %q", got.String(), want)
+	}
+
+	if whole := (&codeBlockNumberer{}).Mark(response); whole != want {
+		t.Errorf("whole-text marks:\ngot:  %q\nwant: %q", whole, want)
+	}
+}
+
+func TestNumberCodeBlocksRespectsConfig(t *testing.T) {
+	text := "```\nx\n```"
+	if got := NewManager(&types.Config{NumberCodeBlocks: true, IsPipedOutput: true}).NumberCodeBlocks(text); got != text {
+		t.Errorf("piped output should not be numbered, got %q", got)
+	}
+	if got := NewManager(&types.Config{}).NumberCodeBlocks(text); got != text {
+		t.Errorf("number_code_blocks off should not number, got %q", got)
+	}
+	if got := NewManager(&types.Config{NumberCodeBlocks: true}).NumberCodeBlocks(text); !strings.Contains(got, "[1]") {
+		t.Errorf("expected a [1] marker, got %q", got)
+	}
+}
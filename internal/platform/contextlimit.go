@@ -0,0 +1,43 @@
+package platform
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// contextLengthPhrases are lowercase fragments of the errors providers return
+// when a request does not fit in the model's context window
+var contextLengthPhrases = []string{
+	"context_length_exceeded",
+	"context length",
+	"maximum context",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+	"reduce the length",
+}
+
+// IsContextLengthError reports whether err is a provider rejecting a request
+// for exceeding the model's context window
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if code, ok := apiErr.Code.(string); ok && code == "context_length_exceeded" {
+			return true
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, phrase := range contextLengthPhrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
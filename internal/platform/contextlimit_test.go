@@ -0,0 +1,32 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestIsContextLengthError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"openai code", fmt.Errorf("send: %w", &openai.APIError{Code: "context_length_exceeded", Message: "too long"}), true},
+		{"anthropic message", errors.New("error, status code: 400, message: prompt is too long: 210000 tokens > 200000 maximum"), true},
+		{"groq message", errors.New("Please reduce the length of the messages or completion."), true},
+		{"rate limit", &openai.APIError{Code: "rate_limit_exceeded", Message: "slow down"}, false},
+		{"interrupted", errors.New("request was interrupted"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsContextLengthError(tt.err); got != tt.want {
+				t.Errorf("IsContextLengthError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// Cost limit actions for the cost_limit_action config option
+const (
+	CostLimitConfirm = "confirm"
+	CostLimitBlock   = "block"
+)
+
+// costGuardOutputTokens is the completion size assumed when checking whether
+// a request would go over a spend limit, since the real size is not known
+// until the response arrives
+const costGuardOutputTokens = 1000
+
+// defaultModelPrices are USD prices per million tokens for well-known models.
+// Keys match model names exactly or followed by a date suffix, and
+// model_prices in config adds to or overrides them. Models without a price
+// are treated as free.
+var defaultModelPrices = map[string]types.ModelPrice{
+	"gpt-5.4":               {Input: 2.50, Output: 15},
+	"gpt-5.4-mini":          {Input: 0.75, Output: 4.50},
+	"gpt-5":                 {Input: 1.25, Output: 10},
+	"gpt-5-mini":            {Input: 0.25, Output: 2},
+	"gpt-5-nano":            {Input: 0.05, Output: 0.40},
+	"gpt-4.1":               {Input: 2, Output: 8},
+	"gpt-4.1-mini":          {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":          {Input: 0.10, Output: 0.40},
+	"gpt-4o":                {Input: 2.50, Output: 10},
+	"gpt-4o-mini":           {Input: 0.15, Output: 0.60},
+	"o3":                    {Input: 2, Output: 8},
+	"o3-pro":                {Input: 20, Output: 80},
+	"o3-mini":               {Input: 1.10, Output: 4.40},
+	"o4-mini":               {Input: 1.10, Output: 4.40},
+	"claude-opus-4":         {Input: 15, Output: 75},
+	"claude-opus-4-1":       {Input: 15, Output: 75},
+	"claude-sonnet-4":       {Input: 3, Output: 15},
+	"claude-3-5-haiku":      {Input: 0.80, Output: 4},
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"deepseek-chat":         {Input: 0.27, Output: 1.10},
+	"grok-3":                {Input: 3, Output: 15},
+	"grok-3-mini":           {Input: 0.30, Output: 0.50},
+}
+
+// modelDateSuffix matches the snapshot date providers append to model names,
+// as in gpt-4o-2024-08-06, claude-sonnet-4-20250514, or gpt-4-0613
+var modelDateSuffix = regexp.MustCompile(`^[-@](\d{4}|\d{8}|\d{4}-\d{2}-\d{2})$`)
+
+// priceKeyMatches reports whether a price key names model: the whole name,
+// or the name without its date suffix, so gpt-5-mini is never priced as gpt-5
+func priceKeyMatches(model, key string) bool {
+	rest, ok := strings.CutPrefix(model, key)
+	return ok && (rest == "" || modelDateSuffix.MatchString(rest))
+}
+
+// ModelPrice returns the price of model from model_prices and the built-in
+// table. Provider prefixes such as openai/ are ignored, and a key matches the
+// model name itself or the name with a date suffix.
+func (m *Manager) ModelPrice(model string) (types.ModelPrice, bool) {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	var best types.ModelPrice
+	bestLen := -1
+	for _, prices := range []map[string]types.ModelPrice{defaultModelPrices, m.config.ModelPrices} {
+		for key, price := range prices {
+			// Configured prices come second, so they win ties with the built-in table
+			if priceKeyMatches(model, key) && len(key) >= bestLen {
+				best, bestLen = price, len(key)
+			}
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// billable reports whether requests on the current platform cost money. The
+// offline mock platform answers in process, so it is never priced, whatever
+// model name it is given.
+func (m *Manager) billable() bool {
+	return m.config.CurrentPlatform != MockPlatform
+}
+
+// requestCost returns the USD cost of a request with the given token counts
+func (m *Manager) requestCost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := m.ModelPrice(model)
+	if !ok || !m.billable() {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
+
+// SessionSpend returns the estimated USD spent by requests in this run
+func (m *Manager) SessionSpend() float64 {
+	return m.sessionSpend
+}
+
+// checkSpendLimit stops a request that would take the session or today's
+// spend past max_session_cost or max_daily_cost. In confirm mode the user
+// may allow it; in block mode, or when nobody can confirm, it is not sent.
+func (m *Manager) checkSpendLimit(messages []types.ChatMessage, model string) error {
+	cfg := m.config
+	if cfg.MaxSessionCost <= 0 && cfg.MaxDailyCost <= 0 {
+		return nil
+	}
+	estimate := m.requestCost(model, countMessageTokens(model, messages), costGuardOutputTokens)
+	if estimate == 0 {
+		return nil
+	}
+
+	var over []string
+	if cfg.MaxSessionCost > 0 && m.sessionSpend+estimate > cfg.MaxSessionCost {
+		over = append(over, fmt.Sprintf("session $%.4f of $%.2f", m.sessionSpend, cfg.MaxSessionCost))
+	}
+	if cfg.MaxDailyCost > 0 {
+		if today := DailySpend(); today+estimate > cfg.MaxDailyCost {
+			over = append(over, fmt.Sprintf("today $%.4f of $%.2f", today, cfg.MaxDailyCost))
+		}
+	}
+	if len(over) == 0 {
+		return nil
+	}
+
+	summary := fmt.Sprintf("request (about $%.4f) would exceed the spend limit: %s", estimate, strings.Join(over, ", "))
+	action := strings.ToLower(cfg.CostLimitAction)
+	if action == CostLimitBlock {
+		return fmt.Errorf("%s, not sent", summary)
+	}
+	if action != "" && action != CostLimitConfirm {
+		return fmt.Errorf("unknown cost_limit_action %q (use confirm or block)", cfg.CostLimitAction)
+	}
+	if m.ConfirmSpend == nil || !m.ConfirmSpend(summary+", send anyway?") {
+		return fmt.Errorf("%s, not sent", summary)
+	}
+	return nil
+}
+
+// spendFilePath returns the file holding estimated spend per day
+func spendFilePath() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "spend.json"), nil
+}
+
+// loadSpend reads the estimated USD spend keyed by local date (YYYY-MM-DD)
+func loadSpend() (map[string]float64, error) {
+	path, err := spendFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- Spend path is resolved under the current user's ~/.ch directory.
+	if os.IsNotExist(err) {
+		return map[string]float64{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spend file: %w", err)
+	}
+	spend := map[string]float64{}
+	if err := json.Unmarshal(data, &spend); err != nil {
+		return nil, fmt.Errorf("failed to parse spend file: %w", err)
+	}
+	return spend, nil
+}
+
+// DailySpend returns today's estimated USD spend across ch runs
+func DailySpend() float64 {
+	spend, err := loadSpend()
+	if err != nil {
+		return 0
+	}
+	return spend[time.Now().Format("2006-01-02")]
+}
+
+// addDailySpend adds cost to the day of now, dropping days older than a month
+func addDailySpend(now time.Time, cost float64) error {
+	spend, err := loadSpend()
+	if err != nil {
+		spend = map[string]float64{} // start over rather than failing every request on a corrupt file
+	}
+	spend[now.Format("2006-01-02")] += cost
+
+	cutoff := now.AddDate(0, -1, 0).Format("2006-01-02")
+	for day := range spend {
+		if day < cutoff {
+			delete(spend, day)
+		}
+	}
+
+	path, err := spendFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(spend, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
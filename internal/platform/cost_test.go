@@ -0,0 +1,145 @@
+package platform
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestModelPriceMatchesWholeNames(t *testing.T) {
+	m := NewManager(&types.Config{ModelPrices: map[string]types.ModelPrice{
+		"gpt-4o":   {Input: 1, Output: 2},
+		"my-local": {Input: 0.5, Output: 0.5},
+	}})
+
+	if price, ok := m.ModelPrice("gpt-4o-mini-2024-07-18"); !ok || price.Input != 0.15 {
+		t.Errorf("gpt-4o-mini should not be priced as gpt-4o, got %+v", price)
+	}
+	if price, _ := m.ModelPrice("openai/gpt-4o"); price.Input != 1 {
+		t.Errorf("configured price should override the built-in one, got %+v", price)
+	}
+	if _, ok := m.ModelPrice("llama3"); ok {
+		t.Error("unknown models should have no price")
+	}
+	if got := m.requestCost("my-local-20250101", 1_000_000, 2_000_000); math.Abs(got-1.5) > 1e-9 {
+		t.Errorf("requestCost = %v, want 1.5", got)
+	}
+}
+
+func TestModelPriceKeepsVariantsApart(t *testing.T) {
+	m := NewManager(&types.Config{})
+	tests := []struct {
+		model  string
+		input  float64
+		output float64
+		priced bool
+	}{
+		{"gpt-5.4", 2.50, 15, true},
+		{"gpt-5.4-mini", 0.75, 4.50, true},
+		{"openai/gpt-5.4-mini-2026-03-17", 0.75, 4.50, true},
+		{"gpt-5", 1.25, 10, true},
+		{"grok-3-mini", 0.30, 0.50, true},
+		{"grok-3", 3, 15, true},
+		{"o3-pro", 20, 80, true},
+		{"o3", 2, 8, true},
+		{"claude-sonnet-4-20250514", 3, 15, true},
+		{"gpt-5.4-nano", 0, 0, false},
+		{"o3-deep-research", 0, 0, false},
+	}
+	for _, tt := range tests {
+		price, ok := m.ModelPrice(tt.model)
+		if ok != tt.priced || price.Input != tt.input || price.Output != tt.output {
+			t.Errorf("ModelPrice(%q) = %+v, %v; want $%v/$%v, %v", tt.model, price, ok, tt.input, tt.output, tt.priced)
+		}
+	}
+}
+
+func TestCheckSpendLimit(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	cfg := &types.Config{
+		ModelPrices:    map[string]types.ModelPrice{"pricey": {Input: 1000, Output: 1000}},
+		MaxSessionCost: 1,
+	}
+	m := NewManager(cfg)
+	messages := []types.ChatMessage{{Role: "user", Content: "hello"}}
+
+	if err := m.checkSpendLimit(messages, "free-model"); err != nil {
+		t.Errorf("unpriced models should never be limited: %v", err)
+	}
+
+	var asked string
+	m.ConfirmSpend = func(question string) bool {
+		asked = question
+		return true
+	}
+	if err := m.checkSpendLimit(messages, "pricey"); err != nil || !strings.Contains(asked, "session $0.0000 of $1.00") {
+		t.Errorf("confirmed request should be allowed, got %v (asked %q)", err, asked)
+	}
+
+	m.ConfirmSpend = nil
+	if err := m.checkSpendLimit(messages, "pricey"); err == nil {
+		t.Error("request over the limit should not be sent when nobody can confirm")
+	}
+
+	cfg.CostLimitAction = CostLimitBlock
+	m.ConfirmSpend = func(string) bool { return true }
+	if err := m.checkSpendLimit(messages, "pricey"); err == nil || !strings.Contains(err.Error(), "not sent") {
+		t.Errorf("block mode should refuse without asking, got %v", err)
+	}
+}
+
+func TestAddRequestUsageTracksSessionAndDay(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	m := NewManager(&types.Config{
+		ModelPrices:  map[string]types.ModelPrice{"pricey": {Input: 1e6, Output: 1e6}},
+		MaxDailyCost: 100,
+	})
+	m.addRequestUsage([]types.ChatMessage{{Role: "user", Content: "hi"}}, "pricey", "hello")
+	if m.SessionSpend() <= 0 {
+		t.Fatal("session spend not recorded")
+	}
+	if today := DailySpend(); math.Abs(today-m.SessionSpend()) > 1e-9 {
+		t.Errorf("daily spend = %v, want %v", today, m.SessionSpend())
+	}
+
+	// Old days are dropped when spend is added
+	if err := addDailySpend(time.Now().AddDate(0, -2, 0), 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := addDailySpend(time.Now(), 1); err != nil {
+		t.Fatal(err)
+	}
+	spend, err := loadSpend()
+	if err != nil || len(spend) != 1 {
+		t.Errorf("expected only today in the spend file, got %v (%v)", spend, err)
+	}
+}
+
+func TestMockPlatformIsNotPriced(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	m := NewManager(&types.Config{CurrentPlatform: MockPlatform, MaxSessionCost: 0.01, MaxDailyCost: 0.01, CostLimitAction: CostLimitBlock})
+	messages := []types.ChatMessage{{Role: "user", Content: strings.Repeat("hi ", 50000)}}
+	if err := m.checkSpendLimit(messages, "gpt-5.4-mini"); err != nil {
+		t.Errorf("checkSpendLimit() on the mock platform = %v", err)
+	}
+
+	m.addRequestUsage(messages, "gpt-5.4-mini", "hello")
+	if usage := m.SessionUsage()["mock/gpt-5.4-mini"]; usage.Requests != 1 || usage.Cost != 0 {
+		t.Errorf("mock usage = %+v, want one request at no cost", usage)
+	}
+	if m.SessionSpend() != 0 || DailySpend() != 0 {
+		t.Errorf("mock spend = %v session, %v today, want none", m.SessionSpend(), DailySpend())
+	}
+}
@@ -0,0 +1,104 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/internal/ui"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrDryRun is returned by SendChatRequest under --dry-run, after the
+// request it would have sent has been printed
+var ErrDryRun = errors.New("dry run, request not sent")
+
+// dryRunReport describes the request SendChatRequest would send: the target
+// platform, base URL, and model, the request fields with any opt-out and
+// extra_body fields merged in, every message with its size and tokens, and
+// the estimated cost on billable platforms
+func (m *Manager) dryRunReport(req openai.ChatCompletionRequest) string {
+	label := func(name string) string {
+		if m.config.IsPipedOutput {
+			return name
+		}
+		return "\033[96m" + name + "\033[0m"
+	}
+	dim := func(text string) string {
+		if m.config.IsPipedOutput {
+			return text
+		}
+		return "\033[90m" + text + "\033[0m"
+	}
+
+	baseURL := m.config.CurrentBaseURL
+	if baseURL == "" {
+		baseURL = openai.DefaultConfig("").BaseURL
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", label("platform:"), m.config.CurrentPlatform)
+	fmt.Fprintf(&b, "%s %s\n", label("base url:"), baseURL)
+	fmt.Fprintf(&b, "%s %s\n", label("model:"), req.Model)
+	fmt.Fprintf(&b, "%s %s\n", label("request:"), m.dryRunFields(req))
+
+	tok := tokens.For(req.Model)
+	totalBytes, totalTokens := 0, 0
+	var messages strings.Builder
+	for i, msg := range req.Messages {
+		content, images := msg.Content, 0
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeImageURL {
+				images++
+			} else {
+				content += part.Text
+			}
+		}
+		n := tok.Count(content)
+		totalBytes += len(content)
+		totalTokens += n
+		header := fmt.Sprintf("--- [%d] %s · %s · %s", i+1, msg.Role, ui.Plural(len(content), "byte"), ui.Plural(n, "token"))
+		if images > 0 {
+			header += " · " + ui.Plural(images, "image")
+		}
+		fmt.Fprintf(&messages, "%s\n%s\n", dim(header+" ---"), content)
+	}
+	fmt.Fprintf(&b, "%s %s, %s, %s\n", label("messages:"), ui.Plural(len(req.Messages), "message"), ui.Plural(totalBytes, "byte"), ui.Plural(totalTokens, "token"))
+	b.WriteString(messages.String())
+
+	switch _, priced := m.ModelPrice(req.Model); {
+	case !m.billable():
+		// The mock platform is free, so there is no cost to estimate
+	case priced:
+		fmt.Fprintf(&b, "%s $%.4f input, about $%.4f with %d output tokens\n", label("estimated cost:"),
+			m.requestCost(req.Model, totalTokens, 0), m.requestCost(req.Model, totalTokens, costGuardOutputTokens), costGuardOutputTokens)
+	default:
+		fmt.Fprintf(&b, "%s unknown, no price for %s in model_prices\n", label("estimated cost:"), req.Model)
+	}
+	return b.String()
+}
+
+// dryRunFields returns the request body without its messages, as the
+// platform's transport would send it
+func (m *Manager) dryRunFields(req openai.ChatCompletionRequest) string {
+	req.Messages = nil
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err.Error()
+	}
+	_, params, modelParams := m.chatRequestFields(m.config.CurrentPlatform)
+	body = mergeBodyParams(body, params, modelParams)
+
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+	delete(fields, "messages")
+	trimmed, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+	return string(trimmed)
+}
@@ -0,0 +1,76 @@
+package platform
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestDryRunPrintsInsteadOfSending(t *testing.T) {
+	sent := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = true
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		CurrentPlatform: "local",
+		IsPipedOutput:   true,
+		DryRun:          true,
+		ExtraBody:       map[string]map[string]any{"local": {"service_tier": "flex"}},
+		Platforms: map[string]types.Platform{"local": {
+			Name:    "local",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_DRY_RUN_KEY_UNSET",
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() should not need an API key in a dry run: %v", err)
+	}
+
+	var cancel func()
+	var streaming bool
+	messages := []types.ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hi"}}
+	if _, err := m.SendChatRequest(messages, "gpt-4o", &cancel, &streaming); !errors.Is(err, ErrDryRun) {
+		t.Errorf("SendChatRequest() error = %v, want ErrDryRun", err)
+	}
+	if _, err := m.SendSilentChatRequest(messages, "gpt-4o", &cancel, &streaming); !errors.Is(err, ErrDryRun) {
+		t.Errorf("SendSilentChatRequest() error = %v, want ErrDryRun", err)
+	}
+	if sent {
+		t.Error("a dry run should not reach the provider")
+	}
+
+	report := m.dryRunReport(m.newChatRequest(m.openAIMessages(messages, "gpt-4o"), "gpt-4o"))
+	for _, want := range []string{
+		"platform: local\n",
+		"base url: " + server.URL + "\n",
+		"model: gpt-4o\n",
+		`"service_tier":"flex"`,
+		"messages: 2 messages, 10 bytes, ",
+		"--- [1] system · 8 bytes · ",
+		"be brief\n",
+		"estimated cost: $",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, `"messages"`) {
+		t.Errorf("request fields should leave out the messages:\n%s", report)
+	}
+}
+
+func TestDryRunOmitsCostOnMockPlatform(t *testing.T) {
+	m := NewManager(&types.Config{CurrentPlatform: MockPlatform, IsPipedOutput: true, DryRun: true})
+	messages := []types.ChatMessage{{Role: "user", Content: "hi"}}
+	report := m.dryRunReport(m.newChatRequest(m.openAIMessages(messages, "gpt-4o"), "gpt-4o"))
+	if strings.Contains(report, "estimated cost") {
+		t.Errorf("mock dry run should not estimate a cost:\n%s", report)
+	}
+}
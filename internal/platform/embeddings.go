@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// embeddingMaxRetries is how many times a rate-limited batch is retried
+const embeddingMaxRetries = 3
+
+// embeddingSleep pauses between requests; replaced in tests
+var embeddingSleep = time.Sleep
+
+// EmbeddingOptions controls the model, batching, and pacing of embedding requests
+type EmbeddingOptions struct {
+	Model             string
+	BatchSize         int
+	RequestsPerMinute int
+}
+
+// CreateEmbeddings returns one vector per input, in input order. Inputs are
+// sent in batches, spaced out to stay under RequestsPerMinute when it is set,
+// and batches rejected with HTTP 429 are retried with exponential backoff.
+func (m *Manager) CreateEmbeddings(inputs []string, opts EmbeddingOptions) ([][]float32, error) {
+	if m.client == nil {
+		return nil, fmt.Errorf("platform client is not initialized")
+	}
+	if opts.Model == "" {
+		return nil, fmt.Errorf("no embedding model specified")
+	}
+	if m.config.DryRun {
+		return nil, ErrDryRun
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(inputs)
+	}
+	var interval time.Duration
+	if opts.RequestsPerMinute > 0 {
+		interval = time.Minute / time.Duration(opts.RequestsPerMinute)
+	}
+
+	vectors := make([][]float32, 0, len(inputs))
+	var lastRequest time.Time
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+		batch := inputs[start:end]
+
+		var resp openai.EmbeddingResponse
+		var err error
+		for attempt := 0; ; attempt++ {
+			if interval > 0 && !lastRequest.IsZero() {
+				if wait := interval - time.Since(lastRequest); wait > 0 {
+					embeddingSleep(wait)
+				}
+			}
+			lastRequest = time.Now()
+
+			resp, err = m.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+				Input: batch,
+				Model: openai.EmbeddingModel(opts.Model),
+			})
+			if err == nil || !isRateLimitError(err) || attempt == embeddingMaxRetries {
+				break
+			}
+			embeddingSleep(time.Duration(1<<attempt) * time.Second)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("embedding request failed: %w", err)
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(resp.Data))
+		}
+
+		// Providers may return items out of order, so place them by index
+		ordered := make([][]float32, len(batch))
+		for _, item := range resp.Data {
+			if item.Index < 0 || item.Index >= len(batch) {
+				return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+			}
+			ordered[item.Index] = item.Embedding
+		}
+		vectors = append(vectors, ordered...)
+	}
+
+	return vectors, nil
+}
+
+// isRateLimitError reports whether err is an HTTP 429 from the provider
+func isRateLimitError(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
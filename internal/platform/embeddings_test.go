@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newEmbeddingTestManager points a manager at a fake embeddings endpoint
+func newEmbeddingTestManager(t *testing.T, handler http.HandlerFunc) *Manager {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = server.URL
+	return &Manager{client: openai.NewClientWithConfig(clientConfig), config: &types.Config{}}
+}
+
+func TestCreateEmbeddingsBatchesAndOrders(t *testing.T) {
+	var batches [][]string
+	m := newEmbeddingTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != "embed-small" {
+			t.Errorf("model = %q", req.Model)
+		}
+		batches = append(batches, req.Input)
+
+		// Reply in reverse order to check results are placed by index
+		var data []map[string]interface{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{
+				"object":    "embedding",
+				"index":     i,
+				"embedding": []float32{float32(len(req.Input[i]))},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	})
+
+	inputs := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	vectors, err := m.CreateEmbeddings(inputs, EmbeddingOptions{Model: "embed-small", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("CreateEmbeddings: %v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+	for i, vec := range vectors {
+		if len(vec) != 1 || vec[0] != float32(len(inputs[i])) {
+			t.Errorf("vector %d = %v", i, vec)
+		}
+	}
+}
+
+func TestCreateEmbeddingsRetriesRateLimit(t *testing.T) {
+	var sleeps []time.Duration
+	orig := embeddingSleep
+	embeddingSleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	defer func() { embeddingSleep = orig }()
+
+	calls := 0
+	m := newEmbeddingTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"slow down","type":"rate_limit"}}`))
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5]}]}`))
+	})
+
+	vectors, err := m.CreateEmbeddings([]string{"x"}, EmbeddingOptions{Model: "m"})
+	if err != nil {
+		t.Fatalf("CreateEmbeddings: %v", err)
+	}
+	if calls != 2 || len(vectors) != 1 || vectors[0][0] != 0.5 {
+		t.Errorf("calls = %d, vectors = %v", calls, vectors)
+	}
+	if len(sleeps) != 1 || sleeps[0] != time.Second {
+		t.Errorf("expected one 1s backoff, got %v", sleeps)
+	}
+}
+
+func TestCreateEmbeddingsRequiresModel(t *testing.T) {
+	m := newEmbeddingTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request expected without a model")
+	})
+	if _, err := m.CreateEmbeddings([]string{"x"}, EmbeddingOptions{}); err == nil {
+		t.Error("expected an error without a model")
+	}
+}
@@ -0,0 +1,116 @@
+package platform
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// Auth styles for the auth field of a platform, saying how the key from
+// env_name is sent
+const (
+	AuthBearer = "bearer" // Authorization: Bearer <key>, the default
+	AuthHeader = "header" // the raw key in the auth_header header
+	AuthQuery  = "query"  // the key in the auth_param query parameter
+	AuthNone   = "none"   // no key, e.g. a local LM Studio or llama.cpp server
+)
+
+// Defaults for auth_header and auth_param
+const (
+	defaultAuthHeader = "x-api-key"
+	defaultAuthParam  = "key"
+)
+
+// checkAuth reports an auth value that is not one of the known styles
+func checkAuth(platform types.Platform) error {
+	switch platform.Auth {
+	case "", AuthBearer, AuthHeader, AuthQuery, AuthNone:
+		return nil
+	}
+	return fmt.Errorf("unknown auth %q for %s (use bearer, header, query, or none)", platform.Auth, platform.Name)
+}
+
+// needsAPIKey reports whether requests to platform carry a key read from
+// env_name. Ollama, the mock platform, auth "none", and platforms without
+// env_name do not.
+func needsAPIKey(platform types.Platform) bool {
+	return platform.Name != "ollama" && platform.Name != MockPlatform && platform.Auth != AuthNone && platform.EnvName != ""
+}
+
+// sendsOwnAuth reports whether the platform's key is added by authTransport
+// rather than as go-openai's bearer token
+func sendsOwnAuth(platform types.Platform) bool {
+	return platform.Auth == AuthHeader || platform.Auth == AuthQuery
+}
+
+// setPlatformAuth adds apiKey to req in the platform's auth style
+func setPlatformAuth(req *http.Request, platform types.Platform, apiKey string) {
+	if apiKey == "" {
+		return
+	}
+	switch platform.Auth {
+	case AuthNone:
+	case AuthHeader:
+		name := platform.AuthHeader
+		if name == "" {
+			name = defaultAuthHeader
+		}
+		req.Header.Set(name, apiKey)
+	case AuthQuery:
+		name := platform.AuthParam
+		if name == "" {
+			name = defaultAuthParam
+		}
+		query := req.URL.Query()
+		query.Set(name, apiKey)
+		req.URL.RawQuery = query.Encode()
+	default:
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// authTransport sends the platform's key in a header or query parameter,
+// for platforms whose auth style go-openai cannot express
+type authTransport struct {
+	base     http.RoundTripper
+	platform types.Platform
+	apiKey   string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	setPlatformAuth(req, t.platform, t.apiKey)
+	return t.base.RoundTrip(req)
+}
+
+// platformAPIKey returns the platform's key, or "" when it sends none
+func platformAPIKey(platform types.Platform) string {
+	if !needsAPIKey(platform) {
+		return ""
+	}
+	return os.Getenv(platform.EnvName)
+}
+
+// staticModels lists the models set under models.static, for endpoints
+// with no model list
+func staticModels(platform types.Platform) []modelWithTime {
+	models := make([]modelWithTime, len(platform.Models.Static))
+	for i, name := range platform.Models.Static {
+		models[i] = modelWithTime{name: name}
+	}
+	return models
+}
+
+// isLoopbackHost reports whether host is this machine, where model lists
+// may be fetched over plain http
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
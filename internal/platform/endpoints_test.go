@@ -0,0 +1,135 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestCustomEndpointAuthStyles(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(r.Context())
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	t.Setenv("TEST_GATEWAY_KEY", "secret")
+
+	tests := []struct {
+		name     string
+		platform types.Platform
+		check    func(r *http.Request) bool
+	}{
+		{"bearer", types.Platform{EnvName: "TEST_GATEWAY_KEY"}, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer secret"
+		}},
+		{"header", types.Platform{EnvName: "TEST_GATEWAY_KEY", Auth: AuthHeader, AuthHeader: "X-Gateway-Key"}, func(r *http.Request) bool {
+			return r.Header.Get("X-Gateway-Key") == "secret" && r.Header.Get("Authorization") == ""
+		}},
+		{"query", types.Platform{EnvName: "TEST_GATEWAY_KEY", Auth: AuthQuery}, func(r *http.Request) bool {
+			return r.URL.Query().Get("key") == "secret" && r.Header.Get("Authorization") == ""
+		}},
+		{"none", types.Platform{EnvName: "TEST_GATEWAY_KEY", Auth: AuthNone}, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "" && r.URL.RawQuery == ""
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.platform.Name = "gateway"
+			tt.platform.BaseURL = types.BaseURLValue{Single: server.URL + "/v1"}
+			tt.platform.Headers = map[string]string{"X-Team": "search"}
+			m := NewManager(&types.Config{
+				CurrentPlatform: "gateway",
+				IsPipedOutput:   true,
+				Platforms:       map[string]types.Platform{"gateway": tt.platform},
+			})
+			if err := m.Initialize(); err != nil {
+				t.Fatalf("Initialize() error: %v", err)
+			}
+
+			var cancel func()
+			var streaming bool
+			response, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, "local-model", &cancel, &streaming)
+			if err != nil || response != "ok" {
+				t.Fatalf("SendChatRequest() = %q, %v", response, err)
+			}
+			if got.URL.Path != "/v1/chat/completions" || got.Header.Get("X-Team") != "search" || !tt.check(got) {
+				t.Errorf("request %s with headers %v does not use %s auth", got.URL, got.Header, tt.name)
+			}
+		})
+	}
+}
+
+func TestCustomEndpointModels(t *testing.T) {
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		fmt.Fprint(w, `{"data":[{"id":"qwen2.5-coder"}]}`)
+	}))
+	defer server.Close()
+	t.Setenv("TEST_GATEWAY_KEY", "secret")
+	m := NewManager(&types.Config{})
+
+	// A local server over plain http, with the key in a header
+	models, err := m.fetchPlatformModelsWithTime(types.Platform{
+		Name:    "vllm",
+		EnvName: "TEST_GATEWAY_KEY",
+		Auth:    AuthHeader,
+		Headers: map[string]string{"X-Team": "search"},
+		Models:  types.PlatformModels{URL: server.URL + "/v1/models", JSONPath: "data.id", Headers: map[string]string{"X-List": "1"}},
+	})
+	if err != nil {
+		t.Fatalf("fetchPlatformModelsWithTime() error: %v", err)
+	}
+	if labels := sortModelsByTime(models); !reflect.DeepEqual(labels, []string{"qwen2.5-coder"}) {
+		t.Errorf("models = %q", labels)
+	}
+	if gotHeaders.Get("x-api-key") != "secret" || gotHeaders.Get("X-Team") != "search" || gotHeaders.Get("X-List") != "1" || gotHeaders.Get("Authorization") != "" {
+		t.Errorf("model list headers = %v", gotHeaders)
+	}
+
+	// Static lists need no URL and no key
+	models, err = m.fetchPlatformModelsWithTime(types.Platform{
+		Name:   "llamacpp",
+		Auth:   AuthNone,
+		Models: types.PlatformModels{Static: []string{"default"}},
+	})
+	if err != nil || len(models) != 1 || models[0].name != "default" {
+		t.Errorf("static models = %v, %v", models, err)
+	}
+
+	_, err = m.fetchPlatformModelsWithTime(types.Platform{Name: "lmstudio"})
+	if err == nil || !strings.Contains(err.Error(), "models.static") {
+		t.Errorf("missing model list error = %v", err)
+	}
+	_, err = m.fetchPlatformModelsWithTime(types.Platform{
+		Name:    "remote",
+		EnvName: "TEST_GATEWAY_KEY",
+		Models:  types.PlatformModels{URL: "http://gateway.example.com/v1/models"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Errorf("remote http model list error = %v, want the key kept off plain http", err)
+	}
+}
+
+func TestCheckAuth(t *testing.T) {
+	for _, auth := range []string{"", AuthBearer, AuthHeader, AuthQuery, AuthNone} {
+		if err := checkAuth(types.Platform{Name: "p", Auth: auth}); err != nil {
+			t.Errorf("checkAuth(%q) error: %v", auth, err)
+		}
+	}
+	m := NewManager(&types.Config{
+		CurrentPlatform: "p",
+		Platforms:       map[string]types.Platform{"p": {Name: "p", Auth: "basic"}},
+	})
+	if err := m.Initialize(); err == nil || !strings.Contains(err.Error(), `unknown auth "basic"`) {
+		t.Errorf("Initialize() error = %v", err)
+	}
+}
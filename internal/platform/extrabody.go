@@ -0,0 +1,26 @@
+package platform
+
+import "strings"
+
+// extraBody splits the extra_body config for platform into fields sent with
+// every chat request ("platform" keys) and fields sent only for one model
+// ("platform/model" keys), keyed by model name
+func (m *Manager) extraBody(platform string) (map[string]any, map[string]map[string]any) {
+	var params map[string]any
+	var modelParams map[string]map[string]any
+	for key, fields := range m.config.ExtraBody {
+		if key == platform {
+			params = fields
+			continue
+		}
+		model, ok := strings.CutPrefix(key, platform+"/")
+		if !ok || model == "" {
+			continue
+		}
+		if modelParams == nil {
+			modelParams = make(map[string]map[string]any)
+		}
+		modelParams[model] = fields
+	}
+	return params, modelParams
+}
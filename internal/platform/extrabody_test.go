@@ -0,0 +1,78 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestExtraBodyMergesPlatformAndModelFields(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_GROQ_KEY", "test")
+	cfg := &types.Config{
+		CurrentPlatform: "groq",
+		IsPipedOutput:   true,
+		ExtraBody: map[string]map[string]any{
+			"groq":                {"service_tier": "flex", "reasoning_effort": "low"},
+			"groq/thinking-model": {"reasoning_effort": "high"},
+			"openai":              {"service_tier": "priority"},
+		},
+		Platforms: map[string]types.Platform{"groq": {
+			Name:    "groq",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_GROQ_KEY",
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	send := func(model string) {
+		t.Helper()
+		var cancel func()
+		var streaming bool
+		if _, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, model, &cancel, &streaming); err != nil {
+			t.Fatalf("SendChatRequest(%s) error: %v", model, err)
+		}
+	}
+
+	send("other-model")
+	if body["service_tier"] != "flex" || body["reasoning_effort"] != "low" {
+		t.Errorf("platform extra_body not sent: %v", body)
+	}
+
+	send("thinking-model")
+	if body["service_tier"] != "flex" || body["reasoning_effort"] != "high" {
+		t.Errorf("model extra_body should override platform fields: %v", body)
+	}
+	if body["model"] != "thinking-model" {
+		t.Errorf("original request fields lost: %v", body)
+	}
+}
+
+func TestExtraBodyIgnoresOtherPlatforms(t *testing.T) {
+	m := NewManager(&types.Config{ExtraBody: map[string]map[string]any{
+		"openai":        {"service_tier": "priority"},
+		"openrouter/x":  {"top_k": 5},
+		"groq/":         {"ignored": true},
+		"groqish/model": {"ignored": true},
+	}})
+	if m.chatHTTPClient("groq") != nil {
+		t.Error("platforms without extra_body entries should use the default client")
+	}
+}
@@ -0,0 +1,448 @@
+package platform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriverGemini is the platform driver that talks to the native Gemini API
+// (generateContent and streamGenerateContent) instead of its OpenAI
+// compatibility endpoint
+const DriverGemini = "gemini"
+
+// geminiPassthroughFields are chat body fields, set with extra_body or
+// model_params, that are sent to Gemini as they are
+var geminiPassthroughFields = []string{"safetySettings", "generationConfig", "cachedContent", "labels"}
+
+// geminiPart is one piece of a Gemini message: text, a thought summary, or
+// inline image data
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	Thought    bool              `json:"thought,omitempty"`
+	InlineData *geminiInlineData `json:"inlineData,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// geminiResponse is a generateContent response, or one streamed chunk of it
+type geminiResponse struct {
+	Candidates []struct {
+		Content       geminiContent        `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiTransport translates the OpenAI chat completion requests the client
+// sends into native Gemini requests and the replies back, so the Gemini
+// driver runs through the same streaming, cancel, and usage code as every
+// other platform. Other endpoints, like embeddings, go to the OpenAI
+// compatibility endpoint.
+type geminiTransport struct {
+	base   http.RoundTripper
+	prefix string // path of the configured base URL
+	native string // the API version path, prefix without /openai
+
+	mu     sync.Mutex
+	finish string // notice about how the last response ended, if unusual
+}
+
+// newGeminiTransport returns the transport for a platform whose base URL is
+// baseURL, either the native API (…/v1beta) or its /openai endpoint
+func newGeminiTransport(base http.RoundTripper, baseURL string) *geminiTransport {
+	prefix := ""
+	if parsed, err := url.Parse(baseURL); err == nil {
+		prefix = strings.TrimSuffix(parsed.Path, "/")
+	}
+	return &geminiTransport{base: base, prefix: prefix, native: strings.TrimSuffix(prefix, "/openai")}
+}
+
+// takeFinish returns and clears the notice about the last response
+func (t *geminiTransport) takeFinish() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	notice := t.finish
+	t.finish = ""
+	return notice
+}
+
+func (t *geminiTransport) setFinish(notice string) {
+	t.mu.Lock()
+	t.finish = notice
+	t.mu.Unlock()
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *geminiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rest := strings.TrimPrefix(req.URL.Path, t.prefix)
+	out := req.Clone(req.Context())
+	if req.Method != http.MethodPost || rest != "/chat/completions" {
+		out.URL.Path = t.native + "/openai" + rest
+		return t.base.RoundTrip(out)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var chatReq openai.ChatCompletionRequest
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &chatReq); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(geminiRequestBody(chatReq, raw))
+	if err != nil {
+		return nil, err
+	}
+
+	model := chatReq.Model
+	if !strings.Contains(model, "/") {
+		model = "models/" + model
+	}
+	out.URL.Path = t.native + "/" + model + ":generateContent"
+	if chatReq.Stream {
+		out.URL.Path = t.native + "/" + model + ":streamGenerateContent"
+		out.URL.RawQuery = "alt=sse"
+	}
+	out.Body = io.NopCloser(strings.NewReader(string(body)))
+	out.ContentLength = int64(len(body))
+	out.Header.Set("x-goog-api-key", strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+	out.Header.Del("Authorization")
+	t.setFinish("")
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		// Gemini errors already have the {"error": {"message": ...}} shape
+		return resp, err
+	}
+	if chatReq.Stream {
+		return t.stream(req, resp, chatReq.Model)
+	}
+
+	defer resp.Body.Close()
+	var reply geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+	if blocked := geminiBlockedPrompt(reply); blocked != "" {
+		return geminiErrorResponse(req, blocked)
+	}
+	text, thoughts, reason := geminiCandidate(reply)
+	t.setFinish(geminiFinishNotice(reply))
+	completion := openai.ChatCompletionResponse{
+		ID:      "gemini",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   chatReq.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text, ReasoningContent: thoughts},
+			FinishReason: reason,
+		}},
+	}
+	if usage := geminiUsage(reply); usage != nil {
+		completion.Usage = *usage
+	}
+	return mockJSONResponse(req, http.StatusOK, completion)
+}
+
+// stream converts a Gemini server-sent event stream into OpenAI chunks. The
+// first event is read before returning, so a blocked prompt is an error
+// rather than an empty reply.
+func (t *geminiTransport) stream(req *http.Request, resp *http.Response, model string) (*http.Response, error) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	next := func() (*geminiResponse, bool) {
+		for scanner.Scan() {
+			payload, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var event geminiResponse
+			if json.Unmarshal([]byte(strings.TrimSpace(payload)), &event) == nil {
+				return &event, true
+			}
+		}
+		return nil, false
+	}
+
+	first, ok := next()
+	if ok {
+		if blocked := geminiBlockedPrompt(*first); blocked != "" {
+			_ = resp.Body.Close()
+			return geminiErrorResponse(req, blocked)
+		}
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer resp.Body.Close()
+		send := func(chunk map[string]any) bool {
+			chunk["model"] = model
+			data, _ := json.Marshal(chunk)
+			_, err := fmt.Fprintf(writer, "data: %s\n\n", data)
+			return err == nil
+		}
+		var usage *openai.Usage
+		for event := first; event != nil; event, _ = next() {
+			text, thoughts, reason := geminiCandidate(*event)
+			if u := geminiUsage(*event); u != nil {
+				usage = u
+			}
+			if notice := geminiFinishNotice(*event); notice != "" {
+				t.setFinish(notice)
+			}
+			choice := map[string]any{"index": 0, "delta": map[string]string{"content": text, "reasoning_content": thoughts}}
+			if reason != "" {
+				choice["finish_reason"] = reason
+			}
+			if !send(map[string]any{"choices": []map[string]any{choice}}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			_ = writer.CloseWithError(err)
+			return
+		}
+		if usage != nil && !send(map[string]any{"choices": []map[string]any{}, "usage": usage}) {
+			return
+		}
+		_, _ = io.WriteString(writer, "data: [DONE]\n\n")
+		_ = writer.Close()
+	}()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       reader,
+		Request:    req,
+	}, nil
+}
+
+// geminiRequestBody builds a generateContent body from a chat request.
+// System messages become the system instruction, assistant turns are sent
+// as the model role, and data URL image parts are sent as inline data.
+func geminiRequestBody(req openai.ChatCompletionRequest, raw map[string]json.RawMessage) map[string]any {
+	var contents []geminiContent
+	var system []geminiPart
+	for _, msg := range req.Messages {
+		parts := geminiParts(msg)
+		if len(parts) == 0 {
+			continue
+		}
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			system = append(system, parts...)
+		case openai.ChatMessageRoleAssistant:
+			contents = append(contents, geminiContent{Role: "model", Parts: parts})
+		default:
+			contents = append(contents, geminiContent{Role: "user", Parts: parts})
+		}
+	}
+
+	body := map[string]any{"contents": contents}
+	if len(system) > 0 {
+		body["systemInstruction"] = geminiContent{Parts: system}
+	}
+
+	config := map[string]any{}
+	if len(req.Stop) > 0 {
+		config["stopSequences"] = req.Stop
+	}
+	if req.Seed != nil {
+		config["seed"] = *req.Seed
+	}
+	if req.Temperature != 0 {
+		config["temperature"] = req.Temperature
+	}
+	if req.TopP != 0 {
+		config["topP"] = req.TopP
+	}
+	if tokens := max(req.MaxTokens, req.MaxCompletionTokens); tokens > 0 {
+		config["maxOutputTokens"] = tokens
+	}
+	if len(config) > 0 {
+		body["generationConfig"] = config
+	}
+
+	for _, field := range geminiPassthroughFields {
+		value, ok := raw[field]
+		if !ok {
+			continue
+		}
+		// A generationConfig set by the user is merged over the one built here
+		var fields map[string]any
+		if field == "generationConfig" && json.Unmarshal(value, &fields) == nil {
+			for key, v := range fields {
+				config[key] = v
+			}
+			body[field] = config
+			continue
+		}
+		body[field] = value
+	}
+	return body
+}
+
+// geminiParts converts one message to Gemini parts. Image URLs that are not
+// data URLs cannot be sent inline, so they are referenced as text.
+func geminiParts(msg openai.ChatCompletionMessage) []geminiPart {
+	if len(msg.MultiContent) == 0 {
+		if msg.Content == "" {
+			return nil
+		}
+		return []geminiPart{{Text: msg.Content}}
+	}
+	var parts []geminiPart
+	for _, part := range msg.MultiContent {
+		switch {
+		case part.Type == openai.ChatMessagePartTypeText && part.Text != "":
+			parts = append(parts, geminiPart{Text: part.Text})
+		case part.Type == openai.ChatMessagePartTypeImageURL && part.ImageURL != nil:
+			if mimeType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: mimeType, Data: data}})
+			} else {
+				parts = append(parts, geminiPart{Text: "Image: " + part.ImageURL.URL})
+			}
+		}
+	}
+	return parts
+}
+
+// parseDataURL splits a base64 data URL into its MIME type and data
+func parseDataURL(value string) (string, string, bool) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(value, "data:"), ",")
+	if !ok || !strings.HasPrefix(value, "data:") || !strings.HasSuffix(header, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header, ";base64"), data, true
+}
+
+// geminiCandidate returns the text and thought summary of the first
+// candidate and its finish reason in OpenAI terms
+func geminiCandidate(reply geminiResponse) (string, string, openai.FinishReason) {
+	if len(reply.Candidates) == 0 {
+		return "", "", ""
+	}
+	candidate := reply.Candidates[0]
+	var text, thoughts strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thoughts.WriteString(part.Text)
+		} else {
+			text.WriteString(part.Text)
+		}
+	}
+
+	var reason openai.FinishReason
+	switch candidate.FinishReason {
+	case "":
+	case "STOP":
+		reason = openai.FinishReasonStop
+	case "MAX_TOKENS":
+		reason = openai.FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		reason = openai.FinishReasonContentFilter
+	default:
+		reason = openai.FinishReasonStop
+	}
+	return text.String(), thoughts.String(), reason
+}
+
+// geminiUsage converts usage metadata, counting thinking tokens as output
+func geminiUsage(reply geminiResponse) *openai.Usage {
+	meta := reply.UsageMetadata
+	if meta == nil || meta.TotalTokenCount == 0 {
+		return nil
+	}
+	return &openai.Usage{
+		PromptTokens:     meta.PromptTokenCount,
+		CompletionTokens: meta.CandidatesTokenCount + meta.ThoughtsTokenCount,
+		TotalTokens:      meta.TotalTokenCount,
+	}
+}
+
+// geminiFinishNotice explains a response that did not end normally, naming
+// the safety categories that stopped it
+func geminiFinishNotice(reply geminiResponse) string {
+	if len(reply.Candidates) == 0 {
+		return ""
+	}
+	candidate := reply.Candidates[0]
+	switch candidate.FinishReason {
+	case "", "STOP":
+		return ""
+	case "MAX_TOKENS":
+		return "warning: response stopped at the output token limit"
+	case "SAFETY", "IMAGE_SAFETY":
+		return "warning: response stopped by Gemini safety filters" + geminiSafetyDetail(candidate.SafetyRatings)
+	}
+	return "warning: response stopped early (finish reason " + candidate.FinishReason + ")"
+}
+
+// geminiBlockedPrompt describes why Gemini refused the prompt, if it did
+func geminiBlockedPrompt(reply geminiResponse) string {
+	feedback := reply.PromptFeedback
+	if feedback == nil || feedback.BlockReason == "" {
+		return ""
+	}
+	return fmt.Sprintf("prompt blocked by Gemini (%s)%s", feedback.BlockReason, geminiSafetyDetail(feedback.SafetyRatings))
+}
+
+// geminiSafetyDetail lists the blocked or likely harm categories, e.g.
+// ": dangerous content HIGH, harassment MEDIUM"
+func geminiSafetyDetail(ratings []geminiSafetyRating) string {
+	var flagged []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "MEDIUM" || rating.Probability == "HIGH" {
+			category := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(rating.Category, "HARM_CATEGORY_"), "_", " "))
+			flagged = append(flagged, category+" "+rating.Probability)
+		}
+	}
+	if len(flagged) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(flagged, ", ")
+}
+
+// geminiErrorResponse returns message as an OpenAI-style error response
+func geminiErrorResponse(req *http.Request, message string) (*http.Response, error) {
+	return mockJSONResponse(req, http.StatusBadRequest, map[string]any{
+		"error": map[string]any{"message": message, "type": "content_filter"},
+	})
+}
@@ -0,0 +1,162 @@
+package platform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func newGeminiTestManager(t *testing.T, handler http.HandlerFunc) *Manager {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("TEST_GEMINI_KEY", "test-key")
+	m := NewManager(&types.Config{
+		CurrentPlatform: "google",
+		IsPipedOutput:   true,
+		Platforms: map[string]types.Platform{"google": {
+			Name:    "google",
+			BaseURL: types.BaseURLValue{Single: server.URL + "/v1beta"},
+			EnvName: "TEST_GEMINI_KEY",
+			Driver:  DriverGemini,
+		}},
+	})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	return m
+}
+
+func TestGeminiStreamsNativeRequest(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(image, []byte("png bytes"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var path, query, key, auth string
+	var body map[string]json.RawMessage
+	m := newGeminiTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.RawQuery
+		key, auth = r.Header.Get("x-goog-api-key"), r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hello "}]}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"world"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":2,"totalTokenCount":9}}`+"\n\n")
+	})
+
+	var cancel func()
+	var streaming bool
+	response, err := m.SendChatRequest([]types.ChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "ok"},
+		{Role: "user", Content: "describe this", Images: []string{image}},
+	}, "gemini-2.5-flash", &cancel, &streaming)
+	if err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+
+	if response != "Hello world" {
+		t.Errorf("response = %q, want %q", response, "Hello world")
+	}
+	if path != "/v1beta/models/gemini-2.5-flash:streamGenerateContent" || query != "alt=sse" {
+		t.Errorf("request went to %s?%s", path, query)
+	}
+	if key != "test-key" || auth != "" {
+		t.Errorf("x-goog-api-key = %q, Authorization = %q; want the key only in x-goog-api-key", key, auth)
+	}
+	if m.lastUsage == nil || m.lastUsage.PromptTokens != 7 || m.lastUsage.TotalTokens != 9 {
+		t.Errorf("usage = %+v, want 7 prompt and 9 total tokens", m.lastUsage)
+	}
+
+	var system geminiContent
+	var contents []geminiContent
+	_ = json.Unmarshal(body["systemInstruction"], &system)
+	_ = json.Unmarshal(body["contents"], &contents)
+	if len(system.Parts) != 1 || system.Parts[0].Text != "Be brief." {
+		t.Errorf("systemInstruction = %+v", system)
+	}
+	if len(contents) != 3 || contents[0].Role != "user" || contents[1].Role != "model" || contents[2].Role != "user" {
+		t.Fatalf("contents = %+v, want user, model, user", contents)
+	}
+	last := contents[2].Parts
+	if len(last) != 2 || last[0].Text != "describe this" || last[1].InlineData == nil {
+		t.Fatalf("last message parts = %+v, want text then an image", last)
+	}
+	if last[1].InlineData.MimeType != "image/png" || last[1].InlineData.Data != base64.StdEncoding.EncodeToString([]byte("png bytes")) {
+		t.Errorf("inline image = %+v", last[1].InlineData)
+	}
+}
+
+func TestGeminiBlockedPromptIsAnError(t *testing.T) {
+	m := newGeminiTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":generateContent") {
+			t.Errorf("silent request went to %s, want generateContent", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"promptFeedback":{"blockReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true},{"category":"HARM_CATEGORY_HATE_SPEECH","probability":"NEGLIGIBLE"}]}}`)
+	})
+
+	var cancel func()
+	var streaming bool
+	_, err := m.SendSilentChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, "gemini-2.5-flash", &cancel, &streaming)
+	if err == nil || !strings.Contains(err.Error(), "prompt blocked by Gemini (SAFETY): harassment HIGH") {
+		t.Errorf("error = %v, want the block reason and flagged category", err)
+	}
+}
+
+func TestGeminiFinishNotice(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{`{"candidates":[{"finishReason":"STOP"}]}`, ""},
+		{`{"candidates":[{}]}`, ""},
+		{`{"candidates":[{"finishReason":"MAX_TOKENS"}]}`, "warning: response stopped at the output token limit"},
+		{`{"candidates":[{"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_DANGEROUS_CONTENT","probability":"MEDIUM"}]}]}`,
+			"warning: response stopped by Gemini safety filters: dangerous content MEDIUM"},
+		{`{"candidates":[{"finishReason":"RECITATION"}]}`, "warning: response stopped early (finish reason RECITATION)"},
+	}
+	for _, tt := range tests {
+		var reply geminiResponse
+		if err := json.Unmarshal([]byte(tt.reply), &reply); err != nil {
+			t.Fatal(err)
+		}
+		if got := geminiFinishNotice(reply); got != tt.want {
+			t.Errorf("geminiFinishNotice(%s) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestGeminiTransportSendsOtherEndpointsToCompatibilityAPI(t *testing.T) {
+	for _, baseURL := range []string{"https://example.test/v1beta", "https://example.test/v1beta/openai/"} {
+		var got string
+		transport := newGeminiTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.URL.Path
+			return mockJSONResponse(req, http.StatusOK, map[string]any{})
+		}), baseURL)
+
+		req, _ := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+"/embeddings", strings.NewReader("{}"))
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatalf("RoundTrip() error: %v", err)
+		}
+		if got != "/v1beta/openai/embeddings" {
+			t.Errorf("base %s: embeddings went to %s, want /v1beta/openai/embeddings", baseURL, got)
+		}
+	}
+}
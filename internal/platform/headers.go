@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// headerNamePattern matches the token characters HTTP allows in header names
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// ParseHeader parses "Name: value" for !headers. An empty value means the
+// header should be removed.
+func ParseHeader(arg string) (string, string, error) {
+	name, value, ok := strings.Cut(arg, ":")
+	name = strings.TrimSpace(name)
+	if !ok || !headerNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("expected a header as Name: value, got %q", arg)
+	}
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\r\n") {
+		return "", "", fmt.Errorf("header values cannot contain line breaks")
+	}
+	return http.CanonicalHeaderKey(name), value, nil
+}
+
+// ExtraHeaders returns the extra_headers sent to the current platform
+func (m *Manager) ExtraHeaders() map[string]string {
+	return m.config.ExtraHeaders[m.config.CurrentPlatform]
+}
+
+// SetExtraHeader sets a header for every later request to the current
+// platform, or removes it when value is empty. The client is rebuilt so the
+// change applies to the next request.
+func (m *Manager) SetExtraHeader(name, value string) error {
+	platform := m.config.CurrentPlatform
+	headers := m.config.ExtraHeaders[platform]
+	if value == "" {
+		delete(headers, name)
+		if len(headers) == 0 {
+			delete(m.config.ExtraHeaders, platform)
+		}
+	} else {
+		if m.config.ExtraHeaders == nil {
+			m.config.ExtraHeaders = make(map[string]map[string]string)
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+			m.config.ExtraHeaders[platform] = headers
+		}
+		headers[name] = value
+	}
+	return m.Initialize()
+}
+
+// ClearExtraHeaders removes every extra header for the current platform
+func (m *Manager) ClearExtraHeaders() error {
+	delete(m.config.ExtraHeaders, m.config.CurrentPlatform)
+	return m.Initialize()
+}
+
+// FormatHeaders lists headers one per line, sorted by name, with values
+// masked past their first four characters since they are often credentials
+func FormatHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		value := headers[name]
+		if len(value) > 4 {
+			value = value[:4] + strings.Repeat("*", min(len(value)-4, 8))
+		}
+		lines[i] = name + ": " + value
+	}
+	return strings.Join(lines, "\n")
+}
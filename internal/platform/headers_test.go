@@ -0,0 +1,89 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		arg       string
+		name      string
+		value     string
+		wantError bool
+	}{
+		{"X-Portkey-Config: pc-abc", "X-Portkey-Config", "pc-abc", false},
+		{"proxy-authorization:Basic dXNlcg==", "Proxy-Authorization", "Basic dXNlcg==", false},
+		{"X-Remove:", "X-Remove", "", false},
+		{"no colon", "", "", true},
+		{"bad name: x", "", "", true},
+		{": value", "", "", true},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.arg)
+		if (err != nil) != tt.wantError || name != tt.name || value != tt.value {
+			t.Errorf("ParseHeader(%q) = %q, %q, %v", tt.arg, name, value, err)
+		}
+	}
+}
+
+func TestFormatHeadersMasksValues(t *testing.T) {
+	got := FormatHeaders(map[string]string{"X-B": "secret-token-value", "X-A": "abc"})
+	if want := "X-A: abc\nX-B: secr********"; got != want {
+		t.Errorf("FormatHeaders() = %q, want %q", got, want)
+	}
+}
+
+func TestSetExtraHeaderAppliesToNextRequest(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Gateway"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_GATEWAY_KEY", "test")
+	cfg := &types.Config{
+		CurrentPlatform: "gateway",
+		IsPipedOutput:   true,
+		Platforms: map[string]types.Platform{"gateway": {
+			Name:    "gateway",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_GATEWAY_KEY",
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var cancel func()
+	var streaming bool
+	send := func() {
+		if _, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, "some-model", &cancel, &streaming); err != nil {
+			t.Fatalf("SendChatRequest() error: %v", err)
+		}
+	}
+
+	if err := m.SetExtraHeader("X-Gateway", "route-a"); err != nil {
+		t.Fatalf("SetExtraHeader() error: %v", err)
+	}
+	send()
+	if err := m.SetExtraHeader("X-Gateway", ""); err != nil {
+		t.Fatalf("SetExtraHeader() error: %v", err)
+	}
+	send()
+
+	if len(got) != 2 || got[0] != "route-a" || got[1] != "" {
+		t.Errorf("X-Gateway per request = %q, want set then removed", got)
+	}
+	if len(m.ExtraHeaders()) != 0 || len(cfg.ExtraHeaders) != 0 {
+		t.Errorf("removing the last header should drop the platform entry: %v", cfg.ExtraHeaders)
+	}
+}
@@ -0,0 +1,80 @@
+package platform
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxLogprobTokens is how many response tokens are listed with their alternatives.
+// Logprobs are most useful for short completions such as labels or yes/no answers.
+const maxLogprobTokens = 20
+
+// maxTopLogprobs is the most alternatives per token that providers accept
+const maxTopLogprobs = 20
+
+// topLogprobs returns the configured number of alternatives clamped to the API range
+func topLogprobs(n int) int {
+	if n < 0 {
+		return 0
+	}
+	if n > maxTopLogprobs {
+		return maxTopLogprobs
+	}
+	return n
+}
+
+// formatLogprobs renders each token with its probability and top alternatives,
+// listing at most limit tokens
+func formatLogprobs(tokens []openai.LogProb, limit int) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("logprobs:\n")
+	for i, token := range tokens {
+		if i == limit {
+			sb.WriteString(fmt.Sprintf("  ... %d more tokens\n", len(tokens)-limit))
+			break
+		}
+
+		sb.WriteString(fmt.Sprintf("  %-16s %6.2f%%", strconv.Quote(token.Token), logprobPercent(token.LogProb)))
+
+		var alternatives []string
+		for _, alt := range token.TopLogProbs {
+			if alt.Token == token.Token {
+				continue
+			}
+			alternatives = append(alternatives, fmt.Sprintf("%s %.2f%%", strconv.Quote(alt.Token), logprobPercent(alt.LogProb)))
+		}
+		if len(alternatives) > 0 {
+			sb.WriteString("  | " + strings.Join(alternatives, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// logprobPercent converts a natural log probability to a percentage
+func logprobPercent(logprob float64) float64 {
+	return math.Exp(logprob) * 100
+}
+
+// printLogprobs shows token alternatives after a response. Piped output keeps
+// stdout clean by writing them to stderr instead.
+func (m *Manager) printLogprobs(tokens []openai.LogProb) {
+	text := formatLogprobs(tokens, maxLogprobTokens)
+	if text == "" {
+		return
+	}
+	if m.config.IsPipedOutput {
+		fmt.Fprint(os.Stderr, text)
+		return
+	}
+	fmt.Print("\033[90m" + text + "\033[0m")
+}
@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestTopLogprobs(t *testing.T) {
+	tests := map[int]int{-1: 0, 0: 0, 5: 5, 50: maxTopLogprobs}
+	for in, want := range tests {
+		if got := topLogprobs(in); got != want {
+			t.Errorf("topLogprobs(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestFormatLogprobs(t *testing.T) {
+	tokens := []openai.LogProb{
+		{
+			Token:   "Yes",
+			LogProb: math.Log(0.9),
+			TopLogProbs: []openai.TopLogProbs{
+				{Token: "Yes", LogProb: math.Log(0.9)},
+				{Token: "No", LogProb: math.Log(0.08)},
+			},
+		},
+		{Token: ".", LogProb: 0},
+		{Token: "!", LogProb: 0},
+	}
+
+	out := formatLogprobs(tokens, 2)
+	if !strings.Contains(out, `"Yes"`) || !strings.Contains(out, "90.00%") {
+		t.Errorf("missing chosen token probability:\n%s", out)
+	}
+	if !strings.Contains(out, `| "No" 8.00%`) {
+		t.Errorf("missing alternative token:\n%s", out)
+	}
+	if strings.Count(out, `"Yes"`) != 1 {
+		t.Errorf("the chosen token should not be repeated as an alternative:\n%s", out)
+	}
+	if !strings.Contains(out, "... 1 more tokens") {
+		t.Errorf("expected the token list to be truncated:\n%s", out)
+	}
+
+	if formatLogprobs(nil, 5) != "" {
+		t.Error("no tokens should format to an empty string")
+	}
+}
+
+func TestNewChatRequestLogprobs(t *testing.T) {
+	req := NewManager(&types.Config{ShowLogprobs: true, TopLogprobs: 3}).newChatRequest(nil, "gpt-test")
+	if !req.LogProbs || req.TopLogProbs != 3 {
+		t.Errorf("LogProbs=%v TopLogProbs=%d, want true/3", req.LogProbs, req.TopLogProbs)
+	}
+	req = NewManager(&types.Config{TopLogprobs: 3}).newChatRequest(nil, "gpt-test")
+	if req.LogProbs || req.TopLogProbs != 0 {
+		t.Errorf("logprobs should not be requested unless enabled")
+	}
+}
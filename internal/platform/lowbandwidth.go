@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// chunkedWriter collects streamed text and writes it out at most once per
+// interval, so low_bandwidth sessions send a few larger updates instead of
+// one per token
+type chunkedWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	interval time.Duration
+	pending  bytes.Buffer
+	timer    *time.Timer
+}
+
+// Write queues p and schedules a flush at the end of the current interval
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending.Write(p)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.interval, func() { _ = c.Flush() })
+	}
+	return len(p), nil
+}
+
+// Flush writes out queued text right away
+func (c *chunkedWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.pending.Len() == 0 {
+		return nil
+	}
+	_, err := c.w.Write(c.pending.Bytes())
+	c.pending.Reset()
+	return err
+}
+
+// streamOutput returns where streamed reply text is printed and a function
+// that writes out anything still queued, which callers must run when the
+// reply ends
+func (m *Manager) streamOutput() (io.Writer, func()) {
+	if !m.config.LowBandwidth || m.config.StreamJSON {
+		return os.Stdout, func() {}
+	}
+	interval := time.Duration(m.config.LowBandwidthInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	out := &chunkedWriter{w: os.Stdout, interval: interval}
+	return out, func() { _ = out.Flush() }
+}
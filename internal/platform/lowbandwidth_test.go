@@ -0,0 +1,83 @@
+package platform
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// countingWriter records each write it receives
+type countingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes = append(c.writes, string(p))
+	return len(p), nil
+}
+
+func TestChunkedWriterBatchesWrites(t *testing.T) {
+	target := &countingWriter{}
+	out := &chunkedWriter{w: target, interval: time.Hour}
+	for _, token := range []string{"Hel", "lo", ", ", "world"} {
+		_, _ = out.Write([]byte(token))
+	}
+	if len(target.writes) != 0 {
+		t.Fatalf("wrote %q before the interval ended", target.writes)
+	}
+	if err := out.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(target.writes, "|") != "Hello, world" {
+		t.Errorf("writes = %q, want one write of the whole text", target.writes)
+	}
+}
+
+func TestChunkedWriterFlushesAfterInterval(t *testing.T) {
+	target := &countingWriter{}
+	out := &chunkedWriter{w: target, interval: 10 * time.Millisecond}
+	_, _ = out.Write([]byte("partial"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		target.mu.Lock()
+		n := len(target.writes)
+		target.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("queued text was not written after the interval")
+}
+
+func TestStreamOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  types.Config
+		chunked bool
+	}{
+		{"off", types.Config{}, false},
+		{"on", types.Config{LowBandwidth: true}, true},
+		{"stream json", types.Config{LowBandwidth: true, StreamJSON: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, flush := NewManager(&tt.config).streamOutput()
+			defer flush()
+			if _, chunked := out.(*chunkedWriter); chunked != tt.chunked {
+				t.Errorf("streamOutput() = %T, want chunked %v", out, tt.chunked)
+			}
+			if !tt.chunked && out != os.Stdout {
+				t.Errorf("streamOutput() = %T, want os.Stdout", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/sashabaranov/go-openai"
+)
+
+// MockPlatform is the built-in platform that answers from a fixtures file
+// instead of a provider, for offline demos and tests of the CLI flows
+const MockPlatform = "mock"
+
+// mockModel is the model the mock platform lists when fixtures name none
+const mockModel = "mock-model"
+
+// MockFixtures is the mock_fixtures file: scripted replies, the models to
+// list, and the delay between streamed words
+type MockFixtures struct {
+	Models    []string      `json:"models,omitempty"`
+	DelayMS   int           `json:"delay_ms,omitempty"`
+	Responses []MockFixture `json:"responses"`
+}
+
+// MockFixture is one canned reply. With Match it answers every prompt the
+// regex matches; without it, replies are used once each in file order.
+type MockFixture struct {
+	Match     string `json:"match,omitempty"`
+	Response  string `json:"response"`
+	Reasoning string `json:"reasoning,omitempty"`
+}
+
+// mockFixturesPath returns the configured fixtures file, or ~/.ch/mock.json
+func mockFixturesPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(homeDir, path[1:]), nil
+	}
+	if path != "" {
+		return path, nil
+	}
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "mock.json"), nil
+}
+
+// loadMockFixtures reads the fixtures file. A missing default file is not an
+// error: every prompt is then echoed back.
+func loadMockFixtures(path string) (*MockFixtures, error) {
+	resolved, err := mockFixturesPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(resolved) // #nosec G304 -- Fixtures path comes from the user's own config.
+	if os.IsNotExist(err) && path == "" {
+		return &MockFixtures{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock fixtures: %w", err)
+	}
+
+	var fixtures MockFixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse mock fixtures %s: %w", resolved, err)
+	}
+	for _, fixture := range fixtures.Responses {
+		if _, err := regexp.Compile(fixture.Match); err != nil {
+			return nil, fmt.Errorf("invalid mock fixture match %q: %w", fixture.Match, err)
+		}
+	}
+	return &fixtures, nil
+}
+
+// models returns the models the mock platform lists
+func (f *MockFixtures) models() []string {
+	if len(f.Models) == 0 {
+		return []string{mockModel}
+	}
+	return f.Models
+}
+
+// mockTransport answers OpenAI-compatible chat completion and model list
+// requests in process, so the mock platform runs through the same client
+// code as a real provider
+type mockTransport struct {
+	fixtures *MockFixtures
+
+	mu   sync.Mutex
+	next int // next scripted reply
+}
+
+// reply picks the fixture for prompt: the first matching one, then the next
+// scripted one, then an echo of the prompt
+func (t *mockTransport) reply(prompt string) MockFixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, fixture := range t.fixtures.Responses {
+		if fixture.Match != "" && regexp.MustCompile(fixture.Match).MatchString(prompt) {
+			return fixture
+		}
+	}
+	for t.next < len(t.fixtures.Responses) {
+		fixture := t.fixtures.Responses[t.next]
+		t.next++
+		if fixture.Match == "" {
+			return fixture
+		}
+	}
+	return MockFixture{Response: "mock response to: " + prompt}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/models"):
+		var list openai.ModelsList
+		for _, model := range t.fixtures.models() {
+			list.Models = append(list.Models, openai.Model{ID: model, Object: "model", OwnedBy: MockPlatform})
+		}
+		return mockJSONResponse(req, http.StatusOK, list)
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions"):
+		return t.chatCompletion(req)
+	}
+	return mockJSONResponse(req, http.StatusNotFound, map[string]any{
+		"error": map[string]any{"message": fmt.Sprintf("mock platform does not serve %s %s", req.Method, req.URL.Path), "type": "invalid_request_error"},
+	})
+}
+
+// chatCompletion answers a chat request with the fixture for its last user
+// message, streamed word by word when the request asks for a stream
+func (t *mockTransport) chatCompletion(req *http.Request) (*http.Response, error) {
+	var body openai.ChatCompletionRequest
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return mockJSONResponse(req, http.StatusBadRequest, map[string]any{
+				"error": map[string]any{"message": "invalid request body: " + err.Error(), "type": "invalid_request_error"},
+			})
+		}
+	}
+
+	prompt := ""
+	promptTokens := 0
+	for _, msg := range body.Messages {
+		promptTokens += tokens.Count(body.Model, msg.Content)
+		if msg.Role == openai.ChatMessageRoleUser {
+			prompt = msg.Content
+		}
+	}
+	fixture := t.reply(prompt)
+	usage := openai.Usage{PromptTokens: promptTokens, CompletionTokens: tokens.Count(body.Model, fixture.Response)}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	if !body.Stream {
+		return mockJSONResponse(req, http.StatusOK, openai.ChatCompletionResponse{
+			ID:      "mock",
+			Object:  "chat.completion",
+			Created: time.Now().Unix(),
+			Model:   body.Model,
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: fixture.Response, ReasoningContent: fixture.Reasoning},
+				FinishReason: openai.FinishReasonStop,
+			}},
+			Usage: usage,
+		})
+	}
+
+	reader, writer := io.Pipe()
+	go t.stream(req, writer, body.Model, fixture, usage)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       reader,
+		Request:    req,
+	}, nil
+}
+
+// stream writes fixture as server-sent events, one word per chunk with the
+// fixture delay between chunks, and stops early when the request is cancelled
+func (t *mockTransport) stream(req *http.Request, w *io.PipeWriter, model string, fixture MockFixture, usage openai.Usage) {
+	delay := time.Duration(t.fixtures.DelayMS) * time.Millisecond
+	send := func(chunk map[string]any) bool {
+		chunk["model"] = model
+		data, _ := json.Marshal(chunk)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				_ = w.CloseWithError(req.Context().Err())
+				return false
+			}
+		}
+		return true
+	}
+	delta := func(field, text string) map[string]any {
+		return map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]string{field: text}}}}
+	}
+
+	for _, word := range mockWords(fixture.Reasoning) {
+		if !send(delta("reasoning_content", word)) {
+			return
+		}
+	}
+	for _, word := range mockWords(fixture.Response) {
+		if !send(delta("content", word)) {
+			return
+		}
+	}
+	if !send(map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]string{}, "finish_reason": "stop"}}, "usage": usage}) {
+		return
+	}
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	_ = w.Close()
+}
+
+// mockWords splits text into chunks that keep their trailing whitespace, so
+// the chunks join back into text exactly
+func mockWords(text string) []string {
+	var words []string
+	for text != "" {
+		end := strings.IndexAny(text, " \n\t")
+		if end < 0 {
+			words = append(words, text)
+			break
+		}
+		for end < len(text) && strings.ContainsRune(" \n\t", rune(text[end])) {
+			end++
+		}
+		words = append(words, text[:end])
+		text = text[end:]
+	}
+	return words
+}
+
+// mockJSONResponse returns value as a JSON response with status
+func mockJSONResponse(req *http.Request, status int, value any) (*http.Response, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode:    status,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestMockPlatformAnswersFromFixtures(t *testing.T) {
+	fixtures := filepath.Join(t.TempDir(), "mock.json")
+	data := `{
+		"models": ["demo-small", "demo-large"],
+		"responses": [
+			{"match": "(?i)weather", "response": "Always sunny."},
+			{"response": "first scripted reply"},
+			{"response": "second scripted reply"}
+		]
+	}`
+	if err := os.WriteFile(fixtures, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &types.Config{
+		CurrentPlatform:   MockPlatform,
+		IsPipedOutput:     true,
+		MockFixtures:      fixtures,
+		SlowModelPatterns: []string{"large"},
+		Platforms: map[string]types.Platform{MockPlatform: {
+			Name:    MockPlatform,
+			BaseURL: types.BaseURLValue{Single: "http://mock.invalid/v1"},
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	models, err := m.ListModels()
+	if err != nil {
+		t.Fatalf("ListModels() error: %v", err)
+	}
+	sort.Strings(models)
+	if !reflect.DeepEqual(models, []string{"demo-large", "demo-small"}) {
+		t.Errorf("ListModels() = %v, want the fixture models", models)
+	}
+
+	var cancel func()
+	var streaming bool
+	send := func(prompt, model string) string {
+		t.Helper()
+		response, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: prompt}}, model, &cancel, &streaming)
+		if err != nil {
+			t.Fatalf("SendChatRequest(%q) error: %v", prompt, err)
+		}
+		return response
+	}
+
+	tests := []struct {
+		prompt string
+		model  string
+		want   string
+	}{
+		{"hello", "demo-small", "first scripted reply"},
+		{"what's the weather?", "demo-small", "Always sunny."},
+		{"and then", "demo-large", "second scripted reply"},
+		{"anything else", "demo-small", "mock response to: anything else"},
+	}
+	for _, tt := range tests {
+		if got := send(tt.prompt, tt.model); got != tt.want {
+			t.Errorf("reply to %q on %s = %q, want %q", tt.prompt, tt.model, got, tt.want)
+		}
+	}
+	if m.lastUsage == nil || m.lastUsage.CompletionTokens == 0 {
+		t.Errorf("mock replies should report usage, got %+v", m.lastUsage)
+	}
+}
+
+func TestLoadMockFixtures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fixtures, err := loadMockFixtures("")
+	if err != nil {
+		t.Fatalf("a missing default fixtures file should echo prompts: %v", err)
+	}
+	if got := fixtures.models(); !reflect.DeepEqual(got, []string{mockModel}) {
+		t.Errorf("models() = %v, want [%s]", got, mockModel)
+	}
+
+	if _, err := loadMockFixtures(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing configured fixtures file should be an error")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	_ = os.WriteFile(bad, []byte(`{"responses":[{"match":"(","response":"x"}]}`), 0600)
+	if _, err := loadMockFixtures(bad); err == nil || !strings.Contains(err.Error(), "invalid mock fixture match") {
+		t.Errorf("invalid match regex error = %v", err)
+	}
+}
+
+func TestMockWordsRejoin(t *testing.T) {
+	for _, text := range []string{"", "one", "two words", "lines\n\nand  spaces ", "```go\nfmt.Println()\n```"} {
+		if got := strings.Join(mockWords(text), ""); got != text {
+			t.Errorf("mockWords(%q) rejoined = %q", text, got)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"strings"
+)
+
+// ModelFilter narrows the all-models list before it is shown, so huge lists
+// do not all go through fzf: Platform limits fetching to one provider, and
+// every entry of Terms must appear in a model's label
+type ModelFilter struct {
+	Platform string
+	Terms    []string
+}
+
+// ParseModelFilter reads all-models arguments such as "groq llama 70b": a
+// first word naming a platform selects that provider, and the remaining
+// words are case-insensitive substrings
+func (m *Manager) ParseModelFilter(args string) ModelFilter {
+	var filter ModelFilter
+	words := strings.Fields(strings.ToLower(args))
+	if len(words) > 0 {
+		if _, ok := m.config.Platforms[words[0]]; ok || words[0] == "openai" {
+			filter.Platform = words[0]
+			words = words[1:]
+		}
+	}
+	if len(words) > 0 {
+		filter.Terms = words
+	}
+	return filter
+}
+
+// String shows the filter as it was typed
+func (f ModelFilter) String() string {
+	return strings.TrimSpace(f.Platform + " " + strings.Join(f.Terms, " "))
+}
+
+// keepsPlatform reports whether models of platform pass the filter
+func (f ModelFilter) keepsPlatform(platform string) bool {
+	return f.Platform == "" || strings.EqualFold(f.Platform, platform)
+}
+
+// keepsLabel reports whether an all-models label contains every term
+func (f ModelFilter) keepsLabel(label string) bool {
+	label = strings.ToLower(label)
+	for _, term := range f.Terms {
+		if !strings.Contains(label, term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package platform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestParseModelFilter(t *testing.T) {
+	m := NewManager(&types.Config{Platforms: map[string]types.Platform{"groq": {Name: "groq"}}})
+	tests := []struct {
+		args string
+		want ModelFilter
+	}{
+		{"", ModelFilter{}},
+		{" groq ", ModelFilter{Platform: "groq"}},
+		{"Groq Llama 70B", ModelFilter{Platform: "groq", Terms: []string{"llama", "70b"}}},
+		{"openai mini", ModelFilter{Platform: "openai", Terms: []string{"mini"}}},
+		{"llama groq", ModelFilter{Terms: []string{"llama", "groq"}}},
+	}
+	for _, tt := range tests {
+		got := m.ParseModelFilter(tt.args)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseModelFilter(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFetchAllModelsAsyncFilter(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	m := NewManager(&types.Config{Platforms: map[string]types.Platform{
+		"local":   {Name: "local", Auth: AuthNone, Models: types.PlatformModels{Static: []string{"llama-3.3-70b", "llama-3.2-3b", "qwen2.5-coder"}}},
+		"gateway": {Name: "gateway", Auth: AuthNone, Models: types.PlatformModels{Static: []string{"llama-3.3-70b"}}},
+	}})
+
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"", []string{"[gateway] llama-3.3-70b", "[local] llama-3.2-3b", "[local] llama-3.3-70b", "[local] qwen2.5-coder"}},
+		{"local", []string{"[local] llama-3.2-3b", "[local] llama-3.3-70b", "[local] qwen2.5-coder"}},
+		{"llama 70B", []string{"[gateway] llama-3.3-70b", "[local] llama-3.3-70b"}},
+		{"local 70b", []string{"[local] llama-3.3-70b"}},
+	}
+	for _, tt := range tests {
+		got, err := m.FetchAllModelsAsync(m.ParseModelFilter(tt.args))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FetchAllModelsAsync(%q) = %q, %v; want %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	if _, err := m.FetchAllModelsAsync(m.ParseModelFilter("gpt-5")); err == nil || !strings.Contains(err.Error(), `no models match "gpt-5"`) {
+		t.Errorf("unmatched filter error = %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// moderationTimeout bounds how long the moderation pre-check may take
+const moderationTimeout = 15 * time.Second
+
+// Moderation actions for the moderation config option
+const (
+	ModerationOff   = "off"
+	ModerationWarn  = "warn"
+	ModerationBlock = "block"
+)
+
+// moderationResponse is the OpenAI /moderations response shape, which local
+// classifiers can also serve to be used in place of OpenAI
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// checkModeration runs the configured moderation pre-pass on the latest user
+// message. In block mode a flagged prompt, or a failed check, returns an error
+// so the request is not sent. In warn mode problems are only printed.
+func (m *Manager) checkModeration(messages []types.ChatMessage) error {
+	action := strings.ToLower(m.config.Moderation)
+	if action == "" || action == ModerationOff {
+		return nil
+	}
+	if action != ModerationWarn && action != ModerationBlock {
+		return fmt.Errorf("unknown moderation action %q (use off, warn, or block)", m.config.Moderation)
+	}
+
+	var prompt string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			prompt = messages[i].Content
+			break
+		}
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil
+	}
+
+	categories, err := m.moderate(prompt)
+	switch {
+	case err != nil && action == ModerationBlock:
+		return fmt.Errorf("moderation check failed, prompt not sent: %v", err)
+	case err != nil:
+		m.printWarning(fmt.Sprintf("warning: moderation check failed: %v", err))
+	case categories == nil:
+	case action == ModerationBlock:
+		return fmt.Errorf("prompt blocked by moderation (%s)", strings.Join(categories, ", "))
+	default:
+		m.printWarning(fmt.Sprintf("warning: prompt flagged by moderation (%s)", strings.Join(categories, ", ")))
+	}
+	return nil
+}
+
+// moderate sends text to the moderation endpoint and returns the flagged
+// categories, or nil when the text is not flagged
+func (m *Manager) moderate(text string) ([]string, error) {
+	body, err := json.Marshal(map[string]string{
+		"input": text,
+		"model": m.config.ModerationModel,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(m.config.ModerationURL, "/") + "/moderations"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{Timeout: moderationTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("moderation endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid moderation response: %v", err)
+	}
+
+	flagged := false
+	seen := make(map[string]bool)
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		flagged = true
+		for category, hit := range r.Categories {
+			if hit {
+				seen[category] = true
+			}
+		}
+	}
+	if !flagged {
+		return nil, nil
+	}
+
+	categories := make([]string, 0, len(seen))
+	for category := range seen {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	if len(categories) == 0 {
+		categories = []string{"flagged"}
+	}
+	return categories, nil
+}
+
+// printWarning shows a warning without touching piped stdout
+func (m *Manager) printWarning(message string) {
+	if m.config.IsPipedOutput {
+		fmt.Fprintln(os.Stderr, message)
+		return
+	}
+	fmt.Printf("\033[93m%s\033[0m\n", message)
+}
@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// newModerationTestManager returns a manager whose moderation endpoint flags
+// any input containing "forbidden"
+func newModerationTestManager(t *testing.T, action string) (*Manager, *int) {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "")
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/moderations" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		flagged := strings.Contains(req["input"], "forbidden")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{
+				"flagged":    flagged,
+				"categories": map[string]bool{"violence": flagged, "hate": false, "harassment": flagged},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &types.Config{Moderation: action, ModerationModel: "local-classifier", ModerationURL: server.URL + "/", IsPipedOutput: true}
+	return &Manager{config: cfg}, &calls
+}
+
+func TestCheckModerationBlock(t *testing.T) {
+	m, calls := newModerationTestManager(t, "block")
+
+	messages := []types.ChatMessage{
+		{Role: "system", Content: "forbidden system text is not checked"},
+		{Role: "user", Content: "hello"},
+	}
+	if err := m.checkModeration(messages); err != nil {
+		t.Errorf("clean prompt should pass, got %v", err)
+	}
+
+	messages = append(messages, types.ChatMessage{Role: "user", Content: "something forbidden"})
+	err := m.checkModeration(messages)
+	if err == nil || !strings.Contains(err.Error(), "harassment, violence") {
+		t.Errorf("expected a block listing categories, got %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected 2 moderation calls, got %d", *calls)
+	}
+}
+
+func TestCheckModerationWarnAllowsPrompt(t *testing.T) {
+	m, calls := newModerationTestManager(t, "warn")
+	if err := m.checkModeration([]types.ChatMessage{{Role: "user", Content: "forbidden"}}); err != nil {
+		t.Errorf("warn mode should not block, got %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected 1 moderation call, got %d", *calls)
+	}
+}
+
+func TestCheckModerationOff(t *testing.T) {
+	for _, action := range []string{"", "off"} {
+		m, calls := newModerationTestManager(t, action)
+		if err := m.checkModeration([]types.ChatMessage{{Role: "user", Content: "forbidden"}}); err != nil || *calls != 0 {
+			t.Errorf("action %q: err = %v, calls = %d", action, err, *calls)
+		}
+	}
+
+	m, _ := newModerationTestManager(t, "deny")
+	if err := m.checkModeration([]types.ChatMessage{{Role: "user", Content: "x"}}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+func TestCheckModerationBlockFailsClosed(t *testing.T) {
+	m := &Manager{config: &types.Config{Moderation: "block", ModerationURL: "http://127.0.0.1:1"}}
+	if err := m.checkModeration([]types.ChatMessage{{Role: "user", Content: "hi"}}); err == nil {
+		t.Error("block mode should refuse to send when the check fails")
+	}
+}
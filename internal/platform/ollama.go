@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"syscall"
+
+	"github.com/MehmetMHY/ch/internal/ui"
+)
+
+// ollamaRoot returns the Ollama server address without the /v1 suffix its
+// OpenAI-compatible API uses, e.g. http://127.0.0.1:11434
+func (m *Manager) ollamaRoot() string {
+	baseURL := m.config.Platforms["ollama"].BaseURL.Single
+	if m.config.CurrentPlatform == "ollama" && m.config.CurrentBaseURL != "" {
+		baseURL = m.config.CurrentBaseURL
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/v1")
+}
+
+// ollamaDownError replaces a refused connection to the Ollama server at
+// address with a hint to start it; other errors are returned as they are
+func ollamaDownError(err error, address string) error {
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return err
+	}
+	if parsed, parseErr := neturl.Parse(address); parseErr == nil && parsed.Host != "" {
+		address = parsed.Host
+	}
+	return fmt.Errorf("ollama is not running at %s, start it with `ollama serve` (or open the Ollama app) and try again", address)
+}
+
+// extractOllamaModelsWithTime reads Ollama's /api/tags list, describing
+// each model by parameter count, quantization, and download size
+func extractOllamaModelsWithTime(data interface{}) ([]modelWithTime, error) {
+	root, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected Ollama model list object")
+	}
+	items, _ := root["models"].([]interface{})
+
+	var models []modelWithTime
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := itemMap["name"].(string)
+		if !ok {
+			continue
+		}
+
+		var details []string
+		if info, ok := itemMap["details"].(map[string]interface{}); ok {
+			for _, field := range []string{"parameter_size", "quantization_level"} {
+				if value, ok := info[field].(string); ok && value != "" {
+					details = append(details, value)
+				}
+			}
+		}
+		if size := int64(numericJSONField(itemMap, "size")); size > 0 {
+			details = append(details, ui.FormatBytes(size))
+		}
+
+		models = append(models, modelWithTime{
+			name:    name,
+			created: parseTimestamp(itemMap["modified_at"]),
+			detail:  strings.Join(details, ", "),
+		})
+	}
+	return models, nil
+}
+
+// OllamaPullProgress is one status update of an Ollama model pull
+type OllamaPullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// String describes the update in one line, with a percentage while a layer
+// is downloading
+func (p OllamaPullProgress) String() string {
+	if p.Total <= 0 {
+		return p.Status
+	}
+	return fmt.Sprintf("%s: %d%% (%s/%s)", p.Status, p.Completed*100/p.Total, ui.FormatBytes(p.Completed), ui.FormatBytes(p.Total))
+}
+
+// PullOllamaModel downloads model into the local Ollama server, calling
+// progress for each status update. Cancelling ctx stops the download.
+func (m *Manager) PullOllamaModel(ctx context.Context, model string, progress func(OllamaPullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	root := m.ollamaRoot()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, root+"/api/pull", bytes.NewReader(body)) // #nosec G704 -- The Ollama address comes from the user's platform config; Ollama intentionally uses localhost HTTP.
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req) // #nosec G704 -- Request goes to the configured Ollama server.
+	if err != nil {
+		return ollamaDownError(err, root)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var failure OllamaPullProgress
+		if json.Unmarshal(message, &failure) == nil && failure.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", model, failure.Error)
+		}
+		return fmt.Errorf("failed to pull %s: %s", model, resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var update OllamaPullProgress
+		if json.Unmarshal(scanner.Bytes(), &update) != nil {
+			continue
+		}
+		if update.Error != "" {
+			return fmt.Errorf("failed to pull %s: %s", model, update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed to pull %s: the download ended early", model)
+}
@@ -0,0 +1,96 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestExtractOllamaModelsWithTime(t *testing.T) {
+	var data interface{}
+	tags := `{"models":[
+		{"name":"llama3.2:latest","size":2019393189,"modified_at":"2026-05-01T10:00:00Z","details":{"parameter_size":"3.2B","quantization_level":"Q4_K_M"}},
+		{"name":"tiny:latest","size":45000000,"details":{}},
+		{"size":1}
+	]}`
+	if err := json.Unmarshal([]byte(tags), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	models, err := extractOllamaModelsWithTime(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sortModelsByTime(models)
+	want := []string{"llama3.2:latest - 3.2B, Q4_K_M, 1.9 GB", "tiny:latest - 42.9 MB"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+	if name := ModelFromLabel(got[0]); name != "llama3.2:latest" {
+		t.Errorf("ModelFromLabel() = %q", name)
+	}
+}
+
+func TestPullOllamaModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Model string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/api/pull" || body.Model == "missing" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":2000000000,"completed":500000000}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	m := NewManager(&types.Config{Platforms: map[string]types.Platform{
+		"ollama": {Name: "ollama", BaseURL: types.BaseURLValue{Single: server.URL + "/v1"}},
+	}})
+
+	var updates []string
+	if err := m.PullOllamaModel(context.Background(), "llama3.2", func(p OllamaPullProgress) {
+		updates = append(updates, p.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"pulling manifest", "pulling 6a0746a1ec1a: 25% (476.8 MB/1.9 GB)", "success"}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("updates = %q, want %q", updates, want)
+	}
+
+	err := m.PullOllamaModel(context.Background(), "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("PullOllamaModel(missing) error = %v", err)
+	}
+}
+
+func TestOllamaDownError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	root := server.URL
+	server.Close()
+
+	m := NewManager(&types.Config{Platforms: map[string]types.Platform{
+		"ollama": {Name: "ollama", BaseURL: types.BaseURLValue{Single: root + "/v1"}, Models: types.PlatformModels{URL: root + "/api/tags", JSONPath: "models.name"}},
+	}})
+
+	_, err := m.fetchPlatformModelsWithTime(m.config.Platforms["ollama"])
+	if err == nil || !strings.Contains(err.Error(), "ollama serve") || !strings.Contains(err.Error(), strings.TrimPrefix(root, "http://")) {
+		t.Errorf("model list error = %v, want a hint to start ollama", err)
+	}
+	if err := m.PullOllamaModel(context.Background(), "llama3.2", nil); err == nil || !strings.Contains(err.Error(), "ollama serve") {
+		t.Errorf("pull error = %v, want a hint to start ollama", err)
+	}
+	if err := ollamaDownError(fmt.Errorf("boom"), root); err.Error() != "boom" {
+		t.Errorf("ollamaDownError() changed an unrelated error: %v", err)
+	}
+}
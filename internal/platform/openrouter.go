@@ -0,0 +1,100 @@
+package platform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// extractOpenRouterModelsWithTime reads OpenRouter's /models list,
+// describing each model by context length and price per million tokens
+func extractOpenRouterModelsWithTime(data interface{}) ([]modelWithTime, error) {
+	root, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected OpenRouter model list object")
+	}
+	items, _ := root["data"].([]interface{})
+
+	var models []modelWithTime
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := itemMap["id"].(string)
+		if !ok {
+			continue
+		}
+
+		var details []string
+		if contextLength := int(numericJSONField(itemMap, "context_length")); contextLength > 0 {
+			details = append(details, formatContextLength(contextLength)+" ctx")
+		}
+		if pricing, ok := itemMap["pricing"].(map[string]interface{}); ok {
+			if price := formatOpenRouterPrice(pricing); price != "" {
+				details = append(details, price)
+			}
+		}
+
+		models = append(models, modelWithTime{
+			name:    name,
+			created: parseTimestamp(itemMap["created"]),
+			detail:  strings.Join(details, modelDetailSeparator),
+		})
+	}
+	return models, nil
+}
+
+// formatContextLength shortens a token count to "128k" or "1M"
+func formatContextLength(tokens int) string {
+	if tokens >= 1000000 {
+		return strconv.FormatFloat(math.Round(float64(tokens)/100000)/10, 'f', -1, 64) + "M"
+	}
+	return fmt.Sprintf("%dk", tokens/1000)
+}
+
+// formatOpenRouterPrice describes OpenRouter's per-token prompt and
+// completion prices per million tokens: "$0.5/M" when they match, both
+// when they differ, "free" for free models, and "" when unknown
+func formatOpenRouterPrice(pricing map[string]interface{}) string {
+	input, inputOK := openRouterPricePerMillion(pricing["prompt"])
+	output, outputOK := openRouterPricePerMillion(pricing["completion"])
+	switch {
+	case !inputOK || !outputOK:
+		return ""
+	case input == 0 && output == 0:
+		return "free"
+	case input == output:
+		return fmt.Sprintf("$%s/M", formatPrice(input))
+	}
+	return fmt.Sprintf("$%s/M in, $%s/M out", formatPrice(input), formatPrice(output))
+}
+
+// openRouterPricePerMillion converts a per-token price, which OpenRouter
+// sends as a string, to USD per million tokens. Negative prices mark
+// routers whose price depends on the model picked, so they count as unknown.
+func openRouterPricePerMillion(value interface{}) (float64, bool) {
+	var perToken float64
+	switch v := value.(type) {
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		perToken = parsed
+	case float64:
+		perToken = v
+	default:
+		return 0, false
+	}
+	if perToken < 0 {
+		return 0, false
+	}
+	return perToken * 1000000, true
+}
+
+// formatPrice prints a price with at most 3 decimals and no trailing zeros
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(math.Round(price*1000)/1000, 'f', -1, 64)
+}
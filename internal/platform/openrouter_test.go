@@ -0,0 +1,61 @@
+package platform
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractOpenRouterModelsWithTime(t *testing.T) {
+	var data interface{}
+	list := `{"data":[
+		{"id":"openai/gpt-4.1-mini","created":1744651381,"context_length":1047576,"pricing":{"prompt":"0.0000004","completion":"0.0000016"}},
+		{"id":"meta-llama/llama-3.3-70b","created":1733506137,"context_length":131072,"pricing":{"prompt":"0.0000005","completion":"0.0000005"}},
+		{"id":"qwen/qwen3:free","created":1700000000,"context_length":40960,"pricing":{"prompt":"0","completion":"0"}},
+		{"id":"openrouter/auto","created":1600000000,"context_length":2000000,"pricing":{"prompt":"-1","completion":"-1"}},
+		{"id":"bare/model"}
+	]}`
+	if err := json.Unmarshal([]byte(list), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	models, err := extractOpenRouterModelsWithTime(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"openai/gpt-4.1-mini - 1M ctx - $0.4/M in, $1.6/M out",
+		"meta-llama/llama-3.3-70b - 131k ctx - $0.5/M",
+		"qwen/qwen3:free - 40k ctx - free",
+		"openrouter/auto - 2M ctx",
+		"bare/model",
+	}
+	if got := sortModelsByTime(models); !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %q, want %q", got, want)
+	}
+}
+
+func TestAllModelsLabel(t *testing.T) {
+	label := allModelsLabel("openrouter|meta-llama/llama-3.3-70b - 131k ctx - $0.5/M")
+	if label != "[openrouter] meta-llama/llama-3.3-70b - 131k ctx - $0.5/M" {
+		t.Errorf("allModelsLabel() = %q", label)
+	}
+
+	tests := []struct {
+		label    string
+		platform string
+		model    string
+		ok       bool
+	}{
+		{label, "openrouter", "meta-llama/llama-3.3-70b", true},
+		{"[groq] llama-3.1-8b-instant", "groq", "llama-3.1-8b-instant", true},
+		{"groq|llama", "", "", false},
+		{"[groq]", "", "", false},
+	}
+	for _, tt := range tests {
+		platformName, model, ok := ParseAllModelsLabel(tt.label)
+		if platformName != tt.platform || model != tt.model || ok != tt.ok {
+			t.Errorf("ParseAllModelsLabel(%q) = %q, %q, %v", tt.label, platformName, model, ok)
+		}
+	}
+}
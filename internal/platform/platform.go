@@ -9,6 +9,7 @@ import (
 	neturl "net/url"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -21,8 +22,49 @@ import (
 
 // Manager handles AI platform operations
 type Manager struct {
-	client *openai.Client
-	config *types.Config
+	client       *openai.Client
+	config       *types.Config
+	lastLogprobs []openai.LogProb
+	lastModel    string
+	lastUsage    *openai.Usage
+	lastElapsed  time.Duration
+	sessionSpend float64
+
+	// Tokens and cost of requests this run, and the part not yet written to
+	// usage.jsonl, by "platform/model"
+	sessionUsage  map[string]types.ModelUsage
+	unloggedUsage map[string]types.ModelUsage
+
+	// Features providers rejected this session, by "platform/model", and
+	// the capability notices already shown
+	unsupported map[string]map[string]bool
+	noticed     map[string]bool
+
+	// Per-message limit learned from a provider rejecting a message as too
+	// long, and the longest user message of the last request
+	messageLimit   int
+	longestMessage int
+
+	// Answers requests in process when the mock platform is current
+	mock *mockTransport
+
+	// Translates requests when the current platform uses the Gemini driver
+	gemini *geminiTransport
+
+	// Image attachments prepared as data URLs, by path
+	images map[string]cachedImage
+
+	// ConfirmSpend asks whether to send a request over a spend limit; nil
+	// means nobody can confirm, so the request is not sent
+	ConfirmSpend func(question string) bool
+
+	// Local tools the model may call when the tools option is on, and the
+	// calls in the last reply that have not been answered yet
+	tools            []Tool
+	pendingToolCalls []openai.ToolCall
+
+	// ConfirmTool asks before each tool call runs; nil declines every call
+	ConfirmTool func(question string) bool
 }
 
 // NewManager creates a new platform manager
@@ -34,12 +76,18 @@ func NewManager(config *types.Config) *Manager {
 
 // Initialize initializes the AI client for the current platform
 func (m *Manager) Initialize() error {
+	m.gemini = nil
 	if m.config.CurrentPlatform == "openai" {
 		apiKey := os.Getenv("OPENAI_API_KEY")
-		if apiKey == "" {
+		// A dry run never sends, so it works without a key
+		if apiKey == "" && !m.config.DryRun {
 			return fmt.Errorf("OPENAI_API_KEY environment variable is required for OpenAI platform")
 		}
-		m.client = openai.NewClient(apiKey)
+		clientConfig := openai.DefaultConfig(apiKey)
+		if httpClient := m.chatHTTPClient("openai"); httpClient != nil {
+			clientConfig.HTTPClient = httpClient
+		}
+		m.client = openai.NewClientWithConfig(clientConfig)
 		m.config.CurrentBaseURL = ""
 		return nil
 	}
@@ -49,15 +97,29 @@ func (m *Manager) Initialize() error {
 		return fmt.Errorf("platform %s not found", m.config.CurrentPlatform)
 	}
 
-	var apiKey string
-	if platform.Name != "ollama" {
-		apiKey = os.Getenv(platform.EnvName)
-		if apiKey == "" {
-			return fmt.Errorf("%s environment variable is required for %s", platform.EnvName, platform.Name)
+	m.mock = nil
+	if platform.Name == MockPlatform {
+		fixtures, err := loadMockFixtures(m.config.MockFixtures)
+		if err != nil {
+			return err
 		}
+		m.mock = &mockTransport{fixtures: fixtures}
+	}
+
+	if err := checkAuth(platform); err != nil {
+		return err
+	}
+	apiKey := platformAPIKey(platform)
+	if needsAPIKey(platform) && apiKey == "" && !m.config.DryRun {
+		return fmt.Errorf("%s environment variable is required for %s", platform.EnvName, platform.Name)
 	}
 
-	clientConfig := openai.DefaultConfig(apiKey)
+	// Header and query keys are added by authTransport in chatHTTPClient
+	clientKey := apiKey
+	if sendsOwnAuth(platform) {
+		clientKey = ""
+	}
+	clientConfig := openai.DefaultConfig(clientKey)
 	// Use CurrentBaseURL if set, otherwise use the first URL if multi-URL, otherwise use single URL
 	baseURL := m.config.CurrentBaseURL
 	if baseURL == "" {
@@ -67,8 +129,21 @@ func (m *Manager) Initialize() error {
 			baseURL = platform.BaseURL.Single
 		}
 	}
+	if platform.Driver == DriverAzure {
+		var err error
+		if baseURL, err = azureBaseURL(platform, baseURL); err != nil {
+			return err
+		}
+		clientConfig = azureClientConfig(platform, apiKey, baseURL)
+	}
 	m.config.CurrentBaseURL = baseURL
 	clientConfig.BaseURL = baseURL
+	if platform.Driver == DriverGemini {
+		m.gemini = newGeminiTransport(http.DefaultTransport, baseURL)
+	}
+	if httpClient := m.chatHTTPClient(m.config.CurrentPlatform); httpClient != nil {
+		clientConfig.HTTPClient = httpClient
+	}
 	m.client = openai.NewClientWithConfig(clientConfig)
 
 	return nil
@@ -78,38 +153,131 @@ func (m *Manager) Initialize() error {
 // full response without printing anything to stdout. Use for auxiliary
 // requests (e.g. filename suggestions) where streaming output is unwanted.
 func (m *Manager) SendSilentChatRequest(messages []types.ChatMessage, model string, streamingCancel *func(), isStreaming *bool) (string, error) {
-	mergedMessages := m.mergeConsecutiveUserMessages(messages)
-
-	var openaiMessages []openai.ChatCompletionMessage
-	for _, msg := range mergedMessages {
-		openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	mergedMessages := m.splitLongMessages(m.mergeConsecutiveUserMessages(messages))
+	model = m.ResolveModel(messages, model)
+	if m.config.DryRun {
+		return "", ErrDryRun
 	}
 
-	return m.sendNonStreamingRequest(openaiMessages, model, streamingCancel, isStreaming)
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: m.openAIMessages(mergedMessages, model),
+	}
+	return m.sendNonStreamingRequest(req, streamingCancel, isStreaming)
 }
 
 // SendChatRequest sends a chat request to the current platform
 func (m *Manager) SendChatRequest(messages []types.ChatMessage, model string, streamingCancel *func(), isStreaming *bool) (string, error) {
-	var openaiMessages []openai.ChatCompletionMessage
+	// Merge consecutive user messages to handle cases like file loading + follow-up question,
+	// then split any merged message the provider would reject as too long
+	mergedMessages := m.splitLongMessages(m.mergeConsecutiveUserMessages(messages))
+
+	if m.config.DryRun {
+		model = m.ResolveModel(messages, model)
+		fmt.Print(m.dryRunReport(m.newChatRequest(m.openAIMessages(mergedMessages, model), model)))
+		return "", ErrDryRun
+	}
+
+	if err := m.checkModeration(mergedMessages); err != nil {
+		return "", err
+	}
 
-	// Merge consecutive user messages to handle cases like file loading + follow-up question
-	mergedMessages := m.mergeConsecutiveUserMessages(messages)
+	model = m.ResolveModel(messages, model)
+	m.lastModel = model
 
-	for _, msg := range mergedMessages {
-		openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	if err := m.checkSpendLimit(mergedMessages, model); err != nil {
+		return "", err
 	}
 
-	if m.IsReasoningModel(model) {
-		return m.sendNonStreamingRequest(openaiMessages, model, streamingCancel, isStreaming)
+	req := m.newChatRequest(m.openAIMessages(mergedMessages, model), model)
+	m.lastUsage = nil
+	m.pendingToolCalls = nil
+	started := time.Now()
+
+	response, err := m.dispatchChatRequest(req, streamingCancel, isStreaming)
+	if feature := unsupportedFeature(err, req); feature != "" {
+		// Remember the rejection and retry once without the feature
+		m.markUnsupported(model, feature)
+		m.printWarning(fmt.Sprintf("%s/%s does not support %s, retrying without it", m.config.CurrentPlatform, model, feature))
+		req = m.newChatRequest(m.openAIMessages(mergedMessages, model), model)
+		response, err = m.dispatchChatRequest(req, streamingCancel, isStreaming)
+	}
+	if err == nil && len(m.pendingToolCalls) > 0 {
+		response, err = m.runToolLoop(req, response, streamingCancel, isStreaming)
+	}
+
+	if err != nil && m.config.CurrentPlatform == "ollama" {
+		err = ollamaDownError(err, m.ollamaRoot())
 	}
 
-	return m.sendStreamingRequest(openaiMessages, model, streamingCancel, isStreaming)
+	m.lastElapsed = time.Since(started)
+	if m.gemini != nil {
+		if notice := m.gemini.takeFinish(); notice != "" {
+			m.printWarning(notice)
+		}
+	}
+	if err == nil {
+		m.addRequestUsage(mergedMessages, model, response)
+	}
+	if m.config.StreamJSON {
+		m.emitStreamResult(mergedMessages, model, response, err)
+	}
+	return response, err
+}
+
+// dispatchChatRequest streams req, or sends it in one piece when req.Stream is off
+func (m *Manager) dispatchChatRequest(req openai.ChatCompletionRequest, streamingCancel *func(), isStreaming *bool) (string, error) {
+	if req.Stream {
+		return m.sendStreamingRequest(req, streamingCancel, isStreaming)
+	}
+
+	response, err := m.sendNonStreamingRequest(req, streamingCancel, isStreaming)
+	if err == nil {
+		// Enforce stop sequences for providers that ignore the stop parameter
+		response, _ = truncateAtStop(response, m.config.StopSequences)
+		if m.config.StreamJSON {
+			writeStreamEvent(streamEvent{Type: "delta", Text: response})
+		}
+	}
+	return response, err
+}
+
+// newChatRequest builds a chat request with the user's generation options
+// applied, leaving out options the model does not support
+func (m *Manager) newChatRequest(openaiMessages []openai.ChatCompletionMessage, model string) openai.ChatCompletionRequest {
+	caps := m.Capabilities(model)
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: openaiMessages,
+		Stream:   !m.IsReasoningModel(model),
+		Stop:     apiStopSequences(m.config.StopSequences),
+		Seed:     m.config.Seed,
+	}
+	if m.config.ShowLogprobs {
+		if caps.Logprobs {
+			req.LogProbs = true
+			req.TopLogProbs = topLogprobs(m.config.TopLogprobs)
+		} else {
+			m.noticeOnce(model, CapabilityLogprobs, "logprobs are not supported")
+		}
+	}
+	if m.config.Tools && len(m.tools) > 0 {
+		// The Gemini driver only translates text and image messages
+		if caps.Tools && m.gemini == nil {
+			req.Tools = m.toolDefinitions()
+		} else {
+			m.noticeOnce(model, CapabilityTools, "tools are not supported")
+		}
+	}
+	return req
+}
+
+// PrintLastLogprobs shows token alternatives for the last non-streamed response
+// when logprobs are enabled. Streamed responses print them as the stream ends.
+func (m *Manager) PrintLastLogprobs() {
+	if m.config.ShowLogprobs {
+		m.printLogprobs(m.lastLogprobs)
+	}
 }
 
 // mergeConsecutiveUserMessages combines consecutive user messages into one
@@ -120,19 +288,21 @@ func (m *Manager) mergeConsecutiveUserMessages(messages []types.ChatMessage) []t
 	}
 
 	var result []types.ChatMessage
-	var lastUserContent []string
+	var lastUserContent, lastUserImages []string
 
 	for _, msg := range messages {
 		if msg.Role == "user" {
 			lastUserContent = append(lastUserContent, msg.Content)
+			lastUserImages = append(lastUserImages, msg.Images...)
 		} else {
 			// Non-user message: flush any accumulated user messages
 			if len(lastUserContent) > 0 {
 				result = append(result, types.ChatMessage{
 					Role:    "user",
 					Content: strings.Join(lastUserContent, "\n\n"),
+					Images:  lastUserImages,
 				})
-				lastUserContent = nil
+				lastUserContent, lastUserImages = nil, nil
 			}
 			result = append(result, msg)
 		}
@@ -143,16 +313,84 @@ func (m *Manager) mergeConsecutiveUserMessages(messages []types.ChatMessage) []t
 		result = append(result, types.ChatMessage{
 			Role:    "user",
 			Content: strings.Join(lastUserContent, "\n\n"),
+			Images:  lastUserImages,
 		})
 	}
 
 	return result
 }
 
-// modelWithTime holds a model name and its creation timestamp for sorting
+// openAIMessages converts messages for the API. With time_context on, the
+// current date and time is added to the system prompt of every request.
+// Loaded images are sent as image parts when model takes them.
+func (m *Manager) openAIMessages(messages []types.ChatMessage, model string) []openai.ChatCompletionMessage {
+	var openaiMessages []openai.ChatCompletionMessage
+	imageParts := m.sendsImageParts(model)
+	for _, msg := range messages {
+		converted := openai.ChatCompletionMessage{
+			Role:    m.messageRole(msg.Role),
+			Content: msg.Content,
+		}
+		if len(msg.Images) > 0 {
+			if imageParts {
+				converted = m.withImageParts(converted, msg)
+			} else if !m.unsupported[m.config.CurrentPlatform+"/"+model][CapabilityVision] {
+				m.noticeOnce(model, CapabilityVision, "images are not supported")
+			}
+		}
+		openaiMessages = append(openaiMessages, converted)
+	}
+
+	if !m.config.TimeContext {
+		return openaiMessages
+	}
+	timeLine := timeContextLine(time.Now())
+	if len(openaiMessages) > 0 && openaiMessages[0].Role == "system" {
+		openaiMessages[0].Content = timeLine + "\n\n" + openaiMessages[0].Content
+		return openaiMessages
+	}
+	return append([]openai.ChatCompletionMessage{{Role: "system", Content: timeLine}}, openaiMessages...)
+}
+
+// timeContextLine describes now with its timezone, e.g.
+// "Current date and time: Thursday, October 15, 2026 14:03 PDT (UTC-07:00)"
+func timeContextLine(now time.Time) string {
+	return "Current date and time: " + now.Format("Monday, January 2, 2006 15:04 MST (UTC-07:00)")
+}
+
+// messageRole sends "developer" messages as "system" on platforms that are
+// not listed in developer_role_platforms
+func (m *Manager) messageRole(role string) string {
+	if role != "developer" || slices.Contains(m.config.DeveloperRolePlatforms, m.config.CurrentPlatform) {
+		return role
+	}
+	return "system"
+}
+
+// modelWithTime holds a model name and its creation timestamp for sorting,
+// plus optional details (size, parameters) shown after it in pickers
 type modelWithTime struct {
 	name    string
 	created int64
+	detail  string
+}
+
+// modelDetailSeparator joins a model name and its details in picker labels
+const modelDetailSeparator = " - "
+
+// label returns the model as shown in pickers
+func (m modelWithTime) label() string {
+	if m.detail == "" {
+		return m.name
+	}
+	return m.name + modelDetailSeparator + m.detail
+}
+
+// ModelFromLabel returns the model name of a picker label, dropping any
+// details shown after it
+func ModelFromLabel(label string) string {
+	name, _, _ := strings.Cut(label, modelDetailSeparator)
+	return name
 }
 
 // parseTimestamp attempts to extract a Unix timestamp (in seconds) from a value.
@@ -202,7 +440,8 @@ func parseTimestamp(val interface{}) int64 {
 }
 
 // sortModelsByTime sorts models by created timestamp descending (newest first),
-// falling back to alphabetical sort when no timestamps are available
+// falling back to alphabetical sort when no timestamps are available, and
+// returns their picker labels
 func sortModelsByTime(models []modelWithTime) []string {
 	hasTimestamps := false
 	for _, m := range models {
@@ -227,7 +466,7 @@ func sortModelsByTime(models []modelWithTime) []string {
 
 	names := make([]string, len(models))
 	for i, m := range models {
-		names[i] = m.name
+		names[i] = m.label()
 	}
 	return names
 }
@@ -346,7 +585,7 @@ func (m *Manager) SelectPlatform(platformKey, modelName string, fzfSelector func
 			if selected == "" {
 				return nil, fmt.Errorf("no model selected")
 			}
-			finalModel = selected
+			finalModel = ModelFromLabel(selected)
 		}
 
 		return map[string]interface{}{
@@ -400,7 +639,7 @@ func (m *Manager) SelectPlatform(platformKey, modelName string, fzfSelector func
 			return nil, fmt.Errorf("no model selected")
 		}
 
-		finalModel = selected
+		finalModel = ModelFromLabel(selected)
 	}
 
 	return map[string]interface{}{
@@ -413,9 +652,10 @@ func (m *Manager) SelectPlatform(platformKey, modelName string, fzfSelector func
 }
 
 // FetchAllModelsAsync fetches all models from all platforms asynchronously
-// Returns a list of models formatted as "platform|model_name" sorted by newest first
-// Only fetches from platforms where API keys are defined and not empty
-func (m *Manager) FetchAllModelsAsync() ([]string, error) {
+// Returns picker labels formatted as "[platform] model - details" sorted by newest first
+// Only fetches from platforms where API keys are defined and not empty, and
+// only keeps models that pass filter
+func (m *Manager) FetchAllModelsAsync(filter ModelFilter) ([]string, error) {
 	var wg sync.WaitGroup
 	results := make(chan modelWithTime)
 	done := make(chan bool)
@@ -452,6 +692,9 @@ func (m *Manager) FetchAllModelsAsync() ([]string, error) {
 	for _, p := range platformsToFetch {
 		platformName := p.name
 		platformConfig := p.platform
+		if !filter.keepsPlatform(platformName) {
+			continue
+		}
 
 		wg.Add(1)
 		go func(name string, config types.Platform) {
@@ -484,8 +727,7 @@ func (m *Manager) FetchAllModelsAsync() ([]string, error) {
 			}
 
 			// Check if API key is defined and not empty
-			apiKey := os.Getenv(platformConfig.EnvName)
-			if apiKey == "" && platformConfig.Name != "ollama" {
+			if needsAPIKey(platformConfig) && platformAPIKey(platformConfig) == "" {
 				return // Skip if API key is not set
 			}
 
@@ -500,6 +742,7 @@ func (m *Manager) FetchAllModelsAsync() ([]string, error) {
 				results <- modelWithTime{
 					name:    fmt.Sprintf("%s|%s", platformNameFormatted, model.name),
 					created: model.created,
+					detail:  model.detail,
 				}
 			}
 		}(platformName, platformConfig)
@@ -518,7 +761,37 @@ func (m *Manager) FetchAllModelsAsync() ([]string, error) {
 		return nil, fmt.Errorf("no models found from any platform")
 	}
 
-	return sortModelsGroupedByPlatform(models), nil
+	var labels []string
+	for _, entry := range sortModelsGroupedByPlatform(models) {
+		if label := allModelsLabel(entry); filter.keepsLabel(label) {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no models match %q", filter.String())
+	}
+	return labels, nil
+}
+
+// allModelsLabel turns a sorted "platform|model" entry into the all-models
+// picker label "[platform] model"
+func allModelsLabel(entry string) string {
+	platformName, model, _ := strings.Cut(entry, "|")
+	return "[" + platformName + "] " + model
+}
+
+// ParseAllModelsLabel returns the platform and model of an all-models
+// picker label, dropping any details shown after the model
+func ParseAllModelsLabel(label string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(label, "[")
+	if !ok {
+		return "", "", false
+	}
+	platformName, model, ok := strings.Cut(rest, "] ")
+	if !ok || platformName == "" || model == "" {
+		return "", "", false
+	}
+	return platformName, ModelFromLabel(model), true
 }
 
 // isSlowModel checks if the model matches any user-configured slow model patterns.
@@ -534,16 +807,17 @@ func (m *Manager) isSlowModel(modelName string) bool {
 	return false
 }
 
-// IsReasoningModel checks if the model is a reasoning model (like o1, o2, etc.)
+// IsReasoningModel checks if the model's response is sent in one piece
+// instead of streamed: a slow model (like o1, o2, etc.) or one without
+// streaming support
 func (m *Manager) IsReasoningModel(modelName string) bool {
-	return m.isSlowModel(modelName)
+	return m.isSlowModel(modelName) || !m.Capabilities(modelName).Streaming
 }
 
-func (m *Manager) sendNonStreamingRequest(openaiMessages []openai.ChatCompletionMessage, model string, streamingCancel *func(), isStreaming *bool) (string, error) {
-	req := openai.ChatCompletionRequest{
-		Model:    model,
-		Messages: openaiMessages,
-		Stream:   false,
+func (m *Manager) sendNonStreamingRequest(req openai.ChatCompletionRequest, streamingCancel *func(), isStreaming *bool) (string, error) {
+	req.Stream = false
+	if req.LogProbs {
+		m.lastLogprobs = nil
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -565,6 +839,13 @@ func (m *Manager) sendNonStreamingRequest(openaiMessages []openai.ChatCompletion
 	}
 
 	if len(resp.Choices) > 0 {
+		if req.LogProbs && resp.Choices[0].LogProbs != nil {
+			m.lastLogprobs = resp.Choices[0].LogProbs.Content
+		}
+		if len(req.Tools) > 0 {
+			m.pendingToolCalls = resp.Choices[0].Message.ToolCalls
+		}
+		m.recordUsage(&resp.Usage)
 		fullResponse := resp.Choices[0].Message.Content
 		return fullResponse, nil
 	}
@@ -572,12 +853,9 @@ func (m *Manager) sendNonStreamingRequest(openaiMessages []openai.ChatCompletion
 	return "", fmt.Errorf("no response content")
 }
 
-func (m *Manager) sendStreamingRequest(openaiMessages []openai.ChatCompletionMessage, model string, streamingCancel *func(), isStreaming *bool) (string, error) {
-	req := openai.ChatCompletionRequest{
-		Model:    model,
-		Messages: openaiMessages,
-		Stream:   true,
-	}
+func (m *Manager) sendStreamingRequest(req openai.ChatCompletionRequest, streamingCancel *func(), isStreaming *bool) (string, error) {
+	req.Stream = true
+	m.lastLogprobs = nil
 
 	ctx, cancel := context.WithCancel(context.Background())
 	*isStreaming = true
@@ -596,58 +874,86 @@ func (m *Manager) sendStreamingRequest(openaiMessages []openai.ChatCompletionMes
 	defer func() {
 		_ = stream.Close()
 	}()
+	out, flush := m.streamOutput()
+	defer flush()
 
 	type streamChunk struct {
 		Choices []struct {
 			Delta struct {
-				Content          string `json:"content"`
-				ReasoningContent string `json:"reasoning_content"`
-				Reasoning        string `json:"reasoning"`
+				Content          string            `json:"content"`
+				ReasoningContent string            `json:"reasoning_content"`
+				Reasoning        string            `json:"reasoning"`
+				ToolCalls        []openai.ToolCall `json:"tool_calls"`
 			} `json:"delta"`
+			Logprobs *openai.LogProbs `json:"logprobs"`
 		} `json:"choices"`
+		Usage *openai.Usage `json:"usage"`
 	}
 
 	wasReasoning := false
 	lastReasoningEndsWithNewline := false
 	insideThinkTag := false
 	justExitedThinkTag := false
+	stopFilter := newStopSequenceFilter(m.config.StopSequences)
+	stopped := false
+	numberer := m.newCodeBlockNumberer()
+	tables := m.newTableRenderer()
 
-	for {
+	for !stopped {
 		rawBytes, err := stream.RecvRaw()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
 			if ctx.Err() == context.Canceled {
+				// Half-streamed tool calls are dropped with the rest of the reply
+				m.pendingToolCalls = nil
 				return response.String(), nil
 			}
 			return "", err
 		}
 
 		var chunk streamChunk
-		if err := json.Unmarshal(rawBytes, &chunk); err != nil || len(chunk.Choices) == 0 {
+		if err := json.Unmarshal(rawBytes, &chunk); err != nil {
+			continue
+		}
+		m.recordUsage(chunk.Usage)
+		if len(chunk.Choices) == 0 {
 			continue
 		}
 
 		delta := chunk.Choices[0].Delta
 		reasoning := delta.Reasoning + delta.ReasoningContent
+		if len(req.Tools) > 0 {
+			m.addToolCallDeltas(delta.ToolCalls)
+		}
+
+		if req.LogProbs && chunk.Choices[0].Logprobs != nil {
+			m.lastLogprobs = append(m.lastLogprobs, chunk.Choices[0].Logprobs.Content...)
+		}
 
 		if reasoning != "" {
 			wasReasoning = true
 			lastReasoningEndsWithNewline = strings.HasSuffix(reasoning, "\n")
 			if m.config.ShowThinking {
-				if m.config.IsPipedOutput {
-					fmt.Print(reasoning)
+				if m.config.StreamJSON {
+					writeStreamEvent(streamEvent{Type: "reasoning", Text: reasoning})
+				} else if m.config.IsPipedOutput {
+					fmt.Fprint(out, reasoning)
 				} else {
-					fmt.Print("\033[90m" + reasoning + "\033[0m")
+					fmt.Fprint(out, "\033[90m"+reasoning+"\033[0m")
 				}
 			}
 			response.WriteString(reasoning)
 		}
 
+		if stopFilter != nil && delta.Content != "" {
+			delta.Content, stopped = stopFilter.Push(delta.Content)
+		}
+
 		if delta.Content != "" {
-			if wasReasoning && !lastReasoningEndsWithNewline && m.config.ShowThinking {
-				fmt.Println()
+			if wasReasoning && !lastReasoningEndsWithNewline && m.config.ShowThinking && !m.config.StreamJSON {
+				fmt.Fprintln(out)
 			}
 			wasReasoning = false
 
@@ -665,12 +971,14 @@ func (m *Manager) sendStreamingRequest(openaiMessages []openai.ChatCompletionMes
 
 			if insideThinkTag && !m.config.ShowThinking {
 				// Skip displaying think-tagged content
+			} else if m.config.StreamJSON {
+				writeStreamEvent(streamEvent{Type: "delta", Text: delta.Content})
 			} else if m.config.IsPipedOutput {
-				fmt.Print(delta.Content)
+				fmt.Fprint(out, delta.Content)
 			} else if insideThinkTag {
-				fmt.Print("\033[90m" + delta.Content + "\033[0m")
+				fmt.Fprint(out, "\033[90m"+delta.Content+"\033[0m")
 			} else {
-				fmt.Print("\033[92m" + delta.Content + "\033[0m")
+				printResponseText(out, delta.Content, tables, numberer)
 			}
 
 			if strings.Contains(delta.Content, "</think>") {
@@ -684,16 +992,61 @@ func (m *Manager) sendStreamingRequest(openaiMessages []openai.ChatCompletionMes
 		}
 	}
 
-	fmt.Println()
+	if stopFilter != nil {
+		if rest := stopFilter.Flush(); rest != "" {
+			if m.config.StreamJSON {
+				writeStreamEvent(streamEvent{Type: "delta", Text: rest})
+			} else if m.config.IsPipedOutput {
+				fmt.Fprint(out, rest)
+			} else {
+				printResponseText(out, rest, tables, numberer)
+			}
+			response.WriteString(rest)
+		}
+	}
+	if tables != nil {
+		printResponseText(out, tables.Flush(), nil, numberer)
+	}
+
+	if !m.config.StreamJSON {
+		fmt.Fprintln(out)
+	}
+	flush()
+	if req.LogProbs {
+		m.printLogprobs(m.lastLogprobs)
+	}
 	return response.String(), nil
 }
 
 // fetchPlatformModelsWithTime fetches models with their creation timestamps
 func (m *Manager) fetchPlatformModelsWithTime(platform types.Platform) ([]modelWithTime, error) {
+	if platform.Name == MockPlatform {
+		fixtures, err := loadMockFixtures(m.config.MockFixtures)
+		if err != nil {
+			return nil, err
+		}
+		var models []modelWithTime
+		for _, model := range fixtures.models() {
+			models = append(models, modelWithTime{name: model})
+		}
+		return models, nil
+	}
+
+	if platform.Driver == DriverAzure {
+		return azureDeploymentModels(platform)
+	}
+
+	if len(platform.Models.Static) > 0 {
+		return staticModels(platform), nil
+	}
+	if platform.Models.URL == "" {
+		return nil, fmt.Errorf("no model list for platform %s: set models.url, or list models under models.static", platform.Name)
+	}
+
 	httpClient := &http.Client{Timeout: 10 * time.Second}
 
-	apiKey := os.Getenv(platform.EnvName)
-	if apiKey == "" && platform.Name != "ollama" {
+	apiKey := platformAPIKey(platform)
+	if needsAPIKey(platform) && apiKey == "" {
 		return nil, fmt.Errorf("%s environment variable not set", platform.EnvName)
 	}
 
@@ -706,7 +1059,8 @@ func (m *Manager) fetchPlatformModelsWithTime(platform types.Platform) ([]modelW
 	if err != nil || parsedModelURL.Host == "" {
 		return nil, fmt.Errorf("invalid model list URL for platform %s", platform.Name)
 	}
-	if platform.Name != "ollama" && parsedModelURL.Scheme != "https" {
+	// Keys only go to remote hosts over https; local servers may use http
+	if apiKey != "" && parsedModelURL.Scheme != "https" && !isLoopbackHost(parsedModelURL.Hostname()) {
 		return nil, fmt.Errorf("model list URL for platform %s must use https", platform.Name)
 	}
 
@@ -718,13 +1072,22 @@ func (m *Manager) fetchPlatformModelsWithTime(platform types.Platform) ([]modelW
 	if platform.Name == "anthropic" {
 		req.Header.Set("x-api-key", apiKey)
 		req.Header.Set("anthropic-version", "2023-06-01")
-	} else if platform.Name != "ollama" && platform.Name != "google" {
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+	} else if platform.Name != "google" {
+		setPlatformAuth(req, platform, apiKey)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	for key, value := range platform.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range platform.Models.Headers {
+		req.Header.Set(key, value)
+	}
 
 	resp, err := httpClient.Do(req) // #nosec G704 -- Request uses the validated built-in model-list URL for the selected provider.
 	if err != nil {
+		if platform.Name == "ollama" {
+			return nil, ollamaDownError(err, modelURL)
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -747,6 +1110,12 @@ func (m *Manager) extractPlatformModelsWithTimeFromJSON(data interface{}, platfo
 	if platform.Name == "together" {
 		return m.extractTogetherServerlessChatModelsWithTime(data)
 	}
+	if platform.Name == "ollama" {
+		return extractOllamaModelsWithTime(data)
+	}
+	if platform.Name == "openrouter" {
+		return extractOpenRouterModelsWithTime(data)
+	}
 
 	return m.extractModelsWithTimeFromJSON(data, platform.Models.JSONPath)
 }
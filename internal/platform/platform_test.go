@@ -2,7 +2,9 @@ package platform
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/MehmetMHY/ch/pkg/types"
 )
@@ -278,6 +280,21 @@ func TestIsSlowModel_NoPatterns(t *testing.T) {
 	}
 }
 
+func TestMessageRoleDeveloper(t *testing.T) {
+	m := NewManager(&types.Config{CurrentPlatform: "openai", DeveloperRolePlatforms: []string{"openai"}})
+	if got := m.messageRole("developer"); got != "developer" {
+		t.Errorf("messageRole on openai = %q, want developer", got)
+	}
+
+	m.config.CurrentPlatform = "groq"
+	if got := m.messageRole("developer"); got != "system" {
+		t.Errorf("messageRole on groq = %q, want system", got)
+	}
+	if got := m.messageRole("user"); got != "user" {
+		t.Errorf("messageRole should keep other roles, got %q", got)
+	}
+}
+
 // ---- extractModelsWithTimeFromJSON ----
 
 func TestExtractModelsWithTimeFromJSON(t *testing.T) {
@@ -460,3 +477,60 @@ func TestExtractPlatformModelsWithTimeFromJSONFiltersTogetherServerlessChatModel
 		t.Fatalf("unexpected model: %+v", got[0])
 	}
 }
+
+func TestNewChatRequestAppliesGenerationOptions(t *testing.T) {
+	seed := 42
+	m := NewManager(&types.Config{
+		StopSequences: []string{"END"},
+		Seed:          &seed,
+	})
+	req := m.newChatRequest(nil, "gpt-test")
+	if req.Model != "gpt-test" {
+		t.Errorf("Model = %q", req.Model)
+	}
+	if len(req.Stop) != 1 || req.Stop[0] != "END" {
+		t.Errorf("Stop = %v, want [END]", req.Stop)
+	}
+	if req.Seed == nil || *req.Seed != 42 {
+		t.Errorf("Seed = %v, want 42", req.Seed)
+	}
+
+	req = NewManager(&types.Config{}).newChatRequest(nil, "gpt-test")
+	if req.Seed != nil || req.Stop != nil {
+		t.Errorf("unset options should not be sent: seed=%v stop=%v", req.Seed, req.Stop)
+	}
+}
+
+func TestOpenAIMessagesAddsTimeContext(t *testing.T) {
+	messages := []types.ChatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "What day is it?"},
+	}
+
+	m := NewManager(&types.Config{})
+	if got := m.openAIMessages(messages, "gpt-4o"); got[0].Content != "You are helpful." {
+		t.Errorf("time context should be off by default, got %q", got[0].Content)
+	}
+
+	m.config.TimeContext = true
+	got := m.openAIMessages(messages, "gpt-4o")
+	if !strings.HasPrefix(got[0].Content, "Current date and time: ") || !strings.HasSuffix(got[0].Content, "\n\nYou are helpful.") {
+		t.Errorf("system prompt missing time context: %q", got[0].Content)
+	}
+	if messages[0].Content != "You are helpful." {
+		t.Errorf("time context must not change the stored messages, got %q", messages[0].Content)
+	}
+
+	got = m.openAIMessages(messages[1:], "gpt-4o")
+	if len(got) != 2 || got[0].Role != "system" || !strings.HasPrefix(got[0].Content, "Current date and time: ") {
+		t.Errorf("expected a time context system message before the conversation, got %v", got)
+	}
+}
+
+func TestTimeContextLine(t *testing.T) {
+	now := time.Date(2026, time.October, 15, 14, 3, 0, 0, time.FixedZone("PDT", -7*60*60))
+	want := "Current date and time: Thursday, October 15, 2026 14:03 PDT (UTC-07:00)"
+	if got := timeContextLine(now); got != want {
+		t.Errorf("timeContextLine() = %q, want %q", got, want)
+	}
+}
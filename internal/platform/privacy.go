@@ -0,0 +1,148 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// defaultOptOutParams are the request body fields that ask each provider not
+// to store or train on conversations. storage_opt_out_params in config adds
+// to or overrides them per platform.
+var defaultOptOutParams = map[string]map[string]any{
+	"openai":     {"store": false},
+	"openrouter": {"provider": map[string]any{"data_collection": "deny"}},
+}
+
+// chatTransport adds data-retention opt-out headers to every request and
+// opt-out and extra_body fields to chat completion bodies. go-openai omits
+// store when it is false and has no field for provider-specific knobs, so the
+// fields are merged into the JSON body here instead.
+type chatTransport struct {
+	base        http.RoundTripper
+	headers     map[string]string
+	params      map[string]any
+	modelParams map[string]map[string]any
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *chatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	if (len(t.params) > 0 || len(t.modelParams) > 0) && req.Body != nil && req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = mergeBodyParams(body, t.params, t.modelParams)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// mergeBodyParams sets params on a JSON object body, followed by the
+// modelParams entry for the body's model, leaving bodies that are not JSON
+// objects unchanged
+func mergeBodyParams(body []byte, params map[string]any, modelParams map[string]map[string]any) []byte {
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body
+	}
+	for key, value := range params {
+		fields[key] = value
+	}
+	if model, ok := fields["model"].(string); ok {
+		for key, value := range modelParams[model] {
+			fields[key] = value
+		}
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return merged
+}
+
+// chatHTTPClient returns a client that applies the storage opt-out and
+// extra_body fields and the rate_limits entry for platform, or nil when there
+// is nothing to add for it. The mock platform's client always answers from
+// its fixtures.
+func (m *Manager) chatHTTPClient(platform string) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if platform == MockPlatform && m.mock != nil {
+		transport = m.mock
+	}
+	if platform == m.config.CurrentPlatform && m.gemini != nil {
+		transport = m.gemini
+	}
+
+	if config, ok := m.config.Platforms[platform]; ok && sendsOwnAuth(config) {
+		transport = &authTransport{base: transport, platform: config, apiKey: platformAPIKey(config)}
+	}
+
+	headers, params, modelParams := m.chatRequestFields(platform)
+	if len(params) > 0 || len(headers) > 0 || len(modelParams) > 0 {
+		transport = &chatTransport{
+			base:        transport,
+			headers:     headers,
+			params:      params,
+			modelParams: modelParams,
+		}
+	}
+	if limiter := rateLimiterFor(platform, m.config.RateLimits); limiter != nil {
+		transport = &rateLimitTransport{base: transport, limiter: limiter}
+	}
+
+	if transport == http.DefaultTransport {
+		return nil
+	}
+	return &http.Client{Transport: transport}
+}
+
+// chatRequestFields returns the headers for platform (its own headers, then
+// opt-out headers, then extra_headers), the fields merged into every chat
+// body, and the fields merged for one model
+func (m *Manager) chatRequestFields(platform string) (map[string]string, map[string]any, map[string]map[string]any) {
+	params := map[string]any{}
+	var headers map[string]string
+	mergeHeaders := func(add map[string]string) {
+		if len(add) == 0 {
+			return
+		}
+		merged := make(map[string]string, len(headers)+len(add))
+		for key, value := range headers {
+			merged[key] = value
+		}
+		for key, value := range add {
+			merged[key] = value
+		}
+		headers = merged
+	}
+	mergeHeaders(m.config.Platforms[platform].Headers)
+	if m.config.ProviderStorageOptOut {
+		for key, value := range defaultOptOutParams[platform] {
+			params[key] = value
+		}
+		for key, value := range m.config.StorageOptOutParams[platform] {
+			params[key] = value
+		}
+		mergeHeaders(m.config.StorageOptOutHeaders[platform])
+	}
+	mergeHeaders(m.config.ExtraHeaders[platform])
+
+	extra, modelParams := m.extraBody(platform)
+	for key, value := range extra {
+		params[key] = value
+	}
+	return headers, params, modelParams
+}
@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestStorageOptOutAddsParamsAndHeaders(t *testing.T) {
+	var body map[string]any
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		header = r.Header.Get("X-No-Retention")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_ROUTER_KEY", "test")
+	cfg := &types.Config{
+		CurrentPlatform:       "openrouter",
+		IsPipedOutput:         true,
+		ProviderStorageOptOut: true,
+		StorageOptOutHeaders:  map[string]map[string]string{"openrouter": {"X-No-Retention": "1"}},
+		StorageOptOutParams:   map[string]map[string]any{"openrouter": {"store": false}},
+		Platforms: map[string]types.Platform{"openrouter": {
+			Name:    "openrouter",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_ROUTER_KEY",
+		}},
+	}
+	m := NewManager(cfg)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var cancel func()
+	var streaming bool
+	if _, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "hi"}}, "some-model", &cancel, &streaming); err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+
+	if store, ok := body["store"]; !ok || store != false {
+		t.Errorf("store=false not sent: %v", body)
+	}
+	provider, _ := body["provider"].(map[string]any)
+	if provider["data_collection"] != "deny" {
+		t.Errorf("built-in openrouter opt-out not sent: %v", body)
+	}
+	if body["model"] != "some-model" {
+		t.Errorf("original request fields lost: %v", body)
+	}
+	if header != "1" {
+		t.Errorf("opt-out header not sent, got %q", header)
+	}
+}
+
+func TestStorageOptOutOffByDefault(t *testing.T) {
+	m := NewManager(&types.Config{})
+	if m.chatHTTPClient("openai") != nil {
+		t.Error("opt-out client should only be used when provider_storage_opt_out is set")
+	}
+	m.config.ProviderStorageOptOut = true
+	if m.chatHTTPClient("groq") != nil {
+		t.Error("platforms without opt-out settings should use the default client")
+	}
+}
+
+func TestMergeBodyParamsKeepsNonObjectBodies(t *testing.T) {
+	if got := mergeBodyParams([]byte("not json"), map[string]any{"store": false}, nil); string(got) != "not json" {
+		t.Errorf("mergeBodyParams changed a non-JSON body: %q", got)
+	}
+}
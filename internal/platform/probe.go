@@ -0,0 +1,69 @@
+package platform
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// probeMaxTokens caps the reply of a probe request
+const probeMaxTokens = 16
+
+// ProbeResult is the outcome of a Probe request
+type ProbeResult struct {
+	Platform         string `json:"platform"`
+	Model            string `json:"model"`
+	OK               bool   `json:"ok"`
+	LatencyMS        int64  `json:"latency_ms"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Output           string `json:"output,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Probe sends prompt to model as a short non-streaming completion and
+// reports the latency, token usage, and first line of the reply. It is an
+// availability check, so nothing is printed and usage is not logged.
+func (m *Manager) Probe(ctx context.Context, model, prompt string) ProbeResult {
+	model = m.ResolveModel(nil, model)
+	result := ProbeResult{Platform: m.config.CurrentPlatform, Model: model}
+	if m.client == nil {
+		result.Error = "platform client is not initialized"
+		return result
+	}
+	if m.config.DryRun {
+		result.Error = ErrDryRun.Error()
+		return result
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: prompt}},
+	}
+	// Slow reasoning models spend tokens thinking before they answer, so
+	// their reply is left uncapped
+	if !m.isSlowModel(model) {
+		req.MaxTokens = probeMaxTokens
+	}
+
+	started := time.Now()
+	resp, err := m.client.CreateChatCompletion(ctx, req)
+	result.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if len(resp.Choices) == 0 {
+		result.Error = "no response content"
+		return result
+	}
+
+	result.OK = true
+	result.PromptTokens = resp.Usage.PromptTokens
+	result.CompletionTokens = resp.Usage.CompletionTokens
+	output := strings.TrimSpace(resp.Choices[0].Message.Content)
+	result.Output, _, _ = strings.Cut(output, "\n")
+	return result
+}
@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestProbe(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] == "slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		if body["model"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"message":"model not found"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"pong\nsecond line"}}],"usage":{"prompt_tokens":8,"completion_tokens":3,"total_tokens":11}}`)
+	}))
+	defer server.Close()
+
+	m := NewManager(&types.Config{
+		CurrentPlatform: "local",
+		Platforms: map[string]types.Platform{"local": {
+			Name: "local", BaseURL: types.BaseURLValue{Single: server.URL + "/v1"}, Auth: AuthNone,
+		}},
+	})
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := m.Probe(context.Background(), "llama3.2", "ping")
+	want := ProbeResult{Platform: "local", Model: "llama3.2", OK: true, PromptTokens: 8, CompletionTokens: 3, Output: "pong", LatencyMS: result.LatencyMS}
+	if result != want {
+		t.Errorf("Probe() = %+v, want %+v", result, want)
+	}
+	if body["max_tokens"] != float64(probeMaxTokens) || body["stream"] == true {
+		t.Errorf("probe request = %v, want a short non-streaming completion", body)
+	}
+	if m.lastUsage != nil {
+		t.Errorf("probe usage was recorded: %+v", m.lastUsage)
+	}
+
+	if result := m.Probe(context.Background(), "missing", "ping"); result.OK || !strings.Contains(result.Error, "model not found") {
+		t.Errorf("Probe(missing) = %+v", result)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if result := m.Probe(ctx, "slow", "ping"); result.OK || !strings.Contains(result.Error, "deadline exceeded") {
+		t.Errorf("Probe(slow) = %+v, want a timeout", result)
+	}
+}
@@ -0,0 +1,146 @@
+package platform
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// rateLimiter paces requests to one platform with a token bucket refilled at
+// RequestsPerMinute and caps how many are in flight at MaxConcurrent. One
+// limiter is shared by every client for the platform, so parallel features
+// such as chunked summarization stay under the same limits as chat.
+type rateLimiter struct {
+	limit types.RateLimit
+	slots chan struct{} // nil when concurrency is not capped
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[string]*rateLimiter{}
+)
+
+// rateLimiterFor returns the shared limiter for platform, or nil when
+// rate_limits sets no limit for it
+func rateLimiterFor(platform string, limits map[string]types.RateLimit) *rateLimiter {
+	limit, ok := limits[platform]
+	if !ok || (limit.RequestsPerMinute <= 0 && limit.MaxConcurrent <= 0) {
+		return nil
+	}
+
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if limiter := rateLimiters[platform]; limiter != nil && limiter.limit == limit {
+		return limiter
+	}
+	limiter := &rateLimiter{limit: limit}
+	if limit.MaxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	rateLimiters[platform] = limiter
+	return limiter
+}
+
+// acquire waits for a concurrency slot and then a token, returning the
+// function that frees the slot once the request is done
+func (l *rateLimiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-l.slots }) }
+	}
+
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// wait takes a token from the bucket, sleeping until one is due. The bucket
+// holds Burst tokens (default 1), so requests are spread evenly over the
+// minute instead of sent all at once.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.limit.RequestsPerMinute <= 0 {
+		return nil
+	}
+	perToken := time.Minute / time.Duration(l.limit.RequestsPerMinute)
+	burst := float64(max(l.limit.Burst, 1))
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens = min(burst, l.tokens+float64(now.Sub(l.last))/float64(perToken))
+	}
+	l.last = now
+	// Reserve the token now so concurrent callers queue behind each other
+	l.tokens--
+	wait := time.Duration(-l.tokens * float64(perToken))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// rateLimitTransport holds each request to the platform's limits. The
+// concurrency slot is kept until the response body is closed, so a streamed
+// reply counts as in flight for as long as it streams.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody frees a rate limiter slot when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close implements io.Closer
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
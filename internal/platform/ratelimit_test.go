@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRateLimiterFor(t *testing.T) {
+	limits := map[string]types.RateLimit{
+		"groq": {RequestsPerMinute: 30},
+		"off":  {},
+	}
+	if rateLimiterFor("openai", limits) != nil || rateLimiterFor("off", limits) != nil {
+		t.Error("platforms without limits should not get a limiter")
+	}
+	first := rateLimiterFor("groq", limits)
+	if first == nil || rateLimiterFor("groq", limits) != first {
+		t.Error("a platform's limiter should be shared")
+	}
+	if rateLimiterFor("groq", map[string]types.RateLimit{"groq": {RequestsPerMinute: 60}}) == first {
+		t.Error("changed limits should replace the limiter")
+	}
+}
+
+func TestRateLimiterPacesRequests(t *testing.T) {
+	limiter := &rateLimiter{limit: types.RateLimit{RequestsPerMinute: 1200, Burst: 2}}
+	started := time.Now()
+	for range 4 {
+		release, err := limiter.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire() error: %v", err)
+		}
+		release()
+	}
+	// Two requests fit in the burst, the other two wait 50ms each
+	if elapsed := time.Since(started); elapsed < 90*time.Millisecond {
+		t.Errorf("4 requests at 1200/min with burst 2 took %v, want at least 100ms", elapsed)
+	}
+
+	limiter = &rateLimiter{limit: types.RateLimit{RequestsPerMinute: 1}}
+	if _, err := limiter.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire() error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire() past the deadline error = %v", err)
+	}
+}
+
+func TestRateLimitTransportCapsConcurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	limiter := &rateLimiter{limit: types.RateLimit{MaxConcurrent: 1}, slots: make(chan struct{}, 1)}
+	client := &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport, limiter: limiter}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("first request error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := client.Do(req); err == nil {
+		t.Error("a second request should wait while the first body is open")
+	}
+
+	_ = resp.Body.Close()
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request after close error: %v", err)
+	}
+	_ = resp.Body.Close()
+}
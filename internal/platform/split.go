@@ -0,0 +1,161 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// minMessageLimit is the smallest part size a rejected message is split into
+const minMessageLimit = 4000
+
+// messagePartHeader leaves room for the header added to each part
+const messagePartHeader = 160
+
+// messageTooLongPhrases are lowercase fragments of the errors providers
+// return when one message is longer than they accept
+var messageTooLongPhrases = []string{
+	"string_above_max_length",
+	"string too long",
+	"message is too long",
+	"message too long",
+	"content is too long",
+	"content too long",
+}
+
+// maximumLength finds the per-message limit some providers name in the error
+var maximumLength = regexp.MustCompile(`maximum length (?:of )?(\d+)`)
+
+// IsMessageTooLongError reports whether err is a provider rejecting a single
+// message as too long, which splitting it into parts can fix, as opposed to
+// the whole request not fitting in the context window
+func IsMessageTooLongError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if code, ok := apiErr.Code.(string); ok && code == "string_above_max_length" {
+			return true
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, phrase := range messageTooLongPhrases {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageLimit returns the longest user message sent in one piece: the limit
+// learned from a rejection this session, else max_message_chars. Lengths are
+// counted in bytes, which is never fewer than characters. 0 means messages
+// are never split.
+func (m *Manager) MessageLimit() int {
+	if m.messageLimit > 0 {
+		return m.messageLimit
+	}
+	return max(m.config.MaxMessageChars, 0)
+}
+
+// LowerMessageLimit lowers the message limit after err rejected a message as
+// too long: to the maximum the provider names, else to half the longest
+// user message or part sent. It reports false when the limit would not go
+// lower, as when the rejected message is one splitting never shrinks, so
+// resending would fail again.
+func (m *Manager) LowerMessageLimit(err error) bool {
+	sent := m.longestMessage
+	if m.messageLimit > 0 {
+		sent = min(sent, m.messageLimit)
+	}
+	limit := sent / 2
+	if match := maximumLength.FindStringSubmatch(err.Error()); match != nil {
+		if n, convErr := strconv.Atoi(match[1]); convErr == nil {
+			limit = n
+		}
+	}
+	if limit < minMessageLimit || limit >= sent || (m.messageLimit > 0 && limit >= m.messageLimit) {
+		return false
+	}
+	m.messageLimit = limit
+	return true
+}
+
+// splitLongMessages replaces each user message longer than the message limit
+// with numbered parts, each acknowledged by the assistant, followed by a turn
+// asking for the answer, so a huge paste is sent in pieces the provider
+// accepts. Run after mergeConsecutiveUserMessages, whose merged message is
+// the one the provider sees.
+func (m *Manager) splitLongMessages(messages []types.ChatMessage) []types.ChatMessage {
+	m.longestMessage = 0
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			m.longestMessage = max(m.longestMessage, len(msg.Content))
+		}
+	}
+	limit := m.MessageLimit()
+	if limit <= 0 || m.longestMessage <= limit {
+		return messages
+	}
+
+	var result []types.ChatMessage
+	for k, msg := range messages {
+		if msg.Role != "user" || len(msg.Content) <= limit {
+			result = append(result, msg)
+			continue
+		}
+
+		parts := splitText(msg.Content, max(limit-messagePartHeader, limit/2, 1))
+		// Earlier split messages are split again on every request, so only
+		// the pending one is worth a notice
+		if k == len(messages)-1 {
+			m.printWarning(fmt.Sprintf("message of %d characters is over the %d limit, sending it in %d parts", len(msg.Content), limit, len(parts)))
+		}
+		for i, part := range parts {
+			result = append(result,
+				types.ChatMessage{Role: "user", Content: fmt.Sprintf("[part %d/%d of one long message; reply only with an acknowledgement until all parts arrive]\n\n%s", i+1, len(parts), part)},
+				types.ChatMessage{Role: "assistant", Content: fmt.Sprintf("Received part %d of %d.", i+1, len(parts))},
+			)
+		}
+		result = append(result, types.ChatMessage{
+			Role:    "user",
+			Content: fmt.Sprintf("[end of message] That was all %d parts. Now respond to the full message above as if it had been sent at once.", len(parts)),
+			Images:  msg.Images,
+		})
+	}
+	return result
+}
+
+// splitText cuts text into pieces of at most size bytes, preferring to end
+// each piece at a line break, then at a space, and never inside a character
+func splitText(text string, size int) []string {
+	var parts []string
+	for len(text) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			if _, cut = utf8.DecodeRuneInString(text); cut == len(text) {
+				break
+			}
+		}
+		if i := strings.LastIndexByte(text[:cut], '\n'); i >= size/2 {
+			cut = i + 1
+		} else if i := strings.LastIndexByte(text[:cut], ' '); i >= size/2 {
+			cut = i + 1
+		}
+		parts = append(parts, text[:cut])
+		text = text[cut:]
+	}
+	return append(parts, text)
+}
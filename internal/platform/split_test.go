@@ -0,0 +1,127 @@
+package platform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		size int
+		want []string
+	}{
+		{"short text", "hello", 10, []string{"hello"}},
+		{"at line breaks", "aaaa\nbbbb\ncccc", 10, []string{"aaaa\nbbbb\n", "cccc"}},
+		{"at spaces", "aaa bbb ccc ddd", 8, []string{"aaa bbb ", "ccc ddd"}},
+		{"no break", "abcdefgh", 3, []string{"abc", "def", "gh"}},
+		{"never inside a character", "ééé", 3, []string{"é", "é", "é"}},
+		{"character wider than size", "日本", 1, []string{"日", "本"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.size)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLongMessages(t *testing.T) {
+	m := NewManager(&types.Config{MaxMessageChars: 400, IsPipedOutput: true})
+	long := strings.Repeat("line of a huge paste\n", 30)
+	messages := []types.ChatMessage{
+		{Role: "system", Content: strings.Repeat("s", 500)},
+		{Role: "user", Content: "short question"},
+		{Role: "assistant", Content: "short answer"},
+		{Role: "user", Content: long, Images: []string{"/tmp/a.png"}},
+	}
+
+	got := m.splitLongMessages(messages)
+	if got[0].Content != messages[0].Content || got[1].Content != "short question" || got[2].Content != "short answer" {
+		t.Fatalf("messages under the limit changed: %+v", got[:3])
+	}
+	// 630 characters in parts of up to 240 with room for their headers
+	parts := got[3:]
+	if len(parts) != 7 {
+		t.Fatalf("got %d messages for the long one, want three acknowledged parts and the final turn", len(parts))
+	}
+
+	var joined strings.Builder
+	for i := 0; i < 6; i += 2 {
+		header, part, ok := strings.Cut(parts[i].Content, "\n\n")
+		if parts[i].Role != "user" || !ok || !strings.HasPrefix(header, "[part ") || len(parts[i].Content) > 400 {
+			t.Errorf("part %d = %q", i/2+1, parts[i].Content)
+		}
+		if parts[i+1].Role != "assistant" {
+			t.Errorf("part %d is not acknowledged: %+v", i/2+1, parts[i+1])
+		}
+		joined.WriteString(part)
+	}
+	if joined.String() != long {
+		t.Error("parts do not add up to the original message")
+	}
+	if last := parts[6]; last.Role != "user" || !strings.Contains(last.Content, "all 3 parts") || len(last.Images) != 1 {
+		t.Errorf("final turn = %+v", last)
+	}
+
+	m.config.MaxMessageChars = -1
+	if got := m.splitLongMessages(messages); len(got) != len(messages) {
+		t.Errorf("negative max_message_chars split messages: %d", len(got))
+	}
+}
+
+func TestLowerMessageLimit(t *testing.T) {
+	tooLong := &openai.APIError{Code: "string_above_max_length", Message: "Invalid 'messages[1].content': string too long. Expected a string with maximum length 10000, but got a string with length 50000 instead."}
+	tests := []struct {
+		name      string
+		err       error
+		longest   int
+		limit     int
+		wantLimit int
+		wantOK    bool
+	}{
+		{"named maximum", tooLong, 50000, 0, 10000, true},
+		{"halves without a maximum", errors.New("message too long"), 50000, 0, 25000, true},
+		{"maximum no lower than what was sent", tooLong, 9000, 0, 0, false},
+		{"halves the parts already split", errors.New("message too long"), 50000, 25000, 12500, true},
+		{"named maximum already in use", tooLong, 50000, 10000, 0, false},
+		{"already small", errors.New("message too long"), 6000, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&types.Config{MaxMessageChars: 1000000})
+			m.longestMessage, m.messageLimit = tt.longest, tt.limit
+			if ok := m.LowerMessageLimit(tt.err); ok != tt.wantOK {
+				t.Fatalf("LowerMessageLimit() = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.wantOK && m.MessageLimit() != tt.wantLimit {
+				t.Errorf("MessageLimit() = %d, want %d", m.MessageLimit(), tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestIsMessageTooLongError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&openai.APIError{Code: "string_above_max_length", Message: "invalid request"}, true},
+		{errors.New("400: Message is too long"), true},
+		{&openai.APIError{Code: "context_length_exceeded", Message: "This model's maximum context length is 8192 tokens."}, false},
+		{errors.New("connection reset"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsMessageTooLongError(tt.err); got != tt.want {
+			t.Errorf("IsMessageTooLongError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
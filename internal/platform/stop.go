@@ -0,0 +1,101 @@
+package platform
+
+import "strings"
+
+// maxAPIStopSequences is the most stop sequences OpenAI-compatible APIs accept per request
+const maxAPIStopSequences = 4
+
+// apiStopSequences returns the stop sequences to send with a request. Extra
+// sequences beyond the API limit are still enforced client-side.
+func apiStopSequences(stops []string) []string {
+	var out []string
+	for _, stop := range stops {
+		if stop == "" {
+			continue
+		}
+		out = append(out, stop)
+		if len(out) == maxAPIStopSequences {
+			break
+		}
+	}
+	return out
+}
+
+// truncateAtStop cuts text at the earliest stop sequence, reporting whether one was found
+func truncateAtStop(text string, stops []string) (string, bool) {
+	cut := -1
+	for _, stop := range stops {
+		if stop == "" {
+			continue
+		}
+		if idx := strings.Index(text, stop); idx >= 0 && (cut < 0 || idx < cut) {
+			cut = idx
+		}
+	}
+	if cut < 0 {
+		return text, false
+	}
+	return text[:cut], true
+}
+
+// stopSequenceFilter enforces stop sequences on streamed content for providers
+// that ignore the stop parameter. Text that could be the start of a stop
+// sequence split across chunks is held back until it can be resolved.
+type stopSequenceFilter struct {
+	stops   []string
+	pending string
+	stopped bool
+}
+
+// newStopSequenceFilter returns a filter for the given stop sequences, or nil when there are none
+func newStopSequenceFilter(stops []string) *stopSequenceFilter {
+	var active []string
+	for _, stop := range stops {
+		if stop != "" {
+			active = append(active, stop)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return &stopSequenceFilter{stops: active}
+}
+
+// Push adds a streamed chunk and returns the text that is safe to emit.
+// Once a stop sequence is seen, the remainder is dropped and stopped is true.
+func (f *stopSequenceFilter) Push(chunk string) (string, bool) {
+	if f.stopped {
+		return "", true
+	}
+
+	text := f.pending + chunk
+	f.pending = ""
+
+	if truncated, found := truncateAtStop(text, f.stops); found {
+		f.stopped = true
+		return truncated, true
+	}
+
+	// Hold back the longest suffix that is a prefix of some stop sequence
+	hold := 0
+	for _, stop := range f.stops {
+		for n := len(stop) - 1; n > hold; n-- {
+			if n <= len(text) && strings.HasSuffix(text, stop[:n]) {
+				hold = n
+				break
+			}
+		}
+	}
+	f.pending = text[len(text)-hold:]
+	return text[:len(text)-hold], false
+}
+
+// Flush returns any held-back text at the end of the stream
+func (f *stopSequenceFilter) Flush() string {
+	if f.stopped {
+		return ""
+	}
+	text := f.pending
+	f.pending = ""
+	return text
+}
@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApiStopSequences(t *testing.T) {
+	got := apiStopSequences([]string{"a", "", "b", "c", "d", "e"})
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("apiStopSequences() = %v, want %v", got, want)
+	}
+	if got := apiStopSequences(nil); got != nil {
+		t.Errorf("apiStopSequences(nil) = %v, want nil", got)
+	}
+}
+
+func TestTruncateAtStop(t *testing.T) {
+	text, found := truncateAtStop("answer: yes\nEND\nmore", []string{"\nEND", "more"})
+	if !found || text != "answer: yes" {
+		t.Errorf("truncateAtStop() = %q, %v", text, found)
+	}
+	text, found = truncateAtStop("no stops here", []string{"###"})
+	if found || text != "no stops here" {
+		t.Errorf("truncateAtStop() = %q, %v", text, found)
+	}
+}
+
+func TestStopSequenceFilter(t *testing.T) {
+	if newStopSequenceFilter([]string{""}) != nil {
+		t.Fatal("filter with no non-empty stops should be nil")
+	}
+
+	f := newStopSequenceFilter([]string{"</answer>"})
+	var out strings.Builder
+	stopped := false
+	for _, chunk := range []string{"The result", " is 42.</ans", "wer> trailing", " ignored"} {
+		var text string
+		text, stopped = f.Push(chunk)
+		out.WriteString(text)
+		if stopped {
+			break
+		}
+	}
+	if !stopped {
+		t.Fatal("expected the stop sequence split across chunks to be detected")
+	}
+	if out.String() != "The result is 42." {
+		t.Errorf("filtered output = %q", out.String())
+	}
+
+	// A partial match that never completes is emitted on flush
+	f = newStopSequenceFilter([]string{"STOP"})
+	text, stopped := f.Push("almost ST")
+	if stopped || text != "almost " {
+		t.Errorf("Push() = %q, %v, want held-back partial match", text, stopped)
+	}
+	if rest := f.Flush(); rest != "ST" {
+		t.Errorf("Flush() = %q, want %q", rest, "ST")
+	}
+}
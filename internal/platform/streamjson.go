@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// streamEventOutput receives --stream-json events; tests replace it
+var streamEventOutput io.Writer = os.Stdout
+
+// streamEvent is one newline-delimited JSON event written by --stream-json.
+// Types are delta (response text), reasoning (thinking text, with
+// show_thinking), done (final model and usage), and error.
+type streamEvent struct {
+	Type    string       `json:"type"`
+	Text    string       `json:"text,omitempty"`
+	Model   string       `json:"model,omitempty"`
+	Usage   *streamUsage `json:"usage,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// streamUsage reports token counts. Estimated is set when the provider did
+// not return usage and the counts come from the local tokenizer.
+type streamUsage struct {
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated,omitempty"`
+}
+
+// writeStreamEvent writes one event as a single JSON line
+func writeStreamEvent(event streamEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(streamEventOutput, string(data))
+}
+
+// emitStreamResult ends a --stream-json response with a done or error event
+func (m *Manager) emitStreamResult(messages []types.ChatMessage, model, response string, err error) {
+	if err != nil {
+		writeStreamEvent(streamEvent{Type: "error", Message: err.Error()})
+		return
+	}
+
+	usage := &streamUsage{}
+	if m.lastUsage != nil && m.lastUsage.TotalTokens > 0 {
+		usage.PromptTokens = m.lastUsage.PromptTokens
+		usage.CompletionTokens = m.lastUsage.CompletionTokens
+		usage.TotalTokens = m.lastUsage.TotalTokens
+	} else {
+		usage.PromptTokens = countMessageTokens(model, messages)
+		usage.CompletionTokens = tokens.Count(model, response)
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		usage.Estimated = true
+	}
+	writeStreamEvent(streamEvent{Type: "done", Model: model, Usage: usage})
+}
+
+// recordUsage keeps provider-reported usage for the done event
+func (m *Manager) recordUsage(usage *openai.Usage) {
+	if usage != nil && usage.TotalTokens > 0 {
+		m.lastUsage = usage
+	}
+}
@@ -0,0 +1,124 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// newStreamJSONTestManager serves a streamed completion of the given chunks,
+// followed by a usage chunk when withUsage is set
+func newStreamJSONTestManager(t *testing.T, chunks []string, withUsage bool) (*Manager, *bytes.Buffer) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			data, _ := json.Marshal(map[string]any{
+				"choices": []map[string]any{{"index": 0, "delta": map[string]string{"content": chunk}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		if withUsage {
+			fmt.Fprint(w, `data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`+"\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	clientConfig := openai.DefaultConfig("test")
+	clientConfig.BaseURL = server.URL
+	m := &Manager{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: &types.Config{StreamJSON: true, IsPipedOutput: true},
+	}
+
+	var out bytes.Buffer
+	previous := streamEventOutput
+	streamEventOutput = &out
+	t.Cleanup(func() { streamEventOutput = previous })
+	return m, &out
+}
+
+func decodeStreamEvents(t *testing.T, out *bytes.Buffer) []streamEvent {
+	t.Helper()
+	var events []streamEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event streamEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line is not a JSON event: %q", line)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func sendStreamJSONRequest(m *Manager) (string, error) {
+	var cancel func()
+	var streaming bool
+	return m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "say hello"}}, "test-model", &cancel, &streaming)
+}
+
+func TestStreamJSONEmitsDeltasAndProviderUsage(t *testing.T) {
+	m, out := newStreamJSONTestManager(t, []string{"Hel", "lo"}, true)
+
+	response, err := sendStreamJSONRequest(m)
+	if err != nil || response != "Hello" {
+		t.Fatalf("SendChatRequest() = %q, %v", response, err)
+	}
+
+	events := decodeStreamEvents(t, out)
+	if len(events) != 3 {
+		t.Fatalf("expected 2 deltas and done, got %+v", events)
+	}
+	if events[0] != (streamEvent{Type: "delta", Text: "Hel"}) || events[1] != (streamEvent{Type: "delta", Text: "lo"}) {
+		t.Errorf("unexpected deltas: %+v", events[:2])
+	}
+	done := events[2]
+	if done.Type != "done" || done.Model != "test-model" || done.Usage == nil {
+		t.Fatalf("unexpected done event: %+v", done)
+	}
+	if *done.Usage != (streamUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}) {
+		t.Errorf("provider usage not passed through: %+v", *done.Usage)
+	}
+}
+
+func TestStreamJSONEstimatesUsageWhenMissing(t *testing.T) {
+	m, out := newStreamJSONTestManager(t, []string{"Hello there"}, false)
+
+	if _, err := sendStreamJSONRequest(m); err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+
+	events := decodeStreamEvents(t, out)
+	done := events[len(events)-1]
+	if done.Type != "done" || done.Usage == nil || !done.Usage.Estimated || done.Usage.CompletionTokens == 0 {
+		t.Errorf("expected an estimated usage done event, got %+v", done)
+	}
+}
+
+func TestStreamJSONReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"model overloaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	m, out := newStreamJSONTestManager(t, nil, false)
+	clientConfig := openai.DefaultConfig("test")
+	clientConfig.BaseURL = server.URL
+	m.client = openai.NewClientWithConfig(clientConfig)
+
+	if _, err := sendStreamJSONRequest(m); err == nil {
+		t.Fatal("expected an error")
+	}
+	events := decodeStreamEvents(t, out)
+	if len(events) != 1 || events[0].Type != "error" || !strings.Contains(events[0].Message, "overloaded") {
+		t.Errorf("expected one error event, got %+v", events)
+	}
+}
@@ -0,0 +1,264 @@
+package platform
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tableSeparatorCell matches a cell of a markdown table's separator row,
+// whose colons set the column alignment
+var tableSeparatorCell = regexp.MustCompile(`^:?-+:?$`)
+
+// tableBorder is a box-drawing string drawn dim inside green response text,
+// switching back to green afterwards
+func tableBorder(s string) string {
+	return "\033[90m" + s + "\033[92m"
+}
+
+// tableRenderer redraws the markdown tables of a displayed response with
+// aligned box-drawing borders. Lines that may be table rows are held back
+// until the table ends; all other text passes through as it streams.
+type tableRenderer struct {
+	line    strings.Builder
+	passing bool // the current line is not a table row and is being written
+	rows    []string
+	inCode  bool
+}
+
+// Push returns the part of chunk that can be shown now
+func (r *tableRenderer) Push(chunk string) string {
+	var out strings.Builder
+	for {
+		i := strings.IndexByte(chunk, '\n')
+		part := chunk
+		if i >= 0 {
+			part = chunk[:i]
+		}
+		r.line.WriteString(part)
+		if r.passing {
+			out.WriteString(part)
+		} else if !r.mayBeRow(r.line.String()) {
+			out.WriteString(r.release())
+			out.WriteString(r.line.String())
+			r.passing = true
+		}
+		if i < 0 {
+			return out.String()
+		}
+
+		line := r.line.String()
+		r.line.Reset()
+		chunk = chunk[i+1:]
+		if !r.passing {
+			if !r.inCode && strings.HasPrefix(strings.TrimSpace(line), "|") {
+				r.rows = append(r.rows, line)
+				continue
+			}
+			out.WriteString(r.release())
+			out.WriteString(line)
+		}
+		r.passing = false
+		out.WriteByte('\n')
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			r.inCode = !r.inCode
+		}
+	}
+}
+
+// Flush returns everything still held back, at the end of the response
+func (r *tableRenderer) Flush() string {
+	line, passing := r.line.String(), r.passing
+	r.line.Reset()
+	r.passing = false
+	if passing {
+		return ""
+	}
+	// A last row without a newline still belongs to the table
+	if strings.TrimSpace(line) != "" {
+		r.rows = append(r.rows, line)
+		return strings.TrimSuffix(r.release(), "\n")
+	}
+	return r.release() + line
+}
+
+// mayBeRow reports whether a line that has started streaming could still
+// turn out to be a table row
+func (r *tableRenderer) mayBeRow(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	return !r.inCode && (trimmed == "" || strings.HasPrefix(trimmed, "|"))
+}
+
+// release returns the held rows, drawn as a table when they form one, each
+// followed by a newline
+func (r *tableRenderer) release() string {
+	if len(r.rows) == 0 {
+		return ""
+	}
+	rows := r.rows
+	r.rows = nil
+	if table, ok := renderTable(rows); ok {
+		return table
+	}
+	return strings.Join(rows, "\n") + "\n"
+}
+
+// renderTable draws markdown table lines with box-drawing borders, or
+// reports false when the second line is not a separator row
+func renderTable(lines []string) (string, bool) {
+	if len(lines) < 2 {
+		return "", false
+	}
+	header := splitTableRow(lines[0])
+	separator := splitTableRow(lines[1])
+	if len(separator) != len(header) {
+		return "", false
+	}
+	aligns := make([]byte, len(separator))
+	for i, cell := range separator {
+		if !tableSeparatorCell.MatchString(cell) {
+			return "", false
+		}
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns[i] = 'c'
+		case strings.HasSuffix(cell, ":"):
+			aligns[i] = 'r'
+		default:
+			aligns[i] = 'l'
+		}
+	}
+
+	rows := [][]string{header}
+	for _, line := range lines[2:] {
+		cells := splitTableRow(line)
+		// Rows with missing cells are padded and extra cells dropped, as
+		// markdown renderers do
+		for len(cells) < len(header) {
+			cells = append(cells, "")
+		}
+		rows = append(rows, cells[:len(header)])
+	}
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+
+	var b strings.Builder
+	rule := func(left, middle, right string) {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat("─", width+2)
+		}
+		b.WriteString(tableBorder(left+strings.Join(parts, middle)+right) + "\n")
+	}
+	rule("┌", "┬", "┐")
+	for k, row := range rows {
+		b.WriteString(tableBorder("│"))
+		for i, cell := range row {
+			b.WriteString(" " + alignCell(cell, widths[i], aligns[i]) + " " + tableBorder("│"))
+		}
+		b.WriteByte('\n')
+		if k == 0 {
+			rule("├", "┼", "┤")
+		}
+	}
+	rule("└", "┴", "┘")
+	return b.String(), true
+}
+
+// splitTableRow returns the trimmed cells of a table row, keeping escaped
+// \| as a literal pipe
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// alignCell pads text to width, aligned left, right, or centered
+func alignCell(text string, width int, align byte) string {
+	pad := width - displayWidth(text)
+	switch align {
+	case 'r':
+		return strings.Repeat(" ", pad) + text
+	case 'c':
+		return strings.Repeat(" ", pad/2) + text + strings.Repeat(" ", pad-pad/2)
+	default:
+		return text + strings.Repeat(" ", pad)
+	}
+}
+
+// displayWidth estimates the terminal columns text takes: two for wide East
+// Asian characters and emoji, none for combining marks
+func displayWidth(text string) int {
+	width := 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+		switch {
+		case unicode.Is(unicode.Mn, r) || r == '\u200d' || r == '\ufe0f':
+		case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3,
+			r >= 0xf900 && r <= 0xfaff, r >= 0xfe30 && r <= 0xfe4f, r >= 0xff00 && r <= 0xff60,
+			r >= 0xffe0 && r <= 0xffe6, r >= 0x1f300 && r <= 0x1faff, r >= 0x20000 && r <= 0x3fffd:
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// printResponseText writes streamed response text in green, drawing tables
+// and numbering code blocks when those are set
+func printResponseText(out io.Writer, text string, tables *tableRenderer, numberer *codeBlockNumberer) {
+	if tables != nil {
+		text = tables.Push(text)
+	}
+	if numberer != nil {
+		text = numberer.Mark(text)
+	}
+	if text != "" {
+		fmt.Fprint(out, "\033[92m"+text+"\033[0m")
+	}
+}
+
+// newTableRenderer returns a table renderer for streamed output, or nil when
+// render_tables is off or the response is not shown in color
+func (m *Manager) newTableRenderer() *tableRenderer {
+	if !m.config.RenderTables || m.config.IsPipedOutput || m.config.StreamJSON {
+		return nil
+	}
+	return &tableRenderer{}
+}
+
+// FormatResponse prepares a complete response for display the way streamed
+// responses are shown: tables drawn with borders and code blocks numbered
+func (m *Manager) FormatResponse(text string) string {
+	if tables := m.newTableRenderer(); tables != nil {
+		text = tables.Push(text) + tables.Flush()
+	}
+	return m.NumberCodeBlocks(text)
+}
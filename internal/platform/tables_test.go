@@ -0,0 +1,97 @@
+package platform
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+var ansiCode = regexp.MustCompile("\033\\[[0-9;]*m")
+
+func TestTableRendererDrawsStreamedTables(t *testing.T) {
+	table := "┌──────┬───────┬─────┐\n" +
+		"│ Name │ Price │ Tag │\n" +
+		"├──────┼───────┼─────┤\n" +
+		"│ Go   │     0 │ a|b │\n" +
+		"│ 日本 │  1.50 │     │\n" +
+		"└──────┴───────┴─────┘\n"
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{
+			"table between text",
+			"Compare:\n\n| Name | Price | Tag |\n|------|------:|:---:|\n| Go | 0 | a\\|b |\n| 日本 | 1.50 |\n\nDone.",
+			"Compare:\n\n" + table + "\nDone.",
+		},
+		{
+			"table ending the response without a newline",
+			"| Name | Price | Tag |\n| --- | ---: | :-: |\n| Go | 0 | a\\|b |\n| 日本 | 1.50 | |",
+			strings.TrimSuffix(table, "\n"),
+		},
+		{
+			"pipes without a separator row stay raw",
+			"| not | a table |\n| just | pipes |\n",
+			"| not | a table |\n| just | pipes |\n",
+		},
+		{
+			"tables in code blocks stay raw",
+			"```md\n| a | b |\n|---|---|\n```\n",
+			"```md\n| a | b |\n|---|---|\n```\n",
+		},
+		{
+			"text without tables",
+			"plain text\nwith | a pipe\n",
+			"plain text\nwith | a pipe\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Split at every byte so rows arrive across chunks
+			r := &tableRenderer{}
+			var got strings.Builder
+			for i := 0; i < len(tt.response); i++ {
+				got.WriteString(r.Push(tt.response[i : i+1]))
+			}
+			got.WriteString(r.Flush())
+			if plain := ansiCode.ReplaceAllString(got.String(), ""); plain != tt.want {
+				t.Errorf("streamed:\ngot:  %q\nwant: %q", plain, tt.want)
+			}
+
+			whole := &tableRenderer{}
+			if plain := ansiCode.ReplaceAllString(whole.Push(tt.response)+whole.Flush(), ""); plain != tt.want {
+				t.Errorf("whole text:\ngot:  %q\nwant: %q", plain, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableRendererPassesTextThroughWhileStreaming(t *testing.T) {
+	r := &tableRenderer{}
+	if got := r.Push("Hello, wor"); got != "Hello, wor" {
+		t.Errorf("Push() = %q, want text shown before the line ends", got)
+	}
+	if got := r.Push("ld\n| a "); got != "ld\n" {
+		t.Errorf("Push() = %q, want a possible row held back", got)
+	}
+	if got := r.Push("| b |\nnext"); got != "| a | b |\nnext" {
+		t.Errorf("Push() = %q, want a lone row released raw", got)
+	}
+}
+
+func TestFormatResponseRespectsConfig(t *testing.T) {
+	text := "| a | b |\n|---|---|\n| 1 | 2 |\n"
+	if got := NewManager(&types.Config{RenderTables: true, IsPipedOutput: true}).FormatResponse(text); got != text {
+		t.Errorf("piped output should not be drawn, got %q", got)
+	}
+	if got := NewManager(&types.Config{}).FormatResponse(text); got != text {
+		t.Errorf("render_tables off should not draw, got %q", got)
+	}
+	if got := NewManager(&types.Config{RenderTables: true}).FormatResponse(text); !strings.Contains(got, "┌") {
+		t.Errorf("expected a drawn table, got %q", got)
+	}
+}
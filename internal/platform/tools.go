@@ -0,0 +1,181 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxToolRounds caps how many times one request answers tool calls and asks
+// the model again, so a model that keeps calling tools cannot loop forever
+const maxToolRounds = 8
+
+// maxToolResultBytes caps the tool output sent back to the model
+const maxToolResultBytes = 50000
+
+// Tool is a local function the model may call when the tools option is on.
+// Parameters is the JSON schema of the arguments object, and Run returns the
+// text sent back to the model.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+	Run         func(args map[string]any) (string, error)
+}
+
+// RegisterTool adds tool to the registry, replacing a tool with its name
+func (m *Manager) RegisterTool(tool Tool) {
+	for i, existing := range m.tools {
+		if existing.Name == tool.Name {
+			m.tools[i] = tool
+			return
+		}
+	}
+	m.tools = append(m.tools, tool)
+}
+
+// ToolNames lists the registered tools
+func (m *Manager) ToolNames() []string {
+	names := make([]string, len(m.tools))
+	for i, tool := range m.tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// toolDefinitions describes the registered tools for a chat request
+func (m *Manager) toolDefinitions() []openai.Tool {
+	definitions := make([]openai.Tool, len(m.tools))
+	for i, tool := range m.tools {
+		definitions[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		}
+	}
+	return definitions
+}
+
+// runToolLoop answers the tool calls in the last reply, sends the results
+// back, and repeats until the model replies without calling a tool. The text
+// of every round is returned, joined by blank lines.
+func (m *Manager) runToolLoop(req openai.ChatCompletionRequest, response string, streamingCancel *func(), isStreaming *bool) (string, error) {
+	var replies []string
+	for round := 1; len(m.pendingToolCalls) > 0; round++ {
+		calls := m.pendingToolCalls
+		m.pendingToolCalls = nil
+		if strings.TrimSpace(response) != "" {
+			replies = append(replies, response)
+		}
+		if round > maxToolRounds {
+			m.printWarning(fmt.Sprintf("warning: stopped after %d rounds of tool calls", maxToolRounds))
+			return strings.Join(replies, "\n\n"), nil
+		}
+
+		req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+			Role:      openai.ChatMessageRoleAssistant,
+			Content:   response,
+			ToolCalls: calls,
+		})
+		for _, call := range calls {
+			req.Messages = append(req.Messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    m.runToolCall(call),
+				ToolCallID: call.ID,
+			})
+		}
+
+		var err error
+		if response, err = m.dispatchChatRequest(req, streamingCancel, isStreaming); err != nil {
+			return strings.Join(replies, "\n\n"), err
+		}
+	}
+	if strings.TrimSpace(response) != "" {
+		replies = append(replies, response)
+	}
+	return strings.Join(replies, "\n\n"), nil
+}
+
+// runToolCall asks before running one tool call and returns what the model
+// is told: the tool output, or why it did not run
+func (m *Manager) runToolCall(call openai.ToolCall) string {
+	var tool *Tool
+	for i := range m.tools {
+		if m.tools[i].Name == call.Function.Name {
+			tool = &m.tools[i]
+		}
+	}
+	if tool == nil {
+		return fmt.Sprintf("error: there is no tool named %q", call.Function.Name)
+	}
+
+	args := map[string]any{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return fmt.Sprintf("error: arguments are not a JSON object: %v", err)
+		}
+	}
+	if m.ConfirmTool == nil || !m.ConfirmTool(fmt.Sprintf("run tool %s", formatToolCall(tool.Name, args))) {
+		return "error: the user declined to run this tool call"
+	}
+
+	output, err := tool.Run(args)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	if len(output) > maxToolResultBytes {
+		// Back off to a character boundary so the result stays valid UTF-8
+		cut := maxToolResultBytes
+		for cut > 0 && !utf8.RuneStart(output[cut]) {
+			cut--
+		}
+		output = output[:cut] + fmt.Sprintf("\n[output truncated at %d bytes]", maxToolResultBytes)
+	}
+	return output
+}
+
+// formatToolCall shows a call for the confirmation prompt, e.g.
+// run_shell(command="ls -la")
+func formatToolCall(name string, args map[string]any) string {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		value, _ := json.Marshal(args[key])
+		parts[i] = key + "=" + string(value)
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(parts, ", "))
+}
+
+// addToolCallDeltas merges streamed tool call pieces into the pending calls.
+// Each delta names the call by index; the first piece carries its id and
+// name, and the arguments arrive in fragments.
+func (m *Manager) addToolCallDeltas(deltas []openai.ToolCall) {
+	for _, delta := range deltas {
+		index := len(m.pendingToolCalls)
+		if delta.Index != nil {
+			index = *delta.Index
+		}
+		for len(m.pendingToolCalls) <= index {
+			m.pendingToolCalls = append(m.pendingToolCalls, openai.ToolCall{Type: openai.ToolTypeFunction})
+		}
+		call := &m.pendingToolCalls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
@@ -0,0 +1,138 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSendChatRequestRunsToolCalls(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var req openai.ChatCompletionRequest
+		_ = json.Unmarshal(data, &req)
+		requests = append(requests, req)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			// The call arrives in pieces: id and name first, then the arguments
+			fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":""}}]}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"key\":"}}]}}]}`+"\n\n")
+			fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"color\"}"}}]},"finish_reason":"tool_calls"}]}`+"\n\n")
+		} else {
+			fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"It is blue."}}]}`+"\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_TOOLS_KEY", "test")
+	m := NewManager(&types.Config{
+		CurrentPlatform: "groq",
+		IsPipedOutput:   true,
+		Tools:           true,
+		Platforms: map[string]types.Platform{"groq": {
+			Name:    "groq",
+			BaseURL: types.BaseURLValue{Single: server.URL},
+			EnvName: "TEST_TOOLS_KEY",
+		}},
+	})
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var ran map[string]any
+	var asked string
+	m.RegisterTool(Tool{
+		Name:        "lookup",
+		Description: "Look up a value",
+		Parameters:  map[string]any{"type": "object"},
+		Run: func(args map[string]any) (string, error) {
+			ran = args
+			return "blue", nil
+		},
+	})
+	m.ConfirmTool = func(question string) bool {
+		asked = question
+		return true
+	}
+
+	var cancel func()
+	var streaming bool
+	response, err := m.SendChatRequest([]types.ChatMessage{{Role: "user", Content: "what color?"}}, "llama", &cancel, &streaming)
+	if err != nil {
+		t.Fatalf("SendChatRequest() error: %v", err)
+	}
+
+	if response != "It is blue." {
+		t.Errorf("response = %q, want the reply after the tool call", response)
+	}
+	if ran["key"] != "color" {
+		t.Errorf("tool ran with %v, want key=color", ran)
+	}
+	if asked != `run tool lookup(key="color")` {
+		t.Errorf("confirmation = %q", asked)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(requests))
+	}
+	if len(requests[0].Tools) != 1 || requests[0].Tools[0].Function.Name != "lookup" {
+		t.Errorf("first request tools = %+v, want lookup", requests[0].Tools)
+	}
+	messages := requests[1].Messages
+	if len(messages) != 3 {
+		t.Fatalf("second request messages = %+v, want user, assistant tool call, tool result", messages)
+	}
+	if call := messages[1].ToolCalls; len(call) != 1 || call[0].ID != "call_1" || call[0].Function.Arguments != `{"key":"color"}` {
+		t.Errorf("assistant tool calls = %+v", call)
+	}
+	if messages[2].Role != openai.ChatMessageRoleTool || messages[2].ToolCallID != "call_1" || messages[2].Content != "blue" {
+		t.Errorf("tool result message = %+v", messages[2])
+	}
+}
+
+func TestRunToolCallResults(t *testing.T) {
+	m := NewManager(&types.Config{})
+	m.RegisterTool(Tool{Name: "echo", Run: func(args map[string]any) (string, error) {
+		return fmt.Sprint(args["text"]), nil
+	}})
+	call := func(name, arguments string) openai.ToolCall {
+		return openai.ToolCall{ID: "1", Function: openai.FunctionCall{Name: name, Arguments: arguments}}
+	}
+
+	tests := []struct {
+		name    string
+		call    openai.ToolCall
+		confirm func(string) bool
+		want    string
+	}{
+		{"runs when confirmed", call("echo", `{"text":"hi"}`), func(string) bool { return true }, "hi"},
+		{"declined", call("echo", `{"text":"hi"}`), func(string) bool { return false }, "error: the user declined to run this tool call"},
+		{"nobody to confirm", call("echo", `{"text":"hi"}`), nil, "error: the user declined to run this tool call"},
+		{"unknown tool", call("rm", `{}`), func(string) bool { return true }, `error: there is no tool named "rm"`},
+		{"truncated on a character boundary", call("echo", `{"text":"a`+strings.Repeat("é", maxToolResultBytes/2)+`"}`), func(string) bool { return true },
+			"a" + strings.Repeat("é", maxToolResultBytes/2-1) + fmt.Sprintf("\n[output truncated at %d bytes]", maxToolResultBytes)},
+		{"bad arguments", call("echo", `[1]`), func(string) bool { return true }, "error: arguments are not a JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.ConfirmTool = tt.confirm
+			got := m.runToolCall(tt.call)
+			if got != tt.want {
+				t.Errorf("runToolCall() = %.200q, want %.200q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Error("runToolCall() returned invalid UTF-8")
+			}
+		})
+	}
+}
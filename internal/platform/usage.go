@@ -0,0 +1,218 @@
+package platform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// addRequestUsage adds the tokens and cost of a finished request to the
+// session totals and to the usage not yet written to usage.jsonl, using
+// provider usage when it was reported and local token counts otherwise.
+// Spend also goes to the daily total that max_daily_cost checks.
+func (m *Manager) addRequestUsage(messages []types.ChatMessage, model, response string) {
+	usage := types.ModelUsage{Requests: 1}
+	if m.lastUsage != nil && m.lastUsage.TotalTokens > 0 {
+		usage.PromptTokens, usage.CompletionTokens = m.lastUsage.PromptTokens, m.lastUsage.CompletionTokens
+	} else {
+		usage.PromptTokens, usage.CompletionTokens = countMessageTokens(model, messages), tokens.Count(model, response)
+		usage.Estimated = 1
+	}
+	usage.Cost = m.requestCost(model, usage.PromptTokens, usage.CompletionTokens)
+
+	key := m.config.CurrentPlatform + "/" + model
+	if m.sessionUsage == nil {
+		m.sessionUsage = make(map[string]types.ModelUsage)
+		m.unloggedUsage = make(map[string]types.ModelUsage)
+	}
+	m.sessionUsage[key] = addUsage(m.sessionUsage[key], usage)
+	m.unloggedUsage[key] = addUsage(m.unloggedUsage[key], usage)
+
+	if usage.Cost == 0 {
+		return
+	}
+	m.sessionSpend += usage.Cost
+	if m.config.MaxDailyCost > 0 {
+		_ = addDailySpend(time.Now(), usage.Cost)
+	}
+}
+
+// addUsage returns the sum of two usage records
+func addUsage(a, b types.ModelUsage) types.ModelUsage {
+	return types.ModelUsage{
+		Requests:         a.Requests + b.Requests,
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		Cost:             a.Cost + b.Cost,
+		Estimated:        a.Estimated + b.Estimated,
+	}
+}
+
+// UsageTotal adds up usage across models
+func UsageTotal(models map[string]types.ModelUsage) types.ModelUsage {
+	var total types.ModelUsage
+	for _, usage := range models {
+		total = addUsage(total, usage)
+	}
+	return total
+}
+
+// SessionUsage returns the tokens and estimated cost of requests in this
+// run, by "platform/model"
+func (m *Manager) SessionUsage() map[string]types.ModelUsage {
+	return m.sessionUsage
+}
+
+// FormatUsage describes usage in one line, marking token counts that were
+// partly estimated locally with ~
+func FormatUsage(usage types.ModelUsage) string {
+	approx := ""
+	if usage.Estimated > 0 {
+		approx = "~"
+	}
+	requests := "requests"
+	if usage.Requests == 1 {
+		requests = "request"
+	}
+	return fmt.Sprintf("%d %s, %s%d prompt + %s%d completion tokens, $%.4f", usage.Requests, requests, approx, usage.PromptTokens, approx, usage.CompletionTokens, usage.Cost)
+}
+
+// usageFilePath returns the file holding one usage summary per ch run
+func usageFilePath() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "usage.jsonl"), nil
+}
+
+// WriteUsageSummary appends the usage since the last call to usage.jsonl,
+// labelled with session. Nothing is written when usage_log is off or no
+// requests were made, so it is safe to call on every exit path.
+func (m *Manager) WriteUsageSummary(session string) error {
+	if !m.config.UsageLog || len(m.unloggedUsage) == 0 {
+		return nil
+	}
+	line, err := json.Marshal(types.UsageSummary{Time: time.Now().Unix(), Session: session, Models: m.unloggedUsage})
+	if err != nil {
+		return err
+	}
+
+	path, err := usageFilePath()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) // #nosec G304 -- Usage path is resolved under the current user's ~/.ch directory.
+	if err != nil {
+		return fmt.Errorf("failed to open usage file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	m.unloggedUsage = make(map[string]types.ModelUsage)
+	return nil
+}
+
+// LoadUsageSummaries reads usage.jsonl, skipping lines that do not parse
+func LoadUsageSummaries() ([]types.UsageSummary, error) {
+	path, err := usageFilePath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path) // #nosec G304 -- Usage path is resolved under the current user's ~/.ch directory.
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var summaries []types.UsageSummary
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var summary types.UsageSummary
+		if json.Unmarshal(scanner.Bytes(), &summary) == nil && len(summary.Models) > 0 {
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, scanner.Err()
+}
+
+// UsageReport totals summaries recorded at or after since (all of them when
+// since is zero) by model and by local day
+func UsageReport(summaries []types.UsageSummary, since time.Time) string {
+	byModel := make(map[string]types.ModelUsage)
+	byDay := make(map[string]types.ModelUsage)
+	runs := 0
+	for _, summary := range summaries {
+		at := time.Unix(summary.Time, 0)
+		if at.Before(since) {
+			continue
+		}
+		runs++
+		day := at.Format("2006-01-02")
+		for model, usage := range summary.Models {
+			byModel[model] = addUsage(byModel[model], usage)
+			byDay[day] = addUsage(byDay[day], usage)
+		}
+	}
+	if runs == 0 {
+		return "no usage recorded"
+	}
+
+	var out strings.Builder
+	row := func(label string, usage types.ModelUsage) {
+		approx := ""
+		if usage.Estimated > 0 {
+			approx = "~"
+		}
+		fmt.Fprintf(&out, "%-40s %8d %13s %13s %10s\n", label, usage.Requests,
+			approx+fmt.Sprint(usage.PromptTokens), approx+fmt.Sprint(usage.CompletionTokens), fmt.Sprintf("$%.4f", usage.Cost))
+	}
+	header := func(label string) {
+		fmt.Fprintf(&out, "%-40s %8s %13s %13s %10s\n", label, "requests", "prompt", "completion", "cost")
+	}
+
+	header("model")
+	models := make([]string, 0, len(byModel))
+	for model := range byModel {
+		models = append(models, model)
+	}
+	// Most expensive first, then by name
+	sort.Slice(models, func(i, j int) bool {
+		if byModel[models[i]].Cost != byModel[models[j]].Cost {
+			return byModel[models[i]].Cost > byModel[models[j]].Cost
+		}
+		return models[i] < models[j]
+	})
+	for _, model := range models {
+		row(model, byModel[model])
+	}
+
+	out.WriteString("\n")
+	header("day")
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		row(day, byDay[day])
+	}
+
+	out.WriteString("\n")
+	row(fmt.Sprintf("total (%d runs)", runs), UsageTotal(byModel))
+	return out.String()
+}
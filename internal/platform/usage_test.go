@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAddRequestUsage(t *testing.T) {
+	m := NewManager(&types.Config{
+		CurrentPlatform: "openai",
+		ModelPrices:     map[string]types.ModelPrice{"pricey": {Input: 1e6, Output: 2e6}},
+	})
+	messages := []types.ChatMessage{{Role: "user", Content: "hi"}}
+
+	m.lastUsage = &openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	m.addRequestUsage(messages, "pricey", "hello")
+	m.lastUsage = nil
+	m.addRequestUsage(messages, "pricey", "hello")
+
+	got := m.SessionUsage()["openai/pricey"]
+	if got.Requests != 2 || got.Estimated != 1 {
+		t.Errorf("usage = %+v, want 2 requests with 1 estimated", got)
+	}
+	if got.PromptTokens <= 10 || got.CompletionTokens <= 5 {
+		t.Errorf("usage = %+v, want the reported and counted tokens added", got)
+	}
+	if want := float64(got.PromptTokens) + 2*float64(got.CompletionTokens); got.Cost != want || m.SessionSpend() != want {
+		t.Errorf("cost = %v, spend = %v, want %v", got.Cost, m.SessionSpend(), want)
+	}
+}
+
+func TestWriteUsageSummary(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	m := NewManager(&types.Config{CurrentPlatform: "openai", UsageLog: true})
+	if err := m.WriteUsageSummary("ch_session_1.json"); err != nil {
+		t.Fatal(err)
+	}
+	if summaries, _ := LoadUsageSummaries(); len(summaries) != 0 {
+		t.Errorf("a run without requests wrote %d summaries", len(summaries))
+	}
+
+	m.lastUsage = &openai.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	m.addRequestUsage(nil, "gpt-4.1", "")
+	if err := m.WriteUsageSummary("ch_session_1.json"); err != nil {
+		t.Fatal(err)
+	}
+	// A second call only writes usage added since the first
+	if err := m.WriteUsageSummary("ch_session_1.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	summaries, err := LoadUsageSummaries()
+	if err != nil || len(summaries) != 1 {
+		t.Fatalf("LoadUsageSummaries() = %+v, %v; want one summary", summaries, err)
+	}
+	got := summaries[0]
+	if got.Session != "ch_session_1.json" || got.Models["openai/gpt-4.1"].PromptTokens != 100 {
+		t.Errorf("summary = %+v", got)
+	}
+
+	m.config.UsageLog = false
+	m.addRequestUsage(nil, "gpt-4.1", "")
+	if err := m.WriteUsageSummary(""); err != nil {
+		t.Fatal(err)
+	}
+	if summaries, _ := LoadUsageSummaries(); len(summaries) != 1 {
+		t.Errorf("usage_log off still wrote a summary, have %d", len(summaries))
+	}
+}
+
+func TestUsageReport(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	summaries := []types.UsageSummary{
+		{Time: day.AddDate(0, 0, -10).Unix(), Models: map[string]types.ModelUsage{
+			"openai/gpt-4.1": {Requests: 9, PromptTokens: 900, CompletionTokens: 90, Cost: 9},
+		}},
+		{Time: day.Unix(), Models: map[string]types.ModelUsage{
+			"openai/gpt-4.1": {Requests: 2, PromptTokens: 200, CompletionTokens: 20, Cost: 0.5},
+			"groq/llama":     {Requests: 1, PromptTokens: 50, CompletionTokens: 5, Estimated: 1},
+		}},
+	}
+
+	report := UsageReport(summaries, day.AddDate(0, 0, -1))
+	for _, want := range []string{"openai/gpt-4.1", "groq/llama", "~50", "2026-03-02", "total (1 runs)", "$0.5000"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "$9.") || strings.Contains(report, "2026-02-20") {
+		t.Errorf("report includes runs before since:\n%s", report)
+	}
+	if strings.Index(report, "openai/gpt-4.1") > strings.Index(report, "groq/llama") {
+		t.Errorf("models are not ordered by cost:\n%s", report)
+	}
+
+	if got := UsageReport(summaries, day.AddDate(0, 0, 1)); got != "no usage recorded" {
+		t.Errorf("UsageReport() with nothing in range = %q", got)
+	}
+}
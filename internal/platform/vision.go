@@ -0,0 +1,195 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoders for images loaded with !l
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// imageMaxDimension is the longest side an attached image is sent with.
+// Providers scale larger images down anyway, so sending them only adds
+// upload time and tokens.
+const imageMaxDimension = 2048
+
+// imageMaxBytes keeps an encoded image under the strictest common provider
+// limit (5 MB of base64) with room to spare
+const imageMaxBytes = 3_500_000
+
+// imageMinDimension stops shrinking an image that will not fit in
+// imageMaxBytes; such an image is left out
+const imageMinDimension = 256
+
+// imageMimeTypes are the formats image parts are sent in, by extension
+var imageMimeTypes = map[string]string{
+	".png": "image/png", ".jpg": "image/jpeg", ".jpeg": "image/jpeg",
+	".gif": "image/gif", ".webp": "image/webp",
+}
+
+// cachedImage is a prepared data URL, valid while the file is unchanged
+type cachedImage struct {
+	modTime time.Time
+	size    int64
+	url     string
+}
+
+// sendsImageParts reports whether loaded images are sent to model as image
+// parts: when it supports vision, or on the Gemini driver. Other models only
+// get the text description !l loaded with the image.
+func (m *Manager) sendsImageParts(model string) bool {
+	return m.config.Platforms[m.config.CurrentPlatform].Driver == DriverGemini || m.Capabilities(model).Vision
+}
+
+// withImageParts adds msg's images to the converted message as image parts
+func (m *Manager) withImageParts(converted openai.ChatCompletionMessage, msg types.ChatMessage) openai.ChatCompletionMessage {
+	var parts []openai.ChatMessagePart
+	for _, path := range msg.Images {
+		url, err := m.imageDataURL(path)
+		if err != nil {
+			if !m.noticed["image:"+path] {
+				if m.noticed == nil {
+					m.noticed = make(map[string]bool)
+				}
+				m.noticed["image:"+path] = true
+				m.printWarning(fmt.Sprintf("warning: %s is sent as text only: %v", filepath.Base(path), err))
+			}
+			continue
+		}
+		parts = append(parts, openai.ChatMessagePart{
+			Type:     openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{URL: url},
+		})
+	}
+	if len(parts) == 0 {
+		return converted
+	}
+	text := openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: converted.Content}
+	converted.MultiContent = append([]openai.ChatMessagePart{text}, parts...)
+	converted.Content = ""
+	return converted
+}
+
+// imageDataURL returns path as a base64 data URL, resized when needed and
+// cached until the file changes, since every request resends the history
+func (m *Manager) imageDataURL(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if cached, ok := m.images[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.url, nil
+	}
+
+	mimeType := imageMimeTypes[strings.ToLower(filepath.Ext(path))]
+	if mimeType == "" {
+		return "", fmt.Errorf("unsupported image type")
+	}
+	data, err := os.ReadFile(path) // #nosec G304 -- Images were loaded by the user with !l.
+	if err != nil {
+		return "", err
+	}
+	if data, mimeType, err = fitImage(data, mimeType); err != nil {
+		return "", err
+	}
+
+	url := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	if m.images == nil {
+		m.images = make(map[string]cachedImage)
+	}
+	m.images[path] = cachedImage{modTime: info.ModTime(), size: info.Size(), url: url}
+	return url, nil
+}
+
+// fitImage returns data unchanged when it is within imageMaxDimension and
+// imageMaxBytes, and otherwise scales it down until it fits. Resized images
+// are PNG when they have transparency and JPEG otherwise.
+func fitImage(data []byte, mimeType string) ([]byte, string, error) {
+	config, _, configErr := image.DecodeConfig(bytes.NewReader(data))
+	if configErr == nil && max(config.Width, config.Height) <= imageMaxDimension && len(data) <= imageMaxBytes {
+		return data, mimeType, nil
+	}
+	if configErr != nil && len(data) <= imageMaxBytes {
+		// Formats the standard library cannot decode, like WebP, are sent as is
+		return data, mimeType, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("too large to send and cannot be resized: %v", err)
+	}
+	bounds := img.Bounds()
+	scale := min(1, float64(imageMaxDimension)/float64(max(bounds.Dx(), bounds.Dy())))
+	transparent := hasTransparency(img)
+	for {
+		width := max(1, int(float64(bounds.Dx())*scale))
+		height := max(1, int(float64(bounds.Dy())*scale))
+		resized := scaleImage(img, width, height)
+
+		var out bytes.Buffer
+		if transparent {
+			err = png.Encode(&out, resized)
+			mimeType = "image/png"
+		} else {
+			err = jpeg.Encode(&out, resized, &jpeg.Options{Quality: 85})
+			mimeType = "image/jpeg"
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if out.Len() <= imageMaxBytes {
+			return out.Bytes(), mimeType, nil
+		}
+		if max(width, height) <= imageMinDimension {
+			return nil, "", fmt.Errorf("still over %d bytes at %dx%d", imageMaxBytes, width, height)
+		}
+		scale *= 0.75
+	}
+}
+
+// hasTransparency reports whether any pixel of img is not fully opaque
+func hasTransparency(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return !opaque.Opaque()
+	}
+	return false
+}
+
+// scaleImage resizes img to width x height by averaging the source pixels
+// under each destination pixel, which keeps downscaled text readable
+func scaleImage(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r, g, b, a = r+int(p[0]), g+int(p[1]), b+int(p[2]), a+int(p[3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,93 @@
+package platform
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// encodedPNG returns a width x height PNG filled with fill
+func encodedPNG(t *testing.T, width, height int, fill color.NRGBA) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = fill.R, fill.G, fill.B, fill.A
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestFitImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantMime string
+		wantSize int // longest side after fitting, 0 when data is passed through
+	}{
+		{"small image is sent as is", encodedPNG(t, 64, 32, color.NRGBA{R: 200, A: 255}), "image/png", 0},
+		{"undecodable small data is sent as is", []byte("webp bytes"), "image/png", 0},
+		{"large opaque image becomes a JPEG", encodedPNG(t, 4096, 1024, color.NRGBA{G: 200, A: 255}), "image/jpeg", imageMaxDimension},
+		{"large transparent image stays a PNG", encodedPNG(t, 1024, 3000, color.NRGBA{B: 200, A: 100}), "image/png", imageMaxDimension},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, mimeType, err := fitImage(tt.data, "image/png")
+			if err != nil {
+				t.Fatalf("fitImage() error: %v", err)
+			}
+			if mimeType != tt.wantMime {
+				t.Errorf("mime type = %q, want %q", mimeType, tt.wantMime)
+			}
+			if tt.wantSize == 0 {
+				if !bytes.Equal(data, tt.data) {
+					t.Error("fitImage() changed data that was within limits")
+				}
+				return
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("resized image does not decode: %v", err)
+			}
+			if got := max(config.Width, config.Height); got != tt.wantSize {
+				t.Errorf("longest side = %d, want %d", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestOpenAIMessagesAttachesImagesForVisionModels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(path, encodedPNG(t, 8, 8, color.NRGBA{R: 255, A: 255}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(&types.Config{CurrentPlatform: "openai", IsPipedOutput: true})
+	messages := []types.ChatMessage{{Role: "user", Content: "File: chart.png\nwhat is this?", Images: []string{path}}}
+
+	got := m.openAIMessages(messages, "gpt-4o")
+	parts := got[0].MultiContent
+	if len(parts) != 2 || got[0].Content != "" {
+		t.Fatalf("gpt-4o message = %+v, want a text part and an image part", got[0])
+	}
+	if parts[0].Text != messages[0].Content {
+		t.Errorf("text part = %q, want the message content", parts[0].Text)
+	}
+	if parts[1].Type != openai.ChatMessagePartTypeImageURL || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("image part = %+v, want a PNG data URL", parts[1])
+	}
+
+	got = m.openAIMessages(messages, "gpt-3.5-turbo")
+	if len(got[0].MultiContent) != 0 || got[0].Content != messages[0].Content {
+		t.Errorf("gpt-3.5-turbo message = %+v, want text only", got[0])
+	}
+}
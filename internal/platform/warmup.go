@@ -0,0 +1,44 @@
+package platform
+
+import (
+	"context"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// warmupTimeout bounds a warmup request, which nothing waits for
+const warmupTimeout = 60 * time.Second
+
+// Warmup sends a one-token completion for model in the background, so the
+// connection, TLS handshake, and a local server's model load are done
+// before the first real prompt. The reply, any error, and its usage are
+// discarded. The returned channel is closed when the request ends.
+func (m *Manager) Warmup(model string) <-chan struct{} {
+	done := make(chan struct{})
+	if m.client == nil || m.config.DryRun || m.config.CurrentPlatform == MockPlatform {
+		close(done)
+		return done
+	}
+
+	model = m.ResolveModel(nil, model)
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+	}
+	// Slow reasoning models take max_completion_tokens instead of max_tokens
+	if m.isSlowModel(model) {
+		req.MaxCompletionTokens = 1
+	} else {
+		req.MaxTokens = 1
+	}
+
+	client := m.client
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+		defer cancel()
+		_, _ = client.CreateChatCompletion(ctx, req)
+	}()
+	return done
+}
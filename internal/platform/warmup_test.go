@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestWarmup(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests <- body
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"h"}}],"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`)
+	}))
+	defer server.Close()
+
+	m := NewManager(&types.Config{
+		CurrentPlatform: "local",
+		Platforms: map[string]types.Platform{"local": {
+			Name: "local", BaseURL: types.BaseURLValue{Single: server.URL + "/v1"}, Auth: AuthNone,
+		}},
+	})
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-m.Warmup("llama3.2"):
+	case <-time.After(5 * time.Second):
+		t.Fatal("warmup did not finish")
+	}
+	body := <-requests
+	if body["model"] != "llama3.2" || body["max_tokens"] != float64(1) || body["stream"] == true {
+		t.Errorf("warmup request = %v, want a one-token non-streaming completion", body)
+	}
+	if m.lastUsage != nil {
+		t.Errorf("warmup usage was recorded: %+v", m.lastUsage)
+	}
+
+	// Dry runs send nothing
+	m.config.DryRun = true
+	<-m.Warmup("llama3.2")
+	if len(requests) != 0 {
+		t.Error("warmup sent a request in a dry run")
+	}
+}
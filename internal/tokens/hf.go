@@ -0,0 +1,277 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2/v2"
+)
+
+// gpt2Pattern is the pre-tokenizer regex of byte-level BPE tokenizers that do
+// not define their own Split pattern
+const gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// maxCachedWords bounds the per-word count cache of a HF tokenizer
+const maxCachedWords = 50000
+
+// HFTokenizer counts tokens with the BPE model of a Hugging Face
+// tokenizer.json. Byte-level vocabularies (Llama 3) split text with the
+// pre-tokenizer regex, SentencePiece-style vocabularies (Llama 2, Mistral)
+// split it at word boundaries marked with ▁.
+type HFTokenizer struct {
+	name         string
+	vocab        map[string]int
+	ranks        map[string]int
+	byteFallback bool
+	byteLevel    bool
+	split        *regexp2.Regexp
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// hfFile is the part of tokenizer.json needed to count tokens
+type hfFile struct {
+	PreTokenizer any `json:"pre_tokenizer"`
+	Model        struct {
+		Type         string          `json:"type"`
+		Vocab        map[string]int  `json:"vocab"`
+		Merges       json.RawMessage `json:"merges"`
+		ByteFallback bool            `json:"byte_fallback"`
+	} `json:"model"`
+}
+
+// LoadHFTokenizer reads a tokenizer.json with a BPE model
+func LoadHFTokenizer(path string) (*HFTokenizer, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Tokenizer files are read from the ch data directory.
+	if err != nil {
+		return nil, err
+	}
+
+	var file hfFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer: %w", err)
+	}
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model %s: only BPE is supported", file.Model.Type)
+	}
+	if len(file.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer has no vocabulary")
+	}
+
+	merges, err := parseMerges(file.Model.Merges)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make(map[string]int, len(merges))
+	for rank, pair := range merges {
+		key := pair[0] + "\x00" + pair[1]
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = rank
+		}
+	}
+
+	tok := &HFTokenizer{
+		name:         filepath.Base(path),
+		vocab:        file.Model.Vocab,
+		ranks:        ranks,
+		byteFallback: file.Model.ByteFallback,
+		cache:        make(map[string]int),
+	}
+
+	pattern, byteLevel := preTokenizerPattern(file.PreTokenizer)
+	if byteLevel {
+		tok.byteLevel = true
+		if pattern == "" {
+			pattern = gpt2Pattern
+		}
+		if tok.split, err = regexp2.Compile(pattern, regexp2.None); err != nil {
+			return nil, fmt.Errorf("failed to compile pre-tokenizer pattern: %w", err)
+		}
+	}
+	return tok, nil
+}
+
+// parseMerges accepts both the "a b" and ["a", "b"] merge formats
+func parseMerges(raw json.RawMessage) ([][2]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var pairs [][2]string
+	if err := json.Unmarshal(raw, &pairs); err == nil {
+		return pairs, nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer merges: %w", err)
+	}
+	pairs = make([][2]string, 0, len(lines))
+	for _, line := range lines {
+		left, right, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, [2]string{left, right})
+	}
+	return pairs, nil
+}
+
+// preTokenizerPattern walks the pre_tokenizer tree and returns the first
+// Split regex and whether a ByteLevel step is present
+func preTokenizerPattern(node any) (string, bool) {
+	switch v := node.(type) {
+	case map[string]any:
+		pattern, byteLevel := "", v["type"] == "ByteLevel"
+		if v["type"] == "Split" {
+			if p, ok := v["pattern"].(map[string]any); ok {
+				pattern, _ = p["Regex"].(string)
+			}
+		}
+		for _, child := range v {
+			childPattern, childByteLevel := preTokenizerPattern(child)
+			if pattern == "" {
+				pattern = childPattern
+			}
+			byteLevel = byteLevel || childByteLevel
+		}
+		return pattern, byteLevel
+	case []any:
+		pattern, byteLevel := "", false
+		for _, child := range v {
+			childPattern, childByteLevel := preTokenizerPattern(child)
+			if pattern == "" {
+				pattern = childPattern
+			}
+			byteLevel = byteLevel || childByteLevel
+		}
+		return pattern, byteLevel
+	}
+	return "", false
+}
+
+// Name returns the tokenizer file name
+func (h *HFTokenizer) Name() string {
+	return h.name
+}
+
+// Count returns the number of BPE tokens in text
+func (h *HFTokenizer) Count(text string) int {
+	total := 0
+	for _, word := range h.words(text) {
+		total += h.countWord(word)
+	}
+	return total
+}
+
+// words pre-tokenizes text into the units BPE merges within
+func (h *HFTokenizer) words(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	if !h.byteLevel {
+		// SentencePiece marks spaces with ▁ and starts the text with one
+		text = "▁" + strings.ReplaceAll(text, " ", "▁")
+		var words []string
+		start := 0
+		for i := 1; i < len(text); {
+			if strings.HasPrefix(text[i:], "▁") && !strings.HasPrefix(text[i-len("▁"):], "▁") {
+				words = append(words, text[start:i])
+				start = i
+			}
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+		}
+		return append(words, text[start:])
+	}
+
+	matches, err := h.split.FindAllStringIndex(text, -1)
+	if err != nil || len(matches) == 0 {
+		return []string{byteLevelString(text)}
+	}
+	words := make([]string, 0, len(matches))
+	for _, m := range matches {
+		words = append(words, byteLevelString(text[m[0]:m[1]]))
+	}
+	return words
+}
+
+// countWord merges the symbols of word by rank and counts the result.
+// Symbols missing from the vocabulary count one token per byte when the
+// tokenizer has byte fallback.
+func (h *HFTokenizer) countWord(word string) int {
+	h.mu.Lock()
+	if n, ok := h.cache[word]; ok {
+		h.mu.Unlock()
+		return n
+	}
+	h.mu.Unlock()
+
+	symbols := make([]string, 0, len(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+	for len(symbols) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(symbols)-1; i++ {
+			if rank, ok := h.ranks[symbols[i]+"\x00"+symbols[i+1]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		symbols[best] += symbols[best+1]
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+	}
+
+	n := 0
+	for _, symbol := range symbols {
+		if _, ok := h.vocab[symbol]; !ok && h.byteFallback {
+			n += len(symbol)
+		} else {
+			n++
+		}
+	}
+
+	h.mu.Lock()
+	if len(h.cache) >= maxCachedWords {
+		h.cache = make(map[string]int)
+	}
+	h.cache[word] = n
+	h.mu.Unlock()
+	return n
+}
+
+// byteRunes maps every byte to the printable rune GPT-2 style byte-level
+// vocabularies use for it
+var byteRunes = func() [256]rune {
+	var table [256]rune
+	next := rune(256)
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			table[b] = rune(b)
+		} else {
+			table[b] = next
+			next++
+		}
+	}
+	return table
+}()
+
+// byteLevelString rewrites the bytes of s in the byte-level alphabet
+func byteLevelString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(byteRunes[s[i]])
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package tokens
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/tiktoken-go/tokenizer"
+)
+
+// Tokenizer counts the tokens a model would see for a piece of text
+type Tokenizer interface {
+	// Name identifies the tokenizer, e.g. o200k_base or llama.json
+	Name() string
+	Count(text string) int
+}
+
+// TokenizerDir is where downloaded Hugging Face tokenizer.json files are
+// looked up, relative to ~/.ch. A Llama model uses llama.json and a Mistral
+// model uses mistral.json.
+const TokenizerDir = "tokenizers"
+
+var (
+	hfMu     sync.Mutex
+	hfLoaded = make(map[string]Tokenizer)
+)
+
+// For returns the tokenizer for model. OpenAI models use their tiktoken
+// encoding, Llama and Mistral models use a downloaded tokenizer.json when one
+// exists and the bytes/4 heuristic otherwise, and other models are
+// approximated with cl100k_base.
+func For(model string) Tokenizer {
+	if family := modelFamily(model); family != "" {
+		if tok := loadFamilyTokenizer(family); tok != nil {
+			return tok
+		}
+		return Heuristic{}
+	}
+	return tiktokenFor(tiktokenEncoding(model))
+}
+
+// Count counts the tokens of text with the tokenizer for model
+func Count(model, text string) int {
+	return For(model).Count(text)
+}
+
+// modelFamily returns the tokenizer.json family of model, or "" when the
+// model has no downloadable tokenizer
+func modelFamily(model string) string {
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "llama"):
+		return "llama"
+	case strings.Contains(name, "mistral"), strings.Contains(name, "mixtral"),
+		strings.Contains(name, "codestral"), strings.Contains(name, "devstral"),
+		strings.Contains(name, "magistral"), strings.Contains(name, "pixtral"):
+		return "mistral"
+	}
+	return ""
+}
+
+// loadFamilyTokenizer loads ~/.ch/tokenizers/<family>.json once per process.
+// A missing or unreadable file leaves the family on the heuristic.
+func loadFamilyTokenizer(family string) Tokenizer {
+	hfMu.Lock()
+	defer hfMu.Unlock()
+
+	if tok, ok := hfLoaded[family]; ok {
+		return tok
+	}
+
+	var tok Tokenizer
+	if chDir, err := config.GetChDir(); err == nil {
+		if hf, err := LoadHFTokenizer(filepath.Join(chDir, TokenizerDir, family+".json")); err == nil {
+			tok = hf
+		}
+	}
+	hfLoaded[family] = tok
+	return tok
+}
+
+// tiktokenEncoding maps an OpenAI model name to its encoding. Models from
+// other providers use cl100k_base as an approximation.
+func tiktokenEncoding(model string) tokenizer.Encoding {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	switch {
+	case strings.Contains(name, "gpt-2"):
+		return tokenizer.R50kBase
+	case strings.Contains(name, "gpt-4o"), strings.Contains(name, "gpt-4.1"),
+		strings.Contains(name, "gpt-4.5"), strings.Contains(name, "gpt-5"),
+		strings.Contains(name, "gpt-oss"), strings.HasPrefix(name, "chatgpt"),
+		strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "o3"), strings.HasPrefix(name, "o4"):
+		return tokenizer.O200kBase
+	}
+	return tokenizer.Cl100kBase
+}
+
+// tiktoken counts tokens with a tiktoken encoding
+type tiktoken struct {
+	encoding tokenizer.Encoding
+	codec    tokenizer.Codec
+}
+
+func (t tiktoken) Name() string {
+	return string(t.encoding)
+}
+
+func (t tiktoken) Count(text string) int {
+	n, err := t.codec.Count(text)
+	if err != nil {
+		return Heuristic{}.Count(text)
+	}
+	return n
+}
+
+// tiktokenFor returns the tiktoken tokenizer for encoding, falling back to
+// the heuristic when the encoding cannot be loaded
+func tiktokenFor(encoding tokenizer.Encoding) Tokenizer {
+	codec, err := tokenizer.Get(encoding)
+	if err != nil {
+		return Heuristic{}
+	}
+	return tiktoken{encoding: encoding, codec: codec}
+}
+
+// Heuristic estimates one token per four bytes of text
+type Heuristic struct{}
+
+func (Heuristic) Name() string {
+	return "bytes/4 estimate"
+}
+
+func (Heuristic) Count(text string) int {
+	return (len(text) + 3) / 4
+}
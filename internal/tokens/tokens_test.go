@@ -0,0 +1,115 @@
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const sentencePieceJSON = `{
+	"normalizer": {"type": "Sequence", "normalizers": [{"type": "Prepend", "prepend": "▁"}, {"type": "Replace"}]},
+	"pre_tokenizer": null,
+	"model": {
+		"type": "BPE",
+		"byte_fallback": true,
+		"vocab": {"▁": 0, "h": 1, "e": 2, "l": 3, "o": 4, "▁h": 5, "▁he": 6, "ll": 7, "▁hell": 8, "▁hello": 9},
+		"merges": ["▁ h", "▁h e", "l l", "▁he ll", "▁hell o"]
+	}
+}`
+
+const byteLevelJSON = `{
+	"pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+		{"type": "Split", "pattern": {"Regex": " ?\\p{L}+|\\s+"}, "behavior": "Isolated"},
+		{"type": "ByteLevel", "use_regex": false}
+	]},
+	"model": {
+		"type": "BPE",
+		"vocab": {"h": 0, "i": 1, "o": 2, "Ġ": 3, "hi": 4, "Ġhi": 5},
+		"merges": [["h", "i"], ["Ġ", "hi"]]
+	}
+}`
+
+func writeTokenizer(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write tokenizer: %v", err)
+	}
+	return path
+}
+
+func TestHFTokenizerCounts(t *testing.T) {
+	dir := t.TempDir()
+	sentencePiece, err := LoadHFTokenizer(writeTokenizer(t, dir, "sp.json", sentencePieceJSON))
+	if err != nil {
+		t.Fatalf("LoadHFTokenizer(sentencepiece) error: %v", err)
+	}
+	byteLevel, err := LoadHFTokenizer(writeTokenizer(t, dir, "bl.json", byteLevelJSON))
+	if err != nil {
+		t.Fatalf("LoadHFTokenizer(byte-level) error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tok  *HFTokenizer
+		text string
+		want int
+	}{
+		{"merged words", sentencePiece, "hello hello", 2},
+		{"byte fallback", sentencePiece, "hé", 3}, // ▁h plus two bytes for é
+		{"empty", sentencePiece, "", 0},
+		{"byte-level merges", byteLevel, "hi hi", 2},
+		{"byte-level unmerged", byteLevel, "hi ho", 4}, // hi, Ġ, h, o
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tok.Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHFTokenizerRejectsNonBPE(t *testing.T) {
+	path := writeTokenizer(t, t.TempDir(), "wp.json", `{"model": {"type": "WordPiece", "vocab": {"a": 0}}}`)
+	if _, err := LoadHFTokenizer(path); err == nil {
+		t.Error("expected an error for a WordPiece tokenizer")
+	}
+}
+
+func TestForPicksTokenizerByModel(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tokenizerDir := filepath.Join(home, ".ch", TokenizerDir)
+	if err := os.MkdirAll(tokenizerDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTokenizer(t, tokenizerDir, "mistral.json", sentencePieceJSON)
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", "o200k_base"},
+		{"openai/gpt-5", "o200k_base"},
+		{"o3-mini", "o200k_base"},
+		{"gpt-4", "cl100k_base"},
+		{"claude-sonnet-4", "cl100k_base"},
+		{"gpt-2", "r50k_base"},
+		{"mistral-large-latest", "mistral.json"},
+		{"meta-llama/llama-3.3-70b-instruct", "bytes/4 estimate"},
+	}
+	for _, tt := range tests {
+		if got := For(tt.model).Name(); got != tt.want {
+			t.Errorf("For(%q).Name() = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestHeuristicRoundsUp(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "abc": 1, "abcd": 1, "abcde": 2} {
+		if got := (Heuristic{}).Count(text); got != want {
+			t.Errorf("Heuristic.Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
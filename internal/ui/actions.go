@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// speechCommands are text-to-speech programs that read text from stdin, in
+// order of preference
+var speechCommands = [][]string{
+	{"say"},                  // macOS
+	{"espeak-ng", "--stdin"}, // Linux
+	{"espeak", "--stdin"},
+	{"spd-say", "-e", "-w"},
+	{"termux-tts-speak"}, // Android/Termux
+}
+
+// speechFencePattern matches fenced code blocks, which are skipped when a
+// response is read aloud
+var speechFencePattern = regexp.MustCompile("(?s)```.*?(```|$)")
+
+// SpeechCommand returns the first installed text-to-speech program, or nil
+// when there is none
+func SpeechCommand() []string {
+	for _, command := range speechCommands {
+		if _, err := exec.LookPath(command[0]); err == nil {
+			return command
+		}
+	}
+	return nil
+}
+
+// Speak reads text aloud with the installed text-to-speech program, leaving
+// out code blocks and markdown markers
+func (t *Terminal) Speak(text string) error {
+	command := SpeechCommand()
+	if command == nil {
+		return fmt.Errorf("no text-to-speech program found. Please install: say (macOS), espeak-ng/espeak or spd-say (Linux), or termux-tts-speak (Android)")
+	}
+	cmd := exec.Command(command[0], command[1:]...) // #nosec G204 -- The program comes from the fixed speechCommands list.
+	cmd.Stdin = strings.NewReader(speakableText(text))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to speak: %v (stderr: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// speakableText replaces code blocks with a short note and drops markdown
+// emphasis and heading markers
+func speakableText(text string) string {
+	text = speechFencePattern.ReplaceAllString(text, "(code block)")
+	text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimLeft(line, "# ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// OpenURL opens url in the default browser
+func OpenURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return fmt.Errorf("no URL opener found. Please install xdg-open (xdg-utils)")
+		}
+		cmd = exec.Command("xdg-open", url) // #nosec G204 -- The URL is passed as a single argument, not through a shell.
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %v", url, err)
+	}
+	// The opener hands the URL to the browser and exits; reap it in the background
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
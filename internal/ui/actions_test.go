@@ -0,0 +1,23 @@
+package ui
+
+import "testing"
+
+func TestSpeakableText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Hello there.", "Hello there."},
+		{"markdown", "## Steps\n**Run** the `build` first", "Steps\nRun the build first"},
+		{"code block", "Try this:\n```go\nfmt.Println(1)\n```\nDone.", "Try this:\n(code block)\nDone."},
+		{"unclosed code block", "Start:\n```sh\nls -la", "Start:\n(code block)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := speakableText(tt.in); got != tt.want {
+				t.Errorf("speakableText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+)
+
+// ClipboardEntry is one item copied from ch
+type ClipboardEntry struct {
+	Time    int64  `json:"time"`
+	Content string `json:"content"`
+}
+
+// clipboardHistoryPath returns the path of the persistent clipboard history file
+func clipboardHistoryPath() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "clipboard_history.json"), nil
+}
+
+// LoadClipboardHistory reads copied items, newest first
+func LoadClipboardHistory() ([]ClipboardEntry, error) {
+	path, err := clipboardHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Clipboard history path is resolved under the current user's ~/.ch directory.
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clipboard history: %w", err)
+	}
+
+	var entries []ClipboardEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse clipboard history: %w", err)
+	}
+	return entries, nil
+}
+
+// saveClipboardHistory writes copied items, newest first
+func saveClipboardHistory(entries []ClipboardEntry) error {
+	path, err := clipboardHistoryPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode clipboard history: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ClearClipboardHistory removes every saved item
+func ClearClipboardHistory() error {
+	path, err := clipboardHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear clipboard history: %w", err)
+	}
+	return nil
+}
+
+// recordClipboard moves content to the top of the history, keeping at most
+// clipboard_history_size items. A negative size disables the history.
+func (t *Terminal) recordClipboard(content string) error {
+	limit := t.config.ClipboardHistorySize
+	if limit <= 0 || strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	entries, err := LoadClipboardHistory()
+	if err != nil {
+		entries = nil // start over rather than failing every copy on a corrupt file
+	}
+
+	updated := []ClipboardEntry{{Time: time.Now().Unix(), Content: content}}
+	for _, entry := range entries {
+		if entry.Content != content {
+			updated = append(updated, entry)
+		}
+	}
+	if len(updated) > limit {
+		updated = updated[:limit]
+	}
+	return saveClipboardHistory(updated)
+}
+
+// CopyFromClipboardHistory lets the user pick an earlier copied item with fzf
+// and copies it again
+func (t *Terminal) CopyFromClipboardHistory() error {
+	entries, err := LoadClipboardHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("clipboard history is empty")
+	}
+
+	items := make([]string, len(entries))
+	for i, entry := range entries {
+		items[i] = formatClipboardEntry(i, entry)
+	}
+
+	selected, err := t.FzfSelect(items, "copy again: ")
+	if err != nil || selected == "" {
+		return nil
+	}
+	for i, item := range items {
+		if item == selected {
+			if err := t.CopyToClipboard(entries[i].Content); err != nil {
+				return err
+			}
+			t.PrintInfo("copied to clipboard")
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to find selected item")
+}
+
+// formatClipboardEntry renders one history item as a single fzf line
+func formatClipboardEntry(index int, entry ClipboardEntry) string {
+	preview := strings.Join(strings.Fields(entry.Content), " ")
+	if len(preview) > 80 {
+		preview = preview[:80] + "..."
+	}
+	stamp := time.Unix(entry.Time, 0).Format("2006-01-02 15:04")
+	return fmt.Sprintf("%d) %s %s", index+1, stamp, preview)
+}
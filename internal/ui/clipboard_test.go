@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRecordClipboardKeepsNewestUniqueItems(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	terminal := NewTerminal(&types.Config{ClipboardHistorySize: 3})
+	for _, content := range []string{"one", "two", "three", "two", "four", "   "} {
+		if err := terminal.recordClipboard(content); err != nil {
+			t.Fatalf("recordClipboard(%q) error: %v", content, err)
+		}
+	}
+
+	entries, err := LoadClipboardHistory()
+	if err != nil {
+		t.Fatalf("LoadClipboardHistory() error: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Content)
+	}
+	if strings.Join(got, ",") != "four,two,three" {
+		t.Errorf("history = %v, want [four two three]", got)
+	}
+
+	if err := ClearClipboardHistory(); err != nil {
+		t.Fatalf("ClearClipboardHistory() error: %v", err)
+	}
+	if entries, _ := LoadClipboardHistory(); len(entries) != 0 {
+		t.Errorf("expected empty history after clear, got %v", entries)
+	}
+}
+
+func TestRecordClipboardDisabled(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	terminal := NewTerminal(&types.Config{ClipboardHistorySize: -1})
+	if err := terminal.recordClipboard("secret"); err != nil {
+		t.Fatalf("recordClipboard() error: %v", err)
+	}
+	if entries, _ := LoadClipboardHistory(); len(entries) != 0 {
+		t.Errorf("negative size should disable history, got %v", entries)
+	}
+}
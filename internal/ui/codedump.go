@@ -0,0 +1,218 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codedump output formats
+const (
+	CodeDumpText     = "text"
+	CodeDumpMarkdown = "markdown"
+)
+
+// CodeDump holds the files read for a codedump before it is rendered
+type CodeDump struct {
+	Dir   string
+	Total int    // files selected, including ones later skipped as binary
+	Since string // git ref or time the files changed since, for partial dumps
+	Files []CodeDumpFile
+}
+
+// CodeDumpFile is one file of a codedump. Error is set instead of Content
+// when the file could not be read.
+type CodeDumpFile struct {
+	Path      string
+	Content   string
+	Converted bool // Content was extracted from a document such as a PDF
+	Size      int64
+	SHA256    string
+	Error     string
+}
+
+// setChecksum records the size and SHA-256 of the file on disk
+func (f *CodeDumpFile) setChecksum(data []byte) {
+	sum := sha256.Sum256(data)
+	f.Size = int64(len(data))
+	f.SHA256 = hex.EncodeToString(sum[:])
+}
+
+// Render returns the dump in format, which is text or markdown
+func (d *CodeDump) Render(format string) (string, error) {
+	switch format {
+	case "", CodeDumpText:
+		return d.Text(), nil
+	case CodeDumpMarkdown, "md":
+		return d.Markdown(), nil
+	default:
+		return "", fmt.Errorf("unknown codedump format %q (use text or markdown)", format)
+	}
+}
+
+// Text renders the dump in the plain === FILE === layout
+func (d *CodeDump) Text() string {
+	var result strings.Builder
+
+	result.WriteString("=== Code Dump ===\n\n")
+	result.WriteString(fmt.Sprintf("generated from directory: %s\n", d.Dir))
+	if d.Since != "" {
+		result.WriteString(fmt.Sprintf("changed since: %s\n", d.Since))
+	}
+	result.WriteString(fmt.Sprintf("total files: %d\n\n", d.Total))
+
+	for _, file := range d.Files {
+		result.WriteString(fmt.Sprintf("=== FILE: %s ===\n", file.Path))
+		switch {
+		case file.Error != "":
+			result.WriteString(file.Error)
+		case file.Converted:
+			result.WriteString(file.Content)
+		default:
+			result.WriteString(fmt.Sprintf("File: %s\n%s", file.Path, file.Content))
+		}
+		result.WriteString("\n\n")
+	}
+
+	result.WriteString("=== END CODE DUMP ===")
+	return result.String()
+}
+
+// Markdown renders the dump with a heading and fenced block per file
+func (d *CodeDump) Markdown() string {
+	var result strings.Builder
+
+	result.WriteString("# Code Dump\n\n")
+	result.WriteString(fmt.Sprintf("- directory: `%s`\n", d.Dir))
+	if d.Since != "" {
+		result.WriteString(fmt.Sprintf("- changed since: `%s`\n", d.Since))
+	}
+	result.WriteString(fmt.Sprintf("- files: %d\n", len(d.Files)))
+
+	for _, file := range d.Files {
+		result.WriteString(fmt.Sprintf("\n## %s\n\n", file.Path))
+		if file.Error != "" {
+			result.WriteString(file.Error + "\n")
+			continue
+		}
+		fence := markdownFence(file.Content)
+		lang := ""
+		if !file.Converted {
+			lang = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Path)), ".")
+		}
+		result.WriteString(fence + lang + "\n")
+		result.WriteString(file.Content)
+		if !strings.HasSuffix(file.Content, "\n") {
+			result.WriteString("\n")
+		}
+		result.WriteString(fence + "\n")
+	}
+
+	return result.String()
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in
+// content, so files that contain fences themselves stay intact
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// changedFilesSince keeps the files (not directories) of a codedump listing
+// that changed since a git ref or time. Times may be a unix timestamp, a
+// date, RFC 3339, or an age such as 2h, 3d, or 1w; anything else is taken as
+// a git ref, and files differing from it plus untracked files are kept.
+func changedFilesSince(rootDir, since string, items []string) ([]string, error) {
+	var changed func(relPath string) bool
+	if cutoff, ok := ParseSinceTime(since, time.Now()); ok {
+		changed = func(relPath string) bool {
+			info, err := os.Stat(filepath.Join(rootDir, relPath))
+			return err == nil && info.ModTime().After(cutoff)
+		}
+	} else {
+		paths, err := gitChangedFiles(rootDir, since)
+		if err != nil {
+			return nil, err
+		}
+		changed = func(relPath string) bool { return paths[filepath.ToSlash(relPath)] }
+	}
+
+	var files []string
+	for _, item := range items {
+		if !strings.HasSuffix(item, "/") && changed(item) {
+			files = append(files, item)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files changed since %s", since)
+	}
+	return files, nil
+}
+
+// ParseSinceTime parses the time forms accepted by --since relative to now:
+// a duration (2h, 3d, 1w), an epoch, or a date
+func ParseSinceTime(since string, now time.Time) (time.Time, bool) {
+	if matches := regexp.MustCompile(`^(\d+)([dw])$`).FindStringSubmatch(since); matches != nil {
+		n, _ := strconv.Atoi(matches[1])
+		days := map[string]int{"d": 1, "w": 7}[matches[2]]
+		return now.AddDate(0, 0, -n*days), true
+	}
+	if age, err := time.ParseDuration(since); err == nil && age > 0 {
+		return now.Add(-age), true
+	}
+	if regexp.MustCompile(`^\d{9,}$`).MatchString(since) {
+		epoch, err := strconv.ParseInt(since, 10, 64)
+		if err == nil {
+			return time.Unix(epoch, 0), true
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// gitChangedFiles returns the paths under rootDir, relative to it, that
+// differ from ref in the working tree, plus untracked files
+func gitChangedFiles(rootDir, ref string) (map[string]bool, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid --since value %q", ref)
+	}
+	if err := exec.Command("git", "-C", rootDir, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil { // #nosec G204 -- ref is passed as a single argument and cannot start with "-".
+		return nil, fmt.Errorf("%q is not a git ref in %s or a time (try HEAD~3, main, 2h, 3d, or 2024-06-01)", ref, rootDir)
+	}
+
+	paths := map[string]bool{}
+	for _, args := range [][]string{
+		{"-C", rootDir, "diff", "--name-only", "--relative", ref, "--"},
+		{"-C", rootDir, "ls-files", "--others", "--exclude-standard"},
+	} {
+		output, err := exec.Command("git", args...).Output() // #nosec G204 -- Fixed git subcommands with a validated ref.
+		if err != nil {
+			return nil, fmt.Errorf("git %s failed: %v", args[2], err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				paths[line] = true
+			}
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestCollectCodeDumpRendersTextAndMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("use ```go fences```"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	terminal := NewTerminal(&types.Config{})
+	dump := terminal.collectCodeDump([]string{"main.go", "README.md", "missing.txt"}, dir)
+	if len(dump.Files) != 3 || dump.Files[2].Error == "" {
+		t.Fatalf("unexpected files: %+v", dump.Files)
+	}
+	if dump.Files[0].Size != 13 || len(dump.Files[0].SHA256) != 64 {
+		t.Errorf("size and checksum not recorded: %+v", dump.Files[0])
+	}
+
+	text := dump.Text()
+	if !strings.Contains(text, "=== FILE: main.go ===\nFile: main.go\npackage main\n") || !strings.HasSuffix(text, "=== END CODE DUMP ===") {
+		t.Errorf("text layout changed:\n%s", text)
+	}
+
+	markdown, err := dump.Render("md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown, "## main.go\n\n```go\npackage main\n```\n") {
+		t.Errorf("missing fenced go section:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "````md\nuse ```go fences```\n````\n") {
+		t.Errorf("fence should be longer than backticks in the file:\n%s", markdown)
+	}
+
+	if _, err := dump.Render("html"); err == nil {
+		t.Error("unknown formats should be rejected")
+	}
+}
+
+func TestParseSinceTime(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	tests := map[string]time.Time{
+		"2h":         now.Add(-2 * time.Hour),
+		"3d":         now.AddDate(0, 0, -3),
+		"1w":         now.AddDate(0, 0, -7),
+		"1717000000": time.Unix(1717000000, 0),
+		"2024-06-01": time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local),
+	}
+	for since, want := range tests {
+		if got, ok := ParseSinceTime(since, now); !ok || !got.Equal(want) {
+			t.Errorf("ParseSinceTime(%q) = %v, %v, want %v", since, got, ok, want)
+		}
+	}
+	for _, ref := range []string{"HEAD~3", "main", "v1.2.0", "abc1234"} {
+		if _, ok := ParseSinceTime(ref, now); ok {
+			t.Errorf("%q should be treated as a git ref", ref)
+		}
+	}
+}
+
+func TestChangedFilesSinceGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("a.go", "package a\n")
+	write("pkg/b.go", "package b\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	write("pkg/b.go", "package b // changed\n")
+	write("new.go", "package a\n")
+
+	items := []string{"pkg/", "a.go", "new.go", "pkg/b.go"}
+	got, err := changedFilesSince(dir, "HEAD", items)
+	if err != nil {
+		t.Fatalf("changedFilesSince() error: %v", err)
+	}
+	if strings.Join(got, ",") != "new.go,pkg/b.go" {
+		t.Errorf("changed files = %v", got)
+	}
+
+	if _, err := changedFilesSince(dir, "no-such-ref", items); err == nil || !strings.Contains(err.Error(), "not a git ref") {
+		t.Errorf("expected a bad ref error, got %v", err)
+	}
+	if _, err := changedFilesSince(dir, "--output=x", items); err == nil {
+		t.Error("refs that look like options should be rejected")
+	}
+}
@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+)
+
+// scrapeCookie is a single cookie loaded from a cookie file or browser profile
+type scrapeCookie struct {
+	Domain            string
+	IncludeSubdomains bool
+	Path              string
+	Secure            bool
+	Expires           int64 // Unix seconds, 0 for session cookies
+	Name              string
+	Value             string
+}
+
+// matches reports whether the cookie should be sent with a request to the given URL parts
+func (c scrapeCookie) matches(host, path string, secure bool, now time.Time) bool {
+	if c.Expires != 0 && c.Expires < now.Unix() {
+		return false
+	}
+	if c.Secure && !secure {
+		return false
+	}
+
+	host = strings.ToLower(host)
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	if host != domain && !(c.IncludeSubdomains && strings.HasSuffix(host, "."+domain)) {
+		return false
+	}
+
+	cookiePath := c.Path
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path == cookiePath || strings.HasPrefix(path, strings.TrimSuffix(cookiePath, "/")+"/")
+}
+
+// parseNetscapeCookies parses cookies in the Netscape cookies.txt format used by
+// curl, wget, yt-dlp, and browser export extensions.
+func parseNetscapeCookies(r io.Reader) ([]scrapeCookie, error) {
+	var cookies []scrapeCookie
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// "#HttpOnly_" prefixed lines are cookies, every other "#" line is a comment
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+
+		expires, _ := strconv.ParseInt(fields[4], 10, 64)
+		cookies = append(cookies, scrapeCookie{
+			Domain:            fields[0],
+			IncludeSubdomains: strings.EqualFold(fields[1], "TRUE"),
+			Path:              fields[2],
+			Secure:            strings.EqualFold(fields[3], "TRUE"),
+			Expires:           expires,
+			Name:              fields[5],
+			Value:             strings.Join(fields[6:], "\t"),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	return cookies, nil
+}
+
+// loadFirefoxCookies reads cookies from a Firefox cookies.sqlite database with
+// the in-process SQLite driver. The database is copied first because Firefox
+// keeps it locked, together with its -wal and -shm files, which hold recently
+// written cookies such as a fresh login until Firefox checkpoints them.
+func loadFirefoxCookies(dbPath string) ([]scrapeCookie, error) {
+	tempRoot, err := config.GetTempDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get temp directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(tempRoot, "ch_cookies_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	copyPath := filepath.Join(tempDir, "cookies.sqlite")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		data, err := os.ReadFile(dbPath + suffix) // #nosec G304 -- Cookie database path comes from the user's config or their browser profile.
+		if err != nil {
+			if suffix != "" && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read cookie database: %w", err)
+		}
+		if err := os.WriteFile(copyPath+suffix, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to copy cookie database: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+(&url.URL{Path: copyPath}).EscapedPath()+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie database: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT host, path, isSecure, expiry, name, value FROM moz_cookies")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cookie database: %w", err)
+	}
+	defer rows.Close()
+
+	var cookies []scrapeCookie
+	for rows.Next() {
+		var cookie scrapeCookie
+		if err := rows.Scan(&cookie.Domain, &cookie.Path, &cookie.Secure, &cookie.Expires, &cookie.Name, &cookie.Value); err != nil {
+			return nil, fmt.Errorf("failed to read cookie database: %w", err)
+		}
+		// Firefox marks domain cookies, which subdomains also get, with a leading dot
+		cookie.IncludeSubdomains = strings.HasPrefix(cookie.Domain, ".")
+		cookies = append(cookies, cookie)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie database: %w", err)
+	}
+	return cookies, nil
+}
+
+// findFirefoxCookieDB returns the most recently used Firefox cookies.sqlite.
+// A path to a profile directory or cookies.sqlite file is used as-is.
+func findFirefoxCookieDB(location string) (string, error) {
+	if location != "" && location != "firefox" {
+		info, err := os.Stat(location)
+		if err != nil {
+			return "", fmt.Errorf("cookie browser profile not found: %s", location)
+		}
+		if info.IsDir() {
+			return filepath.Join(location, "cookies.sqlite"), nil
+		}
+		return location, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var patterns []string
+	if runtime.GOOS == "darwin" {
+		patterns = append(patterns, filepath.Join(homeDir, "Library", "Application Support", "Firefox", "Profiles", "*", "cookies.sqlite"))
+	} else {
+		patterns = append(patterns,
+			filepath.Join(homeDir, ".mozilla", "firefox", "*", "cookies.sqlite"),
+			filepath.Join(homeDir, "snap", "firefox", "common", ".mozilla", "firefox", "*", "cookies.sqlite"),
+		)
+	}
+
+	newest := ""
+	var newestTime time.Time
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			if newest == "" || info.ModTime().After(newestTime) {
+				newest = match
+				newestTime = info.ModTime()
+			}
+		}
+	}
+
+	if newest == "" {
+		return "", fmt.Errorf("no Firefox profile with cookies found")
+	}
+	return newest, nil
+}
+
+// loadScrapeCookies loads cookies from scrape_cookie_file and scrape_cookie_browser once
+// per session. Load errors are reported once and scraping continues without them.
+// Concurrent scrapes share the same load.
+func (t *Terminal) loadScrapeCookies() []scrapeCookie {
+	t.cookiesOnce.Do(t.readScrapeCookies)
+	return t.cookies
+}
+
+// readScrapeCookies reads the configured cookie sources into t.cookies
+func (t *Terminal) readScrapeCookies() {
+	if cookieFile := expandHomePath(t.config.ScrapeCookieFile); cookieFile != "" {
+		file, err := os.Open(cookieFile) // #nosec G304 -- Cookie file path is explicitly configured by the user.
+		if err != nil {
+			t.PrintError(fmt.Sprintf("warning: failed to open cookie file: %v", err))
+		} else {
+			cookies, err := parseNetscapeCookies(file)
+			_ = file.Close()
+			if err != nil {
+				t.PrintError(fmt.Sprintf("warning: %v", err))
+			}
+			t.cookies = append(t.cookies, cookies...)
+		}
+	}
+
+	if browser := t.config.ScrapeCookieBrowser; browser != "" {
+		dbPath, err := findFirefoxCookieDB(expandHomePath(browser))
+		if err == nil {
+			var cookies []scrapeCookie
+			cookies, err = loadFirefoxCookies(dbPath)
+			t.cookies = append(t.cookies, cookies...)
+		}
+		if err != nil {
+			t.PrintError(fmt.Sprintf("warning: failed to load browser cookies: %v", err))
+		}
+	}
+}
+
+// applyScrapeCookies adds every loaded cookie that matches the request URL
+func (t *Terminal) applyScrapeCookies(req *http.Request) {
+	cookies := t.loadScrapeCookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	now := time.Now()
+	secure := req.URL.Scheme == "https"
+	for _, cookie := range cookies {
+		if cookie.matches(req.URL.Hostname(), req.URL.Path, secure, now) {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+}
+
+// expandHomePath expands a leading ~ to the user's home directory
+func expandHomePath(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[1:])
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+const testCookieFile = `# Netscape HTTP Cookie File
+# This is a generated file! Do not edit.
+
+.example.com	TRUE	/	TRUE	0	session	abc123
+#HttpOnly_wiki.internal	FALSE	/docs	FALSE	4102444800	auth	token=xyz
+old.example.com	FALSE	/	FALSE	1	expired	gone
+malformed line without tabs
+`
+
+func TestParseNetscapeCookies(t *testing.T) {
+	cookies, err := parseNetscapeCookies(strings.NewReader(testCookieFile))
+	if err != nil {
+		t.Fatalf("parseNetscapeCookies returned error: %v", err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("expected 3 cookies, got %d: %+v", len(cookies), cookies)
+	}
+
+	first := cookies[0]
+	if first.Domain != ".example.com" || !first.IncludeSubdomains || !first.Secure || first.Name != "session" || first.Value != "abc123" {
+		t.Errorf("unexpected first cookie: %+v", first)
+	}
+
+	httpOnly := cookies[1]
+	if httpOnly.Domain != "wiki.internal" || httpOnly.Path != "/docs" || httpOnly.Value != "token=xyz" {
+		t.Errorf("HttpOnly cookie not parsed correctly: %+v", httpOnly)
+	}
+}
+
+func TestScrapeCookieMatches(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cookie := scrapeCookie{Domain: ".example.com", IncludeSubdomains: true, Path: "/app", Secure: true}
+
+	tests := []struct {
+		host   string
+		path   string
+		secure bool
+		want   bool
+	}{
+		{"docs.example.com", "/app/page", true, true},
+		{"example.com", "/app", true, true},
+		{"example.com", "/application", true, false},
+		{"example.com", "/app", false, false},
+		{"notexample.com", "/app", true, false},
+	}
+	for _, tt := range tests {
+		if got := cookie.matches(tt.host, tt.path, tt.secure, now); got != tt.want {
+			t.Errorf("matches(%q, %q, %v) = %v, want %v", tt.host, tt.path, tt.secure, got, tt.want)
+		}
+	}
+
+	expired := scrapeCookie{Domain: "example.com", Path: "/", Expires: now.Unix() - 1}
+	if expired.matches("example.com", "/", true, now) {
+		t.Error("expired cookie should not match")
+	}
+}
+
+func TestApplyScrapeCookies(t *testing.T) {
+	cookiePath := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cookiePath, []byte(testCookieFile), 0600); err != nil {
+		t.Fatalf("failed to write cookie file: %v", err)
+	}
+
+	terminal := NewTerminal(&types.Config{IsPipedOutput: true, ScrapeCookieFile: cookiePath})
+	req, err := http.NewRequest("GET", "https://www.example.com/page", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	terminal.applyScrapeCookies(req)
+
+	if got := req.Header.Get("Cookie"); got != "session=abc123" {
+		t.Errorf("Cookie header = %q, want %q", got, "session=abc123")
+	}
+}
+
+func TestLoadFirefoxCookiesReadsWAL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dbPath := filepath.Join(t.TempDir(), "cookies.sqlite")
+
+	// Like a running Firefox, keep the database open with the newest cookie
+	// still in the -wal file
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=journal_mode(WAL)&_pragma=wal_autocheckpoint(0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, stmt := range []string{
+		"CREATE TABLE moz_cookies (id INTEGER PRIMARY KEY, name TEXT, value TEXT, host TEXT, path TEXT, expiry INTEGER, isSecure INTEGER)",
+		"INSERT INTO moz_cookies (name, value, host, path, expiry, isSecure) VALUES ('session', 'fresh-login', '.example.com', '/', 4102444800, 1)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := os.Stat(dbPath + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("test setup left nothing in the WAL: %v", err)
+	}
+
+	cookies, err := loadFirefoxCookies(dbPath)
+	if err != nil {
+		t.Fatalf("loadFirefoxCookies() error: %v", err)
+	}
+	want := scrapeCookie{Domain: ".example.com", IncludeSubdomains: true, Path: "/", Secure: true, Expires: 4102444800, Name: "session", Value: "fresh-login"}
+	if len(cookies) != 1 || cookies[0] != want {
+		t.Errorf("loadFirefoxCookies() = %+v, want [%+v]", cookies, want)
+	}
+}
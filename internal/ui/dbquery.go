@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "modernc.org/sqlite" // pure-Go driver, also used for session storage
+)
+
+// dbQueryMaxRows caps how many result rows !db adds to context
+const dbQueryMaxRows = 100
+
+// dbQueryMaxCell caps the length of one result value
+const dbQueryMaxCell = 200
+
+// dbQueryTimeout bounds how long one !db query may run
+const dbQueryTimeout = 30 * time.Second
+
+// ParseDBQuery splits a !db argument into the database and the SQL after it
+func ParseDBQuery(arg string) (string, string, error) {
+	database, query, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	query = strings.TrimSpace(query)
+	if database == "" || query == "" {
+		return "", "", fmt.Errorf("expected a database path followed by a query")
+	}
+	return database, query, nil
+}
+
+// sqliteReadOnlyDSN returns the DSN that opens a SQLite file read-only.
+// Server databases are rejected, since only the SQLite driver is built in.
+func sqliteReadOnlyDSN(database string) (string, error) {
+	if scheme, _, ok := strings.Cut(database, "://"); ok {
+		return "", fmt.Errorf("%s databases are not supported, only SQLite files", scheme)
+	}
+	path := strings.TrimPrefix(database, "file:")
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a file", database)
+	}
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro&_pragma=query_only(1)&_pragma=busy_timeout(5000)", nil
+}
+
+// QueryDatabase runs a read-only query against a SQLite file and returns the
+// result as a markdown table of at most dbQueryMaxRows rows. The database is
+// opened read-only with query_only set, so statements that write fail.
+func (t *Terminal) QueryDatabase(database, query string) (string, error) {
+	dsn, err := sqliteReadOnlyDSN(database)
+	if err != nil {
+		return "", err
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbQueryTimeout)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var records [][]string
+	more := false
+	for rows.Next() {
+		if len(records) == dbQueryMaxRows {
+			more = true
+			break
+		}
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = formatDBValue(value)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Database: %s\nQuery: %s\n\n", database, query))
+	if len(columns) == 0 {
+		sb.WriteString("(the query returned no columns)\n")
+		return sb.String(), nil
+	}
+	// Pipes are escaped and newlines flattened so every row stays on one line
+	escape := strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = escape.Replace(column)
+	}
+	table := [][]string{header}
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = escape.Replace(cell)
+		}
+		table = append(table, record)
+	}
+	sb.WriteString(markdownTable(table) + "\n")
+	switch {
+	case more:
+		sb.WriteString(fmt.Sprintf("\n(showing the first %d rows, more were returned)\n", dbQueryMaxRows))
+	case len(records) == 0:
+		sb.WriteString("\n(no rows)\n")
+	case len(records) == 1:
+		sb.WriteString("\n(1 row)\n")
+	default:
+		sb.WriteString(fmt.Sprintf("\n(%d rows)\n", len(records)))
+	}
+	return sb.String(), nil
+}
+
+// formatDBValue renders one result value for a table cell
+func formatDBValue(value any) string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if !utf8.Valid(v) {
+			return fmt.Sprintf("<%d bytes>", len(v))
+		}
+		text = string(v)
+	case time.Time:
+		text = v.Format(time.RFC3339)
+	default:
+		text = fmt.Sprint(v)
+	}
+	if utf8.RuneCountInString(text) > dbQueryMaxCell {
+		text = string([]rune(text)[:dbQueryMaxCell]) + "..."
+	}
+	return text
+}
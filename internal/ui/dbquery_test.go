@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func newTestDatabase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shop data.db")
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	statements := []string{
+		`CREATE TABLE orders (id INTEGER, customer TEXT, total REAL, note TEXT, blob BLOB)`,
+		`INSERT INTO orders VALUES (1, 'ann', 9.5, 'a|b', x'00ff'), (2, 'bob', 20, NULL, NULL)`,
+	}
+	for i := 3; i <= dbQueryMaxRows+5; i++ {
+		statements = append(statements, fmt.Sprintf(`INSERT INTO orders VALUES (%d, 'c%d', 1, 'line1
+line2', NULL)`, i, i))
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestQueryDatabase(t *testing.T) {
+	path := newTestDatabase(t)
+	terminal := NewTerminal(&types.Config{})
+
+	content, err := terminal.QueryDatabase(path, "SELECT id, customer, total, note, blob FROM orders WHERE id <= 2 ORDER BY id")
+	if err != nil {
+		t.Fatalf("QueryDatabase() error: %v", err)
+	}
+	want := "| id | customer | total | note | blob |\n| --- | --- | --- | --- | --- |\n| 1 | ann | 9.5 | a\\|b | <2 bytes> |\n| 2 | bob | 20 | NULL | NULL |\n\n(2 rows)\n"
+	if !strings.HasSuffix(content, want) || !strings.HasPrefix(content, "Database: "+path+"\nQuery: SELECT") {
+		t.Errorf("unexpected result:\n%s", content)
+	}
+
+	content, err = terminal.QueryDatabase(path, "SELECT id, note FROM orders WHERE id > 2")
+	if err != nil {
+		t.Fatalf("QueryDatabase() error: %v", err)
+	}
+	if !strings.Contains(content, "| 3 | line1 line2 |") || !strings.Contains(content, fmt.Sprintf("(showing the first %d rows", dbQueryMaxRows)) {
+		t.Errorf("rows were not flattened and capped:\n%s", content)
+	}
+	if strings.Count(content, "\n| ") != dbQueryMaxRows+2 {
+		t.Errorf("got %d table lines, want %d", strings.Count(content, "\n| "), dbQueryMaxRows+2)
+	}
+}
+
+func TestQueryDatabaseIsReadOnly(t *testing.T) {
+	path := newTestDatabase(t)
+	terminal := NewTerminal(&types.Config{})
+
+	for _, query := range []string{"DELETE FROM orders", "DROP TABLE orders", "CREATE TABLE x (y)"} {
+		if _, err := terminal.QueryDatabase(path, query); err == nil {
+			t.Errorf("QueryDatabase(%q) should fail on a read-only database", query)
+		}
+	}
+	content, err := terminal.QueryDatabase(path, "SELECT count(*) AS n FROM orders")
+	if err != nil || !strings.Contains(content, fmt.Sprintf("| %d |", dbQueryMaxRows+5)) {
+		t.Errorf("rows changed after write attempts: %v\n%s", err, content)
+	}
+
+	for _, database := range []string{"postgres://localhost/db", filepath.Join(t.TempDir(), "missing.db")} {
+		if _, err := terminal.QueryDatabase(database, "SELECT 1"); err == nil {
+			t.Errorf("QueryDatabase(%q) should fail", database)
+		}
+	}
+}
+
+func TestParseDBQuery(t *testing.T) {
+	database, query, err := ParseDBQuery("  app.db   SELECT * FROM t WHERE a = 'x y' ")
+	if err != nil || database != "app.db" || query != "SELECT * FROM t WHERE a = 'x y'" {
+		t.Errorf("ParseDBQuery() = %q, %q, %v", database, query, err)
+	}
+	for _, arg := range []string{"", "app.db", "  app.db  "} {
+		if _, _, err := ParseDBQuery(arg); err == nil {
+			t.Errorf("ParseDBQuery(%q) should fail", arg)
+		}
+	}
+}
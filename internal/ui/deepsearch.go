@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+)
+
+// deepSearchInstructions tell the model how to answer from the scraped
+// sources and cite them
+const deepSearchInstructions = "Answer the question using the numbered web sources below. " +
+	"Cite the sources you use inline as [1], [2], ... matching their numbers. " +
+	"If the sources do not cover part of the question, say so instead of guessing."
+
+// DeepSearch searches the web, scrapes the top deep_search_results pages and
+// returns their content, cut to the deep_search_tokens budget, as numbered
+// sources with citation instructions for the model
+func (t *Terminal) DeepSearch(query string) (string, error) {
+	if t.usesBrave() && os.Getenv("BRAVE_API_KEY") == "" && t.config.SearchFallback == "" {
+		return "", fmt.Errorf("the BRAVE_API_KEY environment variable is not set")
+	}
+
+	done := make(chan bool)
+	go t.ShowLoadingAnimation("Searching...", done)
+	results, warnings, err := t.searchWithFallback(query)
+	done <- true
+
+	for _, warning := range warnings {
+		t.PrintError(warning)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sources := deepSearchSources(results, t.config.DeepSearchResults)
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no search results found for: %s", query)
+	}
+	if t.config.ShowSearchResults {
+		fmt.Print(t.formatBraveSearchResults(sources, query))
+	}
+
+	urls := make([]string, len(sources))
+	for i, source := range sources {
+		urls[i] = source.URL
+	}
+	contents, errs := t.scrapeEach(urls, t.config.ScrapeFormat)
+
+	budget := t.config.DeepSearchTokens
+	if budget <= 0 {
+		budget = 6000
+	}
+	return t.formatDeepSearch(query, sources, contents, errs, budget/len(sources)), nil
+}
+
+// deepSearchSources picks the first limit results with distinct http(s)
+// URLs, 3 when limit is unset
+func deepSearchSources(results []BraveWebResult, limit int) []BraveWebResult {
+	if limit <= 0 {
+		limit = 3
+	}
+	seen := make(map[string]bool)
+	var sources []BraveWebResult
+	for _, result := range results {
+		if len(sources) == limit {
+			break
+		}
+		if !strings.HasPrefix(result.URL, "http://") && !strings.HasPrefix(result.URL, "https://") {
+			continue
+		}
+		if seen[result.URL] {
+			continue
+		}
+		seen[result.URL] = true
+		sources = append(sources, result)
+	}
+	return sources
+}
+
+// formatDeepSearch numbers the sources for citation, giving each page at
+// most perPage tokens. Pages that could not be scraped fall back to their
+// search snippet.
+func (t *Terminal) formatDeepSearch(query string, sources []BraveWebResult, contents []string, errs []error, perPage int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nQuestion: %s\n\n", deepSearchInstructions, query)
+	for i, source := range sources {
+		fmt.Fprintf(&b, "[%d] %s\n", i+1, source.Title)
+		if errs[i] != nil {
+			fmt.Fprintf(&b, "URL: %s\n(page could not be read: %v)\n", source.URL, errs[i])
+			if source.Description != "" {
+				snippet := t.guardUntrustedContent(fmt.Sprintf("search results for '%s'", query), source.Description, nil)
+				fmt.Fprintf(&b, "Search snippet: %s\n", snippet)
+			}
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(truncateTokens(t.config.CurrentModel, strings.TrimSpace(contents[i]), perPage))
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// truncateTokens cuts text to at most budget tokens for model, marking
+// where it was cut
+func truncateTokens(model, text string, budget int) string {
+	if tokens.Count(model, text) <= budget {
+		return text
+	}
+	const marker = "\n[... truncated]"
+	runes := []rune(text)
+	// Binary search for the longest prefix that fits with the marker
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if tokens.Count(model, string(runes[:mid])+marker) <= budget {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return strings.TrimRight(string(runes[:low]), " \t\n") + marker
+}
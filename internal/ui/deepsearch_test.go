@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/tokens"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestTruncateTokens(t *testing.T) {
+	text := strings.Repeat("word ", 500)
+	if got := truncateTokens("gpt-4o", "short text", 100); got != "short text" {
+		t.Errorf("truncateTokens(short) = %q", got)
+	}
+	got := truncateTokens("gpt-4o", text, 50)
+	if !strings.HasSuffix(got, "[... truncated]") {
+		t.Errorf("truncated text has no marker: %q", got)
+	}
+	if n := tokens.Count("gpt-4o", got); n > 50 {
+		t.Errorf("truncated text is %d tokens, want at most 50", n)
+	}
+	if n := tokens.Count("gpt-4o", got); n < 40 {
+		t.Errorf("truncated text is %d tokens, want close to 50", n)
+	}
+}
+
+func TestDeepSearchSources(t *testing.T) {
+	results := []BraveWebResult{
+		{Title: "a", URL: "https://a.example/"},
+		{Title: "no url"},
+		{Title: "ftp", URL: "ftp://b.example/"},
+		{Title: "a again", URL: "https://a.example/"},
+		{Title: "c", URL: "http://c.example/"},
+		{Title: "d", URL: "https://d.example/"},
+	}
+	got := deepSearchSources(results, 2)
+	if len(got) != 2 || got[0].Title != "a" || got[1].Title != "c" {
+		t.Errorf("deepSearchSources(2) = %+v", got)
+	}
+	if got := deepSearchSources(results, 0); len(got) != 3 {
+		t.Errorf("deepSearchSources(0) picked %d sources, want the default 3", len(got))
+	}
+}
+
+func TestDeepSearch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><p>Go 1.23 adds range over func. %s</p></body></html>", strings.Repeat("filler ", 2000))
+	}))
+	defer server.Close()
+
+	original := searchBackends["duckduckgo"]
+	defer func() { searchBackends["duckduckgo"] = original }()
+	searchBackends["duckduckgo"] = func(t *Terminal, query string) ([]BraveWebResult, error) {
+		return []BraveWebResult{
+			{Title: "Release notes", URL: server.URL + "/notes", Description: "notes snippet"},
+			{Title: "Gone", URL: server.URL + "/missing", Description: "gone snippet"},
+			{Title: "Extra", URL: server.URL + "/extra"},
+		}, nil
+	}
+
+	terminal := NewTerminal(&types.Config{SearchBackend: "duckduckgo", DeepSearchResults: 2, DeepSearchTokens: 400, ScrapeFormat: "text", CurrentModel: "gpt-4o"})
+	content, err := terminal.DeepSearch("go 1.23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{deepSearchInstructions, "Question: go 1.23", "[1] Release notes", "range over func", "[... truncated]", "[2] Gone", "page could not be read", "Search snippet: gone snippet"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("deep search content is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "Extra") {
+		t.Errorf("deep search read more than deep_search_results pages:\n%s", content)
+	}
+	if n := tokens.Count("gpt-4o", content); n > 500 {
+		t.Errorf("deep search content is %d tokens, want about the 400 token budget", n)
+	}
+}
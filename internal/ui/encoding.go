@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// Encoding names reported by decodeText
+const (
+	encodingUTF16LE  = "UTF-16LE"
+	encodingUTF16BE  = "UTF-16BE"
+	encodingShiftJIS = "Shift-JIS"
+	encodingLatin1   = "Latin-1"
+)
+
+// decodeText converts file content to UTF-8. It returns the text, the
+// encoding it was converted from (empty for UTF-8), and false when the
+// content is not text in any supported encoding. UTF-16 is recognized by its
+// byte order mark or by its zero bytes, Shift-JIS when it decodes cleanly
+// into Japanese text, and anything else that is not UTF-8 is read as Latin-1
+// (Windows-1252, which also covers ISO-8859-1 text).
+func (t *Terminal) decodeText(data []byte) (string, string, bool) {
+	if rest, ok := bytes.CutPrefix(data, []byte("\xef\xbb\xbf")); ok {
+		data = rest
+	}
+	if name, ok := utf16Encoding(data); ok {
+		var enc encoding.Encoding = xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM)
+		if name == encodingUTF16BE {
+			enc = xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM)
+		}
+		if text, err := enc.NewDecoder().Bytes(data); err == nil && t.isTextFile(text) {
+			return string(text), name, true
+		}
+		return "", "", false
+	}
+
+	if !t.isTextFile(data) {
+		return "", "", false
+	}
+	if utf8.Valid(data) {
+		return string(data), "", true
+	}
+	if text, ok := decodeShiftJIS(data); ok {
+		return text, encodingShiftJIS, true
+	}
+	text, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", false
+	}
+	return string(text), encodingLatin1, true
+}
+
+// utf16Encoding reports whether data is UTF-16 and its byte order: from the
+// byte order mark, or from ASCII-range text leaving every other byte zero
+func utf16Encoding(data []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return encodingUTF16LE, true
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return encodingUTF16BE, true
+	case len(data) < 4 || len(data)%2 != 0:
+		return "", false
+	}
+
+	evenZeros, oddZeros := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(data) / 2
+	switch {
+	case oddZeros*10 >= pairs*7 && evenZeros*10 < pairs:
+		return encodingUTF16LE, true
+	case evenZeros*10 >= pairs*7 && oddZeros*10 < pairs:
+		return encodingUTF16BE, true
+	}
+	return "", false
+}
+
+// decodeShiftJIS decodes data as Shift-JIS when the result reads as
+// Japanese: no invalid sequences, some kana, and mostly Japanese characters
+// outside ASCII. Latin-1 text rarely decodes to kana, which keeps accented
+// Western text from being taken for Shift-JIS.
+func decodeShiftJIS(data []byte) (string, bool) {
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", false
+	}
+	text := string(decoded)
+	kana, japaneseRunes, other := 0, 0, 0
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+		case r == utf8.RuneError:
+			return "", false
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+			japaneseRunes++
+		case unicode.Is(unicode.Han, r), r >= 0x3000 && r <= 0x303f, r >= 0xff00 && r <= 0xffef:
+			japaneseRunes++
+		default:
+			other++
+		}
+	}
+	return text, kana > 0 && japaneseRunes > other*4
+}
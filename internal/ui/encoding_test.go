@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestDecodeText(t *testing.T) {
+	terminal := NewTerminal(&types.Config{})
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+		ok       bool
+	}{
+		{"utf-8", []byte("naïve café\n"), "naïve café\n", "", true},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhello"), "hello", "", true},
+		{"utf-16le bom", []byte("\xff\xfeh\x00i\x00\xe9\x00"), "hié", encodingUTF16LE, true},
+		{"utf-16be bom", []byte("\xfe\xff\x00h\x00i"), "hi", encodingUTF16BE, true},
+		{"utf-16le without bom", []byte("l\x00o\x00g\x00\n\x00"), "log\n", encodingUTF16LE, true},
+		{"latin-1", []byte("caf\xe9 cr\xe8me, \xa9 2024"), "café crème, © 2024", encodingLatin1, true},
+		{"windows-1252 quotes", []byte("\x93quoted\x94"), "“quoted”", encodingLatin1, true},
+		// "こんにちは、世界" (hello, world)
+		{"shift-jis", []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x81\x41\x90\xa2\x8a\x45"), "こんにちは、世界", encodingShiftJIS, true},
+		{"binary", []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x03\x00>\x00"), "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding, ok := terminal.decodeText(tt.data)
+			if ok != tt.ok || got != tt.want || encoding != tt.encoding {
+				t.Errorf("decodeText() = %q, %q, %v; want %q, %q, %v", got, encoding, ok, tt.want, tt.encoding, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLoadTextFileConvertsEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("\xff\xfer\x00\xe9\x00s\x00u\x00m\x00\xe9\x00"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content, err := NewTerminal(&types.Config{}).loadTextFile(path)
+	if err != nil {
+		t.Fatalf("loadTextFile() error: %v", err)
+	}
+	if !strings.Contains(content, "résumé") {
+		t.Errorf("loadTextFile() = %q, want the UTF-16 text as UTF-8", content)
+	}
+}
@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileLanguages maps extensions to the code fence tag of their language
+var fileLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".mjs": "javascript", ".cjs": "javascript",
+	".ts": "typescript", ".jsx": "jsx", ".tsx": "tsx", ".java": "java", ".kt": "kotlin",
+	".scala": "scala", ".rb": "ruby", ".php": "php", ".pl": "perl", ".pm": "perl",
+	".r": "r", ".lua": "lua", ".rs": "rust", ".swift": "swift", ".m": "objectivec",
+	".mm": "objectivec", ".cs": "csharp", ".vb": "vbnet", ".fs": "fsharp", ".clj": "clojure",
+	".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".cxx": "cpp", ".hpp": "cpp",
+	".hs": "haskell", ".elm": "elm", ".ex": "elixir", ".exs": "elixir", ".erl": "erlang",
+	".hrl": "erlang", ".dart": "dart", ".sql": "sql", ".vim": "vim",
+	".sh": "bash", ".bash": "bash", ".zsh": "zsh", ".fish": "fish", ".ps1": "powershell",
+	".bat": "batch", ".cmd": "batch", ".html": "html", ".css": "css", ".scss": "scss",
+	".sass": "sass", ".json": "json", ".xml": "xml", ".yaml": "yaml", ".yml": "yaml",
+	".toml": "toml", ".ini": "ini", ".cfg": "ini", ".md": "markdown", ".rst": "rst",
+	".tex": "latex", ".gradle": "groovy", ".cmake": "cmake", ".mk": "makefile",
+	".dockerfile": "dockerfile", ".tf": "hcl", ".proto": "protobuf", ".graphql": "graphql",
+}
+
+// fileNameLanguages covers well-known files without a telling extension
+var fileNameLanguages = map[string]string{
+	"dockerfile": "dockerfile", "makefile": "makefile", "gnumakefile": "makefile",
+	"cmakelists.txt": "cmake", "go.mod": "go.mod", "jenkinsfile": "groovy",
+}
+
+// fileLanguage returns the code fence tag for path, or "" when unknown
+func fileLanguage(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	if language, ok := fileNameLanguages[base]; ok {
+		return language
+	}
+	return fileLanguages[strings.ToLower(filepath.Ext(base))]
+}
+
+// codeFence returns a backtick fence longer than any backtick run in
+// content, so fences inside loaded markdown do not close it early
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// Plural formats a count with its noun, adding an s unless n is 1
+func Plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "512 B" or
+// "2.1 KB", for file headers, model sizes, and cleanup reports
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, suffix := float64(size)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// fileMetadataLine describes a loaded file under its File: header, e.g.
+// "(2.1 KB, 84 lines, go, modified 2026-10-14 09:12)". The File: line
+// itself is left alone, since session tools find loaded paths by it.
+func fileMetadataLine(filePath, content, language string) string {
+	var parts []string
+	info, err := os.Stat(filePath)
+	if err == nil {
+		parts = append(parts, FormatBytes(info.Size()))
+	}
+	lines := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	parts = append(parts, fmt.Sprintf("%d lines", lines))
+	if language != "" {
+		parts = append(parts, language)
+	}
+	if err == nil {
+		parts = append(parts, "modified "+info.ModTime().Format("2006-01-02 15:04"))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// formatFileBody returns a loaded file's contents for context. With
+// file_metadata on, a metadata line comes first and plain text files are
+// wrapped in a code fence tagged with their language; documents such as
+// PDFs keep their extracted text unfenced.
+func (t *Terminal) formatFileBody(filePath, content string, plainText bool) string {
+	if !t.config.FileMetadata {
+		return content
+	}
+	language := ""
+	if plainText {
+		language = fileLanguage(filePath)
+	}
+	header := fileMetadataLine(filePath, content, language) + "\n"
+	if !plainText {
+		return header + content
+	}
+	fence := codeFence(content)
+	return header + fence + language + "\n" + strings.TrimSuffix(content, "\n") + "\n" + fence
+}
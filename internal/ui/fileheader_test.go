@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFileLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":          "go",
+		"src/App.TSX":      "tsx",
+		"build/Dockerfile": "dockerfile",
+		"Makefile":         "makefile",
+		"notes.txt":        "",
+		"README":           "",
+	}
+	for path, want := range tests {
+		if got := fileLanguage(path); got != want {
+			t.Errorf("fileLanguage(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestCodeFenceOutlastsInnerFences(t *testing.T) {
+	if got := codeFence("plain"); got != "```" {
+		t.Errorf("codeFence(plain) = %q", got)
+	}
+	if got := codeFence("# doc\n````go\nx\n````\n"); got != "`````" {
+		t.Errorf("codeFence with a 4-backtick fence = %q, want 5 backticks", got)
+	}
+}
+
+func TestLoadTextFileMetadata(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2026, 10, 14, 9, 12, 0, 0, time.Local)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := NewTerminal(&types.Config{FileMetadata: true}).loadTextFile(path)
+	if err != nil {
+		t.Fatalf("loadTextFile() error: %v", err)
+	}
+	want := "File: " + path + "\n(29 B, 3 lines, go, modified 2026-10-14 09:12)\n```go\npackage main\n\nfunc main() {}\n```\n\n"
+	if content != want {
+		t.Errorf("loadTextFile() = %q, want %q", content, want)
+	}
+
+	plain, err := NewTerminal(&types.Config{}).loadTextFile(path)
+	if err != nil || !strings.HasPrefix(plain, "File: "+path+"\npackage main") {
+		t.Errorf("with file_metadata off, loadTextFile() = %q, %v", plain, err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{2019393189, "1.9 GB"},
+		{3 << 40, "3.0 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.size); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+// commandHelp documents one interactive command for the help picker, its
+// preview pane, and ch cheatsheet. Examples are written after the command's
+// current key, with an optional "# comment".
+type commandHelp struct {
+	key      func(*types.Config) string
+	setting  string // config.json key that changes it
+	args     string
+	summary  string
+	examples []string
+}
+
+// fixedKeyHelp documents keys that cannot be changed in config
+var fixedKeyHelp = []struct{ key, summary string }{
+	{"ctrl+c", "clear prompt input"},
+	{"ctrl+d", "exit completely"},
+}
+
+// commandHelpEntries lists the interactive commands in help order
+var commandHelpEntries = []commandHelp{
+	{func(c *types.Config) string { return c.ExitKey }, "exit_key", "", "exit interface", []string{""}},
+	{func(c *types.Config) string { return c.HelpKey }, "help_key", "", "help page", []string{""}},
+	{func(c *types.Config) string { return c.ClearHistory }, "clear_history", "", "clear chat history", []string{""}},
+	{func(c *types.Config) string { return c.Backtrack }, "backtrack", "", "backtrack messages", []string{"  # pick the message to go back to"}},
+	{func(c *types.Config) string { return c.AllModels }, "all_models", "[platform] [text...] | pull model", "select from all models, or pull an Ollama model", []string{"  # models of every configured platform", " groq llama  # only groq models containing llama", " pull llama3.2  # download into the local Ollama server"}},
+	{func(c *types.Config) string { return c.ModelSwitch }, "model_switch", "", "switch models", []string{"", " gpt-4.1-mini  # switch without the picker"}},
+	{func(c *types.Config) string { return c.PlatformSwitch }, "platform_switch", "", "switch platforms", []string{"", " groq  # then pick one of its models"}},
+	{func(c *types.Config) string { return c.ShellRecord }, "shell_record", "", "record shell session", []string{"  # shell until exit, output added to context", " git diff  # run one command"}},
+	{func(c *types.Config) string { return c.ShellRecordSilent }, "shell_record_silent", "", "shell session (not recorded)", []string{" make test  # output is not saved to history"}},
+	{func(c *types.Config) string { return c.CodeDump }, "code_dump", "", "generate codedump", []string{"  # pick files to leave out, then load the rest"}},
+	{func(c *types.Config) string { return c.CopyToClipboard }, "copy_to_clipboard", "", "add to clipboard", []string{"  # pick a response or code block to copy"}},
+	{func(c *types.Config) string { return c.QuickCopyLatest }, "quick_copy_latest", "", "quick copy latest response", []string{""}},
+	{func(c *types.Config) string { return c.ClipboardHistory }, "clipboard_history", "[clear]", "re-copy from clipboard history", []string{"", " clear"}},
+	{func(c *types.Config) string { return c.MultiLine }, "multi_line", "", "multi-line input mode", []string{"  # type lines, then the key again to send"}},
+	{func(c *types.Config) string { return c.ExportChat }, "export_chat", "[--last N] [--since t] [--role r] [--strip-context] [--notes] [file]", "export chat(s)", []string{"", " --last 3 --strip-context notes.md", " --role assistant --format markdown answers.md"}},
+	{func(c *types.Config) string { return c.EditorInput }, "editor_input", "[buff]", "text editor mode", []string{"  # write the prompt in $EDITOR", " buff  # load editor text as context without sending it"}},
+	{func(c *types.Config) string { return c.LoadFiles }, "load_files", "[dir]", "load files/dirs", []string{"", " src/  # pick files under src", " ~/notes.md"}},
+	{func(c *types.Config) string { return c.ScrapeURL }, "scrape_url", "[--md|--text] [url]", "scrape URL(s)", []string{" https://go.dev/doc/effective_go", " --md https://example.com", "  # pick a URL from history"}},
+	{func(c *types.Config) string { return c.WebSearch }, "web_search", "[query]", "web search", []string{" go 1.23 release notes", "  # pick a query from history"}},
+	{func(c *types.Config) string { return c.DeepSearch }, "deep_search", "[query]", "search, read the top pages, and answer with citations", []string{" what changed in go 1.23 iterators", "  # pick a query from history"}},
+	{func(c *types.Config) string { return c.AnswerSearch }, "answer_search", "[filter] [--exact]", "search past answers", []string{"", " 1w  # answers from the last week", " #favorite --exact"}},
+	{func(c *types.Config) string { return c.RateSession }, "rate_session", "[1-5]", "rate session for dataset exports", []string{" 5", "  # show the rating", " 0  # clear it"}},
+	{func(c *types.Config) string { return c.TagExchange }, "tag_exchange", "[name]", "tag last exchange (favorite if no name)", []string{"", " go", " -go  # remove the tag"}},
+	{func(c *types.Config) string { return c.AddNote }, "add_note", "[text|clear]", "add a session note (not sent to the model)", []string{" use the retry wrapper, not the raw client", "  # list notes", " clear"}},
+	{func(c *types.Config) string { return c.EditStopSequences }, "edit_stop_sequences", "[seq|clear]", "set stop sequences", []string{` \n\n`, "  # list them", " clear"}},
+	{func(c *types.Config) string { return c.EditRedactions }, "edit_redactions", "[find => replace|clear]", "redact exported content", []string{" db01.corp.local => db-host", ` re:10\.\d+\.\d+\.\d+ => <ip>`, " clear"}},
+	{func(c *types.Config) string { return c.EditHeaders }, "edit_headers", "[Name: value|Name:|clear|save]", "extra HTTP headers for this platform", []string{" X-Request-Source: ch", " X-Request-Source:  # remove it", " save"}},
+	{func(c *types.Config) string { return c.BigFile }, "big_file", "[path|clear]", "chunked Q&A over a huge file", []string{" server.log", "  # show the indexed file", " clear"}},
+	{func(c *types.Config) string { return c.Retrieve }, "retrieve", "[question]", "answer from the ch --index of this directory", []string{" where are retries configured?", "  # show the index in use"}},
+	{func(c *types.Config) string { return c.ApplyPatches }, "apply_patches", "[request]", "edit existing files with a diff preview", []string{" rename Config.Timeout to RequestTimeout in config.go", "  # apply edits from the last response"}},
+	{func(c *types.Config) string { return c.EditFile }, "edit_file", "<file> <instruction>", "edit a file with a diff preview and .bak backup", []string{" main.go add a --verbose flag"}},
+	{func(c *types.Config) string { return c.Remember }, "remember", "<fact>", "save a long-term memory (needs memory=true)", []string{" I use Go 1.22 and zsh"}},
+	{func(c *types.Config) string { return c.Memories }, "memories", "[clear]", "review and delete long-term memories", []string{"", " clear"}},
+	{func(c *types.Config) string { return c.LiveFiles }, "live_files", "[path|clear]", "load a file that is re-read when it changes", []string{" main.go", "  # list live files", " clear"}},
+	{func(c *types.Config) string { return c.RemoteLoad }, "remote_load", "[user@]host:path", "load a remote file or directory listing over ssh", []string{" web1:/var/log/nginx/error.log", " deploy@web1:~/app"}},
+	{func(c *types.Config) string { return c.LoadRows }, "load_rows", "<n|start-end> [sheet] [file]", "load rows of a summarized table", []string{" 100-150", " 3 Sheet2"}},
+	{func(c *types.Config) string { return c.DBQuery }, "db_query", "<sqlite file> <sql>", "run a read-only query and load the results", []string{" ~/app.db SELECT status, count(*) FROM orders GROUP BY status"}},
+	{func(c *types.Config) string { return c.LockModel }, "lock_model", "", "confirm before switching model/platform", []string{""}},
+	{func(c *types.Config) string { return c.UnlockModel }, "unlock_model", "", "allow model/platform switches again", []string{""}},
+	{func(c *types.Config) string { return c.ResetTerminal }, "reset_terminal", "", "repair a garbled terminal", []string{""}},
+	{func(c *types.Config) string { return c.AddBookmark }, "add_bookmark", "[label]", "bookmark the latest exchange", []string{"", " before the refactor"}},
+	{func(c *types.Config) string { return c.ListBookmarks }, "list_bookmarks", "", "re-read the conversation from a bookmark", []string{""}},
+	{func(c *types.Config) string { return c.ShowLog }, "show_log", "[n]", "re-print the last n exchanges (all if no n)", []string{"", " 3"}},
+	{func(c *types.Config) string { return c.ReplayTo }, "replay_to", "[platform|model]", "replay the conversation on another model", []string{"  # pick a platform, then a model", " groq", " gpt-4.1"}},
+	{func(c *types.Config) string { return c.UseTemplate }, "use_template", "[name] [var=value...]", "fill in a prompt template from ~/.ch/templates and send it", []string{"  # pick a template", " review-pr focus=error handling", " commit-msg"}},
+	{func(c *types.Config) string { return c.ResponseActions }, "response_actions", "", "actions on the last response: copy, save code, export, regenerate, translate, speak, open URLs", []string{"  # pick an action for the latest response"}},
+	{func(c *types.Config) string { return c.SaveCodeBlock }, "save_code_block", "[n] [path]", "save code block n of the last response (or !s<n>)", []string{"", " 2 cmd/tool/main.go"}},
+}
+
+// line returns the entry as shown in the help list: key, arguments, summary
+func (h commandHelp) line(cfg *types.Config) string {
+	if h.args == "" {
+		return fmt.Sprintf("%s - %s", h.key(cfg), h.summary)
+	}
+	return fmt.Sprintf("%s %s - %s", h.key(cfg), h.args, h.summary)
+}
+
+// syntax returns the key and arguments of the entry
+func (h commandHelp) syntax(cfg *types.Config) string {
+	return strings.TrimSpace(h.key(cfg) + " " + h.args)
+}
+
+// exampleLines returns the entry's examples with the current key filled in
+func (h commandHelp) exampleLines(cfg *types.Config) []string {
+	lines := make([]string, len(h.examples))
+	for i, example := range h.examples {
+		lines[i] = h.key(cfg) + example
+	}
+	return lines
+}
+
+// keyNote describes the entry's current key and where to change it,
+// mentioning the default when the user has changed it
+func (h commandHelp) keyNote(cfg *types.Config) string {
+	note := fmt.Sprintf("key: %s (%q in config.json", h.key(cfg), h.setting)
+	if defaultKey := h.key(config.BuiltinDefaults()); defaultKey != h.key(cfg) {
+		note += ", default " + defaultKey
+	}
+	return note + ")"
+}
+
+// preview returns the text of the help picker's preview pane for the entry
+func (h commandHelp) preview(cfg *types.Config) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\n\n%s\n\n%s\n\nexamples:\n", h.syntax(cfg), h.summary, h.keyNote(cfg))
+	for _, example := range h.exampleLines(cfg) {
+		fmt.Fprintf(&out, "  %s\n", example)
+	}
+	return out.String()
+}
+
+// escapeHelpPreview makes text safe to pass as a tab-separated fzf field
+// printed with printf %b
+func escapeHelpPreview(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\t", " ", "\r", "").Replace(text)
+}
+
+// cheatsheetWidth is the syntax column width of ch cheatsheet; longer
+// syntax puts the summary on the next line
+const cheatsheetWidth = 32
+
+// Cheatsheet renders every interactive command with its current key,
+// syntax, and first example with arguments as a one-page reference for
+// ch cheatsheet
+func (t *Terminal) Cheatsheet() string {
+	colored := !t.config.IsPipedOutput
+	paint := func(code, text string) string {
+		if !colored {
+			return text
+		}
+		return "\033[" + code + "m" + text + "\033[0m"
+	}
+	indent := strings.Repeat(" ", cheatsheetWidth+4)
+	row := func(out *strings.Builder, syntax, summary string) {
+		if len(syntax) > cheatsheetWidth {
+			fmt.Fprintf(out, "  %s\n%s%s\n", paint("93", syntax), indent, summary)
+			return
+		}
+		fmt.Fprintf(out, "  %s%s  %s\n", paint("93", syntax), strings.Repeat(" ", cheatsheetWidth-len(syntax)), summary)
+	}
+
+	defaults := config.BuiltinDefaults()
+	var out strings.Builder
+	out.WriteString(paint("96", "ch cheatsheet") + "\n\n")
+	out.WriteString(paint("96", "commands") + "\n")
+	for _, entry := range commandHelpEntries {
+		summary := entry.summary
+		if defaultKey := entry.key(defaults); defaultKey != entry.key(t.config) {
+			summary += paint("90", fmt.Sprintf(" (default %s)", defaultKey))
+		}
+		row(&out, entry.syntax(t.config), summary)
+		for k, example := range entry.examples {
+			// Examples starting with a space give arguments; the rest only comment
+			if strings.HasPrefix(example, " ") && !strings.HasPrefix(example, "  #") {
+				fmt.Fprintf(&out, "%s%s\n", indent, paint("90", "e.g. "+entry.exampleLines(t.config)[k]))
+				break
+			}
+		}
+	}
+
+	out.WriteString("\n" + paint("96", "keys") + "\n")
+	for _, fixed := range fixedKeyHelp {
+		row(&out, fixed.key, fixed.summary)
+	}
+	out.WriteString("\n" + paint("90", "change command keys in ~/.ch/config.json; ch -h lists command-line flags") + "\n")
+	return out.String()
+}
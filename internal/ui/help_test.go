@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/internal/config"
+)
+
+func TestHelpPickerLines(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LoadFiles = "!load"
+	terminal := NewTerminal(cfg)
+
+	lines := terminal.helpPickerLines()
+	options := terminal.getInteractiveHelpOptions()
+	if len(lines) != len(options) {
+		t.Fatalf("got %d picker lines for %d options", len(lines), len(options))
+	}
+
+	var load string
+	for i, line := range lines {
+		option, preview, ok := strings.Cut(line, "\t")
+		if !ok || option != options[i] || strings.ContainsAny(preview, "\t\n") {
+			t.Errorf("line %d = %q, want %q, a tab, and a one-line preview", i, line, options[i])
+		}
+		if strings.HasPrefix(option, "!load ") {
+			load = preview
+		}
+	}
+	for _, want := range []string{`!load [dir]\n`, `key: !load ("load_files" in config.json, default !l)`, `  !load src/  # pick files under src`} {
+		if !strings.Contains(load, want) {
+			t.Errorf("!load preview is missing %q: %q", want, load)
+		}
+	}
+}
+
+func TestCheatsheet(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.IsPipedOutput = true
+	cfg.ExitKey = "!quit"
+	sheet := NewTerminal(cfg).Cheatsheet()
+
+	for _, want := range []string{"!quit", "exit interface (default !q)", "e.g. !db ~/app.db SELECT", "ctrl+d"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("cheatsheet is missing %q:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, "\033[") {
+		t.Error("piped cheatsheet contains color codes")
+	}
+	if strings.Contains(sheet, "!q ") {
+		t.Error("cheatsheet shows the default exit key instead of the configured one")
+	}
+}
+
+func TestCheatsheetDefaultsIgnoreConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".ch"), 0700)
+	os.WriteFile(filepath.Join(home, ".ch", "config.json"), []byte(`{"exit_key":"!quit"}`), 0600)
+
+	cfg := config.DefaultConfig()
+	if cfg.ExitKey != "!quit" {
+		t.Fatalf("config.json was not loaded: exit key %q", cfg.ExitKey)
+	}
+	cfg.IsPipedOutput = true
+	if sheet := NewTerminal(cfg).Cheatsheet(); !strings.Contains(sheet, "exit interface (default !q)") {
+		t.Errorf("cheatsheet does not note the built-in default of a key set in config.json:\n%s", sheet)
+	}
+	for _, entry := range commandHelpEntries {
+		if entry.setting == "exit_key" {
+			if note := entry.keyNote(cfg); !strings.Contains(note, "default !q") {
+				t.Errorf("keyNote() = %q, want the built-in default", note)
+			}
+		}
+	}
+}
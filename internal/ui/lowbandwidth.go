@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lines of long input kept at each end, and the longest line kept whole,
+// when low_bandwidth shortens echoed input
+const (
+	echoHeadLines    = 6
+	echoTailLines    = 3
+	echoMaxLineChars = 300
+)
+
+// redrawInterval is how often spinners and progress lines are redrawn:
+// low_bandwidth_interval_ms with low_bandwidth on, 100ms otherwise
+func (t *Terminal) redrawInterval() time.Duration {
+	if !t.config.LowBandwidth {
+		return 100 * time.Millisecond
+	}
+	if t.config.LowBandwidthInterval > 0 {
+		return time.Duration(t.config.LowBandwidthInterval) * time.Millisecond
+	}
+	return 500 * time.Millisecond
+}
+
+// RedrawLimiter throttles a status line that is redrawn on every update,
+// such as a download or summarize counter
+type RedrawLimiter struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// NewRedrawLimiter returns a limiter that allows one redraw per the
+// terminal's redraw interval
+func (t *Terminal) NewRedrawLimiter() *RedrawLimiter {
+	return &RedrawLimiter{interval: t.redrawInterval()}
+}
+
+// Allow reports whether the status line may be redrawn now
+func (l *RedrawLimiter) Allow() bool {
+	now := time.Now()
+	if now.Sub(l.last) < l.interval {
+		return false
+	}
+	l.last = now
+	return true
+}
+
+// EchoInput prints input that was written outside the prompt, such as in
+// the editor, quoted with "> ". With low_bandwidth on, long input is
+// shortened first; the full text is still what gets sent.
+func (t *Terminal) EchoInput(input string) {
+	if t.config.LowBandwidth {
+		input = shortenEcho(input)
+	}
+	fmt.Printf("\033[94m> %s\033[0m\n", strings.ReplaceAll(input, "\n", "\n> "))
+}
+
+// shortenEcho keeps the first and last lines of long text and cuts very
+// long lines, noting how much was left out
+func shortenEcho(text string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > echoHeadLines+echoTailLines+1 {
+		omitted := len(lines) - echoHeadLines - echoTailLines
+		kept := append([]string{}, lines[:echoHeadLines]...)
+		kept = append(kept, fmt.Sprintf("... %d more lines ...", omitted))
+		lines = append(kept, lines[len(lines)-echoTailLines:]...)
+	}
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > echoMaxLineChars {
+			lines[i] = fmt.Sprintf("%s ... (%d more chars)", string(runes[:echoMaxLineChars]), len(runes)-echoMaxLineChars)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
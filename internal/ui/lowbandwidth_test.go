@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestShortenEcho(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	got := strings.Split(shortenEcho(strings.Join(lines, "\n")), "\n")
+	if len(got) != echoHeadLines+echoTailLines+1 {
+		t.Fatalf("shortened to %d lines: %q", len(got), got)
+	}
+	if got[0] != "line 1" || got[echoHeadLines] != "... 31 more lines ..." || got[len(got)-1] != "line 40" {
+		t.Errorf("shortenEcho() = %q", got)
+	}
+
+	short := "a\nb\nc"
+	if got := shortenEcho(short); got != short {
+		t.Errorf("shortenEcho(%q) = %q, want it unchanged", short, got)
+	}
+
+	long := strings.Repeat("x", echoMaxLineChars+20)
+	if got := shortenEcho(long); !strings.HasSuffix(got, " ... (20 more chars)") || len(got) > echoMaxLineChars+30 {
+		t.Errorf("shortenEcho(long line) = %q", got)
+	}
+}
+
+func TestRedrawInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		config types.Config
+		want   time.Duration
+	}{
+		{"off", types.Config{LowBandwidthInterval: 2000}, 100 * time.Millisecond},
+		{"on", types.Config{LowBandwidth: true, LowBandwidthInterval: 2000}, 2 * time.Second},
+		{"on without interval", types.Config{LowBandwidth: true}, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terminal := NewTerminal(&tt.config)
+			if got := terminal.redrawInterval(); got != tt.want {
+				t.Errorf("redrawInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedrawLimiter(t *testing.T) {
+	limiter := NewTerminal(&types.Config{LowBandwidth: true, LowBandwidthInterval: 60000}).NewRedrawLimiter()
+	if !limiter.Allow() {
+		t.Error("first redraw was not allowed")
+	}
+	if limiter.Allow() {
+		t.Error("second redraw within the interval was allowed")
+	}
+}
@@ -0,0 +1,345 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	markdownSpaceRegex     = regexp.MustCompile(`\s+`)
+	markdownBlankLineRegex = regexp.MustCompile(`\n{3,}`)
+)
+
+// markdownConverter renders an HTML tree as markdown, resolving relative links against base
+type markdownConverter struct {
+	base *url.URL
+}
+
+// htmlToMarkdown converts an HTML document body to markdown, preserving headings,
+// lists, tables, links, and code blocks so structured pages stay readable.
+func htmlToMarkdown(body io.Reader, baseURL string) (string, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	converter := &markdownConverter{}
+	if base, err := url.Parse(baseURL); err == nil && base.Scheme != "" {
+		converter.base = base
+	}
+
+	var sb strings.Builder
+	converter.blocks(doc, &sb, 0)
+
+	// Clean up trailing spaces and excessive blank lines
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text := markdownBlankLineRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+
+	return strings.TrimSpace(text), nil
+}
+
+// blocks renders all children of n as block content
+func (c *markdownConverter) blocks(n *html.Node, sb *strings.Builder, depth int) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.block(child, sb, depth)
+	}
+}
+
+// block renders a single node in a block context
+func (c *markdownConverter) block(n *html.Node, sb *strings.Builder, depth int) {
+	switch n.Type {
+	case html.DocumentNode:
+		c.blocks(n, sb, depth)
+		return
+	case html.TextNode:
+		if strings.TrimSpace(n.Data) != "" {
+			sb.WriteString(markdownSpaceRegex.ReplaceAllString(n.Data, " "))
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "nav", "header", "footer", "aside", "noscript", "template", "head":
+		return
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		if text := c.inline(n); text != "" {
+			writeMarkdownBlock(sb, strings.Repeat("#", level)+" "+text)
+		}
+	case "p":
+		writeMarkdownBlock(sb, c.inline(n))
+	case "ul", "ol":
+		var list strings.Builder
+		c.list(n, &list, depth)
+		writeMarkdownBlock(sb, strings.TrimRight(list.String(), "\n"))
+	case "pre":
+		writeMarkdownBlock(sb, c.codeBlock(n))
+	case "blockquote":
+		var inner strings.Builder
+		c.blocks(n, &inner, depth)
+		var quoted []string
+		for _, line := range strings.Split(strings.TrimSpace(inner.String()), "\n") {
+			quoted = append(quoted, strings.TrimRight("> "+line, " "))
+		}
+		writeMarkdownBlock(sb, strings.Join(quoted, "\n"))
+	case "table":
+		writeMarkdownBlock(sb, c.table(n))
+	case "hr":
+		writeMarkdownBlock(sb, "---")
+	case "br":
+		sb.WriteString("\n")
+	case "html", "body", "main", "article", "section", "div", "figure", "form", "fieldset", "details", "dl", "dd", "dt", "summary", "figcaption":
+		// Container elements keep their children on separate lines
+		ensureMarkdownNewline(sb)
+		c.blocks(n, sb, depth)
+		ensureMarkdownNewline(sb)
+	default:
+		sb.WriteString(c.inlineNode(n))
+	}
+}
+
+// inline renders the children of n as a single line of inline markdown
+func (c *markdownConverter) inline(n *html.Node) string {
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(c.inlineNode(child))
+	}
+	return cleanMarkdownInline(sb.String())
+}
+
+// inlineNode renders a node in an inline context
+func (c *markdownConverter) inlineNode(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return markdownSpaceRegex.ReplaceAllString(n.Data, " ")
+	}
+	if n.Type != html.ElementNode {
+		return ""
+	}
+
+	switch n.Data {
+	case "script", "style", "noscript", "template":
+		return ""
+	case "br":
+		return " "
+	case "a":
+		text := c.inline(n)
+		href := c.resolveURL(htmlAttr(n, "href"))
+		if text == "" || href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case "img":
+		src := c.resolveURL(htmlAttr(n, "src"))
+		if src == "" {
+			return ""
+		}
+		return fmt.Sprintf("![%s](%s)", htmlAttr(n, "alt"), src)
+	case "strong", "b":
+		return wrapMarkdownInline(c.inline(n), "**")
+	case "em", "i":
+		return wrapMarkdownInline(c.inline(n), "_")
+	case "code", "kbd", "samp":
+		return wrapMarkdownInline(htmlTextContent(n), "`")
+	case "del", "s", "strike":
+		return wrapMarkdownInline(c.inline(n), "~~")
+	}
+
+	// Unknown or block elements inside inline content are flattened
+	text := c.inline(n)
+	if text == "" {
+		return ""
+	}
+	return " " + text + " "
+}
+
+// list renders an ordered or unordered list, nesting sub-lists by indentation
+func (c *markdownConverter) list(n *html.Node, sb *strings.Builder, depth int) {
+	ordered := n.Data == "ol"
+	index := 1
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.Data != "li" {
+			continue
+		}
+
+		var text strings.Builder
+		var nested []*html.Node
+		for child := item.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && (child.Data == "ul" || child.Data == "ol") {
+				nested = append(nested, child)
+				continue
+			}
+			text.WriteString(c.inlineNode(child))
+		}
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+		sb.WriteString(strings.Repeat("  ", depth) + marker + cleanMarkdownInline(text.String()) + "\n")
+
+		for _, sub := range nested {
+			c.list(sub, sb, depth+1)
+		}
+	}
+}
+
+// codeBlock renders a <pre> element as a fenced code block, keeping its whitespace
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	language := markdownCodeLanguage(htmlAttr(n, "class"))
+	for child := n.FirstChild; child != nil && language == ""; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "code" {
+			language = markdownCodeLanguage(htmlAttr(child, "class"))
+		}
+	}
+	code := strings.Trim(htmlTextContent(n), "\n")
+	return "```" + language + "\n" + code + "\n```"
+}
+
+// table renders an HTML table as a markdown pipe table, using the first row as the header
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var collect func(*html.Node)
+	collect = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			case "tr":
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "th" || cell.Data == "td") {
+						cells = append(cells, strings.ReplaceAll(c.inline(cell), "|", `\|`))
+					}
+				}
+				if len(cells) > 0 {
+					rows = append(rows, cells)
+				}
+			}
+		}
+	}
+	collect(n)
+	return markdownTable(rows)
+}
+
+// markdownTable renders rows as a markdown pipe table, using the first row as
+// the header and padding short rows. Cells must already have "|" escaped.
+func markdownTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	var sb strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		sb.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// resolveURL resolves a possibly relative link against the page URL
+func (c *markdownConverter) resolveURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || c.base == nil {
+		return raw
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return c.base.ResolveReference(ref).String()
+}
+
+// writeMarkdownBlock writes text as its own block separated by blank lines
+func writeMarkdownBlock(sb *strings.Builder, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(text)
+	sb.WriteString("\n\n")
+}
+
+// ensureMarkdownNewline starts a new line unless the builder is already at one
+func ensureMarkdownNewline(sb *strings.Builder) {
+	if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteString("\n")
+	}
+}
+
+// cleanMarkdownInline collapses whitespace in inline text
+func cleanMarkdownInline(text string) string {
+	return strings.TrimSpace(markdownSpaceRegex.ReplaceAllString(text, " "))
+}
+
+// wrapMarkdownInline wraps non-empty text in an inline marker such as ** or `
+func wrapMarkdownInline(text, marker string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	return marker + text + marker
+}
+
+// markdownCodeLanguage extracts a language name from a "language-xxx" or "lang-xxx" class
+func markdownCodeLanguage(class string) string {
+	for _, name := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(name, prefix) {
+				return strings.TrimPrefix(name, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// htmlAttr returns the value of an attribute, or an empty string if it is missing
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlTextContent returns the raw text of a node and its descendants
+func htmlTextContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(htmlTextContent(child))
+	}
+	return sb.String()
+}
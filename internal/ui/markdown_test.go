@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	page := `<html><head><title>t</title></head><body>
+<nav>menu</nav>
+<h1>Guide</h1>
+<p>Read the <a href="/docs/intro">intro</a> and <strong>enjoy</strong>.</p>
+<ul>
+  <li>first</li>
+  <li>second
+    <ol><li>nested</li></ol>
+  </li>
+</ul>
+<pre><code class="language-go">func main() {
+	fmt.Println("hi")
+}</code></pre>
+<table>
+  <tr><th>Name</th><th>Value</th></tr>
+  <tr><td>a|b</td><td>1</td></tr>
+</table>
+<blockquote><p>quoted text</p></blockquote>
+<script>ignored()</script>
+</body></html>`
+
+	got, err := htmlToMarkdown(strings.NewReader(page), "https://example.com/guide/")
+	if err != nil {
+		t.Fatalf("htmlToMarkdown returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"# Guide",
+		"Read the [intro](https://example.com/docs/intro) and **enjoy**.",
+		"- first\n- second\n  1. nested",
+		"```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		"| Name | Value |\n| --- | --- |\n| a\\|b | 1 |",
+		"> quoted text",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("markdown missing %q\ngot:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"menu", "ignored()", "\n\n\n"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("markdown should not contain %q\ngot:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestMarkdownCodeLanguage(t *testing.T) {
+	tests := map[string]string{
+		"language-python": "python",
+		"hljs lang-js":    "js",
+		"highlight":       "",
+		"":                "",
+	}
+	for class, want := range tests {
+		if got := markdownCodeLanguage(class); got != want {
+			t.Errorf("markdownCodeLanguage(%q) = %q, want %q", class, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MehmetMHY/ch/internal/fswalk"
+)
+
+// IsGlobMention reports whether an @ mention is a glob such as src/**/*.go
+func IsGlobMention(spec string) bool {
+	return strings.ContainsAny(spec, "*?[")
+}
+
+// MentionFiles lists the text files a directory or glob mention expands to,
+// in walk order, using the same fswalk listing as codedump and !l.
+func (t *Terminal) MentionFiles(spec string) ([]string, error) {
+	base, pattern := splitGlobMention(spec)
+	info, err := os.Stat(base)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", base)
+	}
+
+	opts := fswalk.OptionsFromConfig(t.config, base)
+	opts.Filter = t.isTextFileByPath
+	items, err := fswalk.List(base, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, item := range items {
+		if pattern != "" && !matchGlobPath(pattern, filepath.ToSlash(item)) {
+			continue
+		}
+		files = append(files, filepath.Join(base, item))
+	}
+	return files, nil
+}
+
+// splitGlobMention splits a mention into the directory to walk and the glob
+// for paths below it. Directory mentions have an empty glob.
+func splitGlobMention(spec string) (string, string) {
+	if !IsGlobMention(spec) {
+		return spec, ""
+	}
+	parts := strings.Split(filepath.ToSlash(spec), "/")
+	for i, part := range parts {
+		if IsGlobMention(part) {
+			base := strings.Join(parts[:i], "/")
+			if base == "" {
+				base = "."
+				if strings.HasPrefix(spec, "/") {
+					base = "/"
+				}
+			}
+			return filepath.FromSlash(base), strings.Join(parts[i:], "/")
+		}
+	}
+	return spec, ""
+}
+
+// matchGlobPath matches a slash-separated path against a glob where ** spans
+// any number of directories
+func matchGlobPath(pattern, path string) bool {
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchGlobParts(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchGlobParts(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+		return false
+	}
+	return matchGlobParts(pattern[1:], path[1:])
+}
+
+// Confirm asks a yes/no question that defaults to no. Without a terminal on
+// stdin there is nobody to answer, so it returns false without asking.
+func (t *Terminal) Confirm(question string) bool {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Fprintf(t.UIWriter(), "\033[93m%s (y/N)\033[0m ", question)
+	var response string
+	_, _ = fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
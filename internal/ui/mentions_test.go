@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestMatchGlobPath(t *testing.T) {
+	cases := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"*.go", "a/b.go", false},
+		{"a/**/c.go", "a/c.go", true},
+		{"a/**/c.go", "a/x/y/c.go", true},
+		{"a/*/c.go", "a/x/y/c.go", false},
+		{"**/*_test.go", "pkg/x.go", false},
+	}
+	for _, c := range cases {
+		if got := matchGlobPath(c.pattern, c.path); got != c.want {
+			t.Errorf("matchGlobPath(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestSplitGlobMention(t *testing.T) {
+	if base, pattern := splitGlobMention("src/**/*.go"); base != "src" || pattern != "**/*.go" {
+		t.Errorf("splitGlobMention = %q, %q", base, pattern)
+	}
+	if base, pattern := splitGlobMention("*.md"); base != "." || pattern != "*.md" {
+		t.Errorf("splitGlobMention = %q, %q", base, pattern)
+	}
+	if base, pattern := splitGlobMention("docs"); base != "docs" || pattern != "" {
+		t.Errorf("splitGlobMention = %q, %q", base, pattern)
+	}
+}
+
+func TestMentionFilesSkipsGitignoredPaths(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	for path, content := range map[string]string{
+		".gitignore":          "vendor/\nsrc/util/*.gen.go\n",
+		"src/main.go":         "package main",
+		"src/util/util.go":    "package util",
+		"src/util/api.gen.go": "package util",
+		"src/notes.md":        "notes",
+		"vendor/dep/dep.go":   "package dep",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(".git", 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	terminal := NewTerminal(&types.Config{RespectGitignore: true})
+	files, err := terminal.MentionFiles("src/**/*.go")
+	if err != nil {
+		t.Fatalf("MentionFiles() error: %v", err)
+	}
+	want := []string{filepath.Join("src", "main.go"), filepath.Join("src", "util", "util.go")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("glob files = %v, want %v", files, want)
+	}
+
+	files, err = terminal.MentionFiles("**/*.go")
+	if err != nil || len(files) != 2 {
+		t.Errorf("root glob should skip vendor/ from .gitignore, got %v (%v)", files, err)
+	}
+
+	files, err = terminal.MentionFiles("src")
+	if err != nil || len(files) != 3 {
+		t.Errorf("directory mention should load every text file, got %v (%v)", files, err)
+	}
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// defaultPager keeps colors, exits when the text fits on one screen, and
+// leaves the text on screen after quitting
+var defaultPager = []string{"less", "-RFX"}
+
+// pagerCommand returns $PAGER split into arguments, or the default pager
+func pagerCommand() []string {
+	if pager := strings.Fields(os.Getenv("PAGER")); len(pager) > 0 {
+		return pager
+	}
+	return defaultPager
+}
+
+// Page shows content in the user's pager. It is printed directly when
+// output is piped or no pager is installed.
+func (t *Terminal) Page(content string) error {
+	pager := pagerCommand()
+	if t.config.IsPipedOutput || !t.IsTerminal() {
+		fmt.Fprint(t.UIWriter(), content)
+		return nil
+	}
+	if _, err := exec.LookPath(pager[0]); err != nil {
+		fmt.Fprint(t.UIWriter(), content)
+		return nil
+	}
+
+	cmd := exec.Command(pager[0], pager[1:]...) // #nosec G204 -- The pager is chosen by the user via PAGER and is executed without a shell.
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := RunForeground(cmd); err != nil {
+		return fmt.Errorf("pager failed: %w", err)
+	}
+	return nil
+}
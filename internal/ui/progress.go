@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+)
+
+// Progress is a snapshot of a long load or scrape, such as
+// "loaded 14/37 files, 120k tokens". A non-empty Note is printed once above
+// the progress line, in red when Failed is set.
+type Progress struct {
+	Verb   string
+	Unit   string
+	Done   int
+	Total  int
+	Tokens int
+	Note   string
+	Failed bool
+}
+
+// String renders the progress line without the spinner
+func (p Progress) String() string {
+	line := fmt.Sprintf("%s %d/%d %s", p.Verb, p.Done, p.Total, p.Unit)
+	if p.Tokens > 0 {
+		line += fmt.Sprintf(", %s tokens", formatTokenCount(p.Tokens))
+	}
+	return line
+}
+
+// formatTokenCount shortens large token counts to "1.2k" or "120k"
+func formatTokenCount(tokens int) string {
+	switch {
+	case tokens >= 10000:
+		return fmt.Sprintf("%dk", tokens/1000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
+
+// ProgressReporter feeds a progress line that stays on screen until Stop
+type ProgressReporter struct {
+	updates chan Progress
+	stopped chan struct{}
+}
+
+// StartProgress shows initial on a spinner line that loaders and scrapers
+// advance through Report
+func (t *Terminal) StartProgress(initial Progress) *ProgressReporter {
+	r := &ProgressReporter{
+		updates: make(chan Progress, 16),
+		stopped: make(chan struct{}),
+	}
+	r.updates <- initial
+	go func() {
+		t.ShowProgress(r.updates)
+		close(r.stopped)
+	}()
+	return r
+}
+
+// Report sends the latest progress to the spinner line
+func (r *ProgressReporter) Report(p Progress) {
+	r.updates <- p
+}
+
+// Stop clears the progress line and waits until it is gone
+func (r *ProgressReporter) Stop() {
+	close(r.updates)
+	<-r.stopped
+}
+
+// ShowProgress displays the latest progress from updates next to a spinner
+// until updates is closed
+func (t *Terminal) ShowProgress(updates <-chan Progress) {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
+		for range updates {
+		}
+		return
+	}
+	out := t.UIWriter()
+	chars := []string{"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷", "⠁", "⠂", "⠄", "⡀", "⢀", "⠠", "⠐", "⠈"}
+	ticker := time.NewTicker(t.redrawInterval())
+	defer ticker.Stop()
+
+	var current Progress
+	i := 0
+	for {
+		ticked := false
+		select {
+		case p, ok := <-updates:
+			if !ok {
+				fmt.Fprint(out, "\r\033[K")
+				return
+			}
+			current = p
+			if p.Note != "" {
+				color := "92"
+				if p.Failed {
+					color = "91"
+				}
+				fmt.Fprintf(out, "\r\033[K\033[%sm%s\033[0m\n", color, p.Note)
+			}
+		case <-ticker.C:
+			i = (i + 1) % len(chars)
+			ticked = true
+		}
+		if t.config.LowBandwidth && !ticked {
+			// Updates wait for the next tick instead of redrawing each time
+			continue
+		}
+		fmt.Fprintf(out, "\r\033[K\033[93m%s %s\033[0m", chars[i], current)
+	}
+}
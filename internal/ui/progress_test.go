@@ -0,0 +1,24 @@
+package ui
+
+import "testing"
+
+func TestProgressString(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress Progress
+		want     string
+	}{
+		{"no tokens", Progress{Verb: "scraped", Unit: "URLs", Done: 2, Total: 5}, "scraped 2/5 URLs"},
+		{"small tokens", Progress{Verb: "loaded", Unit: "files", Done: 1, Total: 3, Tokens: 850}, "loaded 1/3 files, 850 tokens"},
+		{"thousands", Progress{Verb: "loaded", Unit: "files", Done: 3, Total: 9, Tokens: 1240}, "loaded 3/9 files, 1.2k tokens"},
+		{"large tokens", Progress{Verb: "loaded", Unit: "files", Done: 14, Total: 37, Tokens: 120400}, "loaded 14/37 files, 120k tokens"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.progress.String(); got != tt.want {
+				t.Errorf("Progress.String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+)
+
+// recentLoadPrefix marks recently loaded paths at the top of the !l picker
+const recentLoadPrefix = "recent: "
+
+// RecentLoad is one file or directory loaded with !l
+type RecentLoad struct {
+	Time int64  `json:"time"`
+	Path string `json:"path"`
+}
+
+// recentLoadsPath returns the path of the recently loaded paths file
+func recentLoadsPath() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "recent_loads.json"), nil
+}
+
+// LoadRecentLoads reads recently loaded paths, newest first
+func LoadRecentLoads() ([]RecentLoad, error) {
+	path, err := recentLoadsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Recent loads path is resolved under the current user's ~/.ch directory.
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent loads: %w", err)
+	}
+
+	var entries []RecentLoad
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse recent loads: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordRecentLoads moves paths to the top of the recent list, keeping at
+// most recent_loads_size entries. A negative size disables the list.
+func (t *Terminal) RecordRecentLoads(paths []string) error {
+	limit := t.config.RecentLoadsSize
+	if limit <= 0 || len(paths) == 0 {
+		return nil
+	}
+
+	entries, err := LoadRecentLoads()
+	if err != nil {
+		entries = nil // start over rather than failing every load on a corrupt file
+	}
+
+	now := time.Now().Unix()
+	seen := map[string]bool{}
+	var updated []RecentLoad
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil || seen[absPath] {
+			continue
+		}
+		seen[absPath] = true
+		updated = append(updated, RecentLoad{Time: now, Path: absPath})
+	}
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			updated = append(updated, entry)
+		}
+	}
+	if len(updated) > limit {
+		updated = updated[:limit]
+	}
+
+	path, err := recentLoadsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(updated, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recent loads: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// RecentLoadOptions returns picker entries for recently loaded paths that
+// still exist, newest first
+func (t *Terminal) RecentLoadOptions() []string {
+	if t.config.RecentLoadsSize <= 0 {
+		return nil
+	}
+	entries, err := LoadRecentLoads()
+	if err != nil {
+		return nil
+	}
+
+	var options []string
+	for _, entry := range entries {
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			continue
+		}
+		option := recentLoadPrefix + entry.Path
+		if info.IsDir() {
+			option += "/"
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// RecentLoadPath returns the absolute path of a recent picker entry
+func RecentLoadPath(option string) (string, bool) {
+	if !strings.HasPrefix(option, recentLoadPrefix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(option, recentLoadPrefix), "/"), true
+}
+
+// ResolveTypedPath expands ~ in a path typed into a picker and reports
+// whether it names an existing file or directory
+func ResolveTypedPath(query string) (string, bool) {
+	path := expandHomePath(strings.TrimSpace(query))
+	if path == "" {
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
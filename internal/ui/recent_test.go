@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestRecentLoadsKeepNewestExistingPaths(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.md")
+	gone := filepath.Join(dir, "gone.txt")
+	for _, path := range []string{file, gone} {
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	terminal := NewTerminal(&types.Config{RecentLoadsSize: 3})
+	for _, paths := range [][]string{{gone}, {dir}, {file, dir}} {
+		if err := terminal.RecordRecentLoads(paths); err != nil {
+			t.Fatalf("RecordRecentLoads(%v) error: %v", paths, err)
+		}
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{recentLoadPrefix + file, recentLoadPrefix + dir + "/"}
+	got := terminal.RecentLoadOptions()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentLoadOptions() = %v, want %v", got, want)
+	}
+	if path, ok := RecentLoadPath(got[1]); !ok || path != dir {
+		t.Errorf("RecentLoadPath(%q) = %q, %v", got[1], path, ok)
+	}
+	if _, ok := RecentLoadPath("src/main.go"); ok {
+		t.Error("regular picker entries are not recent entries")
+	}
+
+	disabled := NewTerminal(&types.Config{RecentLoadsSize: -1})
+	if options := disabled.RecentLoadOptions(); options != nil {
+		t.Errorf("a negative size should hide recent loads, got %v", options)
+	}
+}
+
+func TestResolveTypedPathExpandsHome(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("USERPROFILE", tempHome)
+	if err := os.Mkdir(filepath.Join(tempHome, "docs"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if path, ok := ResolveTypedPath(" ~/docs "); !ok || path != filepath.Join(tempHome, "docs") {
+		t.Errorf("ResolveTypedPath(~/docs) = %q, %v", path, ok)
+	}
+	if _, ok := ResolveTypedPath("~/missing"); ok {
+		t.Error("missing paths should not resolve")
+	}
+}
+
+func TestFzfMultiSelectOrQueryReturnsUnmatchedQuery(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of fzf")
+	}
+	bin := t.TempDir()
+	// Like fzf --print-query when nothing matches: print the query, exit 1
+	script := "#!/bin/sh\necho '/tmp/typed path'\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "fzf"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	terminal := NewTerminal(&types.Config{})
+	selections, query, err := terminal.FzfMultiSelectOrQuery([]string{"a.go"}, "files: ")
+	if err != nil || len(selections) != 0 || query != "/tmp/typed path" {
+		t.Errorf("got %v, %q, %v", selections, query, err)
+	}
+}
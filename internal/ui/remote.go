@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// remoteMaxBytes caps how much a !remote load reads, so a mistyped path to a
+// log or dump does not flood the context window
+const remoteMaxBytes = 1 << 20
+
+// RemoteTarget is a [user@]host:path argument to !remote. An empty path is
+// the remote home directory.
+type RemoteTarget struct {
+	Host string // [user@]host, resolved through the user's ssh config
+	Path string
+}
+
+// String returns the target in scp form
+func (r RemoteTarget) String() string {
+	return r.Host + ":" + r.Path
+}
+
+// ParseRemoteTarget splits a [user@]host:path argument. The host cannot
+// start with "-", so it is never read by ssh as an option.
+func ParseRemoteTarget(arg string) (RemoteTarget, error) {
+	host, path, ok := strings.Cut(strings.TrimSpace(arg), ":")
+	if !ok || host == "" {
+		return RemoteTarget{}, fmt.Errorf("expected [user@]host:path, got %q", arg)
+	}
+	if strings.HasPrefix(host, "-") || strings.ContainsAny(host, " \t/") {
+		return RemoteTarget{}, fmt.Errorf("invalid host %q", host)
+	}
+	return RemoteTarget{Host: host, Path: path}, nil
+}
+
+// remoteScript is the command run on the remote host: list the path when it
+// is a directory, otherwise print it. A leading ~/ is expanded by the remote
+// shell and the rest of the path is quoted.
+func remoteScript(path string) string {
+	quoted := "\"$HOME\""
+	switch {
+	case path == "" || path == "~":
+	case strings.HasPrefix(path, "~/"):
+		quoted += "/" + shellQuote(path[2:])
+	default:
+		quoted = shellQuote(path)
+	}
+	return fmt.Sprintf("p=%s; if [ -d \"$p\" ]; then ls -la -- \"$p\"; else cat -- \"$p\"; fi", quoted)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// LoadRemote fetches a file, or the listing of a directory, from a remote
+// host over ssh. ssh reads the user's ~/.ssh/config and agent, and owns the
+// terminal while it runs so password and host key prompts work.
+func (t *Terminal) LoadRemote(target RemoteTarget) (string, error) {
+	cmd := exec.Command("ssh", target.Host, remoteScript(target.Path)) // #nosec G204 -- The host is validated not to start with "-" and the remote path is shell quoted.
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	output, err := outputForegroundLimited(cmd, remoteMaxBytes)
+	// Checked first, as ssh fails once the pipe closes on a file over the limit
+	if len(output) > remoteMaxBytes {
+		return "", fmt.Errorf("%s is over the %d byte limit", target, remoteMaxBytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("ssh %s failed: %v", target.Host, err)
+	}
+	text, _, ok := t.decodeText(output)
+	if !ok {
+		return "", fmt.Errorf("%s is not a text file", target)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("File: %s\n", target))
+	result.WriteString(text)
+	result.WriteString("\n\n")
+	return result.String(), nil
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestParseRemoteTarget(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    RemoteTarget
+		wantErr bool
+	}{
+		{arg: "me@box:/etc/hosts", want: RemoteTarget{Host: "me@box", Path: "/etc/hosts"}},
+		{arg: "box:~/notes.md", want: RemoteTarget{Host: "box", Path: "~/notes.md"}},
+		{arg: "box:", want: RemoteTarget{Host: "box"}},
+		{arg: "  box:a:b  ", want: RemoteTarget{Host: "box", Path: "a:b"}},
+		{arg: "", wantErr: true},
+		{arg: "box", wantErr: true},
+		{arg: ":/etc/hosts", wantErr: true},
+		{arg: "-oProxyCommand=x:/etc", wantErr: true},
+		{arg: "a b:/etc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRemoteTarget(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseRemoteTarget(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseRemoteTarget(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestRemoteScriptQuotesPath(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		home string
+		want string
+	}{
+		{path: "it's $HOME; rm -rf", home: dir, want: "it's $HOME; rm -rf"},
+		{path: "~/a b", home: dir, want: dir + "/a b"},
+		{path: "", home: dir, want: dir},
+	}
+	for _, tt := range tests {
+		// Replace the listing and printing with an echo of the resolved path
+		script := remoteScript(tt.path)
+		script = "ls() { shift; shift; printf %s \"$1\"; }; cat() { shift; printf %s \"$1\"; }; " + script
+		cmd := exec.Command("sh", "-c", script)
+		cmd.Env = []string{"HOME=" + tt.home}
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("remoteScript(%q) failed: %v", tt.path, err)
+		}
+		if string(output) != tt.want {
+			t.Errorf("remoteScript(%q) resolved %q, want %q", tt.path, output, tt.want)
+		}
+	}
+}
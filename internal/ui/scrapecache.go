@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// fetchedPage is the body, content type, and validators of a fetched page
+type fetchedPage struct {
+	ETag         string
+	LastModified string
+	ContentType  string
+	Body         []byte
+}
+
+// fetchPage sends req and returns the response body and headers. Repeat fetches
+// of a URL send If-None-Match and If-Modified-Since from the earlier response
+// and reuse its body when the server answers 304 Not Modified.
+func (t *Terminal) fetchPage(client *http.Client, req *http.Request) (fetchedPage, error) {
+	key := req.URL.String()
+
+	t.scrapeCacheMu.Lock()
+	cached, hasCached := t.scrapeCache[key]
+	t.scrapeCacheMu.Unlock()
+
+	if hasCached {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fetchedPage{}, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fetchedPage{}, fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fetchedPage{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	page := fetchedPage{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         body,
+	}
+	if page.ETag != "" || page.LastModified != "" {
+		t.scrapeCacheMu.Lock()
+		if t.scrapeCache == nil {
+			t.scrapeCache = make(map[string]fetchedPage)
+		}
+		t.scrapeCache[key] = page
+		t.scrapeCacheMu.Unlock()
+	}
+
+	return page, nil
+}
+
+// formatLastModified renders a Last-Modified header for the scraped context header,
+// falling back to the raw value when it is not a valid HTTP date
+func formatLastModified(header string) string {
+	modified, err := http.ParseTime(header)
+	if err != nil {
+		return header
+	}
+	return modified.UTC().Format(time.DateTime) + " UTC"
+}
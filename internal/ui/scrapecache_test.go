@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestScrapeRevalidatesCachedPage(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Wed, 21 Oct 2026 07:28:00 GMT"
+
+	var conditional, fullFetches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullFetches++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte("<html><body><p>cached page</p></body></html>"))
+	}))
+	defer server.Close()
+
+	terminal := NewTerminal(&types.Config{IsPipedOutput: true})
+	first, err := terminal.scrapeURLInternal(server.URL, "text")
+	if err != nil {
+		t.Fatalf("first scrape failed: %v", err)
+	}
+	second, err := terminal.scrapeURLInternal(server.URL, "text")
+	if err != nil {
+		t.Fatalf("second scrape failed: %v", err)
+	}
+
+	if fullFetches != 1 || conditional != 1 {
+		t.Errorf("full fetches = %d, conditional = %d, want 1 and 1", fullFetches, conditional)
+	}
+	if first != second {
+		t.Errorf("304 response should reuse the cached page:\nfirst:  %q\nsecond: %q", first, second)
+	}
+	if !strings.Contains(first, "Last modified: 2026-10-21 07:28:00 UTC") {
+		t.Errorf("missing last-modified header in %q", first)
+	}
+}
+
+func TestFormatLastModifiedFallsBackToRawValue(t *testing.T) {
+	if got := formatLastModified("last tuesday"); got != "last tuesday" {
+		t.Errorf("formatLastModified = %q, want raw value", got)
+	}
+}
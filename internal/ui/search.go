@@ -0,0 +1,435 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MehmetMHY/ch/internal/config"
+	"golang.org/x/net/html"
+)
+
+// errBraveQuotaExceeded is returned when the Brave API rejects a request for rate or quota limits
+var errBraveQuotaExceeded = errors.New("brave search quota or rate limit exceeded")
+
+// searchUsageMu serializes updates to the search usage file, so searches
+// running at once, such as parallel tool calls, never lose a count
+var searchUsageMu sync.Mutex
+
+// SearchUsage tracks search API requests per provider and month (YYYY-MM)
+type SearchUsage struct {
+	Providers map[string]map[string]int `json:"providers"`
+}
+
+// searchUsagePath returns the path of the persistent search usage file
+func searchUsagePath() (string, error) {
+	chDir, err := config.GetChDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(chDir, "search_usage.json"), nil
+}
+
+// LoadSearchUsage reads the search usage file, returning empty usage if it does not exist
+func LoadSearchUsage() (*SearchUsage, error) {
+	usage := &SearchUsage{Providers: map[string]map[string]int{}}
+
+	path, err := searchUsagePath()
+	if err != nil {
+		return usage, err
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Usage file path is resolved under the current user's ~/.ch directory.
+	if os.IsNotExist(err) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, fmt.Errorf("failed to read search usage: %w", err)
+	}
+
+	if err := json.Unmarshal(data, usage); err != nil {
+		return &SearchUsage{Providers: map[string]map[string]int{}}, fmt.Errorf("failed to parse search usage: %w", err)
+	}
+	if usage.Providers == nil {
+		usage.Providers = map[string]map[string]int{}
+	}
+	return usage, nil
+}
+
+// Save writes the search usage file through a temp file and rename, so a
+// reader never sees a partly written file
+func (u *SearchUsage) Save() error {
+	path, err := searchUsagePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode search usage: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write search usage: %w", err)
+	}
+	tempPath := temp.Name()
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to write search usage: %w", err)
+	}
+	return nil
+}
+
+// recordSearch counts one request to a provider in the usage file and
+// returns the new count. The file is reloaded under searchUsageMu, so
+// requests made since the caller last loaded it are kept.
+func recordSearch(provider, month string) (int, error) {
+	searchUsageMu.Lock()
+	defer searchUsageMu.Unlock()
+
+	// An unreadable file starts over from zero, as it did when loaded
+	usage, _ := LoadSearchUsage()
+	used := usage.Increment(provider, month)
+	return used, usage.Save()
+}
+
+// Count returns the number of requests made to a provider in the given month
+func (u *SearchUsage) Count(provider, month string) int {
+	return u.Providers[provider][month]
+}
+
+// Increment records one request to a provider in the given month and returns the new count
+func (u *SearchUsage) Increment(provider, month string) int {
+	if u.Providers[provider] == nil {
+		u.Providers[provider] = map[string]int{}
+	}
+	u.Providers[provider][month]++
+	return u.Providers[provider][month]
+}
+
+// usageMonth returns the usage bucket key for a point in time
+func usageMonth(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+// braveQuotaWarning returns a warning when usage reached the configured warning threshold
+func braveQuotaWarning(used, quota, warnPercent int) string {
+	if quota <= 0 || warnPercent <= 0 || used*100 < quota*warnPercent {
+		return ""
+	}
+	return fmt.Sprintf("warning: brave search usage at %d/%d requests this month", used, quota)
+}
+
+// searchBackends are the search providers search_backend and
+// search_fallback can name besides "brave", which is handled on its own
+// for its API key and quota
+var searchBackends = map[string]func(t *Terminal, query string) ([]BraveWebResult, error){
+	"duckduckgo": (*Terminal).duckDuckGoSearch,
+	"searxng":    (*Terminal).searxngSearch,
+}
+
+// usesBrave reports whether searches go to Brave first
+func (t *Terminal) usesBrave() bool {
+	return t.config.SearchBackend == "" || t.config.SearchBackend == "brave"
+}
+
+// searchWithFallback searches with search_backend while tracking monthly usage,
+// switching to the configured search_fallback provider when the backend fails,
+// or for Brave when it is unavailable or out of quota.
+// Warnings are returned so they can be printed after the loading animation stops.
+func (t *Terminal) searchWithFallback(query string) ([]BraveWebResult, []string, error) {
+	var warnings []string
+	month := usageMonth(time.Now())
+
+	usage, err := LoadSearchUsage()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("warning: %v", err))
+	}
+
+	backend := t.config.SearchBackend
+	apiKey := os.Getenv("BRAVE_API_KEY")
+	reason := ""
+	if !t.usesBrave() {
+		if _, ok := searchBackends[backend]; !ok {
+			return nil, warnings, fmt.Errorf("unknown search_backend provider: %s", backend)
+		}
+		results, warning, err := t.searchWith(backend, query, month)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		if err == nil {
+			return results, warnings, nil
+		}
+		reason = fmt.Sprintf("%s search failed: %v", backend, err)
+	} else if apiKey == "" {
+		reason = "the BRAVE_API_KEY environment variable is not set"
+	} else if quota := t.config.BraveMonthlyQuota; quota > 0 && usage.Count("brave", month) >= quota {
+		reason = fmt.Sprintf("brave search quota exhausted (%d/%d requests this month)", usage.Count("brave", month), quota)
+	}
+
+	if reason == "" {
+		results, err := t.braveSearch(query, apiKey)
+		if err == nil || errors.Is(err, errBraveQuotaExceeded) {
+			// Rejected requests still count against the Brave quota
+			used, saveErr := recordSearch("brave", month)
+			if saveErr != nil {
+				warnings = append(warnings, fmt.Sprintf("warning: failed to save search usage: %v", saveErr))
+			}
+			if warning := braveQuotaWarning(used, t.config.BraveMonthlyQuota, t.config.BraveQuotaWarnPercent); warning != "" && err == nil {
+				warnings = append(warnings, warning)
+			}
+		}
+		if err == nil {
+			return results, warnings, nil
+		}
+		if !errors.Is(err, errBraveQuotaExceeded) {
+			return nil, warnings, err
+		}
+		reason = err.Error()
+	}
+
+	fallback := t.config.SearchFallback
+	if fallback == "" || fallback == backend {
+		return nil, warnings, errors.New(reason)
+	}
+	if _, ok := searchBackends[fallback]; !ok {
+		return nil, warnings, fmt.Errorf("unknown search_fallback provider: %s", fallback)
+	}
+	// A missing Brave key with a fallback set is a keyless setup, not a failure
+	if !t.usesBrave() || apiKey != "" {
+		warnings = append(warnings, fmt.Sprintf("warning: %s, falling back to %s", reason, fallback))
+	}
+
+	results, warning, err := t.searchWith(fallback, query, month)
+	if warning != "" {
+		warnings = append(warnings, warning)
+	}
+	if err != nil {
+		return nil, warnings, err
+	}
+	return results, warnings, nil
+}
+
+// searchWith searches with one of searchBackends and counts the request in
+// the usage file, returning a warning when usage could not be saved
+func (t *Terminal) searchWith(provider, query, month string) ([]BraveWebResult, string, error) {
+	results, err := searchBackends[provider](t, query)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, saveErr := recordSearch(provider, month); saveErr != nil {
+		return results, fmt.Sprintf("warning: failed to save search usage: %v", saveErr), nil
+	}
+	return results, "", nil
+}
+
+// braveSearch queries the Brave Search API
+func (t *Terminal) braveSearch(query, apiKey string) ([]BraveWebResult, error) {
+	req, err := http.NewRequest("GET", "https://api.search.brave.com/res/v1/web/search", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+
+	q := req.URL.Query()
+	q.Add("q", query)
+	q.Add("count", fmt.Sprintf("%d", t.config.NumSearchResults))
+	q.Add("country", t.config.SearchCountry)
+	q.Add("search_lang", t.config.SearchLang)
+	req.URL.RawQuery = q.Encode()
+
+	req.Header.Set("X-Subscription-Token", apiKey)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusPaymentRequired {
+		return nil, errBraveQuotaExceeded
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search request failed with status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read search response: %w", err)
+	}
+
+	var braveResult BraveSearchResult
+	if err := json.Unmarshal(body, &braveResult); err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	return braveResult.Web.Results, nil
+}
+
+// duckDuckGoSearch queries the DuckDuckGo HTML endpoint, which needs no API key
+func (t *Terminal) duckDuckGoSearch(query string) ([]BraveWebResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if t.config.SearchCountry != "" && t.config.SearchLang != "" {
+		params.Set("kl", strings.ToLower(t.config.SearchCountry)+"-"+strings.ToLower(t.config.SearchLang))
+	}
+
+	req, err := http.NewRequest("GET", "https://html.duckduckgo.com/html/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search request failed with status: %s", resp.Status)
+	}
+
+	return parseDuckDuckGoResults(resp.Body, t.config.NumSearchResults)
+}
+
+// parseDuckDuckGoResults extracts up to limit results from a DuckDuckGo HTML results page
+func parseDuckDuckGoResults(body io.Reader, limit int) ([]BraveWebResult, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	hasClass := func(n *html.Node, class string) bool {
+		for _, name := range strings.Fields(htmlAttr(n, "class")) {
+			if name == class {
+				return true
+			}
+		}
+		return false
+	}
+
+	var results []BraveWebResult
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" && hasClass(n, "result__a") {
+			if limit > 0 && len(results) >= limit {
+				return
+			}
+			results = append(results, BraveWebResult{
+				Title: cleanMarkdownInline(htmlTextContent(n)),
+				URL:   duckDuckGoTargetURL(htmlAttr(n, "href")),
+			})
+			return
+		}
+		if n.Type == html.ElementNode && hasClass(n, "result__snippet") && len(results) > 0 && results[len(results)-1].Description == "" {
+			results[len(results)-1].Description = cleanMarkdownInline(htmlTextContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(doc)
+
+	return results, nil
+}
+
+// duckDuckGoTargetURL unwraps DuckDuckGo's redirect links to the destination URL
+func duckDuckGoTargetURL(href string) string {
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	if target := parsed.Query().Get("uddg"); target != "" {
+		return target
+	}
+	return href
+}
+
+// searxngSearch queries the JSON API of the SearXNG instance at searxng_url,
+// which needs "json" listed under search.formats in its settings.yml
+func (t *Terminal) searxngSearch(query string) ([]BraveWebResult, error) {
+	baseURL := strings.TrimRight(t.config.SearXNGURL, "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("searxng_url is not set in ~/.ch/config.json")
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	if t.config.SearchLang != "" {
+		params.Set("language", t.config.SearchLang)
+	}
+
+	req, err := http.NewRequest("GET", baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req) // #nosec G704 -- The SearXNG URL is the user's own configured instance.
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform search: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("searxng refused the JSON format: add json to search.formats in the instance's settings.yml")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search request failed with status: %s", resp.Status)
+	}
+
+	return parseSearXNGResults(resp.Body, t.config.NumSearchResults)
+}
+
+// parseSearXNGResults reads up to limit results from a SearXNG JSON response
+func parseSearXNGResults(body io.Reader, limit int) ([]BraveWebResult, error) {
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+
+	var results []BraveWebResult
+	for _, result := range response.Results {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		if result.URL == "" {
+			continue
+		}
+		results = append(results, BraveWebResult{
+			Title:       cleanMarkdownInline(result.Title),
+			URL:         result.URL,
+			Description: cleanMarkdownInline(result.Content),
+		})
+	}
+	return results, nil
+}
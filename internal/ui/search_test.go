@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+const testDuckDuckGoHTML = `<html><body>
+<div class="result results_links">
+  <h2 class="result__title">
+    <a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The Go <b>Documentation</b></a>
+  </h2>
+  <a class="result__snippet" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F">Official   docs for the Go language.</a>
+</div>
+<div class="result results_links">
+  <h2 class="result__title"><a class="result__a" href="https://example.com/direct">Direct link</a></h2>
+  <div class="result__snippet">Second snippet</div>
+</div>
+<div class="result results_links">
+  <h2 class="result__title"><a class="result__a" href="https://example.com/third">Third</a></h2>
+</div>
+</body></html>`
+
+func TestParseDuckDuckGoResults(t *testing.T) {
+	results, err := parseDuckDuckGoResults(strings.NewReader(testDuckDuckGoHTML), 2)
+	if err != nil {
+		t.Fatalf("parseDuckDuckGoResults returned error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results (limit), got %d: %+v", len(results), results)
+	}
+
+	if results[0].Title != "The Go Documentation" {
+		t.Errorf("title = %q", results[0].Title)
+	}
+	if results[0].URL != "https://go.dev/doc/" {
+		t.Errorf("redirect URL was not unwrapped: %q", results[0].URL)
+	}
+	if results[0].Description != "Official docs for the Go language." {
+		t.Errorf("description = %q", results[0].Description)
+	}
+	if results[1].URL != "https://example.com/direct" || results[1].Description != "Second snippet" {
+		t.Errorf("unexpected second result: %+v", results[1])
+	}
+}
+
+func TestSearchUsageRoundTrip(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	usage, err := LoadSearchUsage()
+	if err != nil {
+		t.Fatalf("LoadSearchUsage on empty home returned error: %v", err)
+	}
+	month := usageMonth(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	if month != "2026-10" {
+		t.Fatalf("usageMonth = %q, want 2026-10", month)
+	}
+
+	usage.Increment("brave", month)
+	if got := usage.Increment("brave", month); got != 2 {
+		t.Errorf("Increment returned %d, want 2", got)
+	}
+	if err := usage.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpHome, ".ch", "search_usage.json")); err != nil {
+		t.Fatalf("usage file not written: %v", err)
+	}
+
+	reloaded, err := LoadSearchUsage()
+	if err != nil {
+		t.Fatalf("LoadSearchUsage returned error: %v", err)
+	}
+	if got := reloaded.Count("brave", month); got != 2 {
+		t.Errorf("reloaded count = %d, want 2", got)
+	}
+	if got := reloaded.Count("brave", "2026-09"); got != 0 {
+		t.Errorf("other month count = %d, want 0", got)
+	}
+}
+
+func TestRecordSearchKeepsConcurrentCounts(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	month := usageMonth(time.Now())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := recordSearch("brave", month); err != nil {
+				t.Errorf("recordSearch returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := LoadSearchUsage()
+	if err != nil {
+		t.Fatalf("LoadSearchUsage returned error: %v", err)
+	}
+	if got := usage.Count("brave", month); got != 20 {
+		t.Errorf("brave usage = %d, want 20", got)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(tmpHome, ".ch", "*.tmp")); len(leftovers) != 0 {
+		t.Errorf("temp files left behind: %q", leftovers)
+	}
+}
+
+func TestBraveQuotaWarning(t *testing.T) {
+	tests := []struct {
+		used, quota, percent int
+		want                 bool
+	}{
+		{used: 10, quota: 0, percent: 80, want: false},
+		{used: 79, quota: 100, percent: 80, want: false},
+		{used: 80, quota: 100, percent: 80, want: true},
+		{used: 100, quota: 100, percent: 0, want: false},
+	}
+	for _, tt := range tests {
+		got := braveQuotaWarning(tt.used, tt.quota, tt.percent) != ""
+		if got != tt.want {
+			t.Errorf("braveQuotaWarning(%d, %d, %d) warned = %v, want %v", tt.used, tt.quota, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearXNGResults(t *testing.T) {
+	body := `{"query":"go","results":[
+		{"title":"The Go   Programming Language","url":"https://go.dev/","content":"Build simple,\n secure software."},
+		{"title":"No URL","content":"skipped"},
+		{"title":"Go docs","url":"https://go.dev/doc/","content":""},
+		{"title":"Third","url":"https://example.com/"}
+	]}`
+	results, err := parseSearXNGResults(strings.NewReader(body), 2)
+	if err != nil {
+		t.Fatalf("parseSearXNGResults returned error: %v", err)
+	}
+	want := []BraveWebResult{
+		{Title: "The Go Programming Language", URL: "https://go.dev/", Description: "Build simple, secure software."},
+		{Title: "Go docs", URL: "https://go.dev/doc/"},
+	}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+
+	if _, err := parseSearXNGResults(strings.NewReader("<html>"), 5); err == nil {
+		t.Error("expected an error for a non-JSON response")
+	}
+}
+
+func TestSearchBackendSearXNG(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		if r.URL.Path != "/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"results":[{"title":"Go","url":"https://go.dev/","content":"The Go language"}]}`)
+	}))
+	defer server.Close()
+
+	terminal := NewTerminal(&types.Config{SearchBackend: "searxng", SearXNGURL: server.URL + "/", NumSearchResults: 5, SearchLang: "en"})
+	results, warnings, err := terminal.searchWithFallback("golang")
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("searchWithFallback() error = %v, warnings = %q", err, warnings)
+	}
+	if len(results) != 1 || results[0].URL != "https://go.dev/" {
+		t.Errorf("results = %+v", results)
+	}
+	if gotQuery != "format=json&language=en&q=golang" {
+		t.Errorf("query = %q", gotQuery)
+	}
+	usage, _ := LoadSearchUsage()
+	if got := usage.Count("searxng", usageMonth(time.Now())); got != 1 {
+		t.Errorf("searxng usage = %d, want 1", got)
+	}
+}
+
+func TestSearchBackendFallback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	original := searchBackends["duckduckgo"]
+	defer func() { searchBackends["duckduckgo"] = original }()
+	searchBackends["duckduckgo"] = func(t *Terminal, query string) ([]BraveWebResult, error) {
+		return []BraveWebResult{{Title: query, URL: "https://duckduckgo.com/"}}, nil
+	}
+
+	// searxng_url is missing, so the SearXNG search fails and DuckDuckGo answers
+	terminal := NewTerminal(&types.Config{SearchBackend: "searxng", SearchFallback: "duckduckgo"})
+	results, warnings, err := terminal.searchWithFallback("golang")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("searchWithFallback() = %+v, %v", results, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "searxng_url is not set") || !strings.Contains(warnings[0], "falling back to duckduckgo") {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	terminal = NewTerminal(&types.Config{SearchBackend: "searxng"})
+	if _, _, err := terminal.searchWithFallback("golang"); err == nil || !strings.Contains(err.Error(), "searxng search failed") {
+		t.Errorf("error without a fallback = %v", err)
+	}
+	terminal = NewTerminal(&types.Config{SearchBackend: "bing"})
+	if _, _, err := terminal.searchWithFallback("golang"); err == nil || !strings.Contains(err.Error(), "unknown search_backend provider: bing") {
+		t.Errorf("unknown backend error = %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// entireFileOption is the picker entry that loads the whole document
+const entireFileOption = "[entire file]"
+
+// structuredNode is one selectable subtree of a JSON or YAML document,
+// addressed by a jq-like path (.users[0].name). start and end are byte
+// offsets for JSON and line indexes for YAML.
+type structuredNode struct {
+	path       string
+	summary    string
+	start, end int
+}
+
+// isStructuredFile reports whether path is a JSON or YAML file
+func isStructuredFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// jsonPathKey appends an object key to a path, quoting keys that are not
+// plain identifiers the way jq does
+func jsonPathKey(path, key string) string {
+	if regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`).MatchString(key) {
+		return path + "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return path + "." + string(quoted)
+}
+
+// jsonPathIndex appends an array index to a path, as .[0] at the root
+func jsonPathIndex(path string, index int) string {
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+// jsonNodes lists every value in a JSON document below the root in document
+// order, recording where each one starts and ends in data
+func jsonNodes(data []byte) ([]structuredNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var nodes []structuredNode
+
+	// walk reads one value at path and returns its start offset
+	var walk func(path string) error
+	walk = func(path string) error {
+		before := int(dec.InputOffset())
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		// The offset before the token includes the separator and spaces
+		start := before + len(data[before:dec.InputOffset()]) - len(bytes.TrimLeft(data[before:dec.InputOffset()], " \t\r\n:,"))
+
+		index := -1
+		if path != "" {
+			nodes = append(nodes, structuredNode{path: path, start: start})
+			index = len(nodes) - 1
+		}
+
+		summary := ""
+		switch token {
+		case json.Delim('{'):
+			keys := 0
+			for dec.More() {
+				keyToken, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyToken.(string)
+				if err := walk(jsonPathKey(path, key)); err != nil {
+					return err
+				}
+				keys++
+			}
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+			summary = fmt.Sprintf("object, %d keys", keys)
+		case json.Delim('['):
+			items := 0
+			for dec.More() {
+				if err := walk(jsonPathIndex(path, items)); err != nil {
+					return err
+				}
+				items++
+			}
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+			summary = fmt.Sprintf("array, %d items", items)
+		default:
+			raw, _ := json.Marshal(token)
+			summary = string(raw)
+			if len(summary) > 60 {
+				summary = summary[:60] + "..."
+			}
+		}
+
+		if index >= 0 {
+			nodes[index].end = int(dec.InputOffset())
+			nodes[index].summary = summary
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return nodes, nil
+}
+
+// yamlKeyPattern matches a mapping key line, optionally as a list item
+var yamlKeyPattern = regexp.MustCompile(`^( *)(- +)?("[^"]*"|'[^']*'|[^\s#'"-][^:#]*?) *:( |$)`)
+
+// yamlNodes lists the mapping keys of a YAML document by indentation. A key
+// owns the lines below it that are indented further, plus a block sequence
+// at its own indent, and a list item that starts with a key (- name: x) is
+// addressed as [i] under its parent. Flow style and anchors are not
+// understood; those keys still select their indented lines.
+func yamlNodes(data []byte) []structuredNode {
+	lines := strings.Split(string(data), "\n")
+	type frame struct {
+		col   int // column of the key, or of the dash for a list item
+		path  string
+		item  bool
+		items int // list items seen directly under this key
+	}
+	var stack []frame
+	var nodes []structuredNode
+	cols := map[int]int{} // node index to the column its lines must be indented past
+	emptyValue := map[int]bool{}
+
+	for i, line := range lines {
+		match := yamlKeyPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		col := len(match[1])
+		if match[2] != "" {
+			// A new list item closes the previous item at the same column
+			for len(stack) > 0 && (stack[len(stack)-1].col > col || (stack[len(stack)-1].col == col && stack[len(stack)-1].item)) {
+				stack = stack[:len(stack)-1]
+			}
+			parent := frame{col: -1}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+				stack[len(stack)-1].items++
+			}
+			itemPath := jsonPathIndex(parent.path, parent.items)
+			nodes = append(nodes, structuredNode{path: itemPath, summary: "item", start: i})
+			cols[len(nodes)-1] = col
+			stack = append(stack, frame{col: col, path: itemPath, item: true})
+			col += len(match[2])
+		}
+		for len(stack) > 0 && stack[len(stack)-1].col >= col {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := ""
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].path
+		}
+		path := jsonPathKey(parent, strings.Trim(match[3], `"'`))
+		summary := strings.TrimSpace(line[len(match[0]):])
+		if summary == "" {
+			summary = "block"
+		} else if len(summary) > 60 {
+			summary = summary[:60] + "..."
+		}
+		nodes = append(nodes, structuredNode{path: path, summary: summary, start: i})
+		cols[len(nodes)-1] = col
+		emptyValue[len(nodes)-1] = summary == "block"
+		stack = append(stack, frame{col: col, path: path})
+	}
+
+	for index := range nodes {
+		node := &nodes[index]
+		node.end = node.start + 1
+		for j := node.start + 1; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			indent := len(lines[j]) - len(strings.TrimLeft(lines[j], " "))
+			sequence := emptyValue[index] && indent == cols[index] && strings.HasPrefix(trimmed, "-")
+			if indent <= cols[index] && !sequence {
+				break
+			}
+			node.end = j + 1
+		}
+	}
+	return nodes
+}
+
+// structuredSubtree returns the text of node: indented JSON, or the YAML
+// lines as written
+func structuredSubtree(data []byte, node structuredNode, isJSON bool) string {
+	if !isJSON {
+		lines := strings.Split(string(data), "\n")
+		return strings.Join(lines[node.start:node.end], "\n")
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data[node.start:node.end], "", "  "); err != nil {
+		return string(data[node.start:node.end])
+	}
+	return out.String()
+}
+
+// SelectStructuredSubtrees offers a jq-like path picker for each JSON or YAML
+// file of at least structured_select_bytes, so only the chosen subtrees are
+// loaded. It returns the content of files where paths were picked; files
+// loaded whole (picked [entire file], cancelled, or small) are left to the
+// caller.
+func (t *Terminal) SelectStructuredSubtrees(paths []string) (map[string]string, error) {
+	selected := map[string]string{}
+	threshold := t.config.StructuredSelectBytes
+	if threshold < 0 {
+		return selected, nil
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || !isStructuredFile(path) || info.Size() < int64(threshold) {
+			continue
+		}
+		data, err := os.ReadFile(path) // #nosec G304 -- Loading a user-selected file path is core CLI behavior.
+		if err != nil {
+			return nil, err
+		}
+
+		isJSON := strings.ToLower(filepath.Ext(path)) == ".json"
+		var nodes []structuredNode
+		if isJSON {
+			if nodes, err = jsonNodes(data); err != nil {
+				continue // loaded whole, so the model still sees the broken document
+			}
+		} else {
+			nodes = yamlNodes(data)
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		items := []string{entireFileOption}
+		byLine := make(map[string]structuredNode, len(nodes))
+		for _, node := range nodes {
+			line := fmt.Sprintf("%s  (%s)", node.path, node.summary)
+			if _, seen := byLine[line]; !seen {
+				items = append(items, line)
+				byLine[line] = node
+			}
+		}
+		picks, err := t.FzfMultiSelect(items, fmt.Sprintf("%s paths: ", filepath.Base(path)))
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		var content strings.Builder
+		for _, pick := range picks {
+			node, ok := byLine[pick]
+			if !ok {
+				names = nil // [entire file]
+				break
+			}
+			names = append(names, node.path)
+			content.WriteString(fmt.Sprintf("%s:\n%s\n\n", node.path, structuredSubtree(data, node, isJSON)))
+		}
+		if len(names) > 0 {
+			selected[path] = fmt.Sprintf("File: %s (paths: %s)\n%s", path, strings.Join(names, ", "), content.String())
+		}
+	}
+	return selected, nil
+}
+
+// LoadFileContentWithPicker loads selections like LoadFileContent, first
+// offering the path picker for large JSON and YAML files. It is used by !l,
+// where a person is there to pick; other loaders read whole files.
+func (t *Terminal) LoadFileContentWithPicker(selections []string) (string, error) {
+	picked, err := t.SelectStructuredSubtrees(selections)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	var rest []string
+	for _, path := range selections {
+		if content, ok := picked[path]; ok {
+			sb.WriteString(content)
+		} else {
+			rest = append(rest, path)
+		}
+	}
+	if len(rest) > 0 {
+		content, err := t.LoadFileContent(rest)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(content)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestJSONNodes(t *testing.T) {
+	data := []byte(`{
+  "users": [
+    {"name": "ada", "admin": true},
+    {"name": "bob"}
+  ],
+  "odd key": 3
+}`)
+	nodes, err := jsonNodes(data)
+	if err != nil {
+		t.Fatalf("jsonNodes() error: %v", err)
+	}
+
+	want := []struct{ path, summary, text string }{
+		{".users", "array, 2 items", ""},
+		{".users[0]", "object, 2 keys", `{"name": "ada", "admin": true}`},
+		{".users[0].name", `"ada"`, `"ada"`},
+		{".users[0].admin", "true", "true"},
+		{".users[1]", "object, 1 keys", `{"name": "bob"}`},
+		{".users[1].name", `"bob"`, `"bob"`},
+		{`."odd key"`, "3", "3"},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d: %+v", len(nodes), len(want), nodes)
+	}
+	for i, w := range want {
+		node := nodes[i]
+		if node.path != w.path || node.summary != w.summary {
+			t.Errorf("node %d = %q (%s), want %q (%s)", i, node.path, node.summary, w.path, w.summary)
+		}
+		if w.text != "" && string(data[node.start:node.end]) != w.text {
+			t.Errorf("node %s text = %q, want %q", node.path, data[node.start:node.end], w.text)
+		}
+	}
+
+	if _, err := jsonNodes([]byte(`{"a": `)); err == nil {
+		t.Error("jsonNodes() accepted truncated JSON")
+	}
+	rootArray, _ := jsonNodes([]byte(`[1, 2]`))
+	if len(rootArray) != 2 || rootArray[1].path != ".[1]" {
+		t.Errorf("root array nodes = %+v, want .[0] and .[1]", rootArray)
+	}
+}
+
+func TestYAMLNodes(t *testing.T) {
+	data := []byte(`server:
+  host: example.com
+  ports:
+  - 80
+  - 443
+users:
+  - name: ada
+    roles:
+      - admin
+  - name: bob
+# trailing comment
+debug: false`)
+	lines := strings.Split(string(data), "\n")
+	nodes := yamlNodes(data)
+
+	want := map[string]string{
+		".server":         "server:\n  host: example.com\n  ports:\n  - 80\n  - 443",
+		".server.host":    "  host: example.com",
+		".server.ports":   "  ports:\n  - 80\n  - 443",
+		".users":          "users:\n  - name: ada\n    roles:\n      - admin\n  - name: bob",
+		".users[0]":       "  - name: ada\n    roles:\n      - admin",
+		".users[0].name":  "  - name: ada",
+		".users[0].roles": "    roles:\n      - admin",
+		".users[1]":       "  - name: bob",
+		".users[1].name":  "  - name: bob",
+		".debug":          "debug: false",
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d: %+v", len(nodes), len(want), nodes)
+	}
+	for _, node := range nodes {
+		text, ok := want[node.path]
+		if !ok {
+			t.Errorf("unexpected node %q", node.path)
+			continue
+		}
+		if got := strings.Join(lines[node.start:node.end], "\n"); got != text {
+			t.Errorf("node %s text = %q, want %q", node.path, got, text)
+		}
+	}
+}
+
+func TestSelectStructuredSubtreesSkipsSmallFiles(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.json")
+	if err := os.WriteFile(small, []byte(`{"a": 1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files under the threshold, and every file when it is -1, load whole
+	// without opening the picker
+	for _, threshold := range []int{1024, -1} {
+		terminal := NewTerminal(&types.Config{StructuredSelectBytes: threshold})
+		picked, err := terminal.SelectStructuredSubtrees([]string{small, filepath.Join(dir, "notes.txt")})
+		if err != nil {
+			t.Fatalf("SelectStructuredSubtrees() error: %v", err)
+		}
+		if len(picked) != 0 {
+			t.Errorf("threshold %d picked %v, want nothing", threshold, picked)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"sync/atomic"
+)
+
+// foregroundChildren counts running subprocesses that own the terminal
+// (fzf, editors, script). They share ch's process group, so Ctrl+C reaches
+// them directly and ch's own SIGINT handler must leave it to them.
+var foregroundChildren atomic.Int32
+
+// ForegroundChildRunning reports whether a picker, editor, or recorded shell
+// currently owns the terminal, meaning a SIGINT is meant for it and not ch
+func ForegroundChildRunning() bool {
+	return foregroundChildren.Load() > 0
+}
+
+// RunForeground runs an interactive subprocess that takes over the terminal.
+// Ctrl+C is handled by the subprocess, and the terminal state saved before it
+// started is restored afterwards, even if it crashed and left the terminal raw
+// or the cursor hidden.
+func RunForeground(cmd *exec.Cmd) error {
+	state := SaveTerminalState()
+	foregroundChildren.Add(1)
+	defer func() {
+		foregroundChildren.Add(-1)
+		state.Restore()
+	}()
+	return cmd.Run()
+}
+
+// OutputForeground is RunForeground for subprocesses whose stdout is the
+// result, such as fzf selections
+func OutputForeground(cmd *exec.Cmd) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	err := RunForeground(cmd)
+	return output.Bytes(), err
+}
+
+// outputForegroundLimited is OutputForeground reading at most limit+1 bytes
+// of stdout, so a caller can tell output went over limit without holding all
+// of it. Past that the pipe is closed, which stops a writer like cat.
+func outputForegroundLimited(cmd *exec.Cmd, limit int) ([]byte, error) {
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	read := make(chan []byte, 1)
+	go func() {
+		output, _ := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+		_ = reader.Close()
+		read <- output
+	}()
+	err := RunForeground(cmd)
+	_ = writer.Close()
+	return <-read, err
+}
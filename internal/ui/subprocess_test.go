@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRunForegroundMarksChildRunning(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- RunForeground(exec.Command("sleep", "0.3")) }()
+
+	deadline := time.Now().Add(time.Second)
+	for !ForegroundChildRunning() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !ForegroundChildRunning() {
+		t.Fatal("ForegroundChildRunning should be true while the subprocess runs")
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("RunForeground: %v", err)
+	}
+	if ForegroundChildRunning() {
+		t.Error("ForegroundChildRunning should be false after the subprocess exits")
+	}
+}
+
+func TestOutputForegroundCapturesStdout(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+
+	output, err := OutputForeground(exec.Command("echo", "picked"))
+	if err != nil {
+		t.Fatalf("OutputForeground: %v", err)
+	}
+	if string(output) != "picked\n" {
+		t.Errorf("output = %q, want %q", output, "picked\n")
+	}
+}
+
+func TestOutputForegroundLimitedStopsAtLimit(t *testing.T) {
+	if _, err := exec.LookPath("yes"); err != nil {
+		t.Skip("yes not available")
+	}
+
+	// yes never ends on its own, so this only returns if the limit stops it
+	output, _ := outputForegroundLimited(exec.Command("yes"), 1000)
+	if len(output) != 1001 {
+		t.Errorf("read %d bytes, want 1001", len(output))
+	}
+
+	output, err := outputForegroundLimited(exec.Command("echo", "short"), 1000)
+	if err != nil || string(output) != "short\n" {
+		t.Errorf("output = %q, %v, want %q", output, err, "short\n")
+	}
+}
@@ -0,0 +1,268 @@
+package ui
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tealeg/xlsx/v3"
+)
+
+// tabularSampleRows is how many data rows a schema summary shows. Tables
+// with no more rows than this are loaded in full.
+const tabularSampleRows = 5
+
+// maxRowsPerLoad caps how many rows one !rows command loads
+const maxRowsPerLoad = 1000
+
+// dataTable is one CSV file or XLSX sheet. The first non-empty row is the
+// header; rows are the data rows after it.
+type dataTable struct {
+	sheet  string // empty for CSV
+	header []string
+	rows   [][]string
+}
+
+// readTables reads a CSV file as one table, or each non-empty XLSX sheet
+func readTables(filePath string) ([]dataTable, error) {
+	var grids []dataTable
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		file, err := os.Open(filePath) // #nosec G304 -- Loading a user-selected CSV path is core CLI behavior.
+		if err != nil {
+			return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		}
+		defer file.Close()
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV file: %w", err)
+		}
+		grids = append(grids, dataTable{rows: records})
+	case ".xlsx":
+		workbook, err := xlsx.OpenFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open XLSX file: %w", err)
+		}
+		for _, sheet := range workbook.Sheets {
+			grid := dataTable{sheet: sheet.Name}
+			err := sheet.ForEachRow(func(row *xlsx.Row) error {
+				var cells []string
+				err := row.ForEachCell(func(cell *xlsx.Cell) error {
+					cells = append(cells, cell.String())
+					return nil
+				})
+				grid.rows = append(grid.rows, cells)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to read sheet %s: %w", sheet.Name, err)
+			}
+			grids = append(grids, grid)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a CSV or XLSX file", filePath)
+	}
+
+	var tables []dataTable
+	for _, grid := range grids {
+		var rows [][]string
+		for _, row := range grid.rows {
+			if strings.TrimSpace(strings.Join(row, "")) != "" {
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		tables = append(tables, dataTable{sheet: grid.sheet, header: rows[0], rows: rows[1:]})
+	}
+	return tables, nil
+}
+
+// title names the table in summaries and row loads
+func (d dataTable) title(filePath string) string {
+	if d.sheet == "" {
+		return filePath
+	}
+	return fmt.Sprintf("%s, sheet %s", filePath, d.sheet)
+}
+
+var (
+	integerPattern = regexp.MustCompile(`^[-+]?\d+$`)
+	boolValues     = map[string]bool{"true": true, "false": true, "yes": true, "no": true}
+	dateLayouts    = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "01/02/2006", "1/2/2006"}
+)
+
+// cellType classifies one non-empty cell
+func cellType(value string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case integerPattern.MatchString(value):
+		return "integer"
+	case boolValues[strings.ToLower(value)]:
+		return "boolean"
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64); err == nil {
+		return "number"
+	}
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return "date"
+		}
+	}
+	return "text"
+}
+
+// columnType infers a column's type from its non-empty cells: integers
+// mixed with decimals are numbers, any other mix is text
+func columnType(rows [][]string, col int) (string, int) {
+	kind, empty := "", 0
+	for _, row := range rows {
+		if col >= len(row) || strings.TrimSpace(row[col]) == "" {
+			empty++
+			continue
+		}
+		next := cellType(row[col])
+		switch {
+		case kind == "" || kind == next:
+			kind = next
+		case (kind == "integer" && next == "number") || (kind == "number" && next == "integer"):
+			kind = "number"
+		default:
+			kind = "text"
+		}
+	}
+	if kind == "" {
+		kind = "empty"
+	}
+	return kind, empty
+}
+
+// formatTableRows writes rows start..end (1-based data rows) under the header
+func formatTableRows(sb *strings.Builder, table dataTable, start, end int) {
+	sb.WriteString(fmt.Sprintf("Header: %s\n", strings.Join(table.header, " | ")))
+	for i := start; i <= end; i++ {
+		sb.WriteString(fmt.Sprintf("Row %d: %s\n", i, strings.Join(table.rows[i-1], " | ")))
+	}
+}
+
+// summarizeTable describes a table by its columns, types, row count, and
+// first rows instead of listing every row
+func summarizeTable(filePath string, table dataTable) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Table: %s (%d rows, %d columns)\n", table.title(filePath), len(table.rows), len(table.header)))
+	sb.WriteString("Columns:\n")
+	for col, name := range table.header {
+		kind, empty := columnType(table.rows, col)
+		if name = strings.TrimSpace(name); name == "" {
+			name = fmt.Sprintf("column %d", col+1)
+		}
+		line := fmt.Sprintf("- %s: %s", name, kind)
+		if empty > 0 && kind != "empty" {
+			line += fmt.Sprintf(", %d empty", empty)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	shown := min(tabularSampleRows, len(table.rows))
+	sb.WriteString(fmt.Sprintf("First %d rows:\n", shown))
+	formatTableRows(&sb, table, 1, shown)
+	if rest := len(table.rows) - shown; rest > 0 {
+		sb.WriteString(fmt.Sprintf("(%d more rows not loaded)\n", rest))
+	}
+	return sb.String()
+}
+
+// loadTableSummary returns schema summaries for a CSV or XLSX file, and
+// whether any table was large enough to need one. Small files load in full.
+func (t *Terminal) loadTableSummary(filePath string) (string, bool, error) {
+	tables, err := readTables(filePath)
+	if err != nil {
+		return "", false, err
+	}
+	large := false
+	for _, table := range tables {
+		if len(table.rows) > tabularSampleRows {
+			large = true
+		}
+	}
+	if !large {
+		return "", false, nil
+	}
+
+	var sb strings.Builder
+	for i, table := range tables {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(summarizeTable(filePath, table))
+	}
+	t.lastTable = filePath
+	return sb.String(), true, nil
+}
+
+// LastTable returns the most recent file loaded as a schema summary
+func (t *Terminal) LastTable() string {
+	return t.lastTable
+}
+
+// ParseRowRange parses "n" or "start-end" into a 1-based inclusive range
+func ParseRowRange(spec string) (int, int, error) {
+	first, last, isRange := strings.Cut(spec, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid row %q, use n or start-end", spec)
+	}
+	end := start
+	if isRange {
+		if end, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid row range %q, use n or start-end", spec)
+		}
+	}
+	if end-start+1 > maxRowsPerLoad {
+		return 0, 0, fmt.Errorf("%d rows requested, load at most %d at a time", end-start+1, maxRowsPerLoad)
+	}
+	return start, end, nil
+}
+
+// LoadTableRows returns data rows start..end of a CSV file or an XLSX sheet
+// (the first sheet when sheet is empty), under the header row
+func (t *Terminal) LoadTableRows(filePath, sheet string, start, end int) (string, error) {
+	tables, err := readTables(filePath)
+	if err != nil {
+		return "", err
+	}
+	if len(tables) == 0 {
+		return "", fmt.Errorf("%s has no rows", filePath)
+	}
+
+	table := tables[0]
+	if sheet != "" {
+		found := false
+		for _, candidate := range tables {
+			if strings.EqualFold(candidate.sheet, sheet) {
+				table, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("no sheet named %q in %s", sheet, filePath)
+		}
+	}
+	if start > len(table.rows) {
+		return "", fmt.Errorf("%s has only %d rows", table.title(filePath), len(table.rows))
+	}
+	end = min(end, len(table.rows))
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Rows %d-%d of %s (%d rows):\n", start, end, table.title(filePath), len(table.rows)))
+	formatTableRows(&sb, table, start, end)
+	return sb.String(), nil
+}
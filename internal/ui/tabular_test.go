@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func writeTestCSV(t *testing.T, rows int) string {
+	t.Helper()
+	var sb strings.Builder
+	sb.WriteString("id,price,active,joined,name\n")
+	for i := 1; i <= rows; i++ {
+		price := fmt.Sprintf("%d.5", i)
+		if i == 2 {
+			price = "7"
+		}
+		name := fmt.Sprintf("user %d", i)
+		if i == 3 {
+			name = ""
+		}
+		sb.WriteString(fmt.Sprintf("%d,%s,%t,2024-01-%02d,%s\n", i, price, i%2 == 0, i, name))
+	}
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTextFileTabularSummary(t *testing.T) {
+	path := writeTestCSV(t, 20)
+	terminal := NewTerminal(&types.Config{TabularSummary: true})
+
+	content, err := terminal.loadTextFile(path)
+	if err != nil {
+		t.Fatalf("loadTextFile() error: %v", err)
+	}
+	for _, want := range []string{
+		"(20 rows, 5 columns)",
+		"- id: integer\n",
+		"- price: number\n",
+		"- active: boolean\n",
+		"- joined: date\n",
+		"- name: text, 1 empty\n",
+		"Header: id | price | active | joined | name\nRow 1: 1 | 1.5 | false | 2024-01-01 | user 1\n",
+		"Row 5: ",
+		"(15 more rows not loaded)",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("summary is missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "Row 6:") {
+		t.Errorf("summary lists rows past the sample:\n%s", content)
+	}
+	if terminal.LastTable() != path {
+		t.Errorf("LastTable() = %q, want %q", terminal.LastTable(), path)
+	}
+
+	// Small tables and the default config load every row
+	small := writeTestCSV(t, 3)
+	if content, _ := terminal.loadTextFile(small); !strings.Contains(content, "Row 4: 3 |") {
+		t.Errorf("small table was not loaded in full:\n%s", content)
+	}
+	if content, _ := NewTerminal(&types.Config{}).loadTextFile(path); !strings.Contains(content, "Row 21: 20 |") {
+		t.Errorf("table was summarized with tabular_summary off")
+	}
+}
+
+func TestLoadTableRows(t *testing.T) {
+	path := writeTestCSV(t, 20)
+	terminal := NewTerminal(&types.Config{})
+
+	content, err := terminal.LoadTableRows(path, "", 18, 25)
+	if err != nil {
+		t.Fatalf("LoadTableRows() error: %v", err)
+	}
+	want := fmt.Sprintf("Rows 18-20 of %s (20 rows):\nHeader: id | price | active | joined | name\nRow 18: 18 |", path)
+	if !strings.HasPrefix(content, want) || !strings.Contains(content, "Row 20: 20 |") || strings.Contains(content, "Row 17:") {
+		t.Errorf("unexpected rows:\n%s", content)
+	}
+
+	if _, err := terminal.LoadTableRows(path, "", 21, 21); err == nil {
+		t.Error("expected an error for a row past the end")
+	}
+	if _, err := terminal.LoadTableRows(path, "Sheet2", 1, 1); err == nil {
+		t.Error("expected an error for a missing sheet")
+	}
+}
+
+func TestParseRowRange(t *testing.T) {
+	tests := []struct {
+		spec       string
+		start, end int
+		wantErr    bool
+	}{
+		{spec: "7", start: 7, end: 7},
+		{spec: "10-20", start: 10, end: 20},
+		{spec: "", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "5-2", wantErr: true},
+		{spec: "a-b", wantErr: true},
+		{spec: "1-5000", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, end, err := ParseRowRange(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseRowRange(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+		if start != tt.start || end != tt.end {
+			t.Errorf("ParseRowRange(%q) = %d, %d, want %d, %d", tt.spec, start, end, tt.start, tt.end)
+		}
+	}
+}
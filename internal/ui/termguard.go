@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// restoreDisplaySequence resets text attributes and shows the cursor
+const restoreDisplaySequence = "\033[0m\033[?25h"
+
+// resetDisplaySequence undoes the modes binary output or a crashed program
+// commonly leaves on: alternate character set, colors, hidden cursor,
+// alternate screen, mouse reporting, insert mode, disabled line wrap, and
+// application cursor keys. The screen contents are kept.
+const resetDisplaySequence = "\x0f\033(B" + restoreDisplaySequence +
+	"\033[?1049l\033[?1000l\033[?1002l\033[?1003l\033[?1006l" +
+	"\033[4l\033[?7h\033[?1l\033>"
+
+// TerminalState is a snapshot of the controlling terminal taken before a
+// subprocess or streamed response can change it
+type TerminalState struct {
+	mode string
+}
+
+// SaveTerminalState records the controlling terminal's settings in stty -g
+// form. Without a terminal or stty, Restore only resets the display.
+func SaveTerminalState() TerminalState {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return TerminalState{}
+	}
+	defer tty.Close()
+
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	if err != nil {
+		return TerminalState{}
+	}
+	return TerminalState{mode: strings.TrimSpace(string(output))}
+}
+
+// Restore reapplies the saved terminal settings, resets colors, and shows the
+// cursor, writing to the terminal directly so piped stdout stays clean
+func (s TerminalState) Restore() {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+
+	if s.mode != "" {
+		cmd := exec.Command("stty", s.mode) // #nosec G204 -- mode is stty -g output captured by this process.
+		cmd.Stdin = tty
+		_ = cmd.Run()
+	}
+	_, _ = tty.WriteString(restoreDisplaySequence)
+}
+
+// ResetTerminal repairs a garbled terminal, such as after binary content was
+// printed, by restoring sane line settings and resetting display modes
+func (t *Terminal) ResetTerminal() error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+
+	cmd := exec.Command("stty", "sane")
+	cmd.Stdin = tty
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	_, err = tty.WriteString(resetDisplaySequence)
+	return err
+}
@@ -12,7 +12,7 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"io/fs"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -22,9 +22,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/MehmetMHY/ch/internal/config"
+	"github.com/MehmetMHY/ch/internal/fswalk"
+	"github.com/MehmetMHY/ch/internal/tokens"
 	"github.com/MehmetMHY/ch/pkg/types"
 	"github.com/ledongthuc/pdf"
 	"github.com/lu4p/cat"
@@ -43,6 +46,17 @@ var (
 // Terminal handles terminal-related operations
 type Terminal struct {
 	config *types.Config
+
+	// Cookies for authenticated scraping, loaded lazily on first scrape
+	cookies     []scrapeCookie
+	cookiesOnce sync.Once
+
+	// Pages scraped this session, revalidated with conditional requests on repeat scrapes
+	scrapeCache   map[string]fetchedPage
+	scrapeCacheMu sync.Mutex
+
+	// Most recent CSV/XLSX loaded as a schema summary, the default for !rows
+	lastTable string
 }
 
 // NewTerminal creates a new terminal handler
@@ -62,31 +76,56 @@ func ContainsAllOption(items []string) bool {
 	return false
 }
 
-// runFzfCore executes fzf and returns raw output bytes, handling common setup and error cases
-func (t *Terminal) runFzfCore(fzfArgs []string, inputText string) ([]byte, bool, error) {
+// runFzfCore executes fzf and returns raw output bytes, handling common setup and error cases.
+// Items are streamed to fzf line by line, so it starts filtering while a
+// long list is still being written and no single giant input string is built.
+func (t *Terminal) runFzfCore(fzfArgs []string, items []string) ([]byte, bool, error) {
 	cmd := exec.Command("fzf", fzfArgs...) // #nosec G204 -- fzf arguments are constructed by this program and executed without a shell.
-	cmd.Stdin = strings.NewReader(inputText)
-	var output bytes.Buffer
-	cmd.Stdout = &output
 	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, false, fmt.Errorf("fzf execution failed: %w", err)
+	}
+	// Writes fail once fzf exits, which ends the goroutine early when a
+	// choice is made before the whole list is written
+	go func() {
+		defer stdin.Close()
+		_ = writeFzfItems(stdin, items)
+	}()
 
-	err := cmd.Run()
+	output, err := OutputForeground(cmd)
 
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		if exitErr.ExitCode() == 130 || exitErr.ExitCode() == 1 {
-			return nil, true, nil // User cancelled
+			// User cancelled, or nothing matched. With --print-query the
+			// output still holds the typed query in the no-match case.
+			return output, true, nil
 		}
 		return nil, false, fmt.Errorf("fzf failed: %w", err)
 	} else if err != nil {
 		return nil, false, fmt.Errorf("fzf execution failed: %w", err)
 	}
 
-	return output.Bytes(), false, nil
+	return output, false, nil
+}
+
+// writeFzfItems writes items to fzf's input, one per line
+func writeFzfItems(w io.Writer, items []string) error {
+	buffered := bufio.NewWriter(w)
+	for _, item := range items {
+		if _, err := buffered.WriteString(item); err != nil {
+			return err
+		}
+		if err := buffered.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
 }
 
 // runFzfSSHSafe executes fzf in a way that works correctly over SSH connections
-func (t *Terminal) runFzfSSHSafe(fzfArgs []string, inputText string) (string, error) {
-	content, cancelled, err := t.runFzfCore(fzfArgs, inputText)
+func (t *Terminal) runFzfSSHSafe(fzfArgs []string, items []string) (string, error) {
+	content, cancelled, err := t.runFzfCore(fzfArgs, items)
 	if err != nil {
 		return "", err
 	}
@@ -97,12 +136,12 @@ func (t *Terminal) runFzfSSHSafe(fzfArgs []string, inputText string) (string, er
 }
 
 // runFzfSSHSafeWithQuery executes fzf with --print-query in a SSH-safe way
-func (t *Terminal) runFzfSSHSafeWithQuery(fzfArgs []string, inputText string) ([]string, error) {
-	content, cancelled, err := t.runFzfCore(fzfArgs, inputText)
+func (t *Terminal) runFzfSSHSafeWithQuery(fzfArgs []string, items []string) ([]string, error) {
+	content, _, err := t.runFzfCore(fzfArgs, items)
 	if err != nil {
 		return nil, err
 	}
-	if cancelled || len(content) == 0 {
+	if len(strings.TrimSpace(string(content))) == 0 {
 		return []string{}, nil
 	}
 	return strings.Split(strings.TrimRight(string(content), "\n"), "\n"), nil
@@ -119,24 +158,53 @@ func (t *Terminal) ShowHelp() {
 	fmt.Println("ch - lightweight CLI for AI models")
 	fmt.Println("")
 	fmt.Println("usage:")
-	fmt.Printf("  ch [-h] [-c] [--clear] [-a|-hs] [-f [file]] [-n] [-d dir] [-p [platform]] [-m model] [-o platform|model] [-l file/url] [-w query] [-s url] [-e|--export] [-t file] [query]\n")
+	fmt.Printf("  ch [-h] [-c] [--clear] [-a|-hs] [-f [file]] [-n] [-d dir [--since ref|time] [--stdout] [--dump-format text|markdown] [--manifest]] [--index dir] [-p [platform]] [-m model] [-o platform|model] [--profile name] [-l file/url] [-w query] [-s url] [-e|--export] [--apply] [-t file] [--dataset format] [--usage [age]] [-T name [var=value...]] [--seed N] [--logprobs] [--low-bandwidth] [--stream-json] [--dry-run] [query]\n")
+	fmt.Printf("  ch embed [file...] [--model name] [--format json|binary] [--lines] [--batch N] [--rpm N]\n")
+	fmt.Printf("  ch summarize <file|dir|url> [focus] [--chunk-size N] [--overlap N] [--parallel N]\n")
+	fmt.Printf("  ch tail [-f] <file> [--ask text] [--interval 30s] [--max-lines 500]\n")
+	fmt.Printf("  ch test [-o platform|model] [--json] [--timeout 30s] [prompt]\n")
+	fmt.Printf("  ch ws [list|switch [name]|model platform|model|prompt text]\n")
+	fmt.Printf("  ch cheatsheet\n")
+	fmt.Printf("  ch export [session] [--since time] [--role user|assistant] [--last N] [--strip-context] [--notes] [--format text|markdown|json]\n")
+	fmt.Printf("  ch db [stats|import [dir] [--overwrite]|export <dir>|prune <age>|search <text>]\n")
 	fmt.Println("")
 	fmt.Println("options:")
 	fmt.Printf("  %-18s %s\n", "-h, --help", "show help and exit")
 	fmt.Printf("  %-18s %s\n", "-c, --continue", "continue from latest session")
-	fmt.Printf("  %-18s %s\n", "--clear", "clear all tmp files")
-	fmt.Printf("  %-18s %s\n", "-a, -hs, --history", "search sessions (supports filters: 1d, 1w, 1m, 1y, exact, <epoch>, <range>)")
+	fmt.Printf("  %-18s %s\n", "--clear [sessions]", "remove stale tmp files; 'sessions [age]' also deletes saved sessions")
+	fmt.Printf("  %-18s %s\n", "-a, -hs, --history", "search sessions (supports filters: 1d, 1w, 1m, 1y, exact, #tag, <epoch>, <range>)")
 	fmt.Printf("  %-18s %s\n", "-f, --fetch [file]", "fetch session into interactive mode (cwd file, temp name, path, or fzf pick)")
-	fmt.Printf("  %-18s %s\n", "-n, --no-history", "disable session saving for this run")
+	fmt.Printf("  %-18s %s\n", "-n, --no-history", "disable session saving for this run (autosave_interval in config is the minimum gap between saves after commands)")
 	fmt.Printf("  %-18s %s\n", "-d dir", "generate codedump")
+	fmt.Printf("  %-18s %s\n", "--since ref|time", "with -d, only files changed since a git ref (HEAD~3, main) or time (2h, 3d, 2024-06-01, epoch)")
+	fmt.Printf("  %-18s %s\n", "--stdout", "with -d, write the codedump to stdout instead of a file")
+	fmt.Printf("  %-18s %s\n", "--dump-format f", "with -d, codedump format: text (default) or markdown")
+	fmt.Printf("  %-18s %s\n", "--manifest", "with -d, also write a JSON manifest (path, size, tokens, sha256)")
+	fmt.Printf("  %-18s %s\n", "--index dir", "build or update the embeddings index of dir, searched by !rag")
 	fmt.Printf("  %-18s %s\n", "-p [platform]", "switch platform")
 	fmt.Printf("  %-18s %s\n", "-m model", "specify model")
 	fmt.Printf("  %-18s %s\n", "-o platform|model", "specify platform and model")
+	fmt.Printf("  %-18s %s\n", "--profile name", "start with a preset from \"profiles\" in config (platform, model, persona, search, safe mode)")
 	fmt.Printf("  %-18s %s\n", "-l file/url", "load file or scrape URL")
 	fmt.Printf("  %-18s %s\n", "-w query", "web search")
 	fmt.Printf("  %-18s %s\n", "-s url", "scrape URL")
 	fmt.Printf("  %-18s %s\n", "-e, --export", "export code blocks")
+	fmt.Printf("  %-18s %s\n", "--apply", "ask for edits to existing files and apply them after a diff preview")
 	fmt.Printf("  %-18s %s\n", "-t, --token file", "estimate token count for a file")
+	fmt.Printf("  %-18s %s\n", "--seed N", "seed for reproducible generations (recorded in history and exports)")
+	fmt.Printf("  %-18s %s\n", "--logprobs", "show token probabilities and top alternatives after responses")
+	fmt.Printf("  %-18s %s\n", "--low-bandwidth", "redraw less and print replies in chunks, for slow SSH links")
+	fmt.Printf("  %-18s %s\n", "--stream-json", "emit JSON lines (delta, reasoning, done with usage, error) instead of text")
+	fmt.Printf("  %-18s %s\n", "--dry-run", "print the request that would be sent, with tokens and estimated cost, without sending it")
+	fmt.Printf("  %-18s %s\n", "embed [file...]", "print embedding vectors for files or stdin (JSON lines, or --format binary)")
+	fmt.Printf("  %-18s %s\n", "test [prompt]", "send one short completion and report latency, tokens, and reply; exit 1 on failure")
+	fmt.Printf("  %-18s %s\n", "summarize target", "map-reduce summary of a file, dir, URL, or stdin of any size")
+	fmt.Printf("  %-18s %s\n", "cheatsheet", "print every interactive command with your keys, syntax, and an example")
+	fmt.Printf("  %-18s %s\n", "ws [command]", "list or switch workspaces, set workspace model/prompt (needs workspaces=true)")
+	fmt.Printf("  %-18s %s\n", "db [command]", "SQLite session database: stats, import JSON sessions, export, prune by age, search")
+	fmt.Printf("  %-18s %s\n", "--usage [age]", "report tokens and estimated cost from ~/.ch/usage.jsonl by model and day")
+	fmt.Printf("  %-18s %s\n", "-T name", "fill in a prompt template from ~/.ch/templates (var=value args, piped stdin for {{stdin}}) and send it")
+	fmt.Printf("  %-18s %s\n", "--dataset format", "export saved sessions as a training dataset (openai, sharegpt; filter with --min-rating N, --tag a,b)")
 	fmt.Println("")
 	fmt.Println("examples:")
 	fmt.Println("  ch -p \"openai\" -m \"gpt-4.1\" \"goal of life\"")
@@ -197,7 +265,7 @@ func (t *Terminal) RecordShellSession() (string, error) {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := RunForeground(cmd); err != nil {
 		// If -q flag doesn't work, try without it (some old versions don't support -q)
 		if _, ok := err.(*exec.ExitError); ok {
 			cmd = exec.Command("script", tempFile.Name()) // #nosec G204 -- script writes to a temp file created by this process.
@@ -206,7 +274,7 @@ func (t *Terminal) RecordShellSession() (string, error) {
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
 
-			if err := cmd.Run(); err != nil {
+			if err := RunForeground(cmd); err != nil {
 				// An exit error is expected when the shell exits, so we don't treat it as a fatal error
 				if _, ok := err.(*exec.ExitError); !ok {
 					return "", fmt.Errorf("failed to run shell session: %w", err)
@@ -233,13 +301,17 @@ func (t *Terminal) RecordShellSession() (string, error) {
 func (t *Terminal) ShowHelpFzf() string {
 	options := t.getInteractiveHelpOptions()
 
+	// The preview after the tab is hidden from the list and shown for the
+	// highlighted command: syntax, examples, and its current key
 	fzfArgs := []string{
-		"--reverse", "--height=40%", "--border",
+		"--reverse", "--height=60%", "--border",
 		"--prompt=option: ", "--multi",
+		"--delimiter=\t",
+		"--with-nth=1",
+		"--preview=printf '%b' {2}",
+		"--preview-window=right:50%:wrap",
 	}
-	inputText := strings.Join(options, "\n")
-
-	output, err := t.runFzfSSHSafe(fzfArgs, inputText)
+	output, err := t.runFzfSSHSafe(fzfArgs, t.helpPickerLines())
 	if err != nil {
 		t.PrintError(fmt.Sprintf("%v", err))
 		return ""
@@ -250,6 +322,9 @@ func (t *Terminal) ShowHelpFzf() string {
 	}
 
 	selectedItems := strings.Split(output, "\n")
+	for i, item := range selectedItems {
+		selectedItems[i], _, _ = strings.Cut(item, "\t")
+	}
 
 	// If >all is selected along with other items, ignore >all and process others
 	if len(selectedItems) > 1 {
@@ -301,29 +376,14 @@ func (t *Terminal) processHelpSelection(selected string, options []string) strin
 
 // getCommandList returns the list of help commands
 func (t *Terminal) getCommandList() []string {
-	return []string{
-		fmt.Sprintf("%s - exit interface", t.config.ExitKey),
-		fmt.Sprintf("%s - help page", t.config.HelpKey),
-		fmt.Sprintf("%s - clear chat history", t.config.ClearHistory),
-		fmt.Sprintf("%s - backtrack messages", t.config.Backtrack),
-		fmt.Sprintf("%s - select from all models", t.config.AllModels),
-		fmt.Sprintf("%s - switch models", t.config.ModelSwitch),
-		fmt.Sprintf("%s - switch platforms", t.config.PlatformSwitch),
-		fmt.Sprintf("%s - record shell session", t.config.ShellRecord),
-		fmt.Sprintf("%s - shell session (not recorded)", t.config.ShellRecordSilent),
-		fmt.Sprintf("%s - generate codedump", t.config.CodeDump),
-		fmt.Sprintf("%s - add to clipboard", t.config.CopyToClipboard),
-		fmt.Sprintf("%s - quick copy latest response", t.config.QuickCopyLatest),
-		fmt.Sprintf("%s - multi-line input mode", t.config.MultiLine),
-		fmt.Sprintf("%s [file] - export chat(s)", t.config.ExportChat),
-		fmt.Sprintf("%s [buff] - text editor mode", t.config.EditorInput),
-		fmt.Sprintf("%s [dir] - load files/dirs", t.config.LoadFiles),
-		fmt.Sprintf("%s [url] - scrape URL(s)", t.config.ScrapeURL),
-		fmt.Sprintf("%s [query] - web search", t.config.WebSearch),
-		fmt.Sprintf("%s [filter] - search sessions", t.config.AnswerSearch),
-		"ctrl+c - clear prompt input",
-		"ctrl+d - exit completely",
+	commands := make([]string, 0, len(commandHelpEntries)+len(fixedKeyHelp))
+	for _, entry := range commandHelpEntries {
+		commands = append(commands, entry.line(t.config))
 	}
+	for _, fixed := range fixedKeyHelp {
+		commands = append(commands, fmt.Sprintf("%s - %s", fixed.key, fixed.summary))
+	}
+	return commands
 }
 
 // getInteractiveHelpOptions returns a slice of strings containing the help information for fzf selection.
@@ -336,23 +396,47 @@ func (t *Terminal) getInteractiveHelpOptions() []string {
 	return options
 }
 
+// helpPickerLines returns the help options for fzf, each followed by a tab
+// and the escaped text of its preview pane
+func (t *Terminal) helpPickerLines() []string {
+	previews := []string{
+		"print every command and key below",
+		"show the date, platform, model, capabilities, session file, chat count, tokens, usage, and spend",
+	}
+	for _, entry := range commandHelpEntries {
+		previews = append(previews, entry.preview(t.config))
+	}
+	for _, fixed := range fixedKeyHelp {
+		previews = append(previews, fmt.Sprintf("%s\n\n%s\n\nthis key cannot be changed", fixed.key, fixed.summary))
+	}
+
+	options := t.getInteractiveHelpOptions()
+	lines := make([]string, len(options))
+	for i, option := range options {
+		lines[i] = option + "\t" + escapeHelpPreview(previews[i])
+	}
+	return lines
+}
+
 // ShowLoadingAnimation displays a loading animation
 func (t *Terminal) ShowLoadingAnimation(message string, done chan bool) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		<-done // Just wait for done signal, don't show animation
 		return
 	}
+	out := t.UIWriter()
 	chars := []string{"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷", "⠁", "⠂", "⠄", "⡀", "⢀", "⠠", "⠐", "⠈"}
+	ticker := time.NewTicker(t.redrawInterval())
+	defer ticker.Stop()
 	i := 0
 	for {
+		fmt.Fprintf(out, "\r\033[93m%s %s\033[0m", chars[i], message)
 		select {
 		case <-done:
-			fmt.Print("\r\033[K")
+			fmt.Fprint(out, "\r\033[K")
 			return
-		default:
-			fmt.Printf("\r\033[93m%s %s\033[0m", chars[i], message)
+		case <-ticker.C:
 			i = (i + 1) % len(chars)
-			time.Sleep(100 * time.Millisecond)
 		}
 	}
 }
@@ -360,17 +444,15 @@ func (t *Terminal) ShowLoadingAnimation(message string, done chan bool) {
 // FzfSelect provides a fuzzy finder interface for selection
 func (t *Terminal) FzfSelect(items []string, prompt string) (string, error) {
 	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt}
-	inputText := strings.Join(items, "\n")
 
-	return t.runFzfSSHSafe(fzfArgs, inputText)
+	return t.runFzfSSHSafe(fzfArgs, items)
 }
 
 // FzfMultiSelect provides a fuzzy finder interface for multiple selections
 func (t *Terminal) FzfMultiSelect(items []string, prompt string) ([]string, error) {
 	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt, "--multi", "--bind=tab:toggle+down"}
-	inputText := strings.Join(items, "\n")
 
-	result, err := t.runFzfSSHSafe(fzfArgs, inputText)
+	result, err := t.runFzfSSHSafe(fzfArgs, items)
 	if err != nil {
 		return nil, err
 	}
@@ -382,12 +464,31 @@ func (t *Terminal) FzfMultiSelect(items []string, prompt string) ([]string, erro
 	return strings.Split(result, "\n"), nil
 }
 
+// FzfMultiSelectOrQuery provides multi-selection that also returns the typed
+// query, so a path that is not in the list can be entered directly
+func (t *Terminal) FzfMultiSelectOrQuery(items []string, prompt string) ([]string, string, error) {
+	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt, "--multi", "--bind=tab:toggle+down", "--print-query"}
+
+	lines, err := t.runFzfSSHSafeWithQuery(fzfArgs, items)
+	if err != nil || len(lines) == 0 {
+		return nil, "", err
+	}
+
+	// The query is on the first line and selections follow it
+	var selections []string
+	for _, line := range lines[1:] {
+		if line != "" {
+			selections = append(selections, line)
+		}
+	}
+	return selections, lines[0], nil
+}
+
 // FzfMultiSelectExact provides an exact matching fuzzy finder interface for multiple selections
 func (t *Terminal) FzfMultiSelectExact(items []string, prompt string) ([]string, error) {
 	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt, "--multi", "--bind=tab:toggle+down", "--exact"}
-	inputText := strings.Join(items, "\n")
 
-	result, err := t.runFzfSSHSafe(fzfArgs, inputText)
+	result, err := t.runFzfSSHSafe(fzfArgs, items)
 	if err != nil {
 		return nil, err
 	}
@@ -402,9 +503,8 @@ func (t *Terminal) FzfMultiSelectExact(items []string, prompt string) ([]string,
 // FzfMultiSelectForCLI provides a fuzzy finder interface for multiple selections with cancellation detection
 func (t *Terminal) FzfMultiSelectForCLI(items []string, prompt string) ([]string, error) {
 	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt, "--multi", "--bind=tab:toggle+down"}
-	inputText := strings.Join(items, "\n")
 
-	result, err := t.runFzfSSHSafe(fzfArgs, inputText)
+	result, err := t.runFzfSSHSafe(fzfArgs, items)
 	if err != nil {
 		return nil, err
 	}
@@ -420,9 +520,8 @@ func (t *Terminal) FzfMultiSelectForCLI(items []string, prompt string) ([]string
 // FzfSelectOrQuery provides a fuzzy finder interface that allows for selection or custom query input.
 func (t *Terminal) FzfSelectOrQuery(items []string, prompt string) (string, error) {
 	fzfArgs := []string{"--reverse", "--height=40%", "--border", "--prompt=" + prompt, "--print-query"}
-	inputText := strings.Join(items, "\n")
 
-	lines, err := t.runFzfSSHSafeWithQuery(fzfArgs, inputText)
+	lines, err := t.runFzfSSHSafeWithQuery(fzfArgs, items)
 	if err != nil {
 		return "", err
 	}
@@ -445,90 +544,165 @@ func (t *Terminal) FzfSelectOrQuery(items []string, prompt string) (string, erro
 	return "", nil
 }
 
+// UIWriter returns where interactive UI text goes. In an interactive session
+// with piped stdout it is stderr, so stdout only carries response text.
+func (t *Terminal) UIWriter() io.Writer {
+	if t.config.UIToStderr {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // PrintSuccess prints a success message
 func (t *Terminal) PrintSuccess(message string) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		fmt.Printf("%s\n", message)
 	} else {
-		fmt.Printf("\033[92m%s\033[0m\n", message)
+		fmt.Fprintf(t.UIWriter(), "\033[92m%s\033[0m\n", message)
 	}
 }
 
 // PrintError prints an error message
 func (t *Terminal) PrintError(message string) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		fmt.Fprintf(os.Stderr, "%s\n", message)
 	} else {
-		fmt.Printf("\033[91m%s\033[0m\n", message)
+		fmt.Fprintf(t.UIWriter(), "\033[91m%s\033[0m\n", message)
 	}
 }
 
 // PrintInfo prints an informational message
 func (t *Terminal) PrintInfo(message string) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		return // Suppress info messages when piped
 	}
-	fmt.Printf("\033[93m%s\033[0m\n", message)
+	fmt.Fprintf(t.UIWriter(), "\033[93m%s\033[0m\n", message)
+}
+
+// PrintAnnotation prints a dim annotation line such as the model and time of a response
+func (t *Terminal) PrintAnnotation(text string) {
+	if text == "" || (t.config.IsPipedOutput && !t.config.UIToStderr) {
+		return // Suppress when piped
+	}
+	fmt.Fprintf(t.UIWriter(), "\033[90m%s\033[0m\n", text)
+}
+
+// PrintArtifacts lists files written during the session. When stdout is
+// piped the list goes to stderr so it never mixes with response text.
+func (t *Terminal) PrintArtifacts(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
+		fmt.Fprintf(os.Stderr, "artifacts this session:\n  %s\n", strings.Join(paths, "\n  "))
+		return
+	}
+	fmt.Fprintf(t.UIWriter(), "\033[93martifacts this session:\033[0m\n  %s\n", strings.Join(paths, "\n  "))
 }
 
 // PrintModelSwitch prints model switch confirmation
 func (t *Terminal) PrintModelSwitch(model string) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		return // Suppress when piped
 	}
-	fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", t.config.CurrentPlatform, model)
+	fmt.Fprintf(t.UIWriter(), "\033[96m%s\033[0m \033[95m%s\033[0m\n", t.config.CurrentPlatform, model)
 }
 
 // PrintPlatformSwitch prints platform switch confirmation
 func (t *Terminal) PrintPlatformSwitch(platform, model string) {
-	if t.config.IsPipedOutput {
+	if t.config.IsPipedOutput && !t.config.UIToStderr {
 		return // Suppress when piped
 	}
-	fmt.Printf("\033[96m%s\033[0m \033[95m%s\033[0m\n", platform, model)
+	fmt.Fprintf(t.UIWriter(), "\033[96m%s\033[0m \033[95m%s\033[0m\n", platform, model)
 }
 
-// LoadFileContent loads and returns content from selected files/directories or URLs
+// LoadFileContent loads and returns content from selected files/directories or URLs.
+// Loads of more than one file or URL show a progress line with running counts.
 func (t *Terminal) LoadFileContent(selections []string) (string, error) {
+	items := t.expandLoadSelections(selections)
+	if len(items) > 1 && t.allURLs(items) {
+		return t.ScrapeURLsWithFormat(items, t.config.ScrapeFormat)
+	}
+	if len(items) == 1 && t.isURL(items[0]) {
+		urlContent, err := t.scrapeURL(items[0], t.config.ScrapeFormat)
+		if err != nil {
+			return fmt.Sprintf("Error scraping %s: %v\n", items[0], err), nil
+		}
+		return urlContent, nil
+	}
+
+	var progress *ProgressReporter
+	status := Progress{Verb: "loaded", Unit: "files", Total: len(items)}
+	if len(items) > 1 {
+		progress = t.StartProgress(status)
+		defer progress.Stop()
+	}
+
 	var contentBuilder strings.Builder
+	for _, item := range items {
+		var content string
+		var err error
+		if t.isURL(item) {
+			content, err = t.scrapeURLInternal(item, t.config.ScrapeFormat)
+			if err != nil {
+				content = fmt.Sprintf("Error scraping %s: %v\n", item, err)
+			}
+		} else if content, err = t.loadTextFile(item); err != nil {
+			content = ""
+		}
+		contentBuilder.WriteString(content)
+
+		if progress != nil {
+			status.Done++
+			status.Tokens += tokens.Count(t.config.CurrentModel, content)
+			progress.Report(status)
+		}
+	}
+
+	return contentBuilder.String(), nil
+}
 
+// expandLoadSelections turns selections into the URLs and files to load,
+// listing the files inside directories and dropping paths that do not exist
+func (t *Terminal) expandLoadSelections(selections []string) []string {
+	var items []string
 	for _, selection := range selections {
 		if selection == "" {
 			continue
 		}
-
-		// Check if this is a URL
 		if t.isURL(selection) {
-			urlContent, err := t.scrapeURL(selection)
-			if err != nil {
-				contentBuilder.WriteString(fmt.Sprintf("Error scraping %s: %v\n", selection, err))
-				continue
-			}
-			contentBuilder.WriteString(urlContent)
+			items = append(items, selection)
 			continue
 		}
 
-		// Handle files and directories
 		info, err := os.Stat(selection)
 		if err != nil {
 			continue
 		}
+		if !info.IsDir() {
+			items = append(items, selection)
+			continue
+		}
 
-		if info.IsDir() {
-			dirContent, err := t.loadDirectoryContent(selection)
-			if err != nil {
-				continue
-			}
-			contentBuilder.WriteString(dirContent)
-		} else {
-			fileContent, err := t.loadTextFile(selection)
-			if err != nil {
-				continue
-			}
-			contentBuilder.WriteString(fileContent)
+		files, err := fswalk.List(selection, fswalk.OptionsFromConfig(t.config, selection))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			items = append(items, filepath.Join(selection, file))
 		}
 	}
+	return items
+}
 
-	return contentBuilder.String(), nil
+// allURLs reports whether every item is a URL
+func (t *Terminal) allURLs(items []string) bool {
+	for _, item := range items {
+		if !t.isURL(item) {
+			return false
+		}
+	}
+	return true
 }
 
 // loadTextFile loads content from various file types (text, PDF, DOCX, XLSX, CSV, images)
@@ -537,16 +711,25 @@ func (t *Terminal) loadTextFile(filePath string) (string, error) {
 
 	var content string
 	var err error
+	plainText := false
 
 	switch ext {
 	case ".pdf":
 		content, err = t.loadPDF(filePath)
 	case ".docx", ".odt", ".rtf":
 		content, err = t.loadDOCX(filePath)
-	case ".xlsx":
-		content, err = t.loadXLSX(filePath)
-	case ".csv":
-		content, err = t.loadCSV(filePath)
+	case ".xlsx", ".csv":
+		summarized := false
+		if t.config.TabularSummary {
+			content, summarized, err = t.loadTableSummary(filePath)
+		}
+		if err == nil && !summarized {
+			if ext == ".csv" {
+				content, err = t.loadCSV(filePath)
+			} else {
+				content, err = t.loadXLSX(filePath)
+			}
+		}
 	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tiff", ".tif", ".webp":
 		content, err = t.loadImage(filePath)
 	default:
@@ -556,12 +739,15 @@ func (t *Terminal) loadTextFile(filePath string) (string, error) {
 			return "", readErr
 		}
 
-		// Check if file is likely a text file by examining content
-		if !t.isTextFile(fileContent) {
+		// Check if file is likely a text file by examining content, converting
+		// UTF-16, Shift-JIS, and Latin-1 text to UTF-8
+		text, _, ok := t.decodeText(fileContent)
+		if !ok {
 			return "", fmt.Errorf("file is not a supported file type")
 		}
 
-		content = string(fileContent)
+		content = text
+		plainText = true
 	}
 
 	if err != nil {
@@ -570,41 +756,12 @@ func (t *Terminal) loadTextFile(filePath string) (string, error) {
 
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("File: %s\n", filePath))
-	result.WriteString(content)
+	result.WriteString(t.formatFileBody(filePath, content, plainText))
 	result.WriteString("\n\n")
 
 	return result.String(), nil
 }
 
-// loadDirectoryContent loads content from all text files in a directory
-func (t *Terminal) loadDirectoryContent(dirPath string) (string, error) {
-	var result strings.Builder
-
-	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		fileContent, err := t.loadTextFile(path)
-		if err != nil {
-			return nil
-		}
-
-		result.WriteString(fileContent)
-		return nil
-	})
-
-	if err != nil {
-		return "", err
-	}
-
-	return result.String(), nil
-}
-
 // loadPDF extracts text content from PDF files
 func (t *Terminal) loadPDF(filePath string) (string, error) {
 	file, err := os.Open(filePath) // #nosec G304 -- Loading a user-selected PDF path is core CLI behavior.
@@ -712,6 +869,20 @@ func (t *Terminal) loadCSV(filePath string) (string, error) {
 	return content.String(), nil
 }
 
+// ImagePaths returns the absolute paths of the image files among paths
+func ImagePaths(paths []string) []string {
+	var images []string
+	for _, path := range paths {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+			if abs, err := filepath.Abs(path); err == nil {
+				images = append(images, abs)
+			}
+		}
+	}
+	return images
+}
+
 // loadImage loads and extracts metadata and basic information from image files
 func (t *Terminal) loadImage(filePath string) (string, error) {
 	file, err := os.Open(filePath) // #nosec G304 -- Loading a user-selected image path is core CLI behavior.
@@ -929,61 +1100,24 @@ func (t *Terminal) GetCurrentDirFilesRecursive() ([]string, error) {
 
 // GetDirFilesRecursive returns all files and directories in the specified directory and subdirectories
 func (t *Terminal) GetDirFilesRecursive(targetDir string) ([]string, error) {
-	var items []string
-
-	// Convert targetDir to absolute path for comparison
-	absTargetDir, err := filepath.Abs(targetDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %v", err)
-	}
-
-	// Check if this directory should be loaded shallowly
-	isShallow := config.IsShallowLoadDir(t.config, absTargetDir)
-
-	err = filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-
-		// Skip the root directory itself
-		if path == targetDir {
-			return nil
-		}
-
-		// Get relative path from target directory
-		relPath, err := filepath.Rel(targetDir, path)
-		if err != nil {
-			return nil // Skip if we can't get relative path
-		}
-
-		// For shallow directories, only include direct children (depth 1)
-		if isShallow {
-			depth := strings.Count(relPath, string(filepath.Separator))
-			if d.IsDir() && depth > 0 {
-				// Skip subdirectories beyond depth 1
-				return filepath.SkipDir
-			}
-			if !d.IsDir() && depth > 0 {
-				// Skip files beyond depth 1
-				return nil
-			}
-		}
-
-		// Skip certain system directories but allow hidden files
-		if d.IsDir() && (filepath.Base(relPath) == ".git" || filepath.Base(relPath) == ".svn" || filepath.Base(relPath) == ".hg") {
-			return filepath.SkipDir
-		}
+	return t.listDirFiles(targetDir, false)
+}
 
-		// Add directories with trailing slash and files as-is
-		if d.IsDir() {
-			items = append(items, relPath+"/")
-		} else {
-			items = append(items, relPath)
-		}
+// GetDirFilesDeep lists a directory like GetDirFilesRecursive but ignores
+// shallow loading rules, for when the user asks to load deep anyway
+func (t *Terminal) GetDirFilesDeep(targetDir string) ([]string, error) {
+	return t.listDirFiles(targetDir, true)
+}
 
-		return nil
-	})
+// listDirFiles lists files and directories below targetDir
+func (t *Terminal) listDirFiles(targetDir string, deep bool) ([]string, error) {
+	opts := fswalk.OptionsFromConfig(t.config, targetDir)
+	opts.Dirs = true
+	if deep {
+		opts.ShallowDepth = 0
+	}
 
+	items, err := fswalk.List(targetDir, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to walk directory: %v", err)
 	}
@@ -1050,20 +1184,38 @@ func (t *Terminal) CodeDumpFromDir(targetDir string) (string, error) {
 
 // CodeDumpFromDirForCLI generates a comprehensive code dump for CLI usage with cancellation detection
 func (t *Terminal) CodeDumpFromDirForCLI(targetDir string) (string, error) {
+	dump, err := t.CodeDumpFilesForCLI(targetDir, "")
+	if err != nil {
+		return "", err
+	}
+	return dump.Text(), nil
+}
+
+// CodeDumpFilesForCLI reads the files of a CLI codedump so they can be
+// rendered in any format or described in a manifest. A non-empty since
+// limits the dump to files changed since that git ref or time.
+func (t *Terminal) CodeDumpFilesForCLI(targetDir, since string) (*CodeDump, error) {
 	// Convert to absolute path
 	absDir, err := filepath.Abs(targetDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path: %v", err)
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
 	}
 
 	// Discover all files while respecting .gitignore
 	allFiles, err := t.discoverFiles(absDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to discover files: %v", err)
+		return nil, fmt.Errorf("failed to discover files: %v", err)
+	}
+
+	if since != "" {
+		allFiles, err = changedFilesSince(absDir, since, allFiles)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(allFiles) == 0 {
-		return "", fmt.Errorf("no text files found in directory")
+		return nil, fmt.Errorf("no text files found in directory")
 	}
 
 	// Add NONE option at the top of the list
@@ -1072,7 +1224,7 @@ func (t *Terminal) CodeDumpFromDirForCLI(targetDir string) (string, error) {
 	// Use CLI-specific fzf that detects cancellation
 	excludedItems, err := t.FzfMultiSelectForCLI(fzfOptions, "exclude from dump (tab=multi): ")
 	if err != nil {
-		return "", fmt.Errorf("failed to get exclusions: %v", err)
+		return nil, fmt.Errorf("failed to get exclusions: %v", err)
 	}
 
 	// Filter out the NONE option if selected
@@ -1088,130 +1240,58 @@ func (t *Terminal) CodeDumpFromDirForCLI(targetDir string) (string, error) {
 	includedFiles := t.filterExcludedFiles(allFiles, excludedItems)
 
 	if len(includedFiles) == 0 {
-		return "", fmt.Errorf("no files remaining after exclusions")
+		return nil, fmt.Errorf("no files remaining after exclusions")
 	}
 
-	// Generate the codedump string
-	return t.generateCodeDumpFromDir(includedFiles, absDir)
+	dump := t.collectCodeDump(includedFiles, absDir)
+	dump.Since = since
+	return dump, nil
 }
 
-// discoverFiles finds all text files in the directory, respecting .gitignore
-func (t *Terminal) discoverFiles(rootDir string) ([]string, error) {
-	var allFiles []string
-	var allDirs []string
-	gitignorePatterns := t.loadGitignorePatterns(rootDir)
-
-	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-
-		relPath, err := filepath.Rel(rootDir, path)
-		if err != nil {
-			return nil
-		}
-
-		// Skip the root directory itself
-		if relPath == "." {
-			return nil
-		}
-
-		// Check if this path should be ignored
-		if t.shouldIgnore(relPath, gitignorePatterns) {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		if d.IsDir() {
-			allDirs = append(allDirs, relPath+"/")
-		} else {
-			// Check if it's a text file
-			if t.isTextFileByPath(path) {
-				allFiles = append(allFiles, relPath)
-			}
-		}
-
-		return nil
-	})
-
+// ReadTextFiles reads every text file a codedump of dir would offer, with no
+// exclusion picker
+func (t *Terminal) ReadTextFiles(dir string) (*CodeDump, error) {
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get absolute path: %v", err)
 	}
-
-	// Combine directories and files for the selection list
-	var combined []string
-	combined = append(combined, allDirs...)
-	combined = append(combined, allFiles...)
-
-	return combined, nil
-}
-
-// loadGitignorePatterns loads patterns from .gitignore file if it exists
-func (t *Terminal) loadGitignorePatterns(rootDir string) []string {
-	// Always ignore .git directory by default
-	patterns := []string{".git/"}
-
-	gitignorePath := filepath.Join(rootDir, ".gitignore")
-	file, err := os.Open(gitignorePath) // #nosec G304 -- .gitignore is read from the user-selected codedump root.
+	items, err := t.discoverFiles(absDir)
 	if err != nil {
-		return patterns // No .gitignore file, return with default patterns
+		return nil, fmt.Errorf("failed to discover files: %v", err)
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			patterns = append(patterns, line)
+	var files []string
+	for _, item := range items {
+		if !strings.HasSuffix(item, "/") {
+			files = append(files, item)
 		}
 	}
-
-	return patterns
-}
-
-// shouldIgnore checks if a path should be ignored based on gitignore patterns
-func (t *Terminal) shouldIgnore(path string, patterns []string) bool {
-	for _, pattern := range patterns {
-		if t.matchesPattern(path, pattern) {
-			return true
-		}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no text files found in %s", absDir)
 	}
-	return false
+	return t.collectCodeDump(files, absDir), nil
 }
 
-// matchesPattern checks if a path matches a gitignore pattern (simplified)
-func (t *Terminal) matchesPattern(path, pattern string) bool {
-	// Remove leading slash if present
-	pattern = strings.TrimPrefix(pattern, "/")
+// discoverFiles finds all text files in the directory, respecting ignore files
+func (t *Terminal) discoverFiles(rootDir string) ([]string, error) {
+	opts := fswalk.OptionsFromConfig(t.config, rootDir)
+	opts.Dirs = true
+	opts.Filter = t.isTextFileByPath
 
-	// Handle directory patterns (ending with /)
-	if strings.HasSuffix(pattern, "/") {
-		dirPattern := strings.TrimSuffix(pattern, "/")
-		// Check if the path starts with the directory pattern
-		return strings.HasPrefix(path, dirPattern+"/") || path == dirPattern
+	items, err := fswalk.List(rootDir, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Handle wildcard patterns
-	if strings.Contains(pattern, "*") {
-		matched, _ := filepath.Match(pattern, path)
-		if matched {
-			return true
-		}
-		// Also check if any parent directory matches
-		parts := strings.Split(path, "/")
-		for i := range parts {
-			partialPath := strings.Join(parts[:i+1], "/")
-			if matched, _ := filepath.Match(pattern, partialPath); matched {
-				return true
-			}
+	// List directories before files for the selection list
+	var allDirs, allFiles []string
+	for _, item := range items {
+		if strings.HasSuffix(item, "/") {
+			allDirs = append(allDirs, item)
+		} else {
+			allFiles = append(allFiles, item)
 		}
-		return false
 	}
-
-	// Exact match or prefix match for directories
-	return path == pattern || strings.HasPrefix(path, pattern+"/")
+	return append(allDirs, allFiles...), nil
 }
 
 // isTextFileByPath checks if a file is supported based on its path and content
@@ -1271,7 +1351,8 @@ func (t *Terminal) isTextFileByPath(filePath string) bool {
 		if err != nil {
 			return false
 		}
-		return t.isTextFile(content)
+		_, _, ok := t.decodeText(content)
+		return ok
 	}
 
 	return false
@@ -1321,11 +1402,12 @@ func (t *Terminal) filterExcludedFiles(allFiles, excludedItems []string) []strin
 
 // generateCodeDumpFromDir creates the final codedump string from a specific directory
 func (t *Terminal) generateCodeDumpFromDir(files []string, sourceDir string) (string, error) {
-	var result strings.Builder
+	return t.collectCodeDump(files, sourceDir).Text(), nil
+}
 
-	result.WriteString("=== Code Dump ===\n\n")
-	result.WriteString(fmt.Sprintf("generated from directory: %s\n", sourceDir))
-	result.WriteString(fmt.Sprintf("total files: %d\n\n", len(files)))
+// collectCodeDump reads the files of a codedump, skipping binary files
+func (t *Terminal) collectCodeDump(files []string, sourceDir string) *CodeDump {
+	dump := &CodeDump{Dir: sourceDir, Total: len(files)}
 
 	for _, file := range files {
 		// Build full path for reading
@@ -1343,38 +1425,42 @@ func (t *Terminal) generateCodeDumpFromDir(files []string, sourceDir string) (st
 			}
 		}
 
-		var content string
+		entry := CodeDumpFile{Path: file}
 		if isSpecialFile {
 			// Use loadTextFile for special file types (PDFs, images, etc.)
 			fileContent, err := t.loadTextFile(fullPath)
 			if err != nil {
-				result.WriteString(fmt.Sprintf("=== FILE: %s ===\nError processing file: %v\n\n", file, err))
+				entry.Error = fmt.Sprintf("Error processing file: %v", err)
+				dump.Files = append(dump.Files, entry)
 				continue
 			}
-			content = fileContent
+			entry.Content = fileContent
+			entry.Converted = true
+			if fileBytes, err := os.ReadFile(fullPath); err == nil { // #nosec G304 -- Codedump reads files discovered under the user-selected directory.
+				entry.setChecksum(fileBytes)
+			}
 		} else {
 			// Use regular file reading for text files
 			fileBytes, err := os.ReadFile(fullPath) // #nosec G304 -- Codedump reads files discovered under the user-selected directory.
 			if err != nil {
-				result.WriteString(fmt.Sprintf("=== FILE: %s ===\nError reading file: %v\n\n", file, err))
+				entry.Error = fmt.Sprintf("Error reading file: %v", err)
+				dump.Files = append(dump.Files, entry)
 				continue
 			}
 
 			// Double-check if it's a text file
-			if !t.isTextFile(fileBytes) {
+			text, _, ok := t.decodeText(fileBytes)
+			if !ok {
 				continue
 			}
 
-			content = fmt.Sprintf("File: %s\n%s", file, string(fileBytes))
+			entry.Content = text
+			entry.setChecksum(fileBytes)
 		}
-
-		result.WriteString(fmt.Sprintf("=== FILE: %s ===\n", file))
-		result.WriteString(content)
-		result.WriteString("\n\n")
+		dump.Files = append(dump.Files, entry)
 	}
 
-	result.WriteString("=== END CODE DUMP ===")
-	return result.String(), nil
+	return dump
 }
 
 // isURL checks if a string is a valid URL
@@ -1412,12 +1498,12 @@ func (t *Terminal) cleanURL(urlStr string) string {
 }
 
 // scrapeURL scrapes content from a single URL (with loading animation)
-func (t *Terminal) scrapeURL(urlStr string) (string, error) {
+func (t *Terminal) scrapeURL(urlStr, format string) (string, error) {
 	// Show loading animation
 	done := make(chan bool)
 	go t.ShowLoadingAnimation("Scraping...", done)
 
-	content, err := t.scrapeURLInternal(urlStr)
+	content, err := t.scrapeURLInternal(urlStr, format)
 
 	// Stop loading animation
 	done <- true
@@ -1425,8 +1511,9 @@ func (t *Terminal) scrapeURL(urlStr string) (string, error) {
 	return content, err
 }
 
-// scrapeURLInternal scrapes content from a single URL without animation
-func (t *Terminal) scrapeURLInternal(urlStr string) (string, error) {
+// scrapeURLInternal scrapes content from a single URL without animation.
+// format selects "markdown" or plain "text" output for regular web pages.
+func (t *Terminal) scrapeURLInternal(urlStr, format string) (string, error) {
 	// Clean any shell escapes from the URL
 	cleanedURL := t.cleanURL(urlStr)
 
@@ -1440,15 +1527,18 @@ func (t *Terminal) scrapeURLInternal(urlStr string) (string, error) {
 		if err != nil {
 			scrapeErr = fmt.Errorf("failed to scrape YouTube URL: %w", err)
 		} else {
-			result.WriteString(content)
+			result.WriteString(t.guardUntrustedContent(cleanedURL, content, nil))
 		}
 	} else {
 		// Regular web scraping with curl + lynx
-		content, err := t.scrapeWeb(cleanedURL)
+		content, hiddenFindings, lastModified, err := t.scrapeWeb(cleanedURL, format)
 		if err != nil {
 			scrapeErr = fmt.Errorf("failed to scrape URL: %w", err)
 		} else {
-			result.WriteString(content)
+			if lastModified != "" {
+				result.WriteString(fmt.Sprintf("Last modified: %s\n\n", formatLastModified(lastModified)))
+			}
+			result.WriteString(t.guardUntrustedContent(cleanedURL, content, hiddenFindings))
 		}
 	}
 
@@ -1461,28 +1551,111 @@ func (t *Terminal) scrapeURLInternal(urlStr string) (string, error) {
 }
 
 // scrapeWeb scrapes regular web pages using native Go http and html parsing.
-func (t *Terminal) scrapeWeb(urlStr string) (string, error) {
+// It also returns prompt-injection findings from text hidden from human readers
+// and the page's Last-Modified header, if any.
+func (t *Terminal) scrapeWeb(urlStr, format string) (string, []string, string, error) {
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 	// Set a user-agent to mimic a browser, as some sites block default Go user-agent
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	t.applyScrapeCookies(req)
+
+	page, err := t.fetchPage(client, req)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	// Only HTML goes through the HTML parser; other text types are rendered
+	// for their format and binary responses are rejected
+	mediaType := "text/html"
+	if page.ContentType != "" {
+		if parsed, _, parseErr := mime.ParseMediaType(page.ContentType); parseErr == nil {
+			mediaType = parsed
+		}
+	}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return prettyJSON(page.Body), nil, page.LastModified, nil
+	case mediaType == "text/csv":
+		text, err := csvMarkdownTable(page.Body)
+		if err != nil {
+			return "", nil, "", err
+		}
+		return text, nil, page.LastModified, nil
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return string(page.Body), nil, page.LastModified, nil
+	default:
+		return "", nil, "", fmt.Errorf("unsupported content type %s: only HTML, JSON, CSV, and text responses can be loaded", mediaType)
+	}
 
-	resp, err := client.Do(req)
+	var text string
+	if format == "markdown" {
+		text, err = htmlToMarkdown(bytes.NewReader(page.Body), urlStr)
+	} else {
+		text, err = t.textContentFromHTML(bytes.NewReader(page.Body))
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+		return "", nil, "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	var hiddenFindings []string
+	if t.config.InjectionCheck {
+		if hidden, hiddenErr := hiddenTextFromHTML(bytes.NewReader(page.Body)); hiddenErr == nil && len(DetectPromptInjection(hidden)) > 0 {
+			hiddenFindings = append(hiddenFindings, "hidden HTML instructions")
+		}
 	}
 
-	return t.textContentFromHTML(resp.Body)
+	return text, hiddenFindings, page.LastModified, nil
+}
+
+// prettyJSON indents a JSON response body, returning it unchanged when it is not valid JSON
+func prettyJSON(body []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return string(body)
+	}
+	return out.String() + "\n"
+}
+
+// csvMarkdownTable renders a CSV response body as a markdown table
+func csvMarkdownTable(body []byte) (string, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	for _, record := range records {
+		for i, cell := range record {
+			record[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+	}
+	return markdownTable(records) + "\n", nil
+}
+
+// guardUntrustedContent warns about likely prompt-injection content and, when
+// injection_neutralize is enabled, quotes the content so the model treats it as data.
+func (t *Terminal) guardUntrustedContent(source, content string, extraFindings []string) string {
+	if !t.config.InjectionCheck {
+		return content
+	}
+
+	findings := append(DetectPromptInjection(content), extraFindings...)
+	if len(findings) == 0 {
+		return content
+	}
+
+	t.PrintError(fmt.Sprintf("warning: possible prompt injection in %s (%s)", source, strings.Join(findings, ", ")))
+	if !t.config.InjectionNeutralize {
+		return content
+	}
+	return NeutralizeUntrustedContent(source, content)
 }
 
 // textContentFromHTML extracts readable text from an HTML document body.
@@ -1643,39 +1816,80 @@ func (t *Terminal) parseYouTubeMetadata(jsonStr string) string {
 	return result.String()
 }
 
-// ScrapeURLs scrapes content from multiple URLs
+// ScrapeURLs scrapes content from multiple URLs using the configured scrape_format
 func (t *Terminal) ScrapeURLs(urls []string) (string, error) {
-	var result strings.Builder
+	return t.ScrapeURLsWithFormat(urls, t.config.ScrapeFormat)
+}
 
+// ScrapeURLsWithFormat scrapes content from multiple URLs, rendering web pages as
+// "markdown" or plain "text"
+func (t *Terminal) ScrapeURLsWithFormat(urls []string, format string) (string, error) {
+	var targets []string
 	for _, urlStr := range urls {
-		if urlStr == "" {
-			continue
+		if urlStr != "" {
+			targets = append(targets, urlStr)
 		}
+	}
+
+	contents, errs := t.scrapeEach(targets, format)
+	for i, err := range errs {
+		if err != nil {
+			contents[i] = fmt.Sprintf("Error scraping %s: %v\n", targets[i], err)
+		}
+	}
+	return strings.Join(contents, ""), nil
+}
 
-		// Show loading animation for each URL
-		done := make(chan bool)
-		go t.ShowLoadingAnimation("Scraping...", done)
+// scrapeEach scrapes targets with at most scrape_parallel requests in
+// flight, reporting each URL as it finishes. Contents and errors are
+// returned in input order.
+func (t *Terminal) scrapeEach(targets []string, format string) ([]string, []error) {
+	status := Progress{Verb: "scraped", Unit: "URLs", Total: len(targets)}
+	progress := t.StartProgress(status)
+	defer progress.Stop()
 
-		content, err := t.scrapeURLInternal(urlStr)
+	parallel := t.config.ScrapeParallel
+	if parallel <= 0 {
+		parallel = 1
+	}
 
-		// Stop loading animation
-		done <- true
+	contents := make([]string, len(targets))
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
 
-		if err != nil {
-			result.WriteString(fmt.Sprintf("Error scraping %s: %v\n", urlStr, err))
-			continue
-		}
+	for i, urlStr := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, urlStr string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			content, err := t.scrapeURLInternal(urlStr, format)
 
-		result.WriteString(content)
+			mu.Lock()
+			defer mu.Unlock()
+			status.Done++
+			if err != nil {
+				errs[i] = err
+				status.Note, status.Failed = fmt.Sprintf("failed %s: %v", urlStr, err), true
+			} else {
+				contents[i] = content
+				status.Tokens += tokens.Count(t.config.CurrentModel, content)
+				status.Note, status.Failed = "scraped "+urlStr, false
+			}
+			progress.Report(status)
+		}(i, urlStr)
 	}
+	wg.Wait()
 
-	return result.String(), nil
+	return contents, errs
 }
 
-// WebSearch performs a web search using the Brave Search API
+// WebSearch performs a web search with the configured search_backend
 func (t *Terminal) WebSearch(query string) (string, error) {
-	apiKey := os.Getenv("BRAVE_API_KEY")
-	if apiKey == "" {
+	if t.usesBrave() && os.Getenv("BRAVE_API_KEY") == "" && t.config.SearchFallback == "" {
 		return "", fmt.Errorf("the BRAVE_API_KEY environment variable is not set")
 	}
 
@@ -1683,49 +1897,17 @@ func (t *Terminal) WebSearch(query string) (string, error) {
 	done := make(chan bool)
 	go t.ShowLoadingAnimation("Searching...", done)
 
-	req, err := http.NewRequest("GET", "https://api.search.brave.com/res/v1/web/search", nil)
-	if err != nil {
-		done <- true
-		return "", fmt.Errorf("failed to create search request: %w", err)
-	}
-
-	q := req.URL.Query()
-	q.Add("q", query)
-	q.Add("count", fmt.Sprintf("%d", t.config.NumSearchResults))
-	q.Add("country", t.config.SearchCountry)
-	q.Add("search_lang", t.config.SearchLang)
-	req.URL.RawQuery = q.Encode()
-
-	req.Header.Set("X-Subscription-Token", apiKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		done <- true
-		return "", fmt.Errorf("failed to perform search: %w", err)
-	}
-	defer resp.Body.Close()
+	results, warnings, err := t.searchWithFallback(query)
+	done <- true
 
-	if resp.StatusCode != http.StatusOK {
-		done <- true
-		return "", fmt.Errorf("search request failed with status: %s", resp.Status)
+	for _, warning := range warnings {
+		t.PrintError(warning)
 	}
-
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		done <- true
-		return "", fmt.Errorf("failed to read search response: %w", err)
-	}
-
-	var braveResult BraveSearchResult
-	if err := json.Unmarshal(body, &braveResult); err != nil {
-		done <- true
-		return "", fmt.Errorf("failed to parse search results: %w", err)
+		return "", err
 	}
 
-	done <- true
-
-	if len(braveResult.Web.Results) == 0 {
+	if len(results) == 0 {
 		noResultsMsg := fmt.Sprintf("No search results found for: %s\n", query)
 		if t.config.ShowSearchResults {
 			fmt.Print(noResultsMsg)
@@ -1733,13 +1915,13 @@ func (t *Terminal) WebSearch(query string) (string, error) {
 		return noResultsMsg, nil
 	}
 
-	formatted := t.formatBraveSearchResults(braveResult.Web.Results, query)
+	formatted := t.formatBraveSearchResults(results, query)
 
 	if t.config.ShowSearchResults {
 		fmt.Print(formatted)
 	}
 
-	return formatted, nil
+	return t.guardUntrustedContent(fmt.Sprintf("search results for '%s'", query), formatted, nil), nil
 }
 
 // BraveSearchResult represents the top-level structure of the Brave Search API response
@@ -1821,6 +2003,11 @@ func (t *Terminal) CopyToClipboard(content string) error {
 		return fmt.Errorf("failed to copy to clipboard: %v (stderr: %s)", err, stderr.String())
 	}
 
+	// The system clipboard only holds the latest copy, so keep a local history for !yh
+	if err := t.recordClipboard(content); err != nil {
+		t.PrintError(fmt.Sprintf("warning: failed to save clipboard history: %v", err))
+	}
+
 	return nil
 }
 
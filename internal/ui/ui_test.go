@@ -0,0 +1,60 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/MehmetMHY/ch/pkg/types"
+)
+
+func TestFzfSelectStreamsItems(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of fzf")
+	}
+	// Far more than a pipe buffer holds
+	items := make([]string, 50000)
+	for i := range items {
+		items[i] = fmt.Sprintf("[platform] model-%d", i)
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		// Reads the whole list, like a user picking the last entry
+		{"whole list", "#!/bin/sh\nwhile read -r line; do last=$line; done\necho \"$last\"\n", items[len(items)-1]},
+		// Exits after the first line while ch is still writing
+		{"early exit", "#!/bin/sh\nread -r line\necho \"$line\"\n", items[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bin := t.TempDir()
+			if err := os.WriteFile(filepath.Join(bin, "fzf"), []byte(tt.script), 0700); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", bin)
+
+			terminal := NewTerminal(&types.Config{})
+			done := make(chan struct{})
+			var got string
+			var err error
+			go func() {
+				got, err = terminal.FzfSelect(items, "model: ")
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("FzfSelect did not return")
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("FzfSelect() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
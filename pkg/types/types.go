@@ -45,18 +45,22 @@ func (b *BaseURLValue) GetURLs() []string {
 
 // ChatMessage represents a single chat message
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // image files loaded with the message
 }
 
 // ChatHistory represents a chat exchange entry
 type ChatHistory struct {
-	Time     int64  `json:"time"`
-	User     string `json:"user"`
-	Bot      string `json:"bot"`
-	Platform string `json:"platform"`
-	Model    string `json:"model"`
-	Context  string `json:"context,omitempty"`
+	Time     int64    `json:"time"`
+	User     string   `json:"user"`
+	Bot      string   `json:"bot"`
+	Platform string   `json:"platform"`
+	Model    string   `json:"model"`
+	Context  string   `json:"context,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Seed     *int     `json:"seed,omitempty"`
+	Elapsed  float64  `json:"elapsed,omitempty"` // seconds the response took
 }
 
 // Platform represents an AI platform configuration
@@ -65,7 +69,19 @@ type Platform struct {
 	BaseURL BaseURLValue      `json:"base_url"`
 	EnvName string            `json:"env_name"`
 	Models  PlatformModels    `json:"models"`
-	Headers map[string]string `json:"headers"`
+	Headers map[string]string `json:"headers"`          // Sent with chat and model list requests
+	Driver  string            `json:"driver,omitempty"` // "gemini" for the native Gemini API, "azure" for Azure OpenAI, empty for OpenAI-compatible
+
+	// How the env_name key is sent: "bearer" (default), "header" in
+	// auth_header, "query" in auth_param, or "none"
+	Auth       string `json:"auth,omitempty"`
+	AuthHeader string `json:"auth_header,omitempty"`
+	AuthParam  string `json:"auth_param,omitempty"`
+
+	// Azure OpenAI: the api-version query value and friendly model names
+	// mapped to deployment names
+	APIVersion  string            `json:"api_version,omitempty"`
+	Deployments map[string]string `json:"deployments,omitempty"`
 }
 
 // PlatformModels contains model endpoint configuration
@@ -73,6 +89,7 @@ type PlatformModels struct {
 	URL      string            `json:"url"`
 	JSONPath string            `json:"json_name_path"`
 	Headers  map[string]string `json:"headers"`
+	Static   []string          `json:"static,omitempty"` // Fixed model list for endpoints without a models URL
 }
 
 // Config holds application configuration
@@ -82,6 +99,8 @@ type Config struct {
 	CurrentModel       string              `json:"current_model,omitempty"`
 	CurrentBaseURL     string              `json:"current_base_url,omitempty"`
 	SystemPrompt       string              `json:"system_prompt,omitempty"`
+	DeveloperPrompt    string              `json:"developer_prompt,omitempty"`
+	TimeContext        bool                `json:"time_context,omitempty"`
 	ExitKey            string              `json:"exit_key,omitempty"`
 	ModelSwitch        string              `json:"model_switch,omitempty"`
 	EditorInput        string              `json:"editor_input,omitempty"`
@@ -90,11 +109,13 @@ type Config struct {
 	ExportChat         string              `json:"export_chat,omitempty"`
 	Backtrack          string              `json:"backtrack,omitempty"`
 	WebSearch          string              `json:"web_search,omitempty"`
+	DeepSearch         string              `json:"deep_search,omitempty"`
 	ShowSearchResults  bool                `json:"show_search_results,omitempty"`
 	NumSearchResults   int                 `json:"num_search_results,omitempty"`
 	SearchCountry      string              `json:"search_country,omitempty"`
 	SearchLang         string              `json:"search_lang,omitempty"`
 	ScrapeURL          string              `json:"scrape_url,omitempty"`
+	ScrapeFormat       string              `json:"scrape_format,omitempty"`
 	CopyToClipboard    string              `json:"copy_to_clipboard,omitempty"`
 	QuickCopyLatest    string              `json:"quick_copy_latest,omitempty"`
 	LoadFiles          string              `json:"load_files,omitempty"`
@@ -108,15 +129,48 @@ type Config struct {
 	PreferredEditor    string              `json:"preferred_editor,omitempty"`
 	CurrentPlatform    string              `json:"current_platform,omitempty"`
 	AllModels          string              `json:"all_models,omitempty"`
+	RateSession        string              `json:"rate_session,omitempty"`
+	TagExchange        string              `json:"tag_exchange,omitempty"`
+	AddNote            string              `json:"add_note,omitempty"`
+	EditStopSequences  string              `json:"edit_stop_sequences,omitempty"`
+	EditRedactions     string              `json:"edit_redactions,omitempty"`
+	BigFile            string              `json:"big_file,omitempty"`
+	Retrieve           string              `json:"retrieve,omitempty"`
+	ApplyPatches       string              `json:"apply_patches,omitempty"`
+	EditFile           string              `json:"edit_file,omitempty"`
+	Remember           string              `json:"remember,omitempty"`
+	Memories           string              `json:"memories,omitempty"`
+	ClipboardHistory   string              `json:"clipboard_history,omitempty"`
+	LiveFiles          string              `json:"live_files,omitempty"`
+	LockModel          string              `json:"lock_model,omitempty"`
+	UnlockModel        string              `json:"unlock_model,omitempty"`
+	ResetTerminal      string              `json:"reset_terminal,omitempty"`
+	AddBookmark        string              `json:"add_bookmark,omitempty"`
+	ListBookmarks      string              `json:"list_bookmarks,omitempty"`
+	ShowLog            string              `json:"show_log,omitempty"`
+	ReplayTo           string              `json:"replay_to,omitempty"`
+	UseTemplate        string              `json:"use_template,omitempty"`
+	ResponseActions    string              `json:"response_actions,omitempty"`
+	SaveCodeBlock      string              `json:"save_code_block,omitempty"`
+	EditHeaders        string              `json:"edit_headers,omitempty"`
+	RemoteLoad         string              `json:"remote_load,omitempty"`
+	LoadRows           string              `json:"load_rows,omitempty"`
+	DBQuery            string              `json:"db_query,omitempty"`
 	MuteNotifications  bool                `json:"mute_notifications,omitempty"`
 	EnableSessionSave  bool                `json:"enable_session_save"`
 	SaveAllSessions    bool                `json:"save_all_sessions,omitempty"`
+	AutosaveInterval   int                 `json:"autosave_interval,omitempty"` // minimum seconds between saves after commands, not a timer
 	ShallowLoadDirs    []string            `json:"shallow_load_dirs,omitempty"`
+	ShallowLoadDepths  map[string]int      `json:"shallow_load_depths,omitempty"`
 	ShowThinking       bool                `json:"show_thinking"`
 	SlowModelPatterns  []string            `json:"slow_model_patterns,omitempty"`
 	IsPipedOutput      bool                `json:"-"` // Runtime detection, not from config file
+	StreamJSON         bool                `json:"-"` // Set by --stream-json, not from config file
+	DryRun             bool                `json:"-"` // Set by --dry-run, not from config file
+	UIToStderr         bool                `json:"-"` // Interactive session with piped stdout: UI on stderr, responses on stdout
 	Platforms          map[string]Platform `json:"platforms,omitempty"`
 	ExplicitBoolFields map[string]bool     `json:"-"`
+	KeyConflicts       []string            `json:"-"` // Command key problems found when merging config.json
 
 	// AI-generated filename suggestion settings (used by !e export flow)
 	AINameEnable         bool   `json:"ai_name_enable,omitempty"`
@@ -124,15 +178,270 @@ type Config struct {
 	AINameCount          int    `json:"ai_name_count,omitempty"`
 	AINameTimeoutSeconds int    `json:"ai_name_timeout_seconds,omitempty"`
 	AINamePrompt         string `json:"ai_name_prompt,omitempty"`
+
+	// Prompt-injection heuristics for scraped and searched web content
+	InjectionCheck      bool `json:"injection_check"`
+	InjectionNeutralize bool `json:"injection_neutralize,omitempty"`
+
+	// Cookies sent with scrape requests for pages behind logins
+	ScrapeCookieFile    string `json:"scrape_cookie_file,omitempty"`
+	ScrapeCookieBrowser string `json:"scrape_cookie_browser,omitempty"`
+
+	// Number of URLs scraped at the same time by !s and -l
+	ScrapeParallel int `json:"scrape_parallel,omitempty"`
+
+	// Search provider ("brave", "searxng", or "duckduckgo"), Brave usage
+	// tracking, and the fallback provider
+	SearchBackend         string `json:"search_backend,omitempty"`
+	SearXNGURL            string `json:"searxng_url,omitempty"`
+	BraveMonthlyQuota     int    `json:"brave_monthly_quota,omitempty"`
+	BraveQuotaWarnPercent int    `json:"brave_quota_warn_percent,omitempty"`
+	SearchFallback        string `json:"search_fallback,omitempty"`
+
+	// Deep search: scrape the top results and answer from their content
+	WebSearchDeep     bool `json:"web_search_deep,omitempty"`
+	DeepSearchResults int  `json:"deep_search_results,omitempty"`
+	DeepSearchTokens  int  `json:"deep_search_tokens,omitempty"`
+
+	// Follow-up question suggestions shown after interactive responses
+	SuggestFollowups bool `json:"suggest_followups,omitempty"`
+	FollowupCount    int  `json:"followup_count,omitempty"`
+
+	// Generation options passed through to chat requests
+	StopSequences []string `json:"stop_sequences,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	ShowLogprobs  bool     `json:"show_logprobs,omitempty"`
+	TopLogprobs   int      `json:"top_logprobs,omitempty"`
+
+	// Embeddings subcommand defaults (ch embed)
+	EmbeddingModel     string `json:"embedding_model,omitempty"`
+	EmbeddingBatchSize int    `json:"embedding_batch_size,omitempty"`
+	EmbeddingRPM       int    `json:"embedding_rpm,omitempty"`
+
+	// In-memory chunk retrieval for one large file (!bigfile)
+	BigFileChunkTokens int `json:"big_file_chunk_tokens,omitempty"`
+	BigFileTopK        int `json:"big_file_top_k,omitempty"`
+
+	// Persistent embeddings index of a directory (ch --index, !rag)
+	IndexChunkTokens int `json:"index_chunk_tokens,omitempty"`
+	IndexTopK        int `json:"index_top_k,omitempty"`
+
+	// Long-term memories recalled across sessions (!remember, !memories)
+	Memory     bool `json:"memory,omitempty"`
+	MemoryTopK int  `json:"memory_top_k,omitempty"`
+
+	// Map-reduce summarization subcommand defaults (ch summarize)
+	SummarizeChunkTokens   int `json:"summarize_chunk_tokens,omitempty"`
+	SummarizeOverlapTokens int `json:"summarize_overlap_tokens,omitempty"`
+	SummarizeParallel      int `json:"summarize_parallel,omitempty"`
+
+	// Moderation pre-check on outgoing prompts (off, warn, block)
+	Moderation      string `json:"moderation,omitempty"`
+	ModerationModel string `json:"moderation_model,omitempty"`
+	ModerationURL   string `json:"moderation_url,omitempty"`
+
+	// Cheap-model distillation of large loaded contexts before sending
+	CompressModel     string `json:"compress_model,omitempty"`
+	CompressThreshold int    `json:"compress_threshold,omitempty"`
+
+	// Token budget for packing earlier messages into each request by recency and relevance; 0 sends everything
+	ContextBudget int `json:"context_budget,omitempty"`
+
+	// Longest user message sent in one piece; longer ones are split into acknowledged parts. Negative never splits
+	MaxMessageChars int `json:"max_message_chars,omitempty"`
+
+	// Prompt-size routing for the "auto" model alias
+	AutoModelRoutes []AutoModelRoute `json:"auto_model_routes,omitempty"`
+
+	// Per-project session isolation (ch ws)
+	Workspaces bool `json:"workspaces,omitempty"`
+
+	// Named startup presets selected with ch --profile
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// Local history of copied text for !yh (negative size disables it)
+	ClipboardHistorySize int `json:"clipboard_history_size,omitempty"`
+
+	// Recently loaded files and directories shown at the top of !l (negative size disables it)
+	RecentLoadsSize int `json:"recent_loads_size,omitempty"`
+
+	// Session storage backend (json or sqlite)
+	StorageBackend string `json:"storage_backend,omitempty"`
+
+	// Confirmation thresholds for @dir and @glob prompt mentions (negative never asks)
+	MentionConfirmFiles int `json:"mention_confirm_files,omitempty"`
+	MentionConfirmBytes int `json:"mention_confirm_bytes,omitempty"`
+
+	// Opt-in $(command) substitution in prompts, with an output cap
+	ShellInterpolation         bool `json:"shell_interpolation,omitempty"`
+	ShellInterpolationMaxBytes int  `json:"shell_interpolation_max_bytes,omitempty"`
+
+	// Offer the earlier answer before resending a prompt already asked this session
+	DuplicatePromptCheck bool `json:"duplicate_prompt_check,omitempty"`
+
+	// Spend ceilings in USD, estimated from model prices per million tokens
+	ModelPrices     map[string]ModelPrice `json:"model_prices,omitempty"`
+	MaxSessionCost  float64               `json:"max_session_cost,omitempty"`
+	MaxDailyCost    float64               `json:"max_daily_cost,omitempty"`
+	CostLimitAction string                `json:"cost_limit_action,omitempty"`
+
+	// Append a token and cost summary of each run to ~/.ch/usage.jsonl for ch --usage
+	UsageLog bool `json:"usage_log,omitempty"`
+
+	// Provider data-retention opt-out, with per-platform extra headers and body fields
+	ProviderStorageOptOut bool                         `json:"provider_storage_opt_out,omitempty"`
+	StorageOptOutHeaders  map[string]map[string]string `json:"storage_opt_out_headers,omitempty"`
+	StorageOptOutParams   map[string]map[string]any    `json:"storage_opt_out_params,omitempty"`
+
+	// Platforms that accept the "developer" role; others get developer_prompt as a system message
+	DeveloperRolePlatforms []string `json:"developer_role_platforms,omitempty"`
+
+	// Extra HTTP headers sent with every request to a platform, e.g. for API gateways
+	ExtraHeaders map[string]map[string]string `json:"extra_headers,omitempty"`
+
+	// Provider-specific chat request fields, keyed by "platform" or "platform/model"
+	ExtraBody map[string]map[string]any `json:"extra_body,omitempty"`
+
+	// Client-side request pacing per platform, shared by every feature that sends requests
+	RateLimits map[string]RateLimit `json:"rate_limits,omitempty"`
+
+	// Fixtures file for the offline mock platform; default ~/.ch/mock.json
+	MockFixtures string `json:"mock_fixtures,omitempty"`
+
+	// Capability corrections, keyed by "platform" or "platform/model"
+	ModelCapabilities map[string]CapabilityOverride `json:"model_capabilities,omitempty"`
+
+	// Dim [platform/model · time] line after each response
+	ShowModelAnnotation bool `json:"show_model_annotation,omitempty"`
+
+	// Dim [words · tokens · lines of code · time] line after each response
+	ShowResponseStats bool `json:"show_response_stats,omitempty"`
+
+	// Label code blocks [1], [2], ... in displayed responses for !save and !sN
+	NumberCodeBlocks bool `json:"number_code_blocks,omitempty"`
+
+	// Draw markdown tables in displayed responses with aligned box-drawing borders
+	RenderTables bool `json:"render_tables,omitempty"`
+
+	// File walking for !l, codedump, mentions, and export file pickers
+	FollowSymlinks    bool  `json:"follow_symlinks,omitempty"`
+	IncludeSubmodules bool  `json:"include_submodules,omitempty"`
+	MaxWalkDepth      int   `json:"max_walk_depth,omitempty"`
+	RespectGitignore  bool  `json:"respect_gitignore,omitempty"`
+	MaxWalkFileBytes  int64 `json:"max_walk_file_bytes,omitempty"`
+
+	// Add size, line count, language, and mtime under File: headers and fence plain text files
+	FileMetadata bool `json:"file_metadata,omitempty"`
+
+	// Send a unified diff instead of the full contents when !l reloads a file already in context
+	ReloadDiff bool `json:"reload_diff,omitempty"`
+
+	// Let the model call local tools (shell, file read, web search, URL scrape), confirming each call
+	Tools bool `json:"tools,omitempty"`
+
+	// Offer a path picker when loading JSON/YAML files of at least this size; -1 turns it off
+	StructuredSelectBytes int `json:"structured_select_bytes,omitempty"`
+
+	// Load large CSV/XLSX files as a schema summary with sample rows; !rows loads ranges
+	TabularSummary bool `json:"tabular_summary,omitempty"`
+
+	// Find-and-replace rules applied to exported content
+	Redactions []Redaction `json:"redactions,omitempty"`
+
+	// Redraw spinners and progress lines less often, print streamed replies
+	// in chunks, and shorten long echoed input, for slow SSH links
+	LowBandwidth         bool `json:"low_bandwidth,omitempty"`
+	LowBandwidthInterval int  `json:"low_bandwidth_interval_ms,omitempty"`
+
+	// Send a one-token request in the background when an interactive
+	// session starts or the model changes, so the first prompt is faster
+	Warmup bool `json:"warmup,omitempty"`
+}
+
+// ModelPrice is the USD price per million input and output tokens
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ModelUsage is the tokens and estimated USD cost of requests to one model.
+// Estimated counts requests whose tokens were counted locally because the
+// provider reported no usage.
+type ModelUsage struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	Estimated        int     `json:"estimated,omitempty"`
+}
+
+// UsageSummary is one line of ~/.ch/usage.jsonl: the usage of one ch run,
+// keyed by "platform/model"
+type UsageSummary struct {
+	Time    int64                 `json:"time"`
+	Session string                `json:"session,omitempty"`
+	Models  map[string]ModelUsage `json:"models"`
+}
+
+// RateLimit caps requests to a platform; 0 leaves a limit off. Burst is how
+// many requests may go out back to back before pacing starts (default 1).
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	MaxConcurrent     int `json:"max_concurrent,omitempty"`
+	Burst             int `json:"burst,omitempty"`
+}
+
+// CapabilityOverride corrects the built-in capability data; unset fields keep it
+type CapabilityOverride struct {
+	Streaming *bool `json:"streaming,omitempty"`
+	Tools     *bool `json:"tools,omitempty"`
+	Vision    *bool `json:"vision,omitempty"`
+	JSONMode  *bool `json:"json_mode,omitempty"`
+	Logprobs  *bool `json:"logprobs,omitempty"`
+	MaxOutput int   `json:"max_output,omitempty"`
+}
+
+// AutoModelRoute sends prompts of up to MaxTokens tokens to Model; 0 means no limit
+type AutoModelRoute struct {
+	MaxTokens int    `json:"max_tokens"`
+	Model     string `json:"model"`
+}
+
+// WorkspaceSettings are per-workspace defaults applied on startup
+type WorkspaceSettings struct {
+	Platform     string `json:"platform,omitempty"`
+	Model        string `json:"model,omitempty"`
+	SystemPrompt string `json:"system_prompt,omitempty"`
+}
+
+// Profile is a named startup preset applied with ch --profile; empty fields
+// keep the configured values. SafeMode turns off tools and shell
+// interpolation and turns on injection checks and the provider storage
+// opt-out.
+type Profile struct {
+	Platform      string `json:"platform,omitempty"`
+	Model         string `json:"model,omitempty"`
+	SystemPrompt  string `json:"system_prompt,omitempty"`
+	SearchBackend string `json:"search_backend,omitempty"`
+	SafeMode      bool   `json:"safe_mode,omitempty"`
+}
+
+// Redaction is a find-and-replace rule applied to exported content
+type Redaction struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+	Regex   bool   `json:"regex,omitempty"`
 }
 
 // ExportEntry represents a single entry in the JSON export
 type ExportEntry struct {
-	Platform    string `json:"platform"`
-	ModelName   string `json:"model_name"`
-	UserPrompt  string `json:"user_prompt"`
-	BotResponse string `json:"bot_response"`
-	Timestamp   int64  `json:"timestamp"`
+	Platform    string  `json:"platform"`
+	ModelName   string  `json:"model_name"`
+	UserPrompt  string  `json:"user_prompt"`
+	BotResponse string  `json:"bot_response"`
+	Timestamp   int64   `json:"timestamp"`
+	Seed        *int    `json:"seed,omitempty"`
+	Elapsed     float64 `json:"elapsed,omitempty"`
 }
 
 // ChatExport represents the complete JSON export structure
@@ -148,19 +457,34 @@ type SessionFile struct {
 	Model       string        `json:"model"`
 	BaseURL     string        `json:"base_url"`
 	ChatHistory []ChatHistory `json:"messages"`
+	Rating      int           `json:"rating,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+	Notes       []SessionNote `json:"notes,omitempty"`
 	SourceFile  string        `json:"-"`
 }
 
+// SessionNote is a free-form note attached to a session with !note. Notes
+// are never sent to the model.
+type SessionNote struct {
+	Time int64  `json:"time"`
+	Text string `json:"text"`
+}
+
 // AppState holds the application's runtime state
 type AppState struct {
 	Config               *Config
 	Messages             []ChatMessage
 	ChatHistory          []ChatHistory
 	RecentlyCreatedFiles []string
+	SessionArtifacts     []string // Absolute paths of every file written this session, listed on exit
 	IsStreaming          bool
 	StreamingCancel      func()
 	IsExecutingCommand   bool
 	CommandCancel        func()
 	SessionStartTime     int64 // Tracks when the current session started for consistent filename
 	SessionFilePath      string
+	SessionRating        int           // Session-level rating (1-5) used to curate dataset exports
+	SessionTags          []string      // Session-level tags persisted with the session file
+	SessionNotes         []SessionNote // !note notes persisted with the session file
+	ModelLocked          bool          // Set by !lock; model and platform switches ask for confirmation
 }